@@ -33,6 +33,9 @@ func main() {
 	app.BuildAuthLayer()
 	app.BuildWalletLayer()
 	app.BuildExchangeLayer()
+	app.BuildTransferLayer()
+	app.BuildHoldLayer()
+	app.BuildAdminLayer()
 
 	if err := app.Run(); err != nil {
 		log.Fatalf("Ошибка при работе приложения: %v", err)
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+)
+
+type AccountHandler struct {
+	service service.Account
+}
+
+func NewAccountHandler(service service.Account) *AccountHandler {
+	return &AccountHandler{
+		service: service,
+	}
+}
+
+// VerifyEmail godoc
+// @Summary      Подтверждение email
+// @Description  Подтверждает email токеном из письма. Токен одноразовый. Чтобы признак подтверждения попал в access токен, его нужно обновить через /token/refresh
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.VerifyEmailRequest true "Токен из письма"
+// @Success      200 {object} models.AccountActionResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /email/verify [post]
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.VerifyEmail"
+	log := middlew.GetLogger(r.Context())
+
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	if err := h.service.VerifyEmail(r.Context(), req); err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, models.AccountActionResponse{Message: "Email verified successfully"})
+}
+
+// ResendVerification godoc
+// @Summary      Повторная отправка письма подтверждения
+// @Description  Отправляет новое письмо подтверждения email; ссылки из прежних писем перестают действовать
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      202 {object} models.AccountActionResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /email/verify/resend [post]
+func (h *AccountHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ResendVerification"
+	log := middlew.GetLogger(r.Context())
+
+	if err := h.service.ResendVerification(r.Context(), middlew.GetUserID(r.Context())); err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusAccepted, models.AccountActionResponse{Message: "Verification email sent"})
+}
+
+// ForgotPassword godoc
+// @Summary      Запрос сброса пароля
+// @Description  Отправляет на email ссылку для сброса пароля. Ответ одинаков независимо от того, зарегистрирован ли адрес
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.ForgotPasswordRequest true "Email пользователя"
+// @Success      202 {object} models.AccountActionResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /password/forgot [post]
+func (h *AccountHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ForgotPassword"
+	log := middlew.GetLogger(r.Context())
+
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	if err := h.service.ForgotPassword(r.Context(), req); err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusAccepted, models.AccountActionResponse{
+		Message: "If the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword godoc
+// @Summary      Сброс пароля
+// @Description  Устанавливает новый пароль по токену из письма. Токен одноразовый; все refresh токены пользователя отзываются
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.ResetPasswordRequest true "Токен из письма и новый пароль"
+// @Success      200 {object} models.AccountActionResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /password/reset [post]
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ResetPassword"
+	log := middlew.GetLogger(r.Context())
+
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), req); err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, models.AccountActionResponse{Message: "Password has been reset"})
+}
+
+func (h *AccountHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrInvalidToken):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_token", "Token is invalid, expired or already used")
+	case errors.Is(err, custom_err.ErrEmailAlreadyVerified):
+		response.WriteJSONError(w, log, http.StatusConflict, "email_already_verified", "Email is already verified")
+	case errors.Is(err, custom_err.ErrNotFound):
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "User not found")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	default:
+		log.Error("account operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type AdminHandler struct {
+	service service.Admin
+}
+
+func NewAdminHandler(service service.Admin) *AdminHandler {
+	return &AdminHandler{
+		service: service,
+	}
+}
+
+// SearchUsers godoc
+// @Summary      Поиск пользователей
+// @Description  Ищет пользователей по подстроке username или email. Доступно ролям support и admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        q      query string false "Подстрока username или email"
+// @Param        role   query string false "Фильтр по роли (user, support, admin)"
+// @Param        limit  query int    false "Размер страницы (1-100, по умолчанию 20)"
+// @Param        cursor query string false "Курсор следующей страницы из next_cursor"
+// @Success      200 {object} models.UserSearchResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/users [get]
+func (h *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.SearchUsers"
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+	query := r.URL.Query()
+
+	req := models.UserSearchRequest{
+		Query:  query.Get("q"),
+		Role:   models.Role(query.Get("role")),
+		Cursor: query.Get("cursor"),
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			log.Warn("invalid limit", slog.String("op", op), slog.String("limit", v))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", "limit must be an integer")
+			return
+		}
+		req.Limit = limit
+	}
+
+	result, err := h.service.SearchUsers(r.Context(), actor, req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// GetUser godoc
+// @Summary      Пользователь и его кошельки
+// @Description  Возвращает данные пользователя и все его кошельки, включая замороженные. Доступно ролям support и admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        userID path string true "ID пользователя"
+// @Success      200 {object} models.AdminUserResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/users/{userID} [get]
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.AdminGetUser"
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+
+	userID, ok := parseUUIDParam(w, r, log, op, "userID")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.GetUser(r.Context(), actor, userID)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// GetUserTransactions godoc
+// @Summary      История операций пользователя
+// @Description  Возвращает историю операций пользователя с теми же фильтрами, что и /wallet/transactions. Доступно ролям support и admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        userID   path  string true  "ID пользователя"
+// @Param        currency query string false "Фильтр по коду валюты"
+// @Param        type     query string false "Фильтр по типу операции"
+// @Param        from     query string false "Начало периода (RFC3339, включительно)"
+// @Param        to       query string false "Конец периода (RFC3339, не включительно)"
+// @Param        limit    query int    false "Размер страницы (1-100, по умолчанию 20)"
+// @Param        cursor   query string false "Курсор следующей страницы из next_cursor"
+// @Success      200 {object} models.TransactionHistoryResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/users/{userID}/transactions [get]
+func (h *AdminHandler) GetUserTransactions(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.AdminGetUserTransactions"
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+
+	userID, ok := parseUUIDParam(w, r, log, op, "userID")
+	if !ok {
+		return
+	}
+
+	req, err := parseTransactionHistoryQuery(r.URL.Query())
+	if err != nil {
+		log.Warn("invalid query parameter", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", err.Error())
+		return
+	}
+
+	result, err := h.service.GetUserTransactions(r.Context(), actor, userID, req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// FreezeWallet godoc
+// @Summary      Заморозить кошелек
+// @Description  Запрещает пополнения, выводы, обмены и переводы по кошельку. Причина обязательна. Доступно роли admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        walletID path string                     true "ID кошелька"
+// @Param        request  body models.WalletFreezeRequest true "Причина заморозки"
+// @Success      200 {object} models.AdminWallet
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/wallets/{walletID}/freeze [post]
+func (h *AdminHandler) FreezeWallet(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.FreezeWallet"
+	h.changeFreeze(w, r, op, h.service.FreezeWallet)
+}
+
+// UnfreezeWallet godoc
+// @Summary      Разморозить кошелек
+// @Description  Снимает заморозку с кошелька. Причина обязательна. Доступно роли admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        walletID path string                     true "ID кошелька"
+// @Param        request  body models.WalletFreezeRequest true "Причина разморозки"
+// @Success      200 {object} models.AdminWallet
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/wallets/{walletID}/unfreeze [post]
+func (h *AdminHandler) UnfreezeWallet(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.UnfreezeWallet"
+	h.changeFreeze(w, r, op, h.service.UnfreezeWallet)
+}
+
+type freezeFunc func(ctx context.Context, actor *models.JWTClaims, walletID uuid.UUID, req models.WalletFreezeRequest) (*models.AdminWallet, error)
+
+func (h *AdminHandler) changeFreeze(w http.ResponseWriter, r *http.Request, op string, apply freezeFunc) {
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+
+	defer r.Body.Close()
+
+	walletID, ok := parseUUIDParam(w, r, log, op, "walletID")
+	if !ok {
+		return
+	}
+
+	var req models.WalletFreezeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	result, err := apply(r.Context(), actor, walletID, req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// AdjustBalance godoc
+// @Summary      Ручная корректировка баланса
+// @Description  Зачисляет (положительная сумма) или списывает (отрицательная сумма) средства с кошелька. Причина и request_id обязательны. Доступно роли admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        walletID path string                          true "ID кошелька"
+// @Param        request  body models.BalanceAdjustmentRequest true "Сумма и причина корректировки"
+// @Success      200 {object} models.BalanceAdjustmentResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/wallets/{walletID}/adjustments [post]
+func (h *AdminHandler) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.AdjustBalance"
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+
+	defer r.Body.Close()
+
+	walletID, ok := parseUUIDParam(w, r, log, op, "walletID")
+	if !ok {
+		return
+	}
+
+	var req models.BalanceAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	result, err := h.service.AdjustBalance(r.Context(), actor, walletID, req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// SetUserRole godoc
+// @Summary      Сменить роль пользователя
+// @Description  Назначает пользователю роль user, support или admin. Новая роль попадает в токены, выданные после смены. Доступно роли admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        userID  path string                true "ID пользователя"
+// @Param        request body models.SetRoleRequest true "Роль и причина"
+// @Success      200 {object} models.AdminUser
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/users/{userID}/role [put]
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.SetUserRole"
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+
+	defer r.Body.Close()
+
+	userID, ok := parseUUIDParam(w, r, log, op, "userID")
+	if !ok {
+		return
+	}
+
+	var req models.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	result, err := h.service.SetUserRole(r.Context(), actor, userID, req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// UnlockUser godoc
+// @Summary      Снять блокировку входа
+// @Description  Снимает временную блокировку учетной записи после серии неверных паролей и сбрасывает счетчик неверных попыток по имени пользователя. Ограничения по адресу клиента не снимаются. Доступно роли admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        userID  path string                   true "ID пользователя"
+// @Param        request body models.UnlockUserRequest true "Причина"
+// @Success      200 {object} models.AdminUser
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/users/{userID}/unlock [post]
+func (h *AdminHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.UnlockUser"
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+
+	defer r.Body.Close()
+
+	userID, ok := parseUUIDParam(w, r, log, op, "userID")
+	if !ok {
+		return
+	}
+
+	var req models.UnlockUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	result, err := h.service.UnlockUser(r.Context(), actor, userID, req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// GetUserLimits godoc
+// @Summary      Лимиты пользователя
+// @Description  Возвращает действующие лимиты операций пользователя: по умолчанию и переопределенные. Доступно ролям support и admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        userID path string true "ID пользователя"
+// @Success      200 {object} models.UserLimitsResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/users/{userID}/limits [get]
+func (h *AdminHandler) GetUserLimits(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.GetUserLimits"
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+
+	userID, ok := parseUUIDParam(w, r, log, op, "userID")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.GetUserLimits(r.Context(), actor, userID)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// SetUserLimit godoc
+// @Summary      Переопределить лимит пользователя
+// @Description  Задает пользователю лимит операции (amount), снимает его (unlimited) или возвращает лимит по умолчанию (без amount и unlimited). Доступно роли admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        userID  path string                     true "ID пользователя"
+// @Param        request body models.SetUserLimitRequest true "Лимит и причина"
+// @Success      200 {object} models.UserLimitsResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/users/{userID}/limits [put]
+func (h *AdminHandler) SetUserLimit(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.SetUserLimit"
+	log := middlew.GetLogger(r.Context())
+	actor := middlew.GetClaims(r.Context())
+
+	defer r.Body.Close()
+
+	userID, ok := parseUUIDParam(w, r, log, op, "userID")
+	if !ok {
+		return
+	}
+
+	var req models.SetUserLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	result, err := h.service.SetUserLimit(r.Context(), actor, userID, req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+func (h *AdminHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrNotFound):
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Resource not found")
+	case errors.Is(err, custom_err.ErrForbidden):
+		response.WriteJSONError(w, log, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, custom_err.ErrWalletFrozen):
+		response.WriteJSONError(w, log, http.StatusConflict, "wallet_frozen", "Wallet is already frozen")
+	case errors.Is(err, custom_err.ErrWalletNotFrozen):
+		response.WriteJSONError(w, log, http.StatusConflict, "wallet_not_frozen", "Wallet is not frozen")
+	case errors.Is(err, custom_err.ErrDuplicateRequest):
+		response.WriteJSONError(w, log, http.StatusConflict, "duplicate_request",
+			"Operation with this requestID already processed")
+	case errors.Is(err, custom_err.ErrInsufficientFunds):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "insufficient_funds", "Adjustment would make the balance negative")
+	case errors.Is(err, custom_err.ErrInvalidCurrency):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currency")
+	case errors.Is(err, custom_err.ErrAmountPrecision):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount_precision",
+			"Amount has more decimal places than the currency allows")
+	case errors.Is(err, custom_err.ErrInvalidAmount):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Amount must not be zero")
+	case errors.Is(err, custom_err.ErrInvalidCursor):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_cursor", "Invalid pagination cursor")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		log.Warn("invalid input", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	default:
+		log.Error("admin operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
+
+func parseUUIDParam(w http.ResponseWriter, r *http.Request, log *slog.Logger, op, name string) (uuid.UUID, bool) {
+	raw := chi.URLParam(r, name)
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		log.Warn("invalid UUID", slog.String("op", op), slog.String(name, raw))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_request", "Invalid "+name+" format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
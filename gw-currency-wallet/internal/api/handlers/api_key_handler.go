@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	service service.APIKeys
+}
+
+func NewAPIKeyHandler(service service.APIKeys) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary      Создать API ключ
+// @Description  Выпускает персональный API ключ с указанными разрешениями. Ключ возвращается только в этом ответе, сервер хранит лишь его хэш. Ключ передается в заголовке Authorization: Bearer gwk_... и принимается только маршрутами, для которых у него есть разрешение
+// @Tags         api-keys
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateAPIKeyRequest true "Название, разрешения, разрешенные адреса и срок действия"
+// @Success      201 {object} models.CreateAPIKeyResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CreateAPIKey"
+	log := middlew.GetLogger(r.Context())
+
+	defer r.Body.Close()
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	resp, err := h.service.Create(r.Context(), middlew.GetUserID(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusCreated, resp)
+}
+
+// ListAPIKeys godoc
+// @Summary      Список API ключей
+// @Description  Ключи пользователя, включая отозванные и истекшие, новые первыми. Сами ключи не возвращаются
+// @Tags         api-keys
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200 {object} models.APIKeyListResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListAPIKeys"
+	log := middlew.GetLogger(r.Context())
+
+	resp, err := h.service.List(r.Context(), middlew.GetUserID(r.Context()))
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Отозвать API ключ
+// @Description  Отзывает ключ; запросы с ним сразу отклоняются. Повторный отзыв возвращает тот же ключ
+// @Tags         api-keys
+// @Security     BearerAuth
+// @Produce      json
+// @Param        keyID path string true "ID ключа"
+// @Success      200 {object} models.APIKeyResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /api-keys/{keyID} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.RevokeAPIKey"
+	log := middlew.GetLogger(r.Context())
+
+	idStr := chi.URLParam(r, "keyID")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		log.Warn("invalid UUID", slog.String("op", op), slog.String("uuid", idStr))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_request", "Invalid API key ID format")
+		return
+	}
+
+	resp, err := h.service.Revoke(r.Context(), middlew.GetUserID(r.Context()), id)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+func (h *APIKeyHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	case errors.Is(err, custom_err.ErrAPIKeyLimitReached):
+		response.WriteJSONError(w, log, http.StatusConflict, "api_key_limit_reached", "Maximum number of active API keys reached")
+	case errors.Is(err, custom_err.ErrNotFound):
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "API key not found")
+	default:
+		log.Error("api key operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+	"net/netip"
+
+	"github.com/google/uuid"
+)
+
+type AuditEventHandler struct {
+	service service.AuditEvents
+}
+
+func NewAuditEventHandler(service service.AuditEvents) *AuditEventHandler {
+	return &AuditEventHandler{
+		service: service,
+	}
+}
+
+// ListSecurityEvents godoc
+// @Summary      События безопасности учетной записи
+// @Description  Возвращает входы, неудачные попытки входа, выпуск токенов, смену пароля и другие события безопасности текущего пользователя, новые первыми. Доступно только по JWT
+// @Tags         auth
+// @Security     BearerAuth
+// @Produce      json
+// @Param        limit  query int    false "Размер страницы (1-100, по умолчанию 20)"
+// @Param        cursor query string false "Курсор следующей страницы из next_cursor"
+// @Success      200 {object} models.AuditEventListResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /security/events [get]
+func (h *AuditEventHandler) ListSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListSecurityEvents"
+	log := middlew.GetLogger(r.Context())
+
+	limit, ok := parseLimitParam(w, r, log, op)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ListOwn(r.Context(), middlew.GetUserID(r.Context()), models.AuditEventListRequest{
+		Cursor: r.URL.Query().Get("cursor"),
+		Limit:  limit,
+	})
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// SearchAuditEvents godoc
+// @Summary      Поиск по журналу событий безопасности
+// @Description  Ищет события безопасности всех пользователей по учетной записи, типу, адресу и периоду, новые первыми. Вместе с событием возвращается его место в цепочке хэшей. Доступно ролям support и admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        user_id query string false "ID пользователя"
+// @Param        type    query string false "Тип события (например, login.failed)"
+// @Param        ip      query string false "Адрес клиента"
+// @Param        from    query string false "Начало периода (RFC3339, включительно)"
+// @Param        to      query string false "Конец периода (RFC3339, не включительно)"
+// @Param        limit   query int    false "Размер страницы (1-100, по умолчанию 20)"
+// @Param        cursor  query string false "Курсор следующей страницы из next_cursor"
+// @Success      200 {object} models.AdminAuditEventListResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/audit-events [get]
+func (h *AuditEventHandler) SearchAuditEvents(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.SearchAuditEvents"
+	log := middlew.GetLogger(r.Context())
+	query := r.URL.Query()
+
+	limit, ok := parseLimitParam(w, r, log, op)
+	if !ok {
+		return
+	}
+
+	req := models.AuditEventSearchRequest{
+		Type:   models.AuditEventType(query.Get("type")),
+		Cursor: query.Get("cursor"),
+		Limit:  limit,
+	}
+	if v := query.Get("user_id"); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			log.Warn("invalid query parameter", slog.String("op", op), slog.String("user_id", v))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", "user_id must be a UUID")
+			return
+		}
+		req.UserID = &userID
+	}
+	if v := query.Get("ip"); v != "" {
+		ip, err := netip.ParseAddr(v)
+		if err != nil {
+			log.Warn("invalid query parameter", slog.String("op", op), slog.String("ip", v))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", "ip must be an IP address")
+			return
+		}
+		ip = ip.Unmap()
+		req.IP = &ip
+	}
+	var err error
+	if req.From, err = parseTimeParam(query.Get("from")); err != nil {
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", "from must be an RFC3339 timestamp")
+		return
+	}
+	if req.To, err = parseTimeParam(query.Get("to")); err != nil {
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", "to must be an RFC3339 timestamp")
+		return
+	}
+
+	result, err := h.service.Search(r.Context(), middlew.GetClaims(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// VerifyAuditChain godoc
+// @Summary      Проверка целостности журнала событий безопасности
+// @Description  Пересчитывает цепочку хэшей всего журнала и сообщает первую измененную или выпавшую запись. Хэш последней записи (head_hash) стоит сохранять вне сервиса, чтобы обнаружить удаление записей с конца. Доступно роли admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200 {object} models.AuditChainReport
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/audit-events/verify [get]
+func (h *AuditEventHandler) VerifyAuditChain(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.VerifyAuditChain"
+	log := middlew.GetLogger(r.Context())
+
+	report, err := h.service.VerifyChain(r.Context(), middlew.GetClaims(r.Context()))
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, report)
+}
+
+func (h *AuditEventHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrInvalidCursor):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_cursor", "Invalid pagination cursor")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		log.Warn("invalid input", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	default:
+		log.Error("audit event operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
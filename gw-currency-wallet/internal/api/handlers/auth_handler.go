@@ -8,8 +8,11 @@ import (
 	"gw-currency-wallet/internal/models"
 	"gw-currency-wallet/internal/service"
 	"gw-currency-wallet/pkg/response"
+	"io"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 )
 
 type AuthHandler struct {
@@ -66,13 +69,14 @@ func (h *AuthHandler) handleRegisterError(w http.ResponseWriter, log *slog.Logge
 
 // Login godoc
 // @Summary      Авторизация пользователя
-// @Description  Авторизует пользователя и возвращает JWT токен
+// @Description  Авторизует пользователя и возвращает JWT токен. После серии неверных паролей по имени пользователя или с одного адреса вход временно недоступен: ответ 429 с заголовком Retry-After
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        request body models.LoginRequest true "Данные входа"
 // @Success      200 {object} models.LoginResponse
 // @Failure      401 {object} response.ErrorResponse
+// @Failure      429 {object} response.ErrorResponse
 // @Failure      500 {object} response.ErrorResponse
 // @Router       /login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -97,11 +101,17 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req.IP = middlew.ClientIP(r)
 	log.Info("user login attempt", slog.String("op", op), slog.String("username", req.Username))
 
 	resp, err := h.service.Login(r.Context(), req)
 	if err != nil {
+		var throttled *models.LoginThrottledError
 		switch {
+		case errors.As(err, &throttled):
+			log.Warn("login throttled", slog.String("op", op), slog.String("username", req.Username))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			response.WriteJSONError(w, log, http.StatusTooManyRequests, "too_many_attempts", "Too many failed login attempts, try again later")
 		case errors.Is(err, custom_err.ErrInvalidCredentials):
 			log.Info("invalid credentials", slog.String("op", op), slog.String("username", req.Username))
 			response.WriteJSONError(w, log, http.StatusUnauthorized, "invalid_credentials", "Invalid username or password")
@@ -117,3 +127,184 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
 }
+
+// LoginMFA godoc
+// @Summary      Второй шаг входа
+// @Description  Завершает вход пользователя с включенной двухфакторной аутентификацией: принимает токен второго шага из ответа /login и код TOTP или код восстановления
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.LoginMFARequest true "Токен второго шага и код"
+// @Success      200 {object} models.LoginResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      429 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /login/mfa [post]
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.LoginMFA"
+	log := middlew.GetLogger(r.Context())
+
+	var req models.LoginMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	resp, err := h.service.LoginMFA(r.Context(), req)
+	if err != nil {
+		handleMFAError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+// StepUp godoc
+// @Summary      Подтверждение сессии вторым фактором
+// @Description  Проверяет код TOTP или код восстановления и выдает access токен, которым можно выполнять операции, требующие step-up. Refresh токен не выдается
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body models.MFACodeRequest true "Код"
+// @Success      200 {object} models.LoginResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      429 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /mfa/step-up [post]
+func (h *AuthHandler) StepUp(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.StepUp"
+	log := middlew.GetLogger(r.Context())
+
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	resp, err := h.service.StepUp(r.Context(), middlew.GetClaims(r.Context()), req)
+	if err != nil {
+		handleMFAError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+// handleMFAError ответы на ошибки проверки второго фактора
+func handleMFAError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrInvalidMFACode):
+		response.WriteJSONError(w, log, http.StatusUnauthorized, "invalid_mfa_code", "Invalid verification code")
+	case errors.Is(err, custom_err.ErrMFALocked):
+		response.WriteJSONError(w, log, http.StatusTooManyRequests, "mfa_locked", "Too many invalid codes, try again later")
+	case errors.Is(err, custom_err.ErrMFANotEnabled):
+		response.WriteJSONError(w, log, http.StatusConflict, "mfa_not_enabled", "Two-factor authentication is not enabled")
+	case errors.Is(err, custom_err.ErrInvalidToken):
+		response.WriteJSONError(w, log, http.StatusUnauthorized, "invalid_token", "Invalid or expired MFA token")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	default:
+		log.Error("failed to verify second factor", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
+
+// Refresh godoc
+// @Summary      Обновление токенов
+// @Description  Обменивает refresh токен на новую пару access и refresh токенов. Предъявленный refresh токен становится недействительным
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.RefreshRequest true "Refresh токен"
+// @Success      200 {object} models.LoginResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /token/refresh [post]
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.Refresh"
+	log := middlew.GetLogger(r.Context())
+
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	if req.RefreshToken == "" {
+		log.Warn("refresh_token is required", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", "refresh_token is required")
+		return
+	}
+
+	resp, err := h.service.Refresh(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, custom_err.ErrRefreshTokenReused):
+			log.Warn("refresh token reuse detected", slog.String("op", op))
+			response.WriteJSONError(w, log, http.StatusUnauthorized, "token_reused", "Refresh token has already been used, all sessions of this login are revoked")
+		case errors.Is(err, custom_err.ErrTokenExpired):
+			response.WriteJSONError(w, log, http.StatusUnauthorized, "token_expired", "Refresh token has expired")
+		case errors.Is(err, custom_err.ErrTokenRevoked):
+			response.WriteJSONError(w, log, http.StatusUnauthorized, "token_revoked", "Refresh token has been revoked")
+		case errors.Is(err, custom_err.ErrInvalidToken):
+			response.WriteJSONError(w, log, http.StatusUnauthorized, "invalid_token", "Invalid refresh token")
+		case errors.Is(err, custom_err.ErrInvalidInput):
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", "Invalid input data")
+		default:
+			log.Error("failed to refresh token", slog.String("op", op), slog.String("error", err.Error()))
+			response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+		}
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+// Logout godoc
+// @Summary      Выход
+// @Description  Отзывает текущий access токен. Если передан refresh токен, отзывается он и все токены, полученные из него обновлением
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body models.LogoutRequest false "Refresh токен"
+// @Success      200 {object} models.LogoutResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.Logout"
+	log := middlew.GetLogger(r.Context())
+	claims := middlew.GetClaims(r.Context())
+
+	var req models.LogoutRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+			return
+		}
+	}
+
+	if err := h.service.Logout(r.Context(), claims, req); err != nil {
+		switch {
+		case errors.Is(err, custom_err.ErrInvalidToken):
+			response.WriteJSONError(w, log, http.StatusUnauthorized, "invalid_token", "Invalid token")
+		default:
+			log.Error("failed to logout", slog.String("op", op), slog.String("error", err.Error()))
+			response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+		}
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, models.LogoutResponse{Message: "Logged out successfully"})
+}
@@ -50,17 +50,57 @@ func (h *ExchangeHandler) GetExchangeRates(w http.ResponseWriter, r *http.Reques
 	response.WriteJSONSuccess(w, log, http.StatusOK, responseData)
 }
 
+// QuoteExchange godoc
+// @Summary      Получить котировку обмена
+// @Description  Фиксирует курс и суммы обмена на короткое время. Котировку можно один раз использовать в POST /exchange через quote_id.
+// @Tags         exchange
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.ExchangeQuoteRequest true "Параметры обмена"
+// @Success      200 {object} models.ExchangeQuoteResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Router       /exchange/quote [post]
+func (h *ExchangeHandler) QuoteExchange(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.QuoteExchange"
+	log := middlew.GetLogger(r.Context())
+
+	defer r.Body.Close()
+
+	userID := middlew.GetUserID(r.Context())
+
+	var req models.ExchangeQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	quote, err := h.service.QuoteExchange(r.Context(), userID, req)
+	if err != nil {
+		writeExchangeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, quote)
+}
+
 // ExchangeCurrency godoc
 // @Summary      Обменять валюту
-// @Description  Выполняет обмен одной валюты на другую по текущему курсу
+// @Description  Выполняет обмен одной валюты на другую по текущему курсу или по котировке quote_id
 // @Tags         exchange
 // @Security     BearerAuth
 // @Accept       json
 // @Produce      json
 // @Param        request body models.ExchangeRequest true "Данные обмена"
+// @Param        Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом возвращает сохраненный ответ"
 // @Success      200 {object} models.ExchangeResponse
 // @Failure      400 {object} response.ErrorResponse
 // @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
 // @Router       /exchange [post]
 func (h *ExchangeHandler) ExchangeCurrency(w http.ResponseWriter, r *http.Request) {
 	const op = "handler.ExchangeCurrency"
@@ -82,29 +122,55 @@ func (h *ExchangeHandler) ExchangeCurrency(w http.ResponseWriter, r *http.Reques
 		slog.String("user_id", userID.String()),
 		slog.String("from", string(req.FromCurrency)),
 		slog.String("to", string(req.ToCurrency)),
-		slog.Float64("amount", req.Amount))
+		slog.String("amount", req.Amount.String()))
 
 	result, err := h.service.ExchangeCurrency(r.Context(), userID, req)
 	if err != nil {
-		switch {
-		case errors.Is(err, custom_err.ErrNotFound):
-			log.Info("wallet not found", slog.String("op", op))
-			response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Wallet not found")
-		case errors.Is(err, custom_err.ErrInsufficientFunds):
-			log.Warn("insufficient funds", slog.String("op", op))
-			response.WriteJSONError(w, log, http.StatusBadRequest, "insufficient_funds", "Insufficient funds for exchange")
-		case errors.Is(err, custom_err.ErrInvalidCurrency):
-			log.Warn("invalid currency", slog.String("op", op))
-			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currencies")
-		case errors.Is(err, custom_err.ErrInvalidAmount):
-			log.Warn("invalid amount", slog.String("op", op))
-			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Invalid amount")
-		default:
-			log.Error("failed to exchange currency", slog.String("op", op), slog.String("error", err.Error()))
-			response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
-		}
+		writeExchangeError(w, log, op, err)
 		return
 	}
 
 	response.WriteJSONSuccess(w, log, http.StatusOK, result)
 }
+
+func writeExchangeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrQuoteNotFound):
+		log.Info("quote not found", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusNotFound, "quote_not_found", "Quote not found")
+	case errors.Is(err, custom_err.ErrQuoteExpired):
+		log.Info("quote expired", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusConflict, "quote_expired", "Quote has expired or was already used")
+	case errors.Is(err, custom_err.ErrNotFound):
+		log.Info("wallet not found", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Wallet not found")
+	case errors.Is(err, custom_err.ErrWalletFrozen):
+		log.Warn("wallet is frozen", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusForbidden, "wallet_frozen", "Wallet is frozen")
+	case errors.Is(err, custom_err.ErrDuplicateRequest):
+		log.Info("operation already processed", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusConflict, "duplicate_request",
+			"Operation with this requestID already processed")
+	case errors.Is(err, custom_err.ErrInsufficientFunds):
+		log.Warn("insufficient funds", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "insufficient_funds", "Insufficient funds for exchange")
+	case errors.Is(err, custom_err.ErrLimitExceeded):
+		writeLimitExceeded(w, log, op, err)
+	case errors.Is(err, custom_err.ErrInvalidCurrency):
+		log.Warn("invalid currency", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currencies")
+	case errors.Is(err, custom_err.ErrAmountPrecision):
+		log.Warn("invalid amount precision", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount_precision",
+			"Amount has more decimal places than the currency allows")
+	case errors.Is(err, custom_err.ErrInvalidAmount):
+		log.Warn("invalid amount", slog.String("op", op))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Invalid amount")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		log.Warn("invalid input", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	default:
+		log.Error("failed to exchange currency", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type HoldHandler struct {
+	service service.Holds
+}
+
+func NewHoldHandler(service service.Holds) *HoldHandler {
+	return &HoldHandler{
+		service: service,
+	}
+}
+
+// CreateHold godoc
+// @Summary      Зарезервировать средства
+// @Description  Создает холд на кошельке в указанной валюте. Сумма холда недоступна для выводов, обменов и переводов, пока холд не списан, не освобожден или не истек
+// @Tags         holds
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateHoldRequest true "Данные холда"
+// @Param        Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом возвращает сохраненный ответ"
+// @Success      201 {object} models.HoldResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /holds [post]
+func (h *HoldHandler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CreateHold"
+	log := middlew.GetLogger(r.Context())
+
+	defer r.Body.Close()
+
+	var req models.CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	hold, err := h.service.CreateHold(r.Context(), middlew.GetUserID(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusCreated, hold)
+}
+
+// GetHold godoc
+// @Summary      Получить холд
+// @Tags         holds
+// @Security     BearerAuth
+// @Produce      json
+// @Param        holdID path string true "ID холда"
+// @Success      200 {object} models.HoldResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /holds/{holdID} [get]
+func (h *HoldHandler) GetHold(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.GetHold"
+	log := middlew.GetLogger(r.Context())
+
+	id, ok := parseHoldID(w, r, log, op)
+	if !ok {
+		return
+	}
+
+	hold, err := h.service.GetHold(r.Context(), middlew.GetClaims(r.Context()), id)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, hold)
+}
+
+// CaptureHold godoc
+// @Summary      Списать средства по холду
+// @Description  Списывает всю сумму холда или ее часть (amount). Остаток холда освобождается, повторное списание невозможно
+// @Tags         holds
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        holdID path string true "ID холда"
+// @Param        request body models.CaptureHoldRequest false "Сумма списания"
+// @Param        Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом возвращает сохраненный ответ"
+// @Success      200 {object} models.HoldResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /holds/{holdID}/capture [post]
+func (h *HoldHandler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CaptureHold"
+	log := middlew.GetLogger(r.Context())
+
+	defer r.Body.Close()
+
+	id, ok := parseHoldID(w, r, log, op)
+	if !ok {
+		return
+	}
+
+	// Тело необязательно: без него списывается весь холд
+	var req models.CaptureHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	hold, err := h.service.CaptureHold(r.Context(), middlew.GetClaims(r.Context()), id, req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, hold)
+}
+
+// ReleaseHold godoc
+// @Summary      Освободить холд
+// @Description  Снимает резерв без списания средств
+// @Tags         holds
+// @Security     BearerAuth
+// @Produce      json
+// @Param        holdID path string true "ID холда"
+// @Success      200 {object} models.HoldResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /holds/{holdID}/release [post]
+func (h *HoldHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ReleaseHold"
+	log := middlew.GetLogger(r.Context())
+
+	id, ok := parseHoldID(w, r, log, op)
+	if !ok {
+		return
+	}
+
+	hold, err := h.service.ReleaseHold(r.Context(), middlew.GetClaims(r.Context()), id)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, hold)
+}
+
+func parseHoldID(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string) (uuid.UUID, bool) {
+	idStr := chi.URLParam(r, "holdID")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		log.Warn("invalid UUID", slog.String("op", op), slog.String("uuid", idStr))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_request", "Invalid hold ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *HoldHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrNotFound):
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Hold or wallet not found")
+	case errors.Is(err, custom_err.ErrHoldNotActive):
+		response.WriteJSONError(w, log, http.StatusConflict, "hold_not_active", "Hold is already captured, released or expired")
+	case errors.Is(err, custom_err.ErrWalletFrozen):
+		response.WriteJSONError(w, log, http.StatusForbidden, "wallet_frozen", "Wallet is frozen")
+	case errors.Is(err, custom_err.ErrInsufficientFunds):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "insufficient_funds", "Insufficient available funds for the hold")
+	case errors.Is(err, custom_err.ErrLimitExceeded):
+		writeLimitExceeded(w, log, op, err)
+	case errors.Is(err, custom_err.ErrInvalidCurrency):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currency")
+	case errors.Is(err, custom_err.ErrAmountPrecision):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount_precision",
+			"Amount has more decimal places than the currency allows")
+	case errors.Is(err, custom_err.ErrInvalidAmount):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Amount must be positive")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	case errors.Is(err, custom_err.ErrDuplicateRequest):
+		response.WriteJSONError(w, log, http.StatusConflict, "duplicate_request",
+			"Hold with this requestID already exists")
+	default:
+		log.Error("hold operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fmt"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/jwtkeys"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+)
+
+type JWKSHandler struct {
+	keys service.SigningKeys
+}
+
+func NewJWKSHandler(keys service.SigningKeys) *JWKSHandler {
+	return &JWKSHandler{
+		keys: keys,
+	}
+}
+
+// GetJWKS godoc
+// @Summary      Открытые ключи подписи JWT
+// @Description  Возвращает JWK Set (RFC 7517) с открытыми ключами, которыми можно проверить токены кошелька по kid из заголовка
+// @Tags         auth
+// @Produce      json
+// @Success      200 {object} jwtkeys.JWKSet
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /.well-known/jwks.json [get]
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.GetJWKS"
+	log := middlew.GetLogger(r.Context())
+
+	set, err := h.keys.JWKS(r.Context())
+	if err != nil {
+		log.Error("failed to get JWKS", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwtkeys.JWKSCacheMaxAge.Seconds())))
+	response.WriteJSONSuccess(w, log, http.StatusOK, set)
+}
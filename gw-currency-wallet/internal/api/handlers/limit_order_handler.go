@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type LimitOrderHandler struct {
+	service service.LimitOrders
+}
+
+func NewLimitOrderHandler(service service.LimitOrders) *LimitOrderHandler {
+	return &LimitOrderHandler{
+		service: service,
+	}
+}
+
+// CreateLimitOrder godoc
+// @Summary      Создать лимитный ордер
+// @Description  Резервирует сумму на кошельке-источнике. Обмен выполняется автоматически, когда курс для клиента (с учетом спреда) достигнет rate. Без expires_at ордер действует до отмены
+// @Tags         exchange
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateLimitOrderRequest true "Параметры ордера"
+// @Param        Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом возвращает сохраненный ответ"
+// @Success      201 {object} models.LimitOrderResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /exchange/orders [post]
+func (h *LimitOrderHandler) CreateLimitOrder(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CreateLimitOrder"
+	log := middlew.GetLogger(r.Context())
+
+	defer r.Body.Close()
+
+	var req models.CreateLimitOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	order, err := h.service.CreateOrder(r.Context(), middlew.GetUserID(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusCreated, order)
+}
+
+// ListLimitOrders godoc
+// @Summary      Список лимитных ордеров
+// @Description  Ордера пользователя, новые первыми
+// @Tags         exchange
+// @Security     BearerAuth
+// @Produce      json
+// @Param        status query string false "Фильтр по статусу: OPEN, FILLED, CANCELLED, EXPIRED"
+// @Param        limit query int false "Количество записей (1-100, по умолчанию 20)"
+// @Success      200 {object} models.LimitOrderListResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /exchange/orders [get]
+func (h *LimitOrderHandler) ListLimitOrders(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListLimitOrders"
+	log := middlew.GetLogger(r.Context())
+
+	query := r.URL.Query()
+	req := models.LimitOrderListRequest{
+		Status: models.LimitOrderStatus(strings.ToUpper(query.Get("status"))),
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			log.Warn("invalid query parameter", slog.String("op", op), slog.String("limit", v))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", "limit must be an integer")
+			return
+		}
+		req.Limit = limit
+	}
+
+	orders, err := h.service.ListOrders(r.Context(), middlew.GetUserID(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, orders)
+}
+
+// CancelLimitOrder godoc
+// @Summary      Отменить лимитный ордер
+// @Description  Отменяет открытый ордер и освобождает зарезервированную сумму
+// @Tags         exchange
+// @Security     BearerAuth
+// @Produce      json
+// @Param        orderID path string true "ID ордера"
+// @Success      200 {object} models.LimitOrderResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /exchange/orders/{orderID}/cancel [post]
+func (h *LimitOrderHandler) CancelLimitOrder(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CancelLimitOrder"
+	log := middlew.GetLogger(r.Context())
+
+	idStr := chi.URLParam(r, "orderID")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		log.Warn("invalid UUID", slog.String("op", op), slog.String("uuid", idStr))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_request", "Invalid order ID format")
+		return
+	}
+
+	order, err := h.service.CancelOrder(r.Context(), middlew.GetClaims(r.Context()), id)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, order)
+}
+
+func (h *LimitOrderHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrNotFound):
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Order or wallet not found")
+	case errors.Is(err, custom_err.ErrOrderNotOpen):
+		response.WriteJSONError(w, log, http.StatusConflict, "order_not_open", "Order is already filled, cancelled or expired")
+	case errors.Is(err, custom_err.ErrWalletFrozen):
+		response.WriteJSONError(w, log, http.StatusForbidden, "wallet_frozen", "Wallet is frozen")
+	case errors.Is(err, custom_err.ErrInsufficientFunds):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "insufficient_funds", "Insufficient available funds for the order")
+	case errors.Is(err, custom_err.ErrInvalidCurrency):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currencies")
+	case errors.Is(err, custom_err.ErrAmountPrecision):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount_precision",
+			"Amount has more decimal places than the currency allows")
+	case errors.Is(err, custom_err.ErrInvalidAmount):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Amount must be positive")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	case errors.Is(err, custom_err.ErrDuplicateRequest):
+		response.WriteJSONError(w, log, http.StatusConflict, "duplicate_request",
+			"Order with this requestID already exists")
+	default:
+		log.Error("limit order operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
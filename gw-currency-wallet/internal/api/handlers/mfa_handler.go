@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+)
+
+type MFAHandler struct {
+	service service.MFA
+}
+
+func NewMFAHandler(service service.MFA) *MFAHandler {
+	return &MFAHandler{
+		service: service,
+	}
+}
+
+// Status godoc
+// @Summary      Состояние двухфакторной аутентификации
+// @Description  Показывает, включен ли TOTP, и сколько кодов восстановления осталось
+// @Tags         mfa
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} models.MFAStatusResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /mfa [get]
+func (h *MFAHandler) Status(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.MFAStatus"
+	log := middlew.GetLogger(r.Context())
+
+	resp, err := h.service.Status(r.Context(), middlew.GetUserID(r.Context()))
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+// EnrollTOTP godoc
+// @Summary      Подключение TOTP
+// @Description  Проверяет пароль и выдает секрет TOTP и ссылку otpauth:// для QR кода. TOTP включается после подтверждения первым кодом из приложения. Повторный вызов до подтверждения заменяет секрет
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body models.TOTPEnrollRequest true "Текущий пароль"
+// @Success      200 {object} models.TOTPEnrollResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /mfa/totp/enroll [post]
+func (h *MFAHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.EnrollTOTP"
+	log := middlew.GetLogger(r.Context())
+
+	var req models.TOTPEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	resp, err := h.service.EnrollTOTP(r.Context(), middlew.GetUserID(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+// ConfirmTOTP godoc
+// @Summary      Подтверждение подключения TOTP
+// @Description  Включает TOTP по коду из приложения и возвращает коды восстановления. Коды показываются один раз
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body models.MFACodeRequest true "Код из приложения"
+// @Success      200 {object} models.RecoveryCodesResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ConfirmTOTP"
+	log := middlew.GetLogger(r.Context())
+
+	var req models.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON body", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	resp, err := h.service.ConfirmTOTP(r.Context(), middlew.GetUserID(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+// DisableTOTP godoc
+// @Summary      Отключение TOTP
+// @Description  Отключает двухфакторную аутентификацию и удаляет коды восстановления. Требует токена, подтвержденного вторым фактором через /mfa/step-up
+// @Tags         mfa
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} models.AccountActionResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /mfa/totp/disable [post]
+func (h *MFAHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.DisableTOTP"
+	log := middlew.GetLogger(r.Context())
+
+	if err := h.service.DisableTOTP(r.Context(), middlew.GetUserID(r.Context())); err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, models.AccountActionResponse{Message: "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Новые коды восстановления
+// @Description  Заменяет все коды восстановления новыми. Требует токена, подтвержденного вторым фактором через /mfa/step-up
+// @Tags         mfa
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} models.RecoveryCodesResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.RegenerateRecoveryCodes"
+	log := middlew.GetLogger(r.Context())
+
+	resp, err := h.service.RegenerateRecoveryCodes(r.Context(), middlew.GetUserID(r.Context()))
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, resp)
+}
+
+func (h *MFAHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrInvalidCredentials):
+		response.WriteJSONError(w, log, http.StatusForbidden, "invalid_password", "Invalid password")
+	case errors.Is(err, custom_err.ErrInvalidMFACode):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_mfa_code", "Invalid verification code")
+	case errors.Is(err, custom_err.ErrMFAAlreadyEnabled):
+		response.WriteJSONError(w, log, http.StatusConflict, "mfa_already_enabled", "Two-factor authentication is already enabled")
+	case errors.Is(err, custom_err.ErrMFANotEnabled):
+		response.WriteJSONError(w, log, http.StatusConflict, "mfa_not_enabled", "Two-factor authentication is not enabled")
+	case errors.Is(err, custom_err.ErrMFANotEnrolled):
+		response.WriteJSONError(w, log, http.StatusConflict, "mfa_not_enrolled", "TOTP enrollment has not been started")
+	case errors.Is(err, custom_err.ErrNotFound):
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "User not found")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	default:
+		log.Error("mfa operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
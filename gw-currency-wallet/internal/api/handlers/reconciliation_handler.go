@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+)
+
+// reconciliationCSVHeader колонки выгрузки расхождений
+var reconciliationCSVHeader = []string{
+	"wallet_id", "user_id", "currency",
+	"balance", "ledger_balance", "balance_diff",
+	"held_balance", "reserved", "held_diff",
+}
+
+type ReconciliationHandler struct {
+	service service.Reconciliation
+}
+
+func NewReconciliationHandler(service service.Reconciliation) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		service: service,
+	}
+}
+
+// StartReconciliation godoc
+// @Summary      Запуск сверки балансов
+// @Description  Запускает в фоне сверку балансов всех кошельков с главной книгой, холдами и лимитными ордерами. Ход и итог доступны по ID прогона. Доступно роли admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      202 {object} models.ReconciliationReportResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/reconciliation/runs [post]
+func (h *ReconciliationHandler) StartReconciliation(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.StartReconciliation"
+	log := middlew.GetLogger(r.Context())
+
+	report, err := h.service.StartRun(r.Context(), middlew.GetClaims(r.Context()))
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusAccepted, report)
+}
+
+// ListReconciliationReports godoc
+// @Summary      Прогоны сверки балансов
+// @Description  Возвращает последние прогоны сверки, новые первыми. Доступно ролям support и admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        limit query int false "Количество прогонов (1-100, по умолчанию 20)"
+// @Success      200 {object} models.ReconciliationReportListResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/reconciliation/reports [get]
+func (h *ReconciliationHandler) ListReconciliationReports(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListReconciliationReports"
+	log := middlew.GetLogger(r.Context())
+
+	limit, ok := parseLimitParam(w, r, log, op)
+	if !ok {
+		return
+	}
+
+	reports, err := h.service.ListReports(r.Context(), middlew.GetClaims(r.Context()), limit)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, reports)
+}
+
+// GetReconciliationReport godoc
+// @Summary      Прогон сверки балансов
+// @Description  Возвращает состояние и итог прогона сверки. Доступно ролям support и admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        reportID path string true "ID прогона"
+// @Success      200 {object} models.ReconciliationReportResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/reconciliation/reports/{reportID} [get]
+func (h *ReconciliationHandler) GetReconciliationReport(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.GetReconciliationReport"
+	log := middlew.GetLogger(r.Context())
+
+	id, ok := parseUUIDParam(w, r, log, op, "reportID")
+	if !ok {
+		return
+	}
+
+	report, err := h.service.GetReport(r.Context(), middlew.GetClaims(r.Context()), id)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, report)
+}
+
+// ExportReconciliationReport godoc
+// @Summary      Выгрузка расхождений сверки
+// @Description  Выгружает кошельки с расхождениями в CSV. Суммы в единицах валюты; *_diff - сохраненное значение минус пересчитанное. Доступно ролям support и admin
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      text/csv
+// @Param        reportID path string true "ID прогона"
+// @Success      200 {string} string "CSV с расхождениями"
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /admin/reconciliation/reports/{reportID}/export [get]
+func (h *ReconciliationHandler) ExportReconciliationReport(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ExportReconciliationReport"
+	log := middlew.GetLogger(r.Context())
+
+	id, ok := parseUUIDParam(w, r, log, op, "reportID")
+	if !ok {
+		return
+	}
+
+	// Заголовки ответа пишутся при первой строке, чтобы ошибки до начала выгрузки вернулись как JSON
+	cw := csv.NewWriter(w)
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="reconciliation-`+id.String()+`.csv"`)
+		w.WriteHeader(http.StatusOK)
+		return cw.Write(reconciliationCSVHeader)
+	}
+
+	err := h.service.ExportDiscrepancies(r.Context(), middlew.GetClaims(r.Context()), id,
+		func(row models.ReconciliationDiscrepancyRow) error {
+			if !started {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			return cw.Write([]string{
+				row.WalletID.String(), row.UserID.String(), row.Currency,
+				row.Balance.String(), row.LedgerBalance.String(), row.BalanceDiff.String(),
+				row.HeldBalance.String(), row.Reserved.String(), row.HeldDiff.String(),
+			})
+		})
+	if err != nil {
+		if !started {
+			h.writeError(w, log, op, err)
+			return
+		}
+		// Ответ уже начат, клиент получит обрезанный файл
+		log.Error("reconciliation export interrupted", slog.String("op", op), slog.String("error", err.Error()))
+		return
+	}
+	if !started {
+		if err := start(); err != nil {
+			log.Error("failed to write reconciliation export", slog.String("op", op), slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Error("failed to write reconciliation export", slog.String("op", op), slog.String("error", err.Error()))
+	}
+}
+
+func (h *ReconciliationHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrNotFound):
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Reconciliation report not found")
+	case errors.Is(err, custom_err.ErrReconciliationInProgress):
+		response.WriteJSONError(w, log, http.StatusConflict, "reconciliation_in_progress",
+			"Another reconciliation run is in progress")
+	default:
+		log.Error("reconciliation operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ScheduledExchangeHandler struct {
+	service service.ScheduledExchanges
+}
+
+func NewScheduledExchangeHandler(service service.ScheduledExchanges) *ScheduledExchangeHandler {
+	return &ScheduledExchangeHandler{
+		service: service,
+	}
+}
+
+// CreateScheduledExchange godoc
+// @Summary      Запланировать обмен
+// @Description  Разовый обмен в run_at или повторяющийся по cron-выражению в UTC (например, "0 9 * * 1" - каждый понедельник в 9:00). Средства не резервируются: обмен выполняется по курсу и балансу на момент запуска, результат каждого запуска сохраняется
+// @Tags         exchange
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateScheduledExchangeRequest true "Параметры обмена"
+// @Param        Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом возвращает сохраненный ответ"
+// @Success      201 {object} models.ScheduledExchangeResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /exchange/schedules [post]
+func (h *ScheduledExchangeHandler) CreateScheduledExchange(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CreateScheduledExchange"
+	log := middlew.GetLogger(r.Context())
+
+	defer r.Body.Close()
+
+	var req models.CreateScheduledExchangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	schedule, err := h.service.CreateSchedule(r.Context(), middlew.GetUserID(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusCreated, schedule)
+}
+
+// ListScheduledExchanges godoc
+// @Summary      Список запланированных обменов
+// @Description  Запланированные обмены пользователя, новые первыми
+// @Tags         exchange
+// @Security     BearerAuth
+// @Produce      json
+// @Param        status query string false "Фильтр по статусу: ACTIVE, COMPLETED, CANCELLED"
+// @Param        limit query int false "Количество записей (1-100, по умолчанию 20)"
+// @Success      200 {object} models.ScheduledExchangeListResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /exchange/schedules [get]
+func (h *ScheduledExchangeHandler) ListScheduledExchanges(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListScheduledExchanges"
+	log := middlew.GetLogger(r.Context())
+
+	limit, ok := parseLimitParam(w, r, log, op)
+	if !ok {
+		return
+	}
+	req := models.ScheduledExchangeListRequest{
+		Status: models.ScheduledExchangeStatus(strings.ToUpper(r.URL.Query().Get("status"))),
+		Limit:  limit,
+	}
+
+	schedules, err := h.service.ListSchedules(r.Context(), middlew.GetUserID(r.Context()), req)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, schedules)
+}
+
+// CancelScheduledExchange godoc
+// @Summary      Отменить запланированный обмен
+// @Description  Отменяет активный обмен; следующие запуски не выполняются
+// @Tags         exchange
+// @Security     BearerAuth
+// @Produce      json
+// @Param        scheduleID path string true "ID запланированного обмена"
+// @Success      200 {object} models.ScheduledExchangeResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /exchange/schedules/{scheduleID}/cancel [post]
+func (h *ScheduledExchangeHandler) CancelScheduledExchange(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.CancelScheduledExchange"
+	log := middlew.GetLogger(r.Context())
+
+	id, ok := parseScheduleID(w, r, log, op)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.service.CancelSchedule(r.Context(), middlew.GetClaims(r.Context()), id)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, schedule)
+}
+
+// ListScheduledExchangeRuns godoc
+// @Summary      Запуски запланированного обмена
+// @Description  Результаты запусков, последние первыми. Для неуспешного запуска указаны error_code (insufficient_funds, rate_unavailable, limit_exceeded и др.) и error
+// @Tags         exchange
+// @Security     BearerAuth
+// @Produce      json
+// @Param        scheduleID path string true "ID запланированного обмена"
+// @Param        limit query int false "Количество записей (1-100, по умолчанию 20)"
+// @Success      200 {object} models.ScheduledExchangeRunListResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /exchange/schedules/{scheduleID}/runs [get]
+func (h *ScheduledExchangeHandler) ListScheduledExchangeRuns(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ListScheduledExchangeRuns"
+	log := middlew.GetLogger(r.Context())
+
+	id, ok := parseScheduleID(w, r, log, op)
+	if !ok {
+		return
+	}
+	limit, ok := parseLimitParam(w, r, log, op)
+	if !ok {
+		return
+	}
+
+	runs, err := h.service.ListRuns(r.Context(), middlew.GetClaims(r.Context()), id, limit)
+	if err != nil {
+		h.writeError(w, log, op, err)
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, runs)
+}
+
+func parseScheduleID(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string) (uuid.UUID, bool) {
+	idStr := chi.URLParam(r, "scheduleID")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		log.Warn("invalid UUID", slog.String("op", op), slog.String("uuid", idStr))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_request", "Invalid schedule ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// parseLimitParam читает необязательный параметр limit; 0 - значение по умолчанию сервиса
+func parseLimitParam(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string) (int, bool) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil {
+		log.Warn("invalid query parameter", slog.String("op", op), slog.String("limit", v))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", "limit must be an integer")
+		return 0, false
+	}
+	return limit, true
+}
+
+func (h *ScheduledExchangeHandler) writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, custom_err.ErrNotFound):
+		response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Scheduled exchange or wallet not found")
+	case errors.Is(err, custom_err.ErrScheduleNotActive):
+		response.WriteJSONError(w, log, http.StatusConflict, "schedule_not_active",
+			"Scheduled exchange is already completed or cancelled")
+	case errors.Is(err, custom_err.ErrInvalidCurrency):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currencies")
+	case errors.Is(err, custom_err.ErrAmountPrecision):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount_precision",
+			"Amount has more decimal places than the currency allows")
+	case errors.Is(err, custom_err.ErrInvalidAmount):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Amount must be positive")
+	case errors.Is(err, custom_err.ErrInvalidInput):
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+	case errors.Is(err, custom_err.ErrDuplicateRequest):
+		response.WriteJSONError(w, log, http.StatusConflict, "duplicate_request",
+			"Scheduled exchange with this requestID already exists")
+	default:
+		log.Error("scheduled exchange operation failed", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"net/http"
+)
+
+type TransferHandler struct {
+	service service.Transfer
+}
+
+func NewTransferHandler(service service.Transfer) *TransferHandler {
+	return &TransferHandler{
+		service: service,
+	}
+}
+
+// Transfer godoc
+// @Summary      Перевести средства другому пользователю
+// @Description  Переводит средства на кошелек другого пользователя по username или email. Если указана to_currency, сумма конвертируется по текущему курсу
+// @Tags         transfers
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.TransferRequest true "Данные перевода"
+// @Param        Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом возвращает сохраненный ответ"
+// @Success      200 {object} models.TransferResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      404 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /transfers [post]
+func (h *TransferHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.Transfer"
+	log := middlew.GetLogger(r.Context())
+
+	defer r.Body.Close()
+
+	userID := middlew.GetUserID(r.Context())
+
+	var req models.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Warn("invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+		return
+	}
+
+	result, err := h.service.Transfer(r.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, custom_err.ErrRecipientNotFound):
+			response.WriteJSONError(w, log, http.StatusNotFound, "recipient_not_found", "Recipient not found")
+		case errors.Is(err, custom_err.ErrNotFound):
+			response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Wallet not found")
+		case errors.Is(err, custom_err.ErrWalletFrozen):
+			response.WriteJSONError(w, log, http.StatusForbidden, "wallet_frozen", "Wallet is frozen")
+		case errors.Is(err, custom_err.ErrSelfTransfer):
+			response.WriteJSONError(w, log, http.StatusBadRequest, "self_transfer", "Cannot transfer to yourself")
+		case errors.Is(err, custom_err.ErrInsufficientFunds):
+			response.WriteJSONError(w, log, http.StatusBadRequest, "insufficient_funds", "Insufficient funds for transfer")
+		case errors.Is(err, custom_err.ErrLimitExceeded):
+			writeLimitExceeded(w, log, op, err)
+		case errors.Is(err, custom_err.ErrInvalidCurrency):
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currency")
+		case errors.Is(err, custom_err.ErrAmountPrecision):
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount_precision",
+				"Amount has more decimal places than the currency allows")
+		case errors.Is(err, custom_err.ErrInvalidAmount):
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Amount must be positive")
+		case errors.Is(err, custom_err.ErrInvalidInput):
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", "Recipient and requestID are required")
+		case errors.Is(err, custom_err.ErrDuplicateRequest):
+			response.WriteJSONError(w, log, http.StatusConflict, "duplicate_request",
+				"Operation with this requestID already processed")
+		default:
+			log.Error("failed to transfer", slog.String("op", op), slog.String("error", err.Error()))
+			response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "An internal error occurred")
+		}
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
@@ -10,6 +10,10 @@ import (
 	"gw-currency-wallet/pkg/response"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -37,7 +41,7 @@ func (h *WalletHandler) GetWalletByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	wallet, err := h.service.GetWalletByID(r.Context(), id)
+	wallet, err := h.service.GetWalletByID(r.Context(), middlew.GetClaims(r.Context()), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, custom_err.ErrNotFound):
@@ -120,11 +124,12 @@ func (h *WalletHandler) GetWalletByID(w http.ResponseWriter, r *http.Request) {
 
 // GetBalance godoc
 // @Summary      Получить баланс пользователя
-// @Description  Возвращает баланс по всем валютам (USD, RUB, EUR)
+// @Description  Возвращает балансы по кодам валют: все включенные валюты реестра и валюты существующих кошельков.
+// @Description  balance - полный баланс, available - доступная сумма без зарезервированных холдами средств
 // @Tags         wallet
 // @Security     BearerAuth
 // @Produce      json
-// @Success      200 {object} models.UserBalanceResponse
+// @Success      200 {object} models.BalanceResponse
 // @Failure      401 {object} response.ErrorResponse
 // @Failure      500 {object} response.ErrorResponse
 // @Router       /balance [get]
@@ -143,9 +148,7 @@ func (h *WalletHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.WriteJSONSuccess(w, log, http.StatusOK, map[string]interface{}{
-		"balance": balances,
-	})
+	response.WriteJSONSuccess(w, log, http.StatusOK, balances)
 }
 
 // Deposit godoc
@@ -156,9 +159,12 @@ func (h *WalletHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        request body models.DepositRequest true "Данные пополнения"
+// @Param        Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом возвращает сохраненный ответ"
 // @Success      200 {object} models.BalanceOperationResponse
 // @Failure      400 {object} response.ErrorResponse
 // @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
 // @Router       /wallet/deposit [post]
 func (h *WalletHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 	const op = "handler.Deposit"
@@ -178,7 +184,7 @@ func (h *WalletHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 	log.Info("deposit request",
 		slog.String("op", op),
 		slog.String("user_id", userID.String()),
-		slog.Float64("amount", req.Amount),
+		slog.String("amount", req.Amount.String()),
 		slog.String("currency", string(req.Currency)))
 
 	result, err := h.service.Deposit(r.Context(), userID, req)
@@ -187,13 +193,22 @@ func (h *WalletHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, custom_err.ErrNotFound):
 			log.Info("wallet not found", slog.String("op", op))
 			response.WriteJSONError(w, log, http.StatusNotFound, "not_found", "Wallet not found")
+		case errors.Is(err, custom_err.ErrWalletFrozen):
+			log.Warn("wallet is frozen", slog.String("op", op))
+			response.WriteJSONError(w, log, http.StatusForbidden, "wallet_frozen", "Wallet is frozen")
 		case errors.Is(err, custom_err.ErrDuplicateRequest):
 			response.WriteJSONError(w, log, http.StatusConflict, "duplicate_request",
 				"Operation with this requestID already processed")
 			return
+		case errors.Is(err, custom_err.ErrLimitExceeded):
+			writeLimitExceeded(w, log, op, err)
 		case errors.Is(err, custom_err.ErrInvalidCurrency):
 			log.Warn("invalid currency", slog.String("op", op))
 			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currency")
+		case errors.Is(err, custom_err.ErrAmountPrecision):
+			log.Warn("invalid amount precision", slog.String("op", op))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount_precision",
+				"Amount has more decimal places than the currency allows")
 		case errors.Is(err, custom_err.ErrInvalidAmount):
 			log.Warn("invalid amount", slog.String("op", op))
 			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Invalid amount")
@@ -215,9 +230,12 @@ func (h *WalletHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        request body models.WithdrawRequest true "Данные вывода"
+// @Param        Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом возвращает сохраненный ответ"
 // @Success      200 {object} models.BalanceOperationResponse
 // @Failure      400 {object} response.ErrorResponse
 // @Failure      401 {object} response.ErrorResponse
+// @Failure      403 {object} response.ErrorResponse
+// @Failure      409 {object} response.ErrorResponse
 // @Router       /wallet/withdraw [post]
 func (h *WalletHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	const op = "handler.Withdraw"
@@ -237,7 +255,7 @@ func (h *WalletHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 	log.Info("withdraw request",
 		slog.String("op", op),
 		slog.String("user_id", userID.String()),
-		slog.Float64("amount", req.Amount),
+		slog.String("amount", req.Amount.String()),
 		slog.String("currency", string(req.Currency)))
 
 	result, err := h.service.Withdraw(r.Context(), userID, req)
@@ -249,6 +267,11 @@ func (h *WalletHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, custom_err.ErrInsufficientFunds):
 			log.Warn("insufficient funds", slog.String("op", op))
 			response.WriteJSONError(w, log, http.StatusBadRequest, "insufficient_funds", "Insufficient funds in the wallet")
+		case errors.Is(err, custom_err.ErrLimitExceeded):
+			writeLimitExceeded(w, log, op, err)
+		case errors.Is(err, custom_err.ErrWalletFrozen):
+			log.Warn("wallet is frozen", slog.String("op", op))
+			response.WriteJSONError(w, log, http.StatusForbidden, "wallet_frozen", "Wallet is frozen")
 		case errors.Is(err, custom_err.ErrDuplicateRequest):
 			response.WriteJSONError(w, log, http.StatusConflict, "duplicate_request",
 				"Operation with this requestID already processed")
@@ -256,6 +279,10 @@ func (h *WalletHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, custom_err.ErrInvalidCurrency):
 			log.Warn("invalid currency", slog.String("op", op))
 			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currency")
+		case errors.Is(err, custom_err.ErrAmountPrecision):
+			log.Warn("invalid amount precision", slog.String("op", op))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount_precision",
+				"Amount has more decimal places than the currency allows")
 		case errors.Is(err, custom_err.ErrInvalidAmount):
 			log.Warn("invalid amount", slog.String("op", op))
 			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_amount", "Invalid amount")
@@ -268,3 +295,115 @@ func (h *WalletHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteJSONSuccess(w, log, http.StatusOK, result)
 }
+
+// GetTransactions godoc
+// @Summary      История операций
+// @Description  Возвращает ленту пополнений, выводов и обменов пользователя (от новых к старым) с постраничной навигацией по курсору
+// @Tags         wallet
+// @Security     BearerAuth
+// @Produce      json
+// @Param        currency query string false "Фильтр по коду валюты из реестра, например USD"
+// @Param        type     query string false "Фильтр по типу операции (DEPOSIT, WITHDRAW, EXCHANGE)"
+// @Param        from     query string false "Начало периода (RFC3339, включительно)"
+// @Param        to       query string false "Конец периода (RFC3339, не включительно)"
+// @Param        limit    query int    false "Размер страницы (1-100, по умолчанию 20)"
+// @Param        cursor   query string false "Курсор следующей страницы из next_cursor"
+// @Success      200 {object} models.TransactionHistoryResponse
+// @Failure      400 {object} response.ErrorResponse
+// @Failure      401 {object} response.ErrorResponse
+// @Failure      500 {object} response.ErrorResponse
+// @Router       /wallet/transactions [get]
+func (h *WalletHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.GetTransactions"
+	log := middlew.GetLogger(r.Context())
+
+	userID := middlew.GetUserID(r.Context())
+
+	req, err := parseTransactionHistoryQuery(r.URL.Query())
+	if err != nil {
+		log.Warn("invalid query parameter", slog.String("op", op), slog.String("error", err.Error()))
+		response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_field", err.Error())
+		return
+	}
+
+	result, err := h.service.GetTransactions(r.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, custom_err.ErrInvalidCurrency):
+			log.Warn("invalid currency", slog.String("op", op))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_currency", "Invalid currency")
+		case errors.Is(err, custom_err.ErrInvalidCursor):
+			log.Warn("invalid cursor", slog.String("op", op))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_cursor", "Invalid pagination cursor")
+		case errors.Is(err, custom_err.ErrInvalidInput):
+			log.Warn("invalid input", slog.String("op", op), slog.String("error", err.Error()))
+			response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", err.Error())
+		default:
+			log.Error("failed to get transactions", slog.String("op", op), slog.String("error", err.Error()))
+			response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "Failed to retrieve transactions")
+		}
+		return
+	}
+
+	response.WriteJSONSuccess(w, log, http.StatusOK, result)
+}
+
+// parseTransactionHistoryQuery разбирает фильтры истории операций из query-параметров
+func parseTransactionHistoryQuery(query url.Values) (models.TransactionHistoryRequest, error) {
+	req := models.TransactionHistoryRequest{
+		Currency: models.Currency(strings.ToUpper(query.Get("currency"))),
+		Type:     models.OperationType(strings.ToUpper(query.Get("type"))),
+		Cursor:   query.Get("cursor"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return req, errors.New("limit must be an integer")
+		}
+		req.Limit = limit
+	}
+
+	from, err := parseTimeParam(query.Get("from"))
+	if err != nil {
+		return req, errors.New("from must be in RFC3339 format")
+	}
+	to, err := parseTimeParam(query.Get("to"))
+	if err != nil {
+		return req, errors.New("to must be in RFC3339 format")
+	}
+	req.From, req.To = from, to
+
+	return req, nil
+}
+
+func parseTimeParam(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// writeLimitExceeded ответ на операцию сверх лимита с суммой, доступной до конца периода
+func writeLimitExceeded(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	var limitErr *models.LimitExceededError
+	if !errors.As(err, &limitErr) {
+		response.WriteJSONError(w, log, http.StatusBadRequest, "limit_exceeded", "Transaction limit exceeded")
+		return
+	}
+
+	log.Warn("limit exceeded", slog.String("op", op), slog.String("error", limitErr.Error()))
+	response.WriteJSONSuccess(w, log, http.StatusBadRequest, models.LimitExceededResponse{
+		Error:         "limit_exceeded",
+		Message:       "Transaction limit exceeded",
+		OperationType: limitErr.OperationType,
+		Currency:      limitErr.Currency,
+		Period:        limitErr.Period,
+		Limit:         limitErr.Limit,
+		Remaining:     limitErr.Remaining,
+	})
+}
@@ -1,19 +1,29 @@
 package middlew
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
 	"gw-currency-wallet/internal/service"
 	"gw-currency-wallet/pkg/response"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
-func RequireAuth(authService service.Auth) func(http.Handler) http.Handler {
+// RequireAuth проверяет Bearer токен: JWT или персональный API ключ (gwk_...).
+// API ключ принимается, только если у него есть все перечисленные scopes; маршруты,
+// которые не перечисляют разрешений, доступны только по JWT.
+func RequireAuth(authService service.Auth, scopes ...models.APIKeyScope) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			log := GetLogger(r.Context())
@@ -33,13 +43,25 @@ func RequireAuth(authService service.Auth) func(http.Handler) http.Handler {
 
 			tokenString := parts[1]
 
-			claims, err := authService.ValidateToken(tokenString)
+			var (
+				claims *models.JWTClaims
+				err    error
+			)
+			if strings.HasPrefix(tokenString, models.APIKeyPrefix) {
+				claims, err = authService.AuthenticateAPIKey(r.Context(), tokenString, ClientIP(r))
+			} else {
+				claims, err = authService.ValidateToken(r.Context(), tokenString)
+			}
 			if err != nil {
 				switch {
+				case errors.Is(err, custom_err.ErrAPIKeyIPNotAllowed):
+					response.WriteJSONError(w, log, http.StatusForbidden, "ip_not_allowed", "API key is not allowed from this address")
 				case errors.Is(err, custom_err.ErrTokenExpired):
 					response.WriteJSONError(w, log, http.StatusUnauthorized, "token_expired", "Token has expired")
 				case errors.Is(err, custom_err.ErrTokenNotActive):
 					response.WriteJSONError(w, log, http.StatusUnauthorized, "token_not_active", "Token not yet active")
+				case errors.Is(err, custom_err.ErrTokenRevoked):
+					response.WriteJSONError(w, log, http.StatusUnauthorized, "token_revoked", "Token has been revoked")
 				case errors.Is(err, custom_err.ErrInvalidToken):
 					response.WriteJSONError(w, log, http.StatusUnauthorized, "invalid_token", "Invalid token")
 				default:
@@ -48,10 +70,20 @@ func RequireAuth(authService service.Auth) func(http.Handler) http.Handler {
 				}
 				return
 			}
+			loggerWithUser := log.With(slog.String("user_id", claims.UserID.String()))
+			if claims.APIKeyID != nil {
+				loggerWithUser = loggerWithUser.With(slog.String("api_key_id", claims.APIKeyID.String()))
+				if len(scopes) == 0 || !claims.HasScopes(scopes...) {
+					loggerWithUser.Warn("api key scope denied")
+					response.WriteJSONError(w, loggerWithUser, http.StatusForbidden, "insufficient_scope",
+						"API key does not have the required scope")
+					return
+				}
+			}
+
 			ctx := r.Context()
 			ctx = context.WithValue(ctx, userIDKey, claims.UserID)
-
-			loggerWithUser := log.With(slog.String("user_id", claims.UserID.String()))
+			ctx = context.WithValue(ctx, claimsKey, claims)
 			ctx = context.WithValue(ctx, loggerKey, loggerWithUser)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -59,6 +91,18 @@ func RequireAuth(authService service.Auth) func(http.Handler) http.Handler {
 	}
 }
 
+// ClientIP адрес клиента. Порт отбрасывается; без порта RemoteAddr выставляет RealIP
+func ClientIP(r *http.Request) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		return addrPort.Addr().Unmap()
+	}
+	addr, err := netip.ParseAddr(r.RemoteAddr)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
 func GetUserID(ctx context.Context) uuid.UUID {
 	userID, ok := ctx.Value(userIDKey).(uuid.UUID)
 	if !ok {
@@ -66,3 +110,111 @@ func GetUserID(ctx context.Context) uuid.UUID {
 	}
 	return userID
 }
+
+func GetClaims(ctx context.Context) *models.JWTClaims {
+	claims, ok := ctx.Value(claimsKey).(*models.JWTClaims)
+	if !ok {
+		panic("claims not found in context - RequireAuth middleware not applied?")
+	}
+	return claims
+}
+
+// RequireRole пропускает только пользователей с одной из перечисленных ролей.
+// Применяется после RequireAuth.
+func RequireRole(roles ...models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaims(r.Context())
+			for _, role := range roles {
+				if claims.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			log := GetLogger(r.Context())
+			log.Warn("access denied", slog.String("role", string(claims.Role)))
+			response.WriteJSONError(w, log, http.StatusForbidden, "forbidden", "Insufficient permissions")
+		})
+	}
+}
+
+// RequireVerifiedEmail пропускает только пользователей с подтвержденным email.
+// Признак берется из токена, поэтому после подтверждения нужно обновить токен.
+// Применяется после RequireAuth.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetClaims(r.Context()).EmailVerified {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log := GetLogger(r.Context())
+		log.Warn("email not verified")
+		response.WriteJSONError(w, log, http.StatusForbidden, "email_not_verified",
+			"Email address must be verified for this operation")
+	})
+}
+
+// RequireStepUp пропускает только токены, подтвержденные вторым фактором не раньше maxAge назад.
+// Применяется после RequireAuth.
+func RequireStepUp(maxAge time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetClaims(r.Context()).SteppedUp(maxAge, time.Now()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := GetLogger(r.Context())
+			log.Warn("step-up required")
+			response.WriteJSONError(w, log, http.StatusForbidden, "step_up_required",
+				"This operation requires confirmation with a second factor")
+		})
+	}
+}
+
+// RequireStepUpAbove требует подтверждения вторым фактором, если сумма списания из тела запроса
+// превышает порог для валюты. Для списания по холду валюта и сумма берутся из холда {holdID}.
+// Тело читается и возвращается в запрос. Тело, которое не разбирается целиком, отклоняется:
+// обработчик мог бы прочитать из него сумму, не проверенную здесь.
+// Применяется после RequireAuth и до Idempotency, чтобы отказ не сохранялся как ответ на ключ.
+func RequireStepUpAbove(stepUp service.StepUp) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := GetLogger(r.Context())
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotencyMaxBody))
+			if err != nil {
+				response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			var operation models.StepUpOperation
+			if len(bytes.TrimSpace(body)) > 0 {
+				if err := json.Unmarshal(body, &operation); err != nil {
+					response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_json", "Invalid JSON body")
+					return
+				}
+			}
+			if holdID, err := uuid.Parse(chi.URLParam(r, "holdID")); err == nil {
+				operation.HoldID = &holdID
+			}
+
+			if err := stepUp.Require(r.Context(), GetClaims(r.Context()), operation); err != nil {
+				if errors.Is(err, custom_err.ErrStepUpRequired) {
+					log.Warn("step-up required", slog.String("amount", operation.Amount.String()))
+					response.WriteJSONError(w, log, http.StatusForbidden, "step_up_required",
+						"Operations above the threshold require confirmation with a second factor")
+					return
+				}
+				log.Error("failed to check step-up", slog.String("error", err.Error()))
+				response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "Internal error")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package middlew
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	idempotencyMaxKeyLength = 255
+	// idempotencyMaxBody предел тела запроса, которое читается для отпечатка
+	idempotencyMaxBody = 1 << 20
+)
+
+// Idempotency повторяет сохраненный ответ на запрос с уже использованным заголовком Idempotency-Key.
+// Повтор ключа с другим запросом или во время выполнения первого - 409. Ответы 5xx не сохраняются,
+// ключ освобождается и запрос можно повторить. Без заголовка запрос обрабатывается как обычно.
+// Применяется после RequireAuth: ключи принадлежат пользователю.
+func Idempotency(idempotency service.Idempotency) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(models.IdempotencyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := GetLogger(r.Context()).With(slog.String("idempotency_key", key))
+			if len(key) > idempotencyMaxKeyLength {
+				response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_idempotency_key", "Idempotency-Key must be at most 255 characters")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotencyMaxBody))
+			if err != nil {
+				response.WriteJSONError(w, log, http.StatusBadRequest, "invalid_input", "Failed to read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			userID := GetUserID(r.Context())
+			saved, err := idempotency.Begin(r.Context(), userID, key, requestFingerprint(r, body))
+			if err != nil {
+				switch {
+				case errors.Is(err, custom_err.ErrIdempotencyKeyReused):
+					response.WriteJSONError(w, log, http.StatusConflict, "idempotency_key_reused", "Idempotency-Key was already used with a different request")
+				case errors.Is(err, custom_err.ErrIdempotencyKeyInProgress):
+					response.WriteJSONError(w, log, http.StatusConflict, "request_in_progress", "A request with this Idempotency-Key is still in progress")
+				default:
+					log.Error("failed to reserve idempotency key", slog.String("error", err.Error()))
+					response.WriteJSONError(w, log, http.StatusInternalServerError, "internal_error", "Internal error")
+				}
+				return
+			}
+			if saved != nil {
+				log.Info("replaying idempotent response", slog.Int("status", saved.StatusCode))
+				replayResponse(w, log, saved)
+				return
+			}
+
+			// Ключ занят этим запросом: ответ сохраняется или ключ освобождается, даже если клиент
+			// отключился или обработчик запаниковал
+			ctx := context.WithoutCancel(r.Context())
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := idempotency.Release(ctx, userID, key); err != nil {
+					log.Error("failed to release idempotency key", slog.String("error", err.Error()))
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				return
+			}
+			if err := idempotency.Complete(ctx, userID, key, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+				log.Error("failed to save idempotent response", slog.String("error", err.Error()))
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// requestFingerprint отпечаток запроса: метод, путь и тело
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replayResponse(w http.ResponseWriter, log *slog.Logger, saved *models.IdempotencyRecord) {
+	if saved.ContentType != "" {
+		w.Header().Set("Content-Type", saved.ContentType)
+	}
+	w.Header().Set(models.IdempotencyReplayedHeader, "true")
+	w.WriteHeader(saved.StatusCode)
+	if _, err := w.Write(saved.Body); err != nil {
+		log.Error("failed to write replayed response", slog.String("error", err.Error()))
+	}
+}
+
+// responseRecorder пропускает ответ клиенту и запоминает его для сохранения
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
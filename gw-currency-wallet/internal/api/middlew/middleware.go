@@ -2,6 +2,8 @@ package middlew
 
 import (
 	"context"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
 	"log/slog"
 	"net/http"
 
@@ -13,6 +15,7 @@ type contextKey string
 const (
 	loggerKey contextKey = "logger"
 	userIDKey contextKey = "user_id"
+	claimsKey contextKey = "claims"
 )
 
 func WithLogger(log *slog.Logger) func(http.Handler) http.Handler {
@@ -33,3 +36,15 @@ func GetLogger(ctx context.Context) *slog.Logger {
 	}
 	return slog.Default()
 }
+
+// WithClientInfo передает сервисам адрес клиента и User-Agent для журнала событий безопасности.
+// Адрес берется из RemoteAddr, поэтому middleware подключается после RealIP.
+func WithClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := service.WithClientInfo(r.Context(), models.ClientInfo{
+			IP:        ClientIP(r),
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,87 @@
+package middlew
+
+import (
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/pkg/response"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RateLimit ограничивает частоту запросов по политике policy. После RequireAuth корзина
+// принадлежит пользователю, до нее - адресу клиента (для IPv6 - сети /64). Ответ получает
+// заголовки RateLimit-*, превышение - 429 с Retry-After. Если хранилище корзин недоступно,
+// запрос пропускается: ограничение частоты не должно останавливать сервис.
+func RateLimit(limiter service.RateLimiter, policy string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := rateLimitKey(r)
+			result, err := limiter.Allow(r.Context(), policy, key)
+			if err != nil {
+				GetLogger(r.Context()).Error("rate limit check failed",
+					slog.String("policy", policy), slog.String("error", err.Error()))
+				next.ServeHTTP(w, r)
+				return
+			}
+			if result == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			header.Set("RateLimit-Policy", rateLimitPolicyHeader(result.Policy))
+
+			if !result.Allowed {
+				log := GetLogger(r.Context())
+				log.Warn("rate limit exceeded", slog.String("policy", policy), slog.String("key", key))
+				header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+				response.WriteJSONError(w, log, http.StatusTooManyRequests, "rate_limited", "Too many requests, try again later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey клиент, которому принадлежит корзина
+func rateLimitKey(r *http.Request) string {
+	if userID, ok := r.Context().Value(userIDKey).(uuid.UUID); ok {
+		return "user:" + userID.String()
+	}
+
+	ip := ClientIP(r)
+	if ip.Is6() {
+		// У клиента IPv6 обычно целая сеть /64, поэтому отдельные адреса не различаются
+		if prefix, err := ip.Prefix(64); err == nil {
+			return "ip:" + prefix.String()
+		}
+	}
+	if !ip.IsValid() {
+		ip = netip.IPv4Unspecified()
+	}
+	return "ip:" + ip.String()
+}
+
+// rateLimitPolicyHeader правило в формате RateLimit-Policy: <запросов>;w=<окно в секундах>[;burst=<емкость>]
+func rateLimitPolicyHeader(limit models.RateLimit) string {
+	policy := fmt.Sprintf("%d;w=%s", limit.Requests, ceilSeconds(limit.Period))
+	if limit.Burst != limit.Requests {
+		policy += ";burst=" + strconv.Itoa(limit.Burst)
+	}
+	return policy
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
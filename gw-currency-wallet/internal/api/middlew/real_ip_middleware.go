@@ -0,0 +1,89 @@
+package middlew
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies разбирает адреса и сети доверенных прокси, например 10.0.0.0/8 или 192.168.1.10
+func ParseTrustedProxies(raw []string) ([]netip.Prefix, error) {
+	proxies := make([]netip.Prefix, 0, len(raw))
+	for _, value := range raw {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q must be an IP address or CIDR", value)
+			}
+			addr = addr.Unmap()
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q must be an IP address or CIDR", value)
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// RealIP заменяет RemoteAddr адресом клиента из X-Forwarded-For или X-Real-IP, но только если запрос
+// пришел от доверенного прокси: любой клиент может прислать эти заголовки сам, а по адресу клиента
+// проверяются списки адресов API ключей и ограничиваются попытки входа и частота запросов.
+// X-Forwarded-For читается справа налево до первого адреса, который не принадлежит доверенному прокси.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if peer := ClientIP(r); peer.IsValid() && isTrustedProxy(trusted, peer) {
+				if client, ok := forwardedClientIP(r, trusted); ok {
+					r.RemoteAddr = client.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClientIP адрес клиента из заголовков, выставленных доверенным прокси
+func forwardedClientIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+		return addr.Unmap(), err == nil
+	}
+
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// Левее некорректной записи адресам верить нельзя
+			break
+		}
+		client = addr.Unmap()
+		if !isTrustedProxy(trusted, client) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+func isTrustedProxy(trusted []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"gw-currency-wallet/internal/api/middlew"
 	"gw-currency-wallet/internal/grpc_client"
+	"gw-currency-wallet/internal/jwtkeys"
 	"gw-currency-wallet/internal/kafka"
+	"gw-currency-wallet/internal/mailer"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/secretbox"
 	"gw-currency-wallet/internal/storage/postgres"
 	"gw-currency-wallet/pkg/logger"
 	"log/slog"
@@ -25,6 +29,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
 )
 
 type App struct {
@@ -37,6 +42,22 @@ type App struct {
 	exchangeService *service.ExchangeService
 	exchangeClient  grpc_client.ExchangerClient
 	kafkaProducer   kafka.Producer
+	outboxRelay     *service.OutboxRelay
+	idempotency     *service.IdempotencyService
+	holds           *service.HoldService
+	limitOrders     *service.LimitOrderService
+	schedules       *service.ScheduledExchangeService
+	reconciliation  *service.ReconciliationService
+	currencies      service.CurrencyRegistry
+	signingKeys     service.SigningKeys
+	keyRotation     *service.RotatingSigningKeys
+	rounding        models.RoundingMode
+	stepUp          service.StepUp
+	auditEvents     *service.AuditEventService
+	rateLimiter     *service.RateLimitService
+	secrets         *secretbox.Box
+	// stepUpThresholds пороги сумм списания, выше которых операции требуют step-up
+	stepUpThresholds map[models.Currency]decimal.Decimal
 }
 
 func NewApp() (*App, error) {
@@ -50,6 +71,39 @@ func NewApp() (*App, error) {
 	}
 	log.Info("конфигурация загружена", slog.String("port", cfg.HTTPPort))
 
+	rounding, err := models.ParseRoundingMode(cfg.Money.RoundingMode)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка конфигурации округления: %w", err)
+	}
+
+	stepUpThresholds, err := models.ParseStepUpThresholds(cfg.MFA.StepUpThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка конфигурации порогов step-up: %w", err)
+	}
+
+	trustedProxies, err := middlew.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка конфигурации доверенных прокси: %w", err)
+	}
+
+	secretsKey, err := secretbox.ParseKey(cfg.Secrets.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка конфигурации ключа шифрования секретов: %w", err)
+	}
+	secrets, err := secretbox.New(secretsKey)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка конфигурации ключа шифрования секретов: %w", err)
+	}
+
+	rateLimits, err := models.ParseRateLimitPolicies(cfg.RateLimit.Policies())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка конфигурации ограничения частоты запросов: %w", err)
+	}
+	if !cfg.RateLimit.Enabled {
+		log.Warn("ограничение частоты запросов отключено в конфигурации")
+		rateLimits = nil
+	}
+
 	log.Info("выполнение миграций базы данных")
 	if err := db.RunMigrations(cfg.DB.MigrationURL(), "migrations"); err != nil {
 		return nil, fmt.Errorf("ошибка выполнения миграций: %w", err)
@@ -78,10 +132,17 @@ func NewApp() (*App, error) {
 	}
 	log.Info("gRPC client инициализирован")
 
+	currencies := service.NewCurrencyRegistry(grpcClient, time.Minute, log)
+
+	signingKeys, keyRotation, err := newSigningKeys(pool, secrets, cfg.JWT, log)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации ключей подписи JWT: %w", err)
+	}
+
 	var kafkaProducer kafka.Producer
 	if cfg.Kafka.Enabled {
 		log.Info("инициализация kafka producer", slog.Any("brokers", cfg.Kafka.Brokers))
-		kafkaProducer, err = kafka.NewKafkaProducer(cfg.Kafka.Brokers, cfg.Kafka.Topic, log)
+		kafkaProducer, err = kafka.NewKafkaProducer(cfg.Kafka.Brokers, cfg.Kafka.Topic, cfg.Kafka.AlertsTopic, log)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка инициализации kafka: %w", err)
 		}
@@ -90,12 +151,26 @@ func NewApp() (*App, error) {
 		kafkaProducer = kafka.NewNoOpProducer(log)
 	}
 
+	outboxRelay := service.NewOutboxRelay(postgres.NewOutboxRepository(pool), kafkaProducer, log)
+	outboxRelay.Start()
+
+	idempotency := service.NewIdempotencyService(postgres.NewIdempotencyRepository(pool), cfg.Idempotency.TTL, log)
+	idempotency.Start()
+
+	rateLimiter, err := newRateLimiter(pool, cfg.RateLimit.Backend, rateLimits, log)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации ограничения частоты запросов: %w", err)
+	}
+	rateLimiter.Start()
+
 	srv := server.NewServer(cfg.HTTPPort)
 	log.Info("сервер инициализирован", slog.String("port", cfg.HTTPPort))
 	srv.Router.Use(middleware.RequestID)
 	srv.Router.Use(middlew.WithLogger(log))
-	srv.Router.Use(middleware.RealIP)
+	srv.Router.Use(middlew.RealIP(trustedProxies))
+	srv.Router.Use(middlew.WithClientInfo)
 	srv.Router.Use(middleware.Recoverer)
+	srv.Router.Use(middlew.RateLimit(rateLimiter, models.RateLimitPolicyGlobal))
 	srv.RegisterSwagger()
 
 	return &App{
@@ -106,31 +181,243 @@ func NewApp() (*App, error) {
 		cfg:            cfg,
 		exchangeClient: grpcClient,
 		kafkaProducer:  kafkaProducer,
+		outboxRelay:    outboxRelay,
+		idempotency:    idempotency,
+		rateLimiter:    rateLimiter,
+		currencies:     currencies,
+		signingKeys:    signingKeys,
+		keyRotation:    keyRotation,
+		rounding:       rounding,
+		secrets:        secrets,
+
+		stepUpThresholds: stepUpThresholds,
 	}, nil
 }
 
+// newSigningKeys создает ключи подписи JWT. Для асимметричных алгоритмов ключи
+// создаются при первом запуске и далее ротируются в фоне.
+func newSigningKeys(pool *pgxpool.Pool, secrets *secretbox.Box, cfg config.JWTConfig, log *slog.Logger) (service.SigningKeys, *service.RotatingSigningKeys, error) {
+	if cfg.Algorithm == jwtkeys.AlgHS256 {
+		log.Warn("JWT подписывается общим секретом HS256, JWKS не публикуется")
+		return service.NewHMACSigningKeys(cfg.Secret), nil, nil
+	}
+
+	rotating, err := service.NewRotatingSigningKeys(
+		postgres.NewSigningKeyRepository(pool),
+		service.NewPgxTxManager(pool),
+		secrets,
+		cfg.Algorithm,
+		cfg.KeyRotation,
+		cfg.KeyOverlap,
+		log,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := rotating.Rotate(ctx); err != nil {
+		return nil, nil, err
+	}
+	rotating.Start()
+
+	log.Info("ключи подписи JWT загружены", slog.String("algorithm", cfg.Algorithm))
+	return rotating, rotating, nil
+}
+
 func (a *App) BuildAuthLayer() {
 	txManager := service.NewPgxTxManager(a.pool)
 	userRepo := postgres.NewUserRepository(a.pool)
 	walletRepo := postgres.NewWalletRepository(a.pool)
+	tokenRepo := postgres.NewTokenRepository(a.pool)
+
+	a.auditEvents = service.NewAuditEventService(
+		postgres.NewAuditEventRepository(a.pool),
+		postgres.NewAuditRepository(a.pool),
+		txManager,
+		a.log,
+	)
+
+	accountService := service.NewAccountService(
+		userRepo,
+		postgres.NewAccountTokenRepository(a.pool),
+		tokenRepo,
+		txManager,
+		newMailer(a.cfg.Mail, a.log),
+		a.auditEvents,
+		a.cfg.Account.EmailVerificationTTL,
+		a.cfg.Account.PasswordResetTTL,
+		a.cfg.Mail.LinkBaseURL,
+		a.log,
+	)
+
+	mfaService := service.NewMFAService(
+		postgres.NewMFARepository(a.pool),
+		userRepo,
+		txManager,
+		a.auditEvents,
+		a.secrets,
+		a.cfg.MFA.Issuer,
+		a.cfg.MFA.ChallengeTTL,
+		a.log,
+	)
+	// Ошибка не мешает запуску: открытые секреты продолжают приниматься до следующей попытки
+	sealCtx, cancelSeal := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := mfaService.SealPlaintextSecrets(sealCtx); err != nil {
+		a.log.Error("ошибка шифрования секретов TOTP", slog.String("error", err.Error()))
+	}
+	cancelSeal()
+	a.stepUp = service.NewStepUpPolicy(
+		postgres.NewQuoteRepository(a.pool),
+		postgres.NewHoldRepository(a.pool),
+		a.currencies,
+		a.stepUpThresholds,
+		a.cfg.MFA.StepUpMaxAge,
+	)
+
+	apiKeyService := service.NewAPIKeyService(postgres.NewAPIKeyRepository(a.pool), a.auditEvents, a.log)
+	loginGuard := service.NewLoginGuard(
+		postgres.NewLoginThrottleRepository(a.pool),
+		userRepo,
+		txManager,
+		a.auditEvents,
+		models.LoginBackoff{FreeAttempts: a.cfg.Login.FreeAttempts, Base: a.cfg.Login.BackoffBase, Max: a.cfg.Login.BackoffMax},
+		models.LoginBackoff{FreeAttempts: a.cfg.Login.IPFreeAttempts, Base: a.cfg.Login.BackoffBase, Max: a.cfg.Login.BackoffMax},
+		a.cfg.Login.LockoutThreshold,
+		a.cfg.Login.LockoutDuration,
+		a.cfg.Login.FailureWindow,
+		a.log,
+	)
 
 	a.authService = service.NewAuthService(
 		userRepo,
 		walletRepo,
+		tokenRepo,
 		txManager,
-		a.cfg.JWT.Secret,
+		a.currencies,
+		a.signingKeys,
+		accountService,
+		mfaService,
+		apiKeyService,
+		loginGuard,
+		a.auditEvents,
 		a.cfg.JWT.Expiration,
+		a.cfg.JWT.RefreshExpiration,
 		a.log,
 	)
 
 	authHandler := handlers.NewAuthHandler(a.authService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	mfaHandler := handlers.NewMFAHandler(mfaService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	auditEventHandler := handlers.NewAuditEventHandler(a.auditEvents)
+	jwksHandler := handlers.NewJWKSHandler(a.signingKeys)
 
-	a.server.Router.Post("/api/v1/register", authHandler.Register)
-	a.server.Router.Post("/api/v1/login", authHandler.Login)
+	a.server.Router.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
+	a.server.Router.Group(func(r chi.Router) {
+		r.Use(middlew.RateLimit(a.rateLimiter, models.RateLimitPolicyAuth))
+
+		r.Post("/api/v1/register", authHandler.Register)
+		r.Post("/api/v1/login", authHandler.Login)
+		r.Post("/api/v1/login/mfa", authHandler.LoginMFA)
+		r.Post("/api/v1/token/refresh", authHandler.Refresh)
+		r.Post("/api/v1/email/verify", accountHandler.VerifyEmail)
+		r.Post("/api/v1/password/forgot", accountHandler.ForgotPassword)
+		r.Post("/api/v1/password/reset", accountHandler.ResetPassword)
+	})
+
+	a.server.Router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(a.authService))
+
+		r.Post("/api/v1/logout", authHandler.Logout)
+		r.Post("/api/v1/email/verify/resend", accountHandler.ResendVerification)
+	})
+
+	registerMFARoutes(a.server.Router, a.authService, a.cfg.MFA.StepUpMaxAge, authHandler, mfaHandler)
+	registerAPIKeyRoutes(a.server.Router, a.authService, apiKeyHandler)
+	registerSecurityEventRoutes(a.server.Router, a.authService, auditEventHandler)
 
 	a.log.Info("слой 'auth' собран и маршруты зарегистрированы")
 }
 
+// registerMFARoutes маршруты двухфакторной аутентификации. Отключение TOTP и перевыпуск кодов
+// восстановления требуют токена, недавно подтвержденного вторым фактором.
+func registerMFARoutes(
+	router chi.Router,
+	auth service.Auth,
+	stepUpMaxAge time.Duration,
+	authHandler *handlers.AuthHandler,
+	mfaHandler *handlers.MFAHandler,
+) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth))
+
+		r.Get("/api/v1/mfa", mfaHandler.Status)
+		r.Post("/api/v1/mfa/step-up", authHandler.StepUp)
+		r.Post("/api/v1/mfa/totp/enroll", mfaHandler.EnrollTOTP)
+		r.Post("/api/v1/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+		r.With(middlew.RequireStepUp(stepUpMaxAge)).Post("/api/v1/mfa/totp/disable", mfaHandler.DisableTOTP)
+		r.With(middlew.RequireStepUp(stepUpMaxAge)).Post("/api/v1/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+	})
+}
+
+// registerAPIKeyRoutes управление API ключами. Доступно только по JWT: ключ не может выпустить
+// другой ключ или отозвать себя.
+func registerAPIKeyRoutes(router chi.Router, auth service.Auth, apiKeyHandler *handlers.APIKeyHandler) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth))
+
+		r.Post("/api/v1/api-keys", apiKeyHandler.CreateAPIKey)
+		r.Get("/api/v1/api-keys", apiKeyHandler.ListAPIKeys)
+		r.Delete("/api/v1/api-keys/{keyID}", apiKeyHandler.RevokeAPIKey)
+	})
+}
+
+// registerSecurityEventRoutes журнал событий безопасности своей учетной записи. Доступен только по JWT:
+// утекший API ключ не должен раскрывать адреса и устройства, с которых входил владелец.
+func registerSecurityEventRoutes(router chi.Router, auth service.Auth, auditEventHandler *handlers.AuditEventHandler) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth))
+
+		r.Get("/api/v1/security/events", auditEventHandler.ListSecurityEvents)
+	})
+}
+
+// newMailer отправляет письма через SMTP, если он настроен, иначе пишет их в лог
+// newRateLimiter создает ограничение частоты запросов. Корзины в памяти считаются отдельно
+// на каждом экземпляре сервиса, в postgres - общие для всех реплик.
+func newRateLimiter(pool *pgxpool.Pool, backend string, policies map[string]models.RateLimit, log *slog.Logger) (*service.RateLimitService, error) {
+	var store service.RateLimitStore
+	switch backend {
+	case config.RateLimitBackendMemory:
+		store = service.NewMemoryRateLimitStore()
+	case config.RateLimitBackendPostgres:
+		store = postgres.NewRateLimitRepository(pool)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый RATE_LIMIT_BACKEND: %s", backend)
+	}
+
+	log.Info("ограничение частоты запросов настроено", slog.String("backend", backend), slog.Int("policies", len(policies)))
+	return service.NewRateLimitService(store, policies, log), nil
+}
+
+func newMailer(cfg config.MailConfig, log *slog.Logger) mailer.Mailer {
+	if cfg.SMTPHost == "" {
+		log.Warn("SMTP не настроен, письма не отправляются")
+		return mailer.NewLogMailer(log)
+	}
+	return mailer.NewSMTPMailer(mailer.SMTPConfig{
+		Host:     cfg.SMTPHost,
+		Port:     cfg.SMTPPort,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+		From:     cfg.From,
+		Timeout:  cfg.SMTPTimeout,
+	})
+}
+
 func (a *App) BuildWalletLayer() error {
 	if a.authService == nil {
 		err := errors.New("authService not initialized, call BuildAuthLayer first")
@@ -140,21 +427,50 @@ func (a *App) BuildWalletLayer() error {
 
 	txManager := service.NewPgxTxManager(a.pool)
 	walletRepo := postgres.NewWalletRepository(a.pool)
-	walletService := service.NewWalletService(walletRepo, txManager)
+	ledgerRepo := postgres.NewLedgerRepository(a.pool)
+	limits := service.NewLimitService(postgres.NewLimitRepository(a.pool), a.currencies)
+	walletService := service.NewWalletService(walletRepo, ledgerRepo, limits, txManager, a.currencies, service.NewPolicy())
 	walletHandler := handlers.NewWalletHandler(walletService)
 
-	a.server.Router.Group(func(r chi.Router) {
-		r.Use(middlew.RequireAuth(a.authService))
+	registerWalletRoutes(a.server.Router, a.authService, a.idempotency, a.stepUp, a.rateLimiter, walletHandler)
+
+	a.log.Info("слой 'wallet' собран и маршруты зарегистрированы")
+	return nil
+}
+
+// registerWalletRoutes маршруты кошелька. Операции выполняются от имени субъекта токена,
+// доступ к кошельку по ID проверяет политика доступа в сервисе. Вывод средств доступен
+// только пользователям с подтвержденным email, крупный вывод требует step-up.
+// API ключам нужны разрешения balance:read и wallet:write. Частота пополнений и выводов
+// ограничивается политикой wallet.
+func registerWalletRoutes(
+	router chi.Router,
+	auth service.Auth,
+	idempotency service.Idempotency,
+	stepUp service.StepUp,
+	limiter service.RateLimiter,
+	walletHandler *handlers.WalletHandler,
+) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth, models.ScopeBalanceRead))
 
 		r.Get("/api/v1/wallets/{walletID}", walletHandler.GetWalletByID)
 		//r.Post("/api/v1/wallet", walletHandler.UpdateBalance)
 		r.Get("/api/v1/balance", walletHandler.GetBalance)
-		r.Post("/api/v1/wallet/deposit", walletHandler.Deposit)
-		r.Post("/api/v1/wallet/withdraw", walletHandler.Withdraw)
+		r.Get("/api/v1/wallet/transactions", walletHandler.GetTransactions)
 	})
 
-	a.log.Info("слой 'wallet' собран и маршруты зарегистрированы")
-	return nil
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth, models.ScopeWalletWrite))
+		r.Use(middlew.RateLimit(limiter, models.RateLimitPolicyWallet))
+
+		r.With(middlew.Idempotency(idempotency)).Post("/api/v1/wallet/deposit", walletHandler.Deposit)
+		r.With(
+			middlew.RequireVerifiedEmail,
+			middlew.RequireStepUpAbove(stepUp),
+			middlew.Idempotency(idempotency),
+		).Post("/api/v1/wallet/withdraw", walletHandler.Withdraw)
+	})
 }
 
 func (a *App) BuildExchangeLayer() error {
@@ -168,34 +484,288 @@ func (a *App) BuildExchangeLayer() error {
 		a.log.Error(err.Error())
 		return err
 	}
-	if a.kafkaProducer == nil {
-		err := errors.New("kafkaProducer not initialized")
-		a.log.Error(err.Error())
-		return err
-	}
 
 	txManager := service.NewPgxTxManager(a.pool)
 	walletRepo := postgres.NewWalletRepository(a.pool)
+	ledgerRepo := postgres.NewLedgerRepository(a.pool)
+	outboxRepo := postgres.NewOutboxRepository(a.pool)
+	quoteRepo := postgres.NewQuoteRepository(a.pool)
+	limits := service.NewLimitService(postgres.NewLimitRepository(a.pool), a.currencies)
+	fees := service.NewFeeSchedule(postgres.NewFeeRepository(a.pool), time.Minute, a.log)
 
 	a.exchangeService = service.NewExchangeService(
 		walletRepo,
+		ledgerRepo,
+		outboxRepo,
+		quoteRepo,
+		limits,
 		txManager,
 		a.exchangeClient,
-		a.kafkaProducer,
+		a.currencies,
+		fees,
 		5*time.Minute,
+		a.cfg.Exchange.QuoteTTL,
+		a.rounding,
 		a.log,
 	)
 
+	a.limitOrders = service.NewLimitOrderService(
+		postgres.NewLimitOrderRepository(a.pool),
+		walletRepo,
+		a.exchangeService,
+		txManager,
+		a.currencies,
+		service.NewPolicy(),
+		a.cfg.Exchange.LimitOrderInterval,
+		a.log,
+	)
+	a.limitOrders.Start()
+
+	a.schedules = service.NewScheduledExchangeService(
+		postgres.NewScheduledExchangeRepository(a.pool),
+		walletRepo,
+		a.exchangeService,
+		txManager,
+		a.currencies,
+		service.NewPolicy(),
+		a.cfg.Exchange.ScheduleInterval,
+		a.log,
+	)
+	a.schedules.Start()
+
 	exchangeHandler := handlers.NewExchangeHandler(a.exchangeService)
+	limitOrderHandler := handlers.NewLimitOrderHandler(a.limitOrders)
+	scheduleHandler := handlers.NewScheduledExchangeHandler(a.schedules)
+
+	registerExchangeRoutes(a.server.Router, a.authService, a.idempotency, a.stepUp, a.rateLimiter, exchangeHandler, limitOrderHandler, scheduleHandler)
+
+	a.log.Info("слой 'exchange' собран и маршруты зарегистрированы")
+	return nil
+}
+
+// registerExchangeRoutes маршруты обмена. Обмен, лимитный ордер и запланированный обмен
+// на сумму выше порога требуют step-up. API ключам для просмотра ордеров и расписаний
+// нужно разрешение exchange:read, для остальных операций - exchange:write. Частота операций
+// обмена ограничивается политикой exchange.
+func registerExchangeRoutes(
+	router chi.Router,
+	auth service.Auth,
+	idempotency service.Idempotency,
+	stepUp service.StepUp,
+	limiter service.RateLimiter,
+	exchangeHandler *handlers.ExchangeHandler,
+	limitOrderHandler *handlers.LimitOrderHandler,
+	scheduleHandler *handlers.ScheduledExchangeHandler,
+) {
+	router.Get("/api/v1/exchange/rates", exchangeHandler.GetExchangeRates)
+
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth, models.ScopeExchangeRead))
+
+		r.Get("/api/v1/exchange/orders", limitOrderHandler.ListLimitOrders)
+		r.Get("/api/v1/exchange/schedules", scheduleHandler.ListScheduledExchanges)
+		r.Get("/api/v1/exchange/schedules/{scheduleID}/runs", scheduleHandler.ListScheduledExchangeRuns)
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth, models.ScopeExchangeWrite))
+		r.Use(middlew.RateLimit(limiter, models.RateLimitPolicyExchange))
+		debit := r.With(middlew.RequireStepUpAbove(stepUp), middlew.Idempotency(idempotency))
+
+		r.Post("/api/v1/exchange/quote", exchangeHandler.QuoteExchange)
+		debit.Post("/api/v1/exchange", exchangeHandler.ExchangeCurrency)
+
+		debit.Post("/api/v1/exchange/orders", limitOrderHandler.CreateLimitOrder)
+		r.Post("/api/v1/exchange/orders/{orderID}/cancel", limitOrderHandler.CancelLimitOrder)
+
+		debit.Post("/api/v1/exchange/schedules", scheduleHandler.CreateScheduledExchange)
+		r.Post("/api/v1/exchange/schedules/{scheduleID}/cancel", scheduleHandler.CancelScheduledExchange)
+	})
+}
+
+func registerTransferRoutes(
+	router chi.Router,
+	auth service.Auth,
+	idempotency service.Idempotency,
+	stepUp service.StepUp,
+	limiter service.RateLimiter,
+	transferHandler *handlers.TransferHandler,
+) {
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth, models.ScopeTransfersWrite))
+		r.Use(middlew.RateLimit(limiter, models.RateLimitPolicyWallet))
+		r.Use(middlew.RequireVerifiedEmail)
+		r.Use(middlew.RequireStepUpAbove(stepUp))
+		r.Use(middlew.Idempotency(idempotency))
+		r.Post("/api/v1/transfers", transferHandler.Transfer)
+	})
+}
+
+func (a *App) BuildTransferLayer() error {
+	if a.authService == nil {
+		err := errors.New("authService not initialized, call BuildAuthLayer first")
+		a.log.Error(err.Error())
+		return err
+	}
+	if a.exchangeService == nil {
+		err := errors.New("exchangeService not initialized, call BuildExchangeLayer first")
+		a.log.Error(err.Error())
+		return err
+	}
+
+	txManager := service.NewPgxTxManager(a.pool)
+	userRepo := postgres.NewUserRepository(a.pool)
+	walletRepo := postgres.NewWalletRepository(a.pool)
+	ledgerRepo := postgres.NewLedgerRepository(a.pool)
+	outboxRepo := postgres.NewOutboxRepository(a.pool)
+	limits := service.NewLimitService(postgres.NewLimitRepository(a.pool), a.currencies)
+
+	transferService := service.NewTransferService(
+		userRepo,
+		walletRepo,
+		ledgerRepo,
+		outboxRepo,
+		limits,
+		txManager,
+		a.exchangeService,
+		a.currencies,
+		a.rounding,
+		a.log,
+	)
+	transferHandler := handlers.NewTransferHandler(transferService)
+	registerTransferRoutes(a.server.Router, a.authService, a.idempotency, a.stepUp, a.rateLimiter, transferHandler)
+
+	a.log.Info("слой 'transfer' собран и маршруты зарегистрированы")
+	return nil
+}
+
+func (a *App) BuildHoldLayer() error {
+	if a.authService == nil {
+		err := errors.New("authService not initialized, call BuildAuthLayer first")
+		a.log.Error(err.Error())
+		return err
+	}
+
+	a.holds = service.NewHoldService(
+		postgres.NewHoldRepository(a.pool),
+		postgres.NewWalletRepository(a.pool),
+		postgres.NewLedgerRepository(a.pool),
+		service.NewLimitService(postgres.NewLimitRepository(a.pool), a.currencies),
+		service.NewPgxTxManager(a.pool),
+		a.currencies,
+		service.NewPolicy(),
+		a.cfg.Hold.DefaultTTL,
+		a.cfg.Hold.MaxTTL,
+		a.log,
+	)
+	a.holds.Start()
+	holdHandler := handlers.NewHoldHandler(a.holds)
 
-	a.server.Router.Get("/api/v1/exchange/rates", exchangeHandler.GetExchangeRates)
+	registerHoldRoutes(a.server.Router, a.authService, a.idempotency, a.stepUp, a.rateLimiter, holdHandler)
+
+	a.log.Info("слой 'hold' собран и маршруты зарегистрированы")
+	return nil
+}
+
+// registerHoldRoutes маршруты холдов. Доступ к холду по ID проверяет политика доступа в сервисе.
+// API ключам нужны разрешения holds:read и holds:write. Создание и закрытие холдов
+// ограничивается политикой wallet. Списание по холду выводит средства из кошелька, поэтому,
+// как и вывод, доступно только с подтвержденным email и на крупную сумму требует step-up.
+func registerHoldRoutes(
+	router chi.Router,
+	auth service.Auth,
+	idempotency service.Idempotency,
+	stepUp service.StepUp,
+	limiter service.RateLimiter,
+	holdHandler *handlers.HoldHandler,
+) {
+	router.With(middlew.RequireAuth(auth, models.ScopeHoldsRead)).Get("/api/v1/holds/{holdID}", holdHandler.GetHold)
+
+	router.Group(func(r chi.Router) {
+		r.Use(middlew.RequireAuth(auth, models.ScopeHoldsWrite))
+		r.Use(middlew.RateLimit(limiter, models.RateLimitPolicyWallet))
+
+		r.With(middlew.Idempotency(idempotency)).Post("/api/v1/holds", holdHandler.CreateHold)
+		r.With(
+			middlew.RequireVerifiedEmail,
+			middlew.RequireStepUpAbove(stepUp),
+			middlew.Idempotency(idempotency),
+		).Post("/api/v1/holds/{holdID}/capture", holdHandler.CaptureHold)
+		r.Post("/api/v1/holds/{holdID}/release", holdHandler.ReleaseHold)
+	})
+}
+
+func (a *App) BuildAdminLayer() error {
+	if a.authService == nil {
+		err := errors.New("authService not initialized, call BuildAuthLayer first")
+		a.log.Error(err.Error())
+		return err
+	}
+
+	txManager := service.NewPgxTxManager(a.pool)
+	userRepo := postgres.NewUserRepository(a.pool)
+	walletRepo := postgres.NewWalletRepository(a.pool)
+	ledgerRepo := postgres.NewLedgerRepository(a.pool)
+	auditRepo := postgres.NewAuditRepository(a.pool)
+	limitRepo := postgres.NewLimitRepository(a.pool)
+
+	walletService := service.NewWalletService(walletRepo, ledgerRepo, service.NewLimitService(limitRepo, a.currencies), txManager, a.currencies, service.NewPolicy())
+	adminService := service.NewAdminService(
+		userRepo,
+		walletRepo,
+		ledgerRepo,
+		auditRepo,
+		limitRepo,
+		txManager,
+		walletService,
+		a.currencies,
+		a.auditEvents,
+		a.log,
+	)
+	adminHandler := handlers.NewAdminHandler(adminService)
+	auditEventHandler := handlers.NewAuditEventHandler(a.auditEvents)
+
+	a.reconciliation = service.NewReconciliationService(
+		postgres.NewReconciliationRepository(a.pool),
+		postgres.NewOutboxRepository(a.pool),
+		auditRepo,
+		txManager,
+		a.currencies,
+		a.cfg.Reconciliation.Interval,
+		a.cfg.Reconciliation.AlertsEnabled,
+		a.log,
+	)
+	a.reconciliation.Start()
+	reconciliationHandler := handlers.NewReconciliationHandler(a.reconciliation)
 
 	a.server.Router.Group(func(r chi.Router) {
 		r.Use(middlew.RequireAuth(a.authService))
-		r.Post("/api/v1/exchange", exchangeHandler.ExchangeCurrency)
+		r.Use(middlew.RequireRole(models.RoleSupport, models.RoleAdmin))
+
+		r.Get("/api/v1/admin/users", adminHandler.SearchUsers)
+		r.Get("/api/v1/admin/users/{userID}", adminHandler.GetUser)
+		r.Get("/api/v1/admin/users/{userID}/transactions", adminHandler.GetUserTransactions)
+		r.Get("/api/v1/admin/users/{userID}/limits", adminHandler.GetUserLimits)
+		r.Get("/api/v1/admin/reconciliation/reports", reconciliationHandler.ListReconciliationReports)
+		r.Get("/api/v1/admin/reconciliation/reports/{reportID}", reconciliationHandler.GetReconciliationReport)
+		r.Get("/api/v1/admin/reconciliation/reports/{reportID}/export", reconciliationHandler.ExportReconciliationReport)
+		r.Get("/api/v1/admin/audit-events", auditEventHandler.SearchAuditEvents)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlew.RequireRole(models.RoleAdmin))
+
+			r.Post("/api/v1/admin/wallets/{walletID}/freeze", adminHandler.FreezeWallet)
+			r.Post("/api/v1/admin/wallets/{walletID}/unfreeze", adminHandler.UnfreezeWallet)
+			r.Post("/api/v1/admin/wallets/{walletID}/adjustments", adminHandler.AdjustBalance)
+			r.Put("/api/v1/admin/users/{userID}/role", adminHandler.SetUserRole)
+			r.Post("/api/v1/admin/users/{userID}/unlock", adminHandler.UnlockUser)
+			r.Put("/api/v1/admin/users/{userID}/limits", adminHandler.SetUserLimit)
+			r.Post("/api/v1/admin/reconciliation/runs", reconciliationHandler.StartReconciliation)
+			r.Get("/api/v1/admin/audit-events/verify", auditEventHandler.VerifyAuditChain)
+		})
 	})
 
-	a.log.Info("слой 'exchange' собран и маршруты зарегистрированы")
+	a.log.Info("слой 'admin' собран и маршруты зарегистрированы")
 	return nil
 }
 
@@ -223,15 +793,60 @@ func (a *App) Run() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if a.exchangeService != nil {
-		a.log.Info("остановка exchange service")
-		if err := a.exchangeService.Shutdown(ctx); err != nil {
-			a.log.Error("ошибка при остановке exchange service", slog.String("error", err.Error()))
+	if err := a.server.Shutdown(ctx); err != nil {
+		a.log.Error("ошибка при остановке http сервера", slog.String("error", err.Error()))
+	}
+
+	if a.outboxRelay != nil {
+		// Неотправленные события остаются в outbox и будут отправлены после перезапуска
+		a.log.Info("остановка outbox relay")
+		if err := a.outboxRelay.Shutdown(ctx); err != nil {
+			a.log.Error("ошибка при остановке outbox relay", slog.String("error", err.Error()))
 		}
 	}
 
-	if err := a.server.Shutdown(ctx); err != nil {
-		a.log.Error("ошибка при остановке http сервера", slog.String("error", err.Error()))
+	if a.idempotency != nil {
+		if err := a.idempotency.Shutdown(ctx); err != nil {
+			a.log.Error("ошибка при остановке очистки ключей идемпотентности", slog.String("error", err.Error()))
+		}
+	}
+
+	if a.rateLimiter != nil {
+		if err := a.rateLimiter.Shutdown(ctx); err != nil {
+			a.log.Error("ошибка при остановке очистки корзин ограничения частоты", slog.String("error", err.Error()))
+		}
+	}
+
+	if a.limitOrders != nil {
+		if err := a.limitOrders.Shutdown(ctx); err != nil {
+			a.log.Error("ошибка при остановке исполнения лимитных ордеров", slog.String("error", err.Error()))
+		}
+	}
+
+	if a.schedules != nil {
+		if err := a.schedules.Shutdown(ctx); err != nil {
+			a.log.Error("ошибка при остановке планировщика обменов", slog.String("error", err.Error()))
+		}
+	}
+
+	if a.reconciliation != nil {
+		// Прерванный прогон помечается неудачным, следующий прогон начнется заново
+		if err := a.reconciliation.Shutdown(ctx); err != nil {
+			a.log.Error("ошибка при остановке сверки балансов", slog.String("error", err.Error()))
+		}
+	}
+
+	if a.holds != nil {
+		if err := a.holds.Shutdown(ctx); err != nil {
+			a.log.Error("ошибка при остановке освобождения истекших холдов", slog.String("error", err.Error()))
+		}
+	}
+
+	if a.keyRotation != nil {
+		a.log.Info("остановка ротации ключей подписи")
+		if err := a.keyRotation.Shutdown(ctx); err != nil {
+			a.log.Error("ошибка при остановке ротации ключей", slog.String("error", err.Error()))
+		}
 	}
 
 	if a.kafkaProducer != nil {
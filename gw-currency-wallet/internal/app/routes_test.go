@@ -0,0 +1,964 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/api/handlers"
+	"gw-currency-wallet/internal/api/middlew"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/service"
+	"gw-currency-wallet/internal/storage/postgres"
+)
+
+// stubAuth принимает токены вида "<name>" из заранее заданного набора субъектов
+type stubAuth struct {
+	service.Auth
+	claims map[string]*models.JWTClaims
+}
+
+func (a *stubAuth) ValidateToken(ctx context.Context, token string) (*models.JWTClaims, error) {
+	claims, ok := a.claims[token]
+	if !ok {
+		return nil, custom_err.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// AuthenticateAPIKey принимает ключи из того же набора; ключ "gwk_offsite" отклоняется по адресу
+func (a *stubAuth) AuthenticateAPIKey(ctx context.Context, key string, ip netip.Addr) (*models.JWTClaims, error) {
+	if key == models.APIKeyPrefix+"offsite" {
+		return nil, custom_err.ErrAPIKeyIPNotAllowed
+	}
+	return a.ValidateToken(ctx, key)
+}
+
+// stubWalletRepo отдает кошельки из памяти; остальные методы в этих тестах не вызываются
+type stubWalletRepo struct {
+	postgres.WalletRepository
+	wallets map[uuid.UUID]*models.Wallet
+}
+
+func (r *stubWalletRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+	wallet, ok := r.wallets[id]
+	if !ok {
+		return nil, custom_err.ErrNotFound
+	}
+	return wallet, nil
+}
+
+// recordingWallet запоминает, от чьего имени выполнялись операции над собственными ресурсами.
+// GetWalletByID обслуживает настоящий WalletService с политикой доступа.
+type recordingWallet struct {
+	service.Wallet
+	subjects   []uuid.UUID
+	depositErr error
+}
+
+func (w *recordingWallet) GetUserBalance(ctx context.Context, userID uuid.UUID) (*models.BalanceResponse, error) {
+	w.subjects = append(w.subjects, userID)
+	return &models.BalanceResponse{}, nil
+}
+
+func (w *recordingWallet) Deposit(ctx context.Context, userID uuid.UUID, req models.DepositRequest) (*models.BalanceOperationResponse, error) {
+	w.subjects = append(w.subjects, userID)
+	if w.depositErr != nil {
+		return nil, w.depositErr
+	}
+	return &models.BalanceOperationResponse{Message: fmt.Sprintf("deposit #%d", len(w.subjects))}, nil
+}
+
+func (w *recordingWallet) Withdraw(ctx context.Context, userID uuid.UUID, req models.WithdrawRequest) (*models.BalanceOperationResponse, error) {
+	w.subjects = append(w.subjects, userID)
+	return &models.BalanceOperationResponse{}, nil
+}
+
+func (w *recordingWallet) GetTransactions(ctx context.Context, userID uuid.UUID, req models.TransactionHistoryRequest) (*models.TransactionHistoryResponse, error) {
+	w.subjects = append(w.subjects, userID)
+	return &models.TransactionHistoryResponse{}, nil
+}
+
+// memIdempotencyRepo хранилище ключей идемпотентности в памяти
+type memIdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+func newTestIdempotency() service.Idempotency {
+	repo := &memIdempotencyRepo{records: make(map[string]*models.IdempotencyRecord)}
+	return service.NewIdempotencyService(repo, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// newTestRateLimiter ограничение частоты с корзинами в памяти; без политик запросы не ограничиваются
+func newTestRateLimiter(policies map[string]models.RateLimit) service.RateLimiter {
+	return service.NewRateLimitService(service.NewMemoryRateLimitStore(), policies, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func (r *memIdempotencyRepo) Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, ttl, lockTimeout time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.records[userID.String()+key]; ok {
+		return false, nil
+	}
+	r.records[userID.String()+key] = &models.IdempotencyRecord{UserID: userID, Key: key, Fingerprint: fingerprint}
+	return true, nil
+}
+
+func (r *memIdempotencyRepo) Get(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec, ok := r.records[userID.String()+key]
+	if !ok {
+		return nil, custom_err.ErrNotFound
+	}
+	copied := *rec
+	return &copied, nil
+}
+
+func (r *memIdempotencyRepo) SaveResponse(ctx context.Context, userID uuid.UUID, key string, status int, contentType string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := r.records[userID.String()+key]
+	rec.StatusCode, rec.ContentType, rec.Body = status, contentType, body
+	return nil
+}
+
+func (r *memIdempotencyRepo) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rec, ok := r.records[userID.String()+key]; ok && !rec.Completed() {
+		delete(r.records, userID.String()+key)
+	}
+	return nil
+}
+
+func (r *memIdempotencyRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+type recordingExchange struct {
+	subjects []uuid.UUID
+}
+
+func (e *recordingExchange) GetExchangeRates(ctx context.Context) (map[string]decimal.Decimal, error) {
+	return map[string]decimal.Decimal{"USD": decimal.NewFromInt(1)}, nil
+}
+
+func (e *recordingExchange) QuoteExchange(ctx context.Context, userID uuid.UUID, req models.ExchangeQuoteRequest) (*models.ExchangeQuoteResponse, error) {
+	e.subjects = append(e.subjects, userID)
+	return &models.ExchangeQuoteResponse{}, nil
+}
+
+func (e *recordingExchange) ExchangeCurrency(ctx context.Context, userID uuid.UUID, req models.ExchangeRequest) (*models.ExchangeResponse, error) {
+	e.subjects = append(e.subjects, userID)
+	return &models.ExchangeResponse{}, nil
+}
+
+func (e *recordingExchange) CreateOrder(ctx context.Context, userID uuid.UUID, req models.CreateLimitOrderRequest) (*models.LimitOrderResponse, error) {
+	e.subjects = append(e.subjects, userID)
+	return &models.LimitOrderResponse{}, nil
+}
+
+func (e *recordingExchange) ListOrders(ctx context.Context, userID uuid.UUID, req models.LimitOrderListRequest) (*models.LimitOrderListResponse, error) {
+	e.subjects = append(e.subjects, userID)
+	return &models.LimitOrderListResponse{}, nil
+}
+
+func (e *recordingExchange) CancelOrder(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.LimitOrderResponse, error) {
+	e.subjects = append(e.subjects, actor.UserID)
+	return &models.LimitOrderResponse{}, nil
+}
+
+func (e *recordingExchange) CreateSchedule(ctx context.Context, userID uuid.UUID, req models.CreateScheduledExchangeRequest) (*models.ScheduledExchangeResponse, error) {
+	e.subjects = append(e.subjects, userID)
+	return &models.ScheduledExchangeResponse{}, nil
+}
+
+func (e *recordingExchange) ListSchedules(ctx context.Context, userID uuid.UUID, req models.ScheduledExchangeListRequest) (*models.ScheduledExchangeListResponse, error) {
+	e.subjects = append(e.subjects, userID)
+	return &models.ScheduledExchangeListResponse{}, nil
+}
+
+func (e *recordingExchange) CancelSchedule(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.ScheduledExchangeResponse, error) {
+	e.subjects = append(e.subjects, actor.UserID)
+	return &models.ScheduledExchangeResponse{}, nil
+}
+
+func (e *recordingExchange) ListRuns(ctx context.Context, actor *models.JWTClaims, id uuid.UUID, limit int) (*models.ScheduledExchangeRunListResponse, error) {
+	e.subjects = append(e.subjects, actor.UserID)
+	return &models.ScheduledExchangeRunListResponse{}, nil
+}
+
+func TestRoutes_ResourceAuthorization(t *testing.T) {
+	alice := &models.JWTClaims{UserID: uuid.New(), Username: "alice", Role: models.RoleUser, EmailVerified: true}
+	bob := &models.JWTClaims{UserID: uuid.New(), Username: "bob", Role: models.RoleUser, EmailVerified: true}
+	carol := &models.JWTClaims{UserID: uuid.New(), Username: "carol", Role: models.RoleUser}
+	support := &models.JWTClaims{UserID: uuid.New(), Username: "helpdesk", Role: models.RoleSupport}
+	admin := &models.JWTClaims{UserID: uuid.New(), Username: "root", Role: models.RoleAdmin}
+
+	aliceWallet := &models.Wallet{ID: uuid.New(), UserID: alice.UserID, Currency: "USD", Balance: 1000}
+	missingWallet := uuid.New()
+
+	auth := &stubAuth{claims: map[string]*models.JWTClaims{
+		"alice": alice, "bob": bob, "carol": carol, "support": support, "admin": admin,
+	}}
+	repo := &stubWalletRepo{wallets: map[uuid.UUID]*models.Wallet{aliceWallet.ID: aliceWallet}}
+
+	walletPath := "/api/v1/wallets/" + aliceWallet.ID.String()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		token       string
+		wantStatus  int
+		wantSubject *models.JWTClaims
+	}{
+		{name: "owner reads own wallet", method: http.MethodGet, path: walletPath, token: "alice", wantStatus: http.StatusOK},
+		{name: "other user gets not found", method: http.MethodGet, path: walletPath, token: "bob", wantStatus: http.StatusNotFound},
+		{name: "support reads any wallet", method: http.MethodGet, path: walletPath, token: "support", wantStatus: http.StatusOK},
+		{name: "admin reads any wallet", method: http.MethodGet, path: walletPath, token: "admin", wantStatus: http.StatusOK},
+		{name: "missing wallet", method: http.MethodGet, path: "/api/v1/wallets/" + missingWallet.String(), token: "alice", wantStatus: http.StatusNotFound},
+		{name: "wallet without token", method: http.MethodGet, path: walletPath, wantStatus: http.StatusUnauthorized},
+
+		{name: "balance", method: http.MethodGet, path: "/api/v1/balance", token: "bob", wantStatus: http.StatusOK, wantSubject: bob},
+		{name: "balance without token", method: http.MethodGet, path: "/api/v1/balance", wantStatus: http.StatusUnauthorized},
+		{name: "deposit", method: http.MethodPost, path: "/api/v1/wallet/deposit", token: "bob",
+			body: `{"amount":"1","currency":"USD","request_id":"r1"}`, wantStatus: http.StatusOK, wantSubject: bob},
+		{name: "deposit without token", method: http.MethodPost, path: "/api/v1/wallet/deposit",
+			body: `{"amount":"1","currency":"USD","request_id":"r1"}`, wantStatus: http.StatusUnauthorized},
+		{name: "withdraw", method: http.MethodPost, path: "/api/v1/wallet/withdraw", token: "alice",
+			body: `{"amount":"1","currency":"USD","request_id":"r2"}`, wantStatus: http.StatusOK, wantSubject: alice},
+		{name: "withdraw without token", method: http.MethodPost, path: "/api/v1/wallet/withdraw",
+			body: `{"amount":"1","currency":"USD","request_id":"r2"}`, wantStatus: http.StatusUnauthorized},
+		{name: "withdraw with unverified email", method: http.MethodPost, path: "/api/v1/wallet/withdraw", token: "carol",
+			body: `{"amount":"1","currency":"USD","request_id":"r2"}`, wantStatus: http.StatusForbidden},
+		{name: "deposit with unverified email", method: http.MethodPost, path: "/api/v1/wallet/deposit", token: "carol",
+			body: `{"amount":"1","currency":"USD","request_id":"r1"}`, wantStatus: http.StatusOK, wantSubject: carol},
+		{name: "transactions", method: http.MethodGet, path: "/api/v1/wallet/transactions", token: "admin", wantStatus: http.StatusOK, wantSubject: admin},
+		{name: "transactions without token", method: http.MethodGet, path: "/api/v1/wallet/transactions", wantStatus: http.StatusUnauthorized},
+
+		{name: "exchange rates are public", method: http.MethodGet, path: "/api/v1/exchange/rates", wantStatus: http.StatusOK},
+		{name: "exchange", method: http.MethodPost, path: "/api/v1/exchange", token: "alice",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"1","request_id":"r3"}`, wantStatus: http.StatusOK, wantSubject: alice},
+		{name: "exchange without token", method: http.MethodPost, path: "/api/v1/exchange",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"1","request_id":"r3"}`, wantStatus: http.StatusUnauthorized},
+		{name: "exchange quote", method: http.MethodPost, path: "/api/v1/exchange/quote", token: "bob",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"1"}`, wantStatus: http.StatusOK, wantSubject: bob},
+		{name: "exchange quote without token", method: http.MethodPost, path: "/api/v1/exchange/quote",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"1"}`, wantStatus: http.StatusUnauthorized},
+		{name: "exchange with invalid token", method: http.MethodPost, path: "/api/v1/exchange", token: "mallory",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"1","request_id":"r3"}`, wantStatus: http.StatusUnauthorized},
+		{name: "create limit order", method: http.MethodPost, path: "/api/v1/exchange/orders", token: "alice",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"1","rate":"0.95","requestID":"o1"}`, wantStatus: http.StatusCreated, wantSubject: alice},
+		{name: "create limit order without token", method: http.MethodPost, path: "/api/v1/exchange/orders",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"1","rate":"0.95","requestID":"o1"}`, wantStatus: http.StatusUnauthorized},
+		{name: "list limit orders", method: http.MethodGet, path: "/api/v1/exchange/orders?status=open", token: "bob", wantStatus: http.StatusOK, wantSubject: bob},
+		{name: "list limit orders with invalid limit", method: http.MethodGet, path: "/api/v1/exchange/orders?limit=x", token: "bob", wantStatus: http.StatusBadRequest},
+		{name: "cancel limit order", method: http.MethodPost, path: "/api/v1/exchange/orders/" + uuid.NewString() + "/cancel", token: "alice", wantStatus: http.StatusOK, wantSubject: alice},
+		{name: "cancel limit order with invalid id", method: http.MethodPost, path: "/api/v1/exchange/orders/42/cancel", token: "alice", wantStatus: http.StatusBadRequest},
+		{name: "create scheduled exchange", method: http.MethodPost, path: "/api/v1/exchange/schedules", token: "alice",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"100","cron":"0 9 * * 1","requestID":"s1"}`, wantStatus: http.StatusCreated, wantSubject: alice},
+		{name: "create scheduled exchange without token", method: http.MethodPost, path: "/api/v1/exchange/schedules",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"100","cron":"0 9 * * 1","requestID":"s1"}`, wantStatus: http.StatusUnauthorized},
+		{name: "list scheduled exchanges", method: http.MethodGet, path: "/api/v1/exchange/schedules", token: "bob", wantStatus: http.StatusOK, wantSubject: bob},
+		{name: "cancel scheduled exchange", method: http.MethodPost, path: "/api/v1/exchange/schedules/" + uuid.NewString() + "/cancel", token: "alice", wantStatus: http.StatusOK, wantSubject: alice},
+		{name: "list scheduled exchange runs", method: http.MethodGet, path: "/api/v1/exchange/schedules/" + uuid.NewString() + "/runs?limit=5", token: "bob", wantStatus: http.StatusOK, wantSubject: bob},
+		{name: "list scheduled exchange runs with invalid id", method: http.MethodGet, path: "/api/v1/exchange/schedules/42/runs", token: "bob", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallets := &recordingWallet{Wallet: service.NewWalletService(repo, nil, nil, nil, nil, service.NewPolicy())}
+			exchange := &recordingExchange{}
+
+			router := chi.NewRouter()
+			idempotency := newTestIdempotency()
+			stepUp := service.NewStepUpPolicy(nil, nil, nil, nil, 0)
+			registerWalletRoutes(router, auth, idempotency, stepUp, newTestRateLimiter(nil), handlers.NewWalletHandler(wallets))
+			registerExchangeRoutes(router, auth, idempotency, stepUp, newTestRateLimiter(nil), handlers.NewExchangeHandler(exchange), handlers.NewLimitOrderHandler(exchange), handlers.NewScheduledExchangeHandler(exchange))
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+
+			subjects := append(wallets.subjects, exchange.subjects...)
+			if tt.wantSubject == nil {
+				assert.Empty(t, subjects)
+				return
+			}
+			assert.Equal(t, []uuid.UUID{tt.wantSubject.UserID}, subjects)
+		})
+	}
+}
+
+func TestRoutes_IdempotencyKey(t *testing.T) {
+	alice := &models.JWTClaims{UserID: uuid.New(), Username: "alice", Role: models.RoleUser}
+	bob := &models.JWTClaims{UserID: uuid.New(), Username: "bob", Role: models.RoleUser}
+	auth := &stubAuth{claims: map[string]*models.JWTClaims{"alice": alice, "bob": bob}}
+
+	wallets := &recordingWallet{}
+	router := chi.NewRouter()
+	registerWalletRoutes(router, auth, newTestIdempotency(), service.NewStepUpPolicy(nil, nil, nil, nil, 0), newTestRateLimiter(nil), handlers.NewWalletHandler(wallets))
+
+	deposit := func(token, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/deposit", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if key != "" {
+			req.Header.Set(models.IdempotencyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	body := `{"amount":"1","currency":"USD","request_id":"r1"}`
+
+	first := deposit("alice", "key-1", body)
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+	assert.Empty(t, first.Header().Get(models.IdempotencyReplayedHeader))
+
+	replayed := deposit("alice", "key-1", body)
+	assert.Equal(t, http.StatusOK, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get(models.IdempotencyReplayedHeader))
+	assert.Equal(t, first.Header().Get("Content-Type"), replayed.Header().Get("Content-Type"))
+	assert.JSONEq(t, first.Body.String(), replayed.Body.String())
+	assert.Len(t, wallets.subjects, 1, "retry must not execute the operation again")
+
+	conflict := deposit("alice", "key-1", `{"amount":"2","currency":"USD","request_id":"r2"}`)
+	assert.Equal(t, http.StatusConflict, conflict.Code)
+	assert.Contains(t, conflict.Body.String(), "idempotency_key_reused")
+
+	// Ключи принадлежат пользователю
+	other := deposit("bob", "key-1", body)
+	assert.Equal(t, http.StatusOK, other.Code)
+	assert.Empty(t, other.Header().Get(models.IdempotencyReplayedHeader))
+	assert.Len(t, wallets.subjects, 2)
+
+	tooLong := deposit("alice", strings.Repeat("k", 256), body)
+	assert.Equal(t, http.StatusBadRequest, tooLong.Code)
+	assert.Len(t, wallets.subjects, 2)
+
+	// Ответ 5xx не сохраняется: повтор с тем же ключом выполняет операцию снова
+	wallets.depositErr = errors.New("db down")
+	failed := deposit("alice", "key-2", body)
+	assert.Equal(t, http.StatusInternalServerError, failed.Code)
+	wallets.depositErr = nil
+	retried := deposit("alice", "key-2", body)
+	assert.Equal(t, http.StatusOK, retried.Code)
+	assert.Empty(t, retried.Header().Get(models.IdempotencyReplayedHeader))
+	assert.Len(t, wallets.subjects, 4)
+}
+
+// stubMFA обслуживает маршруты управления TOTP
+type stubMFA struct {
+	service.MFA
+	disabled []uuid.UUID
+}
+
+func (m *stubMFA) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	m.disabled = append(m.disabled, userID)
+	return nil
+}
+
+func TestRoutes_StepUp(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+	plain := &models.JWTClaims{UserID: userID, Username: "dave", Role: models.RoleUser, EmailVerified: true,
+		AMR: []string{models.AMRPassword}, AuthTime: jwt.NewNumericDate(now)}
+	steppedUp := &models.JWTClaims{UserID: userID, Username: "dave", Role: models.RoleUser, EmailVerified: true,
+		AMR: []string{models.AMRPassword, models.AMROTP}, AuthTime: jwt.NewNumericDate(now)}
+	stale := &models.JWTClaims{UserID: userID, Username: "dave", Role: models.RoleUser, EmailVerified: true,
+		AMR: []string{models.AMRPassword, models.AMROTP}, AuthTime: jwt.NewNumericDate(now.Add(-time.Hour))}
+	auth := &stubAuth{claims: map[string]*models.JWTClaims{"plain": plain, "stepped": steppedUp, "stale": stale}}
+
+	thresholds := map[models.Currency]decimal.Decimal{"USD": decimal.NewFromInt(1000)}
+	stepUp := service.NewStepUpPolicy(nil, nil, nil, thresholds, 5*time.Minute)
+
+	wallets := &recordingWallet{}
+	exchange := &recordingExchange{}
+	mfa := &stubMFA{}
+	router := chi.NewRouter()
+	idempotency := newTestIdempotency()
+	registerWalletRoutes(router, auth, idempotency, stepUp, newTestRateLimiter(nil), handlers.NewWalletHandler(wallets))
+	registerExchangeRoutes(router, auth, idempotency, stepUp, newTestRateLimiter(nil), handlers.NewExchangeHandler(exchange), handlers.NewLimitOrderHandler(exchange), handlers.NewScheduledExchangeHandler(exchange))
+	registerMFARoutes(router, auth, 5*time.Minute, handlers.NewAuthHandler(auth), handlers.NewMFAHandler(mfa))
+
+	do := func(path, token, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if key != "" {
+			req.Header.Set(models.IdempotencyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{name: "withdraw below threshold", path: "/api/v1/wallet/withdraw", token: "plain",
+			body: `{"amount":"1000","currency":"USD","requestID":"w1"}`, wantStatus: http.StatusOK},
+		{name: "withdraw above threshold", path: "/api/v1/wallet/withdraw", token: "plain",
+			body: `{"amount":"1000.01","currency":"USD","requestID":"w2"}`, wantStatus: http.StatusForbidden},
+		{name: "withdraw above threshold after step-up", path: "/api/v1/wallet/withdraw", token: "stepped",
+			body: `{"amount":"5000","currency":"USD","requestID":"w3"}`, wantStatus: http.StatusOK},
+		{name: "withdraw above threshold with stale step-up", path: "/api/v1/wallet/withdraw", token: "stale",
+			body: `{"amount":"5000","currency":"USD","requestID":"w4"}`, wantStatus: http.StatusForbidden},
+		{name: "withdraw in currency without threshold", path: "/api/v1/wallet/withdraw", token: "plain",
+			body: `{"amount":"5000","currency":"EUR","requestID":"w5"}`, wantStatus: http.StatusOK},
+		{name: "exchange above threshold", path: "/api/v1/exchange", token: "plain",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"2000","requestID":"e1"}`, wantStatus: http.StatusForbidden},
+		{name: "limit order above threshold", path: "/api/v1/exchange/orders", token: "plain",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"2000","rate":"0.95","requestID":"o1"}`, wantStatus: http.StatusForbidden},
+		{name: "scheduled exchange above threshold", path: "/api/v1/exchange/schedules", token: "plain",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"2000","cron":"@daily","requestID":"s1"}`, wantStatus: http.StatusForbidden},
+		{name: "withdraw above threshold with trailing data", path: "/api/v1/wallet/withdraw", token: "plain",
+			body: `{"amount":"100000","currency":"USD","requestID":"w7"} x`, wantStatus: http.StatusBadRequest},
+		{name: "exchange above threshold with trailing data", path: "/api/v1/exchange", token: "plain",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"2000","requestID":"e2"} {}`, wantStatus: http.StatusBadRequest},
+		{name: "exchange quote is not checked", path: "/api/v1/exchange/quote", token: "plain",
+			body: `{"from_currency":"USD","to_currency":"EUR","amount":"2000"}`, wantStatus: http.StatusOK},
+		{name: "disable totp without step-up", path: "/api/v1/mfa/totp/disable", token: "plain", wantStatus: http.StatusForbidden},
+		{name: "disable totp with stale step-up", path: "/api/v1/mfa/totp/disable", token: "stale", wantStatus: http.StatusForbidden},
+		{name: "disable totp after step-up", path: "/api/v1/mfa/totp/disable", token: "stepped", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.path, tt.token, "", tt.body)
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			switch tt.wantStatus {
+			case http.StatusForbidden:
+				assert.Contains(t, rec.Body.String(), "step_up_required")
+			case http.StatusBadRequest:
+				assert.Contains(t, rec.Body.String(), "invalid_json")
+			}
+		})
+	}
+	assert.Equal(t, []uuid.UUID{userID}, mfa.disabled)
+
+	// Отказ не сохраняется как ответ на Idempotency-Key: после step-up запрос с тем же ключом выполняется
+	body := `{"amount":"5000","currency":"USD","requestID":"w6"}`
+	before := len(wallets.subjects)
+	refused := do("/api/v1/wallet/withdraw", "plain", "key-1", body)
+	require.Equal(t, http.StatusForbidden, refused.Code)
+	accepted := do("/api/v1/wallet/withdraw", "stepped", "key-1", body)
+	assert.Equal(t, http.StatusOK, accepted.Code, accepted.Body.String())
+	assert.Empty(t, accepted.Header().Get(models.IdempotencyReplayedHeader))
+	assert.Len(t, wallets.subjects, before+1)
+}
+
+// stubAPIKeys список ключей без хранилища
+type stubAPIKeys struct {
+	service.APIKeys
+}
+
+func (s *stubAPIKeys) List(ctx context.Context, userID uuid.UUID) (*models.APIKeyListResponse, error) {
+	return &models.APIKeyListResponse{APIKeys: []models.APIKeyResponse{}}, nil
+}
+
+func TestRoutes_APIKeyScopes(t *testing.T) {
+	userID := uuid.New()
+	readerID, traderID := uuid.New(), uuid.New()
+	user := &models.JWTClaims{UserID: userID, Username: "erin", Role: models.RoleUser, EmailVerified: true}
+	reader := &models.JWTClaims{UserID: userID, Username: "erin", Role: models.RoleUser, EmailVerified: true,
+		APIKeyID: &readerID, Scopes: []models.APIKeyScope{models.ScopeBalanceRead, models.ScopeExchangeRead}}
+	trader := &models.JWTClaims{UserID: userID, Username: "erin", Role: models.RoleUser, EmailVerified: true,
+		APIKeyID: &traderID, Scopes: []models.APIKeyScope{models.ScopeExchangeWrite, models.ScopeWalletWrite}}
+	auth := &stubAuth{claims: map[string]*models.JWTClaims{
+		"erin": user, models.APIKeyPrefix + "reader": reader, models.APIKeyPrefix + "trader": trader,
+	}}
+
+	thresholds := map[models.Currency]decimal.Decimal{"USD": decimal.NewFromInt(1000)}
+	stepUp := service.NewStepUpPolicy(nil, nil, nil, thresholds, 5*time.Minute)
+
+	wallets := &recordingWallet{}
+	exchange := &recordingExchange{}
+	router := chi.NewRouter()
+	idempotency := newTestIdempotency()
+	registerWalletRoutes(router, auth, idempotency, stepUp, newTestRateLimiter(nil), handlers.NewWalletHandler(wallets))
+	registerExchangeRoutes(router, auth, idempotency, stepUp, newTestRateLimiter(nil), handlers.NewExchangeHandler(exchange), handlers.NewLimitOrderHandler(exchange), handlers.NewScheduledExchangeHandler(exchange))
+	registerMFARoutes(router, auth, 5*time.Minute, handlers.NewAuthHandler(auth), handlers.NewMFAHandler(&stubMFA{}))
+	registerAPIKeyRoutes(router, auth, handlers.NewAPIKeyHandler(&stubAPIKeys{}))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		token      string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "jwt reads balance", method: http.MethodGet, path: "/api/v1/balance", token: "erin", wantStatus: http.StatusOK},
+		{name: "jwt deposits", method: http.MethodPost, path: "/api/v1/wallet/deposit", token: "erin",
+			body: `{"amount":"10","currency":"USD","requestID":"d1"}`, wantStatus: http.StatusOK},
+		{name: "key with balance:read reads balance", method: http.MethodGet, path: "/api/v1/balance",
+			token: "gwk_reader", wantStatus: http.StatusOK},
+		{name: "key with balance:read reads transactions", method: http.MethodGet, path: "/api/v1/wallet/transactions",
+			token: "gwk_reader", wantStatus: http.StatusOK},
+		{name: "key without wallet:write cannot deposit", method: http.MethodPost, path: "/api/v1/wallet/deposit",
+			token: "gwk_reader", body: `{"amount":"10","currency":"USD","requestID":"d2"}`,
+			wantStatus: http.StatusForbidden, wantCode: "insufficient_scope"},
+		{name: "key with wallet:write deposits", method: http.MethodPost, path: "/api/v1/wallet/deposit",
+			token: "gwk_trader", body: `{"amount":"10","currency":"USD","requestID":"d3"}`, wantStatus: http.StatusOK},
+		{name: "key without balance:read cannot read balance", method: http.MethodGet, path: "/api/v1/balance",
+			token: "gwk_trader", wantStatus: http.StatusForbidden, wantCode: "insufficient_scope"},
+		{name: "key with exchange:read lists orders", method: http.MethodGet, path: "/api/v1/exchange/orders",
+			token: "gwk_reader", wantStatus: http.StatusOK},
+		{name: "key without exchange:write cannot exchange", method: http.MethodPost, path: "/api/v1/exchange",
+			token: "gwk_reader", body: `{"from_currency":"USD","to_currency":"EUR","amount":"10","requestID":"e1"}`,
+			wantStatus: http.StatusForbidden, wantCode: "insufficient_scope"},
+		{name: "key with exchange:write exchanges", method: http.MethodPost, path: "/api/v1/exchange",
+			token: "gwk_trader", body: `{"from_currency":"USD","to_currency":"EUR","amount":"10","requestID":"e2"}`, wantStatus: http.StatusOK},
+		{name: "key cannot skip step-up", method: http.MethodPost, path: "/api/v1/exchange",
+			token: "gwk_trader", body: `{"from_currency":"USD","to_currency":"EUR","amount":"5000","requestID":"e3"}`,
+			wantStatus: http.StatusForbidden, wantCode: "step_up_required"},
+		{name: "key cannot manage api keys", method: http.MethodGet, path: "/api/v1/api-keys",
+			token: "gwk_trader", wantStatus: http.StatusForbidden, wantCode: "insufficient_scope"},
+		{name: "key cannot manage mfa", method: http.MethodGet, path: "/api/v1/mfa",
+			token: "gwk_reader", wantStatus: http.StatusForbidden, wantCode: "insufficient_scope"},
+		{name: "jwt manages api keys", method: http.MethodGet, path: "/api/v1/api-keys", token: "erin", wantStatus: http.StatusOK},
+		{name: "key from disallowed address", method: http.MethodGet, path: "/api/v1/balance",
+			token: "gwk_offsite", wantStatus: http.StatusForbidden, wantCode: "ip_not_allowed"},
+		{name: "unknown key", method: http.MethodGet, path: "/api/v1/balance",
+			token: "gwk_unknown", wantStatus: http.StatusUnauthorized, wantCode: "invalid_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCode != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+// throttledAuth отклоняет вход, пока не пройдет retryAfter, и запоминает адрес клиента
+type throttledAuth struct {
+	service.Auth
+	retryAfter time.Duration
+	ip         netip.Addr
+}
+
+func (a *throttledAuth) Login(ctx context.Context, req models.LoginRequest) (*models.LoginResponse, error) {
+	a.ip = req.IP
+	return nil, &models.LoginThrottledError{RetryAfter: a.retryAfter}
+}
+
+func TestRoutes_LoginThrottled(t *testing.T) {
+	auth := &throttledAuth{retryAfter: 1500 * time.Millisecond}
+	router := chi.NewRouter()
+	router.Post("/api/v1/login", handlers.NewAuthHandler(auth).Login)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(`{"username":"erin","password":"secret"}`))
+	req.RemoteAddr = "203.0.113.10:51234"
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "too_many_attempts")
+	// Retry-After округляется вверх до целых секунд
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, netip.MustParseAddr("203.0.113.10"), auth.ip)
+}
+
+// stubAuditEvents запоминает, чьи события безопасности запрашивались
+type stubAuditEvents struct {
+	service.AuditEvents
+	subjects []uuid.UUID
+}
+
+func (s *stubAuditEvents) ListOwn(ctx context.Context, userID uuid.UUID, req models.AuditEventListRequest) (*models.AuditEventListResponse, error) {
+	s.subjects = append(s.subjects, userID)
+	if req.Cursor == "bad" {
+		return nil, custom_err.ErrInvalidCursor
+	}
+	return &models.AuditEventListResponse{Events: []models.AuditEventResponse{}}, nil
+}
+
+func TestRoutes_SecurityEvents(t *testing.T) {
+	userID, keyID := uuid.New(), uuid.New()
+	auth := &stubAuth{claims: map[string]*models.JWTClaims{
+		"erin": {UserID: userID, Username: "erin", Role: models.RoleUser},
+		models.APIKeyPrefix + "reader": {UserID: userID, Username: "erin", Role: models.RoleUser,
+			APIKeyID: &keyID, Scopes: models.APIKeyScopes},
+	}}
+	events := &stubAuditEvents{}
+	router := chi.NewRouter()
+	registerSecurityEventRoutes(router, auth, handlers.NewAuditEventHandler(events))
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "jwt lists own events", path: "/api/v1/security/events?limit=10", token: "erin", wantStatus: http.StatusOK},
+		{name: "api key with every scope is rejected", path: "/api/v1/security/events", token: "gwk_reader",
+			wantStatus: http.StatusForbidden, wantCode: "insufficient_scope"},
+		{name: "invalid limit", path: "/api/v1/security/events?limit=ten", token: "erin",
+			wantStatus: http.StatusBadRequest, wantCode: "invalid_field"},
+		{name: "invalid cursor", path: "/api/v1/security/events?cursor=bad", token: "erin",
+			wantStatus: http.StatusBadRequest, wantCode: "invalid_cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCode != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+
+	// События запрашиваются только для владельца токена
+	assert.Equal(t, []uuid.UUID{userID, userID}, events.subjects)
+}
+
+// failingRateLimitStore хранилище корзин, которое недоступно
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit models.RateLimit) (float64, bool, error) {
+	return 0, false, errors.New("db down")
+}
+
+func (failingRateLimitStore) DeleteIdle(ctx context.Context, idle time.Duration) (int64, error) {
+	return 0, errors.New("db down")
+}
+
+func TestRoutes_RateLimit(t *testing.T) {
+	alice := &models.JWTClaims{UserID: uuid.New(), Username: "alice", Role: models.RoleUser}
+	bob := &models.JWTClaims{UserID: uuid.New(), Username: "bob", Role: models.RoleUser}
+	auth := &stubAuth{claims: map[string]*models.JWTClaims{"alice": alice, "bob": bob}}
+	limiter := newTestRateLimiter(map[string]models.RateLimit{
+		models.RateLimitPolicyWallet: {Requests: 2, Period: time.Minute, Burst: 2},
+		models.RateLimitPolicyAuth:   {Requests: 1, Period: time.Minute, Burst: 1},
+	})
+
+	wallets := &recordingWallet{}
+	router := chi.NewRouter()
+	registerWalletRoutes(router, auth, newTestIdempotency(), service.NewStepUpPolicy(nil, nil, nil, nil, 0), limiter, handlers.NewWalletHandler(wallets))
+	router.With(middlew.RateLimit(limiter, models.RateLimitPolicyAuth)).Post("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	deposit := func(token, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet/deposit", strings.NewReader(`{"amount":10,"currency":"USD"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := deposit("alice", "203.0.113.10:1000")
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", first.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "2;w=60", first.Header().Get("RateLimit-Policy"))
+
+	require.Equal(t, http.StatusOK, deposit("alice", "203.0.113.11:1000").Code)
+
+	limited := deposit("alice", "203.0.113.12:1000")
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Contains(t, limited.Body.String(), "rate_limited")
+	assert.Equal(t, "0", limited.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", limited.Header().Get("Retry-After"))
+
+	// Корзина принадлежит пользователю, а не адресу
+	assert.Equal(t, http.StatusOK, deposit("bob", "203.0.113.10:1000").Code)
+	assert.Len(t, wallets.subjects, 3)
+
+	login := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Без токена корзина принадлежит адресу, адреса IPv6 одной сети /64 делят корзину
+	assert.Equal(t, http.StatusNoContent, login("203.0.113.10:1000"))
+	assert.Equal(t, http.StatusTooManyRequests, login("203.0.113.10:2000"))
+	assert.Equal(t, http.StatusNoContent, login("[::ffff:203.0.113.11]:1000"))
+	assert.Equal(t, http.StatusNoContent, login("[2001:db8::1]:1000"))
+	assert.Equal(t, http.StatusTooManyRequests, login("[2001:db8::2]:1000"))
+	assert.Equal(t, http.StatusNoContent, login("[2001:db8:0:1::1]:1000"))
+}
+
+func TestRoutes_RateLimit_StoreUnavailable(t *testing.T) {
+	limiter := service.NewRateLimitService(failingRateLimitStore{}, map[string]models.RateLimit{
+		models.RateLimitPolicyAuth: {Requests: 1, Period: time.Minute, Burst: 1},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	router := chi.NewRouter()
+	router.With(middlew.RateLimit(limiter, models.RateLimitPolicyAuth)).Post("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	for range 3 {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		// Запрос пропускается без заголовков ограничения
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	}
+}
+
+// stubHolds отдает холды из памяти для проверки step-up и запоминает списания
+type stubHolds struct {
+	postgres.HoldRepository
+	service.Holds
+	holds    map[uuid.UUID]*models.Hold
+	captured []uuid.UUID
+}
+
+func (s *stubHolds) GetByID(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	hold, ok := s.holds[id]
+	if !ok {
+		return nil, custom_err.ErrNotFound
+	}
+	return hold, nil
+}
+
+func (s *stubHolds) CaptureHold(ctx context.Context, actor *models.JWTClaims, id uuid.UUID, req models.CaptureHoldRequest) (*models.HoldResponse, error) {
+	s.captured = append(s.captured, id)
+	return &models.HoldResponse{ID: id, Status: models.HoldStatusCaptured}, nil
+}
+
+// stubCurrencies реестр из одной валюты USD
+type stubCurrencies struct{}
+
+func (stubCurrencies) List(ctx context.Context) ([]models.CurrencyInfo, error) {
+	return []models.CurrencyInfo{{Code: "USD", Exponent: 2, Enabled: true}}, nil
+}
+
+func (stubCurrencies) Get(ctx context.Context, code models.Currency) (*models.CurrencyInfo, error) {
+	if code != "USD" {
+		return nil, custom_err.ErrInvalidCurrency
+	}
+	return &models.CurrencyInfo{Code: "USD", Exponent: 2, Enabled: true}, nil
+}
+
+func TestRoutes_HoldCapture(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+	auth := &stubAuth{claims: map[string]*models.JWTClaims{
+		"unverified": {UserID: userID, Username: "frank", Role: models.RoleUser,
+			AMR: []string{models.AMRPassword}, AuthTime: jwt.NewNumericDate(now)},
+		"plain": {UserID: userID, Username: "frank", Role: models.RoleUser, EmailVerified: true,
+			AMR: []string{models.AMRPassword}, AuthTime: jwt.NewNumericDate(now)},
+		"stepped": {UserID: userID, Username: "frank", Role: models.RoleUser, EmailVerified: true,
+			AMR: []string{models.AMRPassword, models.AMROTP}, AuthTime: jwt.NewNumericDate(now)},
+	}}
+
+	hold := &models.Hold{ID: uuid.New(), UserID: userID, Currency: "USD", Amount: 500000}
+	holds := &stubHolds{holds: map[uuid.UUID]*models.Hold{hold.ID: hold}}
+	thresholds := map[models.Currency]decimal.Decimal{"USD": decimal.NewFromInt(1000)}
+	stepUp := service.NewStepUpPolicy(nil, holds, stubCurrencies{}, thresholds, 5*time.Minute)
+
+	router := chi.NewRouter()
+	registerHoldRoutes(router, auth, newTestIdempotency(), stepUp, newTestRateLimiter(nil), handlers.NewHoldHandler(holds))
+
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "unverified email", token: "unverified", body: `{"amount":"10"}`,
+			wantStatus: http.StatusForbidden, wantCode: "email_not_verified"},
+		{name: "full capture above threshold", token: "plain",
+			wantStatus: http.StatusForbidden, wantCode: "step_up_required"},
+		{name: "partial capture below threshold", token: "plain", body: `{"amount":"10"}`, wantStatus: http.StatusOK},
+		{name: "full capture after step-up", token: "stepped", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/holds/"+hold.ID.String()+"/capture", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCode != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+	assert.Len(t, holds.captured, 2)
+}
+
+// recordingTransfer запоминает отправителей выполненных переводов
+type recordingTransfer struct {
+	senders []uuid.UUID
+}
+
+func (s *recordingTransfer) Transfer(ctx context.Context, senderID uuid.UUID, req models.TransferRequest) (*models.TransferResponse, error) {
+	s.senders = append(s.senders, senderID)
+	return &models.TransferResponse{Message: "Transfer successful"}, nil
+}
+
+func TestRoutes_Transfer(t *testing.T) {
+	userID := uuid.New()
+	now := time.Now()
+	auth := &stubAuth{claims: map[string]*models.JWTClaims{
+		"unverified": {UserID: userID, Username: "grace", Role: models.RoleUser,
+			AMR: []string{models.AMRPassword}, AuthTime: jwt.NewNumericDate(now)},
+		"plain": {UserID: userID, Username: "grace", Role: models.RoleUser, EmailVerified: true,
+			AMR: []string{models.AMRPassword}, AuthTime: jwt.NewNumericDate(now)},
+		"stepped": {UserID: userID, Username: "grace", Role: models.RoleUser, EmailVerified: true,
+			AMR: []string{models.AMRPassword, models.AMROTP}, AuthTime: jwt.NewNumericDate(now)},
+	}}
+
+	thresholds := map[models.Currency]decimal.Decimal{"USD": decimal.NewFromInt(1000)}
+	stepUp := service.NewStepUpPolicy(nil, nil, nil, thresholds, 5*time.Minute)
+	transfers := &recordingTransfer{}
+
+	router := chi.NewRouter()
+	registerTransferRoutes(router, auth, newTestIdempotency(), stepUp, newTestRateLimiter(nil), handlers.NewTransferHandler(transfers))
+
+	tests := []struct {
+		name       string
+		token      string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "unverified email", token: "unverified",
+			body:       `{"recipient":"bob","amount":"10","currency":"USD","requestID":"t0"}`,
+			wantStatus: http.StatusForbidden, wantCode: "email_not_verified"},
+		{name: "below threshold", token: "plain",
+			body: `{"recipient":"bob","amount":"1000","currency":"USD","requestID":"t1"}`, wantStatus: http.StatusOK},
+		{name: "above threshold", token: "plain",
+			body:       `{"recipient":"bob","amount":"5000","currency":"USD","requestID":"t2"}`,
+			wantStatus: http.StatusForbidden, wantCode: "step_up_required"},
+		{name: "above threshold after step-up", token: "stepped",
+			body: `{"recipient":"bob","amount":"5000","currency":"USD","requestID":"t3"}`, wantStatus: http.StatusOK},
+		{name: "above threshold with trailing data", token: "plain",
+			body:       `{"recipient":"bob","amount":"5000","currency":"USD","requestID":"t4"} x`,
+			wantStatus: http.StatusBadRequest, wantCode: "invalid_json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCode != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantCode)
+			}
+		})
+	}
+	assert.Len(t, transfers.senders, 2)
+}
+
+func TestRoutes_RealIP(t *testing.T) {
+	trusted, err := middlew.ParseTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.1 ", ""})
+	require.NoError(t, err)
+	_, err = middlew.ParseTrustedProxies([]string{"proxy.local"})
+	assert.Error(t, err)
+
+	router := chi.NewRouter()
+	router.Use(middlew.RealIP(trusted))
+	router.Get("/ip", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, middlew.ClientIP(r).String())
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{name: "forged header from client is ignored", remoteAddr: "203.0.113.10:5000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Real-IP": "198.51.100.8"}, want: "203.0.113.10"},
+		{name: "forwarded by trusted proxy", remoteAddr: "10.1.2.3:5000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7"}, want: "198.51.100.7"},
+		{name: "forged entry left of the real client", remoteAddr: "10.1.2.3:5000",
+			headers: map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.7, 10.4.5.6"}, want: "198.51.100.7"},
+		{name: "real ip from trusted proxy", remoteAddr: "192.0.2.1:5000",
+			headers: map[string]string{"X-Real-IP": "198.51.100.9"}, want: "198.51.100.9"},
+		{name: "malformed header keeps proxy address", remoteAddr: "10.1.2.3:5000",
+			headers: map[string]string{"X-Forwarded-For": "unknown"}, want: "10.1.2.3"},
+		{name: "no headers", remoteAddr: "10.1.2.3:5000", want: "10.1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Body.String())
+		})
+	}
+
+	// Подмена заголовка не дает новой корзины ограничения частоты
+	limiter := newTestRateLimiter(map[string]models.RateLimit{
+		models.RateLimitPolicyAuth: {Requests: 1, Period: time.Minute, Burst: 1},
+	})
+	router.With(middlew.RateLimit(limiter, models.RateLimitPolicyAuth)).Post("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	for i, want := range []int{http.StatusNoContent, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/login", nil)
+		req.RemoteAddr = "203.0.113.10:5000"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code)
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"gw-currency-wallet/internal/jwtkeys"
+	"gw-currency-wallet/internal/models"
 	"log"
 	"time"
 
@@ -10,11 +12,31 @@ import (
 )
 
 type Config struct {
-	HTTPPort string `envconfig:"APP_PORT" default:"8080"`
-	DB       DBConfig
-	JWT      JWTConfig
-	GRPC     GRPCConfig
-	Kafka    KafkaConfig
+	HTTPPort       string `envconfig:"APP_PORT" default:"8080"`
+	DB             DBConfig
+	JWT            JWTConfig
+	GRPC           GRPCConfig
+	Kafka          KafkaConfig
+	Money          MoneyConfig
+	Exchange       ExchangeConfig
+	Idempotency    IdempotencyConfig
+	Hold           HoldConfig
+	Reconciliation ReconciliationConfig
+	Mail           MailConfig
+	Account        AccountConfig
+	MFA            MFAConfig
+	Login          LoginConfig
+	RateLimit      RateLimitConfig
+	Secrets        SecretsConfig
+	// TrustedProxies адреса и сети прокси, которым доверяются заголовки X-Forwarded-For и X-Real-IP,
+	// например 10.0.0.0/8,127.0.0.1; пусто - адрес клиента берется из соединения
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
+}
+
+type SecretsConfig struct {
+	// EncryptionKey ключ шифрования закрытых ключей подписи JWT и секретов TOTP в БД:
+	// 32 байта в base64, например из openssl rand -base64 32
+	EncryptionKey string `envconfig:"SECRETS_ENCRYPTION_KEY" required:"true"`
 }
 
 type DBConfig struct {
@@ -26,8 +48,16 @@ type DBConfig struct {
 	SSLMode  string `envconfig:"POSTGRES_SSLMODE"  default:"disable"`
 }
 type JWTConfig struct {
-	Secret     string        `envconfig:"JWT_SECRET" required:"true"`
-	Expiration time.Duration `envconfig:"JWT_EXPIRATION" default:"24h"`
+	// Algorithm алгоритм подписи: RS256, EdDSA или HS256 (общий секрет JWT_SECRET, без JWKS)
+	Algorithm  string        `envconfig:"JWT_SIGNING_ALG" default:"RS256"`
+	Secret     string        `envconfig:"JWT_SECRET"`
+	Expiration time.Duration `envconfig:"JWT_EXPIRATION" default:"15m"`
+	// RefreshExpiration срок жизни refresh токена
+	RefreshExpiration time.Duration `envconfig:"JWT_REFRESH_EXPIRATION" default:"720h"`
+	// KeyRotation сколько времени ключ подписывает новые токены
+	KeyRotation time.Duration `envconfig:"JWT_KEY_ROTATION" default:"720h"`
+	// KeyOverlap сколько следующий ключ публикуется до начала подписи и старый принимается после ее окончания
+	KeyOverlap time.Duration `envconfig:"JWT_KEY_OVERLAP" default:"1h"`
 }
 
 type GRPCConfig struct {
@@ -37,7 +67,123 @@ type GRPCConfig struct {
 type KafkaConfig struct {
 	Brokers []string `envconfig:"KAFKA_BROKERS" default:"localhost:9092"`
 	Topic   string   `envconfig:"KAFKA_TOPIC" default:"large-transfers"`
-	Enabled bool     `envconfig:"KAFKA_ENABLED" default:"true"`
+	// AlertsTopic топик операционных алертов (расхождения сверки балансов)
+	AlertsTopic string `envconfig:"KAFKA_ALERTS_TOPIC" default:"wallet-alerts"`
+	Enabled     bool   `envconfig:"KAFKA_ENABLED" default:"true"`
+}
+
+type MoneyConfig struct {
+	// RoundingMode правило округления при конвертации: HALF_EVEN, HALF_UP или DOWN
+	RoundingMode string `envconfig:"MONEY_ROUNDING_MODE" default:"HALF_EVEN"`
+}
+
+type ExchangeConfig struct {
+	// QuoteTTL сколько действует котировка обмена
+	QuoteTTL time.Duration `envconfig:"EXCHANGE_QUOTE_TTL" default:"30s"`
+	// LimitOrderInterval как часто открытые лимитные ордера сверяются с текущими курсами
+	LimitOrderInterval time.Duration `envconfig:"EXCHANGE_LIMIT_ORDER_INTERVAL" default:"10s"`
+	// ScheduleInterval как часто планировщик проверяет наступившие запланированные обмены
+	ScheduleInterval time.Duration `envconfig:"EXCHANGE_SCHEDULE_INTERVAL" default:"30s"`
+}
+
+type IdempotencyConfig struct {
+	// TTL сколько хранится ответ на запрос с заголовком Idempotency-Key
+	TTL time.Duration `envconfig:"IDEMPOTENCY_KEY_TTL" default:"24h"`
+}
+
+type HoldConfig struct {
+	// DefaultTTL срок действия холда, если клиент не указал expires_in
+	DefaultTTL time.Duration `envconfig:"HOLD_DEFAULT_TTL" default:"168h"`
+	// MaxTTL максимальный срок действия холда
+	MaxTTL time.Duration `envconfig:"HOLD_MAX_TTL" default:"720h"`
+}
+
+type ReconciliationConfig struct {
+	// Interval как часто выполняется плановая сверка балансов; 0 отключает плановую сверку
+	Interval time.Duration `envconfig:"RECONCILIATION_INTERVAL" default:"24h"`
+	// AlertsEnabled отправлять ли событие в Kafka, если сверка нашла расхождения
+	AlertsEnabled bool `envconfig:"RECONCILIATION_ALERTS_ENABLED" default:"true"`
+}
+
+type MailConfig struct {
+	// SMTPHost адрес SMTP сервера; если не задан, письма только пишутся в лог
+	SMTPHost     string        `envconfig:"SMTP_HOST"`
+	SMTPPort     int           `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername string        `envconfig:"SMTP_USERNAME"`
+	SMTPPassword string        `envconfig:"SMTP_PASSWORD"`
+	SMTPTimeout  time.Duration `envconfig:"SMTP_TIMEOUT" default:"10s"`
+	From         string        `envconfig:"MAIL_FROM" default:"no-reply@wallet.local"`
+	// LinkBaseURL адрес клиентского приложения, в которое ведут ссылки из писем
+	LinkBaseURL string `envconfig:"MAIL_LINK_BASE_URL" default:"http://localhost:8080"`
+}
+
+type AccountConfig struct {
+	// EmailVerificationTTL сколько действует ссылка подтверждения email
+	EmailVerificationTTL time.Duration `envconfig:"EMAIL_VERIFICATION_TTL" default:"48h"`
+	// PasswordResetTTL сколько действует ссылка сброса пароля
+	PasswordResetTTL time.Duration `envconfig:"PASSWORD_RESET_TTL" default:"1h"`
+}
+
+type MFAConfig struct {
+	// Issuer название сервиса в приложении-аутентификаторе
+	Issuer string `envconfig:"MFA_TOTP_ISSUER" default:"GW Wallet"`
+	// ChallengeTTL сколько действует токен второго шага входа
+	ChallengeTTL time.Duration `envconfig:"MFA_CHALLENGE_TTL" default:"5m"`
+	// StepUpMaxAge сколько после подтверждения вторым фактором токен допускается к операциям, требующим step-up
+	StepUpMaxAge time.Duration `envconfig:"MFA_STEP_UP_MAX_AGE" default:"5m"`
+	// StepUpThresholds пороги сумм списания по валютам, выше которых вывод, перевод и обмен требуют step-up,
+	// например USD:1000,EUR:1000; пусто - step-up по сумме не требуется
+	StepUpThresholds map[string]string `envconfig:"MFA_STEP_UP_THRESHOLDS"`
+}
+
+// LoginConfig ограничение неверных попыток входа
+type LoginConfig struct {
+	// FreeAttempts сколько неверных паролей подряд для одного имени пользователя допускается без задержки
+	FreeAttempts int `envconfig:"LOGIN_FREE_ATTEMPTS" default:"3"`
+	// IPFreeAttempts то же для одного адреса; выше, потому что за NAT может быть много пользователей
+	IPFreeAttempts int `envconfig:"LOGIN_IP_FREE_ATTEMPTS" default:"20"`
+	// BackoffBase задержка после первой платной попытки; каждая следующая ее удваивает
+	BackoffBase time.Duration `envconfig:"LOGIN_BACKOFF_BASE" default:"1s"`
+	// BackoffMax максимальная задержка между попытками
+	BackoffMax time.Duration `envconfig:"LOGIN_BACKOFF_MAX" default:"15m"`
+	// LockoutThreshold после стольких неверных паролей подряд учетная запись блокируется
+	LockoutThreshold int `envconfig:"LOGIN_LOCKOUT_THRESHOLD" default:"10"`
+	// LockoutDuration срок блокировки учетной записи
+	LockoutDuration time.Duration `envconfig:"LOGIN_LOCKOUT_DURATION" default:"30m"`
+	// FailureWindow через сколько после последней неверной попытки счетчик начинается заново
+	FailureWindow time.Duration `envconfig:"LOGIN_FAILURE_WINDOW" default:"1h"`
+}
+
+// Хранилища корзин ограничения частоты запросов
+const (
+	RateLimitBackendMemory   = "memory"
+	RateLimitBackendPostgres = "postgres"
+)
+
+// RateLimitConfig ограничение частоты запросов. Правило политики имеет вид <запросов>/<период>[:<емкость>],
+// например 60/1m или 60/1m:10; пустое правило отключает политику.
+type RateLimitConfig struct {
+	Enabled bool `envconfig:"RATE_LIMIT_ENABLED" default:"true"`
+	// Backend где хранятся корзины: memory - в памяти экземпляра, postgres - общие для всех реплик
+	Backend string `envconfig:"RATE_LIMIT_BACKEND" default:"memory"`
+	// Global все запросы с одного адреса
+	Global string `envconfig:"RATE_LIMIT_GLOBAL" default:"600/1m"`
+	// Auth вход, регистрация, обновление токенов и сброс пароля с одного адреса
+	Auth string `envconfig:"RATE_LIMIT_AUTH" default:"20/1m"`
+	// Wallet пополнения, выводы, переводы и холды одного пользователя
+	Wallet string `envconfig:"RATE_LIMIT_WALLET" default:"60/1m"`
+	// Exchange обмены, лимитные ордера и запланированные обмены одного пользователя
+	Exchange string `envconfig:"RATE_LIMIT_EXCHANGE" default:"30/1m"`
+}
+
+// Policies правила политик по их названиям
+func (c *RateLimitConfig) Policies() map[string]string {
+	return map[string]string{
+		models.RateLimitPolicyGlobal:   c.Global,
+		models.RateLimitPolicyAuth:     c.Auth,
+		models.RateLimitPolicyWallet:   c.Wallet,
+		models.RateLimitPolicyExchange: c.Exchange,
+	}
 }
 
 func NewConfig() (*Config, error) {
@@ -51,6 +197,9 @@ func NewConfig() (*Config, error) {
 	if err := envconfig.Process("", &cfg); err != nil {
 		return nil, fmt.Errorf("ошибка парсинга конфигурации: %w", err)
 	}
+	if err := cfg.JWT.Validate(); err != nil {
+		return nil, fmt.Errorf("ошибка конфигурации JWT: %w", err)
+	}
 
 	return &cfg, nil
 }
@@ -67,3 +216,26 @@ func (d *DBConfig) MigrationURL() string {
 		d.User, d.Password, d.Host, d.Port, d.DBName, d.SSLMode,
 	)
 }
+
+// Validate проверяет согласованность параметров подписи
+func (j *JWTConfig) Validate() error {
+	switch j.Algorithm {
+	case jwtkeys.AlgHS256:
+		if j.Secret == "" {
+			return fmt.Errorf("JWT_SECRET обязателен для %s", j.Algorithm)
+		}
+	case jwtkeys.AlgRS256, jwtkeys.AlgEdDSA:
+		if j.KeyRotation <= 0 {
+			return fmt.Errorf("JWT_KEY_ROTATION должен быть положительным")
+		}
+		// Старый ключ должен приниматься, пока живут подписанные им токены,
+		// а новый - попасть в кэш JWKS у клиентов до начала подписи
+		if j.KeyOverlap < j.Expiration || j.KeyOverlap < jwtkeys.JWKSCacheMaxAge {
+			return fmt.Errorf("JWT_KEY_OVERLAP (%s) должен быть не меньше JWT_EXPIRATION (%s) и %s",
+				j.KeyOverlap, j.Expiration, jwtkeys.JWKSCacheMaxAge)
+		}
+	default:
+		return fmt.Errorf("неподдерживаемый JWT_SIGNING_ALG: %s", j.Algorithm)
+	}
+	return nil
+}
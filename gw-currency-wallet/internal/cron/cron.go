@@ -0,0 +1,186 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchHorizon насколько далеко вперед ищется следующее срабатывание.
+// Выражения вроде "0 0 30 2 *" никогда не срабатывают и отклоняются при разборе.
+const searchHorizon = 5 * 366 * 24 * time.Hour
+
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 1",
+	"@monthly": "0 0 1 * *",
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Schedule разобранное cron-выражение из пяти полей: минута, час, день месяца, месяц, день недели.
+// Поддерживаются *, числа, списки через запятую, диапазоны a-b и шаги */n, a-b/n,
+// а также макросы @hourly, @daily, @weekly и @monthly. Время считается в UTC.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domAny/dowAny поле задано как *. Если ограничены оба поля, достаточно совпадения любого из них
+	domAny, dowAny bool
+}
+
+// Parse разбирает выражение и проверяет, что по нему будет хотя бы одно срабатывание
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidExpression, len(fields), len(parts))
+	}
+
+	var masks [5]uint64
+	for i, part := range parts {
+		mask, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		masks[i] = mask
+	}
+
+	// 7 и 0 - оба воскресенье
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = masks[4]&^(1<<7) | 1
+	}
+
+	s := &Schedule{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w: expression never fires", ErrInvalidExpression)
+	}
+	return s, nil
+}
+
+// Next возвращает первое срабатывание строго после after или нулевое время, если его нет
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(searchHorizon)
+
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domOK := has(s.dom, t.Day())
+	dowOK := has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+func has(mask uint64, v int) bool {
+	return mask&(1<<uint(v)) != 0
+}
+
+func parseField(expr string, f field) (uint64, error) {
+	var mask uint64
+	for _, item := range strings.Split(expr, ",") {
+		lo, hi, step, err := parseItem(item, f)
+		if err != nil {
+			return 0, err
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func parseItem(item string, f field) (lo, hi, step int, err error) {
+	rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+	step = 1
+	if hasStep {
+		step, err = strconv.Atoi(stepPart)
+		if err != nil || step <= 0 {
+			return 0, 0, 0, fmt.Errorf("%w: bad step %q in %s field", ErrInvalidExpression, stepPart, f.name)
+		}
+	}
+
+	switch {
+	case rangePart == "*":
+		return f.min, f.max, step, nil
+	case strings.Contains(rangePart, "-"):
+		from, to, _ := strings.Cut(rangePart, "-")
+		if lo, err = parseValue(from, f); err != nil {
+			return 0, 0, 0, err
+		}
+		if hi, err = parseValue(to, f); err != nil {
+			return 0, 0, 0, err
+		}
+		if lo > hi {
+			return 0, 0, 0, fmt.Errorf("%w: empty range %q in %s field", ErrInvalidExpression, rangePart, f.name)
+		}
+		return lo, hi, step, nil
+	default:
+		if lo, err = parseValue(rangePart, f); err != nil {
+			return 0, 0, 0, err
+		}
+		// "5/15" трактуется как "5-max/15"
+		if hasStep {
+			return lo, f.max, step, nil
+		}
+		return lo, lo, step, nil
+	}
+}
+
+func parseValue(s string, f field) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%w: value %q out of range %d-%d in %s field", ErrInvalidExpression, s, f.min, f.max, f.name)
+	}
+	return v, nil
+}
@@ -0,0 +1,65 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// 2024-01-03 - среда
+	from := time.Date(2024, 1, 3, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2024, 1, 3, 10, 31, 0, 0, time.UTC)},
+		{"every monday 9:00", "0 9 * * 1", time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"step", "*/15 * * * *", time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC)},
+		{"range with step", "0 8-18/4 * * *", time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{"list", "0 6,22 * * *", time.Date(2024, 1, 3, 22, 0, 0, 0, time.UTC)},
+		{"monthly macro", "@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"dom or dow", "0 0 15 * 5", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"year rollover", "0 0 1 1 *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s.Next(from))
+		})
+	}
+}
+
+func TestSchedule_NextIsStrictlyAfter(t *testing.T) {
+	s, err := Parse("0 9 * * *")
+	require.NoError(t, err)
+
+	at := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, at.Add(24*time.Hour), s.Next(at))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+	} {
+		_, err := Parse(expr)
+		assert.ErrorIs(t, err, ErrInvalidExpression, expr)
+	}
+}
@@ -7,6 +7,38 @@ var (
 	ErrNotFound          = errors.New("resource not found")
 	ErrInsufficientFunds = errors.New("insufficient funds")
 	ErrDuplicateRequest  = errors.New("duplicate request")
+	ErrWalletFrozen      = errors.New("wallet is frozen")
+	ErrWalletNotFrozen   = errors.New("wallet is not frozen")
+	// ErrLimitExceeded операция превышает лимит пользователя; подробности в models.LimitExceededError
+	ErrLimitExceeded = errors.New("transaction limit exceeded")
+	// ErrHoldNotActive холд уже списан, освобожден или истек
+	ErrHoldNotActive = errors.New("hold is not active")
+
+	// Idempotency errors
+	// ErrIdempotencyKeyReused ключ Idempotency-Key уже использован с другим запросом
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+	// ErrIdempotencyKeyInProgress запрос с этим ключом еще выполняется
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is in progress")
+
+	// Exchange errors
+	ErrQuoteNotFound = errors.New("quote not found")
+	// ErrQuoteExpired срок котировки истек или она уже использована
+	ErrQuoteExpired = errors.New("quote expired or already used")
+	// ErrOrderNotOpen лимитный ордер уже исполнен, отменен или истек
+	ErrOrderNotOpen = errors.New("limit order is not open")
+	// ErrRateUnavailable не удалось получить курс у exchanger сервиса
+	ErrRateUnavailable = errors.New("exchange rate unavailable")
+	// ErrScheduleNotActive запланированный обмен уже завершен или отменен,
+	// либо его запуск уже обработан
+	ErrScheduleNotActive = errors.New("scheduled exchange is not active")
+
+	// Reconciliation errors
+	// ErrReconciliationInProgress сверка балансов уже выполняется
+	ErrReconciliationInProgress = errors.New("reconciliation is already in progress")
+
+	// Transfer errors
+	ErrRecipientNotFound = errors.New("recipient not found")
+	ErrSelfTransfer      = errors.New("cannot transfer to yourself")
 
 	// User errors
 	ErrUsernameExists     = errors.New("username already exists")
@@ -14,11 +46,44 @@ var (
 	ErrInvalidCredentials = errors.New("invalid username or password")
 	ErrInvalidToken       = errors.New("invalid or expired token")
 	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
 	ErrTokenExpired       = errors.New("token has expired")
 	ErrTokenNotActive     = errors.New("token not active yet")
+	ErrTokenRevoked       = errors.New("token has been revoked")
+	// ErrRefreshTokenReused повторное использование refresh токена; семейство токенов отозвано
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	// ErrEmailNotVerified операция доступна только после подтверждения email
+	ErrEmailNotVerified = errors.New("email is not verified")
+	// ErrEmailAlreadyVerified email пользователя уже подтвержден
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+	// ErrLoginThrottled вход временно недоступен после серии неверных попыток; подробности в models.LoginThrottledError
+	ErrLoginThrottled = errors.New("too many failed login attempts")
+
+	// MFA errors
+	// ErrInvalidMFACode код TOTP или код восстановления неверен либо уже использован
+	ErrInvalidMFACode = errors.New("invalid mfa code")
+	// ErrMFALocked проверка кодов временно заблокирована после серии неверных попыток
+	ErrMFALocked = errors.New("mfa verification is temporarily locked")
+	// ErrMFANotEnabled двухфакторная аутентификация не включена
+	ErrMFANotEnabled = errors.New("mfa is not enabled")
+	// ErrMFAAlreadyEnabled двухфакторная аутентификация уже включена
+	ErrMFAAlreadyEnabled = errors.New("mfa is already enabled")
+	// ErrMFANotEnrolled подключение TOTP не начато
+	ErrMFANotEnrolled = errors.New("totp enrollment not started")
+	// ErrStepUpRequired операция требует свежего подтверждения вторым фактором
+	ErrStepUpRequired = errors.New("step-up authentication required")
+
+	// API key errors
+	// ErrAPIKeyIPNotAllowed API ключ использован с адреса вне списка разрешенных
+	ErrAPIKeyIPNotAllowed = errors.New("api key is not allowed from this address")
+	// ErrAPIKeyLimitReached у пользователя уже максимальное число действующих ключей
+	ErrAPIKeyLimitReached = errors.New("api key limit reached")
 
 	// Validation errors
 	ErrInvalidInput    = errors.New("invalid input")
 	ErrInvalidCurrency = errors.New("invalid currency")
 	ErrInvalidAmount   = errors.New("invalid amount")
+	// ErrAmountPrecision сумма содержит больше знаков после запятой, чем допускает валюта
+	ErrAmountPrecision = errors.New("amount has more decimal places than the currency allows")
+	ErrInvalidCursor   = errors.New("invalid pagination cursor")
 )
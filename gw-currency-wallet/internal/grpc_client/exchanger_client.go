@@ -7,23 +7,33 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 type ExchangeRatesResponse struct {
-	Rates map[string]float64
+	Rates map[string]decimal.Decimal
 }
 
 type ExchangeRateResponse struct {
 	FromCurrency string
 	ToCurrency   string
-	Rate         float64
+	Rate         decimal.Decimal
+}
+
+// Currency запись реестра валют exchanger сервиса
+type Currency struct {
+	Code     string
+	Name     string
+	Exponent int32
+	Enabled  bool
 }
 
 type ExchangerClient interface {
 	GetExchangeRates(ctx context.Context) (*ExchangeRatesResponse, error)
 	GetExchangeRateForCurrency(ctx context.Context, from, to string) (*ExchangeRateResponse, error)
+	GetCurrencies(ctx context.Context) ([]Currency, error)
 	Close() error
 }
 
@@ -84,9 +94,17 @@ func (c *grpcExchangerClient) GetExchangeRates(ctx context.Context) (*ExchangeRa
 			slog.Duration("duration", duration))
 	}
 
-	rates := make(map[string]float64)
+	rates := make(map[string]decimal.Decimal, len(resp.Rates))
 	for currency, rate := range resp.Rates {
-		rates[currency] = float64(rate)
+		rates[currency] = decimal.NewFromFloat(rate)
+	}
+	// Точные курсы перекрывают приближенные; старый exchanger присылает только rates
+	for currency, raw := range resp.RatesDecimal {
+		rate, err := decimal.NewFromString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid rate for %s: %w", op, currency, err)
+		}
+		rates[currency] = rate
 	}
 
 	return &ExchangeRatesResponse{
@@ -117,18 +135,51 @@ func (c *grpcExchangerClient) GetExchangeRateForCurrency(ctx context.Context, fr
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	rate := decimal.NewFromFloat(resp.Rate)
+	if resp.RateDecimal != "" {
+		rate, err = decimal.NewFromString(resp.RateDecimal)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid rate: %w", op, err)
+		}
+	}
+
 	c.log.Debug("получен курс валюты",
 		slog.String("from", resp.FromCurrency),
 		slog.String("to", resp.ToCurrency),
-		slog.Float64("rate", float64(resp.Rate)))
+		slog.String("rate", rate.String()))
 
 	return &ExchangeRateResponse{
 		FromCurrency: resp.FromCurrency,
 		ToCurrency:   resp.ToCurrency,
-		Rate:         float64(resp.Rate),
+		Rate:         rate,
 	}, nil
 }
 
+func (c *grpcExchangerClient) GetCurrencies(ctx context.Context) ([]Currency, error) {
+	const op = "grpc_client.GetCurrencies"
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.GetCurrencies(ctx, &pb.Empty{})
+	if err != nil {
+		c.log.Error("ошибка получения реестра валют", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	currencies := make([]Currency, 0, len(resp.Currencies))
+	for _, cur := range resp.Currencies {
+		currencies = append(currencies, Currency{
+			Code:     cur.Code,
+			Name:     cur.Name,
+			Exponent: cur.Exponent,
+			Enabled:  cur.Enabled,
+		})
+	}
+
+	return currencies, nil
+}
+
 func (c *grpcExchangerClient) Close() error {
 	if c.conn == nil {
 		return nil
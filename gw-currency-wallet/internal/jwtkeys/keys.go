@@ -0,0 +1,171 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	rsaKeyBits = 2048
+
+	// JWKSCacheMaxAge время, на которое клиентам разрешено кэшировать JWKS
+	JWKSCacheMaxAge = 5 * time.Minute
+)
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// Key ключ подписи JWT. Для HS256 используется Secret, для асимметричных алгоритмов - Private.
+type Key struct {
+	ID        string
+	Algorithm string
+	Secret    []byte
+	Private   crypto.Signer
+
+	// ActivatesAt..DeactivatesAt период, в который ключом подписываются новые токены
+	ActivatesAt   time.Time
+	DeactivatesAt time.Time
+	// ExpiresAt момент, после которого ключ не принимается и не публикуется в JWKS
+	ExpiresAt time.Time
+}
+
+// SigningMethod возвращает метод подписи jwt для алгоритма ключа
+func (k *Key) SigningMethod() (jwt.SigningMethod, error) {
+	switch k.Algorithm {
+	case AlgHS256:
+		return jwt.SigningMethodHS256, nil
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+}
+
+// SignKey ключ для jwt.Token.SignedString
+func (k *Key) SignKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.Secret
+	}
+	return k.Private
+}
+
+// VerifyKey ключ для проверки подписи
+func (k *Key) VerifyKey() interface{} {
+	if k.Algorithm == AlgHS256 {
+		return k.Secret
+	}
+	return k.Private.Public()
+}
+
+// SignsAt проверяет, подписываются ли ключом токены в момент t
+func (k *Key) SignsAt(t time.Time) bool {
+	return !t.Before(k.ActivatesAt) && t.Before(k.DeactivatesAt)
+}
+
+// IsAsymmetric проверяет, что алгоритм использует пару ключей и может быть опубликован в JWKS
+func IsAsymmetric(alg string) bool {
+	return alg == AlgRS256 || alg == AlgEdDSA
+}
+
+// GeneratePrivateKey создает новый закрытый ключ для алгоритма
+func GeneratePrivateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case AlgRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+// MarshalPrivateKey кодирует закрытый ключ в PEM (PKCS#8)
+func MarshalPrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParsePrivateKey разбирает закрытый ключ из PEM (PKCS#8) и проверяет, что он подходит алгоритму
+func ParsePrivateKey(alg, data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg == AlgRS256 {
+			return key, nil
+		}
+	case ed25519.PrivateKey:
+		if alg == AlgEdDSA {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: key type %T does not match %s", ErrUnsupportedAlgorithm, parsed, alg)
+}
+
+// JWK открытый ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet набор открытых ключей, отдаваемый по /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ToJWK возвращает открытую часть ключа. Для HS256 ключей публиковать нечего.
+func ToJWK(k *Key) (JWK, error) {
+	if !IsAsymmetric(k.Algorithm) {
+		return JWK{}, fmt.Errorf("%w: %s keys are not published", ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+	jwk := JWK{Use: "sig", Alg: k.Algorithm, Kid: k.ID}
+
+	switch public := k.Private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, fmt.Errorf("%w: key type %T", ErrUnsupportedAlgorithm, public)
+	}
+	return jwk, nil
+}
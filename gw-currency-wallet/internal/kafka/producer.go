@@ -13,16 +13,19 @@ import (
 
 type Producer interface {
 	SendLargeTransferEvent(ctx context.Context, event models.LargeTransferEvent) error
+	// SendReconciliationAlert публикует в топик алертов сообщение о расхождениях сверки балансов
+	SendReconciliationAlert(ctx context.Context, event models.ReconciliationAlertEvent) error
 	Close() error
 }
 
 type KafkaProducer struct {
-	producer sarama.SyncProducer
-	topic    string
-	log      *slog.Logger
+	producer    sarama.SyncProducer
+	topic       string
+	alertsTopic string
+	log         *slog.Logger
 }
 
-func NewKafkaProducer(brokers []string, topic string, log *slog.Logger) (Producer, error) {
+func NewKafkaProducer(brokers []string, topic, alertsTopic string, log *slog.Logger) (Producer, error) {
 	config := sarama.NewConfig()
 	config.Producer.Return.Successes = true
 	config.Producer.RequiredAcks = sarama.WaitForAll
@@ -35,12 +38,16 @@ func NewKafkaProducer(brokers []string, topic string, log *slog.Logger) (Produce
 		return nil, fmt.Errorf("failed to create kafka producer: %w", err)
 	}
 
-	log.Info("kafka producer создан", slog.String("topic", topic), slog.Any("brokers", brokers))
+	log.Info("kafka producer создан",
+		slog.String("topic", topic),
+		slog.String("alerts_topic", alertsTopic),
+		slog.Any("brokers", brokers))
 
 	return &KafkaProducer{
-		producer: producer,
-		topic:    topic,
-		log:      log,
+		producer:    producer,
+		topic:       topic,
+		alertsTopic: alertsTopic,
+		log:         log,
 	}, nil
 }
 
@@ -49,11 +56,22 @@ func (p *KafkaProducer) SendLargeTransferEvent(ctx context.Context, event models
 	if err != nil {
 		return fmt.Errorf("marshal error: %w", err)
 	}
+	return p.send(ctx, p.topic, event.TransactionID, eventData)
+}
+
+func (p *KafkaProducer) SendReconciliationAlert(ctx context.Context, event models.ReconciliationAlertEvent) error {
+	eventData, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal error: %w", err)
+	}
+	return p.send(ctx, p.alertsTopic, event.ReportID.String(), eventData)
+}
 
+func (p *KafkaProducer) send(ctx context.Context, topic, key string, value []byte) error {
 	msg := &sarama.ProducerMessage{
-		Topic: p.topic,
-		Key:   sarama.StringEncoder(event.TransactionID),
-		Value: sarama.ByteEncoder(eventData),
+		Topic: topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(value),
 	}
 
 	type result struct {
@@ -73,19 +91,22 @@ func (p *KafkaProducer) SendLargeTransferEvent(ctx context.Context, event models
 	case res := <-resultCh:
 		if res.err != nil {
 			p.log.Error("kafka send failed",
-				slog.String("tx_id", event.TransactionID),
+				slog.String("topic", topic),
+				slog.String("key", key),
 				slog.String("error", res.err.Error()))
 			return res.err
 		}
 		p.log.Debug("kafka send success",
-			slog.String("tx_id", event.TransactionID),
+			slog.String("topic", topic),
+			slog.String("key", key),
 			slog.Int("partition", int(res.partition)),
 			slog.Int64("offset", res.offset))
 		return nil
 
 	case <-ctx.Done():
 		p.log.Warn("kafka send cancelled",
-			slog.String("tx_id", event.TransactionID))
+			slog.String("topic", topic),
+			slog.String("key", key))
 		return ctx.Err()
 	}
 }
@@ -112,6 +133,12 @@ func (p *NoOpProducer) SendLargeTransferEvent(ctx context.Context, event models.
 	return nil
 }
 
+func (p *NoOpProducer) SendReconciliationAlert(ctx context.Context, event models.ReconciliationAlertEvent) error {
+	p.log.Debug("kafka отключен, алерт сверки не отправлен",
+		slog.String("report_id", event.ReportID.String()))
+	return nil
+}
+
 func (p *NoOpProducer) Close() error {
 	return nil
 }
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// ErrInvalidMessage адрес или тема письма содержат перевод строки либо адрес пуст
+var ErrInvalidMessage = errors.New("invalid mail message")
+
+// Message текстовое письмо одному получателю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Validate защищает заголовки письма от внедрения дополнительных полей
+func (m Message) Validate() error {
+	if m.To == "" || strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer не отправляет письма, а пишет их в лог. Используется, когда SMTP не настроен
+type LogMailer struct {
+	log *slog.Logger
+}
+
+func NewLogMailer(log *slog.Logger) Mailer {
+	return &LogMailer{log: log}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	// Тело письма содержит одноразовые токены, поэтому пишется только на уровне debug
+	m.log.Info("SMTP не настроен, письмо не отправлено",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject))
+	m.log.Debug("содержимое письма", slog.String("to", msg.To), slog.String("body", msg.Body))
+	return nil
+}
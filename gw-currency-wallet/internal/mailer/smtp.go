@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username и Password для AUTH PLAIN; пустой Username отключает аутентификацию
+	Username string
+	Password string
+	From     string
+	// Timeout ограничивает весь сеанс отправки одного письма
+	Timeout time.Duration
+}
+
+// SMTPMailer отправляет письма через SMTP сервер. Если сервер поддерживает STARTTLS,
+// соединение шифруется до аутентификации.
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) Mailer {
+	return &SMTPMailer{cfg: cfg}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	const op = "mailer.SMTPSend"
+
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+	if m.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.cfg.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port)))
+	if err != nil {
+		return fmt.Errorf("%s: dial: %w", op, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return fmt.Errorf("%s: starttls: %w", op, err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("%s: auth: %w", op, err)
+		}
+	}
+
+	if err := client.Mail(m.cfg.From); err != nil {
+		return fmt.Errorf("%s: mail from: %w", op, err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("%s: rcpt to: %w", op, err)
+	}
+
+	body, err := m.build(msg)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("%s: data: %w", op, err)
+	}
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return fmt.Errorf("%s: data: %w", op, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("%s: data: %w", op, err)
+	}
+
+	if err := client.Quit(); err != nil {
+		return fmt.Errorf("%s: quit: %w", op, err)
+	}
+	return nil
+}
+
+// build собирает письмо в формате RFC 5322. Тема кодируется по RFC 2047, тело - quoted-printable,
+// чтобы кириллица доходила без искажений через любые серверы.
+func (m *SMTPMailer) build(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", uuid.NewString(), m.cfg.Host)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(msg.Body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
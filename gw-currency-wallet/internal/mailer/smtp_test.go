@@ -0,0 +1,201 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer минимальный SMTP сервер для одного сеанса: запоминает конверт, данные и AUTH
+type fakeSMTPServer struct {
+	listener net.Listener
+	// advertiseAuth объявлять ли расширение AUTH PLAIN
+	advertiseAuth bool
+	// rejectRcpt отвечать ошибкой на RCPT TO
+	rejectRcpt bool
+
+	done chan struct{}
+	from string
+	rcpt string
+	auth string
+	data string
+}
+
+func startFakeSMTPServer(t *testing.T, advertiseAuth, rejectRcpt bool) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTPServer{
+		listener:      listener,
+		advertiseAuth: advertiseAuth,
+		rejectRcpt:    rejectRcpt,
+		done:          make(chan struct{}),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP fake")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			if s.advertiseAuth {
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250 localhost")
+			}
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			s.auth = strings.TrimSpace(line[len("AUTH PLAIN"):])
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = line[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			if s.rejectRcpt {
+				reply("550 5.1.1 No such user")
+				continue
+			}
+			s.rcpt = line[len("RCPT TO:"):]
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data = data.String()
+			reply("250 OK queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake SMTP session did not finish")
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	server := startFakeSMTPServer(t, false, false)
+	m := NewSMTPMailer(SMTPConfig{
+		Host:    "127.0.0.1",
+		Port:    server.port(),
+		From:    "no-reply@wallet.local",
+		Timeout: 5 * time.Second,
+	})
+
+	err := m.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "Подтверждение email",
+		Body:    "Здравствуйте, alice!\nСсылка: http://localhost:8080/verify-email?token=abc",
+	})
+	require.NoError(t, err)
+	server.wait(t)
+
+	assert.Equal(t, "<no-reply@wallet.local>", server.from)
+	assert.Equal(t, "<alice@example.com>", server.rcpt)
+	assert.Empty(t, server.auth)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(server.data))
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", parsed.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Подтверждение email", subject)
+	assert.Equal(t, "quoted-printable", parsed.Header.Get("Content-Transfer-Encoding"))
+
+	body, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	require.NoError(t, err)
+	// Клиент SMTP переводит строки в CRLF и завершает данные переводом строки
+	assert.Equal(t, "Здравствуйте, alice!\r\nСсылка: http://localhost:8080/verify-email?token=abc\r\n", string(body))
+}
+
+func TestSMTPMailer_Send_Auth(t *testing.T) {
+	server := startFakeSMTPServer(t, true, false)
+	m := NewSMTPMailer(SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Username: "wallet",
+		Password: "secret",
+		From:     "no-reply@wallet.local",
+		Timeout:  5 * time.Second,
+	})
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "bob@example.com", Subject: "Test", Body: "hi"}))
+	server.wait(t)
+
+	creds, err := base64.StdEncoding.DecodeString(server.auth)
+	require.NoError(t, err)
+	assert.Equal(t, "\x00wallet\x00secret", string(creds))
+}
+
+func TestSMTPMailer_Send_RecipientRejected(t *testing.T) {
+	server := startFakeSMTPServer(t, false, true)
+	m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: server.port(), From: "no-reply@wallet.local", Timeout: 5 * time.Second})
+
+	err := m.Send(context.Background(), Message{To: "nobody@example.com", Subject: "Test", Body: "hi"})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rcpt to")
+}
+
+func TestSMTPMailer_Send_RejectsHeaderInjection(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "127.0.0.1", Port: 1, From: "no-reply@wallet.local"})
+
+	err := m.Send(context.Background(), Message{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "Test"})
+
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountTokenPurpose назначение одноразового токена, отправляемого на email
+type AccountTokenPurpose string
+
+const (
+	AccountTokenEmailVerification AccountTokenPurpose = "EMAIL_VERIFICATION"
+	AccountTokenPasswordReset     AccountTokenPurpose = "PASSWORD_RESET"
+)
+
+// AccountToken серверная запись одноразового токена. Сам токен не хранится, только его хэш
+type AccountToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   AccountTokenPurpose
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time // токен использован или заменен более новым
+	CreatedAt time.Time
+}
+
+// VerifyEmailRequest подтверждение email токеном из письма
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ForgotPasswordRequest запрос письма со ссылкой сброса пароля
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"alice@example.com"`
+}
+
+// ResetPasswordRequest установка нового пароля токеном из письма
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// AccountActionResponse ответ на действия с учетной записью
+type AccountActionResponse struct {
+	Message string `json:"message"`
+}
+
+func (r ForgotPasswordRequest) Validate() error {
+	return ValidateEmail(r.Email)
+}
+
+func (r ResetPasswordRequest) Validate() error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	return ValidatePassword(r.NewPassword)
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultUserSearchLimit = 20
+	MaxUserSearchLimit     = 100
+
+	// MaxAdminReasonLength максимальная длина причины административного действия
+	MaxAdminReasonLength = 500
+)
+
+// Действия, записываемые в журнал администратора
+const (
+	AuditActionUserSearch       = "user.search"
+	AuditActionUserView         = "user.view"
+	AuditActionUserTransactions = "user.transactions.view"
+	AuditActionUserRoleChange   = "user.role.change"
+	AuditActionWalletFreeze     = "wallet.freeze"
+	AuditActionWalletUnfreeze   = "wallet.unfreeze"
+	AuditActionWalletAdjustment = "wallet.adjustment"
+	AuditActionUserLimitsView   = "user.limits.view"
+	AuditActionUserLimitChange  = "user.limit.change"
+	AuditActionUserUnlock       = "user.unlock"
+
+	AuditActionReconciliationRun    = "reconciliation.run"
+	AuditActionReconciliationView   = "reconciliation.view"
+	AuditActionReconciliationExport = "reconciliation.export"
+
+	AuditActionSecurityEventsView   = "security_events.view"
+	AuditActionSecurityEventsVerify = "security_events.verify"
+)
+
+// UserSearchRequest параметры поиска пользователей
+type UserSearchRequest struct {
+	Query  string
+	Role   Role
+	Limit  int
+	Cursor string
+}
+
+// UserSearchFilter условия выборки пользователей на уровне хранилища
+type UserSearchFilter struct {
+	Query         string
+	Role          Role
+	AfterUsername string
+	Limit         int
+}
+
+// EncodeUserCursor кодирует позицию страницы поиска пользователей
+func EncodeUserCursor(username string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(username))
+}
+
+// DecodeUserCursor разбирает курсор, полученный из EncodeUserCursor
+func DecodeUserCursor(cursor string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// AdminUser пользователь в ответах admin API
+type AdminUser struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	Role     Role      `json:"role"`
+	// LockedUntil вход заблокирован после серии неверных паролей
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// UserSearchResponse страница результатов поиска пользователей
+type UserSearchResponse struct {
+	Users      []AdminUser `json:"users"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// AdminWallet кошелек в ответах admin API
+type AdminWallet struct {
+	ID           uuid.UUID       `json:"id"`
+	UserID       uuid.UUID       `json:"user_id"`
+	Currency     string          `json:"currency"`
+	Balance      decimal.Decimal `json:"balance" swaggertype:"string" example:"100.50"`
+	FrozenAt     *time.Time      `json:"frozen_at,omitempty"`
+	FrozenReason string          `json:"frozen_reason,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// AdminUserResponse пользователь вместе с его кошельками
+type AdminUserResponse struct {
+	User    AdminUser     `json:"user"`
+	Wallets []AdminWallet `json:"wallets"`
+}
+
+// WalletFreezeRequest запрос на заморозку или разморозку кошелька
+type WalletFreezeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// BalanceAdjustmentRequest ручная корректировка баланса: положительная сумма зачисляет, отрицательная списывает
+type BalanceAdjustmentRequest struct {
+	Amount    decimal.Decimal `json:"amount" swaggertype:"string" example:"-10.00"`
+	Reason    string          `json:"reason"`
+	RequestID string          `json:"request_id"`
+}
+
+// BalanceAdjustmentResponse результат корректировки
+type BalanceAdjustmentResponse struct {
+	Wallet       AdminWallet     `json:"wallet"`
+	Amount       decimal.Decimal `json:"amount" swaggertype:"string" example:"-10.00"`
+	BalanceAfter decimal.Decimal `json:"balance_after" swaggertype:"string" example:"90.50"`
+}
+
+// SetRoleRequest смена роли пользователя
+type SetRoleRequest struct {
+	Role   Role   `json:"role"`
+	Reason string `json:"reason"`
+}
+
+// AuditEntry запись журнала действий администраторов и поддержки
+type AuditEntry struct {
+	ID             uuid.UUID
+	ActorID        uuid.UUID
+	ActorRole      Role
+	Action         string
+	TargetUserID   *uuid.UUID
+	TargetWalletID *uuid.UUID
+	Reason         string
+	Details        map[string]any
+	CreatedAt      time.Time
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// APIKeyPrefix начало каждого API ключа; по нему middleware отличает ключ от JWT
+	APIKeyPrefix = "gwk_"
+	// APIKeyDisplayLength сколько первых символов ключа хранится открыто и показывается в списке
+	APIKeyDisplayLength = 12
+	// MaxAPIKeyNameLength максимальная длина названия ключа
+	MaxAPIKeyNameLength = 100
+	// MaxAPIKeyAllowedIPs максимальное число сетей в списке разрешенных адресов
+	MaxAPIKeyAllowedIPs = 20
+)
+
+// APIKeyScope разрешение API ключа. Ключ принимается только маршрутами, которые явно
+// перечисляют нужное разрешение; остальные, включая администрирование, доступны только по JWT.
+type APIKeyScope string
+
+const (
+	// ScopeBalanceRead балансы, кошельки и история операций
+	ScopeBalanceRead APIKeyScope = "balance:read"
+	// ScopeWalletWrite пополнение и вывод
+	ScopeWalletWrite APIKeyScope = "wallet:write"
+	// ScopeExchangeRead списки лимитных ордеров и запланированных обменов
+	ScopeExchangeRead APIKeyScope = "exchange:read"
+	// ScopeExchangeWrite котировки, обмены, лимитные ордера и запланированные обмены
+	ScopeExchangeWrite APIKeyScope = "exchange:write"
+	// ScopeTransfersWrite переводы другим пользователям
+	ScopeTransfersWrite APIKeyScope = "transfers:write"
+	// ScopeHoldsRead просмотр холдов
+	ScopeHoldsRead APIKeyScope = "holds:read"
+	// ScopeHoldsWrite создание, списание и освобождение холдов
+	ScopeHoldsWrite APIKeyScope = "holds:write"
+)
+
+// APIKeyScopes все разрешения, которые можно выдать ключу
+var APIKeyScopes = []APIKeyScope{
+	ScopeBalanceRead,
+	ScopeWalletWrite,
+	ScopeExchangeRead,
+	ScopeExchangeWrite,
+	ScopeTransfersWrite,
+	ScopeHoldsRead,
+	ScopeHoldsWrite,
+}
+
+func (s APIKeyScope) IsValid() bool {
+	return slices.Contains(APIKeyScopes, s)
+}
+
+// APIKey персональный ключ пользователя. Сам ключ не хранится, только его SHA-256 хэш
+type APIKey struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Name   string
+	// Prefix первые символы ключа, чтобы пользователь мог отличить ключи в списке
+	Prefix  string
+	KeyHash string
+	Scopes  []APIKeyScope
+	// AllowedIPs сети, из которых можно использовать ключ; пустой список - любые адреса
+	AllowedIPs []netip.Prefix
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP *netip.Addr
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// ActiveAt действует ли ключ в момент now
+func (k *APIKey) ActiveAt(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// AllowsIP разрешено ли использовать ключ с адреса addr
+func (k *APIKey) AllowsIP(addr netip.Addr) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, network := range k.AllowedIPs {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPIKeyRequest создание API ключа
+type CreateAPIKeyRequest struct {
+	Name   string        `json:"name" example:"reporting script"`
+	Scopes []APIKeyScope `json:"scopes" example:"balance:read,exchange:write"`
+	// AllowedIPs адреса или сети в нотации CIDR; пусто - ключ принимается с любого адреса
+	AllowedIPs []string `json:"allowed_ips,omitempty" example:"203.0.113.10,10.0.0.0/8"`
+	// ExpiresAt срок действия; без него ключ действует до отзыва
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate проверяет запрос и возвращает разобранный список разрешенных сетей
+func (r CreateAPIKeyRequest) Validate(now time.Time) ([]netip.Prefix, error) {
+	name := strings.TrimSpace(r.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(name) > MaxAPIKeyNameLength {
+		return nil, fmt.Errorf("name must be at most %d characters", MaxAPIKeyNameLength)
+	}
+	if len(r.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	for _, scope := range r.Scopes {
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+	return ParseAllowedIPs(r.AllowedIPs)
+}
+
+// ParseAllowedIPs разбирает адреса и сети CIDR. Отдельный адрес становится сетью из одного адреса.
+func ParseAllowedIPs(raw []string) ([]netip.Prefix, error) {
+	if len(raw) > MaxAPIKeyAllowedIPs {
+		return nil, fmt.Errorf("at most %d allowed_ips entries are supported", MaxAPIKeyAllowedIPs)
+	}
+
+	networks := make([]netip.Prefix, 0, len(raw))
+	for _, value := range raw {
+		value = strings.TrimSpace(value)
+		if strings.Contains(value, "/") {
+			network, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed_ips entry %q", value)
+			}
+			networks = append(networks, network.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed_ips entry %q", value)
+		}
+		addr = addr.Unmap()
+		networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return networks, nil
+}
+
+// APIKeyResponse ключ в ответах API; сам ключ не возвращается
+type APIKeyResponse struct {
+	ID         uuid.UUID     `json:"id"`
+	Name       string        `json:"name" example:"reporting script"`
+	Prefix     string        `json:"prefix" example:"gwk_Xb3kP9q2"`
+	Scopes     []APIKeyScope `json:"scopes" example:"balance:read,exchange:write"`
+	AllowedIPs []string      `json:"allowed_ips" example:"203.0.113.10/32"`
+	ExpiresAt  *time.Time    `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time    `json:"last_used_at,omitempty"`
+	LastUsedIP string        `json:"last_used_ip,omitempty" example:"203.0.113.10"`
+	RevokedAt  *time.Time    `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// CreateAPIKeyResponse созданный ключ; Key показывается только в этом ответе
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key" example:"gwk_Xb3kP9q2mT0vL8sR4wY7zA1cE5hJ6nQ2uV9xB3dF0gK"`
+}
+
+// APIKeyListResponse ключи пользователя, новые первыми
+type APIKeyListResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
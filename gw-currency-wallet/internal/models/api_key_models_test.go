@@ -0,0 +1,110 @@
+package models
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowedIPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", raw: nil, want: []string{}},
+		{name: "single IPv4 address", raw: []string{"203.0.113.10"}, want: []string{"203.0.113.10/32"}},
+		{name: "single IPv6 address", raw: []string{"2001:db8::1"}, want: []string{"2001:db8::1/128"}},
+		{name: "IPv4-mapped address", raw: []string{"::ffff:203.0.113.10"}, want: []string{"203.0.113.10/32"}},
+		{name: "network is masked", raw: []string{" 10.1.2.3/8 "}, want: []string{"10.0.0.0/8"}},
+		{name: "hostname", raw: []string{"example.com"}, wantErr: true},
+		{name: "bad prefix length", raw: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "too many entries", raw: make([]string, MaxAPIKeyAllowedIPs+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			networks, err := ParseAllowedIPs(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			got := make([]string, 0, len(networks))
+			for _, network := range networks {
+				got = append(got, network.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAPIKey_AllowsIP(t *testing.T) {
+	open := &APIKey{}
+	restricted := &APIKey{AllowedIPs: []netip.Prefix{
+		netip.MustParsePrefix("203.0.113.0/24"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}}
+
+	assert.True(t, open.AllowsIP(netip.MustParseAddr("198.51.100.7")))
+	assert.True(t, restricted.AllowsIP(netip.MustParseAddr("203.0.113.200")))
+	assert.True(t, restricted.AllowsIP(netip.MustParseAddr("::ffff:203.0.113.200")))
+	assert.True(t, restricted.AllowsIP(netip.MustParseAddr("2001:db8::42")))
+	assert.False(t, restricted.AllowsIP(netip.MustParseAddr("198.51.100.7")))
+	assert.False(t, restricted.AllowsIP(netip.Addr{}))
+}
+
+func TestAPIKey_ActiveAt(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	assert.True(t, (&APIKey{}).ActiveAt(now))
+	assert.True(t, (&APIKey{ExpiresAt: &future}).ActiveAt(now))
+	assert.False(t, (&APIKey{ExpiresAt: &past}).ActiveAt(now))
+	assert.False(t, (&APIKey{RevokedAt: &past}).ActiveAt(now))
+}
+
+func TestCreateAPIKeyRequest_Validate(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	scopes := []APIKeyScope{ScopeBalanceRead}
+
+	tests := []struct {
+		name    string
+		req     CreateAPIKeyRequest
+		wantErr bool
+	}{
+		{name: "valid", req: CreateAPIKeyRequest{Name: "script", Scopes: scopes, ExpiresAt: &future}},
+		{name: "blank name", req: CreateAPIKeyRequest{Name: "  ", Scopes: scopes}, wantErr: true},
+		{name: "long name", req: CreateAPIKeyRequest{Name: strings.Repeat("a", MaxAPIKeyNameLength+1), Scopes: scopes}, wantErr: true},
+		{name: "no scopes", req: CreateAPIKeyRequest{Name: "script"}, wantErr: true},
+		{name: "unknown scope", req: CreateAPIKeyRequest{Name: "script", Scopes: []APIKeyScope{"admin:write"}}, wantErr: true},
+		{name: "expiry in the past", req: CreateAPIKeyRequest{Name: "script", Scopes: scopes, ExpiresAt: &past}, wantErr: true},
+		{name: "invalid address", req: CreateAPIKeyRequest{Name: "script", Scopes: scopes, AllowedIPs: []string{"10.0.0"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.req.Validate(now)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestJWTClaims_HasScopes(t *testing.T) {
+	keyID := uuid.New()
+	jwtClaims := &JWTClaims{}
+	keyClaims := &JWTClaims{APIKeyID: &keyID, Scopes: []APIKeyScope{ScopeBalanceRead, ScopeExchangeWrite}}
+
+	assert.True(t, jwtClaims.HasScopes(ScopeWalletWrite))
+	assert.True(t, keyClaims.HasScopes(ScopeBalanceRead))
+	assert.True(t, keyClaims.HasScopes(ScopeBalanceRead, ScopeExchangeWrite))
+	assert.False(t, keyClaims.HasScopes(ScopeWalletWrite))
+	assert.False(t, keyClaims.HasScopes(ScopeBalanceRead, ScopeWalletWrite))
+}
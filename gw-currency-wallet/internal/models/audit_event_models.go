@@ -0,0 +1,201 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultAuditEventsLimit = 20
+	MaxAuditEventsLimit     = 100
+
+	// MaxUserAgentLength длиннее User-Agent обрезается перед записью в журнал
+	MaxUserAgentLength = 512
+)
+
+// AuditEventType тип события в журнале безопасности
+type AuditEventType string
+
+const (
+	AuditEventUserRegistered           AuditEventType = "user.registered"
+	AuditEventLoginSucceeded           AuditEventType = "login.succeeded"
+	AuditEventLoginFailed              AuditEventType = "login.failed"
+	AuditEventTokenRefreshed           AuditEventType = "token.refreshed"
+	AuditEventTokenReused              AuditEventType = "token.reuse_detected"
+	AuditEventStepUp                   AuditEventType = "session.step_up"
+	AuditEventStepUpFailed             AuditEventType = "session.step_up_failed"
+	AuditEventLogout                   AuditEventType = "logout"
+	AuditEventEmailVerified            AuditEventType = "email.verified"
+	AuditEventPasswordResetRequested   AuditEventType = "password.reset_requested"
+	AuditEventPasswordReset            AuditEventType = "password.reset"
+	AuditEventMFAEnabled               AuditEventType = "mfa.enabled"
+	AuditEventMFADisabled              AuditEventType = "mfa.disabled"
+	AuditEventRecoveryCodesRegenerated AuditEventType = "mfa.recovery_codes_regenerated"
+	AuditEventAPIKeyCreated            AuditEventType = "api_key.created"
+	AuditEventAPIKeyRevoked            AuditEventType = "api_key.revoked"
+	AuditEventAccountLocked            AuditEventType = "account.locked"
+	AuditEventAccountUnlocked          AuditEventType = "account.unlocked"
+)
+
+// AuditReasonInvalidMFACode причина login.failed, когда пароль принят, но код второго фактора неверен.
+// Остальные причины совпадают с причинами журнала login_failures.
+const AuditReasonInvalidMFACode = "invalid_mfa_code"
+
+// ClientInfo адрес и User-Agent клиента, от которого пришел запрос
+type ClientInfo struct {
+	IP        netip.Addr
+	UserAgent string
+}
+
+// AuditEvent запись журнала событий безопасности. Hash считается по PrevHash и полям события,
+// поэтому изменение любой записи разрывает цепочку хэшей от нее до конца журнала.
+type AuditEvent struct {
+	ID   uuid.UUID
+	Seq  int64
+	Type AuditEventType
+	// UserID учетная запись, к которой относится событие; пуст для входа под несуществующим именем
+	UserID *uuid.UUID
+	// ActorID кто выполнил действие: сам пользователь или администратор
+	ActorID   *uuid.UUID
+	IP        *netip.Addr
+	UserAgent string
+	Metadata  map[string]string
+	CreatedAt time.Time
+	PrevHash  string
+	Hash      string
+}
+
+// ComputeHash SHA-256 канонического представления события вместе с PrevHash. Метаданные - строки,
+// а время - с точностью до микросекунд, чтобы значения, прочитанные из БД, давали тот же хэш.
+func (e *AuditEvent) ComputeHash() string {
+	var ip string
+	if e.IP != nil {
+		ip = e.IP.String()
+	}
+	metadata := e.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	// Массив фиксированного порядка; json экранирует строки и сортирует ключи метаданных
+	payload, _ := json.Marshal([]any{
+		e.PrevHash,
+		e.ID,
+		e.Type,
+		e.UserID,
+		e.ActorID,
+		ip,
+		e.UserAgent,
+		metadata,
+		e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// EncodeAuditEventCursor кодирует позицию страницы журнала (seq DESC)
+func EncodeAuditEventCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+// DecodeAuditEventCursor разбирает курсор, полученный из EncodeAuditEventCursor
+func DecodeAuditEventCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("malformed cursor")
+	}
+	seq, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || seq <= 0 {
+		return 0, errors.New("malformed cursor")
+	}
+	return seq, nil
+}
+
+// AuditEventListRequest страница собственных событий пользователя
+type AuditEventListRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// AuditEventSearchRequest параметры поиска по журналу в admin API
+type AuditEventSearchRequest struct {
+	UserID *uuid.UUID
+	Type   AuditEventType
+	IP     *netip.Addr
+	From   *time.Time
+	To     *time.Time
+	Cursor string
+	Limit  int
+}
+
+// AuditEventFilter условия выборки событий на уровне хранилища
+type AuditEventFilter struct {
+	UserID *uuid.UUID
+	Type   AuditEventType
+	IP     *netip.Addr
+	From   *time.Time
+	To     *time.Time
+	// BeforeSeq события строго раньше этой позиции; 0 - с последнего
+	BeforeSeq int64
+	Limit     int
+}
+
+// AuditEventResponse событие в ленте пользователя
+type AuditEventResponse struct {
+	ID        uuid.UUID         `json:"id"`
+	Type      AuditEventType    `json:"type"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// AuditEventListResponse страница событий пользователя, новые первыми
+type AuditEventListResponse struct {
+	Events     []AuditEventResponse `json:"events"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// AdminAuditEvent событие в ответах admin API вместе с позицией в цепочке хэшей
+type AdminAuditEvent struct {
+	AuditEventResponse
+	Seq      int64      `json:"seq"`
+	UserID   *uuid.UUID `json:"user_id,omitempty"`
+	ActorID  *uuid.UUID `json:"actor_id,omitempty"`
+	PrevHash string     `json:"prev_hash"`
+	Hash     string     `json:"hash"`
+}
+
+// AdminAuditEventListResponse страница результатов поиска по журналу
+type AdminAuditEventListResponse struct {
+	Events     []AdminAuditEvent `json:"events"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// Причины, по которым проверка цепочки журнала не прошла
+const (
+	AuditChainHashMismatch = "hash_mismatch"
+	AuditChainBrokenLink   = "broken_link"
+)
+
+// AuditChainReport результат проверки цепочки хэшей журнала. HeadHash стоит сохранять вне
+// сервиса: проверка находит изменения внутри журнала, но не удаление последних записей.
+type AuditChainReport struct {
+	Valid   bool  `json:"valid"`
+	Checked int64 `json:"checked"`
+	HeadSeq int64 `json:"head_seq,omitempty"`
+	// HeadHash хэш последней проверенной записи
+	HeadHash string `json:"head_hash,omitempty"`
+	// BrokenSeq первая запись, не прошедшая проверку
+	BrokenSeq  int64     `json:"broken_seq,omitempty"`
+	Problem    string    `json:"problem,omitempty"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
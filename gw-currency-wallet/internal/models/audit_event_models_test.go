@@ -0,0 +1,82 @@
+package models
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAuditEvent() AuditEvent {
+	userID := uuid.New()
+	ip := netip.MustParseAddr("203.0.113.10")
+	return AuditEvent{
+		ID:        uuid.New(),
+		Seq:       7,
+		Type:      AuditEventLoginSucceeded,
+		UserID:    &userID,
+		ActorID:   &userID,
+		IP:        &ip,
+		UserAgent: "curl/8.0",
+		Metadata:  map[string]string{"amr": "pwd", "b": "2"},
+		CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 123456000, time.UTC),
+		PrevHash:  "abc",
+	}
+}
+
+func TestAuditEvent_ComputeHash(t *testing.T) {
+	event := testAuditEvent()
+	hash := event.ComputeHash()
+	assert.Len(t, hash, 64)
+
+	// Значения, прочитанные из БД, дают тот же хэш: другая зона времени, seq и сохраненный хэш не участвуют
+	stored := event
+	stored.CreatedAt = event.CreatedAt.In(time.FixedZone("MSK", 3*60*60))
+	stored.Seq = 100
+	stored.Hash = hash
+	stored.Metadata = map[string]string{"b": "2", "amr": "pwd"}
+	assert.Equal(t, hash, stored.ComputeHash())
+
+	// Пустые метаданные в БД хранятся как {}
+	empty := event
+	empty.Metadata = nil
+	emptyMap := event
+	emptyMap.Metadata = map[string]string{}
+	assert.Equal(t, empty.ComputeHash(), emptyMap.ComputeHash())
+
+	otherIP := netip.MustParseAddr("203.0.113.11")
+	otherUser := uuid.New()
+	changes := map[string]func(e *AuditEvent){
+		"prev hash":  func(e *AuditEvent) { e.PrevHash = "abd" },
+		"type":       func(e *AuditEvent) { e.Type = AuditEventLoginFailed },
+		"user":       func(e *AuditEvent) { e.UserID = &otherUser },
+		"actor":      func(e *AuditEvent) { e.ActorID = nil },
+		"ip":         func(e *AuditEvent) { e.IP = &otherIP },
+		"user agent": func(e *AuditEvent) { e.UserAgent = "curl/8.1" },
+		"metadata":   func(e *AuditEvent) { e.Metadata = map[string]string{"amr": "pwd,otp", "b": "2"} },
+		"created at": func(e *AuditEvent) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) },
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			changed := testAuditEvent()
+			changed.ID = event.ID
+			changed.UserID, changed.ActorID = event.UserID, event.ActorID
+			change(&changed)
+			assert.NotEqual(t, hash, changed.ComputeHash())
+		})
+	}
+}
+
+func TestAuditEventCursor(t *testing.T) {
+	seq, err := DecodeAuditEventCursor(EncodeAuditEventCursor(42))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), seq)
+
+	for _, cursor := range []string{"!!!", EncodeAuditEventCursor(0), EncodeAuditEventCursor(-5), "YWJj"} {
+		_, err := DecodeAuditEventCursor(cursor)
+		assert.Error(t, err, "cursor=%q", cursor)
+	}
+}
@@ -4,35 +4,86 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 // ExchangeRequest запрос на обмен валют
 type ExchangeRequest struct {
-	FromCurrency Currency `json:"from_currency"`
-	ToCurrency   Currency `json:"to_currency"`
-	Amount       float64  `json:"amount"`
-	RequestID    string   `json:"requestID"`
+	FromCurrency Currency        `json:"from_currency"`
+	ToCurrency   Currency        `json:"to_currency"`
+	Amount       decimal.Decimal `json:"amount" swaggertype:"string" example:"100.00"`
+	RequestID    string          `json:"requestID"`
+	// QuoteID выполнить обмен по ранее полученной котировке. Валюты и сумму в этом случае
+	// можно не указывать; если указаны, они должны совпадать с котировкой.
+	QuoteID *uuid.UUID `json:"quote_id,omitempty" swaggertype:"string" example:"6f1c2a9e-3b1d-4c4e-9f0a-2d5b7c8e9f10"`
 }
 
-// ExchangeResponse ответ на обмен валют
-type ExchangeResponse struct {
-	Message         string  `json:"message"`
-	ExchangedAmount float64 `json:"exchanged_amount"`
-	Rate            float64 `json:"rate,omitempty"`
+// ExchangeQuoteRequest запрос котировки обмена
+type ExchangeQuoteRequest struct {
+	FromCurrency Currency        `json:"from_currency"`
+	ToCurrency   Currency        `json:"to_currency"`
+	Amount       decimal.Decimal `json:"amount" swaggertype:"string" example:"100.00"`
 }
 
-// ExchangeRatesResponse ответ с курсами валют
-type ExchangeRatesResponse struct {
-	Rates map[string]float64 `json:"rates"`
+// ExchangeQuoteResponse котировка обмена, действующая до expires_at
+type ExchangeQuoteResponse struct {
+	QuoteID         uuid.UUID       `json:"quote_id"`
+	FromCurrency    string          `json:"from_currency"`
+	ToCurrency      string          `json:"to_currency"`
+	Amount          decimal.Decimal `json:"amount" swaggertype:"string" example:"100.00"`
+	ExchangedAmount decimal.Decimal `json:"exchanged_amount" swaggertype:"string" example:"92.00"`
+	Rate            decimal.Decimal `json:"rate" swaggertype:"string" example:"0.92"`
+	Fee             decimal.Decimal `json:"fee" swaggertype:"string" example:"0.50"`
+	ExpiresAt       time.Time       `json:"expires_at"`
 }
-type ExchangeOperation struct {
+
+// ExchangeQuote зафиксированные курс и суммы обмена. Используется один раз и только своим пользователем.
+type ExchangeQuote struct {
 	ID              uuid.UUID
 	UserID          uuid.UUID
-	FromCurrency    string
-	ToCurrency      string
+	FromCurrency    Currency
+	ToCurrency      Currency
 	Amount          int64
 	ExchangedAmount int64
-	Rate            float64
-	RequestID       string
+	Rate            decimal.Decimal
+	Fee             int64
+	ExpiresAt       time.Time
+	UsedAt          *time.Time
 	CreatedAt       time.Time
 }
+
+// UsableAt можно ли выполнить обмен по котировке в момент now
+func (q *ExchangeQuote) UsableAt(now time.Time) bool {
+	return q.UsedAt == nil && now.Before(q.ExpiresAt)
+}
+
+// ExchangeResponse ответ на обмен валют
+type ExchangeResponse struct {
+	Message         string          `json:"message"`
+	ExchangedAmount decimal.Decimal `json:"exchanged_amount" swaggertype:"string" example:"92.00"`
+	// Rate курс для клиента с учетом спреда
+	Rate decimal.Decimal `json:"rate" swaggertype:"string" example:"0.92"`
+	// Fee комиссия в валюте списания; конвертируется сумма за вычетом комиссии
+	Fee         decimal.Decimal `json:"fee" swaggertype:"string" example:"0.50"`
+	FeeCurrency string          `json:"fee_currency" example:"USD"`
+}
+
+// ExchangeRatesResponse ответ с курсами валют
+type ExchangeRatesResponse struct {
+	Rates map[string]decimal.Decimal `json:"rates" swaggertype:"object,string"`
+}
+type ExchangeOperation struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	FromCurrency     string
+	ToCurrency       string
+	Amount           int64
+	ExchangedAmount  int64
+	Rate             decimal.Decimal
+	FromBalanceAfter int64
+	ToBalanceAfter   int64
+	RequestID        string
+	QuoteID          *uuid.UUID
+	Fee              int64
+	CreatedAt        time.Time
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// PricingAnyCurrency код валюты в правилах ценообразования, совпадающий с любой валютой
+const PricingAnyCurrency = "*"
+
+// FeeTier ступень комиссии обмена. Суммы в минимальных единицах валюты списания.
+type FeeTier struct {
+	MinAmount int64
+	Percent   decimal.Decimal // доля суммы, 0.005 = 0.5%
+	Fixed     int64
+}
+
+// ExchangePricing спред и комиссия обмена для пары валют
+type ExchangePricing struct {
+	FromCurrency string
+	ToCurrency   string
+	SpreadBps    int32
+	Tiers        []FeeTier // по возрастанию MinAmount
+}
+
+// ClientRate курс для клиента: средний курс за вычетом спреда
+func (p ExchangePricing) ClientRate(mid decimal.Decimal) decimal.Decimal {
+	if p.SpreadBps == 0 {
+		return mid
+	}
+	return mid.Mul(decimal.NewFromInt(1).Sub(decimal.New(int64(p.SpreadBps), -4))).Round(10)
+}
+
+// Fee комиссия с суммы amount. Ступень с наибольшим MinAmount <= amount применяется ко всей сумме.
+func (p ExchangePricing) Fee(amount int64, mode RoundingMode) (int64, error) {
+	i := sort.Search(len(p.Tiers), func(i int) bool { return p.Tiers[i].MinAmount > amount }) - 1
+	if i < 0 {
+		return 0, nil
+	}
+	tier := p.Tiers[i]
+
+	percentFee, err := RoundToMinorUnits(decimal.NewFromInt(amount).Mul(tier.Percent), 0, mode)
+	if err != nil {
+		return 0, err
+	}
+	return percentFee + tier.Fixed, nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangePricing_Fee(t *testing.T) {
+	pricing := ExchangePricing{
+		Tiers: []FeeTier{
+			{MinAmount: 1000, Percent: decimal.RequireFromString("0.01"), Fixed: 25},
+			{MinAmount: 100000, Percent: decimal.RequireFromString("0.005")},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		amount int64
+		want   int64
+	}{
+		{"below first tier", 999, 0},
+		{"first tier boundary", 1000, 35},
+		{"first tier rounded", 1234, 37},
+		{"second tier boundary", 100000, 500},
+		{"second tier", 250050, 1250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pricing.Fee(tt.amount, RoundHalfUp)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExchangePricing_ClientRate(t *testing.T) {
+	mid := decimal.RequireFromString("92.5")
+
+	assert.True(t, ExchangePricing{}.ClientRate(mid).Equal(mid))
+	assert.Equal(t, "92.0375", ExchangePricing{SpreadBps: 50}.ClientRate(mid).String())
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// MaxHoldDescriptionLength максимальная длина описания холда
+const MaxHoldDescriptionLength = 255
+
+// HoldStatus состояние холда. Из ACTIVE холд переходит в одно из конечных состояний.
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "ACTIVE"
+	HoldStatusCaptured HoldStatus = "CAPTURED"
+	HoldStatusReleased HoldStatus = "RELEASED"
+	HoldStatusExpired  HoldStatus = "EXPIRED"
+)
+
+// Hold средства кошелька, зарезервированные до списания или освобождения.
+// Пока холд активен, его сумма недоступна для выводов, обменов и переводов.
+type Hold struct {
+	ID       uuid.UUID
+	WalletID uuid.UUID
+	UserID   uuid.UUID
+	Currency string
+	Amount   int64
+	// CapturedAmount списанная сумма; остаток холда при списании освобождается
+	CapturedAmount int64
+	Status         HoldStatus
+	Description    string
+	RequestID      string
+	ExpiresAt      time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ActiveAt действует ли холд в момент now. Истекший холд освобождается фоновой задачей,
+// но списать или освободить его вручную уже нельзя.
+func (h *Hold) ActiveAt(now time.Time) bool {
+	return h.Status == HoldStatusActive && now.Before(h.ExpiresAt)
+}
+
+// CaptureRequestID идентификатор операции списания по холду в истории и главной книге
+func (h *Hold) CaptureRequestID() string {
+	return "hold:" + h.ID.String()
+}
+
+// CreateHoldRequest запрос на резервирование средств
+type CreateHoldRequest struct {
+	Amount      decimal.Decimal `json:"amount" swaggertype:"string" example:"25.00"`
+	Currency    Currency        `json:"currency"`
+	Description string          `json:"description,omitempty" example:"order #1042"`
+	// ExpiresIn срок действия холда в секундах; по умолчанию HOLD_DEFAULT_TTL
+	ExpiresIn int64  `json:"expires_in,omitempty" example:"3600"`
+	RequestID string `json:"requestID"`
+}
+
+// CaptureHoldRequest списание по холду. Без amount списывается вся сумма холда.
+type CaptureHoldRequest struct {
+	Amount *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"20.00"`
+}
+
+// HoldResponse холд в ответах API
+type HoldResponse struct {
+	ID             uuid.UUID        `json:"id"`
+	WalletID       uuid.UUID        `json:"wallet_id"`
+	Currency       string           `json:"currency" example:"USD"`
+	Amount         decimal.Decimal  `json:"amount" swaggertype:"string" example:"25.00"`
+	CapturedAmount *decimal.Decimal `json:"captured_amount,omitempty" swaggertype:"string" example:"20.00"`
+	Status         HoldStatus       `json:"status" example:"ACTIVE"`
+	Description    string           `json:"description,omitempty"`
+	RequestID      string           `json:"requestID"`
+	ExpiresAt      time.Time        `json:"expires_at"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyHeader заголовок, которым клиент помечает повторяемый запрос
+const IdempotencyHeader = "Idempotency-Key"
+
+// IdempotencyReplayedHeader выставляется в ответе, восстановленном из сохраненного
+const IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+// IdempotencyRecord запрос с ключом Idempotency-Key и его результат
+type IdempotencyRecord struct {
+	UserID      uuid.UUID
+	Key         string
+	Fingerprint string
+	// StatusCode 0, пока первый запрос выполняется
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Completed есть ли у записи сохраненный ответ
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Системные счета главной книги (по одному на валюту)
+const (
+	// SystemAccountExternalCash деньги за пределами системы: источник пополнений и получатель выводов
+	SystemAccountExternalCash = "EXTERNAL_CASH"
+	// SystemAccountFXHouse обменный пункт: контрагент обеих ног обмена валют
+	SystemAccountFXHouse = "FX_HOUSE"
+	// SystemAccountManualAdjustment контрагент ручных корректировок баланса
+	SystemAccountManualAdjustment = "MANUAL_ADJUSTMENT"
+	// SystemAccountFeeIncome доход от комиссий за обмен
+	SystemAccountFeeIncome = "FEE_INCOME"
+	// SystemAccountHoldSettlement получатель средств, списанных по холдам
+	SystemAccountHoldSettlement = "HOLD_SETTLEMENT"
+)
+
+// JournalEntry проводка главной книги, объединяющая сбалансированный набор записей
+type JournalEntry struct {
+	ID        uuid.UUID
+	EntryType OperationType
+	RequestID string
+	Postings  []Posting
+	CreatedAt time.Time
+}
+
+// Posting запись по счету: положительная сумма увеличивает остаток счета, отрицательная уменьшает.
+// Счет кошелька имеет тот же ID, что и сам кошелек.
+type Posting struct {
+	AccountID uuid.UUID
+	Currency  string
+	Amount    int64
+}
+
+// Validate проверяет, что проводка сбалансирована: сумма записей в каждой валюте равна нулю
+func (e JournalEntry) Validate() error {
+	if e.RequestID == "" {
+		return errors.New("journal entry requires request id")
+	}
+	if len(e.Postings) < 2 {
+		return errors.New("journal entry requires at least two postings")
+	}
+
+	totals := make(map[string]int64, 2)
+	for _, p := range e.Postings {
+		if p.Amount == 0 {
+			return errors.New("posting amount must not be zero")
+		}
+		totals[p.Currency] += p.Amount
+	}
+	for currency, total := range totals {
+		if total != 0 {
+			return fmt.Errorf("journal entry is not balanced in %s: %d", currency, total)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// LimitPeriod скользящее окно, за которое суммируются операции
+type LimitPeriod string
+
+const (
+	// LimitPeriodDaily последние 24 часа
+	LimitPeriodDaily LimitPeriod = "DAILY"
+	// LimitPeriodMonthly последние 30 дней
+	LimitPeriodMonthly LimitPeriod = "MONTHLY"
+)
+
+// LimitPeriods периоды в порядке проверки
+var LimitPeriods = []LimitPeriod{LimitPeriodDaily, LimitPeriodMonthly}
+
+func (p LimitPeriod) IsValid() bool {
+	return p == LimitPeriodDaily || p == LimitPeriodMonthly
+}
+
+// Window длительность окна периода
+func (p LimitPeriod) Window() time.Duration {
+	if p == LimitPeriodMonthly {
+		return 30 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// IsLimitedOperation можно ли задать лимит для типа операции. Для обмена и перевода
+// лимит считается в валюте списания.
+func IsLimitedOperation(t OperationType) bool {
+	switch t {
+	case OperationDeposit, OperationWithdraw, OperationExchange, OperationTransferOut:
+		return true
+	}
+	return false
+}
+
+// TransactionLimit лимит суммы операций одного типа в валюте за период.
+// UserID nil - лимит по умолчанию, иначе переопределение для пользователя.
+type TransactionLimit struct {
+	UserID        *uuid.UUID
+	OperationType OperationType
+	Currency      string
+	Period        LimitPeriod
+	// MaxAmount в минимальных единицах; nil - без ограничения (только в переопределении)
+	MaxAmount *int64
+	UpdatedAt time.Time
+}
+
+// LimitExceededError операция превышает лимит; Remaining - сколько еще можно провести в периоде
+type LimitExceededError struct {
+	OperationType OperationType
+	Currency      string
+	Period        LimitPeriod
+	Limit         decimal.Decimal
+	Remaining     decimal.Decimal
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s %s limit of %s %s exceeded, remaining %s",
+		e.Period, e.OperationType, e.Limit, e.Currency, e.Remaining)
+}
+
+func (e *LimitExceededError) Unwrap() error {
+	return custom_err.ErrLimitExceeded
+}
+
+// LimitExceededResponse ответ на операцию, превышающую лимит
+type LimitExceededResponse struct {
+	Error         string          `json:"error" example:"limit_exceeded"`
+	Message       string          `json:"message"`
+	OperationType OperationType   `json:"operation_type" example:"WITHDRAW"`
+	Currency      string          `json:"currency" example:"USD"`
+	Period        LimitPeriod     `json:"period" example:"DAILY"`
+	Limit         decimal.Decimal `json:"limit" swaggertype:"string" example:"1000.00"`
+	Remaining     decimal.Decimal `json:"remaining" swaggertype:"string" example:"250.00"`
+}
+
+// UserLimit действующий лимит пользователя в ответах admin API
+type UserLimit struct {
+	OperationType OperationType `json:"operation_type" example:"WITHDRAW"`
+	Currency      string        `json:"currency" example:"USD"`
+	Period        LimitPeriod   `json:"period" example:"DAILY"`
+	// Amount null - без ограничения
+	Amount *decimal.Decimal `json:"amount" swaggertype:"string" example:"1000.00"`
+	// Overridden лимит задан для пользователя, а не по умолчанию
+	Overridden bool `json:"overridden"`
+}
+
+// UserLimitsResponse действующие лимиты пользователя
+type UserLimitsResponse struct {
+	Limits []UserLimit `json:"limits"`
+}
+
+// SetUserLimitRequest переопределение лимита для пользователя.
+// Amount задает лимит, Unlimited снимает его; без обоих полей действует лимит по умолчанию.
+type SetUserLimitRequest struct {
+	OperationType OperationType    `json:"operation_type" example:"WITHDRAW"`
+	Currency      string           `json:"currency" example:"USD"`
+	Period        LimitPeriod      `json:"period" example:"DAILY"`
+	Amount        *decimal.Decimal `json:"amount,omitempty" swaggertype:"string" example:"5000.00"`
+	Unlimited     bool             `json:"unlimited,omitempty"`
+	Reason        string           `json:"reason"`
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultLimitOrdersLimit = 20
+	MaxLimitOrdersLimit     = 100
+)
+
+// LimitOrderStatus состояние лимитного ордера. Из OPEN ордер переходит в одно из конечных состояний.
+type LimitOrderStatus string
+
+const (
+	LimitOrderStatusOpen      LimitOrderStatus = "OPEN"
+	LimitOrderStatusFilled    LimitOrderStatus = "FILLED"
+	LimitOrderStatusCancelled LimitOrderStatus = "CANCELLED"
+	LimitOrderStatusExpired   LimitOrderStatus = "EXPIRED"
+)
+
+// Valid известен ли статус
+func (s LimitOrderStatus) Valid() bool {
+	switch s {
+	case LimitOrderStatusOpen, LimitOrderStatusFilled, LimitOrderStatusCancelled, LimitOrderStatusExpired:
+		return true
+	}
+	return false
+}
+
+// LimitOrder обмен, который выполняется, когда курс для клиента достигнет Rate.
+// Пока ордер открыт, его сумма зарезервирована на кошельке-источнике.
+type LimitOrder struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	WalletID     uuid.UUID
+	FromCurrency Currency
+	ToCurrency   Currency
+	Amount       int64
+	// Rate минимальный курс для клиента (единиц to_currency за единицу from_currency)
+	Rate   decimal.Decimal
+	Status LimitOrderStatus
+	// Fill условия исполнения; заполнены для FILLED
+	Fill      LimitOrderFill
+	RequestID string
+	// ExpiresAt nil - ордер действует до отмены
+	ExpiresAt *time.Time
+	FilledAt  *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// LimitOrderFill условия, на которых исполнен ордер
+type LimitOrderFill struct {
+	ExchangedAmount int64
+	Rate            decimal.Decimal
+	Fee             int64
+}
+
+// OpenAt действует ли ордер в момент now. Истекший ордер закрывается фоновой задачей,
+// но исполнить или отменить его уже нельзя.
+func (o *LimitOrder) OpenAt(now time.Time) bool {
+	return o.Status == LimitOrderStatusOpen && (o.ExpiresAt == nil || now.Before(*o.ExpiresAt))
+}
+
+// ExchangeRequestID идентификатор обмена по ордеру в истории и главной книге
+func (o *LimitOrder) ExchangeRequestID() string {
+	return "limit-order:" + o.ID.String()
+}
+
+// CreateLimitOrderRequest запрос на создание лимитного ордера
+type CreateLimitOrderRequest struct {
+	FromCurrency Currency        `json:"from_currency"`
+	ToCurrency   Currency        `json:"to_currency"`
+	Amount       decimal.Decimal `json:"amount" swaggertype:"string" example:"1000.00"`
+	// Rate минимальный курс для клиента с учетом спреда
+	Rate decimal.Decimal `json:"rate" swaggertype:"string" example:"0.95"`
+	// ExpiresAt срок действия; без него ордер действует до отмены
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RequestID string     `json:"requestID"`
+}
+
+// LimitOrderListRequest параметры списка ордеров пользователя
+type LimitOrderListRequest struct {
+	Status LimitOrderStatus
+	Limit  int
+}
+
+// LimitOrderResponse лимитный ордер в ответах API
+type LimitOrderResponse struct {
+	ID              uuid.UUID        `json:"id"`
+	FromCurrency    string           `json:"from_currency" example:"USD"`
+	ToCurrency      string           `json:"to_currency" example:"EUR"`
+	Amount          decimal.Decimal  `json:"amount" swaggertype:"string" example:"1000.00"`
+	Rate            decimal.Decimal  `json:"rate" swaggertype:"string" example:"0.95"`
+	Status          LimitOrderStatus `json:"status" example:"OPEN"`
+	ExchangedAmount *decimal.Decimal `json:"exchanged_amount,omitempty" swaggertype:"string" example:"951.20"`
+	ExecutedRate    *decimal.Decimal `json:"executed_rate,omitempty" swaggertype:"string" example:"0.9512"`
+	Fee             *decimal.Decimal `json:"fee,omitempty" swaggertype:"string" example:"0.00"`
+	RequestID       string           `json:"requestID"`
+	ExpiresAt       *time.Time       `json:"expires_at,omitempty"`
+	FilledAt        *time.Time       `json:"filled_at,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+// LimitOrderListResponse список ордеров, новые первыми
+type LimitOrderListResponse struct {
+	Orders []LimitOrderResponse `json:"orders"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"math"
+	"net/netip"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginThrottleScope по чему считаются неверные попытки входа
+type LoginThrottleScope string
+
+const (
+	LoginThrottleByUsername LoginThrottleScope = "username"
+	LoginThrottleByIP       LoginThrottleScope = "ip"
+)
+
+// Причины неудачного входа в журнале login_failures
+const (
+	LoginFailureInvalidPassword = "invalid_password"
+	LoginFailureUnknownUser     = "unknown_user"
+	// LoginFailureThrottled попытка отклонена без проверки пароля: ключ в периоде ожидания
+	LoginFailureThrottled = "throttled"
+	// LoginFailureLocked попытка отклонена без проверки пароля: учетная запись заблокирована
+	LoginFailureLocked = "locked"
+)
+
+// LoginThrottle счетчик неверных попыток входа по имени пользователя или адресу
+type LoginThrottle struct {
+	Scope          LoginThrottleScope
+	Key            string
+	FailedAttempts int
+	BlockedUntil   *time.Time
+	LastFailedAt   time.Time
+}
+
+// LoginFailure запись журнала неудачных входов. UserID пуст, если пользователя с таким именем нет
+type LoginFailure struct {
+	ID        uuid.UUID
+	Username  string
+	UserID    *uuid.UUID
+	IP        *netip.Addr
+	Reason    string
+	CreatedAt time.Time
+}
+
+// LoginBackoff экспоненциальная задержка после неверных попыток: первые FreeAttempts попыток
+// бесплатны, дальше каждая следующая удваивает ожидание начиная с Base, но не больше Max
+type LoginBackoff struct {
+	FreeAttempts int
+	Base         time.Duration
+	Max          time.Duration
+}
+
+// Delay ожидание после failedAttempts неверных попыток подряд
+func (b LoginBackoff) Delay(failedAttempts int) time.Duration {
+	over := failedAttempts - b.FreeAttempts
+	if over <= 0 || b.Base <= 0 {
+		return 0
+	}
+	// Сдвиг ограничен, чтобы не переполнить Duration
+	shift := min(over-1, 62)
+	delay := float64(b.Base) * math.Pow(2, float64(shift))
+	if b.Max > 0 && delay >= float64(b.Max) {
+		return b.Max
+	}
+	if delay >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// LoginThrottledError вход временно недоступен; RetryAfter - через сколько можно повторить
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return custom_err.ErrLoginThrottled
+}
+
+// UnlockUserRequest снятие блокировки входа администратором
+type UnlockUserRequest struct {
+	Reason string `json:"reason"`
+}
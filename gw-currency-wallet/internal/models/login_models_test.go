@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gw-currency-wallet/internal/custom_err"
+)
+
+func TestLoginBackoff_Delay(t *testing.T) {
+	backoff := LoginBackoff{FreeAttempts: 3, Base: time.Second, Max: time.Minute}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 0},
+		{attempts: 3, want: 0},
+		{attempts: 4, want: time.Second},
+		{attempts: 5, want: 2 * time.Second},
+		{attempts: 8, want: 16 * time.Second},
+		{attempts: 10, want: time.Minute},
+		{attempts: 1000, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, backoff.Delay(tt.attempts), "attempts=%d", tt.attempts)
+	}
+
+	// Без верхней границы задержка все равно не переполняется
+	assert.Positive(t, LoginBackoff{Base: time.Second}.Delay(500))
+}
+
+func TestLoginThrottledError(t *testing.T) {
+	var err error = &LoginThrottledError{RetryAfter: 90 * time.Second}
+	assert.True(t, errors.Is(err, custom_err.ErrLoginThrottled))
+
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.Equal(t, 90*time.Second, throttled.RetryAfter)
+}
+
+func TestUser_LockedAt(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	assert.False(t, (&User{}).LockedAt(now))
+	assert.False(t, (&User{LockedUntil: &past}).LockedAt(now))
+	assert.True(t, (&User{LockedUntil: &future}).LockedAt(now))
+}
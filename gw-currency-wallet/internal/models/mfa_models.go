@@ -0,0 +1,139 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Способы аутентификации в claim amr (RFC 8176)
+const (
+	// AMRPassword вход по паролю
+	AMRPassword = "pwd"
+	// AMROTP подтверждение одноразовым кодом: TOTP или кодом восстановления
+	AMROTP = "otp"
+)
+
+// UserMFA настройки второго фактора пользователя
+type UserMFA struct {
+	UserID     uuid.UUID
+	TOTPSecret string // base32 без выравнивания, зашифрованный secretbox
+	// EnabledAt момент подтверждения первым кодом; nil, пока подключение не завершено
+	EnabledAt *time.Time
+	// LastUsedStep последний принятый интервал TOTP; коды этого и более ранних интервалов отклоняются
+	LastUsedStep   int64
+	FailedAttempts int
+	// LockedUntil до этого момента проверка кодов отклоняется после серии неверных попыток
+	LockedUntil *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Enabled включена ли двухфакторная аутентификация
+func (m *UserMFA) Enabled() bool {
+	return m.EnabledAt != nil
+}
+
+// LockedAt заблокирована ли проверка кодов в момент now
+func (m *UserMFA) LockedAt(now time.Time) bool {
+	return m.LockedUntil != nil && now.Before(*m.LockedUntil)
+}
+
+// MFAChallenge серверная запись токена второго шага входа. Сам токен не хранится, только его хэш
+type MFAChallenge struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// UsableAt можно ли завершить вход по токену в момент now
+func (c *MFAChallenge) UsableAt(now time.Time) bool {
+	return c.UsedAt == nil && now.Before(c.ExpiresAt)
+}
+
+// TOTPEnrollRequest начало подключения TOTP; пароль подтверждает, что запрос делает владелец
+type TOTPEnrollRequest struct {
+	Password string `json:"password"`
+}
+
+// TOTPEnrollResponse секрет для приложения-аутентификатора
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	// OTPAuthURI содержимое QR кода для приложения-аутентификатора
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/GW%20Wallet:alice?algorithm=SHA1&digits=6&issuer=GW+Wallet&period=30&secret=JBSWY3DPEHPK3PXP"`
+}
+
+// MFACodeRequest код из приложения-аутентификатора или код восстановления
+type MFACodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// LoginMFARequest второй шаг входа
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code" example:"123456"`
+}
+
+func (r LoginMFARequest) Validate() error {
+	if r.MFAToken == "" {
+		return errors.New("mfa_token is required")
+	}
+	if r.Code == "" {
+		return errors.New("code is required")
+	}
+	return nil
+}
+
+// RecoveryCodesResponse коды восстановления; показываются один раз
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"k7m2p-9xq4d,3hv8n-c2wtr"`
+}
+
+// MFAStatusResponse состояние двухфакторной аутентификации пользователя
+type MFAStatusResponse struct {
+	Enabled bool `json:"enabled"`
+	// RecoveryCodesRemaining сколько неиспользованных кодов восстановления осталось
+	RecoveryCodesRemaining int `json:"recovery_codes_remaining"`
+}
+
+// StepUpOperation валюта и сумма списания из тела запроса операции, по которым решается, нужен ли step-up.
+// Вывод передает валюту в currency, обмены - в from_currency; обмен по котировке может не содержать суммы.
+// Списание по холду передает ID холда в пути, а сумму - необязательно в amount.
+type StepUpOperation struct {
+	Amount       decimal.Decimal `json:"amount"`
+	Currency     Currency        `json:"currency"`
+	FromCurrency Currency        `json:"from_currency"`
+	QuoteID      *uuid.UUID      `json:"quote_id"`
+	HoldID       *uuid.UUID      `json:"-"`
+}
+
+// DebitCurrency валюта списания
+func (o StepUpOperation) DebitCurrency() Currency {
+	if o.FromCurrency != "" {
+		return o.FromCurrency
+	}
+	return o.Currency
+}
+
+// ParseStepUpThresholds разбирает пороги step-up из конфигурации вида USD:1000,EUR:1000
+func ParseStepUpThresholds(raw map[string]string) (map[Currency]decimal.Decimal, error) {
+	thresholds := make(map[Currency]decimal.Decimal, len(raw))
+	for code, value := range raw {
+		amount, err := decimal.NewFromString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid step-up threshold %q for %s", value, code)
+		}
+		if amount.IsNegative() {
+			return nil, fmt.Errorf("step-up threshold for %s must not be negative", code)
+		}
+		thresholds[Currency(strings.ToUpper(strings.TrimSpace(code)))] = amount
+	}
+	return thresholds, nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStepUpThresholds(t *testing.T) {
+	thresholds, err := ParseStepUpThresholds(map[string]string{"usd": " 1000 ", "EUR": "250.50"})
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(1000).Equal(thresholds["USD"]))
+	assert.True(t, decimal.RequireFromString("250.50").Equal(thresholds["EUR"]))
+
+	empty, err := ParseStepUpThresholds(nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = ParseStepUpThresholds(map[string]string{"USD": "many"})
+	assert.Error(t, err)
+	_, err = ParseStepUpThresholds(map[string]string{"USD": "-1"})
+	assert.Error(t, err)
+}
+
+func TestJWTClaims_SteppedUp(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		amr      []string
+		authTime *jwt.NumericDate
+		want     bool
+	}{
+		{name: "password only", amr: []string{AMRPassword}, authTime: jwt.NewNumericDate(now), want: false},
+		{name: "fresh otp", amr: []string{AMRPassword, AMROTP}, authTime: jwt.NewNumericDate(now.Add(-time.Minute)), want: true},
+		{name: "stale otp", amr: []string{AMRPassword, AMROTP}, authTime: jwt.NewNumericDate(now.Add(-10 * time.Minute)), want: false},
+		{name: "otp without auth time", amr: []string{AMRPassword, AMROTP}, want: false},
+		{name: "token issued before mfa support"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := &JWTClaims{AMR: tt.amr, AuthTime: tt.authTime}
+			assert.Equal(t, tt.want, claims.SteppedUp(5*time.Minute, now))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"math"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultMinorUnitExponent точность валюты, которой нет в реестре (например, удаленной из него)
+const DefaultMinorUnitExponent = 2
+
+var ErrAmountOverflow = errors.New("amount is out of range")
+
+// RoundingMode правило округления при конвертации сумм (обмен, перевод с конвертацией)
+type RoundingMode string
+
+const (
+	// RoundHalfEven банковское округление: половина округляется к четному
+	RoundHalfEven RoundingMode = "HALF_EVEN"
+	// RoundHalfUp половина округляется от нуля
+	RoundHalfUp RoundingMode = "HALF_UP"
+	// RoundDown отбрасывание лишних знаков (к нулю)
+	RoundDown RoundingMode = "DOWN"
+)
+
+// ParseRoundingMode разбирает правило округления из конфигурации
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	mode := RoundingMode(strings.ToUpper(strings.TrimSpace(s)))
+	switch mode {
+	case RoundHalfEven, RoundHalfUp, RoundDown:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown rounding mode %q", s)
+	}
+}
+
+// Round округляет сумму до places знаков после запятой
+func (m RoundingMode) Round(d decimal.Decimal, places int32) decimal.Decimal {
+	switch m {
+	case RoundHalfUp:
+		return d.Round(places)
+	case RoundDown:
+		return d.RoundDown(places)
+	default:
+		return d.RoundBank(places)
+	}
+}
+
+// AmountToMinorUnits точно конвертирует сумму в основных единицах в минимальные единицы валюты
+// с exponent знаками после запятой. Сумма с лишними знаками не округляется, а отклоняется
+// с ошибкой custom_err.ErrAmountPrecision.
+func AmountToMinorUnits(amount decimal.Decimal, exponent int32) (int64, error) {
+	if !amount.Equal(amount.Truncate(exponent)) {
+		return 0, custom_err.ErrAmountPrecision
+	}
+	return minorUnits(amount, exponent)
+}
+
+// RoundToMinorUnits конвертирует результат вычислений в минимальные единицы с округлением
+func RoundToMinorUnits(amount decimal.Decimal, exponent int32, mode RoundingMode) (int64, error) {
+	return minorUnits(mode.Round(amount, exponent), exponent)
+}
+
+// AmountFromMinorUnits конвертирует минимальные единицы в основные
+func AmountFromMinorUnits(amount int64, exponent int32) decimal.Decimal {
+	return decimal.New(amount, -exponent)
+}
+
+func minorUnits(amount decimal.Decimal, exponent int32) (int64, error) {
+	units := amount.Shift(exponent)
+	if units.GreaterThan(decimal.NewFromInt(math.MaxInt64)) || units.LessThan(decimal.NewFromInt(math.MinInt64)) {
+		return 0, ErrAmountOverflow
+	}
+	return units.IntPart(), nil
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+)
+
+func TestAmountToMinorUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		exponent int32
+		want     int64
+		wantErr  error
+	}{
+		{name: "no float truncation", amount: "0.29", exponent: 2, want: 29},
+		{name: "whole amount", amount: "100", exponent: 2, want: 10000},
+		{name: "trailing zeros", amount: "1.100", exponent: 2, want: 110},
+		{name: "negative", amount: "-5.05", exponent: 2, want: -505},
+		{name: "too many decimal places", amount: "1.005", exponent: 2, wantErr: custom_err.ErrAmountPrecision},
+		{name: "overflow", amount: "100000000000000000000", exponent: 2, wantErr: ErrAmountOverflow},
+		{name: "zero exponent", amount: "1500", exponent: 0, want: 1500},
+		{name: "zero exponent rejects fraction", amount: "1500.5", exponent: 0, wantErr: custom_err.ErrAmountPrecision},
+		{name: "three digits", amount: "1.005", exponent: 3, want: 1005},
+		{name: "eight digits", amount: "0.00000001", exponent: 8, want: 1},
+		{name: "eight digits rejects more", amount: "0.000000001", exponent: 8, wantErr: custom_err.ErrAmountPrecision},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AmountToMinorUnits(decimal.RequireFromString(tt.amount), tt.exponent)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRoundToMinorUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		exponent int32
+		mode     RoundingMode
+		want     int64
+	}{
+		{name: "half even rounds to even", amount: "0.125", exponent: 2, mode: RoundHalfEven, want: 12},
+		{name: "half even rounds up above half", amount: "0.1251", exponent: 2, mode: RoundHalfEven, want: 13},
+		{name: "half up", amount: "0.125", exponent: 2, mode: RoundHalfUp, want: 13},
+		{name: "down truncates", amount: "0.129", exponent: 2, mode: RoundDown, want: 12},
+		{name: "exact value unchanged", amount: "92.00", exponent: 2, mode: RoundDown, want: 9200},
+		{name: "zero exponent", amount: "152.5", exponent: 0, mode: RoundHalfEven, want: 152},
+		{name: "three digits", amount: "0.0325", exponent: 3, mode: RoundHalfUp, want: 33},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RoundToMinorUnits(decimal.RequireFromString(tt.amount), tt.exponent, tt.mode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	mode, err := ParseRoundingMode(" half_up ")
+	require.NoError(t, err)
+	assert.Equal(t, RoundHalfUp, mode)
+
+	_, err = ParseRoundingMode("CEILING")
+	assert.Error(t, err)
+}
+
+func TestDepositRequest_AmountJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "decimal string", body: `{"amount": "0.29", "currency": "USD"}`, want: "0.29"},
+		{name: "legacy json number", body: `{"amount": 0.29, "currency": "USD"}`, want: "0.29"},
+		{name: "legacy integer", body: `{"amount": 100, "currency": "USD"}`, want: "100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req DepositRequest
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &req))
+			assert.Equal(t, tt.want, req.Amount.String())
+
+			units, err := AmountToMinorUnits(req.Amount, 2)
+			require.NoError(t, err)
+			assert.Equal(t, req.Amount.Shift(2).IntPart(), units)
+		})
+	}
+
+	var req DepositRequest
+	assert.Error(t, json.Unmarshal([]byte(`{"amount": "abc"}`), &req))
+}
+
+func TestUserBalanceResponse_MarshalsDecimalStrings(t *testing.T) {
+	resp := UserBalanceResponse{
+		"USD": AmountFromMinorUnits(100050, 2),
+		"JPY": AmountFromMinorUnits(1500, 0),
+		"KWD": AmountFromMinorUnits(1005, 3),
+		"EUR": decimal.Zero,
+	}
+
+	body, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"USD": "1000.5", "JPY": "1500", "KWD": "1.005", "EUR": "0"}`, string(body))
+}
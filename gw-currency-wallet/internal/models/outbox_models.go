@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы событий outbox
+const (
+	OutboxEventLargeTransfer = "large_transfer"
+	// OutboxEventReconciliationAlert сверка балансов нашла расхождения
+	OutboxEventReconciliationAlert = "reconciliation_alert"
+)
+
+// OutboxMessage событие, ожидающее отправки в Kafka
+type OutboxMessage struct {
+	ID        uuid.UUID
+	EventType string
+	Key       string
+	Payload   []byte
+	Attempts  int
+	CreatedAt time.Time
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Политики ограничения частоты запросов
+const (
+	// RateLimitPolicyGlobal все запросы с одного адреса
+	RateLimitPolicyGlobal = "global"
+	// RateLimitPolicyAuth регистрация, вход, обновление токенов, подтверждение email и сброс пароля
+	RateLimitPolicyAuth = "auth"
+	// RateLimitPolicyWallet пополнения, выводы, переводы и холды пользователя
+	RateLimitPolicyWallet = "wallet"
+	// RateLimitPolicyExchange котировки, обмены, лимитные ордера и запланированные обмены пользователя
+	RateLimitPolicyExchange = "exchange"
+)
+
+// RateLimit правило корзины токенов: корзина вмещает Burst запросов и пополняется
+// со скоростью Requests за Period
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// ParseRateLimit разбирает правило вида <запросов>/<период>[:<емкость>], например 20/1m или 600/1h:50.
+// Без емкости корзина вмещает столько запросов, сколько пополняется за период.
+func ParseRateLimit(raw string) (RateLimit, error) {
+	spec, burstRaw, hasBurst := strings.Cut(strings.TrimSpace(raw), ":")
+	requestsRaw, periodRaw, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("rate limit %q must look like <requests>/<period>[:<burst>]", raw)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(requestsRaw))
+	if err != nil || requests <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: requests must be a positive integer", raw)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodRaw))
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("rate limit %q: period must be a positive duration", raw)
+	}
+	limit := RateLimit{Requests: requests, Period: period, Burst: requests}
+	if hasBurst {
+		burst, err := strconv.Atoi(strings.TrimSpace(burstRaw))
+		if err != nil || burst <= 0 {
+			return RateLimit{}, fmt.Errorf("rate limit %q: burst must be a positive integer", raw)
+		}
+		limit.Burst = burst
+	}
+	return limit, nil
+}
+
+// ParseRateLimitPolicies разбирает правила политик из конфигурации; политика с пустым правилом не ограничивается
+func ParseRateLimitPolicies(raw map[string]string) (map[string]RateLimit, error) {
+	policies := make(map[string]RateLimit, len(raw))
+	for name, value := range raw {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		limit, err := ParseRateLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", name, err)
+		}
+		policies[name] = limit
+	}
+	return policies, nil
+}
+
+// Rate скорость пополнения корзины, токенов в секунду
+func (l RateLimit) Rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Refill сколько токенов будет в корзине, где было tokens, спустя elapsed
+func (l RateLimit) Refill(tokens float64, elapsed time.Duration) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(l.Burst), tokens+elapsed.Seconds()*l.Rate())
+}
+
+// FullAfter через сколько опустевшая корзина заполнится полностью. Корзину, не использовавшуюся
+// дольше, можно удалить: новая будет такой же.
+func (l RateLimit) FullAfter() time.Duration {
+	return l.durationFor(float64(l.Burst))
+}
+
+// Result итог запроса, у которого после взятия токена в корзине осталось tokens
+func (l RateLimit) Result(tokens float64, allowed bool) RateLimitResult {
+	result := RateLimitResult{
+		Allowed:   allowed,
+		Limit:     l.Burst,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     l.durationFor(float64(l.Burst) - tokens),
+		Policy:    l,
+	}
+	if !allowed {
+		result.RetryAfter = l.durationFor(1 - tokens)
+	}
+	return result
+}
+
+// durationFor за сколько в корзину поступит tokens токенов
+func (l RateLimit) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / l.Rate() * float64(time.Second)))
+}
+
+// RateLimitResult решение по запросу и значения заголовков RateLimit-*
+type RateLimitResult struct {
+	Allowed bool
+	// Limit емкость корзины
+	Limit int
+	// Remaining сколько запросов можно отправить сразу
+	Remaining int
+	// Reset через сколько корзина заполнится полностью
+	Reset time.Duration
+	// RetryAfter через сколько появится токен для отклоненного запроса
+	RetryAfter time.Duration
+	Policy     RateLimit
+}
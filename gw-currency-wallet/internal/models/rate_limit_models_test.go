@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    RateLimit
+		wantErr bool
+	}{
+		{raw: "20/1m", want: RateLimit{Requests: 20, Period: time.Minute, Burst: 20}},
+		{raw: " 600/1h:50 ", want: RateLimit{Requests: 600, Period: time.Hour, Burst: 50}},
+		{raw: "5/30s:1", want: RateLimit{Requests: 5, Period: 30 * time.Second, Burst: 1}},
+		{raw: "20", wantErr: true},
+		{raw: "0/1m", wantErr: true},
+		{raw: "x/1m", wantErr: true},
+		{raw: "20/minute", wantErr: true},
+		{raw: "20/-1m", wantErr: true},
+		{raw: "20/1m:0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseRateLimit(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRateLimitPolicies(t *testing.T) {
+	policies, err := ParseRateLimitPolicies(map[string]string{
+		RateLimitPolicyAuth:   "20/1m",
+		RateLimitPolicyGlobal: "",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]RateLimit{RateLimitPolicyAuth: {Requests: 20, Period: time.Minute, Burst: 20}}, policies)
+
+	_, err = ParseRateLimitPolicies(map[string]string{RateLimitPolicyWallet: "many"})
+	assert.ErrorContains(t, err, RateLimitPolicyWallet)
+}
+
+func TestRateLimit_Refill(t *testing.T) {
+	limit := RateLimit{Requests: 60, Period: time.Minute, Burst: 10}
+
+	assert.Equal(t, 1.0, limit.Rate())
+	assert.InDelta(t, 2.5, limit.Refill(0, 2500*time.Millisecond), 1e-9)
+	assert.Equal(t, 10.0, limit.Refill(8, time.Hour))
+	// Часы могут отличаться между репликами: время назад корзину не опустошает
+	assert.Equal(t, 3.0, limit.Refill(3, -time.Second))
+	assert.Equal(t, 10*time.Second, limit.FullAfter())
+}
+
+func TestRateLimit_Result(t *testing.T) {
+	limit := RateLimit{Requests: 60, Period: time.Minute, Burst: 10}
+
+	allowed := limit.Result(7.4, true)
+	assert.True(t, allowed.Allowed)
+	assert.Equal(t, 10, allowed.Limit)
+	assert.Equal(t, 7, allowed.Remaining)
+	assert.Equal(t, 2600*time.Millisecond, allowed.Reset)
+	assert.Zero(t, allowed.RetryAfter)
+
+	denied := limit.Result(0.25, false)
+	assert.False(t, denied.Allowed)
+	assert.Equal(t, 0, denied.Remaining)
+	assert.Equal(t, 750*time.Millisecond, denied.RetryAfter)
+	assert.Equal(t, limit, denied.Policy)
+}
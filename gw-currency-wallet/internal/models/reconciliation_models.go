@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultReconciliationReportsLimit = 20
+	MaxReconciliationReportsLimit     = 100
+)
+
+// ReconciliationStatus состояние прогона сверки
+type ReconciliationStatus string
+
+const (
+	ReconciliationStatusRunning   ReconciliationStatus = "RUNNING"
+	ReconciliationStatusCompleted ReconciliationStatus = "COMPLETED"
+	ReconciliationStatusFailed    ReconciliationStatus = "FAILED"
+)
+
+// ReconciliationTrigger кто запустил сверку
+type ReconciliationTrigger string
+
+const (
+	ReconciliationTriggerScheduled ReconciliationTrigger = "SCHEDULED"
+	ReconciliationTriggerManual    ReconciliationTrigger = "MANUAL"
+)
+
+// ReconciliationReport итог прогона сверки балансов кошельков с главной книгой.
+// Сами расхождения хранятся отдельно и выгружаются потоком.
+type ReconciliationReport struct {
+	ID      uuid.UUID
+	Trigger ReconciliationTrigger
+	Status  ReconciliationStatus
+	// RequestedBy администратор, запустивший сверку; nil для плановой
+	RequestedBy      *uuid.UUID
+	WalletsChecked   int64
+	DiscrepancyCount int64
+	Error            string
+	StartedAt        time.Time
+	FinishedAt       *time.Time
+}
+
+// WalletReconciliation сохраненные и пересчитанные из истории суммы кошелька в минимальных единицах
+type WalletReconciliation struct {
+	WalletID uuid.UUID
+	UserID   uuid.UUID
+	Currency string
+	// Balance и HeldBalance значения из wallets
+	Balance     int64
+	HeldBalance int64
+	// LedgerBalance сумма записей главной книги по счету кошелька
+	LedgerBalance int64
+	// Reserved сумма активных холдов и открытых лимитных ордеров кошелька
+	Reserved int64
+}
+
+// Matches совпадают ли сохраненные суммы с пересчитанными
+func (w WalletReconciliation) Matches() bool {
+	return w.Balance == w.LedgerBalance && w.HeldBalance == w.Reserved
+}
+
+// ReconciliationReportResponse прогон сверки в ответах API
+type ReconciliationReportResponse struct {
+	ID               uuid.UUID             `json:"id"`
+	Trigger          ReconciliationTrigger `json:"trigger" example:"MANUAL"`
+	Status           ReconciliationStatus  `json:"status" example:"COMPLETED"`
+	RequestedBy      *uuid.UUID            `json:"requested_by,omitempty"`
+	WalletsChecked   int64                 `json:"wallets_checked" example:"125000"`
+	DiscrepancyCount int64                 `json:"discrepancy_count" example:"0"`
+	Error            string                `json:"error,omitempty"`
+	StartedAt        time.Time             `json:"started_at"`
+	FinishedAt       *time.Time            `json:"finished_at,omitempty"`
+}
+
+// ReconciliationReportListResponse прогоны сверки, последние первыми
+type ReconciliationReportListResponse struct {
+	Reports []ReconciliationReportResponse `json:"reports"`
+}
+
+// ReconciliationDiscrepancyRow строка выгрузки расхождений
+type ReconciliationDiscrepancyRow struct {
+	WalletID      uuid.UUID
+	UserID        uuid.UUID
+	Currency      string
+	Balance       decimal.Decimal
+	LedgerBalance decimal.Decimal
+	// BalanceDiff Balance - LedgerBalance
+	BalanceDiff decimal.Decimal
+	HeldBalance decimal.Decimal
+	Reserved    decimal.Decimal
+	// HeldDiff HeldBalance - Reserved
+	HeldDiff decimal.Decimal
+}
+
+// ReconciliationAlertEvent событие в Kafka о найденных расхождениях
+type ReconciliationAlertEvent struct {
+	ReportID         uuid.UUID `json:"report_id"`
+	WalletsChecked   int64     `json:"wallets_checked"`
+	DiscrepancyCount int64     `json:"discrepancy_count"`
+	FinishedAt       time.Time `json:"finished_at"`
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultScheduledExchangesLimit = 20
+	MaxScheduledExchangesLimit     = 100
+)
+
+// ScheduledExchangeStatus состояние запланированного обмена
+type ScheduledExchangeStatus string
+
+const (
+	// ScheduledExchangeStatusActive обмен ждет следующего запуска в NextRunAt
+	ScheduledExchangeStatusActive ScheduledExchangeStatus = "ACTIVE"
+	// ScheduledExchangeStatusCompleted разовый обмен запущен
+	ScheduledExchangeStatusCompleted ScheduledExchangeStatus = "COMPLETED"
+	ScheduledExchangeStatusCancelled ScheduledExchangeStatus = "CANCELLED"
+)
+
+// Valid известен ли статус
+func (s ScheduledExchangeStatus) Valid() bool {
+	switch s {
+	case ScheduledExchangeStatusActive, ScheduledExchangeStatusCompleted, ScheduledExchangeStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ScheduledExchange обмен, который планировщик выполняет в NextRunAt: один раз
+// или повторно по cron-выражению. Средства не резервируются до момента запуска.
+type ScheduledExchange struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	FromCurrency Currency
+	ToCurrency   Currency
+	Amount       int64
+	// Cron выражение повторяющегося обмена в UTC; пустое для разового
+	Cron   string
+	Status ScheduledExchangeStatus
+	// NextRunAt время следующего запуска; nil, если запусков больше не будет
+	NextRunAt *time.Time
+	RequestID string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Recurring повторяется ли обмен
+func (s *ScheduledExchange) Recurring() bool {
+	return s.Cron != ""
+}
+
+// RunRequestID детерминированный requestID обмена для запуска, запланированного на scheduledFor.
+// Повторная попытка того же запуска отклоняется идемпотентностью обмена.
+func (s *ScheduledExchange) RunRequestID(scheduledFor time.Time) string {
+	return fmt.Sprintf("schedule:%s:%d", s.ID, scheduledFor.Unix())
+}
+
+// ScheduledExchangeRunStatus результат запуска запланированного обмена
+type ScheduledExchangeRunStatus string
+
+const (
+	ScheduledExchangeRunSucceeded ScheduledExchangeRunStatus = "SUCCEEDED"
+	ScheduledExchangeRunFailed    ScheduledExchangeRunStatus = "FAILED"
+)
+
+// ScheduledExchangeRun результат одного запуска. Для неуспешного запуска заполнены ErrorCode и Error.
+type ScheduledExchangeRun struct {
+	ID           uuid.UUID
+	ScheduleID   uuid.UUID
+	ScheduledFor time.Time
+	RequestID    string
+	Status       ScheduledExchangeRunStatus
+	// Fill условия обмена для SUCCEEDED
+	Fill      LimitOrderFill
+	ErrorCode string
+	Error     string
+	CreatedAt time.Time
+}
+
+// CreateScheduledExchangeRequest запрос на создание запланированного обмена.
+// Задается ровно одно из полей RunAt (разовый обмен) и Cron (повторяющийся).
+type CreateScheduledExchangeRequest struct {
+	FromCurrency Currency        `json:"from_currency"`
+	ToCurrency   Currency        `json:"to_currency"`
+	Amount       decimal.Decimal `json:"amount" swaggertype:"string" example:"100.00"`
+	RunAt        *time.Time      `json:"run_at,omitempty"`
+	// Cron пять полей (минута час день месяц день_недели) в UTC или @hourly, @daily, @weekly, @monthly
+	Cron      string `json:"cron,omitempty" example:"0 9 * * 1"`
+	RequestID string `json:"requestID"`
+}
+
+// ScheduledExchangeListRequest параметры списка запланированных обменов пользователя
+type ScheduledExchangeListRequest struct {
+	Status ScheduledExchangeStatus
+	Limit  int
+}
+
+// ScheduledExchangeResponse запланированный обмен в ответах API
+type ScheduledExchangeResponse struct {
+	ID           uuid.UUID               `json:"id"`
+	FromCurrency string                  `json:"from_currency" example:"USD"`
+	ToCurrency   string                  `json:"to_currency" example:"EUR"`
+	Amount       decimal.Decimal         `json:"amount" swaggertype:"string" example:"100.00"`
+	Cron         string                  `json:"cron,omitempty" example:"0 9 * * 1"`
+	Status       ScheduledExchangeStatus `json:"status" example:"ACTIVE"`
+	NextRunAt    *time.Time              `json:"next_run_at,omitempty"`
+	RequestID    string                  `json:"requestID"`
+	CreatedAt    time.Time               `json:"created_at"`
+	UpdatedAt    time.Time               `json:"updated_at"`
+}
+
+// ScheduledExchangeListResponse список запланированных обменов, новые первыми
+type ScheduledExchangeListResponse struct {
+	Schedules []ScheduledExchangeResponse `json:"schedules"`
+}
+
+// ScheduledExchangeRunResponse запуск запланированного обмена в ответах API
+type ScheduledExchangeRunResponse struct {
+	ScheduledFor    time.Time                  `json:"scheduled_for"`
+	RequestID       string                     `json:"requestID" example:"schedule:6f1c...:1700000000"`
+	Status          ScheduledExchangeRunStatus `json:"status" example:"SUCCEEDED"`
+	ExchangedAmount *decimal.Decimal           `json:"exchanged_amount,omitempty" swaggertype:"string" example:"92.10"`
+	Rate            *decimal.Decimal           `json:"rate,omitempty" swaggertype:"string" example:"0.921"`
+	Fee             *decimal.Decimal           `json:"fee,omitempty" swaggertype:"string" example:"0.00"`
+	ErrorCode       string                     `json:"error_code,omitempty" example:"insufficient_funds"`
+	Error           string                     `json:"error,omitempty"`
+	CreatedAt       time.Time                  `json:"created_at"`
+}
+
+// ScheduledExchangeRunListResponse запуски обмена, последние первыми
+type ScheduledExchangeRunListResponse struct {
+	Runs []ScheduledExchangeRunResponse `json:"runs"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SigningKey сохраненный ключ подписи JWT
+type SigningKey struct {
+	KID           string
+	Algorithm     string
+	PrivateKey    string // PKCS#8 PEM, зашифрованный secretbox
+	ActivatesAt   time.Time
+	DeactivatesAt time.Time
+	ExpiresAt     time.Time
+	CreatedAt     time.Time
+}
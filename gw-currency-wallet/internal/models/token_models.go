@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken серверная запись refresh токена. Сам токен не хранится, только его хэш
+type RefreshToken struct {
+	ID        uuid.UUID
+	FamilyID  uuid.UUID // общий для всех токенов, выданных ротацией от одного входа
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time // токен уже обменян на новую пару
+	RevokedAt *time.Time
+	// AuthTime и AMR момент и способы входа; переходят к токенам, полученным ротацией
+	AuthTime  time.Time
+	AMR       []string
+	CreatedAt time.Time
+}
+
+// RefreshRequest запрос на обновление пары токенов
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutRequest запрос на выход. Если передан refresh токен, отзывается все его семейство
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// LogoutResponse ответ на выход
+type LogoutResponse struct {
+	Message string `json:"message"`
+}
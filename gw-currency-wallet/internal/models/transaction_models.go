@@ -0,0 +1,114 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultTransactionsLimit = 20
+	MaxTransactionsLimit     = 100
+)
+
+// TransactionHistoryRequest параметры запроса истории операций
+type TransactionHistoryRequest struct {
+	Currency Currency
+	Type     OperationType
+	From     *time.Time
+	To       *time.Time
+	Cursor   string
+	Limit    int
+}
+
+// TransactionFilter фильтр выборки истории на уровне хранилища
+type TransactionFilter struct {
+	Currency Currency
+	Type     OperationType
+	From     *time.Time
+	To       *time.Time
+	After    *TransactionCursor
+	Limit    int
+}
+
+// TransactionCursor позиция в ленте операций (created_at DESC, id DESC)
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode кодирует курсор в непрозрачную строку для клиента
+func (c TransactionCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeTransactionCursor разбирает курсор, полученный от клиента
+func DecodeTransactionCursor(s string) (*TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("malformed cursor")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, errors.New("malformed cursor timestamp")
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return nil, errors.New("malformed cursor id")
+	}
+
+	return &TransactionCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// TransactionRecord строка единой ленты операций в минимальных единицах.
+// Для обмена и исходящего перевода Currency/Amount описывают списание, ToCurrency/ToAmount — зачисление.
+// Counterparty — username второй стороны перевода.
+type TransactionRecord struct {
+	ID             uuid.UUID
+	Type           OperationType
+	Currency       string
+	Amount         int64
+	BalanceAfter   *int64
+	ToCurrency     string
+	ToAmount       int64
+	ToBalanceAfter *int64
+	Rate           decimal.Decimal
+	Fee            int64
+	RequestID      string
+	Counterparty   string
+	CreatedAt      time.Time
+}
+
+// Transaction элемент истории операций
+type Transaction struct {
+	ID             uuid.UUID        `json:"id"`
+	Type           OperationType    `json:"type"`
+	Currency       string           `json:"currency"`
+	Amount         decimal.Decimal  `json:"amount" swaggertype:"string"`
+	BalanceAfter   *decimal.Decimal `json:"balance_after,omitempty" swaggertype:"string"`
+	ToCurrency     string           `json:"to_currency,omitempty"`
+	ToAmount       *decimal.Decimal `json:"to_amount,omitempty" swaggertype:"string"`
+	ToBalanceAfter *decimal.Decimal `json:"to_balance_after,omitempty" swaggertype:"string"`
+	Rate           *decimal.Decimal `json:"rate,omitempty" swaggertype:"string"`
+	Fee            *decimal.Decimal `json:"fee,omitempty" swaggertype:"string"`
+	RequestID      string           `json:"request_id,omitempty"`
+	Counterparty   string           `json:"counterparty,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// TransactionHistoryResponse страница истории операций
+type TransactionHistoryResponse struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// TransferRequest запрос на перевод другому пользователю
+type TransferRequest struct {
+	Recipient  string          `json:"recipient"` // username или email получателя
+	Amount     decimal.Decimal `json:"amount" swaggertype:"string" example:"100.00"`
+	Currency   Currency        `json:"currency"`
+	ToCurrency Currency        `json:"to_currency,omitempty"` // валюта зачисления, по умолчанию совпадает с currency
+	RequestID  string          `json:"requestID"`
+}
+
+// TransferResponse ответ на перевод
+type TransferResponse struct {
+	Message        string          `json:"message"`
+	TransferID     string          `json:"transfer_id"`
+	Recipient      string          `json:"recipient"`
+	Amount         decimal.Decimal `json:"amount" swaggertype:"string" example:"100.00"`
+	Currency       string          `json:"currency"`
+	ReceivedAmount decimal.Decimal `json:"received_amount" swaggertype:"string" example:"92.00"`
+	ToCurrency     string          `json:"to_currency"`
+	Rate           decimal.Decimal `json:"rate" swaggertype:"string" example:"0.92"`
+}
+
+type Transfer struct {
+	ID                    uuid.UUID
+	SenderID              uuid.UUID
+	RecipientID           uuid.UUID
+	FromCurrency          string
+	ToCurrency            string
+	Amount                int64
+	ReceivedAmount        int64
+	Rate                  decimal.Decimal
+	SenderBalanceAfter    int64
+	RecipientBalanceAfter int64
+	RequestID             string
+	CreatedAt             time.Time
+}
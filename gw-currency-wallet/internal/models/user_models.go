@@ -2,6 +2,11 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,8 +19,44 @@ type User struct {
 	Username     string    `json:"username" db:"username"`
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	Role         Role      `json:"role" db:"role"`
+	// EmailVerifiedAt момент подтверждения email; nil, пока адрес не подтвержден
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+	// LockedUntil до какого момента вход заблокирован после серии неверных паролей
+	LockedUntil *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// LockedAt заблокирован ли вход в момент now
+func (u *User) LockedAt(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// EmailVerified подтвержден ли email пользователя
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// MaxUsernameLength максимальная длина имени пользователя
+const MaxUsernameLength = 50
+
+// MaxEmailLength максимальная длина адреса по RFC 5321
+const MaxEmailLength = 254
+
+// Role роль пользователя
+type Role string
+
+const (
+	RoleUser Role = "user"
+	// RoleSupport просмотр данных пользователей через admin API
+	RoleSupport Role = "support"
+	// RoleAdmin полный доступ к admin API
+	RoleAdmin Role = "admin"
+)
+
+func (r Role) IsValid() bool {
+	return r == RoleUser || r == RoleSupport || r == RoleAdmin
 }
 
 // RegisterRequest запрос на регистрацию
@@ -34,35 +75,114 @@ type RegisterResponse struct {
 type LoginRequest struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	// IP адрес клиента для ограничения попыток входа; заполняет обработчик
+	IP netip.Addr `json:"-"`
 }
 
-// LoginResponse ответ на авторизацию
+// LoginResponse ответ на авторизацию и обновление токенов. Если у пользователя включена
+// двухфакторная аутентификация, вход по паролю возвращает только MFAToken для второго шага.
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"` // время жизни access токена в секундах
+	MFARequired  bool   `json:"mfa_required,omitempty"`
+	MFAToken     string `json:"mfa_token,omitempty"`
+	MFAExpiresIn int64  `json:"mfa_expires_in,omitempty"` // время жизни MFAToken в секундах
 }
 
 // JWTClaims кастомные claims для JWT токена
 type JWTClaims struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Username string    `json:"username"`
+	Role     Role      `json:"role"`
+	// EmailVerified подтвержден ли email на момент выдачи токена
+	EmailVerified bool `json:"email_verified,omitempty"`
+	// AMR способы, которыми пользователь подтвердил вход (RFC 8176): pwd, otp
+	AMR []string `json:"amr,omitempty"`
+	// AuthTime момент входа или последнего подтверждения вторым фактором
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
+	// APIKeyID ключ, которым аутентифицирован запрос; nil для запросов с JWT
+	APIKeyID *uuid.UUID `json:"-"`
+	// Scopes разрешения API ключа; в JWT не передаются
+	Scopes []APIKeyScope `json:"-"`
 	jwt.RegisteredClaims
 }
 
+// HasScopes есть ли у запроса все перечисленные разрешения. JWT дает полный доступ
+// пользователя, ограничения действуют только для API ключей.
+func (c *JWTClaims) HasScopes(scopes ...APIKeyScope) bool {
+	if c.APIKeyID == nil {
+		return true
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// SteppedUp подтвержден ли токен вторым фактором не раньше чем maxAge до now
+func (c *JWTClaims) SteppedUp(maxAge time.Duration, now time.Time) bool {
+	if c.AuthTime == nil || !slices.Contains(c.AMR, AMROTP) {
+		return false
+	}
+	return now.Sub(c.AuthTime.Time) <= maxAge
+}
+
 func (r RegisterRequest) Validate() error {
 	if r.Username == "" {
 		return errors.New("username is required")
 	}
-	if len(r.Username) < 3 || len(r.Username) > 50 {
-		return errors.New("username must be 3-50 characters")
+	if len(r.Username) < 3 || len(r.Username) > MaxUsernameLength {
+		return fmt.Errorf("username must be 3-%d characters", MaxUsernameLength)
 	}
-	if r.Password == "" {
+	if err := ValidatePassword(r.Password); err != nil {
+		return err
+	}
+	return ValidateEmail(r.Email)
+}
+
+// ValidatePassword проверяет требования к паролю
+func ValidatePassword(password string) error {
+	if password == "" {
 		return errors.New("password is required")
 	}
-	if len(r.Password) < 6 {
+	if len(password) < 6 {
 		return errors.New("password must be at least 6 characters")
 	}
-	if r.Email == "" {
+	return nil
+}
+
+// ValidateEmail принимает только голый адрес вида local@domain.tld, без отображаемого имени и угловых скобок
+func ValidateEmail(email string) error {
+	if email == "" {
 		return errors.New("email is required")
 	}
+	if len(email) > MaxEmailLength {
+		return errors.New("email is too long")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return errors.New("email is invalid")
+	}
+	at := strings.LastIndexByte(email, '@')
+	domain := email[at+1:]
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return errors.New("email is invalid")
+	}
+	return nil
+}
+
+func (r LoginRequest) Validate() error {
+	if r.Username == "" {
+		return errors.New("username is required")
+	}
+	if len(r.Username) > MaxUsernameLength {
+		return fmt.Errorf("username must be at most %d characters", MaxUsernameLength)
+	}
+	if r.Password == "" {
+		return errors.New("password is required")
+	}
 	return nil
 }
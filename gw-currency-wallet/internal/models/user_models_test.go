@@ -0,0 +1,39 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   string
+		wantErr bool
+	}{
+		{name: "plain address", email: "alice@example.com"},
+		{name: "subdomain and plus tag", email: "alice+wallet@mail.example.co.uk"},
+		{name: "empty", email: "", wantErr: true},
+		{name: "missing at", email: "alice.example.com", wantErr: true},
+		{name: "domain without dot", email: "alice@localhost", wantErr: true},
+		{name: "domain with trailing dot", email: "alice@example.", wantErr: true},
+		{name: "display name", email: "Alice <alice@example.com>", wantErr: true},
+		{name: "angle brackets", email: "<alice@example.com>", wantErr: true},
+		{name: "surrounding spaces", email: " alice@example.com", wantErr: true},
+		{name: "header injection", email: "alice@example.com\r\nBcc: eve@example.com", wantErr: true},
+		{name: "too long", email: strings.Repeat("a", MaxEmailLength) + "@example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEmail(tt.email)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
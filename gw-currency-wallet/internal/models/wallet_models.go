@@ -4,36 +4,74 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 // Wallet представляет кошелек пользователя в определенной валюте
 type Wallet struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	UserID    uuid.UUID `json:"user_id" db:"user_id"`
-	Currency  string    `json:"currency" db:"currency"`
-	Balance   int64     `json:"balance" db:"balance"`
-	Version   int64     `json:"version" db:"version"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID           uuid.UUID  `json:"id" db:"id"`
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	Currency     string     `json:"currency" db:"currency"`
+	Balance      int64      `json:"balance" db:"balance"`
+	HeldBalance  int64      `json:"held_balance" db:"held_balance"` // зарезервировано активными холдами
+	Version      int64      `json:"version" db:"version"`
+	FrozenAt     *time.Time `json:"frozen_at,omitempty" db:"frozen_at"` // заполнено, пока кошелек заморожен
+	FrozenReason string     `json:"frozen_reason,omitempty" db:"frozen_reason"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
 }
 
-// Currency типы
+// Available сумма, доступная для списания: баланс за вычетом холдов
+func (w *Wallet) Available() int64 {
+	return w.Balance - w.HeldBalance
+}
+
+// WalletBalance остаток заблокированного для операции кошелька
+type WalletBalance struct {
+	Balance int64
+	Held    int64
+}
+
+// Available сумма, доступная для списания
+func (b WalletBalance) Available() int64 {
+	return b.Balance - b.Held
+}
+
+// Currency код валюты: ISO 4217 или код токена, например USDT. Список поддерживаемых валют
+// хранится в реестре exchanger сервиса
 type Currency string
 
+// CurrencyCodeMaxLength максимальная длина кода валюты, совпадает с шириной колонок в БД
+const CurrencyCodeMaxLength = 10
+
 const (
 	CurrencyUSD Currency = "USD"
 	CurrencyRUB Currency = "RUB"
 	CurrencyEUR Currency = "EUR"
 )
 
-// IsValid проверяет валидность валюты
+// IsValid проверяет формат кода валюты: от 3 до CurrencyCodeMaxLength заглавных латинских букв
+// и цифр, первая - буква. Поддерживается ли валюта, определяет реестр валют.
 func (c Currency) IsValid() bool {
-	return c == CurrencyUSD || c == CurrencyRUB || c == CurrencyEUR
+	if len(c) < 3 || len(c) > CurrencyCodeMaxLength {
+		return false
+	}
+	for i := 0; i < len(c); i++ {
+		isLetter := c[i] >= 'A' && c[i] <= 'Z'
+		isDigit := c[i] >= '0' && c[i] <= '9'
+		if !isLetter && (i == 0 || !isDigit) {
+			return false
+		}
+	}
+	return true
 }
 
-// SupportedCurrencies возвращает список поддерживаемых валют
-func SupportedCurrencies() []Currency {
-	return []Currency{CurrencyUSD, CurrencyRUB, CurrencyEUR}
+// CurrencyInfo запись реестра валют
+type CurrencyInfo struct {
+	Code     Currency
+	Name     string
+	Exponent int32 // количество знаков после запятой в минимальных единицах
+	Enabled  bool  // по отключенной валюте нельзя проводить новые операции
 }
 
 type OperationType string
@@ -41,52 +79,70 @@ type OperationType string
 const (
 	OperationDeposit  OperationType = "DEPOSIT"
 	OperationWithdraw OperationType = "WITHDRAW"
+	OperationExchange OperationType = "EXCHANGE"
+	OperationTransfer OperationType = "TRANSFER"
+	// OperationAdjustment ручная корректировка баланса администратором
+	OperationAdjustment OperationType = "ADJUSTMENT"
+	// OperationCapture списание по холду
+	OperationCapture OperationType = "CAPTURE"
+
+	// Стороны перевода в истории операций
+	OperationTransferIn  OperationType = "TRANSFER_IN"
+	OperationTransferOut OperationType = "TRANSFER_OUT"
 )
 
 func (ot OperationType) IsValid() bool {
 	return ot == OperationDeposit || ot == OperationWithdraw
 }
 
+// Operation запись о пополнении или выводе средств
+type Operation struct {
+	ID            uuid.UUID
+	WalletID      uuid.UUID
+	OperationType OperationType
+	Amount        int64 // со знаком: > 0 пополнение, < 0 вывод
+	BalanceAfter  int64
+	RequestID     string
+	CreatedAt     time.Time
+}
+
 type WalletOperationRequest struct {
+	UserID        uuid.UUID     `json:"userID"`
 	WalletID      uuid.UUID     `json:"walletID"`
+	Currency      Currency      `json:"currency"`
 	OperationType OperationType `json:"operationType"`
 	Amount        int64         `json:"amount"`
 	RequestID     string        `json:"requestID"`
 }
 
-// UserBalanceResponse ответ с балансами пользователя по всем валютам
-type UserBalanceResponse struct {
-	USD float64 `json:"USD"`
-	RUB float64 `json:"RUB"`
-	EUR float64 `json:"EUR"`
+// UserBalanceResponse балансы пользователя по кодам валют: все включенные валюты реестра
+// и валюты существующих кошельков. Суммы сериализуются десятичными строками.
+type UserBalanceResponse map[string]decimal.Decimal
+
+// BalanceResponse полный баланс и доступная для списания часть (без зарезервированных холдами сумм)
+type BalanceResponse struct {
+	Balance   UserBalanceResponse `json:"balance" swaggertype:"object,string" example:"USD:100.50,RUB:0,EUR:0"`
+	Available UserBalanceResponse `json:"available" swaggertype:"object,string" example:"USD:80.50,RUB:0,EUR:0"`
 }
 
-// DepositRequest запрос на пополнение
+// DepositRequest запрос на пополнение.
+// Сумма принимается десятичной строкой; JSON-число тоже допускается и разбирается без потери точности.
 type DepositRequest struct {
-	Amount    float64  `json:"amount"`
-	Currency  Currency `json:"currency"`
-	RequestID string   `json:"requestID"`
+	Amount    decimal.Decimal `json:"amount" swaggertype:"string" example:"100.50"`
+	Currency  Currency        `json:"currency"`
+	RequestID string          `json:"requestID"`
 }
 
 // WithdrawRequest запрос на вывод средств
 type WithdrawRequest struct {
-	Amount    float64  `json:"amount"`
-	Currency  Currency `json:"currency"`
-	RequestID string   `json:"requestID"`
+	Amount    decimal.Decimal `json:"amount" swaggertype:"string" example:"50.00"`
+	Currency  Currency        `json:"currency"`
+	RequestID string          `json:"requestID"`
 }
 
 // BalanceOperationResponse ответ на операцию пополнения/вывода
 type BalanceOperationResponse struct {
 	Message    string              `json:"message"`
-	NewBalance UserBalanceResponse `json:"new_balance"`
-}
-
-// AmountToMinorUnits конвертирует сумму в основных единицах в минимальные единицы
-func AmountToMinorUnits(amount float64) int64 {
-	return int64(amount * 100)
-}
-
-// AmountFromMinorUnits конвертирует минимальные единицы в основные
-func AmountFromMinorUnits(amount int64) float64 {
-	return float64(amount) / 100.0
+	NewBalance UserBalanceResponse `json:"new_balance" swaggertype:"object,string" example:"USD:100.50,RUB:0,EUR:0"`
+	Available  UserBalanceResponse `json:"available" swaggertype:"object,string" example:"USD:80.50,RUB:0,EUR:0"`
 }
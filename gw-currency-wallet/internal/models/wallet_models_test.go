@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrency_IsValid(t *testing.T) {
+	tests := []struct {
+		code Currency
+		want bool
+	}{
+		{code: "USD", want: true},
+		{code: "USDT", want: true},
+		{code: "USDC", want: true},
+		{code: "1INCH"},
+		{code: "BTC2", want: true},
+		{code: "ABCDEFGHIJ", want: true},
+		{code: "ABCDEFGHIJK"},
+		{code: "US"},
+		{code: "usd"},
+		{code: "US D"},
+		{code: "US-D"},
+		{code: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.code), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.code.IsValid())
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Package secretbox шифрует секреты, которые хранятся в БД: закрытые ключи подписи JWT и секреты TOTP.
+// Используется AES-256-GCM с ключом шифрования ключей (KEK) из окружения, поэтому копия БД, реплика
+// или чтение таблицы через SQL-инъекцию не раскрывают секретов.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize длина ключа шифрования (AES-256)
+const KeySize = 32
+
+// SealedPrefix отличает зашифрованное значение от открытого, записанного до включения шифрования
+const SealedPrefix = "enc:v1:"
+
+var (
+	ErrNotSealed = errors.New("value is not encrypted")
+	ErrOpen      = errors.New("failed to decrypt value")
+)
+
+// Box шифрует и расшифровывает значения одним ключом
+type Box struct {
+	aead cipher.AEAD
+}
+
+// ParseKey разбирает ключ шифрования из base64 (стандартный алфавит); ключ - 32 случайных байта,
+// например из openssl rand -base64 32
+func ParseKey(raw string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, errors.New("encryption key must be base64")
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+func New(key []byte) (*Box, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal шифрует plaintext. associated привязывает значение к строке БД (например, kid или user_id):
+// значение, перенесенное в другую строку, не расшифруется.
+func (b *Box) Seal(plaintext, associated string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), []byte(associated))
+	return SealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open расшифровывает значение из Seal с тем же associated. Для открытого значения возвращает ErrNotSealed.
+func (b *Box) Open(value, associated string) (string, error) {
+	if !IsSealed(value) {
+		return "", ErrNotSealed
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, SealedPrefix))
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", ErrOpen
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, []byte(associated))
+	if err != nil {
+		return "", ErrOpen
+	}
+	return string(plaintext), nil
+}
+
+// IsSealed зашифровано ли значение
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, SealedPrefix)
+}
@@ -0,0 +1,74 @@
+package secretbox
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBox(t *testing.T) *Box {
+	t.Helper()
+
+	box, err := New(make([]byte, KeySize))
+	require.NoError(t, err)
+	return box
+}
+
+func TestBox_SealOpen(t *testing.T) {
+	box := newTestBox(t)
+
+	sealed, err := box.Seal("secret", "row-1")
+	require.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, sealed, "secret")
+
+	// Одинаковые значения шифруются по-разному
+	again, err := box.Seal("secret", "row-1")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again)
+
+	opened, err := box.Open(sealed, "row-1")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", opened)
+}
+
+func TestBox_OpenRejects(t *testing.T) {
+	box := newTestBox(t)
+	sealed, err := box.Seal("secret", "row-1")
+	require.NoError(t, err)
+
+	other, err := New([]byte(strings.Repeat("k", KeySize)))
+	require.NoError(t, err)
+
+	_, err = box.Open(sealed, "row-2")
+	assert.ErrorIs(t, err, ErrOpen, "значение из другой строки")
+
+	_, err = other.Open(sealed, "row-1")
+	assert.ErrorIs(t, err, ErrOpen, "другой ключ")
+
+	_, err = box.Open(sealed[:len(sealed)-4]+"AAAA", "row-1")
+	assert.ErrorIs(t, err, ErrOpen, "измененное значение")
+
+	_, err = box.Open(SealedPrefix+"!", "row-1")
+	assert.ErrorIs(t, err, ErrOpen, "не base64")
+
+	_, err = box.Open("secret", "row-1")
+	assert.ErrorIs(t, err, ErrNotSealed)
+}
+
+func TestParseKey(t *testing.T) {
+	raw := base64.StdEncoding.EncodeToString(make([]byte, KeySize))
+
+	key, err := ParseKey(" " + raw + "\n")
+	require.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	_, err = ParseKey(base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	assert.Error(t, err)
+
+	_, err = ParseKey("not base64!")
+	assert.Error(t, err)
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/mailer"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Account подтверждение email и восстановление пароля по одноразовым токенам из писем
+type Account interface {
+	VerifyEmail(ctx context.Context, req models.VerifyEmailRequest) error
+	ResendVerification(ctx context.Context, userID uuid.UUID) error
+	// ForgotPassword отправляет письмо со ссылкой сброса, если адрес зарегистрирован.
+	// Результат не зависит от существования адреса, чтобы по нему нельзя было перебирать пользователей.
+	ForgotPassword(ctx context.Context, req models.ForgotPasswordRequest) error
+	// ResetPassword меняет пароль и отзывает все refresh токены пользователя
+	ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error
+}
+
+// VerificationSender отправляет пользователю письмо со ссылкой подтверждения email
+type VerificationSender interface {
+	SendVerification(ctx context.Context, user *models.User) error
+}
+
+type AccountService struct {
+	userRepo        postgres.UserRepository
+	tokens          postgres.AccountTokenRepository
+	refreshTokens   postgres.TokenRepository
+	txManager       TxManager
+	mailer          mailer.Mailer
+	audit           AuditRecorder
+	verificationTTL time.Duration
+	resetTTL        time.Duration
+	// linkBaseURL адрес клиентского приложения, в которое ведут ссылки из писем
+	linkBaseURL string
+	log         *slog.Logger
+}
+
+func NewAccountService(
+	userRepo postgres.UserRepository,
+	tokens postgres.AccountTokenRepository,
+	refreshTokens postgres.TokenRepository,
+	txManager TxManager,
+	mailer mailer.Mailer,
+	audit AuditRecorder,
+	verificationTTL time.Duration,
+	resetTTL time.Duration,
+	linkBaseURL string,
+	log *slog.Logger,
+) *AccountService {
+	return &AccountService{
+		userRepo:        userRepo,
+		tokens:          tokens,
+		refreshTokens:   refreshTokens,
+		txManager:       txManager,
+		mailer:          mailer,
+		audit:           audit,
+		verificationTTL: verificationTTL,
+		resetTTL:        resetTTL,
+		linkBaseURL:     strings.TrimRight(linkBaseURL, "/"),
+		log:             log,
+	}
+}
+
+// SendVerification выпускает новый токен подтверждения, гасит прежние и отправляет письмо
+func (s *AccountService) SendVerification(ctx context.Context, user *models.User) error {
+	const op = "service.SendVerification"
+
+	if user.EmailVerified() {
+		return custom_err.ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issueToken(ctx, user.ID, models.AccountTokenEmailVerification, s.verificationTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\n"+
+				"Чтобы подтвердить адрес электронной почты, перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действует до %s. Если вы не регистрировались, просто проигнорируйте это письмо.\n",
+			user.Username, s.link("/verify-email", token), s.expiry(s.verificationTTL)),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: failed to send email: %w", op, err)
+	}
+
+	s.log.Info("письмо подтверждения email отправлено", slog.String("op", op), slog.String("user_id", user.ID.String()))
+	return nil
+}
+
+func (s *AccountService) ResendVerification(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.ResendVerification"
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return custom_err.ErrNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.SendVerification(ctx, user); err != nil {
+		if errors.Is(err, custom_err.ErrEmailAlreadyVerified) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *AccountService) VerifyEmail(ctx context.Context, req models.VerifyEmailRequest) error {
+	const op = "service.VerifyEmail"
+
+	if req.Token == "" {
+		return fmt.Errorf("%w: token is required", custom_err.ErrInvalidInput)
+	}
+
+	var userID uuid.UUID
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		token, err := s.tokens.ConsumeTx(ctx, tx, hashToken(req.Token), models.AccountTokenEmailVerification)
+		if err != nil {
+			if errors.Is(err, custom_err.ErrNotFound) {
+				return custom_err.ErrInvalidToken
+			}
+			return fmt.Errorf("failed to consume token: %w", err)
+		}
+		userID = token.UserID
+
+		if err := s.userRepo.MarkEmailVerifiedTx(ctx, tx, token.UserID); err != nil {
+			return fmt.Errorf("failed to mark email verified: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrInvalidToken) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("email подтвержден", slog.String("op", op), slog.String("user_id", userID.String()))
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventEmailVerified, UserID: &userID})
+	return nil
+}
+
+func (s *AccountService) ForgotPassword(ctx context.Context, req models.ForgotPasswordRequest) error {
+	const op = "service.ForgotPassword"
+
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", custom_err.ErrInvalidInput, err.Error())
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			s.log.Info("сброс пароля для незарегистрированного адреса", slog.String("op", op))
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	token, err := s.issueToken(ctx, user.ID, models.AccountTokenPasswordReset, s.resetTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventPasswordResetRequested, UserID: &user.ID})
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf(
+			"Здравствуйте, %s!\n\n"+
+				"Чтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+				"Ссылка действует до %s и может быть использована один раз. "+
+				"Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+			user.Username, s.link("/reset-password", token), s.expiry(s.resetTTL)),
+	})
+	if err != nil {
+		// Ошибка не возвращается клиенту, иначе ответ выдавал бы существование адреса
+		s.log.Error("не удалось отправить письмо сброса пароля",
+			slog.String("op", op),
+			slog.String("user_id", user.ID.String()),
+			slog.String("error", err.Error()))
+		return nil
+	}
+
+	s.log.Info("письмо сброса пароля отправлено", slog.String("op", op), slog.String("user_id", user.ID.String()))
+	return nil
+}
+
+func (s *AccountService) ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error {
+	const op = "service.ResetPassword"
+
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", custom_err.ErrInvalidInput, err.Error())
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("%s: failed to hash password: %w", op, err)
+	}
+
+	var userID uuid.UUID
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		token, err := s.tokens.ConsumeTx(ctx, tx, hashToken(req.Token), models.AccountTokenPasswordReset)
+		if err != nil {
+			if errors.Is(err, custom_err.ErrNotFound) {
+				return custom_err.ErrInvalidToken
+			}
+			return fmt.Errorf("failed to consume token: %w", err)
+		}
+		userID = token.UserID
+
+		if err := s.userRepo.UpdatePasswordTx(ctx, tx, token.UserID, string(hashedPassword)); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		// Сессии, открытые со старым паролем, закрываются
+		if err := s.refreshTokens.RevokeUserRefreshTokensTx(ctx, tx, token.UserID); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrInvalidToken) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("пароль сброшен", slog.String("op", op), slog.String("user_id", userID.String()))
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventPasswordReset, UserID: &userID})
+	return nil
+}
+
+// issueToken создает одноразовый токен и гасит прежние неиспользованные токены с тем же назначением
+func (s *AccountService) issueToken(
+	ctx context.Context,
+	userID uuid.UUID,
+	purpose models.AccountTokenPurpose,
+	ttl time.Duration,
+) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	record := &models.AccountToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.tokens.InvalidateTx(ctx, tx, userID, purpose); err != nil {
+			return fmt.Errorf("failed to invalidate tokens: %w", err)
+		}
+		if err := s.tokens.CreateTx(ctx, tx, record); err != nil {
+			return fmt.Errorf("failed to store token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AccountService) link(path, token string) string {
+	return s.linkBaseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (s *AccountService) expiry(ttl time.Duration) string {
+	return time.Now().Add(ttl).UTC().Format("02.01.2006 15:04 UTC")
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/mailer"
+	"gw-currency-wallet/internal/models"
+)
+
+type accountMocks struct {
+	userRepo      *MockUserRepository
+	tokens        *MockAccountTokenRepository
+	refreshTokens *MockTokenRepository
+	txManager     *MockTxManager
+	mailer        *MockMailer
+	audit         *MockAuditRecorder
+}
+
+func setupAccountService() (*AccountService, accountMocks) {
+	m := accountMocks{
+		userRepo:      new(MockUserRepository),
+		tokens:        new(MockAccountTokenRepository),
+		refreshTokens: new(MockTokenRepository),
+		txManager:     new(MockTxManager),
+		mailer:        new(MockMailer),
+		audit:         newMockAuditRecorder(),
+	}
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	service := NewAccountService(m.userRepo, m.tokens, m.refreshTokens, m.txManager, m.mailer, m.audit,
+		48*time.Hour, time.Hour, "https://wallet.example.com/", log)
+	return service, m
+}
+
+// tokenFromLink достает токен из ссылки в теле письма
+func tokenFromLink(t *testing.T, body, path string) string {
+	t.Helper()
+
+	prefix := "https://wallet.example.com" + path + "?token="
+	start := strings.Index(body, prefix)
+	require.GreaterOrEqual(t, start, 0, "link %s not found in body", path)
+	raw := body[start+len(prefix):]
+	if end := strings.IndexAny(raw, " \n"); end >= 0 {
+		raw = raw[:end]
+	}
+	token, err := url.QueryUnescape(raw)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAccountService_SendVerification(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com"}
+
+	var stored *models.AccountToken
+	var sent mailer.Message
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.tokens.On("InvalidateTx", ctx, mock.Anything, user.ID, models.AccountTokenEmailVerification).Return(nil)
+	m.tokens.On("CreateTx", ctx, mock.Anything, mock.AnythingOfType("*models.AccountToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(2).(*models.AccountToken) }).
+		Return(nil)
+	m.mailer.On("Send", ctx, mock.AnythingOfType("mailer.Message")).
+		Run(func(args mock.Arguments) { sent = args.Get(1).(mailer.Message) }).
+		Return(nil)
+
+	err := service.SendVerification(ctx, user)
+
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, user.ID, stored.UserID)
+	assert.Equal(t, models.AccountTokenEmailVerification, stored.Purpose)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), stored.ExpiresAt, time.Minute)
+
+	assert.Equal(t, user.Email, sent.To)
+	token := tokenFromLink(t, sent.Body, "/verify-email")
+	// В БД попадает только хэш токена из письма
+	assert.Equal(t, hashToken(token), stored.TokenHash)
+	assert.NotEqual(t, token, stored.TokenHash)
+	m.tokens.AssertExpectations(t)
+}
+
+func TestAccountService_SendVerification_AlreadyVerified(t *testing.T) {
+	service, m := setupAccountService()
+	verifiedAt := time.Now()
+	user := &models.User{ID: uuid.New(), Email: "alice@example.com", EmailVerifiedAt: &verifiedAt}
+
+	err := service.SendVerification(context.Background(), user)
+
+	assert.ErrorIs(t, err, custom_err.ErrEmailAlreadyVerified)
+	m.tokens.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+	m.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestAccountService_ResendVerification_UserNotFound(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	m.userRepo.On("GetByID", ctx, userID).Return(nil, custom_err.ErrNotFound)
+
+	err := service.ResendVerification(ctx, userID)
+
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+}
+
+func TestAccountService_VerifyEmail_Success(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.tokens.On("ConsumeTx", ctx, mock.Anything, hashToken("raw-token"), models.AccountTokenEmailVerification).
+		Return(&models.AccountToken{ID: uuid.New(), UserID: userID, Purpose: models.AccountTokenEmailVerification}, nil)
+	m.userRepo.On("MarkEmailVerifiedTx", ctx, mock.Anything, userID).Return(nil)
+
+	err := service.VerifyEmail(ctx, models.VerifyEmailRequest{Token: "raw-token"})
+
+	require.NoError(t, err)
+	m.tokens.AssertExpectations(t)
+	m.userRepo.AssertExpectations(t)
+}
+
+func TestAccountService_VerifyEmail_InvalidToken(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.tokens.On("ConsumeTx", ctx, mock.Anything, hashToken("stale"), models.AccountTokenEmailVerification).
+		Return(nil, custom_err.ErrNotFound)
+
+	err := service.VerifyEmail(ctx, models.VerifyEmailRequest{Token: "stale"})
+
+	assert.ErrorIs(t, err, custom_err.ErrInvalidToken)
+	m.userRepo.AssertNotCalled(t, "MarkEmailVerifiedTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAccountService_VerifyEmail_EmptyToken(t *testing.T) {
+	service, _ := setupAccountService()
+
+	err := service.VerifyEmail(context.Background(), models.VerifyEmailRequest{})
+
+	assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+}
+
+func TestAccountService_ForgotPassword_UnknownEmail(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+
+	m.userRepo.On("GetByEmail", ctx, "nobody@example.com").Return(nil, custom_err.ErrNotFound)
+
+	err := service.ForgotPassword(ctx, models.ForgotPasswordRequest{Email: "nobody@example.com"})
+
+	assert.NoError(t, err)
+	m.tokens.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+	m.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestAccountService_ForgotPassword_SendsResetLink(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com"}
+
+	var stored *models.AccountToken
+	var sent mailer.Message
+	m.userRepo.On("GetByEmail", ctx, user.Email).Return(user, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.tokens.On("InvalidateTx", ctx, mock.Anything, user.ID, models.AccountTokenPasswordReset).Return(nil)
+	m.tokens.On("CreateTx", ctx, mock.Anything, mock.AnythingOfType("*models.AccountToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(2).(*models.AccountToken) }).
+		Return(nil)
+	m.mailer.On("Send", ctx, mock.AnythingOfType("mailer.Message")).
+		Run(func(args mock.Arguments) { sent = args.Get(1).(mailer.Message) }).
+		Return(nil)
+
+	err := service.ForgotPassword(ctx, models.ForgotPasswordRequest{Email: user.Email})
+
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, models.AccountTokenPasswordReset, stored.Purpose)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+	assert.Equal(t, hashToken(tokenFromLink(t, sent.Body, "/reset-password")), stored.TokenHash)
+}
+
+func TestAccountService_ForgotPassword_MailFailureIsHidden(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "alice", Email: "alice@example.com"}
+
+	m.userRepo.On("GetByEmail", ctx, user.Email).Return(user, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.tokens.On("InvalidateTx", ctx, mock.Anything, user.ID, models.AccountTokenPasswordReset).Return(nil)
+	m.tokens.On("CreateTx", ctx, mock.Anything, mock.AnythingOfType("*models.AccountToken")).Return(nil)
+	m.mailer.On("Send", ctx, mock.AnythingOfType("mailer.Message")).Return(errors.New("smtp unavailable"))
+
+	err := service.ForgotPassword(ctx, models.ForgotPasswordRequest{Email: user.Email})
+
+	assert.NoError(t, err)
+	m.mailer.AssertExpectations(t)
+}
+
+func TestAccountService_ResetPassword_Success(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	var passwordHash string
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.tokens.On("ConsumeTx", ctx, mock.Anything, hashToken("reset-token"), models.AccountTokenPasswordReset).
+		Return(&models.AccountToken{ID: uuid.New(), UserID: userID, Purpose: models.AccountTokenPasswordReset}, nil)
+	m.userRepo.On("UpdatePasswordTx", ctx, mock.Anything, userID, mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { passwordHash = args.String(3) }).
+		Return(nil)
+	m.refreshTokens.On("RevokeUserRefreshTokensTx", ctx, mock.Anything, userID).Return(nil)
+
+	err := service.ResetPassword(ctx, models.ResetPasswordRequest{Token: "reset-token", NewPassword: "new-password-1"})
+
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte("new-password-1")))
+	m.refreshTokens.AssertExpectations(t)
+}
+
+func TestAccountService_ResetPassword_InvalidToken(t *testing.T) {
+	service, m := setupAccountService()
+	ctx := context.Background()
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.tokens.On("ConsumeTx", ctx, mock.Anything, hashToken("used"), models.AccountTokenPasswordReset).
+		Return(nil, custom_err.ErrNotFound)
+
+	err := service.ResetPassword(ctx, models.ResetPasswordRequest{Token: "used", NewPassword: "new-password-1"})
+
+	assert.ErrorIs(t, err, custom_err.ErrInvalidToken)
+	m.userRepo.AssertNotCalled(t, "UpdatePasswordTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.refreshTokens.AssertNotCalled(t, "RevokeUserRefreshTokensTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAccountService_ResetPassword_WeakPassword(t *testing.T) {
+	service, m := setupAccountService()
+
+	err := service.ResetPassword(context.Background(), models.ResetPasswordRequest{Token: "reset-token", NewPassword: "short"})
+
+	assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+	m.tokens.AssertNotCalled(t, "ConsumeTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,649 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Admin операции поддержки и администраторов. Каждое обращение записывается в журнал admin_audit_log.
+type Admin interface {
+	SearchUsers(ctx context.Context, actor *models.JWTClaims, req models.UserSearchRequest) (*models.UserSearchResponse, error)
+	GetUser(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID) (*models.AdminUserResponse, error)
+	GetUserTransactions(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID, req models.TransactionHistoryRequest) (*models.TransactionHistoryResponse, error)
+
+	FreezeWallet(ctx context.Context, actor *models.JWTClaims, walletID uuid.UUID, req models.WalletFreezeRequest) (*models.AdminWallet, error)
+	UnfreezeWallet(ctx context.Context, actor *models.JWTClaims, walletID uuid.UUID, req models.WalletFreezeRequest) (*models.AdminWallet, error)
+	AdjustBalance(ctx context.Context, actor *models.JWTClaims, walletID uuid.UUID, req models.BalanceAdjustmentRequest) (*models.BalanceAdjustmentResponse, error)
+	SetUserRole(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID, req models.SetRoleRequest) (*models.AdminUser, error)
+	// UnlockUser снимает блокировку входа после неверных паролей и сбрасывает счетчик попыток по имени
+	UnlockUser(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID, req models.UnlockUserRequest) (*models.AdminUser, error)
+
+	GetUserLimits(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID) (*models.UserLimitsResponse, error)
+	SetUserLimit(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID, req models.SetUserLimitRequest) (*models.UserLimitsResponse, error)
+}
+
+type AdminService struct {
+	userRepo   postgres.UserRepository
+	walletRepo postgres.WalletRepository
+	ledger     postgres.LedgerRepository
+	audit      postgres.AuditRepository
+	limits     postgres.LimitRepository
+	txManager  TxManager
+	wallets    Wallet
+	currencies CurrencyRegistry
+	// events журнал событий безопасности пользователей, в отличие от audit - журнала действий администраторов
+	events AuditRecorder
+	log    *slog.Logger
+}
+
+func NewAdminService(
+	userRepo postgres.UserRepository,
+	walletRepo postgres.WalletRepository,
+	ledger postgres.LedgerRepository,
+	audit postgres.AuditRepository,
+	limits postgres.LimitRepository,
+	txManager TxManager,
+	wallets Wallet,
+	currencies CurrencyRegistry,
+	events AuditRecorder,
+	log *slog.Logger,
+) Admin {
+	return &AdminService{
+		userRepo:   userRepo,
+		walletRepo: walletRepo,
+		ledger:     ledger,
+		audit:      audit,
+		limits:     limits,
+		txManager:  txManager,
+		wallets:    wallets,
+		currencies: currencies,
+		events:     events,
+		log:        log,
+	}
+}
+
+func (s *AdminService) SearchUsers(ctx context.Context, actor *models.JWTClaims, req models.UserSearchRequest) (*models.UserSearchResponse, error) {
+	const op = "service.Admin.SearchUsers"
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = models.DefaultUserSearchLimit
+	}
+	if limit < 0 || limit > models.MaxUserSearchLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", custom_err.ErrInvalidInput, models.MaxUserSearchLimit)
+	}
+	if req.Role != "" && !req.Role.IsValid() {
+		return nil, fmt.Errorf("%w: unknown role %q", custom_err.ErrInvalidInput, req.Role)
+	}
+
+	filter := models.UserSearchFilter{
+		Query: strings.TrimSpace(req.Query),
+		Role:  req.Role,
+		Limit: limit + 1,
+	}
+	if req.Cursor != "" {
+		after, err := models.DecodeUserCursor(req.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", custom_err.ErrInvalidCursor, err.Error())
+		}
+		filter.AfterUsername = after
+	}
+
+	err := s.audit.Create(ctx, auditEntry(actor, models.AuditActionUserSearch, nil, nil, "", map[string]any{
+		"query": filter.Query,
+		"role":  filter.Role,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	users, err := s.userRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &models.UserSearchResponse{Users: make([]models.AdminUser, 0, min(len(users), limit))}
+	for i, user := range users {
+		if i == limit {
+			resp.NextCursor = models.EncodeUserCursor(users[limit-1].Username)
+			break
+		}
+		resp.Users = append(resp.Users, toAdminUser(user))
+	}
+	return resp, nil
+}
+
+func (s *AdminService) GetUser(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID) (*models.AdminUserResponse, error) {
+	const op = "service.Admin.GetUser"
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.audit.Create(ctx, auditEntry(actor, models.AuditActionUserView, &userID, nil, "", nil)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	wallets, err := s.walletRepo.GetAllUserWallets(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &models.AdminUserResponse{
+		User:    toAdminUser(user),
+		Wallets: make([]models.AdminWallet, 0, len(wallets)),
+	}
+	for _, wallet := range wallets {
+		resp.Wallets = append(resp.Wallets, toAdminWallet(wallet, exps))
+	}
+	return resp, nil
+}
+
+func (s *AdminService) GetUserTransactions(
+	ctx context.Context,
+	actor *models.JWTClaims,
+	userID uuid.UUID,
+	req models.TransactionHistoryRequest,
+) (*models.TransactionHistoryResponse, error) {
+	const op = "service.Admin.GetUserTransactions"
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := s.audit.Create(ctx, auditEntry(actor, models.AuditActionUserTransactions, &userID, nil, "", map[string]any{
+		"currency": req.Currency,
+		"type":     req.Type,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.wallets.GetTransactions(ctx, userID, req)
+}
+
+func (s *AdminService) FreezeWallet(ctx context.Context, actor *models.JWTClaims, walletID uuid.UUID, req models.WalletFreezeRequest) (*models.AdminWallet, error) {
+	return s.setFrozen(ctx, actor, walletID, req.Reason, true)
+}
+
+func (s *AdminService) UnfreezeWallet(ctx context.Context, actor *models.JWTClaims, walletID uuid.UUID, req models.WalletFreezeRequest) (*models.AdminWallet, error) {
+	return s.setFrozen(ctx, actor, walletID, req.Reason, false)
+}
+
+func (s *AdminService) setFrozen(ctx context.Context, actor *models.JWTClaims, walletID uuid.UUID, reason string, frozen bool) (*models.AdminWallet, error) {
+	const op = "service.Admin.setFrozen"
+
+	reason, err := validateReason(reason)
+	if err != nil {
+		return nil, err
+	}
+
+	action := models.AuditActionWalletUnfreeze
+	if frozen {
+		action = models.AuditActionWalletFreeze
+	}
+
+	var updated *models.Wallet
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		wallet, err := s.walletRepo.GetWalletForUpdateTx(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+		if frozen && wallet.FrozenAt != nil {
+			return custom_err.ErrWalletFrozen
+		}
+		if !frozen && wallet.FrozenAt == nil {
+			return custom_err.ErrWalletNotFrozen
+		}
+
+		updated, err = s.walletRepo.SetFrozenTx(ctx, tx, walletID, frozen, reason)
+		if err != nil {
+			return err
+		}
+
+		return s.audit.CreateTx(ctx, tx, auditEntry(actor, action, &wallet.UserID, &walletID, reason, nil))
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) || errors.Is(err, custom_err.ErrWalletFrozen) || errors.Is(err, custom_err.ErrWalletNotFrozen) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("изменена заморозка кошелька",
+		slog.String("op", op),
+		slog.String("actor_id", actor.UserID.String()),
+		slog.String("wallet_id", walletID.String()),
+		slog.Bool("frozen", frozen))
+
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	result := toAdminWallet(updated, exps)
+	return &result, nil
+}
+
+// AdjustBalance проводит ручную корректировку против счета MANUAL_ADJUSTMENT.
+// Корректировка допускается и по замороженному кошельку, и по отключенной валюте.
+func (s *AdminService) AdjustBalance(
+	ctx context.Context,
+	actor *models.JWTClaims,
+	walletID uuid.UUID,
+	req models.BalanceAdjustmentRequest,
+) (*models.BalanceAdjustmentResponse, error) {
+	const op = "service.Admin.AdjustBalance"
+
+	reason, err := validateReason(req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if req.RequestID == "" {
+		return nil, fmt.Errorf("%w: request_id is required", custom_err.ErrInvalidInput)
+	}
+	if req.Amount.IsZero() {
+		return nil, custom_err.ErrInvalidAmount
+	}
+
+	var (
+		wallet       *models.Wallet
+		amount       int64
+		balanceAfter int64
+	)
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		wallet, err = s.walletRepo.GetWalletForUpdateTx(ctx, tx, walletID)
+		if err != nil {
+			return err
+		}
+
+		currency, err := s.currencies.Get(ctx, models.Currency(wallet.Currency))
+		if err != nil {
+			return err
+		}
+		units, err := toMinorUnits(req.Amount.Abs(), currency)
+		if err != nil {
+			return err
+		}
+		amount = units
+		if req.Amount.IsNegative() {
+			amount = -units
+		}
+
+		exists, err := s.walletRepo.OperationExistsTx(ctx, tx, req.RequestID)
+		if err != nil {
+			return fmt.Errorf("failed to check operation: %w", err)
+		}
+		if exists {
+			return custom_err.ErrDuplicateRequest
+		}
+
+		// Списание не может затронуть сумму, зарезервированную холдами
+		if wallet.Available()+amount < 0 {
+			return custom_err.ErrInsufficientFunds
+		}
+		balanceAfter = wallet.Balance + amount
+
+		adjustmentAccountID, err := s.ledger.GetSystemAccountIDTx(ctx, tx, models.SystemAccountManualAdjustment, wallet.Currency)
+		if err != nil {
+			return fmt.Errorf("failed to get adjustment account: %w", err)
+		}
+
+		err = s.ledger.PostEntryTx(ctx, tx, models.JournalEntry{
+			EntryType: models.OperationAdjustment,
+			RequestID: req.RequestID,
+			Postings: []models.Posting{
+				{AccountID: wallet.ID, Currency: wallet.Currency, Amount: amount},
+				{AccountID: adjustmentAccountID, Currency: wallet.Currency, Amount: -amount},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to post ledger entry: %w", err)
+		}
+
+		err = s.walletRepo.CreateOperationTx(ctx, tx, models.Operation{
+			WalletID:      wallet.ID,
+			OperationType: models.OperationAdjustment,
+			Amount:        amount,
+			BalanceAfter:  balanceAfter,
+			RequestID:     req.RequestID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create operation: %w", err)
+		}
+
+		return s.audit.CreateTx(ctx, tx, auditEntry(actor, models.AuditActionWalletAdjustment, &wallet.UserID, &walletID, reason, map[string]any{
+			"amount":        amount,
+			"currency":      wallet.Currency,
+			"balance_after": balanceAfter,
+			"request_id":    req.RequestID,
+		}))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("ручная корректировка баланса",
+		slog.String("op", op),
+		slog.String("actor_id", actor.UserID.String()),
+		slog.String("wallet_id", walletID.String()),
+		slog.Int64("amount", amount))
+
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	wallet.Balance = balanceAfter
+	exp := exps.of(wallet.Currency)
+
+	return &models.BalanceAdjustmentResponse{
+		Wallet:       toAdminWallet(wallet, exps),
+		Amount:       models.AmountFromMinorUnits(amount, exp),
+		BalanceAfter: models.AmountFromMinorUnits(balanceAfter, exp),
+	}, nil
+}
+
+func (s *AdminService) SetUserRole(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID, req models.SetRoleRequest) (*models.AdminUser, error) {
+	const op = "service.Admin.SetUserRole"
+
+	reason, err := validateReason(req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Role.IsValid() {
+		return nil, fmt.Errorf("%w: unknown role %q", custom_err.ErrInvalidInput, req.Role)
+	}
+	// Администратор не может понизить сам себя и оставить систему без администраторов
+	if userID == actor.UserID {
+		return nil, fmt.Errorf("%w: cannot change own role", custom_err.ErrForbidden)
+	}
+
+	var updated *models.User
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		updated, err = s.userRepo.SetRoleTx(ctx, tx, userID, req.Role)
+		if err != nil {
+			return err
+		}
+		return s.audit.CreateTx(ctx, tx, auditEntry(actor, models.AuditActionUserRoleChange, &userID, nil, reason, map[string]any{
+			"role": req.Role,
+		}))
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("изменена роль пользователя",
+		slog.String("op", op),
+		slog.String("actor_id", actor.UserID.String()),
+		slog.String("user_id", userID.String()),
+		slog.String("role", string(req.Role)))
+
+	result := toAdminUser(updated)
+	return &result, nil
+}
+
+func (s *AdminService) UnlockUser(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID, req models.UnlockUserRequest) (*models.AdminUser, error) {
+	const op = "service.Admin.UnlockUser"
+
+	reason, err := validateReason(req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *models.User
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		updated, err = s.userRepo.UnlockTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		return s.audit.CreateTx(ctx, tx, auditEntry(actor, models.AuditActionUserUnlock, &userID, nil, reason, nil))
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("снята блокировка входа пользователя",
+		slog.String("op", op),
+		slog.String("actor_id", actor.UserID.String()),
+		slog.String("user_id", userID.String()))
+	s.events.Record(ctx, models.AuditEvent{
+		Type:     models.AuditEventAccountUnlocked,
+		UserID:   &userID,
+		ActorID:  &actor.UserID,
+		Metadata: map[string]string{"reason": reason},
+	})
+
+	result := toAdminUser(updated)
+	return &result, nil
+}
+
+func (s *AdminService) GetUserLimits(ctx context.Context, actor *models.JWTClaims, userID uuid.UUID) (*models.UserLimitsResponse, error) {
+	const op = "service.Admin.GetUserLimits"
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.audit.Create(ctx, auditEntry(actor, models.AuditActionUserLimitsView, &userID, nil, "", nil)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp, err := s.userLimits(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return resp, nil
+}
+
+// SetUserLimit переопределяет лимит для пользователя или возвращает лимит по умолчанию
+func (s *AdminService) SetUserLimit(
+	ctx context.Context,
+	actor *models.JWTClaims,
+	userID uuid.UUID,
+	req models.SetUserLimitRequest,
+) (*models.UserLimitsResponse, error) {
+	const op = "service.Admin.SetUserLimit"
+
+	reason, err := validateReason(req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsLimitedOperation(req.OperationType) {
+		return nil, fmt.Errorf("%w: limits are not supported for operation type %q", custom_err.ErrInvalidInput, req.OperationType)
+	}
+	if !req.Period.IsValid() {
+		return nil, fmt.Errorf("%w: unknown period %q", custom_err.ErrInvalidInput, req.Period)
+	}
+	if req.Amount != nil && req.Unlimited {
+		return nil, fmt.Errorf("%w: amount and unlimited are mutually exclusive", custom_err.ErrInvalidInput)
+	}
+	// Лимит можно задать и по отключенной валюте
+	currency, err := s.currencies.Get(ctx, models.Currency(req.Currency))
+	if err != nil {
+		return nil, err
+	}
+
+	var maxAmount *int64
+	if req.Amount != nil {
+		var units int64
+		// Нулевой лимит запрещает операции этого типа
+		if !req.Amount.IsZero() {
+			units, err = toMinorUnits(*req.Amount, currency)
+			if err != nil {
+				return nil, err
+			}
+		}
+		maxAmount = &units
+	}
+
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]any{
+		"operation_type": req.OperationType,
+		"currency":       currency.Code,
+		"period":         req.Period,
+	}
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		switch {
+		case req.Amount == nil && !req.Unlimited:
+			details["limit"] = "default"
+			if err := s.limits.DeleteOverrideTx(ctx, tx, userID, req.OperationType, string(currency.Code), req.Period); err != nil {
+				return err
+			}
+		default:
+			details["limit"] = "unlimited"
+			if maxAmount != nil {
+				details["limit"] = *maxAmount
+			}
+			err := s.limits.UpsertOverrideTx(ctx, tx, models.TransactionLimit{
+				UserID:        &userID,
+				OperationType: req.OperationType,
+				Currency:      string(currency.Code),
+				Period:        req.Period,
+				MaxAmount:     maxAmount,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.audit.CreateTx(ctx, tx, auditEntry(actor, models.AuditActionUserLimitChange, &userID, nil, reason, details))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("изменен лимит пользователя",
+		slog.String("op", op),
+		slog.String("actor_id", actor.UserID.String()),
+		slog.String("user_id", userID.String()),
+		slog.String("operation_type", string(req.OperationType)),
+		slog.String("currency", string(currency.Code)),
+		slog.String("period", string(req.Period)),
+		slog.Any("limit", details["limit"]))
+
+	resp, err := s.userLimits(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return resp, nil
+}
+
+// userLimits действующие лимиты пользователя в порядке тип операции, валюта, период
+func (s *AdminService) userLimits(ctx context.Context, userID uuid.UUID) (*models.UserLimitsResponse, error) {
+	limits, err := s.limits.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, err
+	}
+
+	effective := effectiveLimits(limits)
+	resp := &models.UserLimitsResponse{Limits: make([]models.UserLimit, 0, len(effective))}
+	seen := make(map[limitKey]bool, len(effective))
+	for _, l := range limits {
+		key := limitKey{opType: l.OperationType, currency: l.Currency, period: l.Period}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		limit := effective[key]
+		item := models.UserLimit{
+			OperationType: limit.OperationType,
+			Currency:      limit.Currency,
+			Period:        limit.Period,
+			Overridden:    limit.UserID != nil,
+		}
+		if limit.MaxAmount != nil {
+			amount := models.AmountFromMinorUnits(*limit.MaxAmount, exps.of(limit.Currency))
+			item.Amount = &amount
+		}
+		resp.Limits = append(resp.Limits, item)
+	}
+	return resp, nil
+}
+
+// validateReason проверяет обязательную причину административного действия
+func validateReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", fmt.Errorf("%w: reason is required", custom_err.ErrInvalidInput)
+	}
+	if len([]rune(reason)) > models.MaxAdminReasonLength {
+		return "", fmt.Errorf("%w: reason must be at most %d characters", custom_err.ErrInvalidInput, models.MaxAdminReasonLength)
+	}
+	return reason, nil
+}
+
+func auditEntry(actor *models.JWTClaims, action string, userID, walletID *uuid.UUID, reason string, details map[string]any) models.AuditEntry {
+	return models.AuditEntry{
+		ActorID:        actor.UserID,
+		ActorRole:      actor.Role,
+		Action:         action,
+		TargetUserID:   userID,
+		TargetWalletID: walletID,
+		Reason:         reason,
+		Details:        details,
+	}
+}
+
+func toAdminUser(user *models.User) models.AdminUser {
+	return models.AdminUser{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        user.Role,
+		LockedUntil: user.LockedUntil,
+		CreatedAt:   user.CreatedAt,
+	}
+}
+
+func toAdminWallet(wallet *models.Wallet, exps exponents) models.AdminWallet {
+	return models.AdminWallet{
+		ID:           wallet.ID,
+		UserID:       wallet.UserID,
+		Currency:     wallet.Currency,
+		Balance:      models.AmountFromMinorUnits(wallet.Balance, exps.of(wallet.Currency)),
+		FrozenAt:     wallet.FrozenAt,
+		FrozenReason: wallet.FrozenReason,
+		CreatedAt:    wallet.CreatedAt,
+	}
+}
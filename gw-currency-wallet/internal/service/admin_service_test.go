@@ -0,0 +1,513 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+type adminMocks struct {
+	userRepo   *MockUserRepository
+	walletRepo *MockWalletRepo
+	ledger     *MockLedgerRepo
+	audit      *MockAuditRepository
+	limits     *MockLimitRepository
+	txManager  *MockTxManager
+	events     *MockAuditRecorder
+}
+
+func setupAdminService() (*AdminService, adminMocks) {
+	m := adminMocks{
+		userRepo:   new(MockUserRepository),
+		walletRepo: new(MockWalletRepo),
+		ledger:     new(MockLedgerRepo),
+		audit:      new(MockAuditRepository),
+		limits:     new(MockLimitRepository),
+		txManager:  new(MockTxManager),
+		events:     newMockAuditRecorder(),
+	}
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	currencies := newTestCurrencyRegistry()
+
+	service := &AdminService{
+		userRepo:   m.userRepo,
+		walletRepo: m.walletRepo,
+		ledger:     m.ledger,
+		audit:      m.audit,
+		limits:     m.limits,
+		txManager:  m.txManager,
+		wallets: &WalletService{
+			repo:       m.walletRepo,
+			ledger:     m.ledger,
+			limits:     stubLimits{},
+			txManager:  m.txManager,
+			currencies: currencies,
+			policy:     NewPolicy(),
+		},
+		currencies: currencies,
+		events:     m.events,
+		log:        log,
+	}
+
+	return service, m
+}
+
+func adminActor() *models.JWTClaims {
+	return &models.JWTClaims{UserID: uuid.New(), Username: "admin", Role: models.RoleAdmin}
+}
+
+func TestAdminService_FreezeWallet_Success(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	actor := adminActor()
+
+	wallet := &models.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: "USD", Balance: 1000}
+	now := time.Now()
+	frozen := *wallet
+	frozen.FrozenAt = &now
+	frozen.FrozenReason = "suspicious activity"
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletForUpdateTx", ctx, mock.Anything, wallet.ID).Return(wallet, nil)
+	m.walletRepo.On("SetFrozenTx", ctx, mock.Anything, wallet.ID, true, "suspicious activity").Return(&frozen, nil)
+	m.audit.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.ActorID == actor.UserID &&
+			e.ActorRole == models.RoleAdmin &&
+			e.Action == models.AuditActionWalletFreeze &&
+			*e.TargetWalletID == wallet.ID &&
+			*e.TargetUserID == wallet.UserID &&
+			e.Reason == "suspicious activity"
+	})).Return(nil)
+
+	result, err := service.FreezeWallet(ctx, actor, wallet.ID, models.WalletFreezeRequest{Reason: "  suspicious activity "})
+
+	require.NoError(t, err)
+	assert.NotNil(t, result.FrozenAt)
+	assert.Equal(t, "10", result.Balance.String())
+	m.walletRepo.AssertExpectations(t)
+	m.audit.AssertExpectations(t)
+}
+
+func TestAdminService_FreezeWallet_AlreadyFrozen(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+
+	now := time.Now()
+	wallet := &models.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: "USD", FrozenAt: &now}
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletForUpdateTx", ctx, mock.Anything, wallet.ID).Return(wallet, nil)
+
+	_, err := service.FreezeWallet(ctx, adminActor(), wallet.ID, models.WalletFreezeRequest{Reason: "again"})
+
+	assert.ErrorIs(t, err, custom_err.ErrWalletFrozen)
+	m.walletRepo.AssertNotCalled(t, "SetFrozenTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.audit.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdminService_UnfreezeWallet_NotFrozen(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+
+	wallet := &models.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: "USD"}
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletForUpdateTx", ctx, mock.Anything, wallet.ID).Return(wallet, nil)
+
+	_, err := service.UnfreezeWallet(ctx, adminActor(), wallet.ID, models.WalletFreezeRequest{Reason: "resolved"})
+
+	assert.ErrorIs(t, err, custom_err.ErrWalletNotFrozen)
+}
+
+func TestAdminService_ReasonRequired(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	actor := adminActor()
+	id := uuid.New()
+
+	tooLong := strings.Repeat("a", models.MaxAdminReasonLength+1)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"freeze", func() error {
+			_, err := service.FreezeWallet(ctx, actor, id, models.WalletFreezeRequest{Reason: "   "})
+			return err
+		}},
+		{"unfreeze", func() error {
+			_, err := service.UnfreezeWallet(ctx, actor, id, models.WalletFreezeRequest{})
+			return err
+		}},
+		{"adjustment", func() error {
+			_, err := service.AdjustBalance(ctx, actor, id, models.BalanceAdjustmentRequest{
+				Amount: decimal.RequireFromString("1"), RequestID: "adj-1",
+			})
+			return err
+		}},
+		{"role", func() error {
+			_, err := service.SetUserRole(ctx, actor, id, models.SetRoleRequest{Role: models.RoleSupport})
+			return err
+		}},
+		{"too long", func() error {
+			_, err := service.FreezeWallet(ctx, actor, id, models.WalletFreezeRequest{Reason: tooLong})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.call(), custom_err.ErrInvalidInput)
+		})
+	}
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestAdminService_AdjustBalance(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      string
+		balance     int64
+		wantAmount  int64
+		wantBalance int64
+		wantErr     error
+	}{
+		{name: "credit", amount: "12.50", balance: 1000, wantAmount: 1250, wantBalance: 2250},
+		{name: "debit", amount: "-4.00", balance: 1000, wantAmount: -400, wantBalance: 600},
+		{name: "debit below zero", amount: "-10.01", balance: 1000, wantErr: custom_err.ErrInsufficientFunds},
+		{name: "too precise", amount: "0.001", balance: 1000, wantErr: custom_err.ErrAmountPrecision},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, m := setupAdminService()
+			ctx := context.Background()
+			actor := adminActor()
+
+			// Корректировка допускается и по замороженному кошельку
+			now := time.Now()
+			wallet := &models.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: "USD", Balance: tt.balance, FrozenAt: &now}
+			adjustmentAccount := uuid.New()
+			req := models.BalanceAdjustmentRequest{
+				Amount:    decimal.RequireFromString(tt.amount),
+				Reason:    "chargeback",
+				RequestID: "adj-" + tt.name,
+			}
+
+			m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+			m.walletRepo.On("GetWalletForUpdateTx", ctx, mock.Anything, wallet.ID).Return(wallet, nil)
+			m.walletRepo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+			m.ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountManualAdjustment, "USD").
+				Return(adjustmentAccount, nil)
+			m.ledger.On("PostEntryTx", ctx, mock.Anything, mock.MatchedBy(func(e models.JournalEntry) bool {
+				return e.EntryType == models.OperationAdjustment &&
+					e.Validate() == nil &&
+					e.Postings[0].AccountID == wallet.ID &&
+					e.Postings[0].Amount == tt.wantAmount &&
+					e.Postings[1].AccountID == adjustmentAccount
+			})).Return(nil)
+			m.walletRepo.On("CreateOperationTx", ctx, mock.Anything, models.Operation{
+				WalletID:      wallet.ID,
+				OperationType: models.OperationAdjustment,
+				Amount:        tt.wantAmount,
+				BalanceAfter:  tt.wantBalance,
+				RequestID:     req.RequestID,
+			}).Return(nil)
+			m.audit.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(e models.AuditEntry) bool {
+				return e.Action == models.AuditActionWalletAdjustment &&
+					e.Reason == "chargeback" &&
+					e.Details["amount"] == tt.wantAmount
+			})).Return(nil)
+
+			result, err := service.AdjustBalance(ctx, actor, wallet.ID, req)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				m.ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+				m.audit.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, models.AmountFromMinorUnits(tt.wantAmount, 2).Equal(result.Amount))
+			assert.True(t, models.AmountFromMinorUnits(tt.wantBalance, 2).Equal(result.BalanceAfter))
+			m.ledger.AssertExpectations(t)
+			m.walletRepo.AssertExpectations(t)
+			m.audit.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAdminService_AdjustBalance_ZeroAmount(t *testing.T) {
+	service, m := setupAdminService()
+
+	_, err := service.AdjustBalance(context.Background(), adminActor(), uuid.New(), models.BalanceAdjustmentRequest{
+		Amount:    decimal.Zero,
+		Reason:    "noop",
+		RequestID: "adj-zero",
+	})
+
+	assert.ErrorIs(t, err, custom_err.ErrInvalidAmount)
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestAdminService_AdjustBalance_Duplicate(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+
+	wallet := &models.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: "USD", Balance: 1000}
+	req := models.BalanceAdjustmentRequest{Amount: decimal.RequireFromString("1"), Reason: "retry", RequestID: "adj-dup"}
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletForUpdateTx", ctx, mock.Anything, wallet.ID).Return(wallet, nil)
+	m.walletRepo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(true, nil)
+
+	_, err := service.AdjustBalance(ctx, adminActor(), wallet.ID, req)
+
+	assert.ErrorIs(t, err, custom_err.ErrDuplicateRequest)
+}
+
+func TestAdminService_SetUserRole(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	actor := adminActor()
+
+	user := &models.User{ID: uuid.New(), Username: "alice", Role: models.RoleSupport}
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.userRepo.On("SetRoleTx", ctx, mock.Anything, user.ID, models.RoleSupport).Return(user, nil)
+	m.audit.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.Action == models.AuditActionUserRoleChange && *e.TargetUserID == user.ID && e.Reason == "on-call rotation"
+	})).Return(nil)
+
+	result, err := service.SetUserRole(ctx, actor, user.ID, models.SetRoleRequest{Role: models.RoleSupport, Reason: "on-call rotation"})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleSupport, result.Role)
+	m.audit.AssertExpectations(t)
+}
+
+func TestAdminService_SetUserRole_Rejected(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	actor := adminActor()
+
+	_, err := service.SetUserRole(ctx, actor, actor.UserID, models.SetRoleRequest{Role: models.RoleUser, Reason: "step down"})
+	assert.ErrorIs(t, err, custom_err.ErrForbidden)
+
+	_, err = service.SetUserRole(ctx, actor, uuid.New(), models.SetRoleRequest{Role: "root", Reason: "typo"})
+	assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestAdminService_UnlockUser(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	actor := adminActor()
+
+	user := &models.User{ID: uuid.New(), Username: "alice", Role: models.RoleUser}
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.userRepo.On("UnlockTx", ctx, mock.Anything, user.ID).Return(user, nil).Once()
+	m.audit.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.Action == models.AuditActionUserUnlock && *e.TargetUserID == user.ID && e.Reason == "identity confirmed"
+	})).Return(nil)
+
+	result, err := service.UnlockUser(ctx, actor, user.ID, models.UnlockUserRequest{Reason: "identity confirmed"})
+
+	require.NoError(t, err)
+	assert.Nil(t, result.LockedUntil)
+	m.userRepo.AssertExpectations(t)
+	m.audit.AssertExpectations(t)
+
+	events := m.events.recordedAuditEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditEventAccountUnlocked, events[0].Type)
+	assert.Equal(t, &user.ID, events[0].UserID)
+	assert.Equal(t, &actor.UserID, events[0].ActorID)
+}
+
+func TestAdminService_UnlockUser_NotFound(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.userRepo.On("UnlockTx", ctx, mock.Anything, userID).Return(nil, custom_err.ErrNotFound)
+
+	_, err := service.UnlockUser(ctx, adminActor(), userID, models.UnlockUserRequest{Reason: "ticket 42"})
+
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+	m.audit.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAdminService_SearchUsers(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	actor := &models.JWTClaims{UserID: uuid.New(), Username: "helpdesk", Role: models.RoleSupport}
+
+	users := []*models.User{
+		{ID: uuid.New(), Username: "alice", Role: models.RoleUser},
+		{ID: uuid.New(), Username: "alina", Role: models.RoleUser},
+		{ID: uuid.New(), Username: "alister", Role: models.RoleUser},
+	}
+
+	m.audit.On("Create", ctx, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.Action == models.AuditActionUserSearch && e.ActorRole == models.RoleSupport && e.Details["query"] == "ali"
+	})).Return(nil)
+	m.userRepo.On("Search", ctx, models.UserSearchFilter{Query: "ali", Limit: 3}).Return(users, nil)
+
+	result, err := service.SearchUsers(ctx, actor, models.UserSearchRequest{Query: " ali ", Limit: 2})
+
+	require.NoError(t, err)
+	require.Len(t, result.Users, 2)
+	assert.Equal(t, "alina", result.Users[1].Username)
+
+	after, err := models.DecodeUserCursor(result.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, "alina", after)
+	m.audit.AssertExpectations(t)
+}
+
+func TestAdminService_GetUser_NotFound(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	m.userRepo.On("GetByID", ctx, userID).Return(nil, custom_err.ErrNotFound)
+
+	_, err := service.GetUser(ctx, adminActor(), userID)
+
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+	m.audit.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestAdminService_SetUserLimit_Override(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "alice", Role: models.RoleUser}
+	amount := decimal.RequireFromString("5000.00")
+	maxAmount := int64(500000)
+
+	m.userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.limits.On("UpsertOverrideTx", ctx, mock.Anything, models.TransactionLimit{
+		UserID:        &user.ID,
+		OperationType: models.OperationWithdraw,
+		Currency:      "USD",
+		Period:        models.LimitPeriodDaily,
+		MaxAmount:     &maxAmount,
+	}).Return(nil)
+	m.audit.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.Action == models.AuditActionUserLimitChange && *e.TargetUserID == user.ID && e.Details["limit"] == maxAmount
+	})).Return(nil)
+	defaultAmount := int64(100000)
+	m.limits.On("ListForUser", ctx, user.ID).Return([]models.TransactionLimit{
+		{OperationType: models.OperationWithdraw, Currency: "USD", Period: models.LimitPeriodDaily, MaxAmount: &defaultAmount},
+		{UserID: &user.ID, OperationType: models.OperationWithdraw, Currency: "USD", Period: models.LimitPeriodDaily, MaxAmount: &maxAmount},
+	}, nil)
+
+	result, err := service.SetUserLimit(ctx, adminActor(), user.ID, models.SetUserLimitRequest{
+		OperationType: models.OperationWithdraw,
+		Currency:      "USD",
+		Period:        models.LimitPeriodDaily,
+		Amount:        &amount,
+		Reason:        "verified business account",
+	})
+
+	require.NoError(t, err)
+	require.Len(t, result.Limits, 1)
+	assert.True(t, result.Limits[0].Overridden)
+	require.NotNil(t, result.Limits[0].Amount)
+	assert.Equal(t, "5000", result.Limits[0].Amount.String())
+	m.limits.AssertExpectations(t)
+	m.audit.AssertExpectations(t)
+}
+
+func TestAdminService_SetUserLimit_ResetToDefault(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "alice", Role: models.RoleUser}
+
+	m.userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.limits.On("DeleteOverrideTx", ctx, mock.Anything, user.ID, models.OperationExchange, "EUR", models.LimitPeriodMonthly).Return(nil)
+	m.audit.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.Action == models.AuditActionUserLimitChange && e.Details["limit"] == "default"
+	})).Return(nil)
+	m.limits.On("ListForUser", ctx, user.ID).Return([]models.TransactionLimit{}, nil)
+
+	result, err := service.SetUserLimit(ctx, adminActor(), user.ID, models.SetUserLimitRequest{
+		OperationType: models.OperationExchange,
+		Currency:      "EUR",
+		Period:        models.LimitPeriodMonthly,
+		Reason:        "review completed",
+	})
+
+	require.NoError(t, err)
+	assert.Empty(t, result.Limits)
+	m.limits.AssertNotCalled(t, "UpsertOverrideTx", mock.Anything, mock.Anything, mock.Anything)
+	m.limits.AssertExpectations(t)
+}
+
+func TestAdminService_SetUserLimit_InvalidInput(t *testing.T) {
+	service, m := setupAdminService()
+	ctx := context.Background()
+	amount := decimal.RequireFromString("10.00")
+	negative := decimal.RequireFromString("-1")
+
+	tests := []struct {
+		name    string
+		req     models.SetUserLimitRequest
+		wantErr error
+	}{
+		{
+			name:    "missing reason",
+			req:     models.SetUserLimitRequest{OperationType: models.OperationWithdraw, Currency: "USD", Period: models.LimitPeriodDaily, Amount: &amount},
+			wantErr: custom_err.ErrInvalidInput,
+		},
+		{
+			name:    "unsupported operation",
+			req:     models.SetUserLimitRequest{OperationType: models.OperationTransferIn, Currency: "USD", Period: models.LimitPeriodDaily, Amount: &amount, Reason: "r"},
+			wantErr: custom_err.ErrInvalidInput,
+		},
+		{
+			name:    "unknown period",
+			req:     models.SetUserLimitRequest{OperationType: models.OperationWithdraw, Currency: "USD", Period: "WEEKLY", Amount: &amount, Reason: "r"},
+			wantErr: custom_err.ErrInvalidInput,
+		},
+		{
+			name:    "amount with unlimited",
+			req:     models.SetUserLimitRequest{OperationType: models.OperationWithdraw, Currency: "USD", Period: models.LimitPeriodDaily, Amount: &amount, Unlimited: true, Reason: "r"},
+			wantErr: custom_err.ErrInvalidInput,
+		},
+		{
+			name:    "negative amount",
+			req:     models.SetUserLimitRequest{OperationType: models.OperationWithdraw, Currency: "USD", Period: models.LimitPeriodDaily, Amount: &negative, Reason: "r"},
+			wantErr: custom_err.ErrInvalidAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.SetUserLimit(ctx, adminActor(), uuid.New(), tt.req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxAPIKeysPerUser сколько действующих ключей может быть у пользователя одновременно
+const maxAPIKeysPerUser = 20
+
+// APIKeys управление персональными API ключами пользователя
+type APIKeys interface {
+	// Create выпускает ключ; сам ключ возвращается только в ответе на создание
+	Create(ctx context.Context, userID uuid.UUID, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error)
+	List(ctx context.Context, userID uuid.UUID) (*models.APIKeyListResponse, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) (*models.APIKeyResponse, error)
+}
+
+// APIKeyVerifier проверка API ключа при аутентификации запроса
+type APIKeyVerifier interface {
+	// Verify возвращает действующий ключ. Неизвестный ключ - custom_err.ErrInvalidToken,
+	// истекший - custom_err.ErrTokenExpired, отозванный - custom_err.ErrTokenRevoked,
+	// адрес вне списка разрешенных - custom_err.ErrAPIKeyIPNotAllowed.
+	Verify(ctx context.Context, key string, ip netip.Addr) (*models.APIKey, error)
+}
+
+type APIKeyService struct {
+	repo  postgres.APIKeyRepository
+	audit AuditRecorder
+	log   *slog.Logger
+}
+
+func NewAPIKeyService(repo postgres.APIKeyRepository, audit AuditRecorder, log *slog.Logger) *APIKeyService {
+	return &APIKeyService{
+		repo:  repo,
+		audit: audit,
+		log:   log,
+	}
+}
+
+func (s *APIKeyService) Create(ctx context.Context, userID uuid.UUID, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	const op = "service.CreateAPIKey"
+
+	now := time.Now()
+	allowedIPs, err := req.Validate(now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", custom_err.ErrInvalidInput, err.Error())
+	}
+
+	secret, err := generateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key := models.APIKey{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Name:       strings.TrimSpace(req.Name),
+		Prefix:     secret[:models.APIKeyDisplayLength],
+		KeyHash:    hashToken(secret),
+		Scopes:     uniqueScopes(req.Scopes),
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+		CreatedAt:  now,
+	}
+	if err := s.repo.Create(ctx, key, maxAPIKeysPerUser); err != nil {
+		if errors.Is(err, custom_err.ErrAPIKeyLimitReached) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("api key created",
+		slog.String("user_id", userID.String()),
+		slog.String("api_key_id", key.ID.String()),
+	)
+	s.audit.Record(ctx, models.AuditEvent{
+		Type:   models.AuditEventAPIKeyCreated,
+		UserID: &userID,
+		Metadata: map[string]string{
+			"api_key_id": key.ID.String(),
+			"name":       key.Name,
+			"scopes":     joinScopes(key.Scopes),
+		},
+	})
+
+	return &models.CreateAPIKeyResponse{
+		APIKeyResponse: toAPIKeyResponse(key),
+		Key:            secret,
+	}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID uuid.UUID) (*models.APIKeyListResponse, error) {
+	const op = "service.ListAPIKeys"
+
+	keys, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &models.APIKeyListResponse{APIKeys: make([]models.APIKeyResponse, 0, len(keys))}
+	for _, key := range keys {
+		resp.APIKeys = append(resp.APIKeys, toAPIKeyResponse(key))
+	}
+	return resp, nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userID, id uuid.UUID) (*models.APIKeyResponse, error) {
+	const op = "service.RevokeAPIKey"
+
+	key, err := s.repo.Revoke(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("api key revoked",
+		slog.String("user_id", userID.String()),
+		slog.String("api_key_id", id.String()),
+	)
+	s.audit.Record(ctx, models.AuditEvent{
+		Type:     models.AuditEventAPIKeyRevoked,
+		UserID:   &userID,
+		Metadata: map[string]string{"api_key_id": id.String()},
+	})
+
+	resp := toAPIKeyResponse(*key)
+	return &resp, nil
+}
+
+func (s *APIKeyService) Verify(ctx context.Context, secret string, ip netip.Addr) (*models.APIKey, error) {
+	const op = "service.VerifyAPIKey"
+
+	if !strings.HasPrefix(secret, models.APIKeyPrefix) {
+		return nil, custom_err.ErrInvalidToken
+	}
+
+	key, err := s.repo.GetByHash(ctx, hashToken(secret))
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if key.RevokedAt != nil {
+		return nil, custom_err.ErrTokenRevoked
+	}
+	if !key.ActiveAt(time.Now()) {
+		return nil, custom_err.ErrTokenExpired
+	}
+	if !key.AllowsIP(ip) {
+		s.log.Warn("api key used from disallowed address",
+			slog.String("api_key_id", key.ID.String()),
+			slog.String("ip", ip.String()),
+		)
+		return nil, custom_err.ErrAPIKeyIPNotAllowed
+	}
+
+	// Отметка об использовании не должна мешать запросу
+	if err := s.repo.Touch(ctx, key.ID, ip); err != nil {
+		s.log.Error("failed to record api key usage",
+			slog.String("op", op),
+			slog.String("api_key_id", key.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return key, nil
+}
+
+// generateAPIKey генерирует ключ вида gwk_<43 символа base64url>
+func generateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return models.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// uniqueScopes убирает повторы, сохраняя порядок
+func uniqueScopes(scopes []models.APIKeyScope) []models.APIKeyScope {
+	seen := make(map[models.APIKeyScope]bool, len(scopes))
+	result := make([]models.APIKeyScope, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+func toAPIKeyResponse(key models.APIKey) models.APIKeyResponse {
+	resp := models.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		AllowedIPs: make([]string, 0, len(key.AllowedIPs)),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+	for _, network := range key.AllowedIPs {
+		resp.AllowedIPs = append(resp.AllowedIPs, network.String())
+	}
+	if key.LastUsedIP != nil {
+		resp.LastUsedIP = key.LastUsedIP.String()
+	}
+	return resp
+}
+
+func joinScopes(scopes []models.APIKeyScope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ",")
+}
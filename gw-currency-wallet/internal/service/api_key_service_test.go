@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+func setupAPIKeyService() (*APIKeyService, *MockAPIKeyRepository) {
+	repo := new(MockAPIKeyRepository)
+	return NewAPIKeyService(repo, newMockAuditRecorder(), slog.New(slog.NewTextHandler(io.Discard, nil))), repo
+}
+
+func TestAPIKeyService_Create(t *testing.T) {
+	svc, repo := setupAPIKeyService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	var stored models.APIKey
+	repo.On("Create", ctx, mock.AnythingOfType("models.APIKey"), maxAPIKeysPerUser).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(models.APIKey) }).
+		Return(nil).Once()
+
+	resp, err := svc.Create(ctx, userID, models.CreateAPIKeyRequest{
+		Name:       "  reporting  ",
+		Scopes:     []models.APIKeyScope{models.ScopeBalanceRead, models.ScopeExchangeWrite, models.ScopeBalanceRead},
+		AllowedIPs: []string{"203.0.113.10", "10.1.2.3/8"},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(resp.Key, models.APIKeyPrefix))
+	assert.Equal(t, resp.Key[:models.APIKeyDisplayLength], resp.Prefix)
+	assert.Equal(t, "reporting", resp.Name)
+	assert.Equal(t, []models.APIKeyScope{models.ScopeBalanceRead, models.ScopeExchangeWrite}, resp.Scopes)
+	assert.Equal(t, []string{"203.0.113.10/32", "10.0.0.0/8"}, resp.AllowedIPs)
+
+	// Хранится только хэш ключа
+	assert.Equal(t, userID, stored.UserID)
+	assert.Equal(t, hashToken(resp.Key), stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, resp.Key)
+	assert.Equal(t, resp.ID, stored.ID)
+	repo.AssertExpectations(t)
+}
+
+func TestAPIKeyService_Create_InvalidInput(t *testing.T) {
+	svc, repo := setupAPIKeyService()
+
+	tests := []struct {
+		name string
+		req  models.CreateAPIKeyRequest
+	}{
+		{name: "no scopes", req: models.CreateAPIKeyRequest{Name: "script"}},
+		{name: "unknown scope", req: models.CreateAPIKeyRequest{Name: "script", Scopes: []models.APIKeyScope{"admin:write"}}},
+		{name: "invalid address", req: models.CreateAPIKeyRequest{Name: "script", Scopes: []models.APIKeyScope{models.ScopeBalanceRead},
+			AllowedIPs: []string{"example.com"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Create(context.Background(), uuid.New(), tt.req)
+			assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+		})
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAPIKeyService_Create_LimitReached(t *testing.T) {
+	svc, repo := setupAPIKeyService()
+	repo.On("Create", mock.Anything, mock.Anything, maxAPIKeysPerUser).Return(custom_err.ErrAPIKeyLimitReached)
+
+	_, err := svc.Create(context.Background(), uuid.New(), models.CreateAPIKeyRequest{
+		Name:   "script",
+		Scopes: []models.APIKeyScope{models.ScopeBalanceRead},
+	})
+	assert.ErrorIs(t, err, custom_err.ErrAPIKeyLimitReached)
+}
+
+func TestAPIKeyService_Revoke_NotFound(t *testing.T) {
+	svc, repo := setupAPIKeyService()
+	userID, id := uuid.New(), uuid.New()
+	repo.On("Revoke", mock.Anything, id, userID).Return(nil, custom_err.ErrNotFound)
+
+	_, err := svc.Revoke(context.Background(), userID, id)
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+}
+
+func TestAPIKeyService_Verify(t *testing.T) {
+	const secret = models.APIKeyPrefix + "secret"
+	past := time.Now().Add(-time.Hour)
+	office := netip.MustParseAddr("203.0.113.10")
+	home := netip.MustParseAddr("198.51.100.7")
+
+	active := &models.APIKey{ID: uuid.New(), UserID: uuid.New(), Scopes: []models.APIKeyScope{models.ScopeBalanceRead}}
+	restricted := &models.APIKey{ID: uuid.New(), UserID: uuid.New(),
+		AllowedIPs: []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")}}
+
+	tests := []struct {
+		name      string
+		key       string
+		stored    *models.APIKey
+		ip        netip.Addr
+		wantErr   error
+		wantTouch bool
+	}{
+		{name: "active key", key: secret, stored: active, ip: home, wantTouch: true},
+		{name: "allowed address", key: secret, stored: restricted, ip: office, wantTouch: true},
+		{name: "disallowed address", key: secret, stored: restricted, ip: home, wantErr: custom_err.ErrAPIKeyIPNotAllowed},
+		{name: "revoked key", key: secret, stored: &models.APIKey{ID: uuid.New(), RevokedAt: &past}, ip: home, wantErr: custom_err.ErrTokenRevoked},
+		{name: "expired key", key: secret, stored: &models.APIKey{ID: uuid.New(), ExpiresAt: &past}, ip: home, wantErr: custom_err.ErrTokenExpired},
+		{name: "unknown key", key: secret, ip: home, wantErr: custom_err.ErrInvalidToken},
+		{name: "not an api key", key: "eyJhbGciOi", ip: home, wantErr: custom_err.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := setupAPIKeyService()
+			if tt.stored != nil {
+				repo.On("GetByHash", mock.Anything, hashToken(tt.key)).Return(tt.stored, nil)
+			} else {
+				repo.On("GetByHash", mock.Anything, mock.Anything).Return(nil, custom_err.ErrNotFound)
+			}
+			repo.On("Touch", mock.Anything, mock.Anything, tt.ip).Return(nil).Maybe()
+
+			key, err := svc.Verify(context.Background(), tt.key, tt.ip)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, key)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.stored.ID, key.ID)
+			}
+			if tt.wantTouch {
+				repo.AssertCalled(t, "Touch", mock.Anything, tt.stored.ID, tt.ip)
+			} else {
+				repo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAPIKeyService_Verify_TouchFailureIgnored(t *testing.T) {
+	svc, repo := setupAPIKeyService()
+	stored := &models.APIKey{ID: uuid.New(), UserID: uuid.New()}
+	repo.On("GetByHash", mock.Anything, mock.Anything).Return(stored, nil)
+	repo.On("Touch", mock.Anything, stored.ID, mock.Anything).Return(errors.New("connection reset"))
+
+	key, err := svc.Verify(context.Background(), models.APIKeyPrefix+"secret", netip.MustParseAddr("192.0.2.1"))
+	require.NoError(t, err)
+	assert.Equal(t, stored.ID, key.ID)
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// auditChainBatchSize сколько записей журнала читается за раз при проверке цепочки
+	auditChainBatchSize = 1000
+
+	maxAuditMetadataKeyLength   = 64
+	maxAuditMetadataValueLength = 256
+)
+
+// AuditRecorder запись событий безопасности: входов, выпуска токенов, смены пароля и т.п.
+type AuditRecorder interface {
+	// Record дописывает событие в журнал. Адрес и User-Agent берутся из контекста запроса
+	// (WithClientInfo), ActorID по умолчанию - сам пользователь. Ошибка записи только логируется:
+	// сбой журнала не должен делать недоступными вход и смену пароля.
+	Record(ctx context.Context, event models.AuditEvent)
+}
+
+// AuditEvents просмотр журнала событий безопасности
+type AuditEvents interface {
+	// ListOwn события учетной записи пользователя, новые первыми
+	ListOwn(ctx context.Context, userID uuid.UUID, req models.AuditEventListRequest) (*models.AuditEventListResponse, error)
+	// Search поиск по всему журналу для admin API; запрос записывается в журнал администратора
+	Search(ctx context.Context, actor *models.JWTClaims, req models.AuditEventSearchRequest) (*models.AdminAuditEventListResponse, error)
+	// VerifyChain пересчитывает хэши всего журнала и проверяет, что записи не изменены и не удалены
+	VerifyChain(ctx context.Context, actor *models.JWTClaims) (*models.AuditChainReport, error)
+}
+
+type AuditEventService struct {
+	repo       postgres.AuditEventRepository
+	adminAudit postgres.AuditRepository
+	txManager  TxManager
+	log        *slog.Logger
+}
+
+func NewAuditEventService(
+	repo postgres.AuditEventRepository,
+	adminAudit postgres.AuditRepository,
+	txManager TxManager,
+	log *slog.Logger,
+) *AuditEventService {
+	return &AuditEventService{
+		repo:       repo,
+		adminAudit: adminAudit,
+		txManager:  txManager,
+		log:        log,
+	}
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo сохраняет в контексте адрес и User-Agent клиента для журнала событий безопасности
+func WithClientInfo(ctx context.Context, info models.ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+func clientInfoFrom(ctx context.Context) models.ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(models.ClientInfo)
+	return info
+}
+
+func (s *AuditEventService) Record(ctx context.Context, event models.AuditEvent) {
+	const op = "service.RecordAuditEvent"
+
+	// Событие записывается, даже если клиент уже закрыл соединение
+	if err := s.append(context.WithoutCancel(ctx), event); err != nil {
+		s.log.Error("не удалось записать событие безопасности",
+			slog.String("op", op),
+			slog.String("type", string(event.Type)),
+			slog.String("error", err.Error()))
+	}
+}
+
+func (s *AuditEventService) append(ctx context.Context, event models.AuditEvent) error {
+	client := clientInfoFrom(ctx)
+
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if event.ActorID == nil {
+		event.ActorID = event.UserID
+	}
+	// В БД адрес хранится без зоны и IPv4-mapped префикса; хэш считается по тому же значению
+	if ip := client.IP.Unmap().WithZone(""); ip.IsValid() {
+		event.IP = &ip
+	}
+	event.UserAgent = auditText(client.UserAgent, models.MaxUserAgentLength)
+	if len(event.Metadata) > 0 {
+		metadata := make(map[string]string, len(event.Metadata))
+		for k, v := range event.Metadata {
+			metadata[auditText(k, maxAuditMetadataKeyLength)] = auditText(v, maxAuditMetadataValueLength)
+		}
+		event.Metadata = metadata
+	}
+
+	return s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		head, err := s.repo.HeadTx(ctx, tx)
+		if err != nil {
+			return err
+		}
+		event.PrevHash = head
+		event.Hash = event.ComputeHash()
+		return s.repo.CreateTx(ctx, tx, &event)
+	})
+}
+
+func (s *AuditEventService) ListOwn(ctx context.Context, userID uuid.UUID, req models.AuditEventListRequest) (*models.AuditEventListResponse, error) {
+	const op = "service.ListOwnAuditEvents"
+
+	limit := normalizeAuditEventsLimit(req.Limit)
+	beforeSeq, err := decodeAuditEventCursor(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.repo.List(ctx, models.AuditEventFilter{UserID: &userID, BeforeSeq: beforeSeq, Limit: limit + 1})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &models.AuditEventListResponse{Events: make([]models.AuditEventResponse, 0, min(len(events), limit))}
+	for i, event := range events {
+		if i == limit {
+			resp.NextCursor = models.EncodeAuditEventCursor(events[i-1].Seq)
+			break
+		}
+		resp.Events = append(resp.Events, toAuditEventResponse(event))
+	}
+	return resp, nil
+}
+
+func (s *AuditEventService) Search(
+	ctx context.Context,
+	actor *models.JWTClaims,
+	req models.AuditEventSearchRequest,
+) (*models.AdminAuditEventListResponse, error) {
+	const op = "service.SearchAuditEvents"
+
+	limit := normalizeAuditEventsLimit(req.Limit)
+	beforeSeq, err := decodeAuditEventCursor(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, fmt.Errorf("%w: from must be before to", custom_err.ErrInvalidInput)
+	}
+
+	events, err := s.repo.List(ctx, models.AuditEventFilter{
+		UserID:    req.UserID,
+		Type:      req.Type,
+		IP:        req.IP,
+		From:      req.From,
+		To:        req.To,
+		BeforeSeq: beforeSeq,
+		Limit:     limit + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	details := map[string]any{"limit": limit}
+	if req.Type != "" {
+		details["type"] = req.Type
+	}
+	if req.IP != nil {
+		details["ip"] = req.IP.String()
+	}
+	if err := s.adminAudit.Create(ctx, auditEntry(actor, models.AuditActionSecurityEventsView, req.UserID, nil, "", details)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &models.AdminAuditEventListResponse{Events: make([]models.AdminAuditEvent, 0, min(len(events), limit))}
+	for i, event := range events {
+		if i == limit {
+			resp.NextCursor = models.EncodeAuditEventCursor(events[i-1].Seq)
+			break
+		}
+		resp.Events = append(resp.Events, toAdminAuditEvent(event))
+	}
+	return resp, nil
+}
+
+func (s *AuditEventService) VerifyChain(ctx context.Context, actor *models.JWTClaims) (*models.AuditChainReport, error) {
+	const op = "service.VerifyAuditChain"
+
+	report := &models.AuditChainReport{Valid: true}
+	var afterSeq int64
+	prevHash := ""
+
+scan:
+	for {
+		events, err := s.repo.Scan(ctx, afterSeq, auditChainBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, event := range events {
+			report.Checked++
+			switch {
+			case event.PrevHash != prevHash:
+				report.Problem = models.AuditChainBrokenLink
+			case event.ComputeHash() != event.Hash:
+				report.Problem = models.AuditChainHashMismatch
+			}
+			if report.Problem != "" {
+				report.Valid = false
+				report.BrokenSeq = event.Seq
+				break scan
+			}
+			prevHash = event.Hash
+			report.HeadSeq = event.Seq
+			report.HeadHash = event.Hash
+		}
+
+		if len(events) < auditChainBatchSize {
+			break
+		}
+		afterSeq = events[len(events)-1].Seq
+	}
+	report.VerifiedAt = time.Now()
+
+	if !report.Valid {
+		s.log.Error("цепочка журнала событий безопасности нарушена",
+			slog.String("op", op),
+			slog.Int64("seq", report.BrokenSeq),
+			slog.String("problem", report.Problem))
+	}
+
+	if err := s.adminAudit.Create(ctx, auditEntry(actor, models.AuditActionSecurityEventsVerify, nil, nil, "", map[string]any{
+		"valid":   report.Valid,
+		"checked": report.Checked,
+	})); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return report, nil
+}
+
+func normalizeAuditEventsLimit(limit int) int {
+	if limit <= 0 {
+		return models.DefaultAuditEventsLimit
+	}
+	return min(limit, models.MaxAuditEventsLimit)
+}
+
+func decodeAuditEventCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	seq, err := models.DecodeAuditEventCursor(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", custom_err.ErrInvalidCursor, err.Error())
+	}
+	return seq, nil
+}
+
+// auditText приводит строку к виду, который БД сохранит без изменений: валидный UTF-8 без NUL,
+// не длиннее maxLen символов. Иначе хэш, посчитанный при записи, не совпал бы при проверке.
+func auditText(s string, maxLen int) string {
+	s = strings.ReplaceAll(strings.ToValidUTF8(s, ""), "\x00", "")
+	if utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+	return string([]rune(s)[:maxLen])
+}
+
+func toAuditEventResponse(event *models.AuditEvent) models.AuditEventResponse {
+	resp := models.AuditEventResponse{
+		ID:        event.ID,
+		Type:      event.Type,
+		UserAgent: event.UserAgent,
+		Metadata:  event.Metadata,
+		CreatedAt: event.CreatedAt,
+	}
+	if event.IP != nil {
+		resp.IP = event.IP.String()
+	}
+	return resp
+}
+
+func toAdminAuditEvent(event *models.AuditEvent) models.AdminAuditEvent {
+	return models.AdminAuditEvent{
+		AuditEventResponse: toAuditEventResponse(event),
+		Seq:                event.Seq,
+		UserID:             event.UserID,
+		ActorID:            event.ActorID,
+		PrevHash:           event.PrevHash,
+		Hash:               event.Hash,
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+type auditEventMocks struct {
+	repo       *MockAuditEventRepository
+	adminAudit *MockAuditRepository
+	txManager  *MockTxManager
+}
+
+func setupAuditEventService() (*AuditEventService, auditEventMocks) {
+	m := auditEventMocks{
+		repo:       new(MockAuditEventRepository),
+		adminAudit: new(MockAuditRepository),
+		txManager:  new(MockTxManager),
+	}
+	m.txManager.On("WithTx", mock.Anything, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil).Maybe()
+
+	service := NewAuditEventService(m.repo, m.adminAudit, m.txManager, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	return service, m
+}
+
+// auditChain цепочка из n событий, связанных хэшами, в порядке записи
+func auditChain(n int) []*models.AuditEvent {
+	events := make([]*models.AuditEvent, n)
+	prev := ""
+	for i := range events {
+		userID := uuid.New()
+		event := &models.AuditEvent{
+			ID:        uuid.New(),
+			Seq:       int64(i + 1),
+			Type:      models.AuditEventLoginSucceeded,
+			UserID:    &userID,
+			ActorID:   &userID,
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+			PrevHash:  prev,
+		}
+		event.Hash = event.ComputeHash()
+		prev = event.Hash
+		events[i] = event
+	}
+	return events
+}
+
+func TestAuditEventService_Record(t *testing.T) {
+	service, m := setupAuditEventService()
+	userID := uuid.New()
+
+	ctx, cancel := context.WithCancel(WithClientInfo(context.Background(), models.ClientInfo{
+		IP:        netip.MustParseAddr("::ffff:203.0.113.10"),
+		UserAgent: "curl/8.0\x00" + strings.Repeat("x", 600) + "\xff",
+	}))
+	// Запрос уже завершен, но событие все равно записывается
+	cancel()
+
+	m.repo.On("HeadTx", mock.Anything, mock.Anything).Return("prevhash", nil)
+	m.repo.On("CreateTx", mock.Anything, mock.Anything, mock.AnythingOfType("*models.AuditEvent")).Return(nil)
+
+	service.Record(ctx, models.AuditEvent{
+		Type:     models.AuditEventLoginFailed,
+		UserID:   &userID,
+		Metadata: map[string]string{"reason": "invalid_password"},
+	})
+
+	m.repo.AssertExpectations(t)
+	event := m.repo.Calls[1].Arguments.Get(2).(*models.AuditEvent)
+	createCtx := m.repo.Calls[1].Arguments.Get(0).(context.Context)
+	assert.NoError(t, createCtx.Err())
+
+	assert.NotEqual(t, uuid.Nil, event.ID)
+	assert.Equal(t, &userID, event.ActorID)
+	require.NotNil(t, event.IP)
+	assert.Equal(t, "203.0.113.10", event.IP.String())
+	assert.True(t, strings.HasPrefix(event.UserAgent, "curl/8.0x"))
+	assert.Len(t, event.UserAgent, models.MaxUserAgentLength)
+	assert.Equal(t, "prevhash", event.PrevHash)
+	assert.Equal(t, event.ComputeHash(), event.Hash)
+	assert.Equal(t, "invalid_password", event.Metadata["reason"])
+}
+
+func TestAuditEventService_Record_KeepsActor(t *testing.T) {
+	service, m := setupAuditEventService()
+	userID, adminID := uuid.New(), uuid.New()
+
+	m.repo.On("HeadTx", mock.Anything, mock.Anything).Return("", nil)
+	m.repo.On("CreateTx", mock.Anything, mock.Anything, mock.MatchedBy(func(e *models.AuditEvent) bool {
+		return *e.UserID == userID && *e.ActorID == adminID && e.IP == nil && e.PrevHash == ""
+	})).Return(nil)
+
+	service.Record(context.Background(), models.AuditEvent{Type: models.AuditEventAccountUnlocked, UserID: &userID, ActorID: &adminID})
+	m.repo.AssertExpectations(t)
+}
+
+func TestAuditEventService_Record_StorageErrorIsNotReturned(t *testing.T) {
+	service, m := setupAuditEventService()
+	m.txManager.ExpectedCalls = nil
+	m.txManager.On("WithTx", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	assert.NotPanics(t, func() {
+		service.Record(context.Background(), models.AuditEvent{Type: models.AuditEventLogout})
+	})
+}
+
+func TestAuditEventService_ListOwn(t *testing.T) {
+	service, m := setupAuditEventService()
+	ctx := context.Background()
+	userID := uuid.New()
+	chain := auditChain(4)
+	// Новые первыми
+	page := []*models.AuditEvent{chain[3], chain[2], chain[1]}
+
+	m.repo.On("List", ctx, models.AuditEventFilter{UserID: &userID, BeforeSeq: 9, Limit: 3}).Return(page, nil)
+
+	resp, err := service.ListOwn(ctx, userID, models.AuditEventListRequest{Cursor: models.EncodeAuditEventCursor(9), Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 2)
+	assert.Equal(t, chain[3].ID, resp.Events[0].ID)
+	assert.Equal(t, chain[2].ID, resp.Events[1].ID)
+	assert.Equal(t, models.EncodeAuditEventCursor(chain[2].Seq), resp.NextCursor)
+}
+
+func TestAuditEventService_ListOwn_InvalidCursor(t *testing.T) {
+	service, m := setupAuditEventService()
+
+	_, err := service.ListOwn(context.Background(), uuid.New(), models.AuditEventListRequest{Cursor: "!!!"})
+	assert.ErrorIs(t, err, custom_err.ErrInvalidCursor)
+	m.repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestAuditEventService_Search(t *testing.T) {
+	service, m := setupAuditEventService()
+	ctx := context.Background()
+	actor := adminActor()
+	userID := uuid.New()
+	chain := auditChain(1)
+
+	m.repo.On("List", ctx, mock.MatchedBy(func(f models.AuditEventFilter) bool {
+		return *f.UserID == userID && f.Type == models.AuditEventLoginFailed && f.Limit == models.DefaultAuditEventsLimit+1
+	})).Return(chain, nil)
+	m.adminAudit.On("Create", ctx, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.Action == models.AuditActionSecurityEventsView && *e.TargetUserID == userID
+	})).Return(nil)
+
+	resp, err := service.Search(ctx, actor, models.AuditEventSearchRequest{UserID: &userID, Type: models.AuditEventLoginFailed})
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, chain[0].Hash, resp.Events[0].Hash)
+	assert.Empty(t, resp.NextCursor)
+	m.adminAudit.AssertExpectations(t)
+}
+
+func TestAuditEventService_Search_InvalidPeriod(t *testing.T) {
+	service, m := setupAuditEventService()
+	now := time.Now()
+
+	_, err := service.Search(context.Background(), adminActor(), models.AuditEventSearchRequest{From: &now, To: &now})
+	assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+	m.repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestAuditEventService_VerifyChain(t *testing.T) {
+	tests := []struct {
+		name        string
+		tamper      func(chain []*models.AuditEvent) []*models.AuditEvent
+		wantValid   bool
+		wantProblem string
+		wantBroken  int64
+	}{
+		{
+			name:      "intact",
+			tamper:    func(chain []*models.AuditEvent) []*models.AuditEvent { return chain },
+			wantValid: true,
+		},
+		{
+			name: "modified event",
+			tamper: func(chain []*models.AuditEvent) []*models.AuditEvent {
+				chain[1].Metadata = map[string]string{"amr": "pwd"}
+				return chain
+			},
+			wantProblem: models.AuditChainHashMismatch,
+			wantBroken:  2,
+		},
+		{
+			name: "deleted event",
+			tamper: func(chain []*models.AuditEvent) []*models.AuditEvent {
+				return append(chain[:1], chain[2:]...)
+			},
+			wantProblem: models.AuditChainBrokenLink,
+			wantBroken:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, m := setupAuditEventService()
+			ctx := context.Background()
+			chain := tt.tamper(auditChain(3))
+
+			m.repo.On("Scan", ctx, int64(0), auditChainBatchSize).Return(chain, nil)
+			m.adminAudit.On("Create", ctx, mock.MatchedBy(func(e models.AuditEntry) bool {
+				return e.Action == models.AuditActionSecurityEventsVerify && e.Details["valid"] == tt.wantValid
+			})).Return(nil)
+
+			report, err := service.VerifyChain(ctx, adminActor())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValid, report.Valid)
+			assert.Equal(t, tt.wantProblem, report.Problem)
+			assert.Equal(t, tt.wantBroken, report.BrokenSeq)
+			if tt.wantValid {
+				assert.Equal(t, int64(3), report.Checked)
+				assert.Equal(t, chain[2].Hash, report.HeadHash)
+			}
+			m.adminAudit.AssertExpectations(t)
+		})
+	}
+}
@@ -2,12 +2,18 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/models"
 	"gw-currency-wallet/internal/storage/postgres"
 	"log/slog"
+	"net/netip"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -18,33 +24,67 @@ import (
 
 type Auth interface {
 	Register(ctx context.Context, req models.RegisterRequest) (*models.RegisterResponse, error)
+	// Login проверяет пароль. Если у пользователя включена двухфакторная аутентификация,
+	// возвращает токен второго шага вместо пары токенов.
 	Login(ctx context.Context, req models.LoginRequest) (*models.LoginResponse, error)
-	ValidateToken(tokenString string) (*models.JWTClaims, error)
+	// LoginMFA завершает вход кодом второго фактора
+	LoginMFA(ctx context.Context, req models.LoginMFARequest) (*models.LoginResponse, error)
+	// StepUp выдает access токен, подтвержденный вторым фактором, для операций, требующих step-up
+	StepUp(ctx context.Context, claims *models.JWTClaims, req models.MFACodeRequest) (*models.LoginResponse, error)
+	Refresh(ctx context.Context, req models.RefreshRequest) (*models.LoginResponse, error)
+	Logout(ctx context.Context, claims *models.JWTClaims, req models.LogoutRequest) error
+	ValidateToken(ctx context.Context, tokenString string) (*models.JWTClaims, error)
+	// AuthenticateAPIKey проверяет API ключ и возвращает права владельца, ограниченные разрешениями ключа
+	AuthenticateAPIKey(ctx context.Context, key string, ip netip.Addr) (*models.JWTClaims, error)
 }
 type AuthService struct {
-	userRepo      postgres.UserRepository
-	walletRepo    postgres.WalletRepository
-	txManager     TxManager
-	jwtSecret     []byte
-	jwtExpiration time.Duration
-	log           *slog.Logger
+	userRepo          postgres.UserRepository
+	walletRepo        postgres.WalletRepository
+	tokenRepo         postgres.TokenRepository
+	txManager         TxManager
+	currencies        CurrencyRegistry
+	keys              SigningKeys
+	verification      VerificationSender
+	mfa               SecondFactor
+	apiKeys           APIKeyVerifier
+	loginGuard        LoginGuard
+	audit             AuditRecorder
+	jwtExpiration     time.Duration
+	refreshExpiration time.Duration
+	log               *slog.Logger
 }
 
 func NewAuthService(
 	userRepo postgres.UserRepository,
 	walletRepo postgres.WalletRepository,
+	tokenRepo postgres.TokenRepository,
 	txManager TxManager,
-	jwtSecret string,
+	currencies CurrencyRegistry,
+	keys SigningKeys,
+	verification VerificationSender,
+	mfa SecondFactor,
+	apiKeys APIKeyVerifier,
+	loginGuard LoginGuard,
+	audit AuditRecorder,
 	jwtExpiration time.Duration,
+	refreshExpiration time.Duration,
 	log *slog.Logger,
 ) Auth {
 	return &AuthService{
-		userRepo:      userRepo,
-		walletRepo:    walletRepo,
-		txManager:     txManager,
-		jwtSecret:     []byte(jwtSecret),
-		jwtExpiration: jwtExpiration,
-		log:           log,
+		userRepo:          userRepo,
+		walletRepo:        walletRepo,
+		tokenRepo:         tokenRepo,
+		txManager:         txManager,
+		currencies:        currencies,
+		keys:              keys,
+		verification:      verification,
+		mfa:               mfa,
+		apiKeys:           apiKeys,
+		loginGuard:        loginGuard,
+		audit:             audit,
+		jwtExpiration:     jwtExpiration,
+		refreshExpiration: refreshExpiration,
+		log:               log,
 	}
 }
 
@@ -68,22 +108,31 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 		PasswordHash: string(hashedPassword),
 	}
 
+	currencies, err := s.currencies.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get currencies: %w", op, err)
+	}
+
+	var createdUser *models.User
 	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
-		createdUser, err := s.userRepo.CreateTx(ctx, tx, user)
+		createdUser, err = s.userRepo.CreateTx(ctx, tx, user)
 		if err != nil {
 			return fmt.Errorf("failed to create user: %w", err)
 		}
 
-		currencies := models.SupportedCurrencies()
+		// Кошельки заводятся для включенных валют; остальные создаются при первой операции
 		for _, currency := range currencies {
+			if !currency.Enabled {
+				continue
+			}
 			wallet := &models.Wallet{
 				ID:       uuid.New(),
 				UserID:   createdUser.ID,
-				Currency: string(currency),
+				Currency: string(currency.Code),
 				Balance:  0,
 			}
 			if err := s.walletRepo.CreateWalletTx(ctx, tx, wallet); err != nil {
-				return fmt.Errorf("failed to create %s wallet: %w", currency, err)
+				return fmt.Errorf("failed to create %s wallet: %w", currency.Code, err)
 			}
 		}
 
@@ -100,6 +149,16 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventUserRegistered, UserID: &createdUser.ID})
+
+	// Регистрация не откатывается из-за почты: письмо можно запросить повторно
+	if err := s.verification.SendVerification(ctx, createdUser); err != nil {
+		s.log.Warn("не удалось отправить письмо подтверждения email",
+			slog.String("op", op),
+			slog.String("user_id", createdUser.ID.String()),
+			slog.String("error", err.Error()))
+	}
+
 	return &models.RegisterResponse{
 		Message: "User registered successfully",
 	}, nil
@@ -109,6 +168,22 @@ func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*mode
 	const op = "service.Login"
 	const dummyHash = "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"
 
+	if err := req.Validate(); err != nil {
+		return nil, custom_err.ErrInvalidInput
+	}
+
+	// Пароль не проверяется, пока действует ожидание после неверных попыток
+	if err := s.loginGuard.Check(ctx, req.Username, req.IP); err != nil {
+		if errors.Is(err, custom_err.ErrLoginThrottled) {
+			if recordErr := s.loginGuard.RecordFailure(ctx, req.Username, nil, req.IP, models.LoginFailureThrottled); recordErr != nil {
+				s.log.Error("failed to record login failure", slog.String("op", op), slog.String("error", recordErr.Error()))
+			}
+			s.recordLoginFailure(ctx, req.Username, nil, models.LoginFailureThrottled)
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	user, err := s.userRepo.GetByUsername(ctx, req.Username)
 
 	if err != nil && !errors.Is(err, custom_err.ErrNotFound) {
@@ -116,6 +191,15 @@ func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*mode
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	// Заблокированная учетная запись отвечает так же, как ожидание по имени, чтобы не раскрывать ее существование
+	if user != nil && user.LockedAt(time.Now()) {
+		if err := s.loginGuard.RecordFailure(ctx, req.Username, user, req.IP, models.LoginFailureLocked); err != nil {
+			s.log.Error("failed to record login failure", slog.String("op", op), slog.String("error", err.Error()))
+		}
+		s.recordLoginFailure(ctx, req.Username, &user.ID, models.LoginFailureLocked)
+		return nil, &models.LoginThrottledError{RetryAfter: time.Until(*user.LockedUntil)}
+	}
+
 	var hashToCompare string
 	if err != nil {
 		hashToCompare = dummyHash
@@ -126,34 +210,344 @@ func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*mode
 	err = bcrypt.CompareHashAndPassword([]byte(hashToCompare), []byte(req.Password))
 
 	if user == nil || err != nil {
+		reason := models.LoginFailureInvalidPassword
+		var userID *uuid.UUID
+		if user == nil {
+			reason = models.LoginFailureUnknownUser
+		} else {
+			userID = &user.ID
+		}
+		// Без учета попытки защита от перебора не работает, поэтому ошибка записи прерывает вход
+		if err := s.loginGuard.RecordFailure(ctx, req.Username, user, req.IP, reason); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		s.recordLoginFailure(ctx, req.Username, userID, reason)
 		return nil, custom_err.ErrInvalidCredentials
 	}
 
-	token, err := s.generateJWT(user)
+	if err := s.loginGuard.RecordSuccess(ctx, req.Username); err != nil {
+		s.log.Error("failed to reset login throttle", slog.String("op", op), slog.String("error", err.Error()))
+	}
+
+	mfaEnabled, err := s.mfa.Enabled(ctx, user.ID)
 	if err != nil {
-		s.log.Error("failed to generate JWT", slog.String("op", op), slog.String("error", err.Error()))
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	if mfaEnabled {
+		mfaToken, expiresAt, err := s.mfa.NewChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		s.log.Info("пароль принят, требуется второй фактор",
+			slog.String("op", op),
+			slog.String("user_id", user.ID.String()))
+		return &models.LoginResponse{
+			MFARequired:  true,
+			MFAToken:     mfaToken,
+			MFAExpiresIn: int64(time.Until(expiresAt).Seconds()),
+		}, nil
+	}
+
+	resp, err := s.startSession(ctx, user, []string{models.AMRPassword})
+	if err != nil {
+		s.log.Error("failed to start session", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	s.recordLogin(ctx, user.ID, []string{models.AMRPassword})
 
 	s.log.Info("user logged in successfully",
 		slog.String("op", op),
 		slog.String("user_id", user.ID.String()),
 		slog.String("username", user.Username))
 
+	return resp, nil
+}
+
+func (s *AuthService) LoginMFA(ctx context.Context, req models.LoginMFARequest) (*models.LoginResponse, error) {
+	const op = "service.LoginMFA"
+
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", custom_err.ErrInvalidInput, err.Error())
+	}
+
+	var (
+		userID    uuid.UUID
+		verifyErr error
+	)
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		id, err := s.mfa.CompleteChallengeTx(ctx, tx, req.MFAToken, req.Code)
+		if err != nil {
+			// Неверная попытка фиксируется коммитом, ошибка возвращается после него
+			if errors.Is(err, custom_err.ErrInvalidMFACode) {
+				userID = id
+				verifyErr = err
+				return nil
+			}
+			return err
+		}
+		userID = id
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if verifyErr != nil {
+		s.log.Warn("неверный код второго фактора при входе", slog.String("op", op))
+		event := models.AuditEvent{
+			Type:     models.AuditEventLoginFailed,
+			Metadata: map[string]string{"reason": models.AuditReasonInvalidMFACode},
+		}
+		if userID != uuid.Nil {
+			event.UserID = &userID
+		}
+		s.audit.Record(ctx, event)
+		return nil, verifyErr
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get user: %w", op, err)
+	}
+
+	resp, err := s.startSession(ctx, user, []string{models.AMRPassword, models.AMROTP})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	s.recordLogin(ctx, user.ID, []string{models.AMRPassword, models.AMROTP})
+
+	s.log.Info("user logged in with second factor",
+		slog.String("op", op),
+		slog.String("user_id", user.ID.String()),
+		slog.String("username", user.Username))
+
+	return resp, nil
+}
+
+// StepUp подтверждает текущую сессию вторым фактором. Новый access токен несет amr otp и свежий
+// auth_time; refresh токены сессии не меняются, поэтому после обновления подтверждение нужно повторить.
+func (s *AuthService) StepUp(ctx context.Context, claims *models.JWTClaims, req models.MFACodeRequest) (*models.LoginResponse, error) {
+	const op = "service.StepUp"
+
+	if req.Code == "" {
+		return nil, fmt.Errorf("%w: code is required", custom_err.ErrInvalidInput)
+	}
+
+	var verifyErr error
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.mfa.VerifyTx(ctx, tx, claims.UserID, req.Code); err != nil {
+			if errors.Is(err, custom_err.ErrInvalidMFACode) {
+				verifyErr = err
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if verifyErr != nil {
+		s.log.Warn("неверный код второго фактора при step-up",
+			slog.String("op", op),
+			slog.String("user_id", claims.UserID.String()))
+		s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventStepUpFailed, UserID: &claims.UserID})
+		return nil, verifyErr
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to get user: %w", op, err)
+	}
+
+	token, err := s.generateJWT(ctx, user, time.Now(), []string{models.AMRPassword, models.AMROTP})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("сессия подтверждена вторым фактором",
+		slog.String("op", op),
+		slog.String("user_id", user.ID.String()))
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventStepUp, UserID: &user.ID})
+
 	return &models.LoginResponse{
-		Token: token,
+		Token:     token,
+		ExpiresIn: int64(s.jwtExpiration.Seconds()),
 	}, nil
 }
 
-func (s *AuthService) ValidateToken(tokenString string) (*models.JWTClaims, error) {
-	//const op = "service.ValidateToken"
+// recordLogin записывает в журнал безопасности выдачу пары токенов при входе
+func (s *AuthService) recordLogin(ctx context.Context, userID uuid.UUID, amr []string) {
+	s.audit.Record(ctx, models.AuditEvent{
+		Type:     models.AuditEventLoginSucceeded,
+		UserID:   &userID,
+		Metadata: map[string]string{"amr": strings.Join(amr, ",")},
+	})
+}
+
+// recordLoginFailure записывает в журнал безопасности неудачный вход; userID пуст для неизвестного имени
+func (s *AuthService) recordLoginFailure(ctx context.Context, username string, userID *uuid.UUID, reason string) {
+	s.audit.Record(ctx, models.AuditEvent{
+		Type:     models.AuditEventLoginFailed,
+		UserID:   userID,
+		Metadata: map[string]string{"username": username, "reason": reason},
+	})
+}
+
+// startSession выдает пару токенов и открывает новое семейство refresh токенов
+func (s *AuthService) startSession(ctx context.Context, user *models.User, amr []string) (*models.LoginResponse, error) {
+	authTime := time.Now()
+
+	token, err := s.generateJWT(ctx, user, authTime, amr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+
+	refreshToken, record, err := s.newRefreshToken(user.ID, uuid.New(), authTime, amr)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokenRepo.CreateRefreshToken(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.jwtExpiration.Seconds()),
+	}, nil
+}
+
+// Refresh обменивает refresh токен на новую пару токенов. Предъявленный токен становится использованным;
+// его повторное предъявление означает утечку, и все семейство токенов отзывается.
+func (s *AuthService) Refresh(ctx context.Context, req models.RefreshRequest) (*models.LoginResponse, error) {
+	const op = "service.Refresh"
+
+	if req.RefreshToken == "" {
+		return nil, custom_err.ErrInvalidInput
+	}
+
+	var (
+		resp    *models.LoginResponse
+		reused  *models.RefreshToken
+		rotated *models.RefreshToken
+	)
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		current, err := s.tokenRepo.GetRefreshTokenForUpdateTx(ctx, tx, hashToken(req.RefreshToken))
+		if err != nil {
+			if errors.Is(err, custom_err.ErrNotFound) {
+				return custom_err.ErrInvalidToken
+			}
+			return fmt.Errorf("failed to get refresh token: %w", err)
+		}
+		if current.RevokedAt != nil {
+			return custom_err.ErrTokenRevoked
+		}
+		if current.UsedAt != nil {
+			// Отзыв фиксируется коммитом, ошибка возвращается после него
+			reused = current
+			return s.tokenRepo.RevokeRefreshTokenFamilyTx(ctx, tx, current.FamilyID)
+		}
+		if time.Now().After(current.ExpiresAt) {
+			return custom_err.ErrTokenExpired
+		}
+
+		user, err := s.userRepo.GetByID(ctx, current.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+
+		if err := s.tokenRepo.MarkRefreshTokenUsedTx(ctx, tx, current.ID); err != nil {
+			return fmt.Errorf("failed to rotate refresh token: %w", err)
+		}
+		rotated = current
+
+		// Момент и способ входа переходят к новому токену: обновление не равно повторному входу
+		refreshToken, record, err := s.newRefreshToken(user.ID, current.FamilyID, current.AuthTime, current.AMR)
+		if err != nil {
+			return err
+		}
+		if err := s.tokenRepo.CreateRefreshTokenTx(ctx, tx, record); err != nil {
+			return fmt.Errorf("failed to store refresh token: %w", err)
+		}
+
+		token, err := s.generateJWT(ctx, user, current.AuthTime, current.AMR)
+		if err != nil {
+			return fmt.Errorf("failed to generate JWT: %w", err)
+		}
+
+		resp = &models.LoginResponse{
+			Token:        token,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int64(s.jwtExpiration.Seconds()),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if reused != nil {
+		s.log.Warn("повторное использование refresh токена, семейство отозвано",
+			slog.String("op", op),
+			slog.String("user_id", reused.UserID.String()),
+			slog.String("family_id", reused.FamilyID.String()))
+		s.audit.Record(ctx, models.AuditEvent{
+			Type:     models.AuditEventTokenReused,
+			UserID:   &reused.UserID,
+			Metadata: map[string]string{"family_id": reused.FamilyID.String()},
+		})
+		return nil, custom_err.ErrRefreshTokenReused
+	}
+
+	s.audit.Record(ctx, models.AuditEvent{
+		Type:     models.AuditEventTokenRefreshed,
+		UserID:   &rotated.UserID,
+		Metadata: map[string]string{"family_id": rotated.FamilyID.String()},
+	})
+	return resp, nil
+}
+
+// Logout отзывает текущий access токен и, если передан, refresh токен вместе с его семейством
+func (s *AuthService) Logout(ctx context.Context, claims *models.JWTClaims, req models.LogoutRequest) error {
+	const op = "service.Logout"
+
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return custom_err.ErrInvalidToken
+	}
+	if err := s.tokenRepo.RevokeAccessToken(ctx, jti, claims.UserID, claims.ExpiresAt.Time); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if req.RefreshToken != "" {
+		if err := s.tokenRepo.RevokeRefreshTokenFamilyByToken(ctx, claims.UserID, hashToken(req.RefreshToken)); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	s.log.Info("user logged out",
+		slog.String("op", op),
+		slog.String("user_id", claims.UserID.String()))
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventLogout, UserID: &claims.UserID})
+
+	return nil
+}
+
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*models.JWTClaims, error) {
+	const op = "service.ValidateToken"
 
 	claims := &models.JWTClaims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, err := s.keys.VerificationKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		// Алгоритм задается ключом, а не заголовком токена
+		if token.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return s.jwtSecret, nil
+		return key.VerifyKey(), nil
 	})
 
 	if err != nil {
@@ -176,19 +570,121 @@ func (s *AuthService) ValidateToken(tokenString string) (*models.JWTClaims, erro
 		return nil, custom_err.ErrInvalidToken
 	}
 
+	// Токены, выданные до появления ролей, не содержат роли и дают права обычного пользователя
+	if claims.Role == "" {
+		claims.Role = models.RoleUser
+	}
+	if !claims.Role.IsValid() {
+		return nil, custom_err.ErrInvalidToken
+	}
+
+	// Токены без jti нельзя отозвать, поэтому они не принимаются
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, custom_err.ErrInvalidToken
+	}
+	revoked, err := s.tokenRepo.IsAccessTokenRevoked(ctx, jti)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if revoked {
+		return nil, custom_err.ErrTokenRevoked
+	}
+
 	return claims, nil
 }
 
-func (s *AuthService) generateJWT(user *models.User) (string, error) {
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, key string, ip netip.Addr) (*models.JWTClaims, error) {
+	const op = "service.AuthenticateAPIKey"
+
+	apiKey, err := s.apiKeys.Verify(ctx, key, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	// Роль и подтверждение email берутся из текущей записи пользователя, а не на момент выпуска ключа
+	user, err := s.userRepo.GetByID(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
+	return &models.JWTClaims{
+		UserID:        user.ID,
+		Username:      user.Username,
+		Role:          role,
+		EmailVerified: user.EmailVerified(),
+		APIKeyID:      &apiKey.ID,
+		Scopes:        apiKey.Scopes,
+	}, nil
+}
+
+// generateJWT подписывает access токен; authTime и amr - момент и способы входа пользователя
+func (s *AuthService) generateJWT(ctx context.Context, user *models.User, authTime time.Time, amr []string) (string, error) {
+	key, err := s.keys.SigningKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	method, err := key.SigningMethod()
+	if err != nil {
+		return "", err
+	}
+
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
 	claims := models.JWTClaims{
 		UserID:   user.ID,
 		Username: user.Username,
+		Role:     role,
+		// Подтверждение вступает в силу со следующего токена, как и смена роли
+		EmailVerified: user.EmailVerified(),
+		AMR:           amr,
+		AuthTime:      jwt.NewNumericDate(authTime),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(s.jwtExpiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	token := jwt.NewWithClaims(method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+	return token.SignedString(key.SignKey())
+}
+
+// newRefreshToken генерирует случайный refresh токен и запись о нем для хранения
+func (s *AuthService) newRefreshToken(userID, familyID uuid.UUID, authTime time.Time, amr []string) (string, *models.RefreshToken, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	return token, &models.RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.refreshExpiration),
+		AuthTime:  authTime,
+		AMR:       amr,
+	}, nil
+}
+
+// hashToken SHA-256 хэш случайного токена; в БД хранится только он
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -3,40 +3,58 @@ package service
 import (
 	"context"
 	"log/slog"
+	"net/netip"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 
 	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/models"
 )
 
-func setupAuthService() (*AuthService, *MockUserRepository, *MockWalletRepo, *MockTxManager) {
+func setupAuthService() (*AuthService, *MockUserRepository, *MockWalletRepo, *MockTokenRepository, *MockTxManager) {
 	userRepo := new(MockUserRepository)
 	walletRepo := new(MockWalletRepo)
+	tokenRepo := new(MockTokenRepository)
 	txManager := new(MockTxManager)
+	mfa := new(MockSecondFactor)
+	mfa.On("Enabled", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+	loginGuard := new(MockLoginGuard)
+	loginGuard.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	loginGuard.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	loginGuard.On("RecordSuccess", mock.Anything, mock.Anything).Return(nil).Maybe()
 
 	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
 	service := &AuthService{
-		userRepo:      userRepo,
-		walletRepo:    walletRepo,
-		txManager:     txManager,
-		jwtSecret:     []byte("test-secret"),
-		jwtExpiration: time.Hour,
-		log:           log,
+		userRepo:          userRepo,
+		walletRepo:        walletRepo,
+		tokenRepo:         tokenRepo,
+		txManager:         txManager,
+		currencies:        newTestCurrencyRegistry(),
+		keys:              NewHMACSigningKeys("test-secret"),
+		verification:      new(MockVerificationSender),
+		mfa:               mfa,
+		loginGuard:        loginGuard,
+		audit:             newMockAuditRecorder(),
+		jwtExpiration:     time.Hour,
+		refreshExpiration: 24 * time.Hour,
+		log:               log,
 	}
 
-	return service, userRepo, walletRepo, txManager
+	return service, userRepo, walletRepo, tokenRepo, txManager
 }
 
 func TestAuthService_Register_Success(t *testing.T) {
-	service, userRepo, walletRepo, txManager := setupAuthService()
+	service, userRepo, walletRepo, _, txManager := setupAuthService()
 	ctx := context.Background()
 
 	req := models.RegisterRequest{
@@ -45,9 +63,6 @@ func TestAuthService_Register_Success(t *testing.T) {
 		Password: "password123",
 	}
 
-	userRepo.On("ExistsByUsername", ctx, req.Username).Return(false, nil)
-	userRepo.On("ExistsByEmail", ctx, req.Email).Return(false, nil)
-
 	userRepo.On("CreateTx", ctx, mock.Anything, mock.AnythingOfType("*models.User")).
 		Return(&models.User{
 			ID:       uuid.New(),
@@ -55,23 +70,30 @@ func TestAuthService_Register_Success(t *testing.T) {
 			Email:    req.Email,
 		}, nil)
 
-	for _, currency := range models.SupportedCurrencies() {
-		walletRepo.On("CreateWalletTx", ctx, mock.Anything, mock.AnythingOfType("uuid.UUID"), currency).
-			Return(&models.Wallet{
-				ID:       uuid.New(),
-				UserID:   uuid.New(),
-				Currency: string(currency),
-				Balance:  0,
-			}, nil)
+	// Для отключенной валюты кошелек не создается
+	registry := newTestCurrencyRegistry()
+	service.currencies = append(registry, models.CurrencyInfo{Code: "GBP", Name: "Pound Sterling", Exponent: 2})
+
+	for _, currency := range registry {
+		walletRepo.On("CreateWalletTx", ctx, mock.Anything, mock.MatchedBy(func(w *models.Wallet) bool {
+			return w.Currency == string(currency.Code) && w.Balance == 0
+		})).Return(nil).Once()
 	}
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
 
+	verification := new(MockVerificationSender)
+	verification.On("SendVerification", ctx, mock.MatchedBy(func(u *models.User) bool {
+		return u.Email == req.Email
+	})).Return(nil)
+	service.verification = verification
+
 	resp, err := service.Register(ctx, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.Equal(t, "User registered successfully", resp.Message)
+	verification.AssertExpectations(t)
 
 	userRepo.AssertExpectations(t)
 	walletRepo.AssertExpectations(t)
@@ -79,7 +101,7 @@ func TestAuthService_Register_Success(t *testing.T) {
 }
 
 func TestAuthService_Register_UsernameExists(t *testing.T) {
-	service, userRepo, _, _ := setupAuthService()
+	service, userRepo, _, _, txManager := setupAuthService()
 	ctx := context.Background()
 
 	req := models.RegisterRequest{
@@ -88,19 +110,21 @@ func TestAuthService_Register_UsernameExists(t *testing.T) {
 		Password: "password123",
 	}
 
-	userRepo.On("ExistsByUsername", ctx, req.Username).Return(true, nil)
+	userRepo.On("CreateTx", ctx, mock.Anything, mock.AnythingOfType("*models.User")).
+		Return(nil, custom_err.ErrUsernameExists)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
 
 	resp, err := service.Register(ctx, req)
 
 	assert.Error(t, err)
 	assert.Nil(t, resp)
-	assert.Equal(t, custom_err.ErrUsernameExists, err)
+	assert.ErrorIs(t, err, custom_err.ErrUsernameExists)
 
 	userRepo.AssertExpectations(t)
 }
 
 func TestAuthService_Register_EmailExists(t *testing.T) {
-	service, userRepo, _, _ := setupAuthService()
+	service, userRepo, _, _, txManager := setupAuthService()
 	ctx := context.Background()
 
 	req := models.RegisterRequest{
@@ -109,20 +133,21 @@ func TestAuthService_Register_EmailExists(t *testing.T) {
 		Password: "password123",
 	}
 
-	userRepo.On("ExistsByUsername", ctx, req.Username).Return(false, nil)
-	userRepo.On("ExistsByEmail", ctx, req.Email).Return(true, nil)
+	userRepo.On("CreateTx", ctx, mock.Anything, mock.AnythingOfType("*models.User")).
+		Return(nil, custom_err.ErrEmailExists)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
 
 	resp, err := service.Register(ctx, req)
 
 	assert.Error(t, err)
 	assert.Nil(t, resp)
-	assert.Equal(t, custom_err.ErrEmailExists, err)
+	assert.ErrorIs(t, err, custom_err.ErrEmailExists)
 
 	userRepo.AssertExpectations(t)
 }
 
 func TestAuthService_Register_InvalidInput(t *testing.T) {
-	service, _, _, _ := setupAuthService()
+	service, _, _, _, _ := setupAuthService()
 	ctx := context.Background()
 
 	tests := []struct {
@@ -161,13 +186,13 @@ func TestAuthService_Register_InvalidInput(t *testing.T) {
 
 			assert.Error(t, err)
 			assert.Nil(t, resp)
-			assert.Equal(t, custom_err.ErrInvalidInput, err)
+			assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
 		})
 	}
 }
 
 func TestAuthService_Login_Success(t *testing.T) {
-	service, userRepo, _, _ := setupAuthService()
+	service, userRepo, _, tokenRepo, _ := setupAuthService()
 	ctx := context.Background()
 
 	password := "password123"
@@ -186,23 +211,63 @@ func TestAuthService_Login_Success(t *testing.T) {
 	}
 
 	userRepo.On("GetByUsername", ctx, req.Username).Return(user, nil)
+	tokenRepo.On("CreateRefreshToken", ctx, mock.MatchedBy(func(rt *models.RefreshToken) bool {
+		return rt.UserID == user.ID && rt.FamilyID != uuid.Nil && len(rt.TokenHash) == 64
+	})).Return(nil)
+	tokenRepo.On("IsAccessTokenRevoked", ctx, mock.Anything).Return(false, nil)
 
 	resp, err := service.Login(ctx, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.Equal(t, int64(time.Hour.Seconds()), resp.ExpiresIn)
 
-	claims, err := service.ValidateToken(resp.Token)
+	claims, err := service.ValidateToken(ctx, resp.Token)
 	assert.NoError(t, err)
 	assert.Equal(t, user.ID, claims.UserID)
 	assert.Equal(t, user.Username, claims.Username)
+	assert.Equal(t, []string{models.AMRPassword}, claims.AMR)
+	assert.NotNil(t, claims.AuthTime)
+	assert.False(t, resp.MFARequired)
+
+	events := service.audit.(*MockAuditRecorder).recordedAuditEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditEventLoginSucceeded, events[0].Type)
+	assert.Equal(t, &user.ID, events[0].UserID)
+	assert.Equal(t, models.AMRPassword, events[0].Metadata["amr"])
 
 	userRepo.AssertExpectations(t)
+	tokenRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Login_MFARequired(t *testing.T) {
+	service, userRepo, _, tokenRepo, _ := setupAuthService()
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{ID: uuid.New(), Username: "testuser", PasswordHash: string(hashedPassword)}
+	userRepo.On("GetByUsername", ctx, user.Username).Return(user, nil)
+
+	mfa := new(MockSecondFactor)
+	mfa.On("Enabled", ctx, user.ID).Return(true, nil)
+	mfa.On("NewChallenge", ctx, user.ID).Return("challenge", time.Now().Add(5*time.Minute), nil)
+	service.mfa = mfa
+
+	resp, err := service.Login(ctx, models.LoginRequest{Username: user.Username, Password: "password123"})
+
+	assert.NoError(t, err)
+	assert.True(t, resp.MFARequired)
+	assert.Equal(t, "challenge", resp.MFAToken)
+	assert.InDelta(t, (5 * time.Minute).Seconds(), float64(resp.MFAExpiresIn), 1)
+	assert.Empty(t, resp.Token)
+	assert.Empty(t, resp.RefreshToken)
+	tokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything, mock.Anything)
 }
 
 func TestAuthService_Login_UserNotFound(t *testing.T) {
-	service, userRepo, _, _ := setupAuthService()
+	service, userRepo, _, _, _ := setupAuthService()
 	ctx := context.Background()
 
 	req := models.LoginRequest{
@@ -222,7 +287,7 @@ func TestAuthService_Login_UserNotFound(t *testing.T) {
 }
 
 func TestAuthService_Login_WrongPassword(t *testing.T) {
-	service, userRepo, _, _ := setupAuthService()
+	service, userRepo, _, _, _ := setupAuthService()
 	ctx := context.Background()
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correctpassword"), bcrypt.DefaultCost)
@@ -249,8 +314,119 @@ func TestAuthService_Login_WrongPassword(t *testing.T) {
 	userRepo.AssertExpectations(t)
 }
 
+func TestAuthService_Login_Throttled(t *testing.T) {
+	service, userRepo, _, _, _ := setupAuthService()
+	ctx := context.Background()
+	ip := netip.MustParseAddr("203.0.113.10")
+
+	loginGuard := new(MockLoginGuard)
+	loginGuard.On("Check", ctx, "testuser", ip).Return(&models.LoginThrottledError{RetryAfter: 8 * time.Second})
+	loginGuard.On("RecordFailure", ctx, "testuser", (*models.User)(nil), ip, models.LoginFailureThrottled).Return(nil).Once()
+	service.loginGuard = loginGuard
+
+	resp, err := service.Login(ctx, models.LoginRequest{Username: "testuser", Password: "password123", IP: ip})
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrLoginThrottled)
+	// Пароль во время ожидания не проверяется
+	userRepo.AssertNotCalled(t, "GetByUsername", mock.Anything, mock.Anything)
+	loginGuard.AssertExpectations(t)
+}
+
+func TestAuthService_Login_Locked(t *testing.T) {
+	service, userRepo, _, tokenRepo, _ := setupAuthService()
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	lockedUntil := time.Now().Add(20 * time.Minute)
+	user := &models.User{ID: uuid.New(), Username: "testuser", PasswordHash: string(hashedPassword), LockedUntil: &lockedUntil}
+	userRepo.On("GetByUsername", ctx, user.Username).Return(user, nil)
+
+	loginGuard := new(MockLoginGuard)
+	loginGuard.On("Check", ctx, user.Username, mock.Anything).Return(nil)
+	loginGuard.On("RecordFailure", ctx, user.Username, user, mock.Anything, models.LoginFailureLocked).Return(nil).Once()
+	service.loginGuard = loginGuard
+
+	// Даже верный пароль не пускает в заблокированную учетную запись
+	resp, err := service.Login(ctx, models.LoginRequest{Username: user.Username, Password: "password123"})
+
+	assert.Nil(t, resp)
+	var throttled *models.LoginThrottledError
+	if assert.ErrorAs(t, err, &throttled) {
+		assert.InDelta(t, (20 * time.Minute).Seconds(), throttled.RetryAfter.Seconds(), 1)
+	}
+	loginGuard.AssertNotCalled(t, "RecordSuccess", mock.Anything, mock.Anything)
+	loginGuard.AssertExpectations(t)
+	tokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Login_RecordsFailure(t *testing.T) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correctpassword"), bcrypt.MinCost)
+	user := &models.User{ID: uuid.New(), Username: "testuser", PasswordHash: string(hashedPassword)}
+	ip := netip.MustParseAddr("203.0.113.10")
+
+	tests := []struct {
+		name       string
+		user       *models.User
+		wantReason string
+	}{
+		{name: "wrong password", user: user, wantReason: models.LoginFailureInvalidPassword},
+		{name: "unknown user", user: nil, wantReason: models.LoginFailureUnknownUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, userRepo, _, _, _ := setupAuthService()
+			ctx := context.Background()
+			if tt.user != nil {
+				userRepo.On("GetByUsername", ctx, "testuser").Return(tt.user, nil)
+			} else {
+				userRepo.On("GetByUsername", ctx, "testuser").Return(nil, custom_err.ErrNotFound)
+			}
+
+			loginGuard := new(MockLoginGuard)
+			loginGuard.On("Check", ctx, "testuser", ip).Return(nil)
+			loginGuard.On("RecordFailure", ctx, "testuser", tt.user, ip, tt.wantReason).Return(nil).Once()
+			service.loginGuard = loginGuard
+
+			_, err := service.Login(ctx, models.LoginRequest{Username: "testuser", Password: "wrongpassword", IP: ip})
+
+			assert.Equal(t, custom_err.ErrInvalidCredentials, err)
+			loginGuard.AssertExpectations(t)
+
+			events := service.audit.(*MockAuditRecorder).recordedAuditEvents()
+			require.Len(t, events, 1)
+			assert.Equal(t, models.AuditEventLoginFailed, events[0].Type)
+			assert.Equal(t, tt.wantReason, events[0].Metadata["reason"])
+			assert.Equal(t, "testuser", events[0].Metadata["username"])
+			if tt.user != nil {
+				assert.Equal(t, &tt.user.ID, events[0].UserID)
+			} else {
+				assert.Nil(t, events[0].UserID)
+			}
+		})
+	}
+}
+
+func TestAuthService_Login_RecordFailureError(t *testing.T) {
+	service, userRepo, _, _, _ := setupAuthService()
+	ctx := context.Background()
+	userRepo.On("GetByUsername", ctx, "testuser").Return(nil, custom_err.ErrNotFound)
+
+	loginGuard := new(MockLoginGuard)
+	loginGuard.On("Check", ctx, "testuser", mock.Anything).Return(nil)
+	loginGuard.On("RecordFailure", ctx, "testuser", mock.Anything, mock.Anything, mock.Anything).Return(assert.AnError)
+	service.loginGuard = loginGuard
+
+	_, err := service.Login(ctx, models.LoginRequest{Username: "testuser", Password: "wrongpassword"})
+
+	// Неучтенная попытка не должна выглядеть как обычный неверный пароль
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NotErrorIs(t, err, custom_err.ErrInvalidCredentials)
+}
+
 func TestAuthService_Login_InvalidInput(t *testing.T) {
-	service, _, _, _ := setupAuthService()
+	service, _, _, _, _ := setupAuthService()
 	ctx := context.Background()
 
 	tests := []struct {
@@ -285,7 +461,8 @@ func TestAuthService_Login_InvalidInput(t *testing.T) {
 }
 
 func TestAuthService_ValidateToken_Success(t *testing.T) {
-	service, _, _, _ := setupAuthService()
+	service, _, _, tokenRepo, _ := setupAuthService()
+	ctx := context.Background()
 
 	userID := uuid.New()
 	username := "testuser"
@@ -295,19 +472,23 @@ func TestAuthService_ValidateToken_Success(t *testing.T) {
 		Username: username,
 	}
 
-	token, err := service.generateJWT(user)
+	token, err := service.generateJWT(context.Background(), user, time.Now(), []string{models.AMRPassword})
 	assert.NoError(t, err)
 
-	claims, err := service.ValidateToken(token)
+	tokenRepo.On("IsAccessTokenRevoked", ctx, mock.Anything).Return(false, nil)
+
+	claims, err := service.ValidateToken(ctx, token)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, claims)
 	assert.Equal(t, userID, claims.UserID)
 	assert.Equal(t, username, claims.Username)
+	_, err = uuid.Parse(claims.ID)
+	assert.NoError(t, err)
 }
 
 func TestAuthService_ValidateToken_InvalidToken(t *testing.T) {
-	service, _, _, _ := setupAuthService()
+	service, _, _, _, _ := setupAuthService()
 
 	tests := []struct {
 		name  string
@@ -329,7 +510,7 @@ func TestAuthService_ValidateToken_InvalidToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			claims, err := service.ValidateToken(tt.token)
+			claims, err := service.ValidateToken(context.Background(), tt.token)
 
 			assert.Error(t, err)
 			assert.Nil(t, claims)
@@ -348,7 +529,8 @@ func TestAuthService_ValidateToken_ExpiredToken(t *testing.T) {
 		userRepo:      userRepo,
 		walletRepo:    walletRepo,
 		txManager:     txManager,
-		jwtSecret:     []byte("test-secret"),
+		currencies:    newTestCurrencyRegistry(),
+		keys:          NewHMACSigningKeys("test-secret"),
 		jwtExpiration: -time.Hour,
 		log:           log,
 	}
@@ -358,12 +540,341 @@ func TestAuthService_ValidateToken_ExpiredToken(t *testing.T) {
 		Username: "testuser",
 	}
 
-	token, err := service.generateJWT(user)
+	token, err := service.generateJWT(context.Background(), user, time.Now(), []string{models.AMRPassword})
 	assert.NoError(t, err)
 
-	claims, err := service.ValidateToken(token)
+	claims, err := service.ValidateToken(context.Background(), token)
 
 	assert.Error(t, err)
 	assert.Nil(t, claims)
+	assert.Equal(t, custom_err.ErrTokenExpired, err)
+}
+
+func TestAuthService_ValidateToken_Revoked(t *testing.T) {
+	service, _, _, tokenRepo, _ := setupAuthService()
+	ctx := context.Background()
+
+	token, err := service.generateJWT(ctx, &models.User{ID: uuid.New(), Username: "testuser"}, time.Now(), []string{models.AMRPassword})
+	assert.NoError(t, err)
+
+	tokenRepo.On("IsAccessTokenRevoked", ctx, mock.Anything).Return(true, nil)
+
+	claims, err := service.ValidateToken(ctx, token)
+
+	assert.Nil(t, claims)
+	assert.ErrorIs(t, err, custom_err.ErrTokenRevoked)
+}
+
+func TestAuthService_ValidateToken_WithoutJTI(t *testing.T) {
+	service, _, _, _, _ := setupAuthService()
+
+	claims := &models.JWTClaims{
+		UserID:   uuid.New(),
+		Username: "testuser",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	assert.NoError(t, err)
+
+	result, err := service.ValidateToken(context.Background(), token)
+
+	assert.Nil(t, result)
 	assert.Equal(t, custom_err.ErrInvalidToken, err)
 }
+
+func TestAuthService_Refresh_RotatesToken(t *testing.T) {
+	service, userRepo, _, tokenRepo, txManager := setupAuthService()
+	ctx := context.Background()
+
+	user := &models.User{ID: uuid.New(), Username: "testuser"}
+	current := &models.RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken("old-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	tokenRepo.On("GetRefreshTokenForUpdateTx", ctx, mock.Anything, current.TokenHash).Return(current, nil)
+	userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	tokenRepo.On("MarkRefreshTokenUsedTx", ctx, mock.Anything, current.ID).Return(nil)
+	tokenRepo.On("CreateRefreshTokenTx", ctx, mock.Anything, mock.MatchedBy(func(rt *models.RefreshToken) bool {
+		return rt.FamilyID == current.FamilyID && rt.UserID == user.ID && rt.TokenHash != current.TokenHash
+	})).Return(nil)
+
+	resp, err := service.Refresh(ctx, models.RefreshRequest{RefreshToken: "old-token"})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+	assert.NotEqual(t, "old-token", resp.RefreshToken)
+
+	tokenRepo.AssertExpectations(t)
+	userRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Refresh_ReuseRevokesFamily(t *testing.T) {
+	service, userRepo, _, tokenRepo, txManager := setupAuthService()
+	ctx := context.Background()
+
+	usedAt := time.Now().Add(-time.Minute)
+	current := &models.RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: hashToken("stolen-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	tokenRepo.On("GetRefreshTokenForUpdateTx", ctx, mock.Anything, current.TokenHash).Return(current, nil)
+	tokenRepo.On("RevokeRefreshTokenFamilyTx", ctx, mock.Anything, current.FamilyID).Return(nil)
+
+	resp, err := service.Refresh(ctx, models.RefreshRequest{RefreshToken: "stolen-token"})
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrRefreshTokenReused)
+
+	tokenRepo.AssertExpectations(t)
+	tokenRepo.AssertNotCalled(t, "CreateRefreshTokenTx", mock.Anything, mock.Anything, mock.Anything)
+	userRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+
+	events := service.audit.(*MockAuditRecorder).recordedAuditEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditEventTokenReused, events[0].Type)
+	assert.Equal(t, &current.UserID, events[0].UserID)
+	assert.Equal(t, current.FamilyID.String(), events[0].Metadata["family_id"])
+}
+
+func TestAuthService_Refresh_Rejected(t *testing.T) {
+	revokedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		token   *models.RefreshToken
+		repoErr error
+		wantErr error
+	}{
+		{
+			name:    "unknown token",
+			repoErr: custom_err.ErrNotFound,
+			wantErr: custom_err.ErrInvalidToken,
+		},
+		{
+			name:    "expired token",
+			token:   &models.RefreshToken{ID: uuid.New(), ExpiresAt: time.Now().Add(-time.Minute)},
+			wantErr: custom_err.ErrTokenExpired,
+		},
+		{
+			name:    "revoked token",
+			token:   &models.RefreshToken{ID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt},
+			wantErr: custom_err.ErrTokenRevoked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, _, tokenRepo, txManager := setupAuthService()
+			ctx := context.Background()
+
+			txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+			if tt.token != nil {
+				tokenRepo.On("GetRefreshTokenForUpdateTx", ctx, mock.Anything, mock.Anything).Return(tt.token, nil)
+			} else {
+				tokenRepo.On("GetRefreshTokenForUpdateTx", ctx, mock.Anything, mock.Anything).Return(nil, tt.repoErr)
+			}
+
+			resp, err := service.Refresh(ctx, models.RefreshRequest{RefreshToken: "token"})
+
+			assert.Nil(t, resp)
+			assert.ErrorIs(t, err, tt.wantErr)
+			tokenRepo.AssertNotCalled(t, "MarkRefreshTokenUsedTx", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	service, _, _, tokenRepo, _ := setupAuthService()
+	ctx := context.Background()
+
+	jti := uuid.New()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	claims := &models.JWTClaims{
+		UserID:   uuid.New(),
+		Username: "testuser",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	tokenRepo.On("RevokeAccessToken", ctx, jti, claims.UserID, expiresAt).Return(nil)
+	tokenRepo.On("RevokeRefreshTokenFamilyByToken", ctx, claims.UserID, hashToken("refresh")).Return(nil)
+
+	err := service.Logout(ctx, claims, models.LogoutRequest{RefreshToken: "refresh"})
+
+	assert.NoError(t, err)
+	tokenRepo.AssertExpectations(t)
+}
+
+func TestAuthService_LoginMFA_Success(t *testing.T) {
+	service, userRepo, _, tokenRepo, txManager := setupAuthService()
+	ctx := context.Background()
+
+	user := &models.User{ID: uuid.New(), Username: "testuser"}
+	mfa := new(MockSecondFactor)
+	mfa.On("CompleteChallengeTx", ctx, mock.Anything, "challenge", "123456").Return(user.ID, nil)
+	service.mfa = mfa
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	tokenRepo.On("CreateRefreshToken", ctx, mock.MatchedBy(func(rt *models.RefreshToken) bool {
+		return rt.UserID == user.ID && slices.Equal(rt.AMR, []string{models.AMRPassword, models.AMROTP}) && !rt.AuthTime.IsZero()
+	})).Return(nil)
+	tokenRepo.On("IsAccessTokenRevoked", ctx, mock.Anything).Return(false, nil)
+
+	resp, err := service.LoginMFA(ctx, models.LoginMFARequest{MFAToken: "challenge", Code: "123456"})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, resp.RefreshToken)
+	claims, err := service.ValidateToken(ctx, resp.Token)
+	assert.NoError(t, err)
+	assert.True(t, claims.SteppedUp(time.Minute, time.Now()))
+	tokenRepo.AssertExpectations(t)
+}
+
+func TestAuthService_LoginMFA_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     models.LoginMFARequest
+		mfaErr  error
+		wantErr error
+	}{
+		{name: "missing code", req: models.LoginMFARequest{MFAToken: "challenge"}, wantErr: custom_err.ErrInvalidInput},
+		{name: "invalid code", req: models.LoginMFARequest{MFAToken: "challenge", Code: "000000"},
+			mfaErr: custom_err.ErrInvalidMFACode, wantErr: custom_err.ErrInvalidMFACode},
+		{name: "invalid challenge", req: models.LoginMFARequest{MFAToken: "challenge", Code: "000000"},
+			mfaErr: custom_err.ErrInvalidToken, wantErr: custom_err.ErrInvalidToken},
+		{name: "locked", req: models.LoginMFARequest{MFAToken: "challenge", Code: "000000"},
+			mfaErr: custom_err.ErrMFALocked, wantErr: custom_err.ErrMFALocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, userRepo, _, tokenRepo, txManager := setupAuthService()
+			ctx := context.Background()
+
+			mfa := new(MockSecondFactor)
+			mfa.On("CompleteChallengeTx", ctx, mock.Anything, tt.req.MFAToken, tt.req.Code).Return(uuid.Nil, tt.mfaErr)
+			service.mfa = mfa
+			txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+
+			resp, err := service.LoginMFA(ctx, tt.req)
+
+			assert.Nil(t, resp)
+			assert.ErrorIs(t, err, tt.wantErr)
+			userRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+			tokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestAuthService_StepUp(t *testing.T) {
+	service, userRepo, _, tokenRepo, txManager := setupAuthService()
+	ctx := context.Background()
+
+	user := &models.User{ID: uuid.New(), Username: "testuser"}
+	claims := &models.JWTClaims{UserID: user.ID, AMR: []string{models.AMRPassword},
+		AuthTime: jwt.NewNumericDate(time.Now().Add(-time.Hour))}
+
+	mfa := new(MockSecondFactor)
+	mfa.On("VerifyTx", ctx, mock.Anything, user.ID, "123456").Return(nil)
+	mfa.On("VerifyTx", ctx, mock.Anything, user.ID, "000000").Return(custom_err.ErrInvalidMFACode)
+	service.mfa = mfa
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	tokenRepo.On("IsAccessTokenRevoked", ctx, mock.Anything).Return(false, nil)
+
+	_, err := service.StepUp(ctx, claims, models.MFACodeRequest{Code: "000000"})
+	assert.ErrorIs(t, err, custom_err.ErrInvalidMFACode)
+
+	resp, err := service.StepUp(ctx, claims, models.MFACodeRequest{Code: "123456"})
+	assert.NoError(t, err)
+	assert.Empty(t, resp.RefreshToken, "step-up must not extend the session")
+
+	stepped, err := service.ValidateToken(ctx, resp.Token)
+	assert.NoError(t, err)
+	assert.True(t, stepped.SteppedUp(time.Minute, time.Now()))
+	tokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_Refresh_KeepsAuthentication(t *testing.T) {
+	service, userRepo, _, tokenRepo, txManager := setupAuthService()
+	ctx := context.Background()
+
+	user := &models.User{ID: uuid.New(), Username: "testuser"}
+	authTime := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	amr := []string{models.AMRPassword, models.AMROTP}
+	current := &models.RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken("old-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+		AuthTime:  authTime,
+		AMR:       amr,
+	}
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	tokenRepo.On("GetRefreshTokenForUpdateTx", ctx, mock.Anything, current.TokenHash).Return(current, nil)
+	userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	tokenRepo.On("MarkRefreshTokenUsedTx", ctx, mock.Anything, current.ID).Return(nil)
+	tokenRepo.On("CreateRefreshTokenTx", ctx, mock.Anything, mock.MatchedBy(func(rt *models.RefreshToken) bool {
+		return rt.AuthTime.Equal(authTime) && slices.Equal(rt.AMR, amr)
+	})).Return(nil)
+	tokenRepo.On("IsAccessTokenRevoked", ctx, mock.Anything).Return(false, nil)
+
+	resp, err := service.Refresh(ctx, models.RefreshRequest{RefreshToken: "old-token"})
+	assert.NoError(t, err)
+
+	// Обновление не считается повторным входом: подтверждение вторым фактором остается старым
+	claims, err := service.ValidateToken(ctx, resp.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, amr, claims.AMR)
+	assert.True(t, claims.AuthTime.Equal(authTime))
+	assert.False(t, claims.SteppedUp(time.Hour, time.Now()))
+	tokenRepo.AssertExpectations(t)
+}
+
+func TestAuthService_AuthenticateAPIKey(t *testing.T) {
+	service, userRepo, _, _, _ := setupAuthService()
+	apiKeys := new(MockAPIKeyVerifier)
+	service.apiKeys = apiKeys
+	ctx := context.Background()
+	ip := netip.MustParseAddr("203.0.113.10")
+
+	verifiedAt := time.Now()
+	user := &models.User{ID: uuid.New(), Username: "reporter", Role: models.RoleSupport, EmailVerifiedAt: &verifiedAt}
+	key := &models.APIKey{ID: uuid.New(), UserID: user.ID, Scopes: []models.APIKeyScope{models.ScopeBalanceRead}}
+
+	apiKeys.On("Verify", ctx, "gwk_valid", ip).Return(key, nil)
+	apiKeys.On("Verify", ctx, "gwk_revoked", ip).Return(nil, custom_err.ErrTokenRevoked)
+	userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+
+	claims, err := service.AuthenticateAPIKey(ctx, "gwk_valid", ip)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, models.RoleSupport, claims.Role)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, key.ID, *claims.APIKeyID)
+	assert.Equal(t, key.Scopes, claims.Scopes)
+	// Ключ не заменяет второй фактор
+	assert.False(t, claims.SteppedUp(time.Hour, time.Now()))
+
+	_, err = service.AuthenticateAPIKey(ctx, "gwk_revoked", ip)
+	assert.ErrorIs(t, err, custom_err.ErrTokenRevoked)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/grpc_client"
+	"gw-currency-wallet/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CurrencyRegistry реестр поддерживаемых валют
+type CurrencyRegistry interface {
+	// List возвращает все валюты реестра, включая отключенные
+	List(ctx context.Context) ([]models.CurrencyInfo, error)
+	// Get возвращает валюту по коду или custom_err.ErrInvalidCurrency, если ее нет в реестре
+	Get(ctx context.Context, code models.Currency) (*models.CurrencyInfo, error)
+}
+
+// GrpcCurrencyRegistry реестр валют exchanger сервиса с локальным кэшем
+type GrpcCurrencyRegistry struct {
+	client          grpc_client.ExchangerClient
+	cacheExpiration time.Duration
+	log             *slog.Logger
+
+	mu         sync.RWMutex
+	currencies []models.CurrencyInfo
+	byCode     map[models.Currency]models.CurrencyInfo
+	fetchedAt  time.Time
+}
+
+func NewCurrencyRegistry(client grpc_client.ExchangerClient, cacheExpiration time.Duration, log *slog.Logger) CurrencyRegistry {
+	return &GrpcCurrencyRegistry{
+		client:          client,
+		cacheExpiration: cacheExpiration,
+		log:             log,
+	}
+}
+
+func (r *GrpcCurrencyRegistry) List(ctx context.Context) ([]models.CurrencyInfo, error) {
+	if err := r.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	currencies := make([]models.CurrencyInfo, len(r.currencies))
+	copy(currencies, r.currencies)
+	return currencies, nil
+}
+
+func (r *GrpcCurrencyRegistry) Get(ctx context.Context, code models.Currency) (*models.CurrencyInfo, error) {
+	if !code.IsValid() {
+		return nil, custom_err.ErrInvalidCurrency
+	}
+	if err := r.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	currency, ok := r.byCode[code]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown currency %s", custom_err.ErrInvalidCurrency, code)
+	}
+	return &currency, nil
+}
+
+// refresh обновляет кэш по истечении срока. Если exchanger недоступен,
+// продолжаем работать с последним полученным реестром.
+func (r *GrpcCurrencyRegistry) refresh(ctx context.Context) error {
+	const op = "service.CurrencyRegistry.refresh"
+
+	r.mu.RLock()
+	fresh := r.byCode != nil && time.Since(r.fetchedAt) < r.cacheExpiration
+	loaded := r.byCode != nil
+	r.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	resp, err := r.client.GetCurrencies(ctx)
+	if err != nil {
+		if loaded {
+			r.log.Warn("не удалось обновить реестр валют, используется кэш",
+				slog.String("op", op),
+				slog.String("error", err.Error()))
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	currencies := make([]models.CurrencyInfo, 0, len(resp))
+	byCode := make(map[models.Currency]models.CurrencyInfo, len(resp))
+	for _, c := range resp {
+		info := models.CurrencyInfo{
+			Code:     models.Currency(c.Code),
+			Name:     c.Name,
+			Exponent: c.Exponent,
+			Enabled:  c.Enabled,
+		}
+		currencies = append(currencies, info)
+		byCode[info.Code] = info
+	}
+
+	r.mu.Lock()
+	r.currencies = currencies
+	r.byCode = byCode
+	r.fetchedAt = time.Now()
+	r.mu.Unlock()
+
+	return nil
+}
+
+// requireEnabledCurrency проверяет, что по валюте можно проводить операции
+func requireEnabledCurrency(ctx context.Context, registry CurrencyRegistry, code models.Currency) (*models.CurrencyInfo, error) {
+	currency, err := registry.Get(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if !currency.Enabled {
+		return nil, fmt.Errorf("%w: currency %s is disabled", custom_err.ErrInvalidCurrency, code)
+	}
+	return currency, nil
+}
+
+// toMinorUnits проверяет сумму запроса и переводит ее в минимальные единицы валюты
+func toMinorUnits(amount decimal.Decimal, currency *models.CurrencyInfo) (int64, error) {
+	if !amount.IsPositive() {
+		return 0, custom_err.ErrInvalidAmount
+	}
+	units, err := models.AmountToMinorUnits(amount, currency.Exponent)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrAmountPrecision) {
+			return 0, fmt.Errorf("%w: %s allows %d decimal places", custom_err.ErrAmountPrecision, currency.Code, currency.Exponent)
+		}
+		return 0, fmt.Errorf("%w: %s", custom_err.ErrInvalidAmount, err.Error())
+	}
+	return units, nil
+}
+
+// exponents точность валют по коду
+type exponents map[string]int32
+
+// loadExponents собирает точность всех валют реестра, включая отключенные
+func loadExponents(ctx context.Context, registry CurrencyRegistry) (exponents, error) {
+	currencies, err := registry.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exps := make(exponents, len(currencies))
+	for _, c := range currencies {
+		exps[string(c.Code)] = c.Exponent
+	}
+	return exps, nil
+}
+
+// of возвращает точность валюты; для валюты вне реестра используется значение по умолчанию
+func (e exponents) of(code string) int32 {
+	if exp, ok := e[code]; ok {
+		return exp
+	}
+	return models.DefaultMinorUnitExponent
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/grpc_client"
+	"gw-currency-wallet/internal/models"
+)
+
+func setupCurrencyRegistry(cacheExpiration time.Duration) (*GrpcCurrencyRegistry, *MockExchangerClient) {
+	client := new(MockExchangerClient)
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	registry := &GrpcCurrencyRegistry{
+		client:          client,
+		cacheExpiration: cacheExpiration,
+		log:             log,
+	}
+	return registry, client
+}
+
+func TestCurrencyRegistry_Get_Caching(t *testing.T) {
+	registry, client := setupCurrencyRegistry(time.Minute)
+	ctx := context.Background()
+
+	client.On("GetCurrencies", ctx).Return([]grpc_client.Currency{
+		{Code: "USD", Name: "US Dollar", Exponent: 2, Enabled: true},
+		{Code: "JPY", Name: "Yen", Exponent: 0, Enabled: false},
+	}, nil).Once()
+
+	usd, err := registry.Get(ctx, models.CurrencyUSD)
+	require.NoError(t, err)
+	assert.True(t, usd.Enabled)
+
+	jpy, err := registry.Get(ctx, "JPY")
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), jpy.Exponent)
+	assert.False(t, jpy.Enabled)
+
+	_, err = registry.Get(ctx, "GBP")
+	assert.ErrorIs(t, err, custom_err.ErrInvalidCurrency)
+
+	_, err = registry.Get(ctx, "usd")
+	assert.ErrorIs(t, err, custom_err.ErrInvalidCurrency)
+
+	client.AssertNumberOfCalls(t, "GetCurrencies", 1)
+}
+
+func TestCurrencyRegistry_List_KeepsStaleOnError(t *testing.T) {
+	registry, client := setupCurrencyRegistry(time.Millisecond)
+	ctx := context.Background()
+
+	client.On("GetCurrencies", ctx).Return([]grpc_client.Currency{
+		{Code: "USD", Name: "US Dollar", Exponent: 2, Enabled: true},
+	}, nil).Once()
+	client.On("GetCurrencies", ctx).Return(nil, errors.New("unavailable"))
+
+	first, err := registry.List(ctx)
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	second, err := registry.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestCurrencyRegistry_List_ErrorWithoutCache(t *testing.T) {
+	registry, client := setupCurrencyRegistry(time.Minute)
+	ctx := context.Background()
+
+	client.On("GetCurrencies", ctx).Return(nil, errors.New("unavailable"))
+
+	_, err := registry.List(ctx)
+	assert.Error(t, err)
+}
@@ -2,10 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/grpc_client"
-	"gw-currency-wallet/internal/kafka"
 	"gw-currency-wallet/internal/models"
 	"gw-currency-wallet/internal/storage/postgres"
 	"log/slog"
@@ -14,119 +14,86 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
 )
 
+// largeTransferThreshold порог суммы, начиная с которого операция считается крупной
+var largeTransferThreshold = decimal.NewFromInt(30000)
+
 type CachedRate struct {
-	Rate      float64
+	Rate      decimal.Decimal
 	Timestamp time.Time
 }
 
 type Exchange interface {
-	GetExchangeRates(ctx context.Context) (map[string]float64, error)
+	GetExchangeRates(ctx context.Context) (map[string]decimal.Decimal, error)
+	// QuoteExchange фиксирует курс и суммы обмена; котировку можно один раз использовать в ExchangeCurrency
+	QuoteExchange(ctx context.Context, userID uuid.UUID, req models.ExchangeQuoteRequest) (*models.ExchangeQuoteResponse, error)
 	ExchangeCurrency(ctx context.Context, userID uuid.UUID, req models.ExchangeRequest) (*models.ExchangeResponse, error)
 }
 
 type ExchangeService struct {
-	walletRepo    postgres.WalletRepository
-	txManager     TxManager
-	grpcClient    grpc_client.ExchangerClient
-	kafkaProducer kafka.Producer
+	walletRepo postgres.WalletRepository
+	ledger     postgres.LedgerRepository
+	outbox     postgres.OutboxRepository
+	quotes     postgres.QuoteRepository
+	limits     Limits
+	txManager  TxManager
+	grpcClient grpc_client.ExchangerClient
+	currencies CurrencyRegistry
+	fees       FeeSchedule
 
 	cache         map[string]CachedRate
 	allRatesCache *AllRatesCache
 	cacheMutex    sync.RWMutex
 
 	cacheExpiration time.Duration
+	quoteTTL        time.Duration
+	rounding        models.RoundingMode
 	log             *slog.Logger
-
-	eventQueue chan models.LargeTransferEvent
-	wg         sync.WaitGroup
-	stopCh     chan struct{}
 }
 type AllRatesCache struct {
-	Rates     map[string]float64
+	Rates     map[string]decimal.Decimal
 	Timestamp time.Time
 }
 
 func NewExchangeService(
 	walletRepo postgres.WalletRepository,
+	ledger postgres.LedgerRepository,
+	outbox postgres.OutboxRepository,
+	quotes postgres.QuoteRepository,
+	limits Limits,
 	txManager TxManager,
 	grpcClient grpc_client.ExchangerClient,
-	kafkaProducer kafka.Producer,
+	currencies CurrencyRegistry,
+	fees FeeSchedule,
 	cacheExpiration time.Duration,
+	quoteTTL time.Duration,
+	rounding models.RoundingMode,
 	log *slog.Logger,
 ) *ExchangeService {
-	svc := &ExchangeService{
+	return &ExchangeService{
 		walletRepo:      walletRepo,
+		ledger:          ledger,
+		outbox:          outbox,
+		quotes:          quotes,
+		limits:          limits,
 		txManager:       txManager,
 		grpcClient:      grpcClient,
-		kafkaProducer:   kafkaProducer,
+		currencies:      currencies,
+		fees:            fees,
 		cache:           make(map[string]CachedRate),
 		cacheExpiration: cacheExpiration,
-		eventQueue:      make(chan models.LargeTransferEvent, 100),
-		stopCh:          make(chan struct{}),
+		quoteTTL:        quoteTTL,
+		rounding:        rounding,
 		log:             log,
 	}
-
-	for i := 0; i < 5; i++ {
-		svc.wg.Add(1)
-		go svc.kafkaWorker(i)
-	}
-
-	return svc
-}
-func (s *ExchangeService) kafkaWorker(id int) {
-	defer s.wg.Done()
-	s.log.Info("kafka worker started", slog.Int("worker_id", id))
-
-	for {
-		select {
-		case event := <-s.eventQueue:
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			if err := s.kafkaProducer.SendLargeTransferEvent(ctx, event); err != nil {
-				s.log.Error("kafka send failed",
-					slog.Int("worker_id", id),
-					slog.String("tx_id", event.TransactionID),
-					slog.String("error", err.Error()))
-			} else {
-				s.log.Info("event sent to kafka",
-					slog.Int("worker_id", id),
-					slog.String("tx_id", event.TransactionID))
-			}
-			cancel()
-
-		case <-s.stopCh:
-			s.log.Info("kafka worker stopping", slog.Int("worker_id", id))
-			return
-		}
-	}
-}
-
-func (s *ExchangeService) Shutdown(ctx context.Context) error {
-	s.log.Info("shutting down exchange service")
-
-	close(s.stopCh)
-
-	done := make(chan struct{})
-	go func() {
-		s.wg.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		s.log.Info("all kafka workers stopped")
-		return nil
-	case <-ctx.Done():
-		s.log.Warn("shutdown timeout exceeded")
-		return ctx.Err()
-	}
 }
 
-func (s *ExchangeService) GetExchangeRates(ctx context.Context) (map[string]float64, error) {
+func (s *ExchangeService) GetExchangeRates(ctx context.Context) (map[string]decimal.Decimal, error) {
 	s.cacheMutex.RLock()
 	if s.allRatesCache != nil && time.Since(s.allRatesCache.Timestamp) < s.cacheExpiration {
-		rates := make(map[string]float64, len(s.allRatesCache.Rates))
+		rates := make(map[string]decimal.Decimal, len(s.allRatesCache.Rates))
 		for k, v := range s.allRatesCache.Rates {
 			rates[k] = v
 		}
@@ -150,9 +117,8 @@ func (s *ExchangeService) GetExchangeRates(ctx context.Context) (map[string]floa
 	return resp.Rates, nil
 }
 
-func (s *ExchangeService) getExchangeRate(ctx context.Context, from, to string) (float64, error) {
-	const op = "service.getExchangeRate"
-
+// GetExchangeRate возвращает курс пары валют, используя кэш
+func (s *ExchangeService) GetExchangeRate(ctx context.Context, from, to string) (decimal.Decimal, error) {
 	cacheKey := fmt.Sprintf("%s_%s", from, to)
 
 	s.cacheMutex.RLock()
@@ -163,23 +129,33 @@ func (s *ExchangeService) getExchangeRate(ctx context.Context, from, to string)
 			s.log.Debug("курс взят из кэша",
 				slog.String("from", from),
 				slog.String("to", to),
-				slog.Float64("rate", rate))
+				slog.String("rate", rate.String()))
 			return rate, nil
 		}
 	}
 	s.cacheMutex.RUnlock()
 
+	return s.fetchExchangeRate(ctx, from, to)
+}
+
+// fetchExchangeRate запрашивает актуальный курс у exchanger сервиса и обновляет кэш
+func (s *ExchangeService) fetchExchangeRate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	const op = "service.GetExchangeRate"
+
 	s.log.Debug("запрос курса у exchanger сервиса",
 		slog.String("from", from),
 		slog.String("to", to))
 
 	resp, err := s.grpcClient.GetExchangeRateForCurrency(ctx, from, to)
 	if err != nil {
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return decimal.Zero, fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.Rate.Sign() <= 0 {
+		return decimal.Zero, fmt.Errorf("%s: non-positive rate %s", op, resp.Rate)
 	}
 
 	s.cacheMutex.Lock()
-	s.cache[cacheKey] = CachedRate{
+	s.cache[fmt.Sprintf("%s_%s", from, to)] = CachedRate{
 		Rate:      resp.Rate,
 		Timestamp: time.Now(),
 	}
@@ -188,45 +164,242 @@ func (s *ExchangeService) getExchangeRate(ctx context.Context, from, to string)
 	s.log.Debug("курс обновлен в кэше",
 		slog.String("from", from),
 		slog.String("to", to),
-		slog.Float64("rate", resp.Rate))
+		slog.String("rate", resp.Rate.String()))
 
 	return resp.Rate, nil
 }
 
-func (s *ExchangeService) ExchangeCurrency(ctx context.Context, userID uuid.UUID, req models.ExchangeRequest) (*models.ExchangeResponse, error) {
-	const op = "service.ExchangeCurrency"
+// exchangeTerms рассчитанные условия обмена в минимальных единицах валют.
+// Списывается amount, из него fee уходит в доход от комиссий, остаток конвертируется по rate.
+type exchangeTerms struct {
+	from            *models.CurrencyInfo
+	to              *models.CurrencyInfo
+	amount          int64
+	fee             int64
+	exchangedAmount int64
+	rate            decimal.Decimal
+}
+
+// priceExchange проверяет параметры обмена и рассчитывает комиссию и сумму к зачислению
+// по курсу из getRate с учетом спреда
+func (s *ExchangeService) priceExchange(
+	ctx context.Context,
+	from, to models.Currency,
+	amount decimal.Decimal,
+	getRate func(ctx context.Context, from, to string) (decimal.Decimal, error),
+) (*exchangeTerms, error) {
+	fromCurrency, err := requireEnabledCurrency(ctx, s.currencies, from)
+	if err != nil {
+		return nil, err
+	}
+	toCurrency, err := requireEnabledCurrency(ctx, s.currencies, to)
+	if err != nil {
+		return nil, err
+	}
+	amountInMinorUnits, err := toMinorUnits(amount, fromCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, fmt.Errorf("%w: cannot exchange same currency", custom_err.ErrInvalidCurrency)
+	}
+
+	midRate, err := getRate(ctx, string(from), string(to))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", custom_err.ErrRateUnavailable, err)
+	}
+
+	pricing, err := s.fees.Pricing(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange pricing: %w", err)
+	}
+	fee, err := pricing.Fee(amountInMinorUnits, s.rounding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", custom_err.ErrInvalidAmount, err.Error())
+	}
+	if fee >= amountInMinorUnits {
+		return nil, fmt.Errorf("%w: amount does not cover the exchange fee", custom_err.ErrInvalidAmount)
+	}
+	rate := pricing.ClientRate(midRate)
+	netAmount := models.AmountFromMinorUnits(amountInMinorUnits-fee, fromCurrency.Exponent)
+
+	exchangedAmountInMinorUnits, err := models.RoundToMinorUnits(netAmount.Mul(rate), toCurrency.Exponent, s.rounding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", custom_err.ErrInvalidAmount, err.Error())
+	}
+	if exchangedAmountInMinorUnits <= 0 {
+		return nil, fmt.Errorf("%w: amount is too small to exchange", custom_err.ErrInvalidAmount)
+	}
+
+	return &exchangeTerms{
+		from:            fromCurrency,
+		to:              toCurrency,
+		amount:          amountInMinorUnits,
+		fee:             fee,
+		exchangedAmount: exchangedAmountInMinorUnits,
+		rate:            rate,
+	}, nil
+}
+
+// QuoteExchange фиксирует актуальный курс и суммы обмена на время quoteTTL
+func (s *ExchangeService) QuoteExchange(ctx context.Context, userID uuid.UUID, req models.ExchangeQuoteRequest) (*models.ExchangeQuoteResponse, error) {
+	const op = "service.QuoteExchange"
+
+	// Котировка фиксирует курс, поэтому берем его у exchanger, а не из кэша
+	terms, err := s.priceExchange(ctx, req.FromCurrency, req.ToCurrency, req.Amount, s.fetchExchangeRate)
+	if err != nil {
+		return nil, err
+	}
 
-	if !req.FromCurrency.IsValid() || !req.ToCurrency.IsValid() {
-		return nil, custom_err.ErrInvalidCurrency
+	quote := models.ExchangeQuote{
+		ID:              uuid.New(),
+		UserID:          userID,
+		FromCurrency:    terms.from.Code,
+		ToCurrency:      terms.to.Code,
+		Amount:          terms.amount,
+		ExchangedAmount: terms.exchangedAmount,
+		Rate:            terms.rate,
+		Fee:             terms.fee,
+		ExpiresAt:       time.Now().Add(s.quoteTTL),
 	}
-	if req.Amount <= 0 {
-		return nil, custom_err.ErrInvalidAmount
+	if err := s.quotes.Create(ctx, quote); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	if req.FromCurrency == req.ToCurrency {
-		return nil, fmt.Errorf("%s: cannot exchange same currency", op)
+
+	s.log.Info("выдана котировка обмена",
+		slog.String("user_id", userID.String()),
+		slog.String("quote_id", quote.ID.String()),
+		slog.String("from", string(quote.FromCurrency)),
+		slog.String("to", string(quote.ToCurrency)),
+		slog.String("rate", quote.Rate.String()))
+
+	return &models.ExchangeQuoteResponse{
+		QuoteID:         quote.ID,
+		FromCurrency:    string(quote.FromCurrency),
+		ToCurrency:      string(quote.ToCurrency),
+		Amount:          models.AmountFromMinorUnits(quote.Amount, terms.from.Exponent),
+		ExchangedAmount: models.AmountFromMinorUnits(quote.ExchangedAmount, terms.to.Exponent),
+		Rate:            quote.Rate,
+		Fee:             models.AmountFromMinorUnits(quote.Fee, terms.from.Exponent),
+		ExpiresAt:       quote.ExpiresAt,
+	}, nil
+}
+
+func (s *ExchangeService) ExchangeCurrency(ctx context.Context, userID uuid.UUID, req models.ExchangeRequest) (*models.ExchangeResponse, error) {
+	if req.QuoteID != nil {
+		return s.exchangeByQuote(ctx, userID, req)
 	}
+
+	if req.RequestID == "" {
+		return nil, custom_err.ErrInvalidInput
+	}
+
+	terms, err := s.priceExchange(ctx, req.FromCurrency, req.ToCurrency, req.Amount, s.GetExchangeRate)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.executeExchange(ctx, userID, req.RequestID, terms, exchangeSource{})
+}
+
+// exchangeByQuote выполняет обмен строго по курсу и суммам котировки
+func (s *ExchangeService) exchangeByQuote(ctx context.Context, userID uuid.UUID, req models.ExchangeRequest) (*models.ExchangeResponse, error) {
+	const op = "service.ExchangeCurrency"
+
 	if req.RequestID == "" {
 		return nil, custom_err.ErrInvalidInput
 	}
 
-	rate, err := s.getExchangeRate(ctx, string(req.FromCurrency), string(req.ToCurrency))
+	quote, err := s.quotes.GetByID(ctx, *req.QuoteID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrQuoteNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// Чужая котировка неотличима от несуществующей
+	if quote.UserID != userID {
+		return nil, custom_err.ErrQuoteNotFound
+	}
+	if (req.FromCurrency != "" && req.FromCurrency != quote.FromCurrency) ||
+		(req.ToCurrency != "" && req.ToCurrency != quote.ToCurrency) {
+		return nil, fmt.Errorf("%w: currencies do not match the quote", custom_err.ErrInvalidInput)
+	}
+	if !quote.UsableAt(time.Now()) {
+		return nil, custom_err.ErrQuoteExpired
+	}
+
+	fromCurrency, err := requireEnabledCurrency(ctx, s.currencies, quote.FromCurrency)
+	if err != nil {
+		return nil, err
+	}
+	toCurrency, err := requireEnabledCurrency(ctx, s.currencies, quote.ToCurrency)
 	if err != nil {
-		return nil, fmt.Errorf("%s: failed to get exchange rate: %w", op, err)
+		return nil, err
+	}
+	if !req.Amount.IsZero() {
+		amountInMinorUnits, err := toMinorUnits(req.Amount, fromCurrency)
+		if err != nil {
+			return nil, err
+		}
+		if amountInMinorUnits != quote.Amount {
+			return nil, fmt.Errorf("%w: amount does not match the quote", custom_err.ErrInvalidInput)
+		}
 	}
 
-	exchangedAmount := req.Amount * rate
+	return s.executeExchange(ctx, userID, req.RequestID, &exchangeTerms{
+		from:            fromCurrency,
+		to:              toCurrency,
+		amount:          quote.Amount,
+		fee:             quote.Fee,
+		exchangedAmount: quote.ExchangedAmount,
+		rate:            quote.Rate,
+	}, exchangeSource{quoteID: &quote.ID})
+}
+
+// exchangeSource откуда взяты условия обмена
+type exchangeSource struct {
+	// quoteID котировка, которая помечается использованной в транзакции обмена
+	quoteID *uuid.UUID
+	// settleTx вызывается в транзакции обмена до проверки баланса; лимитный ордер
+	// закрывается в нем и снимает резерв, из которого оплачивается обмен
+	settleTx func(ctx context.Context, tx pgx.Tx) error
+}
 
-	s.log.Info("обмен валют",
+// executeExchange проводит обмен на рассчитанных условиях
+func (s *ExchangeService) executeExchange(
+	ctx context.Context,
+	userID uuid.UUID,
+	requestID string,
+	terms *exchangeTerms,
+	source exchangeSource,
+) (*models.ExchangeResponse, error) {
+	const op = "service.ExchangeCurrency"
+
+	quoteID := source.quoteID
+
+	fromCode, toCode := terms.from.Code, terms.to.Code
+	amount := models.AmountFromMinorUnits(terms.amount, terms.from.Exponent)
+	fee := models.AmountFromMinorUnits(terms.fee, terms.from.Exponent)
+	exchangedAmount := models.AmountFromMinorUnits(terms.exchangedAmount, terms.to.Exponent)
+
+	logAttrs := []any{
 		slog.String("user_id", userID.String()),
-		slog.String("from", string(req.FromCurrency)),
-		slog.String("to", string(req.ToCurrency)),
-		slog.Float64("amount", req.Amount),
-		slog.Float64("rate", rate),
-		slog.Float64("exchanged_amount", exchangedAmount))
+		slog.String("from", string(fromCode)),
+		slog.String("to", string(toCode)),
+		slog.String("amount", amount.String()),
+		slog.String("rate", terms.rate.String()),
+		slog.String("fee", fee.String()),
+		slog.String("exchanged_amount", exchangedAmount.String()),
+	}
+	if quoteID != nil {
+		logAttrs = append(logAttrs, slog.String("quote_id", quoteID.String()))
+	}
+	s.log.Info("обмен валют", logAttrs...)
 
-	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
 
-		exists, err := s.walletRepo.ExchangeOperationExistsTx(ctx, tx, req.RequestID)
+		exists, err := s.walletRepo.ExchangeOperationExistsTx(ctx, tx, requestID)
 		if err != nil {
 			return fmt.Errorf("failed to check exchange operation: %w", err)
 		}
@@ -234,31 +407,40 @@ func (s *ExchangeService) ExchangeCurrency(ctx context.Context, userID uuid.UUID
 			return custom_err.ErrDuplicateRequest
 		}
 
-		fromWallet, err := s.walletRepo.GetByUserAndCurrency(ctx, userID, req.FromCurrency)
+		if quoteID != nil {
+			if err := s.quotes.UseTx(ctx, tx, *quoteID, userID, time.Now()); err != nil {
+				return err
+			}
+		}
+		if source.settleTx != nil {
+			if err := source.settleTx(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		fromWallet, err := s.walletRepo.GetByUserAndCurrency(ctx, userID, fromCode)
 		if err != nil {
 			return fmt.Errorf("failed to get source wallet: %w", err)
 		}
 
-		toWallet, err := s.walletRepo.GetByUserAndCurrency(ctx, userID, req.ToCurrency)
+		toWallet, err := s.walletRepo.GetOrCreateByUserAndCurrencyTx(ctx, tx, userID, toCode)
 		if err != nil {
 			return fmt.Errorf("failed to get destination wallet: %w", err)
 		}
 
-		amountInMinorUnits := models.AmountToMinorUnits(req.Amount)
-		exchangedAmountInMinorUnits := models.AmountToMinorUnits(exchangedAmount)
-
 		fromBalance, err := s.walletRepo.GetWalletBalanceForUpdateTx(ctx, tx, fromWallet.ID)
 		if err != nil {
 			return fmt.Errorf("failed to get source balance: %w", err)
 		}
 
-		newFromBalance := fromBalance - amountInMinorUnits
-		if newFromBalance < 0 {
+		// Сумма, зарезервированная холдами, недоступна для обмена
+		if fromBalance.Available() < terms.amount {
 			return custom_err.ErrInsufficientFunds
 		}
+		newFromBalance := fromBalance.Balance - terms.amount
 
-		if err := s.walletRepo.UpdateBalanceTx(ctx, tx, fromWallet.ID, newFromBalance); err != nil {
-			return fmt.Errorf("failed to update source balance: %w", err)
+		if err := s.limits.CheckTx(ctx, tx, userID, models.OperationExchange, fromCode, terms.amount); err != nil {
+			return err
 		}
 
 		toBalance, err := s.walletRepo.GetWalletBalanceForUpdateTx(ctx, tx, toWallet.ID)
@@ -266,24 +448,75 @@ func (s *ExchangeService) ExchangeCurrency(ctx context.Context, userID uuid.UUID
 			return fmt.Errorf("failed to get destination balance: %w", err)
 		}
 
-		newToBalance := toBalance + exchangedAmountInMinorUnits
+		newToBalance := toBalance.Balance + terms.exchangedAmount
 
-		if err := s.walletRepo.UpdateBalanceTx(ctx, tx, toWallet.ID, newToBalance); err != nil {
-			return fmt.Errorf("failed to update destination balance: %w", err)
+		fxFromAccountID, err := s.ledger.GetSystemAccountIDTx(ctx, tx, models.SystemAccountFXHouse, string(fromCode))
+		if err != nil {
+			return fmt.Errorf("failed to get fx account: %w", err)
+		}
+		fxToAccountID, err := s.ledger.GetSystemAccountIDTx(ctx, tx, models.SystemAccountFXHouse, string(toCode))
+		if err != nil {
+			return fmt.Errorf("failed to get fx account: %w", err)
+		}
+
+		// Четыре ноги: в каждой валюте сумма записей равна нулю
+		postings := []models.Posting{
+			{AccountID: fromWallet.ID, Currency: string(fromCode), Amount: -terms.amount},
+			{AccountID: fxFromAccountID, Currency: string(fromCode), Amount: terms.amount - terms.fee},
+			{AccountID: fxToAccountID, Currency: string(toCode), Amount: -terms.exchangedAmount},
+			{AccountID: toWallet.ID, Currency: string(toCode), Amount: terms.exchangedAmount},
+		}
+		if terms.fee > 0 {
+			// Комиссия удерживается в валюте списания и зачисляется на счет доходов
+			feeAccountID, err := s.ledger.GetSystemAccountIDTx(ctx, tx, models.SystemAccountFeeIncome, string(fromCode))
+			if err != nil {
+				return fmt.Errorf("failed to get fee account: %w", err)
+			}
+			postings = append(postings, models.Posting{AccountID: feeAccountID, Currency: string(fromCode), Amount: terms.fee})
+		}
+
+		err = s.ledger.PostEntryTx(ctx, tx, models.JournalEntry{
+			EntryType: models.OperationExchange,
+			RequestID: requestID,
+			Postings:  postings,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to post ledger entry: %w", err)
 		}
 
 		err = s.walletRepo.CreateExchangeOperationTx(ctx, tx, models.ExchangeOperation{
-			UserID:          userID,
-			FromCurrency:    string(req.FromCurrency),
-			ToCurrency:      string(req.ToCurrency),
-			Amount:          amountInMinorUnits,
-			ExchangedAmount: exchangedAmountInMinorUnits,
-			Rate:            rate,
-			RequestID:       req.RequestID,
+			UserID:           userID,
+			FromCurrency:     string(fromCode),
+			ToCurrency:       string(toCode),
+			Amount:           terms.amount,
+			ExchangedAmount:  terms.exchangedAmount,
+			Rate:             terms.rate,
+			FromBalanceAfter: newFromBalance,
+			ToBalanceAfter:   newToBalance,
+			RequestID:        requestID,
+			QuoteID:          quoteID,
+			Fee:              terms.fee,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to create exchange operation: %w", err)
 		}
+
+		if amount.GreaterThanOrEqual(largeTransferThreshold) || exchangedAmount.GreaterThanOrEqual(largeTransferThreshold) {
+			err = enqueueLargeTransferTx(ctx, s.outbox, tx, models.LargeTransferEvent{
+				TransactionID: requestID,
+				Type:          string(models.OperationExchange),
+				UserID:        userID,
+				FromCurrency:  string(fromCode),
+				ToCurrency:    string(toCode),
+				Amount:        amount.InexactFloat64(),
+				ExchangedAmt:  exchangedAmount.InexactFloat64(),
+				Rate:          terms.rate.InexactFloat64(),
+				Timestamp:     time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 
@@ -291,32 +524,11 @@ func (s *ExchangeService) ExchangeCurrency(ctx context.Context, userID uuid.UUID
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	const largeTransferThreshold = 30000.0
-	if req.Amount >= largeTransferThreshold || exchangedAmount >= largeTransferThreshold {
-		event := models.LargeTransferEvent{
-			TransactionID: req.RequestID,
-			UserID:        userID,
-			FromCurrency:  string(req.FromCurrency),
-			ToCurrency:    string(req.ToCurrency),
-			Amount:        req.Amount,
-			ExchangedAmt:  exchangedAmount,
-			Rate:          rate,
-			Timestamp:     time.Now(),
-		}
-
-		select {
-		case s.eventQueue <- event:
-			s.log.Debug("событие о крупном переводе добавлено в очередь", slog.String("transaction_id", req.RequestID))
-		default:
-			s.log.Error("очередь событий переполнена, событие отброшено",
-				slog.String("transaction_id", req.RequestID),
-				slog.Float64("amount", req.Amount))
-		}
-	}
-
 	return &models.ExchangeResponse{
 		Message:         "Exchange successful",
 		ExchangedAmount: exchangedAmount,
-		Rate:            rate,
+		Rate:            terms.rate,
+		Fee:             fee,
+		FeeCurrency:     string(fromCode),
 	}, nil
 }
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"testing"
@@ -9,44 +10,51 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/grpc_client"
 	"gw-currency-wallet/internal/models"
 )
 
-func setupExchangeService(t *testing.T) (*ExchangeService, *MockWalletRepo, *MockTxManager, *MockExchangerClient, *MockKafkaProducer) {
+func setupExchangeService(t *testing.T) (*ExchangeService, *MockWalletRepo, *MockLedgerRepo, *MockTxManager, *MockExchangerClient, *MockOutboxRepository) {
 	walletRepo := new(MockWalletRepo)
+	ledger := new(MockLedgerRepo)
 	txManager := new(MockTxManager)
 	grpcClient := new(MockExchangerClient)
-	kafkaProducer := new(MockKafkaProducer)
+	outbox := new(MockOutboxRepository)
 
 	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
 	service := &ExchangeService{
 		walletRepo:      walletRepo,
+		ledger:          ledger,
+		outbox:          outbox,
+		limits:          stubLimits{},
 		txManager:       txManager,
 		grpcClient:      grpcClient,
-		kafkaProducer:   kafkaProducer,
+		currencies:      newTestCurrencyRegistry(),
+		fees:            staticFeeSchedule{},
 		cache:           make(map[string]CachedRate),
 		cacheExpiration: 5 * time.Minute,
 		log:             log,
 	}
 
-	return service, walletRepo, txManager, grpcClient, kafkaProducer
+	return service, walletRepo, ledger, txManager, grpcClient, outbox
 }
 
 func TestExchangeService_GetExchangeRates_Success(t *testing.T) {
-	service, _, _, grpcClient, _ := setupExchangeService(t)
+	service, _, _, _, grpcClient, _ := setupExchangeService(t)
 	ctx := context.Background()
 
 	expectedRates := &grpc_client.ExchangeRatesResponse{
-		Rates: map[string]float64{
-			"USD": 1.0,
-			"RUB": 95.5,
-			"EUR": 0.92,
+		Rates: map[string]decimal.Decimal{
+			"USD": decimal.RequireFromString("1.0"),
+			"RUB": decimal.RequireFromString("95.5"),
+			"EUR": decimal.RequireFromString("0.92"),
 		},
 	}
 
@@ -56,22 +64,22 @@ func TestExchangeService_GetExchangeRates_Success(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.NotNil(t, rates)
-	assert.Equal(t, 1.0, rates["USD"])
-	assert.Equal(t, 95.5, rates["RUB"])
-	assert.Equal(t, 0.92, rates["EUR"])
+	assert.Equal(t, "1", rates["USD"].String())
+	assert.Equal(t, "95.5", rates["RUB"].String())
+	assert.Equal(t, "0.92", rates["EUR"].String())
 
 	grpcClient.AssertExpectations(t)
 }
 
 func TestExchangeService_GetExchangeRates_Caching(t *testing.T) {
-	service, _, _, grpcClient, _ := setupExchangeService(t)
+	service, _, _, _, grpcClient, _ := setupExchangeService(t)
 	ctx := context.Background()
 
 	expectedRates := &grpc_client.ExchangeRatesResponse{
-		Rates: map[string]float64{
-			"USD": 1.0,
-			"RUB": 95.5,
-			"EUR": 0.92,
+		Rates: map[string]decimal.Decimal{
+			"USD": decimal.RequireFromString("1.0"),
+			"RUB": decimal.RequireFromString("95.5"),
+			"EUR": decimal.RequireFromString("0.92"),
 		},
 	}
 
@@ -93,14 +101,13 @@ func TestExchangeService_GetExchangeRates_CacheExpiration(t *testing.T) {
 	walletRepo := new(MockWalletRepo)
 	txManager := new(MockTxManager)
 	grpcClient := new(MockExchangerClient)
-	kafkaProducer := new(MockKafkaProducer)
 	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
 
 	service := &ExchangeService{
 		walletRepo:      walletRepo,
 		txManager:       txManager,
 		grpcClient:      grpcClient,
-		kafkaProducer:   kafkaProducer,
+		currencies:      newTestCurrencyRegistry(),
 		cache:           make(map[string]CachedRate),
 		cacheExpiration: 100 * time.Millisecond,
 		log:             log,
@@ -109,7 +116,7 @@ func TestExchangeService_GetExchangeRates_CacheExpiration(t *testing.T) {
 	ctx := context.Background()
 
 	expectedRates := &grpc_client.ExchangeRatesResponse{
-		Rates: map[string]float64{"USD": 1.0},
+		Rates: map[string]decimal.Decimal{"USD": decimal.NewFromInt(1)},
 	}
 
 	grpcClient.On("GetExchangeRates", ctx).Return(expectedRates, nil).Twice()
@@ -126,55 +133,77 @@ func TestExchangeService_GetExchangeRates_CacheExpiration(t *testing.T) {
 }
 
 func TestExchangeService_ExchangeCurrency_Success(t *testing.T) {
-	service, walletRepo, txManager, grpcClient, _ := setupExchangeService(t)
+	service, walletRepo, ledger, txManager, grpcClient, _ := setupExchangeService(t)
 	ctx := context.Background()
 	userID := uuid.New()
 	fromWalletID := uuid.New()
 	toWalletID := uuid.New()
+	fxUSDAccountID := uuid.New()
+	fxEURAccountID := uuid.New()
 
 	req := models.ExchangeRequest{
 		FromCurrency: models.CurrencyUSD,
 		ToCurrency:   models.CurrencyEUR,
-		Amount:       100.00,
+		Amount:       decimal.RequireFromString("100.00"),
 		RequestID:    "exchange-001",
 	}
 
 	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
 		FromCurrency: "USD",
 		ToCurrency:   "EUR",
-		Rate:         0.92,
+		Rate:         decimal.RequireFromString("0.92"),
 	}, nil)
 
 	fromWallet := &models.Wallet{ID: fromWalletID, UserID: userID, Currency: "USD", Balance: 100000}
 	toWallet := &models.Wallet{ID: toWalletID, UserID: userID, Currency: "EUR", Balance: 0}
 
 	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
-	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyEUR).Return(toWallet, nil)
+	walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyEUR).Return(toWallet, nil)
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
-	walletRepo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
-	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWalletID).Return(int64(100000), nil)
-	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWalletID).Return(int64(0), nil)
-	walletRepo.On("UpdateBalanceTx", ctx, mock.Anything, fromWalletID, int64(90000)).Return(nil)
-	walletRepo.On("UpdateBalanceTx", ctx, mock.Anything, toWalletID, int64(9200)).Return(nil)
-	walletRepo.On("CreateOperationTx", ctx, mock.Anything, fromWalletID, int64(-10000), req.RequestID).Return(nil)
-	walletRepo.On("CreateOperationTx", ctx, mock.Anything, toWalletID, int64(9200), req.RequestID+"_to").Return(nil)
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWalletID).Return(models.WalletBalance{Balance: 100000}, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWalletID).Return(models.WalletBalance{Balance: 0}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, "USD").Return(fxUSDAccountID, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, "EUR").Return(fxEURAccountID, nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, models.JournalEntry{
+		EntryType: models.OperationExchange,
+		RequestID: req.RequestID,
+		Postings: []models.Posting{
+			{AccountID: fromWalletID, Currency: "USD", Amount: -10000},
+			{AccountID: fxUSDAccountID, Currency: "USD", Amount: 10000},
+			{AccountID: fxEURAccountID, Currency: "EUR", Amount: -9200},
+			{AccountID: toWalletID, Currency: "EUR", Amount: 9200},
+		},
+	}).Return(nil)
+	walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, models.ExchangeOperation{
+		UserID:           userID,
+		FromCurrency:     "USD",
+		ToCurrency:       "EUR",
+		Amount:           10000,
+		ExchangedAmount:  9200,
+		Rate:             decimal.RequireFromString("0.92"),
+		FromBalanceAfter: 90000,
+		ToBalanceAfter:   9200,
+		RequestID:        req.RequestID,
+	}).Return(nil)
 
 	resp, err := service.ExchangeCurrency(ctx, userID, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.Equal(t, "Exchange successful", resp.Message)
-	assert.Equal(t, 92.0, resp.ExchangedAmount)
-	assert.Equal(t, 0.92, resp.Rate)
+	assert.Equal(t, "92", resp.ExchangedAmount.String())
+	assert.Equal(t, "0.92", resp.Rate.String())
 
 	walletRepo.AssertExpectations(t)
+	ledger.AssertExpectations(t)
 	txManager.AssertExpectations(t)
 	grpcClient.AssertExpectations(t)
 }
 
 func TestExchangeService_ExchangeCurrency_InvalidCurrency(t *testing.T) {
-	service, _, _, _, _ := setupExchangeService(t)
+	service, _, _, _, _, _ := setupExchangeService(t)
 	ctx := context.Background()
 	userID := uuid.New()
 
@@ -187,7 +216,7 @@ func TestExchangeService_ExchangeCurrency_InvalidCurrency(t *testing.T) {
 			req: models.ExchangeRequest{
 				FromCurrency: "INVALID",
 				ToCurrency:   models.CurrencyEUR,
-				Amount:       100.00,
+				Amount:       decimal.RequireFromString("100.00"),
 				RequestID:    "exchange-001",
 			},
 		},
@@ -196,7 +225,7 @@ func TestExchangeService_ExchangeCurrency_InvalidCurrency(t *testing.T) {
 			req: models.ExchangeRequest{
 				FromCurrency: models.CurrencyUSD,
 				ToCurrency:   "INVALID",
-				Amount:       100.00,
+				Amount:       decimal.RequireFromString("100.00"),
 				RequestID:    "exchange-001",
 			},
 		},
@@ -214,16 +243,16 @@ func TestExchangeService_ExchangeCurrency_InvalidCurrency(t *testing.T) {
 }
 
 func TestExchangeService_ExchangeCurrency_InvalidAmount(t *testing.T) {
-	service, _, _, _, _ := setupExchangeService(t)
+	service, _, _, _, _, _ := setupExchangeService(t)
 	ctx := context.Background()
 	userID := uuid.New()
 
 	tests := []struct {
 		name   string
-		amount float64
+		amount decimal.Decimal
 	}{
-		{"zero amount", 0.0},
-		{"negative amount", -100.0},
+		{"zero amount", decimal.Zero},
+		{"negative amount", decimal.RequireFromString("-100")},
 	}
 
 	for _, tt := range tests {
@@ -245,14 +274,14 @@ func TestExchangeService_ExchangeCurrency_InvalidAmount(t *testing.T) {
 }
 
 func TestExchangeService_ExchangeCurrency_SameCurrency(t *testing.T) {
-	service, _, _, _, _ := setupExchangeService(t)
+	service, _, _, _, _, _ := setupExchangeService(t)
 	ctx := context.Background()
 	userID := uuid.New()
 
 	req := models.ExchangeRequest{
 		FromCurrency: models.CurrencyUSD,
 		ToCurrency:   models.CurrencyUSD,
-		Amount:       100.00,
+		Amount:       decimal.RequireFromString("100.00"),
 		RequestID:    "exchange-001",
 	}
 
@@ -264,7 +293,7 @@ func TestExchangeService_ExchangeCurrency_SameCurrency(t *testing.T) {
 }
 
 func TestExchangeService_ExchangeCurrency_InsufficientFunds(t *testing.T) {
-	service, walletRepo, txManager, grpcClient, _ := setupExchangeService(t)
+	service, walletRepo, _, txManager, grpcClient, _ := setupExchangeService(t)
 	ctx := context.Background()
 	userID := uuid.New()
 	fromWalletID := uuid.New()
@@ -273,19 +302,19 @@ func TestExchangeService_ExchangeCurrency_InsufficientFunds(t *testing.T) {
 	req := models.ExchangeRequest{
 		FromCurrency: models.CurrencyUSD,
 		ToCurrency:   models.CurrencyEUR,
-		Amount:       1000.00,
+		Amount:       decimal.RequireFromString("1000.00"),
 		RequestID:    "exchange-001",
 	}
 
 	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
-		Rate: 0.92,
+		Rate: decimal.RequireFromString("0.92"),
 	}, nil)
 
 	fromWallet := &models.Wallet{ID: fromWalletID, UserID: userID, Currency: "USD", Balance: 10000}
 	toWallet := &models.Wallet{ID: toWalletID, UserID: userID, Currency: "EUR", Balance: 0}
 
 	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
-	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyEUR).Return(toWallet, nil)
+	walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyEUR).Return(toWallet, nil)
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).
 		Run(func(args mock.Arguments) {
@@ -294,8 +323,8 @@ func TestExchangeService_ExchangeCurrency_InsufficientFunds(t *testing.T) {
 		}).
 		Return(custom_err.ErrInsufficientFunds)
 
-	walletRepo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
-	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWalletID).Return(int64(10000), nil)
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWalletID).Return(models.WalletBalance{Balance: 10000}, nil)
 
 	resp, err := service.ExchangeCurrency(ctx, userID, req)
 
@@ -307,8 +336,8 @@ func TestExchangeService_ExchangeCurrency_InsufficientFunds(t *testing.T) {
 	txManager.AssertExpectations(t)
 	grpcClient.AssertExpectations(t)
 }
-func TestExchangeService_ExchangeCurrency_LargeTransfer_KafkaEvent(t *testing.T) {
-	service, walletRepo, txManager, grpcClient, kafkaProducer := setupExchangeService(t)
+func TestExchangeService_ExchangeCurrency_LargeTransfer_OutboxEvent(t *testing.T) {
+	service, walletRepo, ledger, txManager, grpcClient, outbox := setupExchangeService(t)
 	ctx := context.Background()
 	userID := uuid.New()
 	fromWalletID := uuid.New()
@@ -317,33 +346,36 @@ func TestExchangeService_ExchangeCurrency_LargeTransfer_KafkaEvent(t *testing.T)
 	req := models.ExchangeRequest{
 		FromCurrency: models.CurrencyUSD,
 		ToCurrency:   models.CurrencyRUB,
-		Amount:       35000.00,
+		Amount:       decimal.RequireFromString("35000.00"),
 		RequestID:    "exchange-large-001",
 	}
 
 	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "RUB").Return(&grpc_client.ExchangeRateResponse{
-		Rate: 95.5,
+		Rate: decimal.RequireFromString("95.5"),
 	}, nil)
 
 	fromWallet := &models.Wallet{ID: fromWalletID, UserID: userID, Currency: "USD", Balance: 5000000}
 	toWallet := &models.Wallet{ID: toWalletID, UserID: userID, Currency: "RUB", Balance: 0}
 
 	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
-	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyRUB).Return(toWallet, nil)
+	walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyRUB).Return(toWallet, nil)
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
-	walletRepo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
-	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWalletID).Return(int64(5000000), nil)
-	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWalletID).Return(int64(0), nil)
-	walletRepo.On("UpdateBalanceTx", ctx, mock.Anything, fromWalletID, mock.Anything).Return(nil)
-	walletRepo.On("UpdateBalanceTx", ctx, mock.Anything, toWalletID, mock.Anything).Return(nil)
-	walletRepo.On("CreateOperationTx", ctx, mock.Anything, fromWalletID, mock.Anything, req.RequestID).Return(nil)
-	walletRepo.On("CreateOperationTx", ctx, mock.Anything, toWalletID, mock.Anything, req.RequestID+"_to").Return(nil)
-
-	kafkaProducer.On("SendLargeTransferEvent", mock.Anything, mock.MatchedBy(func(event models.LargeTransferEvent) bool {
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWalletID).Return(models.WalletBalance{Balance: 5000000}, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWalletID).Return(models.WalletBalance{Balance: 0}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, mock.Anything).Return(uuid.New(), nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, mock.MatchedBy(func(entry models.JournalEntry) bool {
+		return entry.Validate() == nil && len(entry.Postings) == 4
+	})).Return(nil)
+	walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, mock.MatchedBy(func(op models.ExchangeOperation) bool {
+		return op.RequestID == req.RequestID && op.FromBalanceAfter == 1500000
+	})).Return(nil)
+
+	outbox.On("CreateTx", ctx, mock.Anything, models.OutboxEventLargeTransfer, req.RequestID, largeTransferPayload(func(event models.LargeTransferEvent) bool {
 		return event.TransactionID == req.RequestID &&
 			event.UserID == userID &&
-			event.Amount == 35000.0
+			event.Amount == 35000
 	})).Return(nil)
 
 	resp, err := service.ExchangeCurrency(ctx, userID, req)
@@ -351,28 +383,63 @@ func TestExchangeService_ExchangeCurrency_LargeTransfer_KafkaEvent(t *testing.T)
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 
-	time.Sleep(100 * time.Millisecond)
-
-	kafkaProducer.AssertExpectations(t)
+	outbox.AssertExpectations(t)
 	walletRepo.AssertExpectations(t)
+	ledger.AssertExpectations(t)
 	txManager.AssertExpectations(t)
 	grpcClient.AssertExpectations(t)
 }
 
+func TestExchangeService_ExchangeCurrency_LargeTransfer_OutboxFailure(t *testing.T) {
+	service, walletRepo, ledger, txManager, grpcClient, outbox := setupExchangeService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "RUB"}
+
+	req := models.ExchangeRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyRUB,
+		Amount:       decimal.RequireFromString("35000.00"),
+		RequestID:    "exchange-large-002",
+	}
+
+	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "RUB").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("95.5"),
+	}, nil)
+	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
+	walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyRUB).Return(toWallet, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 5000000}, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{Balance: 0}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, mock.Anything).Return(uuid.New(), nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, mock.Anything).Return(nil)
+	walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, mock.Anything).Return(nil)
+	outbox.On("CreateTx", ctx, mock.Anything, models.OutboxEventLargeTransfer, req.RequestID, mock.Anything).Return(errors.New("outbox insert failed"))
+
+	// Ошибка записи события откатывает всю операцию
+	resp, err := service.ExchangeCurrency(ctx, userID, req)
+
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+	outbox.AssertExpectations(t)
+}
+
 func TestExchangeService_ExchangeCurrency_DuplicateRequest(t *testing.T) {
-	service, walletRepo, txManager, grpcClient, _ := setupExchangeService(t)
+	service, walletRepo, _, txManager, grpcClient, _ := setupExchangeService(t)
 	ctx := context.Background()
 	userID := uuid.New()
 
 	req := models.ExchangeRequest{
 		FromCurrency: models.CurrencyUSD,
 		ToCurrency:   models.CurrencyEUR,
-		Amount:       100.00,
+		Amount:       decimal.RequireFromString("100.00"),
 		RequestID:    "exchange-001",
 	}
 
 	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
-		Rate: 0.92,
+		Rate: decimal.RequireFromString("0.92"),
 	}, nil)
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).
@@ -380,13 +447,411 @@ func TestExchangeService_ExchangeCurrency_DuplicateRequest(t *testing.T) {
 
 	resp, err := service.ExchangeCurrency(ctx, userID, req)
 
-	assert.NoError(t, err)
-	assert.NotNil(t, resp)
-	assert.Equal(t, "Exchange successful", resp.Message)
-	assert.Equal(t, 92.0, resp.ExchangedAmount)
-	assert.Equal(t, 0.92, resp.Rate)
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrDuplicateRequest)
 
 	walletRepo.AssertExpectations(t)
 	txManager.AssertExpectations(t)
 	grpcClient.AssertExpectations(t)
 }
+
+func TestExchangeService_ExchangeCurrency_RoundsConvertedAmount(t *testing.T) {
+	service, walletRepo, ledger, txManager, grpcClient, _ := setupExchangeService(t)
+	service.rounding = models.RoundDown
+	ctx := context.Background()
+	userID := uuid.New()
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "EUR"}
+
+	req := models.ExchangeRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("0.29"),
+		RequestID:    "exchange-round-001",
+	}
+
+	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("0.9263"),
+	}, nil)
+	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
+	walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyEUR).Return(toWallet, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 100}, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{Balance: 0}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, mock.Anything).Return(uuid.New(), nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, mock.Anything).Return(nil)
+	// 0.29 * 0.9263 = 0.268627 -> DOWN -> 0.26; списывается ровно 29 центов
+	walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, mock.MatchedBy(func(op models.ExchangeOperation) bool {
+		return op.Amount == 29 && op.ExchangedAmount == 26 && op.FromBalanceAfter == 71
+	})).Return(nil)
+
+	resp, err := service.ExchangeCurrency(ctx, userID, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "0.26", resp.ExchangedAmount.String())
+	walletRepo.AssertExpectations(t)
+}
+
+func TestExchangeService_ExchangeCurrency_TooPreciseAmount(t *testing.T) {
+	service, _, _, _, grpcClient, _ := setupExchangeService(t)
+	ctx := context.Background()
+
+	resp, err := service.ExchangeCurrency(ctx, uuid.New(), models.ExchangeRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("1.005"),
+		RequestID:    "exchange-precise-001",
+	})
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrAmountPrecision)
+	grpcClient.AssertNotCalled(t, "GetExchangeRateForCurrency", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExchangeService_ExchangeCurrency_ZeroExponentTarget(t *testing.T) {
+	service, walletRepo, ledger, txManager, grpcClient, _ := setupExchangeService(t)
+	service.currencies = append(newTestCurrencyRegistry(), models.CurrencyInfo{Code: "JPY", Name: "Yen", Exponent: 0, Enabled: true})
+	ctx := context.Background()
+	userID := uuid.New()
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "JPY"}
+
+	req := models.ExchangeRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   "JPY",
+		Amount:       decimal.RequireFromString("10.01"),
+		RequestID:    "exchange-jpy-001",
+	}
+
+	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "JPY").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("151.37"),
+	}, nil)
+	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
+	walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.Currency("JPY")).Return(toWallet, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 5000}, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{Balance: 0}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, mock.Anything).Return(uuid.New(), nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, mock.Anything).Return(nil)
+	// 10.01 * 151.37 = 1515.2137 -> 1515 иен; центы списываются в точности
+	walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, mock.MatchedBy(func(op models.ExchangeOperation) bool {
+		return op.Amount == 1001 && op.ExchangedAmount == 1515 && op.ToBalanceAfter == 1515
+	})).Return(nil)
+
+	resp, err := service.ExchangeCurrency(ctx, userID, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "1515", resp.ExchangedAmount.String())
+	walletRepo.AssertExpectations(t)
+}
+
+func setupQuotedExchangeService(t *testing.T) (*ExchangeService, *MockQuoteRepository, *MockWalletRepo, *MockLedgerRepo, *MockTxManager, *MockExchangerClient) {
+	service, walletRepo, ledger, txManager, grpcClient, _ := setupExchangeService(t)
+	quotes := new(MockQuoteRepository)
+	service.quotes = quotes
+	service.quoteTTL = 30 * time.Second
+	return service, quotes, walletRepo, ledger, txManager, grpcClient
+}
+
+func TestExchangeService_QuoteExchange_LocksFreshRate(t *testing.T) {
+	service, quotes, _, _, _, grpcClient := setupQuotedExchangeService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// Котировка не должна брать курс из кэша
+	service.cache["USD_EUR"] = CachedRate{Rate: decimal.RequireFromString("0.80"), Timestamp: time.Now()}
+	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("0.92"),
+	}, nil).Once()
+
+	var saved models.ExchangeQuote
+	quotes.On("Create", ctx, mock.MatchedBy(func(q models.ExchangeQuote) bool {
+		saved = q
+		return q.UserID == userID && q.FromCurrency == models.CurrencyUSD && q.ToCurrency == models.CurrencyEUR &&
+			q.Amount == 10000 && q.ExchangedAmount == 9200 && q.Rate.Equal(decimal.RequireFromString("0.92"))
+	})).Return(nil)
+
+	before := time.Now()
+	resp, err := service.QuoteExchange(ctx, userID, models.ExchangeQuoteRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("100"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, saved.ID, resp.QuoteID)
+	assert.Equal(t, "92", resp.ExchangedAmount.String())
+	assert.Equal(t, "0.92", resp.Rate.String())
+	assert.WithinDuration(t, before.Add(30*time.Second), resp.ExpiresAt, time.Second)
+	assert.Equal(t, "0.92", service.cache["USD_EUR"].Rate.String())
+	quotes.AssertExpectations(t)
+	grpcClient.AssertExpectations(t)
+}
+
+func TestExchangeService_QuoteExchange_InvalidRequest(t *testing.T) {
+	service, quotes, _, _, _, grpcClient := setupQuotedExchangeService(t)
+	ctx := context.Background()
+
+	_, err := service.QuoteExchange(ctx, uuid.New(), models.ExchangeQuoteRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyUSD,
+		Amount:       decimal.RequireFromString("100"),
+	})
+
+	assert.ErrorIs(t, err, custom_err.ErrInvalidCurrency)
+	grpcClient.AssertNotCalled(t, "GetExchangeRateForCurrency", mock.Anything, mock.Anything, mock.Anything)
+	quotes.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestExchangeService_ExchangeCurrency_WithQuote(t *testing.T) {
+	service, quotes, walletRepo, ledger, txManager, grpcClient := setupQuotedExchangeService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "EUR"}
+
+	quote := &models.ExchangeQuote{
+		ID:              uuid.New(),
+		UserID:          userID,
+		FromCurrency:    models.CurrencyUSD,
+		ToCurrency:      models.CurrencyEUR,
+		Amount:          10000,
+		ExchangedAmount: 9100,
+		Rate:            decimal.RequireFromString("0.91"),
+		ExpiresAt:       time.Now().Add(20 * time.Second),
+	}
+	req := models.ExchangeRequest{QuoteID: &quote.ID, RequestID: "exchange-quote-001"}
+
+	quotes.On("GetByID", ctx, quote.ID).Return(quote, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	quotes.On("UseTx", ctx, mock.Anything, quote.ID, userID, mock.AnythingOfType("time.Time")).Return(nil)
+	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
+	walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyEUR).Return(toWallet, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 50000}, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{Balance: 0}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, mock.Anything).Return(uuid.New(), nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, mock.MatchedBy(func(e models.JournalEntry) bool {
+		return e.Validate() == nil && len(e.Postings) == 4
+	})).Return(nil)
+	walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, mock.MatchedBy(func(op models.ExchangeOperation) bool {
+		return op.Amount == 10000 && op.ExchangedAmount == 9100 &&
+			op.Rate.Equal(quote.Rate) && op.QuoteID != nil && *op.QuoteID == quote.ID
+	})).Return(nil)
+
+	resp, err := service.ExchangeCurrency(ctx, userID, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "91", resp.ExchangedAmount.String())
+	assert.Equal(t, "0.91", resp.Rate.String())
+	quotes.AssertExpectations(t)
+	walletRepo.AssertExpectations(t)
+	grpcClient.AssertNotCalled(t, "GetExchangeRateForCurrency", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExchangeService_ExchangeCurrency_WithQuote_Rejected(t *testing.T) {
+	userID := uuid.New()
+	validQuote := func() *models.ExchangeQuote {
+		return &models.ExchangeQuote{
+			ID:              uuid.New(),
+			UserID:          userID,
+			FromCurrency:    models.CurrencyUSD,
+			ToCurrency:      models.CurrencyEUR,
+			Amount:          10000,
+			ExchangedAmount: 9100,
+			Rate:            decimal.RequireFromString("0.91"),
+			ExpiresAt:       time.Now().Add(20 * time.Second),
+		}
+	}
+	usedAt := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name    string
+		quote   func() *models.ExchangeQuote
+		modify  func(req *models.ExchangeRequest)
+		wantErr error
+	}{
+		{
+			name:    "expired",
+			quote:   func() *models.ExchangeQuote { q := validQuote(); q.ExpiresAt = time.Now().Add(-time.Second); return q },
+			wantErr: custom_err.ErrQuoteExpired,
+		},
+		{
+			name:    "already used",
+			quote:   func() *models.ExchangeQuote { q := validQuote(); q.UsedAt = &usedAt; return q },
+			wantErr: custom_err.ErrQuoteExpired,
+		},
+		{
+			name:    "other user's quote",
+			quote:   func() *models.ExchangeQuote { q := validQuote(); q.UserID = uuid.New(); return q },
+			wantErr: custom_err.ErrQuoteNotFound,
+		},
+		{
+			name:    "amount differs from quote",
+			quote:   validQuote,
+			modify:  func(req *models.ExchangeRequest) { req.Amount = decimal.RequireFromString("150") },
+			wantErr: custom_err.ErrInvalidInput,
+		},
+		{
+			name:    "currency differs from quote",
+			quote:   validQuote,
+			modify:  func(req *models.ExchangeRequest) { req.ToCurrency = models.CurrencyRUB },
+			wantErr: custom_err.ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, quotes, _, _, txManager, _ := setupQuotedExchangeService(t)
+			ctx := context.Background()
+
+			quote := tt.quote()
+			req := models.ExchangeRequest{QuoteID: &quote.ID, RequestID: "exchange-quote-002"}
+			if tt.modify != nil {
+				tt.modify(&req)
+			}
+			quotes.On("GetByID", ctx, quote.ID).Return(quote, nil)
+
+			resp, err := service.ExchangeCurrency(ctx, userID, req)
+
+			assert.Nil(t, resp)
+			assert.ErrorIs(t, err, tt.wantErr)
+			txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestExchangeService_ExchangeCurrency_WithQuote_NotFound(t *testing.T) {
+	service, quotes, _, _, _, _ := setupQuotedExchangeService(t)
+	ctx := context.Background()
+	quoteID := uuid.New()
+
+	quotes.On("GetByID", ctx, quoteID).Return(nil, custom_err.ErrNotFound)
+
+	_, err := service.ExchangeCurrency(ctx, uuid.New(), models.ExchangeRequest{QuoteID: &quoteID, RequestID: "exchange-quote-003"})
+
+	assert.ErrorIs(t, err, custom_err.ErrQuoteNotFound)
+}
+
+func TestExchangeService_ExchangeCurrency_WithQuote_UsedConcurrently(t *testing.T) {
+	service, quotes, walletRepo, _, txManager, _ := setupQuotedExchangeService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	quote := &models.ExchangeQuote{
+		ID:              uuid.New(),
+		UserID:          userID,
+		FromCurrency:    models.CurrencyUSD,
+		ToCurrency:      models.CurrencyEUR,
+		Amount:          10000,
+		ExchangedAmount: 9100,
+		Rate:            decimal.RequireFromString("0.91"),
+		ExpiresAt:       time.Now().Add(20 * time.Second),
+	}
+	req := models.ExchangeRequest{QuoteID: &quote.ID, RequestID: "exchange-quote-004"}
+
+	// Котировку успел использовать параллельный запрос с другим requestID
+	quotes.On("GetByID", ctx, quote.ID).Return(quote, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	quotes.On("UseTx", ctx, mock.Anything, quote.ID, userID, mock.AnythingOfType("time.Time")).Return(custom_err.ErrQuoteExpired)
+
+	resp, err := service.ExchangeCurrency(ctx, userID, req)
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrQuoteExpired)
+	walletRepo.AssertNotCalled(t, "CreateExchangeOperationTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExchangeService_ExchangeCurrency_WithFeeAndSpread(t *testing.T) {
+	service, walletRepo, ledger, txManager, grpcClient, _ := setupExchangeService(t)
+	service.rounding = models.RoundHalfUp
+	service.fees = staticFeeSchedule{
+		{"USD", "EUR"}: {
+			FromCurrency: "USD",
+			ToCurrency:   "EUR",
+			SpreadBps:    50,
+			Tiers: []models.FeeTier{
+				{MinAmount: 0, Percent: decimal.RequireFromString("0.01"), Fixed: 50},
+				{MinAmount: 100000, Percent: decimal.RequireFromString("0.005")},
+			},
+		},
+	}
+	ctx := context.Background()
+	userID := uuid.New()
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "EUR"}
+	fxUSD, fxEUR, feeUSD := uuid.New(), uuid.New(), uuid.New()
+
+	req := models.ExchangeRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("100.00"),
+		RequestID:    "exchange-fee-001",
+	}
+
+	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("0.92"),
+	}, nil)
+	walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
+	walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyEUR).Return(toWallet, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 20000}, nil)
+	walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{Balance: 0}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, "USD").Return(fxUSD, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, "EUR").Return(fxEUR, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFeeIncome, "USD").Return(feeUSD, nil)
+	// Комиссия 1% + 0.50 = 1.50 USD, конвертируется 98.50 по 0.92 * (1 - 0.005) = 0.9154 -> 90.17 EUR
+	ledger.On("PostEntryTx", ctx, mock.Anything, models.JournalEntry{
+		EntryType: models.OperationExchange,
+		RequestID: req.RequestID,
+		Postings: []models.Posting{
+			{AccountID: fromWallet.ID, Currency: "USD", Amount: -10000},
+			{AccountID: fxUSD, Currency: "USD", Amount: 9850},
+			{AccountID: fxEUR, Currency: "EUR", Amount: -9017},
+			{AccountID: toWallet.ID, Currency: "EUR", Amount: 9017},
+			{AccountID: feeUSD, Currency: "USD", Amount: 150},
+		},
+	}).Return(nil)
+	walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, mock.MatchedBy(func(op models.ExchangeOperation) bool {
+		return op.Amount == 10000 && op.Fee == 150 && op.ExchangedAmount == 9017 &&
+			op.Rate.Equal(decimal.RequireFromString("0.9154")) && op.FromBalanceAfter == 10000
+	})).Return(nil)
+
+	resp, err := service.ExchangeCurrency(ctx, userID, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "90.17", resp.ExchangedAmount.String())
+	assert.Equal(t, "0.9154", resp.Rate.String())
+	assert.Equal(t, "1.5", resp.Fee.String())
+	assert.Equal(t, "USD", resp.FeeCurrency)
+	ledger.AssertExpectations(t)
+	walletRepo.AssertExpectations(t)
+}
+
+func TestExchangeService_ExchangeCurrency_AmountBelowFee(t *testing.T) {
+	service, _, _, txManager, grpcClient, _ := setupExchangeService(t)
+	service.fees = staticFeeSchedule{
+		{"USD", "*"}: {FromCurrency: "USD", ToCurrency: "*", Tiers: []models.FeeTier{{Fixed: 100}}},
+	}
+	ctx := context.Background()
+
+	grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("0.92"),
+	}, nil)
+
+	resp, err := service.ExchangeCurrency(ctx, uuid.New(), models.ExchangeRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("1.00"),
+		RequestID:    "exchange-fee-002",
+	})
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrInvalidAmount)
+	txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// FeeSchedule условия обмена по парам валют
+type FeeSchedule interface {
+	// Pricing возвращает наиболее точное правило для пары: (from, to), (from, *), (*, to), (*, *).
+	// Если правил нет, спред и комиссия нулевые.
+	Pricing(ctx context.Context, from, to models.Currency) (models.ExchangePricing, error)
+}
+
+// CachedFeeSchedule правила из базы с локальным кэшем
+type CachedFeeSchedule struct {
+	repo            postgres.FeeRepository
+	cacheExpiration time.Duration
+	log             *slog.Logger
+
+	mu        sync.RWMutex
+	rules     map[[2]string]models.ExchangePricing
+	fetchedAt time.Time
+}
+
+func NewFeeSchedule(repo postgres.FeeRepository, cacheExpiration time.Duration, log *slog.Logger) FeeSchedule {
+	return &CachedFeeSchedule{
+		repo:            repo,
+		cacheExpiration: cacheExpiration,
+		log:             log,
+	}
+}
+
+func (f *CachedFeeSchedule) Pricing(ctx context.Context, from, to models.Currency) (models.ExchangePricing, error) {
+	if err := f.refresh(ctx); err != nil {
+		return models.ExchangePricing{}, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return matchPricing(f.rules, string(from), string(to)), nil
+}
+
+// refresh обновляет кэш по истечении срока. Если база недоступна, используются последние загруженные правила.
+func (f *CachedFeeSchedule) refresh(ctx context.Context) error {
+	const op = "service.FeeSchedule.refresh"
+
+	f.mu.RLock()
+	fresh := f.rules != nil && time.Since(f.fetchedAt) < f.cacheExpiration
+	loaded := f.rules != nil
+	f.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	pricing, err := f.repo.ListPricing(ctx)
+	if err != nil {
+		if loaded {
+			f.log.Warn("не удалось обновить правила комиссий, используется кэш",
+				slog.String("op", op),
+				slog.String("error", err.Error()))
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rules := make(map[[2]string]models.ExchangePricing, len(pricing))
+	for _, p := range pricing {
+		rules[[2]string{p.FromCurrency, p.ToCurrency}] = p
+	}
+
+	f.mu.Lock()
+	f.rules = rules
+	f.fetchedAt = time.Now()
+	f.mu.Unlock()
+
+	return nil
+}
+
+func matchPricing(rules map[[2]string]models.ExchangePricing, from, to string) models.ExchangePricing {
+	candidates := [][2]string{
+		{from, to},
+		{from, models.PricingAnyCurrency},
+		{models.PricingAnyCurrency, to},
+		{models.PricingAnyCurrency, models.PricingAnyCurrency},
+	}
+	for _, key := range candidates {
+		if p, ok := rules[key]; ok {
+			return p
+		}
+	}
+	return models.ExchangePricing{FromCurrency: from, ToCurrency: to}
+}
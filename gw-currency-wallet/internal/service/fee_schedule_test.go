@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/models"
+)
+
+func TestFeeSchedule_Pricing_MostSpecificRule(t *testing.T) {
+	repo := new(MockFeeRepository)
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	schedule := NewFeeSchedule(repo, time.Minute, log)
+	ctx := context.Background()
+
+	repo.On("ListPricing", ctx).Return([]models.ExchangePricing{
+		{FromCurrency: "*", ToCurrency: "*", SpreadBps: 10},
+		{FromCurrency: "*", ToCurrency: "RUB", SpreadBps: 20},
+		{FromCurrency: "USD", ToCurrency: "*", SpreadBps: 30},
+		{FromCurrency: "USD", ToCurrency: "EUR", SpreadBps: 40},
+	}, nil).Once()
+
+	tests := []struct {
+		from, to models.Currency
+		want     int32
+	}{
+		{models.CurrencyUSD, models.CurrencyEUR, 40},
+		{models.CurrencyUSD, models.CurrencyRUB, 30},
+		{models.CurrencyEUR, models.CurrencyRUB, 20},
+		{models.CurrencyEUR, models.CurrencyUSD, 10},
+	}
+	for _, tt := range tests {
+		pricing, err := schedule.Pricing(ctx, tt.from, tt.to)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, pricing.SpreadBps, "%s -> %s", tt.from, tt.to)
+	}
+	repo.AssertExpectations(t)
+}
+
+func TestFeeSchedule_Pricing_NoRules(t *testing.T) {
+	repo := new(MockFeeRepository)
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	schedule := NewFeeSchedule(repo, time.Minute, log)
+	ctx := context.Background()
+
+	repo.On("ListPricing", ctx).Return([]models.ExchangePricing{}, nil)
+
+	pricing, err := schedule.Pricing(ctx, models.CurrencyUSD, models.CurrencyEUR)
+
+	require.NoError(t, err)
+	assert.Zero(t, pricing.SpreadBps)
+	assert.Empty(t, pricing.Tiers)
+}
+
+func TestFeeSchedule_Pricing_KeepsCacheWhenRefreshFails(t *testing.T) {
+	repo := new(MockFeeRepository)
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	schedule := NewFeeSchedule(repo, time.Millisecond, log)
+	ctx := context.Background()
+
+	repo.On("ListPricing", ctx).Return([]models.ExchangePricing{{FromCurrency: "*", ToCurrency: "*", SpreadBps: 25}}, nil).Once()
+	repo.On("ListPricing", ctx).Return(nil, errors.New("db down"))
+
+	_, err := schedule.Pricing(ctx, models.CurrencyUSD, models.CurrencyEUR)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	pricing, err := schedule.Pricing(ctx, models.CurrencyUSD, models.CurrencyEUR)
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(25), pricing.SpreadBps)
+}
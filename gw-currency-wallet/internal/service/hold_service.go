@@ -0,0 +1,400 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	holdExpiryInterval  = 30 * time.Second
+	holdExpiryBatchSize = 100
+)
+
+// Holds резервирование средств кошелька до окончательного списания
+type Holds interface {
+	CreateHold(ctx context.Context, userID uuid.UUID, req models.CreateHoldRequest) (*models.HoldResponse, error)
+	GetHold(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.HoldResponse, error)
+	// CaptureHold списывает весь холд или его часть; остаток освобождается
+	CaptureHold(ctx context.Context, actor *models.JWTClaims, id uuid.UUID, req models.CaptureHoldRequest) (*models.HoldResponse, error)
+	ReleaseHold(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.HoldResponse, error)
+}
+
+type HoldService struct {
+	holds      postgres.HoldRepository
+	walletRepo postgres.WalletRepository
+	ledger     postgres.LedgerRepository
+	limits     Limits
+	txManager  TxManager
+	currencies CurrencyRegistry
+	policy     Policy
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+	log        *slog.Logger
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func NewHoldService(
+	holds postgres.HoldRepository,
+	walletRepo postgres.WalletRepository,
+	ledger postgres.LedgerRepository,
+	limits Limits,
+	txManager TxManager,
+	currencies CurrencyRegistry,
+	policy Policy,
+	defaultTTL time.Duration,
+	maxTTL time.Duration,
+	log *slog.Logger,
+) *HoldService {
+	return &HoldService{
+		holds:      holds,
+		walletRepo: walletRepo,
+		ledger:     ledger,
+		limits:     limits,
+		txManager:  txManager,
+		currencies: currencies,
+		policy:     policy,
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+		log:        log,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (s *HoldService) CreateHold(ctx context.Context, userID uuid.UUID, req models.CreateHoldRequest) (*models.HoldResponse, error) {
+	const op = "service.CreateHold"
+
+	currency, err := requireEnabledCurrency(ctx, s.currencies, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := toMinorUnits(req.Amount, currency)
+	if err != nil {
+		return nil, err
+	}
+	if req.RequestID == "" {
+		return nil, fmt.Errorf("%w: requestID is required", custom_err.ErrInvalidInput)
+	}
+	description := strings.TrimSpace(req.Description)
+	if len([]rune(description)) > models.MaxHoldDescriptionLength {
+		return nil, fmt.Errorf("%w: description must be at most %d characters", custom_err.ErrInvalidInput, models.MaxHoldDescriptionLength)
+	}
+	ttl := s.defaultTTL
+	if req.ExpiresIn != 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+		if req.ExpiresIn < 0 || ttl > s.maxTTL {
+			return nil, fmt.Errorf("%w: expires_in must be between 1 and %d seconds", custom_err.ErrInvalidInput, int64(s.maxTTL.Seconds()))
+		}
+	}
+
+	wallet, err := s.walletRepo.GetByUserAndCurrency(ctx, userID, req.Currency)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to get wallet: %w", op, err)
+	}
+
+	var hold *models.Hold
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		exists, err := s.holds.HoldExistsTx(ctx, tx, req.RequestID)
+		if err != nil {
+			return fmt.Errorf("failed to check hold: %w", err)
+		}
+		if exists {
+			return custom_err.ErrDuplicateRequest
+		}
+
+		balance, err := s.walletRepo.GetWalletBalanceForUpdateTx(ctx, tx, wallet.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet: %w", err)
+		}
+		if balance.Available() < amount {
+			return custom_err.ErrInsufficientFunds
+		}
+
+		hold, err = s.holds.CreateTx(ctx, tx, models.Hold{
+			ID:          uuid.New(),
+			WalletID:    wallet.ID,
+			UserID:      userID,
+			Currency:    string(currency.Code),
+			Amount:      amount,
+			Description: description,
+			RequestID:   req.RequestID,
+			ExpiresAt:   time.Now().Add(ttl),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("средства зарезервированы",
+		slog.String("op", op),
+		slog.String("hold_id", hold.ID.String()),
+		slog.String("user_id", userID.String()),
+		slog.String("currency", hold.Currency),
+		slog.Int64("amount", hold.Amount))
+
+	return s.toResponse(ctx, hold)
+}
+
+func (s *HoldService) GetHold(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.HoldResponse, error) {
+	hold, err := s.getAuthorized(ctx, actor, id, ActionRead)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(ctx, hold)
+}
+
+func (s *HoldService) CaptureHold(ctx context.Context, actor *models.JWTClaims, id uuid.UUID, req models.CaptureHoldRequest) (*models.HoldResponse, error) {
+	const op = "service.CaptureHold"
+
+	hold, err := s.getAuthorized(ctx, actor, id, ActionWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	// Списание по уже зарезервированным средствам допускается и по отключенной валюте
+	currency, err := s.currencies.Get(ctx, models.Currency(hold.Currency))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	amount := hold.Amount
+	if req.Amount != nil {
+		amount, err = toMinorUnits(*req.Amount, currency)
+		if err != nil {
+			return nil, err
+		}
+		if amount > hold.Amount {
+			return nil, fmt.Errorf("%w: capture amount exceeds the held amount", custom_err.ErrInvalidInput)
+		}
+	}
+
+	var captured *models.Hold
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		balance, err := s.walletRepo.GetWalletBalanceForUpdateTx(ctx, tx, hold.WalletID)
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet: %w", err)
+		}
+
+		// Списание по холду выводит средства из кошелька и учитывается в лимитах вывода
+		if err := s.limits.CheckTx(ctx, tx, hold.UserID, models.OperationWithdraw, models.Currency(hold.Currency), amount); err != nil {
+			return err
+		}
+
+		// Резерв снимается до списания: баланс кошелька не может стать меньше суммы холдов
+		captured, err = s.holds.CloseTx(ctx, tx, hold.ID, models.HoldStatusCaptured, amount, time.Now())
+		if err != nil {
+			return err
+		}
+
+		settlementAccountID, err := s.ledger.GetSystemAccountIDTx(ctx, tx, models.SystemAccountHoldSettlement, hold.Currency)
+		if err != nil {
+			return fmt.Errorf("failed to get settlement account: %w", err)
+		}
+
+		err = s.ledger.PostEntryTx(ctx, tx, models.JournalEntry{
+			EntryType: models.OperationCapture,
+			RequestID: hold.CaptureRequestID(),
+			Postings: []models.Posting{
+				{AccountID: hold.WalletID, Currency: hold.Currency, Amount: -amount},
+				{AccountID: settlementAccountID, Currency: hold.Currency, Amount: amount},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to post ledger entry: %w", err)
+		}
+
+		err = s.walletRepo.CreateOperationTx(ctx, tx, models.Operation{
+			WalletID:      hold.WalletID,
+			OperationType: models.OperationCapture,
+			Amount:        -amount,
+			BalanceAfter:  balance.Balance - amount,
+			RequestID:     hold.CaptureRequestID(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create operation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("списание по холду",
+		slog.String("op", op),
+		slog.String("hold_id", hold.ID.String()),
+		slog.String("actor_id", actor.UserID.String()),
+		slog.Int64("amount", amount),
+		slog.Int64("released", hold.Amount-amount))
+
+	return s.toResponse(ctx, captured)
+}
+
+func (s *HoldService) ReleaseHold(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.HoldResponse, error) {
+	const op = "service.ReleaseHold"
+
+	hold, err := s.getAuthorized(ctx, actor, id, ActionWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := s.close(ctx, hold, models.HoldStatusReleased, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("холд освобожден",
+		slog.String("op", op),
+		slog.String("hold_id", hold.ID.String()),
+		slog.String("actor_id", actor.UserID.String()))
+
+	return s.toResponse(ctx, released)
+}
+
+// ExpireHolds освобождает холды, срок которых истек, и возвращает их количество
+func (s *HoldService) ExpireHolds(ctx context.Context) (int, error) {
+	const op = "service.ExpireHolds"
+
+	now := time.Now()
+	holds, err := s.holds.ListExpired(ctx, now, holdExpiryBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	expired := 0
+	for i := range holds {
+		if _, err := s.close(ctx, &holds[i], models.HoldStatusExpired, now); err != nil {
+			// Холд успели списать или освободить
+			if errors.Is(err, custom_err.ErrHoldNotActive) {
+				continue
+			}
+			return expired, fmt.Errorf("%s: %w", op, err)
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// close освобождает холд без списания. Кошелек блокируется независимо от заморозки:
+// освобождение резерва не меняет баланс.
+func (s *HoldService) close(ctx context.Context, hold *models.Hold, status models.HoldStatus, now time.Time) (*models.Hold, error) {
+	var closed *models.Hold
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := s.walletRepo.GetWalletForUpdateTx(ctx, tx, hold.WalletID); err != nil {
+			return fmt.Errorf("failed to lock wallet: %w", err)
+		}
+
+		var err error
+		closed, err = s.holds.CloseTx(ctx, tx, hold.ID, status, 0, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return closed, nil
+}
+
+func (s *HoldService) getAuthorized(ctx context.Context, actor *models.JWTClaims, id uuid.UUID, action Action) (*models.Hold, error) {
+	hold, err := s.holds.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("service.GetHold: %w", err)
+	}
+	if err := s.policy.AuthorizeHold(actor, hold, action); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (s *HoldService) toResponse(ctx context.Context, hold *models.Hold) (*models.HoldResponse, error) {
+	currency, err := s.currencies.Get(ctx, models.Currency(hold.Currency))
+	if err != nil {
+		return nil, fmt.Errorf("service.HoldResponse: %w", err)
+	}
+
+	resp := &models.HoldResponse{
+		ID:          hold.ID,
+		WalletID:    hold.WalletID,
+		Currency:    hold.Currency,
+		Amount:      models.AmountFromMinorUnits(hold.Amount, currency.Exponent),
+		Status:      hold.Status,
+		Description: hold.Description,
+		RequestID:   hold.RequestID,
+		ExpiresAt:   hold.ExpiresAt,
+		CreatedAt:   hold.CreatedAt,
+		UpdatedAt:   hold.UpdatedAt,
+	}
+	// Истекший холд освобождается фоновой задачей с задержкой, но для клиента он уже истек
+	if hold.Status == models.HoldStatusActive && !hold.ActiveAt(time.Now()) {
+		resp.Status = models.HoldStatusExpired
+	}
+	if hold.Status == models.HoldStatusCaptured {
+		captured := models.AmountFromMinorUnits(hold.CapturedAmount, currency.Exponent)
+		resp.CapturedAmount = &captured
+	}
+	return resp, nil
+}
+
+// Start запускает фоновое освобождение истекших холдов
+func (s *HoldService) Start() {
+	s.wg.Add(1)
+	go s.expiryLoop()
+}
+
+func (s *HoldService) expiryLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(holdExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			expired, err := s.ExpireHolds(context.Background())
+			if err != nil {
+				s.log.Error("ошибка освобождения истекших холдов", slog.String("error", err.Error()))
+			}
+			if expired > 0 {
+				s.log.Info("освобождены истекшие холды", slog.Int("count", expired))
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *HoldService) Shutdown(ctx context.Context) error {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+type holdMocks struct {
+	holds      *MockHoldRepository
+	walletRepo *MockWalletRepo
+	ledger     *MockLedgerRepo
+	txManager  *MockTxManager
+}
+
+func setupHoldService() (*HoldService, holdMocks) {
+	m := holdMocks{
+		holds:      new(MockHoldRepository),
+		walletRepo: new(MockWalletRepo),
+		ledger:     new(MockLedgerRepo),
+		txManager:  new(MockTxManager),
+	}
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	service := NewHoldService(m.holds, m.walletRepo, m.ledger, stubLimits{}, m.txManager, newTestCurrencyRegistry(), NewPolicy(),
+		time.Hour, 24*time.Hour, log)
+
+	return service, m
+}
+
+func newActiveHold(userID uuid.UUID, amount int64) *models.Hold {
+	return &models.Hold{
+		ID:        uuid.New(),
+		WalletID:  uuid.New(),
+		UserID:    userID,
+		Currency:  "USD",
+		Amount:    amount,
+		Status:    models.HoldStatusActive,
+		RequestID: "hold-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+}
+
+func TestHoldService_CreateHold_Success(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	wallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	req := models.CreateHoldRequest{
+		Amount:      decimal.RequireFromString("40"),
+		Currency:    models.CurrencyUSD,
+		Description: "  hotel booking  ",
+		ExpiresIn:   600,
+		RequestID:   "hold-1",
+	}
+
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(wallet, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.holds.On("HoldExistsTx", ctx, mock.Anything, "hold-1").Return(false, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, wallet.ID).
+		Return(models.WalletBalance{Balance: 10000, Held: 5000}, nil)
+	m.holds.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(h models.Hold) bool {
+		ttl := time.Until(h.ExpiresAt)
+		return h.WalletID == wallet.ID && h.UserID == userID && h.Amount == 4000 &&
+			h.Description == "hotel booking" && ttl > 9*time.Minute && ttl <= 10*time.Minute
+	})).Return(newActiveHold(userID, 4000), nil)
+
+	resp, err := service.CreateHold(ctx, userID, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusActive, resp.Status)
+	assert.Equal(t, "40", resp.Amount.String())
+	assert.Nil(t, resp.CapturedAmount)
+
+	m.holds.AssertExpectations(t)
+	m.walletRepo.AssertExpectations(t)
+}
+
+func TestHoldService_CreateHold_InsufficientAvailable(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	wallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	req := models.CreateHoldRequest{
+		Amount:    decimal.RequireFromString("60"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "hold-1",
+	}
+
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(wallet, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.holds.On("HoldExistsTx", ctx, mock.Anything, "hold-1").Return(false, nil)
+	// Баланс 100, из них 50 уже зарезервировано
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, wallet.ID).
+		Return(models.WalletBalance{Balance: 10000, Held: 5000}, nil)
+
+	_, err := service.CreateHold(ctx, userID, req)
+
+	assert.ErrorIs(t, err, custom_err.ErrInsufficientFunds)
+	m.holds.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHoldService_CreateHold_Duplicate(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	wallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	req := models.CreateHoldRequest{
+		Amount:    decimal.RequireFromString("10"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "hold-1",
+	}
+
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(wallet, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.holds.On("HoldExistsTx", ctx, mock.Anything, "hold-1").Return(true, nil)
+
+	_, err := service.CreateHold(ctx, userID, req)
+
+	assert.ErrorIs(t, err, custom_err.ErrDuplicateRequest)
+	m.walletRepo.AssertNotCalled(t, "GetWalletBalanceForUpdateTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHoldService_CreateHold_InvalidInput(t *testing.T) {
+	service, _ := setupHoldService()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     models.CreateHoldRequest
+		wantErr error
+	}{
+		{"missing request id", models.CreateHoldRequest{Amount: decimal.NewFromInt(1), Currency: models.CurrencyUSD}, custom_err.ErrInvalidInput},
+		{"zero amount", models.CreateHoldRequest{Amount: decimal.Zero, Currency: models.CurrencyUSD, RequestID: "h"}, custom_err.ErrInvalidAmount},
+		{"ttl above max", models.CreateHoldRequest{Amount: decimal.NewFromInt(1), Currency: models.CurrencyUSD, RequestID: "h", ExpiresIn: 25 * 3600}, custom_err.ErrInvalidInput},
+		{"negative ttl", models.CreateHoldRequest{Amount: decimal.NewFromInt(1), Currency: models.CurrencyUSD, RequestID: "h", ExpiresIn: -1}, custom_err.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateHold(ctx, uuid.New(), tt.req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestHoldService_CaptureHold_Partial(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	hold := newActiveHold(userID, 4000)
+	amount := decimal.RequireFromString("25")
+
+	m.holds.On("GetByID", ctx, hold.ID).Return(hold, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, hold.WalletID).
+		Return(models.WalletBalance{Balance: 10000, Held: 4000}, nil)
+	captured := *hold
+	captured.Status = models.HoldStatusCaptured
+	captured.CapturedAmount = 2500
+	m.holds.On("CloseTx", ctx, mock.Anything, hold.ID, models.HoldStatusCaptured, int64(2500), mock.AnythingOfType("time.Time")).
+		Return(&captured, nil)
+	settlementID := uuid.New()
+	m.ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountHoldSettlement, "USD").Return(settlementID, nil)
+	m.ledger.On("PostEntryTx", ctx, mock.Anything, mock.MatchedBy(func(e models.JournalEntry) bool {
+		return e.EntryType == models.OperationCapture && e.RequestID == hold.CaptureRequestID() &&
+			e.Validate() == nil && e.Postings[0].Amount == -2500 && e.Postings[1].AccountID == settlementID
+	})).Return(nil)
+	m.walletRepo.On("CreateOperationTx", ctx, mock.Anything, mock.MatchedBy(func(op models.Operation) bool {
+		return op.OperationType == models.OperationCapture && op.Amount == -2500 && op.BalanceAfter == 7500
+	})).Return(nil)
+
+	resp, err := service.CaptureHold(ctx, &models.JWTClaims{UserID: userID, Role: models.RoleUser}, hold.ID,
+		models.CaptureHoldRequest{Amount: &amount})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusCaptured, resp.Status)
+	require.NotNil(t, resp.CapturedAmount)
+	assert.Equal(t, "25", resp.CapturedAmount.String())
+
+	m.holds.AssertExpectations(t)
+	m.ledger.AssertExpectations(t)
+	m.walletRepo.AssertExpectations(t)
+}
+
+func TestHoldService_CaptureHold_ExceedsHold(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	hold := newActiveHold(userID, 4000)
+	amount := decimal.RequireFromString("40.01")
+
+	m.holds.On("GetByID", ctx, hold.ID).Return(hold, nil)
+
+	_, err := service.CaptureHold(ctx, &models.JWTClaims{UserID: userID, Role: models.RoleUser}, hold.ID,
+		models.CaptureHoldRequest{Amount: &amount})
+
+	assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestHoldService_CaptureHold_LimitExceeded(t *testing.T) {
+	service, m := setupHoldService()
+	limitErr := &models.LimitExceededError{
+		OperationType: models.OperationWithdraw,
+		Currency:      "USD",
+		Period:        models.LimitPeriodDaily,
+		Limit:         decimal.RequireFromString("1000"),
+		Remaining:     decimal.RequireFromString("10"),
+	}
+	service.limits = stubLimits{err: limitErr}
+	ctx := context.Background()
+
+	userID := uuid.New()
+	hold := newActiveHold(userID, 4000)
+
+	m.holds.On("GetByID", ctx, hold.ID).Return(hold, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, hold.WalletID).
+		Return(models.WalletBalance{Balance: 10000, Held: 4000}, nil)
+
+	_, err := service.CaptureHold(ctx, &models.JWTClaims{UserID: userID, Role: models.RoleUser}, hold.ID, models.CaptureHoldRequest{})
+
+	assert.ErrorIs(t, err, custom_err.ErrLimitExceeded)
+	assert.ErrorAs(t, err, &limitErr)
+	m.holds.AssertNotCalled(t, "CloseTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHoldService_CaptureHold_NotActive(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	hold := newActiveHold(userID, 4000)
+
+	m.holds.On("GetByID", ctx, hold.ID).Return(hold, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, hold.WalletID).
+		Return(models.WalletBalance{Balance: 10000}, nil)
+	m.holds.On("CloseTx", ctx, mock.Anything, hold.ID, models.HoldStatusCaptured, int64(4000), mock.AnythingOfType("time.Time")).
+		Return(nil, custom_err.ErrHoldNotActive)
+
+	_, err := service.CaptureHold(ctx, &models.JWTClaims{UserID: userID, Role: models.RoleUser}, hold.ID,
+		models.CaptureHoldRequest{})
+
+	assert.ErrorIs(t, err, custom_err.ErrHoldNotActive)
+	m.ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHoldService_CaptureHold_OtherUser(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	hold := newActiveHold(uuid.New(), 4000)
+	m.holds.On("GetByID", ctx, hold.ID).Return(hold, nil)
+
+	_, err := service.CaptureHold(ctx, &models.JWTClaims{UserID: uuid.New(), Role: models.RoleUser}, hold.ID,
+		models.CaptureHoldRequest{})
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+
+	// Поддержка видит холд, но не может им распоряжаться
+	support := &models.JWTClaims{UserID: uuid.New(), Role: models.RoleSupport}
+	_, err = service.GetHold(ctx, support, hold.ID)
+	assert.NoError(t, err)
+	_, err = service.ReleaseHold(ctx, support, hold.ID)
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestHoldService_ReleaseHold(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	hold := newActiveHold(userID, 4000)
+	released := *hold
+	released.Status = models.HoldStatusReleased
+
+	m.holds.On("GetByID", ctx, hold.ID).Return(hold, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletForUpdateTx", ctx, mock.Anything, hold.WalletID).
+		Return(&models.Wallet{ID: hold.WalletID, FrozenAt: &hold.CreatedAt}, nil)
+	m.holds.On("CloseTx", ctx, mock.Anything, hold.ID, models.HoldStatusReleased, int64(0), mock.AnythingOfType("time.Time")).
+		Return(&released, nil)
+
+	resp, err := service.ReleaseHold(ctx, &models.JWTClaims{UserID: userID, Role: models.RoleUser}, hold.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusReleased, resp.Status)
+	m.ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+	m.holds.AssertExpectations(t)
+}
+
+func TestHoldService_GetHold_ReportsExpired(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	userID := uuid.New()
+	hold := newActiveHold(userID, 4000)
+	hold.ExpiresAt = time.Now().Add(-time.Second)
+	m.holds.On("GetByID", ctx, hold.ID).Return(hold, nil)
+
+	resp, err := service.GetHold(ctx, &models.JWTClaims{UserID: userID, Role: models.RoleUser}, hold.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusExpired, resp.Status)
+}
+
+func TestHoldService_ExpireHolds_SkipsClosed(t *testing.T) {
+	service, m := setupHoldService()
+	ctx := context.Background()
+
+	first := newActiveHold(uuid.New(), 1000)
+	second := newActiveHold(uuid.New(), 2000)
+
+	m.holds.On("ListExpired", ctx, mock.AnythingOfType("time.Time"), holdExpiryBatchSize).
+		Return([]models.Hold{*first, *second}, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("GetWalletForUpdateTx", ctx, mock.Anything, mock.Anything).Return(&models.Wallet{}, nil)
+	// Первый холд успели освободить вручную
+	m.holds.On("CloseTx", ctx, mock.Anything, first.ID, models.HoldStatusExpired, int64(0), mock.AnythingOfType("time.Time")).
+		Return(nil, custom_err.ErrHoldNotActive)
+	m.holds.On("CloseTx", ctx, mock.Anything, second.ID, models.HoldStatusExpired, int64(0), mock.AnythingOfType("time.Time")).
+		Return(second, nil)
+
+	expired, err := service.ExpireHolds(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	m.holds.AssertExpectations(t)
+}
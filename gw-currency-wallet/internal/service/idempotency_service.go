@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// idempotencyLockTimeout после этого времени запрос без сохраненного ответа считается брошенным
+	// (экземпляр упал) и ключ можно занять снова. Больше WriteTimeout сервера, чтобы живой запрос
+	// не выполнился дважды.
+	idempotencyLockTimeout     = time.Minute
+	idempotencyCleanupInterval = time.Hour
+)
+
+// Idempotency хранит результаты запросов с заголовком Idempotency-Key
+type Idempotency interface {
+	// Begin занимает ключ за запросом с отпечатком fingerprint. Возвращает nil, если запрос
+	// нужно выполнить, или сохраненную запись, если он уже выполнен и ответ нужно повторить.
+	Begin(ctx context.Context, userID uuid.UUID, key, fingerprint string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, status int, contentType string, body []byte) error
+	// Release освобождает ключ запроса, завершившегося без сохраняемого ответа
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+}
+
+type IdempotencyService struct {
+	repo postgres.IdempotencyRepository
+	ttl  time.Duration
+	log  *slog.Logger
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func NewIdempotencyService(repo postgres.IdempotencyRepository, ttl time.Duration, log *slog.Logger) *IdempotencyService {
+	return &IdempotencyService{
+		repo:   repo,
+		ttl:    ttl,
+		log:    log,
+		stopCh: make(chan struct{}),
+	}
+}
+
+func (s *IdempotencyService) Begin(ctx context.Context, userID uuid.UUID, key, fingerprint string) (*models.IdempotencyRecord, error) {
+	const op = "service.IdempotencyService.Begin"
+
+	reserved, err := s.repo.Reserve(ctx, userID, key, fingerprint, s.ttl, idempotencyLockTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	rec, err := s.repo.Get(ctx, userID, key)
+	if err != nil {
+		// Запись освободили или она истекла между резервированием и чтением - клиент повторит запрос
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrIdempotencyKeyInProgress
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if rec.Fingerprint != fingerprint {
+		return nil, custom_err.ErrIdempotencyKeyReused
+	}
+	if !rec.Completed() {
+		return nil, custom_err.ErrIdempotencyKeyInProgress
+	}
+	return rec, nil
+}
+
+func (s *IdempotencyService) Complete(ctx context.Context, userID uuid.UUID, key string, status int, contentType string, body []byte) error {
+	const op = "service.IdempotencyService.Complete"
+
+	if err := s.repo.SaveResponse(ctx, userID, key, status, contentType, body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *IdempotencyService) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	const op = "service.IdempotencyService.Release"
+
+	if err := s.repo.Release(ctx, userID, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Start запускает фоновое удаление просроченных ключей
+func (s *IdempotencyService) Start() {
+	s.wg.Add(1)
+	go s.cleanupLoop()
+}
+
+func (s *IdempotencyService) cleanupLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(idempotencyCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := s.repo.DeleteExpired(context.Background())
+			if err != nil {
+				s.log.Error("ошибка удаления просроченных ключей идемпотентности", slog.String("error", err.Error()))
+				continue
+			}
+			if deleted > 0 {
+				s.log.Info("удалены просроченные ключи идемпотентности", slog.Int64("count", deleted))
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *IdempotencyService) Shutdown(ctx context.Context) error {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+func setupIdempotencyService() (*IdempotencyService, *MockIdempotencyRepository) {
+	repo := new(MockIdempotencyRepository)
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	return NewIdempotencyService(repo, 24*time.Hour, log), repo
+}
+
+func TestIdempotencyService_Begin_Reserved(t *testing.T) {
+	service, repo := setupIdempotencyService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("Reserve", ctx, userID, "key-1", "fp", 24*time.Hour, idempotencyLockTimeout).Return(true, nil)
+
+	saved, err := service.Begin(ctx, userID, "key-1", "fp")
+
+	require.NoError(t, err)
+	assert.Nil(t, saved)
+	repo.AssertNotCalled(t, "Get")
+}
+
+func TestIdempotencyService_Begin_Replay(t *testing.T) {
+	service, repo := setupIdempotencyService()
+	ctx := context.Background()
+	userID := uuid.New()
+	stored := &models.IdempotencyRecord{
+		UserID: userID, Key: "key-1", Fingerprint: "fp",
+		StatusCode: 200, ContentType: "application/json", Body: []byte(`{"message":"ok"}`),
+	}
+
+	repo.On("Reserve", ctx, userID, "key-1", "fp", 24*time.Hour, idempotencyLockTimeout).Return(false, nil)
+	repo.On("Get", ctx, userID, "key-1").Return(stored, nil)
+
+	saved, err := service.Begin(ctx, userID, "key-1", "fp")
+
+	require.NoError(t, err)
+	assert.Equal(t, stored, saved)
+}
+
+func TestIdempotencyService_Begin_Conflicts(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name    string
+		stored  *models.IdempotencyRecord
+		getErr  error
+		wantErr error
+	}{
+		{"different request", &models.IdempotencyRecord{Fingerprint: "other", StatusCode: 200}, nil, custom_err.ErrIdempotencyKeyReused},
+		{"different request in progress", &models.IdempotencyRecord{Fingerprint: "other"}, nil, custom_err.ErrIdempotencyKeyReused},
+		{"same request in progress", &models.IdempotencyRecord{Fingerprint: "fp"}, nil, custom_err.ErrIdempotencyKeyInProgress},
+		{"released concurrently", nil, custom_err.ErrNotFound, custom_err.ErrIdempotencyKeyInProgress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo := setupIdempotencyService()
+			ctx := context.Background()
+
+			repo.On("Reserve", ctx, userID, "key-1", "fp", 24*time.Hour, idempotencyLockTimeout).Return(false, nil)
+			repo.On("Get", ctx, userID, "key-1").Return(tt.stored, tt.getErr)
+
+			saved, err := service.Begin(ctx, userID, "key-1", "fp")
+
+			assert.Nil(t, saved)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestIdempotencyService_Begin_RepositoryError(t *testing.T) {
+	service, repo := setupIdempotencyService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("Reserve", ctx, userID, "key-1", "fp", 24*time.Hour, idempotencyLockTimeout).Return(false, errors.New("db down"))
+
+	_, err := service.Begin(ctx, userID, "key-1", "fp")
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, custom_err.ErrIdempotencyKeyInProgress)
+}
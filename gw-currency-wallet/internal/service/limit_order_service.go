@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+const limitOrderBatchSize = 100
+
+// LimitOrders лимитные ордера на обмен валют
+type LimitOrders interface {
+	// CreateOrder резервирует сумму ордера; обмен выполняется, когда курс для клиента достигнет req.Rate
+	CreateOrder(ctx context.Context, userID uuid.UUID, req models.CreateLimitOrderRequest) (*models.LimitOrderResponse, error)
+	ListOrders(ctx context.Context, userID uuid.UUID, req models.LimitOrderListRequest) (*models.LimitOrderListResponse, error)
+	CancelOrder(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.LimitOrderResponse, error)
+}
+
+type LimitOrderService struct {
+	orders        postgres.LimitOrderRepository
+	walletRepo    postgres.WalletRepository
+	exchange      *ExchangeService
+	txManager     TxManager
+	currencies    CurrencyRegistry
+	policy        Policy
+	matchInterval time.Duration
+	log           *slog.Logger
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func NewLimitOrderService(
+	orders postgres.LimitOrderRepository,
+	walletRepo postgres.WalletRepository,
+	exchange *ExchangeService,
+	txManager TxManager,
+	currencies CurrencyRegistry,
+	policy Policy,
+	matchInterval time.Duration,
+	log *slog.Logger,
+) *LimitOrderService {
+	return &LimitOrderService{
+		orders:        orders,
+		walletRepo:    walletRepo,
+		exchange:      exchange,
+		txManager:     txManager,
+		currencies:    currencies,
+		policy:        policy,
+		matchInterval: matchInterval,
+		log:           log,
+		stopCh:        make(chan struct{}),
+	}
+}
+
+func (s *LimitOrderService) CreateOrder(ctx context.Context, userID uuid.UUID, req models.CreateLimitOrderRequest) (*models.LimitOrderResponse, error) {
+	const op = "service.CreateLimitOrder"
+
+	fromCurrency, err := requireEnabledCurrency(ctx, s.currencies, req.FromCurrency)
+	if err != nil {
+		return nil, err
+	}
+	toCurrency, err := requireEnabledCurrency(ctx, s.currencies, req.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if fromCurrency.Code == toCurrency.Code {
+		return nil, fmt.Errorf("%w: cannot exchange same currency", custom_err.ErrInvalidCurrency)
+	}
+	amount, err := toMinorUnits(req.Amount, fromCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Rate.IsPositive() {
+		return nil, fmt.Errorf("%w: rate must be positive", custom_err.ErrInvalidInput)
+	}
+	if req.RequestID == "" {
+		return nil, fmt.Errorf("%w: requestID is required", custom_err.ErrInvalidInput)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", custom_err.ErrInvalidInput)
+	}
+
+	wallet, err := s.walletRepo.GetByUserAndCurrency(ctx, userID, fromCurrency.Code)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to get wallet: %w", op, err)
+	}
+
+	var order *models.LimitOrder
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		exists, err := s.orders.OrderExistsTx(ctx, tx, req.RequestID)
+		if err != nil {
+			return fmt.Errorf("failed to check limit order: %w", err)
+		}
+		if exists {
+			return custom_err.ErrDuplicateRequest
+		}
+
+		balance, err := s.walletRepo.GetWalletBalanceForUpdateTx(ctx, tx, wallet.ID)
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet: %w", err)
+		}
+		if balance.Available() < amount {
+			return custom_err.ErrInsufficientFunds
+		}
+
+		order, err = s.orders.CreateTx(ctx, tx, models.LimitOrder{
+			ID:           uuid.New(),
+			UserID:       userID,
+			WalletID:     wallet.ID,
+			FromCurrency: fromCurrency.Code,
+			ToCurrency:   toCurrency.Code,
+			Amount:       amount,
+			Rate:         req.Rate,
+			RequestID:    req.RequestID,
+			ExpiresAt:    req.ExpiresAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create limit order: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("создан лимитный ордер",
+		slog.String("op", op),
+		slog.String("order_id", order.ID.String()),
+		slog.String("user_id", userID.String()),
+		slog.String("from", string(order.FromCurrency)),
+		slog.String("to", string(order.ToCurrency)),
+		slog.Int64("amount", order.Amount),
+		slog.String("rate", order.Rate.String()))
+
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	resp := toLimitOrderResponse(order, exps, time.Now())
+	return &resp, nil
+}
+
+func (s *LimitOrderService) ListOrders(ctx context.Context, userID uuid.UUID, req models.LimitOrderListRequest) (*models.LimitOrderListResponse, error) {
+	const op = "service.ListLimitOrders"
+
+	if req.Status != "" && !req.Status.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %s", custom_err.ErrInvalidInput, req.Status)
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = models.DefaultLimitOrdersLimit
+	}
+	if limit < 0 || limit > models.MaxLimitOrdersLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", custom_err.ErrInvalidInput, models.MaxLimitOrdersLimit)
+	}
+
+	orders, err := s.orders.ListByUser(ctx, userID, req.Status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	resp := &models.LimitOrderListResponse{Orders: make([]models.LimitOrderResponse, 0, len(orders))}
+	for i := range orders {
+		resp.Orders = append(resp.Orders, toLimitOrderResponse(&orders[i], exps, now))
+	}
+	return resp, nil
+}
+
+func (s *LimitOrderService) CancelOrder(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.LimitOrderResponse, error) {
+	const op = "service.CancelLimitOrder"
+
+	order, err := s.orders.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.policy.AuthorizeLimitOrder(actor, order, ActionWrite); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.close(ctx, order.ID, models.LimitOrderStatusCancelled, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("лимитный ордер отменен",
+		slog.String("op", op),
+		slog.String("order_id", order.ID.String()),
+		slog.String("actor_id", actor.UserID.String()))
+
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	resp := toLimitOrderResponse(cancelled, exps, time.Now())
+	return &resp, nil
+}
+
+// ExpireOrders закрывает ордера, срок которых истек, и возвращает их количество
+func (s *LimitOrderService) ExpireOrders(ctx context.Context) (int, error) {
+	const op = "service.ExpireLimitOrders"
+
+	now := time.Now()
+	orders, err := s.orders.ListExpired(ctx, now, limitOrderBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	expired := 0
+	for _, order := range orders {
+		if _, err := s.close(ctx, order.ID, models.LimitOrderStatusExpired, now); err != nil {
+			// Ордер успели исполнить или отменить
+			if errors.Is(err, custom_err.ErrOrderNotOpen) {
+				continue
+			}
+			return expired, fmt.Errorf("%s: %w", op, err)
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// MatchOrders сверяет открытые ордера с текущими курсами exchanger сервиса и исполняет те,
+// для которых курс для клиента достиг заданного. Возвращает количество исполненных ордеров.
+// Ордер, который не удалось исполнить, остается открытым до следующей проверки.
+func (s *LimitOrderService) MatchOrders(ctx context.Context) (int, error) {
+	const op = "service.MatchLimitOrders"
+
+	// Курс пары запрашивается один раз за проход
+	rates := make(map[string]decimal.Decimal)
+	failedPairs := make(map[string]bool)
+
+	filled := 0
+	after := uuid.Nil
+	for {
+		orders, err := s.orders.ListOpen(ctx, time.Now(), after, limitOrderBatchSize)
+		if err != nil {
+			return filled, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for i := range orders {
+			order := &orders[i]
+			pair := string(order.FromCurrency) + "_" + string(order.ToCurrency)
+			if failedPairs[pair] {
+				continue
+			}
+			midRate, ok := rates[pair]
+			if !ok {
+				midRate, err = s.exchange.fetchExchangeRate(ctx, string(order.FromCurrency), string(order.ToCurrency))
+				if err != nil {
+					s.log.Warn("не удалось получить курс для лимитных ордеров",
+						slog.String("pair", pair),
+						slog.String("error", err.Error()))
+					failedPairs[pair] = true
+					continue
+				}
+				rates[pair] = midRate
+			}
+
+			executed, err := s.execute(ctx, order, midRate)
+			if err != nil {
+				if !errors.Is(err, custom_err.ErrOrderNotOpen) {
+					s.log.Warn("лимитный ордер не исполнен",
+						slog.String("order_id", order.ID.String()),
+						slog.String("error", err.Error()))
+				}
+				continue
+			}
+			if executed {
+				filled++
+			}
+		}
+
+		if len(orders) < limitOrderBatchSize {
+			return filled, nil
+		}
+		after = orders[len(orders)-1].ID
+	}
+}
+
+// execute исполняет ордер через обычный путь обмена, если курс для клиента с учетом
+// спреда не хуже заданного. Закрытие ордера и снятие резерва идут в транзакции обмена.
+func (s *LimitOrderService) execute(ctx context.Context, order *models.LimitOrder, midRate decimal.Decimal) (bool, error) {
+	fromCurrency, err := s.currencies.Get(ctx, order.FromCurrency)
+	if err != nil {
+		return false, err
+	}
+
+	terms, err := s.exchange.priceExchange(ctx, order.FromCurrency, order.ToCurrency,
+		models.AmountFromMinorUnits(order.Amount, fromCurrency.Exponent),
+		func(context.Context, string, string) (decimal.Decimal, error) { return midRate, nil })
+	if err != nil {
+		return false, err
+	}
+	if terms.rate.LessThan(order.Rate) {
+		return false, nil
+	}
+
+	fill := models.LimitOrderFill{
+		ExchangedAmount: terms.exchangedAmount,
+		Rate:            terms.rate,
+		Fee:             terms.fee,
+	}
+	_, err = s.exchange.executeExchange(ctx, order.UserID, order.ExchangeRequestID(), terms, exchangeSource{
+		settleTx: func(ctx context.Context, tx pgx.Tx) error {
+			_, err := s.orders.CloseTx(ctx, tx, order.ID, models.LimitOrderStatusFilled, fill, time.Now())
+			return err
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	s.log.Info("лимитный ордер исполнен",
+		slog.String("order_id", order.ID.String()),
+		slog.String("user_id", order.UserID.String()),
+		slog.String("limit_rate", order.Rate.String()),
+		slog.String("rate", terms.rate.String()))
+	return true, nil
+}
+
+// close закрывает ордер без обмена и снимает его резерв
+func (s *LimitOrderService) close(ctx context.Context, id uuid.UUID, status models.LimitOrderStatus, now time.Time) (*models.LimitOrder, error) {
+	var closed *models.LimitOrder
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		closed, err = s.orders.CloseTx(ctx, tx, id, status, models.LimitOrderFill{}, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return closed, nil
+}
+
+func toLimitOrderResponse(order *models.LimitOrder, exps exponents, now time.Time) models.LimitOrderResponse {
+	fromExp := exps.of(string(order.FromCurrency))
+	resp := models.LimitOrderResponse{
+		ID:           order.ID,
+		FromCurrency: string(order.FromCurrency),
+		ToCurrency:   string(order.ToCurrency),
+		Amount:       models.AmountFromMinorUnits(order.Amount, fromExp),
+		Rate:         order.Rate,
+		Status:       order.Status,
+		RequestID:    order.RequestID,
+		ExpiresAt:    order.ExpiresAt,
+		FilledAt:     order.FilledAt,
+		CreatedAt:    order.CreatedAt,
+		UpdatedAt:    order.UpdatedAt,
+	}
+	// Истекший ордер закрывается фоновой задачей с задержкой, но для клиента он уже истек
+	if order.Status == models.LimitOrderStatusOpen && !order.OpenAt(now) {
+		resp.Status = models.LimitOrderStatusExpired
+	}
+	if order.Status == models.LimitOrderStatusFilled {
+		exchanged := models.AmountFromMinorUnits(order.Fill.ExchangedAmount, exps.of(string(order.ToCurrency)))
+		rate := order.Fill.Rate
+		fee := models.AmountFromMinorUnits(order.Fill.Fee, fromExp)
+		resp.ExchangedAmount, resp.ExecutedRate, resp.Fee = &exchanged, &rate, &fee
+	}
+	return resp
+}
+
+// Start запускает фоновое исполнение и истечение лимитных ордеров
+func (s *LimitOrderService) Start() {
+	s.wg.Add(1)
+	go s.matchLoop()
+}
+
+func (s *LimitOrderService) matchLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.matchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx := context.Background()
+			expired, err := s.ExpireOrders(ctx)
+			if err != nil {
+				s.log.Error("ошибка закрытия истекших лимитных ордеров", slog.String("error", err.Error()))
+			}
+			if expired > 0 {
+				s.log.Info("закрыты истекшие лимитные ордера", slog.Int("count", expired))
+			}
+
+			filled, err := s.MatchOrders(ctx)
+			if err != nil {
+				s.log.Error("ошибка исполнения лимитных ордеров", slog.String("error", err.Error()))
+			}
+			if filled > 0 {
+				s.log.Info("исполнены лимитные ордера", slog.Int("count", filled))
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *LimitOrderService) Shutdown(ctx context.Context) error {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/grpc_client"
+	"gw-currency-wallet/internal/models"
+)
+
+type limitOrderMocks struct {
+	orders     *MockLimitOrderRepository
+	walletRepo *MockWalletRepo
+	ledger     *MockLedgerRepo
+	txManager  *MockTxManager
+	grpcClient *MockExchangerClient
+}
+
+func setupLimitOrderService(t *testing.T) (*LimitOrderService, limitOrderMocks) {
+	exchange, walletRepo, ledger, txManager, grpcClient, _ := setupExchangeService(t)
+	m := limitOrderMocks{
+		orders:     new(MockLimitOrderRepository),
+		walletRepo: walletRepo,
+		ledger:     ledger,
+		txManager:  txManager,
+		grpcClient: grpcClient,
+	}
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	service := NewLimitOrderService(m.orders, walletRepo, exchange, txManager, newTestCurrencyRegistry(), NewPolicy(),
+		time.Minute, log)
+
+	return service, m
+}
+
+func newOpenLimitOrder(userID uuid.UUID, amount int64, rate string) models.LimitOrder {
+	return models.LimitOrder{
+		ID:           uuid.New(),
+		UserID:       userID,
+		WalletID:     uuid.New(),
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       amount,
+		Rate:         decimal.RequireFromString(rate),
+		Status:       models.LimitOrderStatusOpen,
+		RequestID:    "order-" + rate,
+	}
+}
+
+func TestLimitOrderService_CreateOrder_Success(t *testing.T) {
+	service, m := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	wallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	expiresAt := time.Now().Add(time.Hour)
+	req := models.CreateLimitOrderRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("1000"),
+		Rate:         decimal.RequireFromString("0.95"),
+		ExpiresAt:    &expiresAt,
+		RequestID:    "order-1",
+	}
+	created := newOpenLimitOrder(userID, 100000, "0.95")
+	created.ExpiresAt = &expiresAt
+
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(wallet, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.orders.On("OrderExistsTx", ctx, mock.Anything, "order-1").Return(false, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, wallet.ID).
+		Return(models.WalletBalance{Balance: 150000, Held: 50000}, nil)
+	m.orders.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(o models.LimitOrder) bool {
+		return o.WalletID == wallet.ID && o.Amount == 100000 && o.Rate.Equal(req.Rate) && o.ExpiresAt == &expiresAt
+	})).Return(&created, nil)
+
+	resp, err := service.CreateOrder(ctx, userID, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.LimitOrderStatusOpen, resp.Status)
+	assert.Equal(t, "1000", resp.Amount.String())
+	assert.Nil(t, resp.ExchangedAmount)
+
+	m.orders.AssertExpectations(t)
+	m.walletRepo.AssertExpectations(t)
+}
+
+func TestLimitOrderService_CreateOrder_InsufficientAvailable(t *testing.T) {
+	service, m := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	wallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	req := models.CreateLimitOrderRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("1000"),
+		Rate:         decimal.RequireFromString("0.95"),
+		RequestID:    "order-1",
+	}
+
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(wallet, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.orders.On("OrderExistsTx", ctx, mock.Anything, "order-1").Return(false, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, wallet.ID).
+		Return(models.WalletBalance{Balance: 150000, Held: 60000}, nil)
+
+	_, err := service.CreateOrder(ctx, userID, req)
+
+	assert.ErrorIs(t, err, custom_err.ErrInsufficientFunds)
+	m.orders.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLimitOrderService_CreateOrder_InvalidInput(t *testing.T) {
+	service, _ := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Minute)
+	valid := models.CreateLimitOrderRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.NewFromInt(10),
+		Rate:         decimal.RequireFromString("0.95"),
+		RequestID:    "order-1",
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(req *models.CreateLimitOrderRequest)
+		wantErr error
+	}{
+		{"same currency", func(req *models.CreateLimitOrderRequest) { req.ToCurrency = models.CurrencyUSD }, custom_err.ErrInvalidCurrency},
+		{"unknown currency", func(req *models.CreateLimitOrderRequest) { req.FromCurrency = "XXX" }, custom_err.ErrInvalidCurrency},
+		{"zero amount", func(req *models.CreateLimitOrderRequest) { req.Amount = decimal.Zero }, custom_err.ErrInvalidAmount},
+		{"zero rate", func(req *models.CreateLimitOrderRequest) { req.Rate = decimal.Zero }, custom_err.ErrInvalidInput},
+		{"missing request id", func(req *models.CreateLimitOrderRequest) { req.RequestID = "" }, custom_err.ErrInvalidInput},
+		{"expiry in the past", func(req *models.CreateLimitOrderRequest) { req.ExpiresAt = &past }, custom_err.ErrInvalidInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			_, err := service.CreateOrder(ctx, uuid.New(), req)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestLimitOrderService_MatchOrders_ExecutesReachedRate(t *testing.T) {
+	service, m := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	reached := newOpenLimitOrder(userID, 10000, "0.90")
+	notReached := newOpenLimitOrder(uuid.New(), 10000, "0.95")
+	fromWallet := &models.Wallet{ID: reached.WalletID, UserID: userID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "EUR"}
+
+	m.orders.On("ListOpen", ctx, mock.AnythingOfType("time.Time"), uuid.Nil, limitOrderBatchSize).
+		Return([]models.LimitOrder{reached, notReached}, nil)
+	// Курс пары запрашивается один раз на оба ордера
+	m.grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		FromCurrency: "USD",
+		ToCurrency:   "EUR",
+		Rate:         decimal.RequireFromString("0.92"),
+	}, nil).Once()
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, reached.ExchangeRequestID()).Return(false, nil)
+	m.orders.On("CloseTx", ctx, mock.Anything, reached.ID, models.LimitOrderStatusFilled, models.LimitOrderFill{
+		ExchangedAmount: 9200,
+		Rate:            decimal.RequireFromString("0.92"),
+	}, mock.AnythingOfType("time.Time")).Return(&reached, nil)
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
+	m.walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyEUR).Return(toWallet, nil)
+	// Резерв ордера снят в той же транзакции, поэтому сумма ордера снова доступна
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 10000}, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{}, nil)
+	m.ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, mock.Anything).Return(uuid.New(), nil)
+	m.ledger.On("PostEntryTx", ctx, mock.Anything, mock.MatchedBy(func(e models.JournalEntry) bool {
+		return e.RequestID == reached.ExchangeRequestID() && e.Validate() == nil
+	})).Return(nil)
+	m.walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, mock.MatchedBy(func(op models.ExchangeOperation) bool {
+		return op.RequestID == reached.ExchangeRequestID() && op.Amount == 10000 && op.ExchangedAmount == 9200
+	})).Return(nil)
+
+	filled, err := service.MatchOrders(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, filled)
+	m.orders.AssertNotCalled(t, "CloseTx", mock.Anything, mock.Anything, notReached.ID, mock.Anything, mock.Anything, mock.Anything)
+	m.orders.AssertExpectations(t)
+	m.ledger.AssertExpectations(t)
+	m.grpcClient.AssertExpectations(t)
+}
+
+func TestLimitOrderService_MatchOrders_CancelledConcurrently(t *testing.T) {
+	service, m := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	order := newOpenLimitOrder(uuid.New(), 10000, "0.90")
+
+	m.orders.On("ListOpen", ctx, mock.AnythingOfType("time.Time"), uuid.Nil, limitOrderBatchSize).
+		Return([]models.LimitOrder{order}, nil)
+	m.grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("0.92"),
+	}, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, order.ExchangeRequestID()).Return(false, nil)
+	m.orders.On("CloseTx", ctx, mock.Anything, order.ID, models.LimitOrderStatusFilled, mock.Anything, mock.Anything).
+		Return(nil, custom_err.ErrOrderNotOpen)
+
+	filled, err := service.MatchOrders(ctx)
+
+	require.NoError(t, err)
+	assert.Zero(t, filled)
+	m.ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLimitOrderService_MatchOrders_RateUnavailable(t *testing.T) {
+	service, m := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	m.orders.On("ListOpen", ctx, mock.AnythingOfType("time.Time"), uuid.Nil, limitOrderBatchSize).
+		Return([]models.LimitOrder{newOpenLimitOrder(uuid.New(), 10000, "0.90"), newOpenLimitOrder(uuid.New(), 10000, "0.91")}, nil)
+	m.grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(nil, errors.New("unavailable")).Once()
+
+	filled, err := service.MatchOrders(ctx)
+
+	require.NoError(t, err)
+	assert.Zero(t, filled)
+	m.grpcClient.AssertExpectations(t)
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestLimitOrderService_CancelOrder(t *testing.T) {
+	service, m := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	order := newOpenLimitOrder(userID, 10000, "0.95")
+	cancelled := order
+	cancelled.Status = models.LimitOrderStatusCancelled
+
+	m.orders.On("GetByID", ctx, order.ID).Return(&order, nil)
+
+	_, err := service.CancelOrder(ctx, &models.JWTClaims{UserID: uuid.New(), Role: models.RoleAdmin}, order.ID)
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.orders.On("CloseTx", ctx, mock.Anything, order.ID, models.LimitOrderStatusCancelled, models.LimitOrderFill{}, mock.AnythingOfType("time.Time")).
+		Return(&cancelled, nil)
+
+	resp, err := service.CancelOrder(ctx, &models.JWTClaims{UserID: userID, Role: models.RoleUser}, order.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.LimitOrderStatusCancelled, resp.Status)
+	m.orders.AssertExpectations(t)
+}
+
+func TestLimitOrderService_ExpireOrders_SkipsClosed(t *testing.T) {
+	service, m := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	first := newOpenLimitOrder(uuid.New(), 10000, "0.95")
+	second := newOpenLimitOrder(uuid.New(), 20000, "0.96")
+
+	m.orders.On("ListExpired", ctx, mock.AnythingOfType("time.Time"), limitOrderBatchSize).
+		Return([]models.LimitOrder{first, second}, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.orders.On("CloseTx", ctx, mock.Anything, first.ID, models.LimitOrderStatusExpired, models.LimitOrderFill{}, mock.AnythingOfType("time.Time")).
+		Return(nil, custom_err.ErrOrderNotOpen)
+	m.orders.On("CloseTx", ctx, mock.Anything, second.ID, models.LimitOrderStatusExpired, models.LimitOrderFill{}, mock.AnythingOfType("time.Time")).
+		Return(&second, nil)
+
+	expired, err := service.ExpireOrders(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	m.orders.AssertExpectations(t)
+}
+
+func TestLimitOrderService_ListOrders(t *testing.T) {
+	service, m := setupLimitOrderService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	filledAt := time.Now()
+	filled := newOpenLimitOrder(userID, 10000, "0.90")
+	filled.Status = models.LimitOrderStatusFilled
+	filled.Fill = models.LimitOrderFill{ExchangedAmount: 9150, Rate: decimal.RequireFromString("0.9196"), Fee: 50}
+	filled.FilledAt = &filledAt
+	expiredAt := time.Now().Add(-time.Second)
+	stale := newOpenLimitOrder(userID, 5000, "0.99")
+	stale.ExpiresAt = &expiredAt
+
+	m.orders.On("ListByUser", ctx, userID, models.LimitOrderStatus(""), models.DefaultLimitOrdersLimit).
+		Return([]models.LimitOrder{filled, stale}, nil)
+
+	resp, err := service.ListOrders(ctx, userID, models.LimitOrderListRequest{})
+
+	require.NoError(t, err)
+	require.Len(t, resp.Orders, 2)
+	assert.Equal(t, models.LimitOrderStatusFilled, resp.Orders[0].Status)
+	assert.Equal(t, "91.5", resp.Orders[0].ExchangedAmount.String())
+	assert.Equal(t, "0.9196", resp.Orders[0].ExecutedRate.String())
+	assert.Equal(t, "0.5", resp.Orders[0].Fee.String())
+	assert.Equal(t, models.LimitOrderStatusExpired, resp.Orders[1].Status)
+
+	_, err = service.ListOrders(ctx, userID, models.LimitOrderListRequest{Status: "DONE"})
+	assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+	_, err = service.ListOrders(ctx, userID, models.LimitOrderListRequest{Limit: models.MaxLimitOrdersLimit + 1})
+	assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+}
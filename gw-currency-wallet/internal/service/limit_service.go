@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Limits лимиты сумм операций пользователя за период
+type Limits interface {
+	// CheckTx проверяет, что операция на amount укладывается в лимиты пользователя, и возвращает
+	// *models.LimitExceededError при превышении. Вызывается в транзакции операции после блокировки
+	// кошелька-источника: конкурентные операции по той же валюте ждут блокировку и учитывают друг друга.
+	CheckTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency models.Currency, amount int64) error
+}
+
+type LimitService struct {
+	repo       postgres.LimitRepository
+	currencies CurrencyRegistry
+}
+
+func NewLimitService(repo postgres.LimitRepository, currencies CurrencyRegistry) Limits {
+	return &LimitService{
+		repo:       repo,
+		currencies: currencies,
+	}
+}
+
+func (s *LimitService) CheckTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	opType models.OperationType,
+	currency models.Currency,
+	amount int64,
+) error {
+	const op = "service.LimitService.CheckTx"
+
+	limits, err := s.repo.ListForOperationTx(ctx, tx, userID, opType, string(currency))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	effective := effectiveLimits(limits)
+
+	for _, period := range models.LimitPeriods {
+		limit, ok := effective[limitKey{opType: opType, currency: string(currency), period: period}]
+		if !ok || limit.MaxAmount == nil {
+			continue
+		}
+
+		used, err := s.repo.SumOperationsTx(ctx, tx, userID, opType, string(currency), period.Window())
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if used+amount <= *limit.MaxAmount {
+			continue
+		}
+
+		info, err := s.currencies.Get(ctx, currency)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return &models.LimitExceededError{
+			OperationType: opType,
+			Currency:      string(currency),
+			Period:        period,
+			Limit:         models.AmountFromMinorUnits(*limit.MaxAmount, info.Exponent),
+			Remaining:     models.AmountFromMinorUnits(max(*limit.MaxAmount-used, 0), info.Exponent),
+		}
+	}
+	return nil
+}
+
+type limitKey struct {
+	opType   models.OperationType
+	currency string
+	period   models.LimitPeriod
+}
+
+// effectiveLimits действующие лимиты: переопределение пользователя заменяет лимит по умолчанию
+func effectiveLimits(limits []models.TransactionLimit) map[limitKey]models.TransactionLimit {
+	effective := make(map[limitKey]models.TransactionLimit, len(limits))
+	for _, l := range limits {
+		key := limitKey{opType: l.OperationType, currency: l.Currency, period: l.Period}
+		if current, ok := effective[key]; ok && current.UserID != nil && l.UserID == nil {
+			continue
+		}
+		effective[key] = l
+	}
+	return effective
+}
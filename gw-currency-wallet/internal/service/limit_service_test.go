@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestLimitService_CheckTx_WithinLimit(t *testing.T) {
+	repo := new(MockLimitRepository)
+	service := NewLimitService(repo, newTestCurrencyRegistry())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("ListForOperationTx", ctx, mock.Anything, userID, models.OperationWithdraw, "USD").Return([]models.TransactionLimit{
+		{OperationType: models.OperationWithdraw, Currency: "USD", Period: models.LimitPeriodDaily, MaxAmount: int64Ptr(100000)},
+	}, nil)
+	repo.On("SumOperationsTx", ctx, mock.Anything, userID, models.OperationWithdraw, "USD", models.LimitPeriodDaily.Window()).Return(int64(60000), nil)
+
+	err := service.CheckTx(ctx, nil, userID, models.OperationWithdraw, models.CurrencyUSD, 40000)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestLimitService_CheckTx_DefaultExceeded(t *testing.T) {
+	repo := new(MockLimitRepository)
+	service := NewLimitService(repo, newTestCurrencyRegistry())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("ListForOperationTx", ctx, mock.Anything, userID, models.OperationWithdraw, "USD").Return([]models.TransactionLimit{
+		{OperationType: models.OperationWithdraw, Currency: "USD", Period: models.LimitPeriodDaily, MaxAmount: int64Ptr(100000)},
+	}, nil)
+	repo.On("SumOperationsTx", ctx, mock.Anything, userID, models.OperationWithdraw, "USD", models.LimitPeriodDaily.Window()).Return(int64(75000), nil)
+
+	err := service.CheckTx(ctx, nil, userID, models.OperationWithdraw, models.CurrencyUSD, 30000)
+
+	require.ErrorIs(t, err, custom_err.ErrLimitExceeded)
+	var limitErr *models.LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, models.LimitPeriodDaily, limitErr.Period)
+	assert.Equal(t, "1000", limitErr.Limit.String())
+	assert.Equal(t, "250", limitErr.Remaining.String())
+}
+
+func TestLimitService_CheckTx_MonthlyExceeded(t *testing.T) {
+	repo := new(MockLimitRepository)
+	service := NewLimitService(repo, newTestCurrencyRegistry())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("ListForOperationTx", ctx, mock.Anything, userID, models.OperationExchange, "EUR").Return([]models.TransactionLimit{
+		{OperationType: models.OperationExchange, Currency: "EUR", Period: models.LimitPeriodDaily, MaxAmount: int64Ptr(100000)},
+		{OperationType: models.OperationExchange, Currency: "EUR", Period: models.LimitPeriodMonthly, MaxAmount: int64Ptr(500000)},
+	}, nil)
+	repo.On("SumOperationsTx", ctx, mock.Anything, userID, models.OperationExchange, "EUR", models.LimitPeriodDaily.Window()).Return(int64(0), nil)
+	repo.On("SumOperationsTx", ctx, mock.Anything, userID, models.OperationExchange, "EUR", models.LimitPeriodMonthly.Window()).Return(int64(520000), nil)
+
+	err := service.CheckTx(ctx, nil, userID, models.OperationExchange, models.CurrencyEUR, 100)
+
+	var limitErr *models.LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, models.LimitPeriodMonthly, limitErr.Period)
+	assert.True(t, limitErr.Remaining.IsZero())
+}
+
+func TestLimitService_CheckTx_OverrideReplacesDefault(t *testing.T) {
+	repo := new(MockLimitRepository)
+	service := NewLimitService(repo, newTestCurrencyRegistry())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("ListForOperationTx", ctx, mock.Anything, userID, models.OperationTransferOut, "USD").Return([]models.TransactionLimit{
+		{OperationType: models.OperationTransferOut, Currency: "USD", Period: models.LimitPeriodDaily, MaxAmount: int64Ptr(100000)},
+		{UserID: &userID, OperationType: models.OperationTransferOut, Currency: "USD", Period: models.LimitPeriodDaily, MaxAmount: int64Ptr(1000000)},
+	}, nil)
+	repo.On("SumOperationsTx", ctx, mock.Anything, userID, models.OperationTransferOut, "USD", models.LimitPeriodDaily.Window()).Return(int64(0), nil)
+
+	err := service.CheckTx(ctx, nil, userID, models.OperationTransferOut, models.CurrencyUSD, 500000)
+
+	assert.NoError(t, err)
+}
+
+func TestLimitService_CheckTx_UnlimitedOverride(t *testing.T) {
+	repo := new(MockLimitRepository)
+	service := NewLimitService(repo, newTestCurrencyRegistry())
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("ListForOperationTx", ctx, mock.Anything, userID, models.OperationDeposit, "USD").Return([]models.TransactionLimit{
+		{OperationType: models.OperationDeposit, Currency: "USD", Period: models.LimitPeriodDaily, MaxAmount: int64Ptr(100000)},
+		{UserID: &userID, OperationType: models.OperationDeposit, Currency: "USD", Period: models.LimitPeriodDaily},
+	}, nil)
+
+	err := service.CheckTx(ctx, nil, userID, models.OperationDeposit, models.CurrencyUSD, 10000000)
+
+	assert.NoError(t, err)
+	repo.AssertNotCalled(t, "SumOperationsTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
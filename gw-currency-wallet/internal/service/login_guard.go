@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// LoginGuard защита входа по паролю от перебора. Неверные попытки считаются отдельно по имени
+// пользователя и по адресу клиента: после бесплатных попыток каждая следующая удваивает ожидание,
+// а после серии неверных паролей учетная запись блокируется на время.
+type LoginGuard interface {
+	// Check возвращает *models.LoginThrottledError, если вход по имени или с адреса сейчас запрещен
+	Check(ctx context.Context, username string, ip netip.Addr) error
+	// RecordFailure записывает неудачный вход в журнал. Неверный пароль и неизвестное имя
+	// увеличивают счетчики; user - найденный пользователь или nil.
+	RecordFailure(ctx context.Context, username string, user *models.User, ip netip.Addr, reason string) error
+	// RecordSuccess сбрасывает счетчик по имени пользователя. Счетчик по адресу не сбрасывается:
+	// иначе перебор с одного адреса можно было бы обнулять входом в свою учетную запись.
+	RecordSuccess(ctx context.Context, username string) error
+}
+
+type LoginGuardService struct {
+	repo             postgres.LoginThrottleRepository
+	userRepo         postgres.UserRepository
+	txManager        TxManager
+	audit            AuditRecorder
+	usernameBackoff  models.LoginBackoff
+	ipBackoff        models.LoginBackoff
+	lockoutThreshold int
+	lockoutDuration  time.Duration
+	// window через сколько после последней неверной попытки счетчик начинается заново
+	window time.Duration
+	log    *slog.Logger
+}
+
+func NewLoginGuard(
+	repo postgres.LoginThrottleRepository,
+	userRepo postgres.UserRepository,
+	txManager TxManager,
+	audit AuditRecorder,
+	usernameBackoff models.LoginBackoff,
+	ipBackoff models.LoginBackoff,
+	lockoutThreshold int,
+	lockoutDuration time.Duration,
+	window time.Duration,
+	log *slog.Logger,
+) *LoginGuardService {
+	return &LoginGuardService{
+		repo:             repo,
+		userRepo:         userRepo,
+		txManager:        txManager,
+		audit:            audit,
+		usernameBackoff:  usernameBackoff,
+		ipBackoff:        ipBackoff,
+		lockoutThreshold: lockoutThreshold,
+		lockoutDuration:  lockoutDuration,
+		window:           window,
+		log:              log,
+	}
+}
+
+func (g *LoginGuardService) Check(ctx context.Context, username string, ip netip.Addr) error {
+	const op = "service.LoginGuard.Check"
+
+	until, err := g.repo.BlockedUntil(ctx, usernameKey(username), ipKey(ip))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if until == nil {
+		return nil
+	}
+	retryAfter := time.Until(*until)
+	if retryAfter <= 0 {
+		return nil
+	}
+	return &models.LoginThrottledError{RetryAfter: retryAfter}
+}
+
+func (g *LoginGuardService) RecordFailure(ctx context.Context, username string, user *models.User, ip netip.Addr, reason string) error {
+	const op = "service.LoginGuard.RecordFailure"
+
+	now := time.Now()
+	ip = ip.Unmap()
+	failure := models.LoginFailure{
+		ID:        uuid.New(),
+		Username:  username,
+		Reason:    reason,
+		CreatedAt: now,
+	}
+	if user != nil {
+		failure.UserID = &user.ID
+	}
+	if ip.IsValid() {
+		failure.IP = &ip
+	}
+
+	// Отклоненные без проверки пароля попытки только попадают в журнал: ожидание уже назначено
+	counted := reason == models.LoginFailureInvalidPassword || reason == models.LoginFailureUnknownUser
+
+	var lockedUntil time.Time
+	err := g.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		if counted {
+			attempts, err := g.countFailureTx(ctx, tx, models.LoginThrottleByUsername, usernameKey(username), g.usernameBackoff)
+			if err != nil {
+				return err
+			}
+			if user != nil && g.lockoutThreshold > 0 && attempts >= g.lockoutThreshold {
+				until := now.Add(g.lockoutDuration)
+				if err := g.userRepo.LockTx(ctx, tx, user.ID, until); err != nil {
+					return err
+				}
+				lockedUntil = until
+			}
+
+			if ip.IsValid() {
+				if _, err := g.countFailureTx(ctx, tx, models.LoginThrottleByIP, ipKey(ip), g.ipBackoff); err != nil {
+					return err
+				}
+			}
+		}
+		return g.repo.CreateFailureTx(ctx, tx, failure)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !lockedUntil.IsZero() {
+		g.log.Warn("учетная запись заблокирована после серии неверных паролей",
+			slog.String("op", op),
+			slog.String("user_id", user.ID.String()),
+			slog.Duration("duration", g.lockoutDuration))
+		g.audit.Record(ctx, models.AuditEvent{
+			Type:     models.AuditEventAccountLocked,
+			UserID:   &user.ID,
+			Metadata: map[string]string{"until": lockedUntil.UTC().Format(time.RFC3339)},
+		})
+	}
+	return nil
+}
+
+func (g *LoginGuardService) RecordSuccess(ctx context.Context, username string) error {
+	const op = "service.LoginGuard.RecordSuccess"
+
+	if err := g.repo.Reset(ctx, models.LoginThrottleByUsername, usernameKey(username), g.window); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// countFailureTx увеличивает счетчик ключа и назначает ожидание по правилу backoff
+func (g *LoginGuardService) countFailureTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	scope models.LoginThrottleScope,
+	key string,
+	backoff models.LoginBackoff,
+) (int, error) {
+	attempts, err := g.repo.RecordFailureTx(ctx, tx, scope, key, g.window)
+	if err != nil {
+		return 0, err
+	}
+	if delay := backoff.Delay(attempts); delay > 0 {
+		if err := g.repo.BlockTx(ctx, tx, scope, key, delay); err != nil {
+			return 0, err
+		}
+	}
+	return attempts, nil
+}
+
+// usernameKey имена сравниваются без учета регистра, чтобы перебор нельзя было растянуть вариантами написания
+func usernameKey(username string) string {
+	return strings.ToLower(username)
+}
+
+func ipKey(ip netip.Addr) string {
+	if !ip.IsValid() {
+		return ""
+	}
+	return ip.Unmap().String()
+}
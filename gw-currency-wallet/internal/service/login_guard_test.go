@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+type loginGuardMocks struct {
+	repo      *MockLoginThrottleRepository
+	userRepo  *MockUserRepository
+	txManager *MockTxManager
+	audit     *MockAuditRecorder
+}
+
+func setupLoginGuard() (*LoginGuardService, loginGuardMocks) {
+	m := loginGuardMocks{
+		repo:      new(MockLoginThrottleRepository),
+		userRepo:  new(MockUserRepository),
+		txManager: new(MockTxManager),
+		audit:     newMockAuditRecorder(),
+	}
+	m.txManager.On("WithTx", mock.Anything, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil).Maybe()
+
+	guard := NewLoginGuard(
+		m.repo,
+		m.userRepo,
+		m.txManager,
+		m.audit,
+		models.LoginBackoff{FreeAttempts: 3, Base: time.Second, Max: time.Minute},
+		models.LoginBackoff{FreeAttempts: 20, Base: time.Second, Max: time.Minute},
+		10,
+		30*time.Minute,
+		time.Hour,
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	return guard, m
+}
+
+func TestLoginGuard_Check(t *testing.T) {
+	ip := netip.MustParseAddr("::ffff:203.0.113.10")
+	past, future := time.Now().Add(-time.Second), time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name          string
+		until         *time.Time
+		wantThrottled bool
+	}{
+		{name: "no throttle", until: nil},
+		{name: "expired throttle", until: &past},
+		{name: "active throttle", until: &future, wantThrottled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, m := setupLoginGuard()
+			// Имя сравнивается без учета регистра, адрес - без IPv4-mapped префикса
+			m.repo.On("BlockedUntil", mock.Anything, "alice", "203.0.113.10").Return(tt.until, nil)
+
+			err := guard.Check(context.Background(), "Alice", ip)
+			if !tt.wantThrottled {
+				assert.NoError(t, err)
+				return
+			}
+
+			var throttled *models.LoginThrottledError
+			require.ErrorAs(t, err, &throttled)
+			assert.ErrorIs(t, err, custom_err.ErrLoginThrottled)
+			assert.InDelta(t, time.Minute.Seconds(), throttled.RetryAfter.Seconds(), 1)
+		})
+	}
+}
+
+func TestLoginGuard_RecordFailure_CountsAttempts(t *testing.T) {
+	guard, m := setupLoginGuard()
+	ctx := context.Background()
+	ip := netip.MustParseAddr("203.0.113.10")
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+
+	m.repo.On("RecordFailureTx", ctx, mock.Anything, models.LoginThrottleByUsername, "alice", time.Hour).Return(5, nil).Once()
+	m.repo.On("BlockTx", ctx, mock.Anything, models.LoginThrottleByUsername, "alice", 2*time.Second).Return(nil).Once()
+	m.repo.On("RecordFailureTx", ctx, mock.Anything, models.LoginThrottleByIP, "203.0.113.10", time.Hour).Return(2, nil).Once()
+	m.repo.On("CreateFailureTx", ctx, mock.Anything, mock.MatchedBy(func(f models.LoginFailure) bool {
+		return f.Username == "Alice" && f.UserID != nil && *f.UserID == user.ID &&
+			f.IP != nil && *f.IP == ip && f.Reason == models.LoginFailureInvalidPassword
+	})).Return(nil).Once()
+
+	err := guard.RecordFailure(ctx, "Alice", user, ip, models.LoginFailureInvalidPassword)
+	require.NoError(t, err)
+
+	// Адрес еще в пределах бесплатных попыток, учетная запись не достигла порога
+	m.repo.AssertNumberOfCalls(t, "BlockTx", 1)
+	m.userRepo.AssertNotCalled(t, "LockTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.repo.AssertExpectations(t)
+}
+
+func TestLoginGuard_RecordFailure_LocksAccount(t *testing.T) {
+	guard, m := setupLoginGuard()
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Username: "alice"}
+
+	m.repo.On("RecordFailureTx", ctx, mock.Anything, models.LoginThrottleByUsername, "alice", time.Hour).Return(10, nil)
+	m.repo.On("BlockTx", ctx, mock.Anything, models.LoginThrottleByUsername, "alice", time.Minute).Return(nil)
+	m.repo.On("CreateFailureTx", ctx, mock.Anything, mock.Anything).Return(nil)
+	m.userRepo.On("LockTx", ctx, mock.Anything, user.ID, mock.MatchedBy(func(until time.Time) bool {
+		return time.Until(until) > 29*time.Minute && time.Until(until) <= 30*time.Minute
+	})).Return(nil).Once()
+
+	// Без адреса клиента учитывается только имя
+	err := guard.RecordFailure(ctx, "alice", user, netip.Addr{}, models.LoginFailureInvalidPassword)
+	require.NoError(t, err)
+
+	m.userRepo.AssertExpectations(t)
+	m.repo.AssertNotCalled(t, "RecordFailureTx", mock.Anything, mock.Anything, models.LoginThrottleByIP, mock.Anything, mock.Anything)
+
+	events := m.audit.recordedAuditEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditEventAccountLocked, events[0].Type)
+	assert.Equal(t, &user.ID, events[0].UserID)
+	assert.NotEmpty(t, events[0].Metadata["until"])
+}
+
+func TestLoginGuard_RecordFailure_UnknownUserIsNotLocked(t *testing.T) {
+	guard, m := setupLoginGuard()
+	ctx := context.Background()
+
+	m.repo.On("RecordFailureTx", ctx, mock.Anything, models.LoginThrottleByUsername, "ghost", time.Hour).Return(50, nil)
+	m.repo.On("BlockTx", ctx, mock.Anything, models.LoginThrottleByUsername, "ghost", time.Minute).Return(nil)
+	m.repo.On("CreateFailureTx", ctx, mock.Anything, mock.MatchedBy(func(f models.LoginFailure) bool {
+		return f.UserID == nil && f.IP == nil && f.Reason == models.LoginFailureUnknownUser
+	})).Return(nil)
+
+	err := guard.RecordFailure(ctx, "ghost", nil, netip.Addr{}, models.LoginFailureUnknownUser)
+	require.NoError(t, err)
+	m.userRepo.AssertNotCalled(t, "LockTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.audit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+}
+
+func TestLoginGuard_RecordFailure_RejectedAttemptOnlyLogged(t *testing.T) {
+	for _, reason := range []string{models.LoginFailureThrottled, models.LoginFailureLocked} {
+		t.Run(reason, func(t *testing.T) {
+			guard, m := setupLoginGuard()
+			m.repo.On("CreateFailureTx", mock.Anything, mock.Anything, mock.MatchedBy(func(f models.LoginFailure) bool {
+				return f.Reason == reason
+			})).Return(nil).Once()
+
+			err := guard.RecordFailure(context.Background(), "alice", nil, netip.MustParseAddr("203.0.113.10"), reason)
+			require.NoError(t, err)
+
+			m.repo.AssertNotCalled(t, "RecordFailureTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			m.repo.AssertNotCalled(t, "BlockTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			m.repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLoginGuard_RecordFailure_StorageError(t *testing.T) {
+	guard, m := setupLoginGuard()
+	m.repo.On("RecordFailureTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(0, errors.New("connection reset"))
+
+	err := guard.RecordFailure(context.Background(), "alice", nil, netip.Addr{}, models.LoginFailureInvalidPassword)
+	assert.Error(t, err)
+	m.repo.AssertNotCalled(t, "CreateFailureTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoginGuard_RecordSuccess(t *testing.T) {
+	guard, m := setupLoginGuard()
+	m.repo.On("Reset", mock.Anything, models.LoginThrottleByUsername, "alice", time.Hour).Return(nil).Once()
+
+	require.NoError(t, guard.RecordSuccess(context.Background(), "ALICE"))
+	m.repo.AssertExpectations(t)
+}
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/secretbox"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// mfaMaxFailedAttempts сколько неверных кодов подряд допускается до блокировки проверки
+	mfaMaxFailedAttempts = 5
+	mfaLockDuration      = 15 * time.Minute
+	recoveryCodeCount    = 10
+)
+
+// MFA подключение и отключение двухфакторной аутентификации по TOTP
+type MFA interface {
+	Status(ctx context.Context, userID uuid.UUID) (*models.MFAStatusResponse, error)
+	// EnrollTOTP выдает новый секрет; TOTP включается только после ConfirmTOTP
+	EnrollTOTP(ctx context.Context, userID uuid.UUID, req models.TOTPEnrollRequest) (*models.TOTPEnrollResponse, error)
+	// ConfirmTOTP включает TOTP по первому коду из приложения и выдает коды восстановления
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, req models.MFACodeRequest) (*models.RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+	// RegenerateRecoveryCodes заменяет все коды восстановления новыми
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) (*models.RecoveryCodesResponse, error)
+}
+
+// SecondFactor проверка второго фактора при входе и step-up
+type SecondFactor interface {
+	Enabled(ctx context.Context, userID uuid.UUID) (bool, error)
+	// NewChallenge выпускает токен второго шага входа и возвращает срок его действия
+	NewChallenge(ctx context.Context, userID uuid.UUID) (string, time.Time, error)
+	// CompleteChallengeTx проверяет код для токена второго шага, гасит токен и возвращает пользователя.
+	// Ошибки как у VerifyTx, при неверном коде пользователь возвращается вместе с ошибкой;
+	// неизвестный, истекший или использованный токен - custom_err.ErrInvalidToken.
+	CompleteChallengeTx(ctx context.Context, tx pgx.Tx, challengeToken, code string) (uuid.UUID, error)
+	// VerifyTx проверяет код TOTP или код восстановления. При неверном коде возвращает
+	// custom_err.ErrInvalidMFACode, а попытка уже учтена в tx: транзакцию нужно зафиксировать,
+	// иначе счетчик попыток не увеличится.
+	VerifyTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, code string) error
+}
+
+type MFAService struct {
+	repo      postgres.MFARepository
+	userRepo  postgres.UserRepository
+	txManager TxManager
+	audit     AuditRecorder
+	// box шифрует секреты TOTP в БД
+	box *secretbox.Box
+	// issuer название сервиса, которое показывает приложение-аутентификатор
+	issuer       string
+	challengeTTL time.Duration
+	log          *slog.Logger
+}
+
+func NewMFAService(
+	repo postgres.MFARepository,
+	userRepo postgres.UserRepository,
+	txManager TxManager,
+	audit AuditRecorder,
+	box *secretbox.Box,
+	issuer string,
+	challengeTTL time.Duration,
+	log *slog.Logger,
+) *MFAService {
+	return &MFAService{
+		repo:         repo,
+		userRepo:     userRepo,
+		txManager:    txManager,
+		audit:        audit,
+		box:          box,
+		issuer:       issuer,
+		challengeTTL: challengeTTL,
+		log:          log,
+	}
+}
+
+func (s *MFAService) Status(ctx context.Context, userID uuid.UUID) (*models.MFAStatusResponse, error) {
+	const op = "service.MFAStatus"
+
+	enabled, err := s.Enabled(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !enabled {
+		return &models.MFAStatusResponse{}, nil
+	}
+
+	remaining, err := s.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &models.MFAStatusResponse{Enabled: true, RecoveryCodesRemaining: remaining}, nil
+}
+
+func (s *MFAService) EnrollTOTP(ctx context.Context, userID uuid.UUID, req models.TOTPEnrollRequest) (*models.TOTPEnrollResponse, error) {
+	const op = "service.EnrollTOTP"
+
+	if req.Password == "" {
+		return nil, fmt.Errorf("%w: password is required", custom_err.ErrInvalidInput)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// Пароль защищает от подключения второго фактора по украденному access токену
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, custom_err.ErrInvalidCredentials
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	sealed, err := s.box.Seal(secret, userID.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.repo.SavePending(ctx, userID, sealed); err != nil {
+		if errors.Is(err, custom_err.ErrMFAAlreadyEnabled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("начато подключение TOTP", slog.String("op", op), slog.String("user_id", userID.String()))
+	return &models.TOTPEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totpURI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+func (s *MFAService) ConfirmTOTP(ctx context.Context, userID uuid.UUID, req models.MFACodeRequest) (*models.RecoveryCodesResponse, error) {
+	const op = "service.ConfirmTOTP"
+
+	codes, hashes := newRecoveryCodes()
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		mfa, err := s.repo.GetForUpdateTx(ctx, tx, userID)
+		if err != nil {
+			if errors.Is(err, custom_err.ErrNotFound) {
+				return custom_err.ErrMFANotEnrolled
+			}
+			return fmt.Errorf("failed to get mfa settings: %w", err)
+		}
+		if mfa.Enabled() {
+			return custom_err.ErrMFAAlreadyEnabled
+		}
+
+		secret, err := s.openSecret(mfa)
+		if err != nil {
+			return err
+		}
+		step, ok := verifyTOTP(secret, strings.TrimSpace(req.Code), time.Now(), 0)
+		if !ok {
+			return custom_err.ErrInvalidMFACode
+		}
+		if err := s.repo.EnableTx(ctx, tx, userID, step); err != nil {
+			return err
+		}
+		if err := s.repo.ReplaceRecoveryCodesTx(ctx, tx, userID, hashes); err != nil {
+			return fmt.Errorf("failed to store recovery codes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrMFANotEnrolled) ||
+			errors.Is(err, custom_err.ErrMFAAlreadyEnabled) ||
+			errors.Is(err, custom_err.ErrInvalidMFACode) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("TOTP включен", slog.String("op", op), slog.String("user_id", userID.String()))
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventMFAEnabled, UserID: &userID})
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *MFAService) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	const op = "service.DisableTOTP"
+
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.requireEnabledTx(ctx, tx, userID); err != nil {
+			return err
+		}
+		return s.repo.DeleteTx(ctx, tx, userID)
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrMFANotEnabled) {
+			return err
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Warn("TOTP отключен", slog.String("op", op), slog.String("user_id", userID.String()))
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventMFADisabled, UserID: &userID})
+	return nil
+}
+
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID) (*models.RecoveryCodesResponse, error) {
+	const op = "service.RegenerateRecoveryCodes"
+
+	codes, hashes := newRecoveryCodes()
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.requireEnabledTx(ctx, tx, userID); err != nil {
+			return err
+		}
+		return s.repo.ReplaceRecoveryCodesTx(ctx, tx, userID, hashes)
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrMFANotEnabled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("коды восстановления перевыпущены", slog.String("op", op), slog.String("user_id", userID.String()))
+	s.audit.Record(ctx, models.AuditEvent{Type: models.AuditEventRecoveryCodesRegenerated, UserID: &userID})
+	return &models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *MFAService) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	mfa, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.Enabled(), nil
+}
+
+// openSecret расшифровывает секрет TOTP. Открытый секрет мог сохранить экземпляр без шифрования,
+// его зашифрует SealPlaintextSecrets при следующем запуске.
+func (s *MFAService) openSecret(mfa *models.UserMFA) (string, error) {
+	if !secretbox.IsSealed(mfa.TOTPSecret) {
+		return mfa.TOTPSecret, nil
+	}
+	secret, err := s.box.Open(mfa.TOTPSecret, mfa.UserID.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+	return secret, nil
+}
+
+// SealPlaintextSecrets шифрует секреты TOTP, сохраненные открытыми до включения шифрования
+func (s *MFAService) SealPlaintextSecrets(ctx context.Context) error {
+	const op = "service.SealPlaintextTOTPSecrets"
+
+	plaintext, err := s.repo.ListUnsealedSecrets(ctx, secretbox.SealedPrefix)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, mfa := range plaintext {
+		sealed, err := s.box.Seal(mfa.TOTPSecret, mfa.UserID.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := s.repo.ReplaceSecret(ctx, mfa.UserID, mfa.TOTPSecret, sealed); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if len(plaintext) > 0 {
+		s.log.Info("секреты TOTP зашифрованы", slog.String("op", op), slog.Int("count", len(plaintext)))
+	}
+	return nil
+}
+
+func (s *MFAService) NewChallenge(ctx context.Context, userID uuid.UUID) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate mfa token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	challenge := &models.MFAChallenge{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	}
+	if err := s.repo.CreateChallenge(ctx, challenge); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, challenge.ExpiresAt, nil
+}
+
+func (s *MFAService) CompleteChallengeTx(ctx context.Context, tx pgx.Tx, challengeToken, code string) (uuid.UUID, error) {
+	challenge, err := s.repo.GetChallengeForUpdateTx(ctx, tx, hashToken(challengeToken))
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return uuid.Nil, custom_err.ErrInvalidToken
+		}
+		return uuid.Nil, err
+	}
+	if !challenge.UsableAt(time.Now()) {
+		return uuid.Nil, custom_err.ErrInvalidToken
+	}
+
+	// Неверный код не гасит токен: число попыток ограничивает блокировка проверки
+	if err := s.VerifyTx(ctx, tx, challenge.UserID, code); err != nil {
+		if errors.Is(err, custom_err.ErrInvalidMFACode) {
+			return challenge.UserID, err
+		}
+		return uuid.Nil, err
+	}
+	if err := s.repo.MarkChallengeUsedTx(ctx, tx, challenge.ID); err != nil {
+		return uuid.Nil, err
+	}
+	return challenge.UserID, nil
+}
+
+func (s *MFAService) VerifyTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, code string) error {
+	const op = "service.VerifyMFA"
+
+	mfa, err := s.repo.GetForUpdateTx(ctx, tx, userID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return custom_err.ErrMFANotEnabled
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !mfa.Enabled() {
+		return custom_err.ErrMFANotEnabled
+	}
+	now := time.Now()
+	if mfa.LockedAt(now) {
+		return custom_err.ErrMFALocked
+	}
+
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		secret, err := s.openSecret(mfa)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if step, ok := verifyTOTP(secret, code, now, mfa.LastUsedStep); ok {
+			if err := s.repo.RecordSuccessTx(ctx, tx, userID, step); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			return nil
+		}
+	} else {
+		err := s.repo.ConsumeRecoveryCodeTx(ctx, tx, userID, hashToken(normalizeRecoveryCode(code)))
+		if err == nil {
+			if err := s.repo.RecordSuccessTx(ctx, tx, userID, mfa.LastUsedStep); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			s.log.Warn("использован код восстановления", slog.String("op", op), slog.String("user_id", userID.String()))
+			return nil
+		}
+		if !errors.Is(err, custom_err.ErrNotFound) {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	lockedUntil, err := s.repo.RecordFailureTx(ctx, tx, userID, mfaMaxFailedAttempts, mfaLockDuration)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if lockedUntil != nil && lockedUntil.After(now) {
+		s.log.Warn("проверка кодов заблокирована после серии неверных попыток",
+			slog.String("op", op),
+			slog.String("user_id", userID.String()),
+			slog.Time("locked_until", *lockedUntil))
+	}
+	return custom_err.ErrInvalidMFACode
+}
+
+func (s *MFAService) requireEnabledTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	mfa, err := s.repo.GetForUpdateTx(ctx, tx, userID)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return custom_err.ErrMFANotEnabled
+		}
+		return fmt.Errorf("failed to get mfa settings: %w", err)
+	}
+	if !mfa.Enabled() {
+		return custom_err.ErrMFANotEnabled
+	}
+	return nil
+}
+
+// newRecoveryCodes коды восстановления вида xxxxx-xxxxx (50 бит) и их хэши для хранения
+func newRecoveryCodes() ([]string, []string) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := strings.ToLower(rand.Text()[:10])
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes
+}
+
+// normalizeRecoveryCode приводит введенный код к виду, в котором хранится его хэш
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/secretbox"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+
+func setupMFAService() (*MFAService, *MockMFARepository, *MockUserRepository, *MockTxManager) {
+	repo := new(MockMFARepository)
+	userRepo := new(MockUserRepository)
+	txManager := new(MockTxManager)
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	return NewMFAService(repo, userRepo, txManager, newMockAuditRecorder(), newTestSecretBox(), "GW Wallet", 5*time.Minute, log), repo, userRepo, txManager
+}
+
+// currentTOTP код текущего интервала для testTOTPSecret
+func currentTOTP(t *testing.T) (string, int64) {
+	t.Helper()
+
+	key, err := totpEncoding.DecodeString(testTOTPSecret)
+	require.NoError(t, err)
+	step := totpStep(time.Now())
+	return totpCode(key, step, totpDigits), step
+}
+
+// enabledMFA включенный TOTP с зашифрованным testTOTPSecret
+func enabledMFA(userID uuid.UUID) *models.UserMFA {
+	sealed, err := newTestSecretBox().Seal(testTOTPSecret, userID.String())
+	if err != nil {
+		panic(err)
+	}
+	enabledAt := time.Now().Add(-24 * time.Hour)
+	return &models.UserMFA{UserID: userID, TOTPSecret: sealed, EnabledAt: &enabledAt}
+}
+
+func TestMFAService_EnrollTOTP(t *testing.T) {
+	service, repo, userRepo, _ := setupMFAService()
+	ctx := context.Background()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.User{ID: uuid.New(), Username: "alice", PasswordHash: string(hash)}
+	userRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+
+	_, err = service.EnrollTOTP(ctx, user.ID, models.TOTPEnrollRequest{Password: "wrong"})
+	assert.ErrorIs(t, err, custom_err.ErrInvalidCredentials)
+	repo.AssertNotCalled(t, "SavePending", mock.Anything, mock.Anything, mock.Anything)
+
+	repo.On("SavePending", ctx, user.ID, mock.AnythingOfType("string")).Return(nil)
+
+	resp, err := service.EnrollTOTP(ctx, user.ID, models.TOTPEnrollRequest{Password: "password123"})
+	require.NoError(t, err)
+	_, err = totpEncoding.DecodeString(resp.Secret)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resp.OTPAuthURI, "otpauth://totp/GW%20Wallet:alice?"))
+	assert.Contains(t, resp.OTPAuthURI, "secret="+resp.Secret)
+	// Секрет сохраняется зашифрованным и привязанным к пользователю
+	saved := repo.Calls[len(repo.Calls)-1].Arguments.String(2)
+	assert.NotContains(t, saved, resp.Secret)
+	opened, err := newTestSecretBox().Open(saved, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, resp.Secret, opened)
+}
+
+func TestMFAService_SealPlaintextSecrets(t *testing.T) {
+	service, repo, _, _ := setupMFAService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("ListUnsealedSecrets", ctx, secretbox.SealedPrefix).
+		Return([]models.UserMFA{{UserID: userID, TOTPSecret: testTOTPSecret}}, nil)
+	repo.On("ReplaceSecret", ctx, userID, testTOTPSecret, mock.MatchedBy(func(sealed string) bool {
+		opened, err := newTestSecretBox().Open(sealed, userID.String())
+		return err == nil && opened == testTOTPSecret
+	})).Return(nil).Once()
+
+	require.NoError(t, service.SealPlaintextSecrets(ctx))
+	repo.AssertExpectations(t)
+}
+
+func TestMFAService_ConfirmTOTP(t *testing.T) {
+	service, repo, _, txManager := setupMFAService()
+	ctx := context.Background()
+	userID := uuid.New()
+	code, step := currentTOTP(t)
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	repo.On("GetForUpdateTx", ctx, mock.Anything, userID).
+		Return(&models.UserMFA{UserID: userID, TOTPSecret: testTOTPSecret}, nil)
+	repo.On("EnableTx", ctx, mock.Anything, userID, step).Return(nil)
+	repo.On("ReplaceRecoveryCodesTx", ctx, mock.Anything, userID, mock.MatchedBy(func(hashes []string) bool {
+		return len(hashes) == recoveryCodeCount
+	})).Return(nil)
+
+	resp, err := service.ConfirmTOTP(ctx, userID, models.MFACodeRequest{Code: code})
+	require.NoError(t, err)
+	require.Len(t, resp.RecoveryCodes, recoveryCodeCount)
+
+	// Сохраняются хэши кодов, а не сами коды
+	hashes := repo.Calls[len(repo.Calls)-1].Arguments.Get(3).([]string)
+	assert.Equal(t, hashToken(normalizeRecoveryCode(resp.RecoveryCodes[0])), hashes[0])
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, resp.RecoveryCodes[0])
+}
+
+func TestMFAService_ConfirmTOTP_Rejected(t *testing.T) {
+	userID := uuid.New()
+	enabled := enabledMFA(userID)
+
+	tests := []struct {
+		name    string
+		mfa     *models.UserMFA
+		repoErr error
+		code    string
+		wantErr error
+	}{
+		{name: "not enrolled", repoErr: custom_err.ErrNotFound, code: "123456", wantErr: custom_err.ErrMFANotEnrolled},
+		{name: "already enabled", mfa: enabled, code: "123456", wantErr: custom_err.ErrMFAAlreadyEnabled},
+		{name: "wrong code", mfa: &models.UserMFA{UserID: userID, TOTPSecret: testTOTPSecret}, code: "abcdef", wantErr: custom_err.ErrInvalidMFACode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo, _, txManager := setupMFAService()
+			ctx := context.Background()
+
+			txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+			repo.On("GetForUpdateTx", ctx, mock.Anything, userID).Return(tt.mfa, tt.repoErr)
+
+			resp, err := service.ConfirmTOTP(ctx, userID, models.MFACodeRequest{Code: tt.code})
+
+			assert.Nil(t, resp)
+			assert.ErrorIs(t, err, tt.wantErr)
+			repo.AssertNotCalled(t, "EnableTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestMFAService_VerifyTx_TOTP(t *testing.T) {
+	service, repo, _, _ := setupMFAService()
+	ctx := context.Background()
+	userID := uuid.New()
+	code, step := currentTOTP(t)
+
+	repo.On("GetForUpdateTx", ctx, mock.Anything, userID).Return(enabledMFA(userID), nil)
+	repo.On("RecordSuccessTx", ctx, mock.Anything, userID, step).Return(nil)
+
+	assert.NoError(t, service.VerifyTx(ctx, nil, userID, " "+code+" "))
+	repo.AssertNotCalled(t, "RecordFailureTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMFAService_VerifyTx_ReplayedTOTP(t *testing.T) {
+	service, repo, _, _ := setupMFAService()
+	ctx := context.Background()
+	userID := uuid.New()
+	code, step := currentTOTP(t)
+
+	mfa := enabledMFA(userID)
+	mfa.LastUsedStep = step + totpSkew
+	repo.On("GetForUpdateTx", ctx, mock.Anything, userID).Return(mfa, nil)
+	repo.On("RecordFailureTx", ctx, mock.Anything, userID, mfaMaxFailedAttempts, mfaLockDuration).Return(nil, nil)
+
+	assert.ErrorIs(t, service.VerifyTx(ctx, nil, userID, code), custom_err.ErrInvalidMFACode)
+	repo.AssertNotCalled(t, "RecordSuccessTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMFAService_VerifyTx_RecoveryCode(t *testing.T) {
+	service, repo, _, _ := setupMFAService()
+	ctx := context.Background()
+	userID := uuid.New()
+	mfa := enabledMFA(userID)
+	mfa.LastUsedStep = 42
+
+	repo.On("GetForUpdateTx", ctx, mock.Anything, userID).Return(mfa, nil)
+	repo.On("ConsumeRecoveryCodeTx", ctx, mock.Anything, userID, hashToken("k7m2p9xq4d")).Return(nil)
+	repo.On("RecordSuccessTx", ctx, mock.Anything, userID, int64(42)).Return(nil)
+
+	assert.NoError(t, service.VerifyTx(ctx, nil, userID, "K7M2P-9XQ4D"))
+	repo.AssertExpectations(t)
+}
+
+func TestMFAService_VerifyTx_Rejected(t *testing.T) {
+	userID := uuid.New()
+	lockedUntil := time.Now().Add(time.Minute)
+
+	t.Run("unknown recovery code counts as failure", func(t *testing.T) {
+		service, repo, _, _ := setupMFAService()
+		ctx := context.Background()
+
+		repo.On("GetForUpdateTx", ctx, mock.Anything, userID).Return(enabledMFA(userID), nil)
+		repo.On("ConsumeRecoveryCodeTx", ctx, mock.Anything, userID, mock.Anything).Return(custom_err.ErrNotFound)
+		repo.On("RecordFailureTx", ctx, mock.Anything, userID, mfaMaxFailedAttempts, mfaLockDuration).Return(&lockedUntil, nil)
+
+		assert.ErrorIs(t, service.VerifyTx(ctx, nil, userID, "aaaaa-bbbbb"), custom_err.ErrInvalidMFACode)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		service, repo, _, _ := setupMFAService()
+		ctx := context.Background()
+		code, _ := currentTOTP(t)
+
+		mfa := enabledMFA(userID)
+		mfa.LockedUntil = &lockedUntil
+		repo.On("GetForUpdateTx", ctx, mock.Anything, userID).Return(mfa, nil)
+
+		assert.ErrorIs(t, service.VerifyTx(ctx, nil, userID, code), custom_err.ErrMFALocked)
+		repo.AssertNotCalled(t, "RecordSuccessTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		service, repo, _, _ := setupMFAService()
+		ctx := context.Background()
+
+		repo.On("GetForUpdateTx", ctx, mock.Anything, userID).
+			Return(&models.UserMFA{UserID: userID, TOTPSecret: testTOTPSecret}, nil)
+
+		assert.ErrorIs(t, service.VerifyTx(ctx, nil, userID, "123456"), custom_err.ErrMFANotEnabled)
+	})
+}
+
+func TestMFAService_CompleteChallengeTx(t *testing.T) {
+	userID := uuid.New()
+	usedAt := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name      string
+		challenge *models.MFAChallenge
+		repoErr   error
+		wantErr   error
+	}{
+		{name: "valid", challenge: &models.MFAChallenge{ID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(time.Minute)}},
+		{name: "unknown", repoErr: custom_err.ErrNotFound, wantErr: custom_err.ErrInvalidToken},
+		{name: "expired", challenge: &models.MFAChallenge{ID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(-time.Second)},
+			wantErr: custom_err.ErrInvalidToken},
+		{name: "used", challenge: &models.MFAChallenge{ID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(time.Minute), UsedAt: &usedAt},
+			wantErr: custom_err.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repo, _, _ := setupMFAService()
+			ctx := context.Background()
+			code, step := currentTOTP(t)
+
+			repo.On("GetChallengeForUpdateTx", ctx, mock.Anything, hashToken("challenge")).Return(tt.challenge, tt.repoErr)
+			repo.On("GetForUpdateTx", ctx, mock.Anything, userID).Return(enabledMFA(userID), nil).Maybe()
+			repo.On("RecordSuccessTx", ctx, mock.Anything, userID, step).Return(nil).Maybe()
+			if tt.challenge != nil {
+				repo.On("MarkChallengeUsedTx", ctx, mock.Anything, tt.challenge.ID).Return(nil).Maybe()
+			}
+
+			got, err := service.CompleteChallengeTx(ctx, nil, "challenge", code)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "RecordSuccessTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, userID, got)
+			repo.AssertCalled(t, "MarkChallengeUsedTx", ctx, mock.Anything, tt.challenge.ID)
+		})
+	}
+}
+
+func TestMFAService_Status(t *testing.T) {
+	service, repo, _, _ := setupMFAService()
+	ctx := context.Background()
+	enrolled, pending, none := uuid.New(), uuid.New(), uuid.New()
+
+	repo.On("Get", ctx, enrolled).Return(enabledMFA(enrolled), nil)
+	repo.On("Get", ctx, pending).Return(&models.UserMFA{UserID: pending, TOTPSecret: testTOTPSecret}, nil)
+	repo.On("Get", ctx, none).Return(nil, custom_err.ErrNotFound)
+	repo.On("CountRecoveryCodes", ctx, enrolled).Return(7, nil)
+
+	status, err := service.Status(ctx, enrolled)
+	require.NoError(t, err)
+	assert.Equal(t, &models.MFAStatusResponse{Enabled: true, RecoveryCodesRemaining: 7}, status)
+
+	for _, userID := range []uuid.UUID{pending, none} {
+		status, err := service.Status(ctx, userID)
+		require.NoError(t, err)
+		assert.False(t, status.Enabled)
+	}
+}
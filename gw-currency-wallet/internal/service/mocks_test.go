@@ -2,13 +2,20 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"net/netip"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 
+	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/grpc_client"
+	"gw-currency-wallet/internal/mailer"
 	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/secretbox"
 )
 
 type MockUserRepository struct {
@@ -65,6 +72,45 @@ func (m *MockUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) Search(ctx context.Context, filter models.UserSearchFilter) ([]*models.User, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) SetRoleTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, role models.Role) (*models.User, error) {
+	args := m.Called(ctx, tx, id, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) MarkEmailVerifiedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePasswordTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, tx, id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) LockTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, until time.Time) error {
+	args := m.Called(ctx, tx, id, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UnlockTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, tx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 type MockWalletRepo struct {
 	mock.Mock
 }
@@ -77,15 +123,20 @@ func (m *MockWalletRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Wal
 	return args.Get(0).(*models.Wallet), args.Error(1)
 }
 
-func (m *MockWalletRepo) CreateWalletTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
-	args := m.Called(ctx, tx, userID, currency)
+func (m *MockWalletRepo) CreateWalletTx(ctx context.Context, tx pgx.Tx, wallet *models.Wallet) error {
+	args := m.Called(ctx, tx, wallet)
+	return args.Error(0)
+}
+
+func (m *MockWalletRepo) GetByUserAndCurrency(ctx context.Context, userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+	args := m.Called(ctx, userID, currency)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.Wallet), args.Error(1)
 }
 
-func (m *MockWalletRepo) GetByUserAndCurrency(ctx context.Context, userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+func (m *MockWalletRepo) GetOrCreateByUserAndCurrency(ctx context.Context, userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
 	args := m.Called(ctx, userID, currency)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -93,6 +144,14 @@ func (m *MockWalletRepo) GetByUserAndCurrency(ctx context.Context, userID uuid.U
 	return args.Get(0).(*models.Wallet), args.Error(1)
 }
 
+func (m *MockWalletRepo) GetOrCreateByUserAndCurrencyTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+	args := m.Called(ctx, tx, userID, currency)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
 func (m *MockWalletRepo) GetUserWallets(ctx context.Context, userID uuid.UUID) ([]models.Wallet, error) {
 	args := m.Called(ctx, userID)
 	if args.Get(0) == nil {
@@ -101,18 +160,29 @@ func (m *MockWalletRepo) GetUserWallets(ctx context.Context, userID uuid.UUID) (
 	return args.Get(0).([]models.Wallet), args.Error(1)
 }
 
-func (m *MockWalletRepo) UpdateBalanceTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, newBalance int64) error {
-	args := m.Called(ctx, tx, walletID, newBalance)
-	return args.Error(0)
+func (m *MockWalletRepo) GetWalletBalanceForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (models.WalletBalance, error) {
+	args := m.Called(ctx, tx, walletID)
+	return args.Get(0).(models.WalletBalance), args.Error(1)
 }
 
-func (m *MockWalletRepo) GetWalletBalanceForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (int64, error) {
+func (m *MockWalletRepo) GetWalletForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (*models.Wallet, error) {
 	args := m.Called(ctx, tx, walletID)
-	return args.Get(0).(int64), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
+}
+
+func (m *MockWalletRepo) SetFrozenTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, frozen bool, reason string) (*models.Wallet, error) {
+	args := m.Called(ctx, tx, walletID, frozen, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Wallet), args.Error(1)
 }
 
-func (m *MockWalletRepo) CreateOperationTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, amount int64, requestID string) error {
-	args := m.Called(ctx, tx, walletID, amount, requestID)
+func (m *MockWalletRepo) CreateOperationTx(ctx context.Context, tx pgx.Tx, op models.Operation) error {
+	args := m.Called(ctx, tx, op)
 	return args.Error(0)
 }
 
@@ -129,6 +199,153 @@ func (m *MockWalletRepo) GetAllUserWallets(ctx context.Context, userID uuid.UUID
 	return args.Get(0).([]*models.Wallet), args.Error(1)
 }
 
+func (m *MockWalletRepo) ExchangeOperationExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	args := m.Called(ctx, tx, requestID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWalletRepo) CreateExchangeOperationTx(ctx context.Context, tx pgx.Tx, op models.ExchangeOperation) error {
+	args := m.Called(ctx, tx, op)
+	return args.Error(0)
+}
+
+func (m *MockWalletRepo) ListTransactions(ctx context.Context, userID uuid.UUID, filter models.TransactionFilter) ([]*models.TransactionRecord, error) {
+	args := m.Called(ctx, userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.TransactionRecord), args.Error(1)
+}
+
+func (m *MockWalletRepo) TransferExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	args := m.Called(ctx, tx, requestID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWalletRepo) CreateTransferTx(ctx context.Context, tx pgx.Tx, transfer models.Transfer) error {
+	args := m.Called(ctx, tx, transfer)
+	return args.Error(0)
+}
+
+type MockLedgerRepo struct {
+	mock.Mock
+}
+
+func (m *MockLedgerRepo) GetSystemAccountIDTx(ctx context.Context, tx pgx.Tx, code string, currency string) (uuid.UUID, error) {
+	args := m.Called(ctx, tx, code, currency)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockLedgerRepo) PostEntryTx(ctx context.Context, tx pgx.Tx, entry models.JournalEntry) error {
+	args := m.Called(ctx, tx, entry)
+	return args.Error(0)
+}
+
+func (m *MockLedgerRepo) GetAccountBalance(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) CreateRefreshTokenTx(ctx context.Context, tx pgx.Tx, token *models.RefreshToken) error {
+	args := m.Called(ctx, tx, token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) GetRefreshTokenForUpdateTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(ctx, tx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockTokenRepository) MarkRefreshTokenUsedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeRefreshTokenFamilyTx(ctx context.Context, tx pgx.Tx, familyID uuid.UUID) error {
+	args := m.Called(ctx, tx, familyID)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeRefreshTokenFamilyByToken(ctx context.Context, userID uuid.UUID, tokenHash string) error {
+	args := m.Called(ctx, userID, tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeUserRefreshTokensTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	args := m.Called(ctx, tx, userID)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeAccessToken(ctx context.Context, jti uuid.UUID, userID uuid.UUID, expiresAt time.Time) error {
+	args := m.Called(ctx, jti, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockSigningKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockSigningKeyRepository) ListValid(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]models.SigningKey), args.Error(1)
+}
+
+func (m *MockSigningKeyRepository) ListValidTx(ctx context.Context, tx pgx.Tx, now time.Time) ([]models.SigningKey, error) {
+	args := m.Called(ctx, tx, now)
+	return args.Get(0).([]models.SigningKey), args.Error(1)
+}
+
+func (m *MockSigningKeyRepository) CreateTx(ctx context.Context, tx pgx.Tx, key models.SigningKey) error {
+	args := m.Called(ctx, tx, key)
+	return args.Error(0)
+}
+
+func (m *MockSigningKeyRepository) UpdatePrivateKeyTx(ctx context.Context, tx pgx.Tx, kid, privateKey string) error {
+	args := m.Called(ctx, tx, kid, privateKey)
+	return args.Error(0)
+}
+
+func (m *MockSigningKeyRepository) DeleteExpiredTx(ctx context.Context, tx pgx.Tx, now time.Time) error {
+	args := m.Called(ctx, tx, now)
+	return args.Error(0)
+}
+
+func (m *MockSigningKeyRepository) LockRotationTx(ctx context.Context, tx pgx.Tx) error {
+	args := m.Called(ctx, tx)
+	return args.Error(0)
+}
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Create(ctx context.Context, entry models.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) CreateTx(ctx context.Context, tx pgx.Tx, entry models.AuditEntry) error {
+	args := m.Called(ctx, tx, entry)
+	return args.Error(0)
+}
+
 type MockTxManager struct {
 	mock.Mock
 }
@@ -161,6 +378,14 @@ func (m *MockExchangerClient) GetExchangeRateForCurrency(ctx context.Context, fr
 	return args.Get(0).(*grpc_client.ExchangeRateResponse), args.Error(1)
 }
 
+func (m *MockExchangerClient) GetCurrencies(ctx context.Context) ([]grpc_client.Currency, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]grpc_client.Currency), args.Error(1)
+}
+
 func (m *MockExchangerClient) Close() error {
 	args := m.Called()
 	return args.Error(0)
@@ -175,7 +400,811 @@ func (m *MockKafkaProducer) SendLargeTransferEvent(ctx context.Context, event mo
 	return args.Error(0)
 }
 
+func (m *MockKafkaProducer) SendReconciliationAlert(ctx context.Context, event models.ReconciliationAlertEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
 func (m *MockKafkaProducer) Close() error {
 	args := m.Called()
 	return args.Error(0)
 }
+
+type MockRateProvider struct {
+	mock.Mock
+}
+
+func (m *MockRateProvider) GetExchangeRate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) CreateTx(ctx context.Context, tx pgx.Tx, eventType, key string, payload []byte) error {
+	args := m.Called(ctx, tx, eventType, key, payload)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	args := m.Called(ctx, limit, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.OutboxMessage), args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkSent(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, retryIn time.Duration, reason string) error {
+	args := m.Called(ctx, id, retryIn, reason)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockQuoteRepository struct {
+	mock.Mock
+}
+
+func (m *MockQuoteRepository) Create(ctx context.Context, quote models.ExchangeQuote) error {
+	args := m.Called(ctx, quote)
+	return args.Error(0)
+}
+
+func (m *MockQuoteRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ExchangeQuote, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ExchangeQuote), args.Error(1)
+}
+
+func (m *MockQuoteRepository) UseTx(ctx context.Context, tx pgx.Tx, id, userID uuid.UUID, now time.Time) error {
+	args := m.Called(ctx, tx, id, userID, now)
+	return args.Error(0)
+}
+
+// largeTransferPayload сопоставляет сериализованное событие outbox с условием
+func largeTransferPayload(match func(models.LargeTransferEvent) bool) interface{} {
+	return mock.MatchedBy(func(payload []byte) bool {
+		var event models.LargeTransferEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return false
+		}
+		return match(event)
+	})
+}
+
+// staticCurrencyRegistry неизменяемый реестр валют для тестов
+type staticCurrencyRegistry []models.CurrencyInfo
+
+// newTestSecretBox шифрование секретов нулевым ключом
+func newTestSecretBox() *secretbox.Box {
+	box, err := secretbox.New(make([]byte, secretbox.KeySize))
+	if err != nil {
+		panic(err)
+	}
+	return box
+}
+
+func newTestCurrencyRegistry() staticCurrencyRegistry {
+	return staticCurrencyRegistry{
+		{Code: models.CurrencyEUR, Name: "Euro", Exponent: 2, Enabled: true},
+		{Code: models.CurrencyRUB, Name: "Russian Ruble", Exponent: 2, Enabled: true},
+		{Code: models.CurrencyUSD, Name: "US Dollar", Exponent: 2, Enabled: true},
+	}
+}
+
+func (r staticCurrencyRegistry) List(ctx context.Context) ([]models.CurrencyInfo, error) {
+	return r, nil
+}
+
+func (r staticCurrencyRegistry) Get(ctx context.Context, code models.Currency) (*models.CurrencyInfo, error) {
+	for _, c := range r {
+		if c.Code == code {
+			return &c, nil
+		}
+	}
+	return nil, custom_err.ErrInvalidCurrency
+}
+
+// staticFeeSchedule неизменяемые правила комиссий для тестов; пустой набор - обмен без комиссий
+type staticFeeSchedule map[[2]string]models.ExchangePricing
+
+func (f staticFeeSchedule) Pricing(ctx context.Context, from, to models.Currency) (models.ExchangePricing, error) {
+	return matchPricing(f, string(from), string(to)), nil
+}
+
+type MockFeeRepository struct {
+	mock.Mock
+}
+
+func (m *MockFeeRepository) ListPricing(ctx context.Context) ([]models.ExchangePricing, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ExchangePricing), args.Error(1)
+}
+
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, ttl, lockTimeout time.Duration) (bool, error) {
+	args := m.Called(ctx, userID, key, fingerprint, ttl, lockTimeout)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Get(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	args := m.Called(ctx, userID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IdempotencyRecord), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) SaveResponse(ctx context.Context, userID uuid.UUID, key string, status int, contentType string, body []byte) error {
+	args := m.Called(ctx, userID, key, status, contentType, body)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	args := m.Called(ctx, userID, key)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// stubLimits результат проверки лимитов для тестов; nil err - операция укладывается в лимиты
+type stubLimits struct {
+	err error
+}
+
+func (l stubLimits) CheckTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency models.Currency, amount int64) error {
+	return l.err
+}
+
+type MockLimitRepository struct {
+	mock.Mock
+}
+
+func (m *MockLimitRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.TransactionLimit, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TransactionLimit), args.Error(1)
+}
+
+func (m *MockLimitRepository) ListForOperationTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string) ([]models.TransactionLimit, error) {
+	args := m.Called(ctx, tx, userID, opType, currency)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.TransactionLimit), args.Error(1)
+}
+
+func (m *MockLimitRepository) SumOperationsTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string, window time.Duration) (int64, error) {
+	args := m.Called(ctx, tx, userID, opType, currency, window)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLimitRepository) UpsertOverrideTx(ctx context.Context, tx pgx.Tx, limit models.TransactionLimit) error {
+	args := m.Called(ctx, tx, limit)
+	return args.Error(0)
+}
+
+func (m *MockLimitRepository) DeleteOverrideTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string, period models.LimitPeriod) error {
+	args := m.Called(ctx, tx, userID, opType, currency, period)
+	return args.Error(0)
+}
+
+type MockHoldRepository struct {
+	mock.Mock
+}
+
+func (m *MockHoldRepository) HoldExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	args := m.Called(ctx, tx, requestID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockHoldRepository) CreateTx(ctx context.Context, tx pgx.Tx, hold models.Hold) (*models.Hold, error) {
+	args := m.Called(ctx, tx, hold)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Hold), args.Error(1)
+}
+
+func (m *MockHoldRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Hold), args.Error(1)
+}
+
+func (m *MockHoldRepository) CloseTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, status models.HoldStatus, captured int64, now time.Time) (*models.Hold, error) {
+	args := m.Called(ctx, tx, id, status, captured, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Hold), args.Error(1)
+}
+
+func (m *MockHoldRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]models.Hold, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Hold), args.Error(1)
+}
+
+type MockLimitOrderRepository struct {
+	mock.Mock
+}
+
+func (m *MockLimitOrderRepository) OrderExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	args := m.Called(ctx, tx, requestID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLimitOrderRepository) CreateTx(ctx context.Context, tx pgx.Tx, order models.LimitOrder) (*models.LimitOrder, error) {
+	args := m.Called(ctx, tx, order)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LimitOrder), args.Error(1)
+}
+
+func (m *MockLimitOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.LimitOrder, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LimitOrder), args.Error(1)
+}
+
+func (m *MockLimitOrderRepository) ListByUser(ctx context.Context, userID uuid.UUID, status models.LimitOrderStatus, limit int) ([]models.LimitOrder, error) {
+	args := m.Called(ctx, userID, status, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LimitOrder), args.Error(1)
+}
+
+func (m *MockLimitOrderRepository) ListOpen(ctx context.Context, now time.Time, after uuid.UUID, limit int) ([]models.LimitOrder, error) {
+	args := m.Called(ctx, now, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LimitOrder), args.Error(1)
+}
+
+func (m *MockLimitOrderRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]models.LimitOrder, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.LimitOrder), args.Error(1)
+}
+
+func (m *MockLimitOrderRepository) CloseTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, status models.LimitOrderStatus, fill models.LimitOrderFill, now time.Time) (*models.LimitOrder, error) {
+	args := m.Called(ctx, tx, id, status, fill, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LimitOrder), args.Error(1)
+}
+
+type MockScheduledExchangeRepository struct {
+	mock.Mock
+}
+
+func (m *MockScheduledExchangeRepository) ScheduleExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	args := m.Called(ctx, tx, requestID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockScheduledExchangeRepository) CreateTx(ctx context.Context, tx pgx.Tx, schedule models.ScheduledExchange) (*models.ScheduledExchange, error) {
+	args := m.Called(ctx, tx, schedule)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledExchange), args.Error(1)
+}
+
+func (m *MockScheduledExchangeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ScheduledExchange, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledExchange), args.Error(1)
+}
+
+func (m *MockScheduledExchangeRepository) ListByUser(ctx context.Context, userID uuid.UUID, status models.ScheduledExchangeStatus, limit int) ([]models.ScheduledExchange, error) {
+	args := m.Called(ctx, userID, status, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ScheduledExchange), args.Error(1)
+}
+
+func (m *MockScheduledExchangeRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.ScheduledExchange, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ScheduledExchange), args.Error(1)
+}
+
+func (m *MockScheduledExchangeRepository) AdvanceTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, scheduledFor time.Time, next *time.Time) error {
+	args := m.Called(ctx, tx, id, scheduledFor, next)
+	return args.Error(0)
+}
+
+func (m *MockScheduledExchangeRepository) Cancel(ctx context.Context, id uuid.UUID) (*models.ScheduledExchange, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ScheduledExchange), args.Error(1)
+}
+
+func (m *MockScheduledExchangeRepository) CreateRunTx(ctx context.Context, tx pgx.Tx, run models.ScheduledExchangeRun) error {
+	args := m.Called(ctx, tx, run)
+	return args.Error(0)
+}
+
+func (m *MockScheduledExchangeRepository) ListRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]models.ScheduledExchangeRun, error) {
+	args := m.Called(ctx, scheduleID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ScheduledExchangeRun), args.Error(1)
+}
+
+type MockReconciliationRepository struct {
+	mock.Mock
+}
+
+func (m *MockReconciliationRepository) FailStaleReportsTx(ctx context.Context, tx pgx.Tx, staleAfter time.Duration) (int64, error) {
+	args := m.Called(ctx, tx, staleAfter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) CreateReportTx(ctx context.Context, tx pgx.Tx, report models.ReconciliationReport) (*models.ReconciliationReport, error) {
+	args := m.Called(ctx, tx, report)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReconciliationReport), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) UpdateProgress(ctx context.Context, id uuid.UUID, walletsChecked, discrepancyCount int64) error {
+	args := m.Called(ctx, id, walletsChecked, discrepancyCount)
+	return args.Error(0)
+}
+
+func (m *MockReconciliationRepository) FinishReportTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+	status models.ReconciliationStatus,
+	walletsChecked, discrepancyCount int64,
+	errMsg string,
+) (*models.ReconciliationReport, error) {
+	args := m.Called(ctx, tx, id, status, walletsChecked, discrepancyCount, errMsg)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReconciliationReport), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) GetReport(ctx context.Context, id uuid.UUID) (*models.ReconciliationReport, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReconciliationReport), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) ListReports(ctx context.Context, limit int) ([]models.ReconciliationReport, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ReconciliationReport), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) ScanWallets(ctx context.Context, after uuid.UUID, limit int) ([]models.WalletReconciliation, error) {
+	args := m.Called(ctx, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WalletReconciliation), args.Error(1)
+}
+
+func (m *MockReconciliationRepository) CreateDiscrepancies(ctx context.Context, reportID uuid.UUID, wallets []models.WalletReconciliation) error {
+	args := m.Called(ctx, reportID, wallets)
+	return args.Error(0)
+}
+
+// StreamDiscrepancies передает в fn строки, заданные первым аргументом Return
+func (m *MockReconciliationRepository) StreamDiscrepancies(
+	ctx context.Context,
+	reportID uuid.UUID,
+	fn func(models.WalletReconciliation) error,
+) error {
+	args := m.Called(ctx, reportID, fn)
+	if rows, ok := args.Get(0).([]models.WalletReconciliation); ok {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+type MockAccountTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountTokenRepository) CreateTx(ctx context.Context, tx pgx.Tx, token *models.AccountToken) error {
+	args := m.Called(ctx, tx, token)
+	return args.Error(0)
+}
+
+func (m *MockAccountTokenRepository) InvalidateTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, purpose models.AccountTokenPurpose) error {
+	args := m.Called(ctx, tx, userID, purpose)
+	return args.Error(0)
+}
+
+func (m *MockAccountTokenRepository) ConsumeTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	tokenHash string,
+	purpose models.AccountTokenPurpose,
+) (*models.AccountToken, error) {
+	args := m.Called(ctx, tx, tokenHash, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountToken), args.Error(1)
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+type MockVerificationSender struct {
+	mock.Mock
+}
+
+func (m *MockVerificationSender) SendVerification(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserMFA), args.Error(1)
+}
+
+func (m *MockMFARepository) GetForUpdateTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (*models.UserMFA, error) {
+	args := m.Called(ctx, tx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserMFA), args.Error(1)
+}
+
+func (m *MockMFARepository) SavePending(ctx context.Context, userID uuid.UUID, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ListUnsealedSecrets(ctx context.Context, sealedPrefix string) ([]models.UserMFA, error) {
+	args := m.Called(ctx, sealedPrefix)
+	return args.Get(0).([]models.UserMFA), args.Error(1)
+}
+
+func (m *MockMFARepository) ReplaceSecret(ctx context.Context, userID uuid.UUID, old, secret string) error {
+	args := m.Called(ctx, userID, old, secret)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) EnableTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, step int64) error {
+	args := m.Called(ctx, tx, userID, step)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) RecordSuccessTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, step int64) error {
+	args := m.Called(ctx, tx, userID, step)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) RecordFailureTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	maxAttempts int,
+	lockFor time.Duration,
+) (*time.Time, error) {
+	args := m.Called(ctx, tx, userID, maxAttempts, lockFor)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockMFARepository) DeleteTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	args := m.Called(ctx, tx, userID)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ReplaceRecoveryCodesTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHashes []string) error {
+	args := m.Called(ctx, tx, userID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ConsumeRecoveryCodeTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHash string) error {
+	args := m.Called(ctx, tx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockMFARepository) CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) GetChallengeForUpdateTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.MFAChallenge, error) {
+	args := m.Called(ctx, tx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MFAChallenge), args.Error(1)
+}
+
+func (m *MockMFARepository) MarkChallengeUsedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	args := m.Called(ctx, tx, id)
+	return args.Error(0)
+}
+
+type MockSecondFactor struct {
+	mock.Mock
+}
+
+func (m *MockSecondFactor) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSecondFactor) NewChallenge(ctx context.Context, userID uuid.UUID) (string, time.Time, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Get(1).(time.Time), args.Error(2)
+}
+
+func (m *MockSecondFactor) CompleteChallengeTx(ctx context.Context, tx pgx.Tx, challengeToken, code string) (uuid.UUID, error) {
+	args := m.Called(ctx, tx, challengeToken, code)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockSecondFactor) VerifyTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, code string) error {
+	args := m.Called(ctx, tx, userID, code)
+	return args.Error(0)
+}
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key models.APIKey, maxActive int) error {
+	args := m.Called(ctx, key, maxActive)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id, userID uuid.UUID) (*models.APIKey, error) {
+	args := m.Called(ctx, id, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Touch(ctx context.Context, id uuid.UUID, ip netip.Addr) error {
+	args := m.Called(ctx, id, ip)
+	return args.Error(0)
+}
+
+type MockAPIKeyVerifier struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyVerifier) Verify(ctx context.Context, key string, ip netip.Addr) (*models.APIKey, error) {
+	args := m.Called(ctx, key, ip)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+type MockLoginThrottleRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginThrottleRepository) BlockedUntil(ctx context.Context, usernameKey, ipKey string) (*time.Time, error) {
+	args := m.Called(ctx, usernameKey, ipKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockLoginThrottleRepository) RecordFailureTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	scope models.LoginThrottleScope,
+	key string,
+	window time.Duration,
+) (int, error) {
+	args := m.Called(ctx, tx, scope, key, window)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLoginThrottleRepository) BlockTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	scope models.LoginThrottleScope,
+	key string,
+	delay time.Duration,
+) error {
+	args := m.Called(ctx, tx, scope, key, delay)
+	return args.Error(0)
+}
+
+func (m *MockLoginThrottleRepository) Reset(ctx context.Context, scope models.LoginThrottleScope, key string, window time.Duration) error {
+	args := m.Called(ctx, scope, key, window)
+	return args.Error(0)
+}
+
+func (m *MockLoginThrottleRepository) CreateFailureTx(ctx context.Context, tx pgx.Tx, failure models.LoginFailure) error {
+	args := m.Called(ctx, tx, failure)
+	return args.Error(0)
+}
+
+type MockLoginGuard struct {
+	mock.Mock
+}
+
+func (m *MockLoginGuard) Check(ctx context.Context, username string, ip netip.Addr) error {
+	args := m.Called(ctx, username, ip)
+	return args.Error(0)
+}
+
+func (m *MockLoginGuard) RecordFailure(ctx context.Context, username string, user *models.User, ip netip.Addr, reason string) error {
+	args := m.Called(ctx, username, user, ip, reason)
+	return args.Error(0)
+}
+
+func (m *MockLoginGuard) RecordSuccess(ctx context.Context, username string) error {
+	args := m.Called(ctx, username)
+	return args.Error(0)
+}
+
+type MockAuditRecorder struct {
+	mock.Mock
+}
+
+func (m *MockAuditRecorder) Record(ctx context.Context, event models.AuditEvent) {
+	m.Called(ctx, event)
+}
+
+// newMockAuditRecorder журнал, принимающий любые события; тесты проверяют записанное через AssertCalled
+func newMockAuditRecorder() *MockAuditRecorder {
+	recorder := new(MockAuditRecorder)
+	recorder.On("Record", mock.Anything, mock.Anything).Maybe()
+	return recorder
+}
+
+// recordedAuditEvents события, переданные в журнал, в порядке записи
+func (m *MockAuditRecorder) recordedAuditEvents() []models.AuditEvent {
+	var events []models.AuditEvent
+	for _, call := range m.Calls {
+		if call.Method == "Record" {
+			events = append(events, call.Arguments.Get(1).(models.AuditEvent))
+		}
+	}
+	return events
+}
+
+type MockAuditEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditEventRepository) HeadTx(ctx context.Context, tx pgx.Tx) (string, error) {
+	args := m.Called(ctx, tx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuditEventRepository) CreateTx(ctx context.Context, tx pgx.Tx, event *models.AuditEvent) error {
+	args := m.Called(ctx, tx, event)
+	return args.Error(0)
+}
+
+func (m *MockAuditEventRepository) List(ctx context.Context, filter models.AuditEventFilter) ([]*models.AuditEvent, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AuditEvent), args.Error(1)
+}
+
+func (m *MockAuditEventRepository) Scan(ctx context.Context, afterSeq int64, limit int) ([]*models.AuditEvent, error) {
+	args := m.Called(ctx, afterSeq, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AuditEvent), args.Error(1)
+}
+
+type MockRateLimitStore struct {
+	mock.Mock
+}
+
+func (m *MockRateLimitStore) Take(ctx context.Context, key string, limit models.RateLimit) (float64, bool, error) {
+	args := m.Called(ctx, key, limit)
+	return args.Get(0).(float64), args.Bool(1), args.Error(2)
+}
+
+func (m *MockRateLimitStore) DeleteIdle(ctx context.Context, idle time.Duration) (int64, error) {
+	args := m.Called(ctx, idle)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/kafka"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	outboxPollInterval = time.Second
+	outboxBatchSize    = 20
+	outboxSendTimeout  = 5 * time.Second
+	// outboxLease время, на которое захваченная пачка скрыта от других экземпляров.
+	// Должно превышать худшее время отправки всей пачки, иначе сообщение может уйти дважды.
+	outboxLease = outboxBatchSize*outboxSendTimeout + 30*time.Second
+
+	outboxRetryBase = time.Second
+	outboxRetryMax  = 5 * time.Minute
+
+	outboxRetention       = 7 * 24 * time.Hour
+	outboxCleanupInterval = time.Hour
+)
+
+// enqueueLargeTransferTx записывает событие о крупной операции в outbox в транзакции операции
+func enqueueLargeTransferTx(ctx context.Context, outbox postgres.OutboxRepository, tx pgx.Tx, event models.LargeTransferEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+	if err := outbox.CreateTx(ctx, tx, models.OutboxEventLargeTransfer, event.TransactionID, payload); err != nil {
+		return fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+	return nil
+}
+
+// OutboxRelay отправляет события из таблицы outbox в Kafka.
+// Доставка at-least-once: событие может быть отправлено повторно, если экземпляр упал
+// между отправкой и отметкой, поэтому получатели дедуплицируют по ключу (transaction_id).
+type OutboxRelay struct {
+	repo     postgres.OutboxRepository
+	producer kafka.Producer
+	log      *slog.Logger
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func NewOutboxRelay(repo postgres.OutboxRepository, producer kafka.Producer, log *slog.Logger) *OutboxRelay {
+	return &OutboxRelay{
+		repo:     repo,
+		producer: producer,
+		log:      log,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start запускает фоновую отправку событий
+func (r *OutboxRelay) Start() {
+	r.wg.Add(1)
+	go r.relayLoop()
+}
+
+func (r *OutboxRelay) relayLoop() {
+	defer r.wg.Done()
+	r.log.Info("outbox relay запущен")
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+			r.drain()
+
+			if time.Since(lastCleanup) >= outboxCleanupInterval {
+				r.cleanup()
+				lastCleanup = time.Now()
+			}
+		case <-r.stopCh:
+			r.log.Info("outbox relay остановлен")
+			return
+		}
+	}
+}
+
+// drain отправляет пачки, пока в outbox есть готовые сообщения
+func (r *OutboxRelay) drain() {
+	for {
+		sent, err := r.RelayOnce(context.Background())
+		if err != nil {
+			r.log.Error("ошибка отправки outbox", slog.String("error", err.Error()))
+			return
+		}
+		if sent < outboxBatchSize {
+			return
+		}
+
+		select {
+		case <-r.stopCh:
+			return
+		default:
+		}
+	}
+}
+
+// RelayOnce захватывает одну пачку сообщений и отправляет их. Возвращает размер пачки.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	const op = "service.OutboxRelay.RelayOnce"
+
+	messages, err := r.repo.Claim(ctx, outboxBatchSize, outboxLease)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, msg := range messages {
+		sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+		err := r.publish(sendCtx, msg)
+		cancel()
+
+		if err != nil {
+			retryIn := outboxBackoff(msg.Attempts)
+			r.log.Warn("не удалось отправить событие outbox, повтор позже",
+				slog.String("id", msg.ID.String()),
+				slog.String("key", msg.Key),
+				slog.Int("attempts", msg.Attempts),
+				slog.Duration("retry_in", retryIn),
+				slog.String("error", err.Error()))
+
+			if err := r.repo.MarkFailed(ctx, msg.ID, retryIn, err.Error()); err != nil {
+				return len(messages), fmt.Errorf("%s: %w", op, err)
+			}
+			continue
+		}
+
+		if err := r.repo.MarkSent(ctx, msg.ID); err != nil {
+			return len(messages), fmt.Errorf("%s: %w", op, err)
+		}
+		r.log.Debug("событие outbox отправлено", slog.String("id", msg.ID.String()), slog.String("key", msg.Key))
+	}
+
+	return len(messages), nil
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, msg models.OutboxMessage) error {
+	switch msg.EventType {
+	case models.OutboxEventLargeTransfer:
+		var event models.LargeTransferEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return r.producer.SendLargeTransferEvent(ctx, event)
+	case models.OutboxEventReconciliationAlert:
+		var event models.ReconciliationAlertEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return r.producer.SendReconciliationAlert(ctx, event)
+	default:
+		return fmt.Errorf("unknown outbox event type %q", msg.EventType)
+	}
+}
+
+func (r *OutboxRelay) cleanup() {
+	deleted, err := r.repo.DeleteSentBefore(context.Background(), time.Now().Add(-outboxRetention))
+	if err != nil {
+		r.log.Error("ошибка очистки outbox", slog.String("error", err.Error()))
+		return
+	}
+	if deleted > 0 {
+		r.log.Info("удалены отправленные события outbox", slog.Int64("count", deleted))
+	}
+}
+
+func (r *OutboxRelay) Shutdown(ctx context.Context) error {
+	close(r.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// outboxBackoff экспоненциальная задержка перед попыткой attempts+1
+func outboxBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := outboxRetryBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxRetryMax {
+			return outboxRetryMax
+		}
+	}
+	return delay
+}
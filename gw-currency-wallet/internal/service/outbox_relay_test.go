@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/models"
+)
+
+func setupOutboxRelay() (*OutboxRelay, *MockOutboxRepository, *MockKafkaProducer) {
+	repo := new(MockOutboxRepository)
+	producer := new(MockKafkaProducer)
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	return NewOutboxRelay(repo, producer, log), repo, producer
+}
+
+func largeTransferMessage(t *testing.T, attempts int) (models.OutboxMessage, models.LargeTransferEvent) {
+	event := models.LargeTransferEvent{
+		TransactionID: "exchange-large-001",
+		Type:          string(models.OperationExchange),
+		UserID:        uuid.New(),
+		FromCurrency:  "USD",
+		ToCurrency:    "RUB",
+		Amount:        35000,
+		Timestamp:     time.Now().UTC().Truncate(time.Second),
+	}
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	return models.OutboxMessage{
+		ID:        uuid.New(),
+		EventType: models.OutboxEventLargeTransfer,
+		Key:       event.TransactionID,
+		Payload:   payload,
+		Attempts:  attempts,
+	}, event
+}
+
+func TestOutboxRelay_RelayOnce_MarksSent(t *testing.T) {
+	relay, repo, producer := setupOutboxRelay()
+	ctx := context.Background()
+
+	msg, event := largeTransferMessage(t, 1)
+
+	repo.On("Claim", ctx, outboxBatchSize, outboxLease).Return([]models.OutboxMessage{msg}, nil)
+	producer.On("SendLargeTransferEvent", mock.Anything, mock.MatchedBy(func(e models.LargeTransferEvent) bool {
+		return e.TransactionID == event.TransactionID && e.UserID == event.UserID && e.Amount == event.Amount
+	})).Return(nil)
+	repo.On("MarkSent", ctx, msg.ID).Return(nil)
+
+	n, err := relay.RelayOnce(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	repo.AssertExpectations(t)
+	producer.AssertExpectations(t)
+}
+
+func TestOutboxRelay_RelayOnce_SendFailureSchedulesRetry(t *testing.T) {
+	relay, repo, producer := setupOutboxRelay()
+	ctx := context.Background()
+
+	msg, _ := largeTransferMessage(t, 3)
+
+	repo.On("Claim", ctx, outboxBatchSize, outboxLease).Return([]models.OutboxMessage{msg}, nil)
+	producer.On("SendLargeTransferEvent", mock.Anything, mock.Anything).Return(errors.New("broker unavailable"))
+	repo.On("MarkFailed", ctx, msg.ID, 4*time.Second, "broker unavailable").Return(nil)
+
+	n, err := relay.RelayOnce(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "MarkSent", mock.Anything, mock.Anything)
+}
+
+func TestOutboxRelay_RelayOnce_UnknownEventType(t *testing.T) {
+	relay, repo, producer := setupOutboxRelay()
+	ctx := context.Background()
+
+	msg := models.OutboxMessage{ID: uuid.New(), EventType: "unknown", Key: "k", Payload: []byte(`{}`), Attempts: 1}
+
+	repo.On("Claim", ctx, outboxBatchSize, outboxLease).Return([]models.OutboxMessage{msg}, nil)
+	repo.On("MarkFailed", ctx, msg.ID, outboxRetryBase, mock.AnythingOfType("string")).Return(nil)
+
+	_, err := relay.RelayOnce(ctx)
+
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+	producer.AssertNotCalled(t, "SendLargeTransferEvent", mock.Anything, mock.Anything)
+}
+
+func TestOutboxRelay_RelayOnce_ClaimError(t *testing.T) {
+	relay, repo, _ := setupOutboxRelay()
+	ctx := context.Background()
+
+	repo.On("Claim", ctx, outboxBatchSize, outboxLease).Return(nil, errors.New("db down"))
+
+	n, err := relay.RelayOnce(ctx)
+
+	assert.Error(t, err)
+	assert.Zero(t, n)
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, outboxBackoff(0))
+	assert.Equal(t, time.Second, outboxBackoff(1))
+	assert.Equal(t, 2*time.Second, outboxBackoff(2))
+	assert.Equal(t, 8*time.Second, outboxBackoff(4))
+	assert.Equal(t, outboxRetryMax, outboxBackoff(50))
+}
+
+func TestOutboxRelay_ShutdownStopsLoop(t *testing.T) {
+	relay, repo, _ := setupOutboxRelay()
+
+	repo.On("Claim", mock.Anything, outboxBatchSize, outboxLease).Return([]models.OutboxMessage{}, nil).Maybe()
+
+	relay.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, relay.Shutdown(ctx))
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Action действие над ресурсом, которое проверяет политика доступа
+type Action string
+
+const (
+	// ActionRead просмотр ресурса: кошелька, баланса, истории операций
+	ActionRead Action = "read"
+	// ActionWrite изменение баланса: пополнение, вывод, обмен, перевод
+	ActionWrite Action = "write"
+)
+
+// Policy решает, может ли субъект токена выполнить действие над ресурсом другого пользователя.
+// При отказе возвращается custom_err.ErrNotFound, чтобы не раскрывать существование чужих ресурсов.
+type Policy interface {
+	AuthorizeWallet(actor *models.JWTClaims, wallet *models.Wallet, action Action) error
+	AuthorizeHold(actor *models.JWTClaims, hold *models.Hold, action Action) error
+	AuthorizeLimitOrder(actor *models.JWTClaims, order *models.LimitOrder, action Action) error
+	AuthorizeScheduledExchange(actor *models.JWTClaims, schedule *models.ScheduledExchange, action Action) error
+}
+
+// RolePolicy владелец имеет полный доступ к своим ресурсам, support и admin - доступ на чтение к чужим.
+// Изменение чужих балансов идет только через admin API с отдельным аудитом.
+type RolePolicy struct{}
+
+func NewPolicy() Policy {
+	return RolePolicy{}
+}
+
+func (p RolePolicy) AuthorizeWallet(actor *models.JWTClaims, wallet *models.Wallet, action Action) error {
+	return p.authorizeOwner(actor, wallet.UserID, action)
+}
+
+func (p RolePolicy) AuthorizeHold(actor *models.JWTClaims, hold *models.Hold, action Action) error {
+	return p.authorizeOwner(actor, hold.UserID, action)
+}
+
+func (p RolePolicy) AuthorizeLimitOrder(actor *models.JWTClaims, order *models.LimitOrder, action Action) error {
+	return p.authorizeOwner(actor, order.UserID, action)
+}
+
+func (p RolePolicy) AuthorizeScheduledExchange(actor *models.JWTClaims, schedule *models.ScheduledExchange, action Action) error {
+	return p.authorizeOwner(actor, schedule.UserID, action)
+}
+
+// authorizeOwner общее правило для ресурсов, принадлежащих пользователю ownerID
+func (RolePolicy) authorizeOwner(actor *models.JWTClaims, ownerID uuid.UUID, action Action) error {
+	if actor == nil {
+		return custom_err.ErrNotFound
+	}
+	if actor.UserID == ownerID {
+		return nil
+	}
+	if action == ActionRead && (actor.Role == models.RoleSupport || actor.Role == models.RoleAdmin) {
+		return nil
+	}
+	return custom_err.ErrNotFound
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+func TestRolePolicy_AuthorizeWallet(t *testing.T) {
+	owner := uuid.New()
+	wallet := &models.Wallet{ID: uuid.New(), UserID: owner, Currency: "USD"}
+
+	tests := []struct {
+		name    string
+		actor   *models.JWTClaims
+		action  Action
+		wantErr error
+	}{
+		{"owner reads", &models.JWTClaims{UserID: owner, Role: models.RoleUser}, ActionRead, nil},
+		{"owner writes", &models.JWTClaims{UserID: owner, Role: models.RoleUser}, ActionWrite, nil},
+		{"other user reads", &models.JWTClaims{UserID: uuid.New(), Role: models.RoleUser}, ActionRead, custom_err.ErrNotFound},
+		{"other user writes", &models.JWTClaims{UserID: uuid.New(), Role: models.RoleUser}, ActionWrite, custom_err.ErrNotFound},
+		{"support reads", &models.JWTClaims{UserID: uuid.New(), Role: models.RoleSupport}, ActionRead, nil},
+		{"support writes", &models.JWTClaims{UserID: uuid.New(), Role: models.RoleSupport}, ActionWrite, custom_err.ErrNotFound},
+		{"admin reads", &models.JWTClaims{UserID: uuid.New(), Role: models.RoleAdmin}, ActionRead, nil},
+		{"admin writes", &models.JWTClaims{UserID: uuid.New(), Role: models.RoleAdmin}, ActionWrite, custom_err.ErrNotFound},
+		{"no actor", nil, ActionRead, custom_err.ErrNotFound},
+	}
+
+	policy := NewPolicy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.AuthorizeWallet(tt.actor, wallet, tt.action)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWalletService_GetWalletByID_OtherUser(t *testing.T) {
+	service, repo, _, _ := setupWalletService()
+	ctx := context.Background()
+
+	wallet := &models.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: "USD", Balance: 500}
+	repo.On("GetByID", ctx, wallet.ID).Return(wallet, nil)
+
+	_, err := service.GetWalletByID(ctx, &models.JWTClaims{UserID: uuid.New(), Role: models.RoleUser}, wallet.ID)
+	assert.Equal(t, custom_err.ErrNotFound, err)
+
+	got, err := service.GetWalletByID(ctx, &models.JWTClaims{UserID: wallet.UserID, Role: models.RoleUser}, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, wallet, got)
+}
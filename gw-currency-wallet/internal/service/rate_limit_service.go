@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// rateLimitCleanupInterval как часто удаляются корзины, заполнившиеся до краев
+const rateLimitCleanupInterval = 10 * time.Minute
+
+// RateLimiter решает, можно ли обработать запрос клиента key по политике policy
+type RateLimiter interface {
+	// Allow берет токен из корзины клиента. Возвращает nil, если политика не настроена
+	// и запрос не ограничивается.
+	Allow(ctx context.Context, policy, key string) (*models.RateLimitResult, error)
+}
+
+// RateLimitStore хранилище корзин токенов. В памяти ограничение действует в пределах
+// одного экземпляра сервиса, postgres.RateLimitRepository делит корзины между репликами.
+type RateLimitStore interface {
+	// Take пополняет корзину key по правилу limit и берет из нее токен, если он есть.
+	// Возвращает остаток токенов и получил ли запрос токен.
+	Take(ctx context.Context, key string, limit models.RateLimit) (float64, bool, error)
+	// DeleteIdle удаляет корзины, не использовавшиеся дольше idle
+	DeleteIdle(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+type RateLimitService struct {
+	store    RateLimitStore
+	policies map[string]models.RateLimit
+	// idle через сколько простоя любая корзина заполнена и ее можно удалить
+	idle time.Duration
+	log  *slog.Logger
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func NewRateLimitService(store RateLimitStore, policies map[string]models.RateLimit, log *slog.Logger) *RateLimitService {
+	var idle time.Duration
+	for _, limit := range policies {
+		idle = max(idle, limit.FullAfter())
+	}
+	return &RateLimitService{
+		store:    store,
+		policies: policies,
+		idle:     idle,
+		log:      log,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (s *RateLimitService) Allow(ctx context.Context, policy, key string) (*models.RateLimitResult, error) {
+	const op = "service.RateLimitService.Allow"
+
+	limit, ok := s.policies[policy]
+	if !ok {
+		return nil, nil
+	}
+
+	tokens, allowed, err := s.store.Take(ctx, policy+":"+key, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	result := limit.Result(tokens, allowed)
+	return &result, nil
+}
+
+func (s *RateLimitService) Start() {
+	s.wg.Add(1)
+	go s.cleanupLoop()
+}
+
+func (s *RateLimitService) cleanupLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(rateLimitCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := s.store.DeleteIdle(context.Background(), s.idle)
+			if err != nil {
+				s.log.Error("ошибка удаления неиспользуемых корзин ограничения частоты", slog.String("error", err.Error()))
+				continue
+			}
+			if deleted > 0 {
+				s.log.Debug("удалены неиспользуемые корзины ограничения частоты", slog.Int64("count", deleted))
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *RateLimitService) Shutdown(ctx context.Context) error {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type rateLimitBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryRateLimitStore корзины в памяти процесса: для одного экземпляра сервиса
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*rateLimitBucket
+	now     func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*rateLimitBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit models.RateLimit) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &rateLimitBucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = limit.Refill(bucket.tokens, now.Sub(bucket.updatedAt))
+	bucket.updatedAt = now
+
+	if bucket.tokens < 1 {
+		return bucket.tokens, false, nil
+	}
+	bucket.tokens--
+	return bucket.tokens, true, nil
+}
+
+func (s *MemoryRateLimitStore) DeleteIdle(_ context.Context, idle time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	cutoff := s.now().Add(-idle)
+	for key, bucket := range s.buckets {
+		if bucket.updatedAt.Before(cutoff) {
+			delete(s.buckets, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
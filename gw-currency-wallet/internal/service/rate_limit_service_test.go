@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/models"
+)
+
+// testRateLimit 6 запросов в минуту, то есть токен каждые 10 секунд, емкость 3
+var testRateLimit = models.RateLimit{Requests: 6, Period: time.Minute, Burst: 3}
+
+func newTestMemoryRateLimitStore(now *time.Time) *MemoryRateLimitStore {
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestMemoryRateLimitStore_Take(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestMemoryRateLimitStore(&now)
+
+	// Новая корзина полная: емкость расходуется сразу
+	for want := 2.0; want >= 0; want-- {
+		tokens, allowed, err := store.Take(ctx, "user:a", testRateLimit)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, want, tokens)
+	}
+	tokens, allowed, err := store.Take(ctx, "user:a", testRateLimit)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Zero(t, tokens)
+
+	// Другие ключи считаются отдельно
+	_, allowed, _ = store.Take(ctx, "user:b", testRateLimit)
+	assert.True(t, allowed)
+
+	// За 5 секунд накопилась половина токена, за 10 - целый
+	now = now.Add(5 * time.Second)
+	tokens, allowed, _ = store.Take(ctx, "user:a", testRateLimit)
+	assert.False(t, allowed)
+	assert.InDelta(t, 0.5, tokens, 1e-9)
+	now = now.Add(5 * time.Second)
+	tokens, allowed, _ = store.Take(ctx, "user:a", testRateLimit)
+	assert.True(t, allowed)
+	assert.InDelta(t, 0, tokens, 1e-9)
+
+	// После долгого простоя корзина не превышает емкость
+	now = now.Add(time.Hour)
+	tokens, allowed, _ = store.Take(ctx, "user:a", testRateLimit)
+	assert.True(t, allowed)
+	assert.Equal(t, 2.0, tokens)
+}
+
+func TestMemoryRateLimitStore_DeleteIdle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestMemoryRateLimitStore(&now)
+
+	_, _, _ = store.Take(ctx, "user:old", testRateLimit)
+	now = now.Add(time.Minute)
+	_, _, _ = store.Take(ctx, "user:new", testRateLimit)
+
+	deleted, err := store.DeleteIdle(ctx, 30*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.NotContains(t, store.buckets, "user:old")
+	assert.Contains(t, store.buckets, "user:new")
+}
+
+func TestRateLimitService_Allow(t *testing.T) {
+	ctx := context.Background()
+	store := new(MockRateLimitStore)
+	service := NewRateLimitService(store, map[string]models.RateLimit{models.RateLimitPolicyWallet: testRateLimit},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	store.On("Take", ctx, "wallet:user:a", testRateLimit).Return(0.5, false, nil).Once()
+	result, err := service.Allow(ctx, models.RateLimitPolicyWallet, "user:a")
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 3, result.Limit)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 5*time.Second, result.RetryAfter)
+	assert.Equal(t, 25*time.Second, result.Reset)
+
+	// Ненастроенная политика не ограничивает и не обращается к хранилищу
+	result, err = service.Allow(ctx, models.RateLimitPolicyExchange, "user:a")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	store.On("Take", ctx, "wallet:user:b", testRateLimit).Return(0.0, false, errors.New("db down")).Once()
+	_, err = service.Allow(ctx, models.RateLimitPolicyWallet, "user:b")
+	assert.Error(t, err)
+
+	store.AssertExpectations(t)
+}
+
+func TestRateLimitService_IdleIsLongestRefill(t *testing.T) {
+	service := NewRateLimitService(new(MockRateLimitStore), map[string]models.RateLimit{
+		models.RateLimitPolicyWallet: testRateLimit,
+		models.RateLimitPolicyAuth:   {Requests: 20, Period: time.Minute, Burst: 20},
+		models.RateLimitPolicyGlobal: {Requests: 600, Period: time.Hour, Burst: 50},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	assert.Equal(t, 5*time.Minute, service.idle)
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// reconciliationBatchSize сколько кошельков сверяется одним запросом
+	reconciliationBatchSize = 500
+	// reconciliationStaleAfter прогон без обновлений дольше этого времени считается брошенным
+	reconciliationStaleAfter = 15 * time.Minute
+	// reconciliationFinishTimeout время на запись итога прогона, если его контекст уже отменен
+	reconciliationFinishTimeout = 10 * time.Second
+)
+
+// Reconciliation сверка балансов кошельков с историей операций
+type Reconciliation interface {
+	// StartRun запускает сверку в фоне и сразу возвращает созданный прогон
+	StartRun(ctx context.Context, actor *models.JWTClaims) (*models.ReconciliationReportResponse, error)
+	GetReport(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.ReconciliationReportResponse, error)
+	ListReports(ctx context.Context, actor *models.JWTClaims, limit int) (*models.ReconciliationReportListResponse, error)
+	// ExportDiscrepancies передает расхождения прогона в fn по одному. Для незавершенного прогона
+	// выгружаются уже найденные расхождения.
+	ExportDiscrepancies(
+		ctx context.Context,
+		actor *models.JWTClaims,
+		id uuid.UUID,
+		fn func(models.ReconciliationDiscrepancyRow) error,
+	) error
+}
+
+// ReconciliationService пересчитывает баланс каждого кошелька из главной книги, а зарезервированную
+// сумму из активных холдов и открытых лимитных ордеров, и сохраняет кошельки с расхождениями.
+// Кошельки читаются порциями по id, поэтому память не зависит от их количества.
+type ReconciliationService struct {
+	repo          postgres.ReconciliationRepository
+	outbox        postgres.OutboxRepository
+	audit         postgres.AuditRepository
+	txManager     TxManager
+	currencies    CurrencyRegistry
+	interval      time.Duration
+	alertsEnabled bool
+	log           *slog.Logger
+
+	// runCtx отменяется при остановке сервиса и прерывает фоновые прогоны
+	runCtx    context.Context
+	cancelRun context.CancelFunc
+	wg        sync.WaitGroup
+	stopCh    chan struct{}
+}
+
+func NewReconciliationService(
+	repo postgres.ReconciliationRepository,
+	outbox postgres.OutboxRepository,
+	audit postgres.AuditRepository,
+	txManager TxManager,
+	currencies CurrencyRegistry,
+	interval time.Duration,
+	alertsEnabled bool,
+	log *slog.Logger,
+) *ReconciliationService {
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	return &ReconciliationService{
+		repo:          repo,
+		outbox:        outbox,
+		audit:         audit,
+		txManager:     txManager,
+		currencies:    currencies,
+		interval:      interval,
+		alertsEnabled: alertsEnabled,
+		log:           log,
+		runCtx:        runCtx,
+		cancelRun:     cancelRun,
+		stopCh:        make(chan struct{}),
+	}
+}
+
+func (s *ReconciliationService) StartRun(ctx context.Context, actor *models.JWTClaims) (*models.ReconciliationReportResponse, error) {
+	const op = "service.StartReconciliation"
+
+	report, err := s.begin(ctx, models.ReconciliationTriggerManual, actor)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrReconciliationInProgress) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.finish(report, s.scan(s.runCtx, report.ID))
+	}()
+
+	resp := toReconciliationReportResponse(report)
+	return &resp, nil
+}
+
+// RunOnce выполняет плановую сверку и возвращает ее итог
+func (s *ReconciliationService) RunOnce(ctx context.Context) (*models.ReconciliationReport, error) {
+	const op = "service.RunReconciliation"
+
+	report, err := s.begin(ctx, models.ReconciliationTriggerScheduled, nil)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrReconciliationInProgress) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	finished, err := s.finish(report, s.scan(ctx, report.ID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return finished, nil
+}
+
+// begin создает прогон. Брошенные прогоны упавших экземпляров сначала помечаются неудачными,
+// чтобы не блокировать новую сверку.
+func (s *ReconciliationService) begin(
+	ctx context.Context,
+	trigger models.ReconciliationTrigger,
+	actor *models.JWTClaims,
+) (*models.ReconciliationReport, error) {
+	report := models.ReconciliationReport{
+		ID:      uuid.New(),
+		Trigger: trigger,
+	}
+	if actor != nil {
+		report.RequestedBy = &actor.UserID
+	}
+
+	var created *models.ReconciliationReport
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		abandoned, err := s.repo.FailStaleReportsTx(ctx, tx, reconciliationStaleAfter)
+		if err != nil {
+			return fmt.Errorf("failed to fail stale reports: %w", err)
+		}
+		if abandoned > 0 {
+			s.log.Warn("брошенные прогоны сверки помечены неудачными", slog.Int64("count", abandoned))
+		}
+
+		created, err = s.repo.CreateReportTx(ctx, tx, report)
+		if err != nil {
+			return err
+		}
+
+		if actor == nil {
+			return nil
+		}
+		return s.audit.CreateTx(ctx, tx, auditEntry(actor, models.AuditActionReconciliationRun, nil, nil, "", map[string]any{
+			"report_id": created.ID,
+		}))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// reconciliationProgress сколько кошельков проверено и сколько из них с расхождениями
+type reconciliationProgress struct {
+	walletsChecked   int64
+	discrepancyCount int64
+	err              error
+}
+
+// scan проходит по всем кошелькам порциями и сохраняет расхождения каждой порции
+func (s *ReconciliationService) scan(ctx context.Context, reportID uuid.UUID) reconciliationProgress {
+	var progress reconciliationProgress
+	after := uuid.Nil
+
+	for {
+		wallets, err := s.repo.ScanWallets(ctx, after, reconciliationBatchSize)
+		if err != nil {
+			progress.err = fmt.Errorf("failed to scan wallets: %w", err)
+			return progress
+		}
+		if len(wallets) == 0 {
+			return progress
+		}
+
+		var mismatched []models.WalletReconciliation
+		for _, w := range wallets {
+			if !w.Matches() {
+				mismatched = append(mismatched, w)
+			}
+		}
+		if len(mismatched) > 0 {
+			if err := s.repo.CreateDiscrepancies(ctx, reportID, mismatched); err != nil {
+				progress.err = fmt.Errorf("failed to save discrepancies: %w", err)
+				return progress
+			}
+		}
+
+		progress.walletsChecked += int64(len(wallets))
+		progress.discrepancyCount += int64(len(mismatched))
+		after = wallets[len(wallets)-1].WalletID
+
+		if err := s.repo.UpdateProgress(ctx, reportID, progress.walletsChecked, progress.discrepancyCount); err != nil {
+			progress.err = fmt.Errorf("failed to update progress: %w", err)
+			return progress
+		}
+		if len(wallets) < reconciliationBatchSize {
+			return progress
+		}
+	}
+}
+
+// finish записывает итог прогона и, если найдены расхождения, ставит в outbox событие тревоги.
+// Итог записывается в отдельном контексте, чтобы прерванный прогон тоже был помечен неудачным.
+func (s *ReconciliationService) finish(report *models.ReconciliationReport, progress reconciliationProgress) (*models.ReconciliationReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), reconciliationFinishTimeout)
+	defer cancel()
+
+	status := models.ReconciliationStatusCompleted
+	var errMsg string
+	if progress.err != nil {
+		status = models.ReconciliationStatusFailed
+		errMsg = progress.err.Error()
+		s.log.Error("ошибка сверки балансов",
+			slog.String("report_id", report.ID.String()),
+			slog.String("error", errMsg),
+		)
+	}
+
+	var finished *models.ReconciliationReport
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		finished, err = s.repo.FinishReportTx(ctx, tx, report.ID, status,
+			progress.walletsChecked, progress.discrepancyCount, errMsg)
+		if err != nil {
+			return err
+		}
+		if status != models.ReconciliationStatusCompleted || finished.DiscrepancyCount == 0 || !s.alertsEnabled {
+			return nil
+		}
+		return enqueueReconciliationAlertTx(ctx, s.outbox, tx, models.ReconciliationAlertEvent{
+			ReportID:         finished.ID,
+			WalletsChecked:   finished.WalletsChecked,
+			DiscrepancyCount: finished.DiscrepancyCount,
+			FinishedAt:       *finished.FinishedAt,
+		})
+	})
+	if err != nil {
+		s.log.Error("не удалось записать итог сверки",
+			slog.String("report_id", report.ID.String()),
+			slog.String("error", err.Error()),
+		)
+		return nil, fmt.Errorf("failed to finish report: %w", err)
+	}
+
+	if finished.DiscrepancyCount > 0 {
+		s.log.Warn("сверка балансов нашла расхождения",
+			slog.String("report_id", finished.ID.String()),
+			slog.Int64("wallets_checked", finished.WalletsChecked),
+			slog.Int64("discrepancies", finished.DiscrepancyCount),
+		)
+	} else {
+		s.log.Info("сверка балансов завершена",
+			slog.String("report_id", finished.ID.String()),
+			slog.String("status", string(finished.Status)),
+			slog.Int64("wallets_checked", finished.WalletsChecked),
+		)
+	}
+	return finished, nil
+}
+
+func (s *ReconciliationService) GetReport(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.ReconciliationReportResponse, error) {
+	const op = "service.GetReconciliationReport"
+
+	report, err := s.repo.GetReport(ctx, id)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.audit.Create(ctx, auditEntry(actor, models.AuditActionReconciliationView, nil, nil, "", map[string]any{
+		"report_id": id,
+	})); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := toReconciliationReportResponse(report)
+	return &resp, nil
+}
+
+func (s *ReconciliationService) ListReports(ctx context.Context, actor *models.JWTClaims, limit int) (*models.ReconciliationReportListResponse, error) {
+	const op = "service.ListReconciliationReports"
+
+	if limit <= 0 {
+		limit = models.DefaultReconciliationReportsLimit
+	}
+	if limit > models.MaxReconciliationReportsLimit {
+		limit = models.MaxReconciliationReportsLimit
+	}
+
+	reports, err := s.repo.ListReports(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.audit.Create(ctx, auditEntry(actor, models.AuditActionReconciliationView, nil, nil, "", map[string]any{
+		"limit": limit,
+	})); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &models.ReconciliationReportListResponse{
+		Reports: make([]models.ReconciliationReportResponse, 0, len(reports)),
+	}
+	for i := range reports {
+		resp.Reports = append(resp.Reports, toReconciliationReportResponse(&reports[i]))
+	}
+	return resp, nil
+}
+
+func (s *ReconciliationService) ExportDiscrepancies(
+	ctx context.Context,
+	actor *models.JWTClaims,
+	id uuid.UUID,
+	fn func(models.ReconciliationDiscrepancyRow) error,
+) error {
+	const op = "service.ExportReconciliationDiscrepancies"
+
+	if _, err := s.repo.GetReport(ctx, id); err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return custom_err.ErrNotFound
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.audit.Create(ctx, auditEntry(actor, models.AuditActionReconciliationExport, nil, nil, "", map[string]any{
+		"report_id": id,
+	})); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s.repo.StreamDiscrepancies(ctx, id, func(w models.WalletReconciliation) error {
+		exp := exps.of(w.Currency)
+		return fn(models.ReconciliationDiscrepancyRow{
+			WalletID:      w.WalletID,
+			UserID:        w.UserID,
+			Currency:      w.Currency,
+			Balance:       models.AmountFromMinorUnits(w.Balance, exp),
+			LedgerBalance: models.AmountFromMinorUnits(w.LedgerBalance, exp),
+			BalanceDiff:   models.AmountFromMinorUnits(w.Balance-w.LedgerBalance, exp),
+			HeldBalance:   models.AmountFromMinorUnits(w.HeldBalance, exp),
+			Reserved:      models.AmountFromMinorUnits(w.Reserved, exp),
+			HeldDiff:      models.AmountFromMinorUnits(w.HeldBalance-w.Reserved, exp),
+		})
+	})
+}
+
+// enqueueReconciliationAlertTx записывает событие о расхождениях в outbox в транзакции завершения прогона
+func enqueueReconciliationAlertTx(ctx context.Context, outbox postgres.OutboxRepository, tx pgx.Tx, event models.ReconciliationAlertEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+	if err := outbox.CreateTx(ctx, tx, models.OutboxEventReconciliationAlert, event.ReportID.String(), payload); err != nil {
+		return fmt.Errorf("failed to enqueue outbox event: %w", err)
+	}
+	return nil
+}
+
+func toReconciliationReportResponse(report *models.ReconciliationReport) models.ReconciliationReportResponse {
+	return models.ReconciliationReportResponse{
+		ID:               report.ID,
+		Trigger:          report.Trigger,
+		Status:           report.Status,
+		RequestedBy:      report.RequestedBy,
+		WalletsChecked:   report.WalletsChecked,
+		DiscrepancyCount: report.DiscrepancyCount,
+		Error:            report.Error,
+		StartedAt:        report.StartedAt,
+		FinishedAt:       report.FinishedAt,
+	}
+}
+
+// Start запускает плановую сверку. При нулевом интервале сверка выполняется только по запросу.
+func (s *ReconciliationService) Start() {
+	if s.interval <= 0 {
+		return
+	}
+	s.wg.Add(1)
+	go s.reconciliationLoop()
+}
+
+func (s *ReconciliationService) reconciliationLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.RunOnce(s.runCtx); err != nil {
+				if errors.Is(err, custom_err.ErrReconciliationInProgress) {
+					s.log.Info("плановая сверка пропущена: уже выполняется другой прогон")
+					continue
+				}
+				s.log.Error("ошибка плановой сверки балансов", slog.String("error", err.Error()))
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// Shutdown прерывает выполняющиеся прогоны; они помечаются неудачными
+func (s *ReconciliationService) Shutdown(ctx context.Context) error {
+	close(s.stopCh)
+	s.cancelRun()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+type reconciliationMocks struct {
+	repo      *MockReconciliationRepository
+	outbox    *MockOutboxRepository
+	audit     *MockAuditRepository
+	txManager *MockTxManager
+}
+
+func setupReconciliationService(alertsEnabled bool) (*ReconciliationService, reconciliationMocks) {
+	m := reconciliationMocks{
+		repo:      new(MockReconciliationRepository),
+		outbox:    new(MockOutboxRepository),
+		audit:     new(MockAuditRepository),
+		txManager: new(MockTxManager),
+	}
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	service := NewReconciliationService(m.repo, m.outbox, m.audit, m.txManager, newTestCurrencyRegistry(), 0, alertsEnabled, log)
+	// Итог прогона записывается в собственном контексте
+	m.txManager.On("WithTx", mock.Anything, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	return service, m
+}
+
+func matchingWallets(n int) []models.WalletReconciliation {
+	wallets := make([]models.WalletReconciliation, n)
+	for i := range wallets {
+		wallets[i] = models.WalletReconciliation{
+			WalletID:      uuid.New(),
+			UserID:        uuid.New(),
+			Currency:      "USD",
+			Balance:       1000,
+			LedgerBalance: 1000,
+		}
+	}
+	return wallets
+}
+
+func finishedReport(id uuid.UUID, status models.ReconciliationStatus, checked, discrepancies int64) *models.ReconciliationReport {
+	now := time.Now()
+	return &models.ReconciliationReport{
+		ID:               id,
+		Trigger:          models.ReconciliationTriggerScheduled,
+		Status:           status,
+		WalletsChecked:   checked,
+		DiscrepancyCount: discrepancies,
+		StartedAt:        now.Add(-time.Minute),
+		FinishedAt:       &now,
+	}
+}
+
+func TestReconciliationService_RunOnce_FindsDiscrepanciesAcrossBatches(t *testing.T) {
+	service, m := setupReconciliationService(true)
+	ctx := context.Background()
+	reportID := uuid.New()
+
+	first := matchingWallets(reconciliationBatchSize)
+	first[10].Balance = 1500
+	second := matchingWallets(2)
+	second[1].HeldBalance = 300
+	second[1].Reserved = 100
+
+	m.repo.On("FailStaleReportsTx", ctx, nil, reconciliationStaleAfter).Return(int64(0), nil)
+	m.repo.On("CreateReportTx", ctx, nil, mock.MatchedBy(func(r models.ReconciliationReport) bool {
+		return r.Trigger == models.ReconciliationTriggerScheduled && r.RequestedBy == nil
+	})).Return(&models.ReconciliationReport{ID: reportID, Status: models.ReconciliationStatusRunning}, nil)
+
+	m.repo.On("ScanWallets", ctx, uuid.Nil, reconciliationBatchSize).Return(first, nil)
+	m.repo.On("ScanWallets", ctx, first[len(first)-1].WalletID, reconciliationBatchSize).Return(second, nil)
+	m.repo.On("CreateDiscrepancies", ctx, reportID, []models.WalletReconciliation{first[10]}).Return(nil)
+	m.repo.On("CreateDiscrepancies", ctx, reportID, []models.WalletReconciliation{second[1]}).Return(nil)
+	m.repo.On("UpdateProgress", ctx, reportID, int64(500), int64(1)).Return(nil)
+	m.repo.On("UpdateProgress", ctx, reportID, int64(502), int64(2)).Return(nil)
+
+	finished := finishedReport(reportID, models.ReconciliationStatusCompleted, 502, 2)
+	m.repo.On("FinishReportTx", mock.Anything, nil, reportID, models.ReconciliationStatusCompleted, int64(502), int64(2), "").
+		Return(finished, nil)
+	m.outbox.On("CreateTx", mock.Anything, nil, models.OutboxEventReconciliationAlert, reportID.String(),
+		mock.MatchedBy(func(payload []byte) bool {
+			var event models.ReconciliationAlertEvent
+			return json.Unmarshal(payload, &event) == nil &&
+				event.ReportID == reportID && event.WalletsChecked == 502 && event.DiscrepancyCount == 2
+		})).Return(nil)
+
+	report, err := service.RunOnce(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ReconciliationStatusCompleted, report.Status)
+	assert.Equal(t, int64(2), report.DiscrepancyCount)
+	m.repo.AssertExpectations(t)
+	m.outbox.AssertExpectations(t)
+}
+
+func TestReconciliationService_RunOnce_NoDiscrepanciesNoAlert(t *testing.T) {
+	service, m := setupReconciliationService(true)
+	ctx := context.Background()
+	reportID := uuid.New()
+	wallets := matchingWallets(3)
+
+	m.repo.On("FailStaleReportsTx", ctx, nil, reconciliationStaleAfter).Return(int64(0), nil)
+	m.repo.On("CreateReportTx", ctx, nil, mock.Anything).
+		Return(&models.ReconciliationReport{ID: reportID, Status: models.ReconciliationStatusRunning}, nil)
+	m.repo.On("ScanWallets", ctx, uuid.Nil, reconciliationBatchSize).Return(wallets, nil)
+	m.repo.On("UpdateProgress", ctx, reportID, int64(3), int64(0)).Return(nil)
+	m.repo.On("FinishReportTx", mock.Anything, nil, reportID, models.ReconciliationStatusCompleted, int64(3), int64(0), "").
+		Return(finishedReport(reportID, models.ReconciliationStatusCompleted, 3, 0), nil)
+
+	_, err := service.RunOnce(ctx)
+
+	require.NoError(t, err)
+	m.repo.AssertNotCalled(t, "CreateDiscrepancies", mock.Anything, mock.Anything, mock.Anything)
+	m.outbox.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReconciliationService_RunOnce_AlertsDisabled(t *testing.T) {
+	service, m := setupReconciliationService(false)
+	ctx := context.Background()
+	reportID := uuid.New()
+	wallets := matchingWallets(1)
+	wallets[0].LedgerBalance = 900
+
+	m.repo.On("FailStaleReportsTx", ctx, nil, reconciliationStaleAfter).Return(int64(0), nil)
+	m.repo.On("CreateReportTx", ctx, nil, mock.Anything).
+		Return(&models.ReconciliationReport{ID: reportID, Status: models.ReconciliationStatusRunning}, nil)
+	m.repo.On("ScanWallets", ctx, uuid.Nil, reconciliationBatchSize).Return(wallets, nil)
+	m.repo.On("CreateDiscrepancies", ctx, reportID, wallets).Return(nil)
+	m.repo.On("UpdateProgress", ctx, reportID, int64(1), int64(1)).Return(nil)
+	m.repo.On("FinishReportTx", mock.Anything, nil, reportID, models.ReconciliationStatusCompleted, int64(1), int64(1), "").
+		Return(finishedReport(reportID, models.ReconciliationStatusCompleted, 1, 1), nil)
+
+	_, err := service.RunOnce(ctx)
+
+	require.NoError(t, err)
+	m.outbox.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReconciliationService_RunOnce_ScanFailureMarksReportFailed(t *testing.T) {
+	service, m := setupReconciliationService(true)
+	ctx := context.Background()
+	reportID := uuid.New()
+
+	m.repo.On("FailStaleReportsTx", ctx, nil, reconciliationStaleAfter).Return(int64(1), nil)
+	m.repo.On("CreateReportTx", ctx, nil, mock.Anything).
+		Return(&models.ReconciliationReport{ID: reportID, Status: models.ReconciliationStatusRunning}, nil)
+	m.repo.On("ScanWallets", ctx, uuid.Nil, reconciliationBatchSize).Return(nil, errors.New("connection reset"))
+	m.repo.On("FinishReportTx", mock.Anything, nil, reportID, models.ReconciliationStatusFailed, int64(0), int64(0),
+		mock.MatchedBy(func(msg string) bool { return msg != "" })).
+		Return(finishedReport(reportID, models.ReconciliationStatusFailed, 0, 0), nil)
+
+	report, err := service.RunOnce(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.ReconciliationStatusFailed, report.Status)
+	m.outbox.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReconciliationService_StartRun_InProgress(t *testing.T) {
+	service, m := setupReconciliationService(true)
+	ctx := context.Background()
+	actor := adminActor()
+
+	m.repo.On("FailStaleReportsTx", ctx, nil, reconciliationStaleAfter).Return(int64(0), nil)
+	m.repo.On("CreateReportTx", ctx, nil, mock.Anything).Return(nil, custom_err.ErrReconciliationInProgress)
+
+	_, err := service.StartRun(ctx, actor)
+
+	assert.ErrorIs(t, err, custom_err.ErrReconciliationInProgress)
+	m.audit.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+	m.repo.AssertNotCalled(t, "ScanWallets", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReconciliationService_StartRun_AuditsAndRunsInBackground(t *testing.T) {
+	service, m := setupReconciliationService(true)
+	ctx := context.Background()
+	actor := adminActor()
+	reportID := uuid.New()
+
+	m.repo.On("FailStaleReportsTx", ctx, nil, reconciliationStaleAfter).Return(int64(0), nil)
+	m.repo.On("CreateReportTx", ctx, nil, mock.MatchedBy(func(r models.ReconciliationReport) bool {
+		return r.Trigger == models.ReconciliationTriggerManual && r.RequestedBy != nil && *r.RequestedBy == actor.UserID
+	})).Return(&models.ReconciliationReport{
+		ID:          reportID,
+		Trigger:     models.ReconciliationTriggerManual,
+		Status:      models.ReconciliationStatusRunning,
+		RequestedBy: &actor.UserID,
+	}, nil)
+	m.audit.On("CreateTx", ctx, nil, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.Action == models.AuditActionReconciliationRun && e.ActorID == actor.UserID && e.Details["report_id"] == reportID
+	})).Return(nil)
+	m.repo.On("ScanWallets", mock.Anything, uuid.Nil, reconciliationBatchSize).Return([]models.WalletReconciliation{}, nil)
+	m.repo.On("FinishReportTx", mock.Anything, nil, reportID, models.ReconciliationStatusCompleted, int64(0), int64(0), "").
+		Return(finishedReport(reportID, models.ReconciliationStatusCompleted, 0, 0), nil)
+
+	resp, err := service.StartRun(ctx, actor)
+	require.NoError(t, err)
+	service.wg.Wait()
+
+	assert.Equal(t, reportID, resp.ID)
+	assert.Equal(t, models.ReconciliationStatusRunning, resp.Status)
+	m.audit.AssertExpectations(t)
+	m.repo.AssertExpectations(t)
+}
+
+func TestReconciliationService_ExportDiscrepancies(t *testing.T) {
+	service, m := setupReconciliationService(true)
+	ctx := context.Background()
+	actor := adminActor()
+	reportID := uuid.New()
+
+	m.repo.On("GetReport", ctx, reportID).Return(finishedReport(reportID, models.ReconciliationStatusCompleted, 10, 1), nil)
+	m.audit.On("Create", ctx, mock.MatchedBy(func(e models.AuditEntry) bool {
+		return e.Action == models.AuditActionReconciliationExport
+	})).Return(nil)
+	wallet := models.WalletReconciliation{
+		WalletID:      uuid.New(),
+		UserID:        uuid.New(),
+		Currency:      "USD",
+		Balance:       15050,
+		LedgerBalance: 10000,
+		HeldBalance:   500,
+		Reserved:      500,
+	}
+	m.repo.On("StreamDiscrepancies", ctx, reportID, mock.Anything).Return([]models.WalletReconciliation{wallet}, nil)
+
+	var rows []models.ReconciliationDiscrepancyRow
+	err := service.ExportDiscrepancies(ctx, actor, reportID, func(row models.ReconciliationDiscrepancyRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, wallet.WalletID, rows[0].WalletID)
+	assert.Equal(t, "150.5", rows[0].Balance.String())
+	assert.Equal(t, "100", rows[0].LedgerBalance.String())
+	assert.Equal(t, "50.5", rows[0].BalanceDiff.String())
+	assert.True(t, rows[0].HeldDiff.IsZero())
+}
+
+func TestReconciliationService_ExportDiscrepancies_NotFound(t *testing.T) {
+	service, m := setupReconciliationService(true)
+	ctx := context.Background()
+	reportID := uuid.New()
+
+	m.repo.On("GetReport", ctx, reportID).Return(nil, custom_err.ErrNotFound)
+
+	err := service.ExportDiscrepancies(ctx, adminActor(), reportID, func(models.ReconciliationDiscrepancyRow) error {
+		t.Fatal("unexpected row")
+		return nil
+	})
+
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+	m.audit.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,527 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/cron"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const scheduledExchangeBatchSize = 100
+
+// scheduledRunErrors ошибки обмена, которые записываются в запуск с понятным клиенту кодом.
+// Остальные ошибки записываются как internal_error без подробностей.
+var scheduledRunErrors = []struct {
+	err  error
+	code string
+}{
+	{custom_err.ErrInsufficientFunds, "insufficient_funds"},
+	{custom_err.ErrRateUnavailable, "rate_unavailable"},
+	{custom_err.ErrLimitExceeded, "limit_exceeded"},
+	{custom_err.ErrWalletFrozen, "wallet_frozen"},
+	{custom_err.ErrInvalidCurrency, "invalid_currency"},
+	{custom_err.ErrAmountPrecision, "invalid_amount_precision"},
+	{custom_err.ErrInvalidAmount, "invalid_amount"},
+	{custom_err.ErrNotFound, "wallet_not_found"},
+	{cron.ErrInvalidExpression, "invalid_schedule"},
+}
+
+// ScheduledExchanges разовые и повторяющиеся обмены, которые выполняет планировщик
+type ScheduledExchanges interface {
+	// CreateSchedule планирует обмен на req.RunAt или по cron-выражению req.Cron
+	CreateSchedule(ctx context.Context, userID uuid.UUID, req models.CreateScheduledExchangeRequest) (*models.ScheduledExchangeResponse, error)
+	ListSchedules(ctx context.Context, userID uuid.UUID, req models.ScheduledExchangeListRequest) (*models.ScheduledExchangeListResponse, error)
+	CancelSchedule(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.ScheduledExchangeResponse, error)
+	ListRuns(ctx context.Context, actor *models.JWTClaims, id uuid.UUID, limit int) (*models.ScheduledExchangeRunListResponse, error)
+}
+
+type ScheduledExchangeService struct {
+	schedules  postgres.ScheduledExchangeRepository
+	walletRepo postgres.WalletRepository
+	exchange   *ExchangeService
+	txManager  TxManager
+	currencies CurrencyRegistry
+	policy     Policy
+	interval   time.Duration
+	log        *slog.Logger
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func NewScheduledExchangeService(
+	schedules postgres.ScheduledExchangeRepository,
+	walletRepo postgres.WalletRepository,
+	exchange *ExchangeService,
+	txManager TxManager,
+	currencies CurrencyRegistry,
+	policy Policy,
+	interval time.Duration,
+	log *slog.Logger,
+) *ScheduledExchangeService {
+	return &ScheduledExchangeService{
+		schedules:  schedules,
+		walletRepo: walletRepo,
+		exchange:   exchange,
+		txManager:  txManager,
+		currencies: currencies,
+		policy:     policy,
+		interval:   interval,
+		log:        log,
+		stopCh:     make(chan struct{}),
+	}
+}
+
+func (s *ScheduledExchangeService) CreateSchedule(
+	ctx context.Context,
+	userID uuid.UUID,
+	req models.CreateScheduledExchangeRequest,
+) (*models.ScheduledExchangeResponse, error) {
+	const op = "service.CreateScheduledExchange"
+
+	fromCurrency, err := requireEnabledCurrency(ctx, s.currencies, req.FromCurrency)
+	if err != nil {
+		return nil, err
+	}
+	toCurrency, err := requireEnabledCurrency(ctx, s.currencies, req.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if fromCurrency.Code == toCurrency.Code {
+		return nil, fmt.Errorf("%w: cannot exchange same currency", custom_err.ErrInvalidCurrency)
+	}
+	amount, err := toMinorUnits(req.Amount, fromCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if req.RequestID == "" {
+		return nil, fmt.Errorf("%w: requestID is required", custom_err.ErrInvalidInput)
+	}
+
+	now := time.Now()
+	var nextRunAt time.Time
+	switch {
+	case (req.RunAt == nil) == (req.Cron == ""):
+		return nil, fmt.Errorf("%w: exactly one of run_at and cron is required", custom_err.ErrInvalidInput)
+	case req.RunAt != nil:
+		if !req.RunAt.After(now) {
+			return nil, fmt.Errorf("%w: run_at must be in the future", custom_err.ErrInvalidInput)
+		}
+		nextRunAt = *req.RunAt
+	default:
+		schedule, err := cron.Parse(req.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", custom_err.ErrInvalidInput, err.Error())
+		}
+		nextRunAt = schedule.Next(now)
+	}
+
+	if _, err := s.walletRepo.GetByUserAndCurrency(ctx, userID, fromCurrency.Code); err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: failed to get wallet: %w", op, err)
+	}
+
+	var created *models.ScheduledExchange
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		exists, err := s.schedules.ScheduleExistsTx(ctx, tx, req.RequestID)
+		if err != nil {
+			return fmt.Errorf("failed to check scheduled exchange: %w", err)
+		}
+		if exists {
+			return custom_err.ErrDuplicateRequest
+		}
+
+		created, err = s.schedules.CreateTx(ctx, tx, models.ScheduledExchange{
+			ID:           uuid.New(),
+			UserID:       userID,
+			FromCurrency: fromCurrency.Code,
+			ToCurrency:   toCurrency.Code,
+			Amount:       amount,
+			Cron:         req.Cron,
+			NextRunAt:    &nextRunAt,
+			RequestID:    req.RequestID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create scheduled exchange: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("запланирован обмен",
+		slog.String("op", op),
+		slog.String("schedule_id", created.ID.String()),
+		slog.String("user_id", userID.String()),
+		slog.String("from", string(created.FromCurrency)),
+		slog.String("to", string(created.ToCurrency)),
+		slog.Int64("amount", created.Amount),
+		slog.String("cron", created.Cron),
+		slog.Time("next_run_at", nextRunAt))
+
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	resp := toScheduledExchangeResponse(created, exps)
+	return &resp, nil
+}
+
+func (s *ScheduledExchangeService) ListSchedules(
+	ctx context.Context,
+	userID uuid.UUID,
+	req models.ScheduledExchangeListRequest,
+) (*models.ScheduledExchangeListResponse, error) {
+	const op = "service.ListScheduledExchanges"
+
+	if req.Status != "" && !req.Status.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %s", custom_err.ErrInvalidInput, req.Status)
+	}
+	limit, err := scheduledExchangesLimit(req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.schedules.ListByUser(ctx, userID, req.Status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &models.ScheduledExchangeListResponse{Schedules: make([]models.ScheduledExchangeResponse, 0, len(schedules))}
+	for i := range schedules {
+		resp.Schedules = append(resp.Schedules, toScheduledExchangeResponse(&schedules[i], exps))
+	}
+	return resp, nil
+}
+
+func (s *ScheduledExchangeService) CancelSchedule(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.ScheduledExchangeResponse, error) {
+	const op = "service.CancelScheduledExchange"
+
+	schedule, err := s.getAuthorized(ctx, actor, id, ActionWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.schedules.Cancel(ctx, schedule.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("запланированный обмен отменен",
+		slog.String("op", op),
+		slog.String("schedule_id", schedule.ID.String()),
+		slog.String("actor_id", actor.UserID.String()))
+
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	resp := toScheduledExchangeResponse(cancelled, exps)
+	return &resp, nil
+}
+
+func (s *ScheduledExchangeService) ListRuns(
+	ctx context.Context,
+	actor *models.JWTClaims,
+	id uuid.UUID,
+	limit int,
+) (*models.ScheduledExchangeRunListResponse, error) {
+	const op = "service.ListScheduledExchangeRuns"
+
+	limit, err := scheduledExchangesLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := s.getAuthorized(ctx, actor, id, ActionRead)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := s.schedules.ListRuns(ctx, schedule.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	fromExp, toExp := exps.of(string(schedule.FromCurrency)), exps.of(string(schedule.ToCurrency))
+	resp := &models.ScheduledExchangeRunListResponse{Runs: make([]models.ScheduledExchangeRunResponse, 0, len(runs))}
+	for _, run := range runs {
+		item := models.ScheduledExchangeRunResponse{
+			ScheduledFor: run.ScheduledFor,
+			RequestID:    run.RequestID,
+			Status:       run.Status,
+			ErrorCode:    run.ErrorCode,
+			Error:        run.Error,
+			CreatedAt:    run.CreatedAt,
+		}
+		if run.Status == models.ScheduledExchangeRunSucceeded {
+			exchanged := models.AmountFromMinorUnits(run.Fill.ExchangedAmount, toExp)
+			rate := run.Fill.Rate
+			fee := models.AmountFromMinorUnits(run.Fill.Fee, fromExp)
+			item.ExchangedAmount, item.Rate, item.Fee = &exchanged, &rate, &fee
+		}
+		resp.Runs = append(resp.Runs, item)
+	}
+	return resp, nil
+}
+
+func (s *ScheduledExchangeService) getAuthorized(
+	ctx context.Context,
+	actor *models.JWTClaims,
+	id uuid.UUID,
+	action Action,
+) (*models.ScheduledExchange, error) {
+	const op = "service.GetScheduledExchange"
+
+	schedule, err := s.schedules.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := s.policy.AuthorizeScheduledExchange(actor, schedule, action); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// RunDue выполняет обмены, запуск которых наступил, и возвращает количество обработанных запусков.
+// Запуски, пропущенные пока сервис не работал, не догоняются: обмен выполняется один раз,
+// и следующий запуск назначается по расписанию после текущего момента.
+func (s *ScheduledExchangeService) RunDue(ctx context.Context) (int, error) {
+	const op = "service.RunDueScheduledExchanges"
+
+	schedules, err := s.schedules.ListDue(ctx, time.Now(), scheduledExchangeBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Ошибка одного обмена не останавливает пакет: иначе обмен, который не удается провести,
+	// оставался бы первым в ListDue и блокировал остальные на каждой проверке
+	processed := 0
+	var errs []error
+	for i := range schedules {
+		ran, err := s.run(ctx, &schedules[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %s: %w", schedules[i].ID, err))
+			continue
+		}
+		if ran {
+			processed++
+		}
+	}
+	if len(errs) > 0 {
+		return processed, fmt.Errorf("%s: %d of %d failed: %w", op, len(errs), len(schedules), errors.Join(errs...))
+	}
+	return processed, nil
+}
+
+// run выполняет запуск обмена, запланированный на schedule.NextRunAt. Перенос запуска и запись
+// результата идут в транзакции обмена, поэтому обмен и его запуск фиксируются вместе; повторная
+// попытка с тем же requestID отклоняется идемпотентностью обмена. Возвращает false, если запуск
+// уже обработан другим экземпляром или обмен отменен. Обмен с сохраненным cron-выражением, которое
+// не разбирается, завершается неуспешным запуском.
+func (s *ScheduledExchangeService) run(ctx context.Context, schedule *models.ScheduledExchange) (bool, error) {
+	scheduledFor := *schedule.NextRunAt
+	run := models.ScheduledExchangeRun{
+		ID:           uuid.New(),
+		ScheduleID:   schedule.ID,
+		ScheduledFor: scheduledFor,
+		RequestID:    schedule.RunRequestID(scheduledFor),
+		Status:       models.ScheduledExchangeRunSucceeded,
+	}
+
+	next, err := nextScheduledRun(schedule, time.Now())
+	if err != nil {
+		return s.recordFailedRun(ctx, schedule, run, nil, err)
+	}
+
+	err = s.exchangeForRun(ctx, schedule, &run, next)
+	if err == nil {
+		s.log.Info("выполнен запланированный обмен",
+			slog.String("schedule_id", schedule.ID.String()),
+			slog.String("user_id", schedule.UserID.String()),
+			slog.String("request_id", run.RequestID),
+			slog.String("rate", run.Fill.Rate.String()))
+		return true, nil
+	}
+	if errors.Is(err, custom_err.ErrDuplicateRequest) || errors.Is(err, custom_err.ErrScheduleNotActive) {
+		return false, nil
+	}
+	return s.recordFailedRun(ctx, schedule, run, next, err)
+}
+
+// recordFailedRun записывает неуспешный запуск с кодом ошибки cause и переносит обмен на next;
+// при next == nil обмен завершается
+func (s *ScheduledExchangeService) recordFailedRun(
+	ctx context.Context,
+	schedule *models.ScheduledExchange,
+	run models.ScheduledExchangeRun,
+	next *time.Time,
+	cause error,
+) (bool, error) {
+	run.Status = models.ScheduledExchangeRunFailed
+	run.Fill = models.LimitOrderFill{}
+	run.ErrorCode, run.Error = "internal_error", "An internal error occurred"
+	for _, known := range scheduledRunErrors {
+		if errors.Is(cause, known.err) {
+			run.ErrorCode, run.Error = known.code, known.err.Error()
+			break
+		}
+	}
+	s.log.Warn("запланированный обмен не выполнен",
+		slog.String("schedule_id", schedule.ID.String()),
+		slog.String("request_id", run.RequestID),
+		slog.String("error_code", run.ErrorCode),
+		slog.String("error", cause.Error()))
+
+	err := s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := s.schedules.AdvanceTx(ctx, tx, schedule.ID, run.ScheduledFor, next); err != nil {
+			return err
+		}
+		return s.schedules.CreateRunTx(ctx, tx, run)
+	})
+	if err != nil {
+		if errors.Is(err, custom_err.ErrScheduleNotActive) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to record scheduled exchange run: %w", err)
+	}
+	return true, nil
+}
+
+// exchangeForRun проводит обмен запуска и заполняет run.Fill условиями обмена
+func (s *ScheduledExchangeService) exchangeForRun(
+	ctx context.Context,
+	schedule *models.ScheduledExchange,
+	run *models.ScheduledExchangeRun,
+	next *time.Time,
+) error {
+	fromCurrency, err := s.currencies.Get(ctx, schedule.FromCurrency)
+	if err != nil {
+		return err
+	}
+	terms, err := s.exchange.priceExchange(ctx, schedule.FromCurrency, schedule.ToCurrency,
+		models.AmountFromMinorUnits(schedule.Amount, fromCurrency.Exponent), s.exchange.GetExchangeRate)
+	if err != nil {
+		return err
+	}
+	run.Fill = models.LimitOrderFill{
+		ExchangedAmount: terms.exchangedAmount,
+		Rate:            terms.rate,
+		Fee:             terms.fee,
+	}
+
+	_, err = s.exchange.executeExchange(ctx, schedule.UserID, run.RequestID, terms, exchangeSource{
+		settleTx: func(ctx context.Context, tx pgx.Tx) error {
+			if err := s.schedules.AdvanceTx(ctx, tx, schedule.ID, run.ScheduledFor, next); err != nil {
+				return err
+			}
+			return s.schedules.CreateRunTx(ctx, tx, *run)
+		},
+	})
+	return err
+}
+
+// nextScheduledRun время запуска после now; nil для разового обмена
+func nextScheduledRun(schedule *models.ScheduledExchange, now time.Time) (*time.Time, error) {
+	if !schedule.Recurring() {
+		return nil, nil
+	}
+	expr, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s: %w", schedule.ID, err)
+	}
+	next := expr.Next(now)
+	return &next, nil
+}
+
+func scheduledExchangesLimit(limit int) (int, error) {
+	if limit == 0 {
+		return models.DefaultScheduledExchangesLimit, nil
+	}
+	if limit < 0 || limit > models.MaxScheduledExchangesLimit {
+		return 0, fmt.Errorf("%w: limit must be between 1 and %d", custom_err.ErrInvalidInput, models.MaxScheduledExchangesLimit)
+	}
+	return limit, nil
+}
+
+func toScheduledExchangeResponse(schedule *models.ScheduledExchange, exps exponents) models.ScheduledExchangeResponse {
+	return models.ScheduledExchangeResponse{
+		ID:           schedule.ID,
+		FromCurrency: string(schedule.FromCurrency),
+		ToCurrency:   string(schedule.ToCurrency),
+		Amount:       models.AmountFromMinorUnits(schedule.Amount, exps.of(string(schedule.FromCurrency))),
+		Cron:         schedule.Cron,
+		Status:       schedule.Status,
+		NextRunAt:    schedule.NextRunAt,
+		RequestID:    schedule.RequestID,
+		CreatedAt:    schedule.CreatedAt,
+		UpdatedAt:    schedule.UpdatedAt,
+	}
+}
+
+// Start запускает планировщик обменов
+func (s *ScheduledExchangeService) Start() {
+	s.wg.Add(1)
+	go s.schedulerLoop()
+}
+
+func (s *ScheduledExchangeService) schedulerLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			processed, err := s.RunDue(context.Background())
+			if err != nil {
+				s.log.Error("ошибка выполнения запланированных обменов", slog.String("error", err.Error()))
+			}
+			if processed > 0 {
+				s.log.Info("обработаны запланированные обмены", slog.Int("count", processed))
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *ScheduledExchangeService) Shutdown(ctx context.Context) error {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
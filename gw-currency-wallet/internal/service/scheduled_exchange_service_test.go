@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/grpc_client"
+	"gw-currency-wallet/internal/models"
+)
+
+type scheduledExchangeMocks struct {
+	schedules  *MockScheduledExchangeRepository
+	walletRepo *MockWalletRepo
+	ledger     *MockLedgerRepo
+	txManager  *MockTxManager
+	grpcClient *MockExchangerClient
+}
+
+func setupScheduledExchangeService(t *testing.T) (*ScheduledExchangeService, scheduledExchangeMocks) {
+	exchange, walletRepo, ledger, txManager, grpcClient, _ := setupExchangeService(t)
+	m := scheduledExchangeMocks{
+		schedules:  new(MockScheduledExchangeRepository),
+		walletRepo: walletRepo,
+		ledger:     ledger,
+		txManager:  txManager,
+		grpcClient: grpcClient,
+	}
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	service := NewScheduledExchangeService(m.schedules, walletRepo, exchange, txManager, newTestCurrencyRegistry(), NewPolicy(),
+		time.Minute, log)
+
+	return service, m
+}
+
+func newDueSchedule(userID uuid.UUID, cron string) models.ScheduledExchange {
+	nextRunAt := time.Now().Add(-time.Minute).Truncate(time.Minute)
+	return models.ScheduledExchange{
+		ID:           uuid.New(),
+		UserID:       userID,
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       10000,
+		Cron:         cron,
+		Status:       models.ScheduledExchangeStatusActive,
+		NextRunAt:    &nextRunAt,
+		RequestID:    "schedule-1",
+	}
+}
+
+func TestScheduledExchangeService_CreateSchedule_Recurring(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	wallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	req := models.CreateScheduledExchangeRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("100"),
+		Cron:         "0 9 * * 1",
+		RequestID:    "schedule-1",
+	}
+	created := newDueSchedule(userID, req.Cron)
+
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(wallet, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.schedules.On("ScheduleExistsTx", ctx, mock.Anything, "schedule-1").Return(false, nil)
+	m.schedules.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(s models.ScheduledExchange) bool {
+		return s.Amount == 10000 && s.Cron == req.Cron &&
+			s.NextRunAt.After(time.Now()) && s.NextRunAt.Weekday() == time.Monday && s.NextRunAt.Hour() == 9
+	})).Return(&created, nil)
+
+	resp, err := service.CreateSchedule(ctx, userID, req)
+
+	require.NoError(t, err)
+	assert.Equal(t, "100", resp.Amount.String())
+	assert.Equal(t, req.Cron, resp.Cron)
+	m.schedules.AssertExpectations(t)
+}
+
+func TestScheduledExchangeService_CreateSchedule_InvalidInput(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	base := models.CreateScheduledExchangeRequest{
+		FromCurrency: models.CurrencyUSD,
+		ToCurrency:   models.CurrencyEUR,
+		Amount:       decimal.RequireFromString("100"),
+		RequestID:    "schedule-1",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*models.CreateScheduledExchangeRequest)
+	}{
+		{"neither run_at nor cron", func(r *models.CreateScheduledExchangeRequest) {}},
+		{"both run_at and cron", func(r *models.CreateScheduledExchangeRequest) { r.RunAt, r.Cron = &future, "@daily" }},
+		{"run_at in the past", func(r *models.CreateScheduledExchangeRequest) { r.RunAt = &past }},
+		{"bad cron", func(r *models.CreateScheduledExchangeRequest) { r.Cron = "every monday" }},
+		{"missing requestID", func(r *models.CreateScheduledExchangeRequest) { r.RunAt, r.RequestID = &future, "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base
+			tt.modify(&req)
+
+			_, err := service.CreateSchedule(ctx, uuid.New(), req)
+			assert.ErrorIs(t, err, custom_err.ErrInvalidInput)
+		})
+	}
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestScheduledExchangeService_RunDue_ExecutesWithDerivedRequestID(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	schedule := newDueSchedule(userID, "*/5 * * * *")
+	requestID := schedule.RunRequestID(*schedule.NextRunAt)
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "EUR"}
+
+	m.schedules.On("ListDue", ctx, mock.AnythingOfType("time.Time"), scheduledExchangeBatchSize).
+		Return([]models.ScheduledExchange{schedule}, nil)
+	m.grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("0.92"),
+	}, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, requestID).Return(false, nil)
+	// Следующий запуск - ближайшее время по расписанию после текущего момента
+	m.schedules.On("AdvanceTx", ctx, mock.Anything, schedule.ID, *schedule.NextRunAt, mock.MatchedBy(func(next *time.Time) bool {
+		return next != nil && next.After(time.Now()) && next.Minute()%5 == 0
+	})).Return(nil).Once()
+	m.schedules.On("CreateRunTx", ctx, mock.Anything, mock.MatchedBy(func(run models.ScheduledExchangeRun) bool {
+		return run.RequestID == requestID && run.Status == models.ScheduledExchangeRunSucceeded &&
+			run.Fill.ExchangedAmount == 9200 && run.ErrorCode == ""
+	})).Return(nil).Once()
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
+	m.walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyEUR).Return(toWallet, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 10000}, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{}, nil)
+	m.ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, mock.Anything).Return(uuid.New(), nil)
+	m.ledger.On("PostEntryTx", ctx, mock.Anything, mock.MatchedBy(func(e models.JournalEntry) bool {
+		return e.RequestID == requestID && e.Validate() == nil
+	})).Return(nil)
+	m.walletRepo.On("CreateExchangeOperationTx", ctx, mock.Anything, mock.MatchedBy(func(op models.ExchangeOperation) bool {
+		return op.RequestID == requestID && op.Amount == 10000
+	})).Return(nil)
+
+	processed, err := service.RunDue(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	m.schedules.AssertExpectations(t)
+	m.ledger.AssertExpectations(t)
+}
+
+func TestScheduledExchangeService_RunDue_RecordsInsufficientFunds(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	schedule := newDueSchedule(userID, "@daily")
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: "EUR"}
+
+	m.schedules.On("ListDue", ctx, mock.AnythingOfType("time.Time"), scheduledExchangeBatchSize).
+		Return([]models.ScheduledExchange{schedule}, nil)
+	m.grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("0.92"),
+	}, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, mock.Anything).Return(false, nil)
+	m.schedules.On("AdvanceTx", ctx, mock.Anything, schedule.ID, *schedule.NextRunAt, mock.Anything).Return(nil)
+	m.schedules.On("CreateRunTx", ctx, mock.Anything, mock.Anything).Return(nil)
+	m.walletRepo.On("GetByUserAndCurrency", ctx, userID, models.CurrencyUSD).Return(fromWallet, nil)
+	m.walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, userID, models.CurrencyEUR).Return(toWallet, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).
+		Return(models.WalletBalance{Balance: 15000, Held: 10000}, nil)
+
+	processed, err := service.RunDue(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	m.schedules.AssertCalled(t, "CreateRunTx", ctx, mock.Anything, mock.MatchedBy(func(run models.ScheduledExchangeRun) bool {
+		return run.Status == models.ScheduledExchangeRunFailed && run.ErrorCode == "insufficient_funds" &&
+			run.RequestID == schedule.RunRequestID(*schedule.NextRunAt) && run.Fill == models.LimitOrderFill{}
+	}))
+	m.ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestScheduledExchangeService_RunDue_OneOffRateUnavailable(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	schedule := newDueSchedule(uuid.New(), "")
+
+	m.schedules.On("ListDue", ctx, mock.AnythingOfType("time.Time"), scheduledExchangeBatchSize).
+		Return([]models.ScheduledExchange{schedule}, nil)
+	m.grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(nil, errors.New("unavailable"))
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	// Разовый обмен завершается и после неуспешного запуска
+	m.schedules.On("AdvanceTx", ctx, mock.Anything, schedule.ID, *schedule.NextRunAt, (*time.Time)(nil)).Return(nil).Once()
+	m.schedules.On("CreateRunTx", ctx, mock.Anything, mock.MatchedBy(func(run models.ScheduledExchangeRun) bool {
+		return run.Status == models.ScheduledExchangeRunFailed && run.ErrorCode == "rate_unavailable"
+	})).Return(nil).Once()
+
+	processed, err := service.RunDue(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	m.schedules.AssertExpectations(t)
+}
+
+func TestScheduledExchangeService_RunDue_AlreadyExecuted(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	schedule := newDueSchedule(uuid.New(), "@hourly")
+
+	m.schedules.On("ListDue", ctx, mock.AnythingOfType("time.Time"), scheduledExchangeBatchSize).
+		Return([]models.ScheduledExchange{schedule}, nil)
+	m.grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(&grpc_client.ExchangeRateResponse{
+		Rate: decimal.RequireFromString("0.92"),
+	}, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	// Обмен этого запуска уже проведен другим экземпляром
+	m.walletRepo.On("ExchangeOperationExistsTx", ctx, mock.Anything, schedule.RunRequestID(*schedule.NextRunAt)).Return(true, nil)
+
+	processed, err := service.RunDue(ctx)
+
+	require.NoError(t, err)
+	assert.Zero(t, processed)
+	m.schedules.AssertNotCalled(t, "AdvanceTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.schedules.AssertNotCalled(t, "CreateRunTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestScheduledExchangeService_RunDue_ContinuesAfterFailure(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	failing := newDueSchedule(uuid.New(), "")
+	next := newDueSchedule(uuid.New(), "")
+	dbErr := errors.New("connection reset")
+
+	m.schedules.On("ListDue", ctx, mock.AnythingOfType("time.Time"), scheduledExchangeBatchSize).
+		Return([]models.ScheduledExchange{failing, next}, nil)
+	m.grpcClient.On("GetExchangeRateForCurrency", ctx, "USD", "EUR").Return(nil, errors.New("unavailable"))
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	// Запуск первого обмена не удается записать, второй все равно выполняется
+	m.schedules.On("AdvanceTx", ctx, mock.Anything, failing.ID, *failing.NextRunAt, (*time.Time)(nil)).Return(dbErr).Once()
+	m.schedules.On("AdvanceTx", ctx, mock.Anything, next.ID, *next.NextRunAt, (*time.Time)(nil)).Return(nil).Once()
+	m.schedules.On("CreateRunTx", ctx, mock.Anything, mock.MatchedBy(func(run models.ScheduledExchangeRun) bool {
+		return run.ScheduleID == next.ID && run.ErrorCode == "rate_unavailable"
+	})).Return(nil).Once()
+
+	processed, err := service.RunDue(ctx)
+
+	assert.ErrorIs(t, err, dbErr)
+	assert.ErrorContains(t, err, failing.ID.String())
+	assert.Equal(t, 1, processed)
+	m.schedules.AssertExpectations(t)
+}
+
+func TestScheduledExchangeService_RunDue_InvalidStoredCron(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	schedule := newDueSchedule(uuid.New(), "every monday")
+
+	m.schedules.On("ListDue", ctx, mock.AnythingOfType("time.Time"), scheduledExchangeBatchSize).
+		Return([]models.ScheduledExchange{schedule}, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	// Обмен с расписанием, которое не разбирается, завершается без попытки обмена
+	m.schedules.On("AdvanceTx", ctx, mock.Anything, schedule.ID, *schedule.NextRunAt, (*time.Time)(nil)).Return(nil).Once()
+	m.schedules.On("CreateRunTx", ctx, mock.Anything, mock.MatchedBy(func(run models.ScheduledExchangeRun) bool {
+		return run.Status == models.ScheduledExchangeRunFailed && run.ErrorCode == "invalid_schedule"
+	})).Return(nil).Once()
+
+	processed, err := service.RunDue(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	m.schedules.AssertExpectations(t)
+	m.grpcClient.AssertNotCalled(t, "GetExchangeRateForCurrency", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestScheduledExchangeService_ListRuns(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	userID := uuid.New()
+	schedule := newDueSchedule(userID, "@daily")
+	runs := []models.ScheduledExchangeRun{
+		{
+			ScheduleID: schedule.ID,
+			Status:     models.ScheduledExchangeRunSucceeded,
+			Fill:       models.LimitOrderFill{ExchangedAmount: 9200, Rate: decimal.RequireFromString("0.92")},
+		},
+		{ScheduleID: schedule.ID, Status: models.ScheduledExchangeRunFailed, ErrorCode: "insufficient_funds"},
+	}
+
+	m.schedules.On("GetByID", ctx, schedule.ID).Return(&schedule, nil)
+	m.schedules.On("ListRuns", ctx, schedule.ID, models.DefaultScheduledExchangesLimit).Return(runs, nil)
+
+	_, err := service.ListRuns(ctx, &models.JWTClaims{UserID: uuid.New(), Role: models.RoleUser}, schedule.ID, 0)
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+
+	resp, err := service.ListRuns(ctx, &models.JWTClaims{UserID: uuid.New(), Role: models.RoleSupport}, schedule.ID, 0)
+
+	require.NoError(t, err)
+	require.Len(t, resp.Runs, 2)
+	assert.Equal(t, "92", resp.Runs[0].ExchangedAmount.String())
+	assert.Nil(t, resp.Runs[1].ExchangedAmount)
+	assert.Equal(t, "insufficient_funds", resp.Runs[1].ErrorCode)
+}
+
+func TestScheduledExchangeService_CancelSchedule_ReadOnlyRoleDenied(t *testing.T) {
+	service, m := setupScheduledExchangeService(t)
+	ctx := context.Background()
+
+	schedule := newDueSchedule(uuid.New(), "@daily")
+	m.schedules.On("GetByID", ctx, schedule.ID).Return(&schedule, nil)
+
+	_, err := service.CancelSchedule(ctx, &models.JWTClaims{UserID: uuid.New(), Role: models.RoleSupport}, schedule.ID)
+
+	assert.ErrorIs(t, err, custom_err.ErrNotFound)
+	m.schedules.AssertNotCalled(t, "Cancel", mock.Anything, mock.Anything)
+}
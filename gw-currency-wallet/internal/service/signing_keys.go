@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/jwtkeys"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/secretbox"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// signingKeysCheckInterval период проверки необходимости ротации и перечитывания ключей
+	signingKeysCheckInterval = time.Minute
+	// signingKeysReloadInterval минимальный интервал перечитывания ключей при встрече неизвестного kid
+	signingKeysReloadInterval = 10 * time.Second
+)
+
+var errKeyNotFound = errors.New("signing key not found")
+
+// SigningKeys ключи подписи и проверки JWT
+type SigningKeys interface {
+	// SigningKey возвращает ключ, которым подписываются новые токены
+	SigningKey(ctx context.Context) (*jwtkeys.Key, error)
+	// VerificationKey возвращает ключ для проверки токена по kid из заголовка
+	VerificationKey(ctx context.Context, kid string) (*jwtkeys.Key, error)
+	// JWKS возвращает открытые ключи для проверки токенов другими сервисами
+	JWKS(ctx context.Context) (jwtkeys.JWKSet, error)
+}
+
+// HMACSigningKeys единственный общий секрет HS256. Открытых ключей нет, JWKS пуст.
+type HMACSigningKeys struct {
+	key jwtkeys.Key
+}
+
+func NewHMACSigningKeys(secret string) SigningKeys {
+	return &HMACSigningKeys{key: jwtkeys.Key{Algorithm: jwtkeys.AlgHS256, Secret: []byte(secret)}}
+}
+
+func (k *HMACSigningKeys) SigningKey(ctx context.Context) (*jwtkeys.Key, error) {
+	return &k.key, nil
+}
+
+func (k *HMACSigningKeys) VerificationKey(ctx context.Context, kid string) (*jwtkeys.Key, error) {
+	if kid != "" {
+		return nil, errKeyNotFound
+	}
+	return &k.key, nil
+}
+
+func (k *HMACSigningKeys) JWKS(ctx context.Context) (jwtkeys.JWKSet, error) {
+	return jwtkeys.JWKSet{Keys: []jwtkeys.JWK{}}, nil
+}
+
+// RotatingSigningKeys асимметричные ключи из таблицы signing_keys с плановой ротацией.
+// Каждый ключ подписывает токены в течение rotation, следующий ключ публикуется в JWKS
+// за overlap до начала использования, а выведенный из подписи ключ принимается еще overlap.
+// Закрытые ключи хранятся зашифрованными box.
+type RotatingSigningKeys struct {
+	repo      postgres.SigningKeyRepository
+	txManager TxManager
+	box       *secretbox.Box
+	algorithm string
+	rotation  time.Duration
+	overlap   time.Duration
+	log       *slog.Logger
+	now       func() time.Time
+
+	mu       sync.RWMutex
+	keys     []jwtkeys.Key // по возрастанию ActivatesAt
+	loadedAt time.Time
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+func NewRotatingSigningKeys(
+	repo postgres.SigningKeyRepository,
+	txManager TxManager,
+	box *secretbox.Box,
+	algorithm string,
+	rotation time.Duration,
+	overlap time.Duration,
+	log *slog.Logger,
+) (*RotatingSigningKeys, error) {
+	if !jwtkeys.IsAsymmetric(algorithm) {
+		return nil, fmt.Errorf("%w: %s", jwtkeys.ErrUnsupportedAlgorithm, algorithm)
+	}
+	return &RotatingSigningKeys{
+		repo:      repo,
+		txManager: txManager,
+		box:       box,
+		algorithm: algorithm,
+		rotation:  rotation,
+		overlap:   overlap,
+		log:       log,
+		now:       time.Now,
+		stopCh:    make(chan struct{}),
+	}, nil
+}
+
+// Start запускает фоновую ротацию ключей
+func (k *RotatingSigningKeys) Start() {
+	k.wg.Add(1)
+	go k.rotationLoop()
+}
+
+func (k *RotatingSigningKeys) rotationLoop() {
+	defer k.wg.Done()
+
+	ticker := time.NewTicker(signingKeysCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := k.Rotate(ctx); err != nil {
+				k.log.Error("ошибка ротации ключей подписи", slog.String("error", err.Error()))
+			}
+			cancel()
+		case <-k.stopCh:
+			return
+		}
+	}
+}
+
+func (k *RotatingSigningKeys) Shutdown(ctx context.Context) error {
+	close(k.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		k.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Rotate удаляет истекшие ключи, создает текущий и следующий ключи, если их еще нет,
+// и перечитывает набор ключей. Безопасно вызывать с нескольких экземпляров сервиса.
+func (k *RotatingSigningKeys) Rotate(ctx context.Context) error {
+	const op = "service.SigningKeys.Rotate"
+
+	now := k.now()
+	var stored []models.SigningKey
+	err := k.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+		if err := k.repo.LockRotationTx(ctx, tx); err != nil {
+			return err
+		}
+		if err := k.repo.DeleteExpiredTx(ctx, tx, now); err != nil {
+			return err
+		}
+
+		var err error
+		stored, err = k.repo.ListValidTx(ctx, tx, now)
+		if err != nil {
+			return err
+		}
+		if err := k.sealPlaintextKeysTx(ctx, tx, stored); err != nil {
+			return err
+		}
+
+		current := k.latestSigning(stored, now)
+		if current == nil {
+			key, err := k.createKeyTx(ctx, tx, now)
+			if err != nil {
+				return err
+			}
+			stored = append(stored, *key)
+			current = key
+		}
+
+		// Следующий ключ публикуется заранее, чтобы проверяющие успели получить его из JWKS
+		if current.DeactivatesAt.Sub(now) <= k.overlap && !k.hasSuccessor(stored, current) {
+			key, err := k.createKeyTx(ctx, tx, current.DeactivatesAt)
+			if err != nil {
+				return err
+			}
+			stored = append(stored, *key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := k.setKeys(stored); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (k *RotatingSigningKeys) latestSigning(stored []models.SigningKey, now time.Time) *models.SigningKey {
+	var latest *models.SigningKey
+	for i := range stored {
+		key := &stored[i]
+		if key.Algorithm != k.algorithm || now.Before(key.ActivatesAt) || !now.Before(key.DeactivatesAt) {
+			continue
+		}
+		if latest == nil || key.ActivatesAt.After(latest.ActivatesAt) {
+			latest = key
+		}
+	}
+	return latest
+}
+
+func (k *RotatingSigningKeys) hasSuccessor(stored []models.SigningKey, current *models.SigningKey) bool {
+	for _, key := range stored {
+		if key.Algorithm == k.algorithm && !key.ActivatesAt.Before(current.DeactivatesAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *RotatingSigningKeys) createKeyTx(ctx context.Context, tx pgx.Tx, activatesAt time.Time) (*models.SigningKey, error) {
+	private, err := jwtkeys.GeneratePrivateKey(k.algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	encoded, err := jwtkeys.MarshalPrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+	kid := uuid.NewString()
+	sealed, err := k.box.Seal(encoded, kid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+
+	deactivatesAt := activatesAt.Add(k.rotation)
+	key := &models.SigningKey{
+		KID:           kid,
+		Algorithm:     k.algorithm,
+		PrivateKey:    sealed,
+		ActivatesAt:   activatesAt,
+		DeactivatesAt: deactivatesAt,
+		ExpiresAt:     deactivatesAt.Add(k.overlap),
+	}
+	if err := k.repo.CreateTx(ctx, tx, *key); err != nil {
+		return nil, err
+	}
+
+	k.log.Info("создан ключ подписи JWT",
+		slog.String("kid", key.KID),
+		slog.String("algorithm", key.Algorithm),
+		slog.Time("activates_at", key.ActivatesAt))
+	return key, nil
+}
+
+// sealPlaintextKeysTx шифрует ключи, сохраненные открытыми до включения шифрования
+func (k *RotatingSigningKeys) sealPlaintextKeysTx(ctx context.Context, tx pgx.Tx, stored []models.SigningKey) error {
+	for i := range stored {
+		if secretbox.IsSealed(stored[i].PrivateKey) {
+			continue
+		}
+		sealed, err := k.box.Seal(stored[i].PrivateKey, stored[i].KID)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt signing key %s: %w", stored[i].KID, err)
+		}
+		if err := k.repo.UpdatePrivateKeyTx(ctx, tx, stored[i].KID, sealed); err != nil {
+			return err
+		}
+		stored[i].PrivateKey = sealed
+		k.log.Info("ключ подписи JWT зашифрован", slog.String("kid", stored[i].KID))
+	}
+	return nil
+}
+
+func (k *RotatingSigningKeys) setKeys(stored []models.SigningKey) error {
+	keys := make([]jwtkeys.Key, 0, len(stored))
+	for _, s := range stored {
+		encoded := s.PrivateKey
+		// Открытый ключ мог записать экземпляр без шифрования; его зашифрует следующая ротация
+		if secretbox.IsSealed(encoded) {
+			var err error
+			if encoded, err = k.box.Open(encoded, s.KID); err != nil {
+				return fmt.Errorf("failed to decrypt signing key %s: %w", s.KID, err)
+			}
+		}
+		private, err := jwtkeys.ParsePrivateKey(s.Algorithm, encoded)
+		if err != nil {
+			return fmt.Errorf("failed to parse signing key %s: %w", s.KID, err)
+		}
+		keys = append(keys, jwtkeys.Key{
+			ID:            s.KID,
+			Algorithm:     s.Algorithm,
+			Private:       private,
+			ActivatesAt:   s.ActivatesAt,
+			DeactivatesAt: s.DeactivatesAt,
+			ExpiresAt:     s.ExpiresAt,
+		})
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.loadedAt = k.now()
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *RotatingSigningKeys) reload(ctx context.Context) error {
+	stored, err := k.repo.ListValid(ctx, k.now())
+	if err != nil {
+		return err
+	}
+	return k.setKeys(stored)
+}
+
+func (k *RotatingSigningKeys) SigningKey(ctx context.Context) (*jwtkeys.Key, error) {
+	const op = "service.SigningKeys.SigningKey"
+
+	if key := k.findSigning(k.now()); key != nil {
+		return key, nil
+	}
+
+	// Фоновая ротация не успела или не смогла выполниться
+	if err := k.Rotate(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if key := k.findSigning(k.now()); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s: no active signing key", op)
+}
+
+func (k *RotatingSigningKeys) findSigning(now time.Time) *jwtkeys.Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if k.keys[i].Algorithm == k.algorithm && k.keys[i].SignsAt(now) {
+			key := k.keys[i]
+			return &key
+		}
+	}
+	return nil
+}
+
+func (k *RotatingSigningKeys) VerificationKey(ctx context.Context, kid string) (*jwtkeys.Key, error) {
+	const op = "service.SigningKeys.VerificationKey"
+
+	if key := k.findByID(kid, k.now()); key != nil {
+		return key, nil
+	}
+
+	// Ключ мог быть создан другим экземпляром сервиса после последнего чтения
+	k.mu.RLock()
+	stale := k.now().Sub(k.loadedAt) >= signingKeysReloadInterval
+	k.mu.RUnlock()
+	if !stale {
+		return nil, errKeyNotFound
+	}
+	if err := k.reload(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if key := k.findByID(kid, k.now()); key != nil {
+		return key, nil
+	}
+	return nil, errKeyNotFound
+}
+
+func (k *RotatingSigningKeys) findByID(kid string, now time.Time) *jwtkeys.Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID == kid && now.Before(key.ExpiresAt) {
+			return &key
+		}
+	}
+	return nil
+}
+
+func (k *RotatingSigningKeys) JWKS(ctx context.Context) (jwtkeys.JWKSet, error) {
+	const op = "service.SigningKeys.JWKS"
+
+	now := k.now()
+	k.mu.RLock()
+	keys := make([]jwtkeys.Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if now.Before(key.ExpiresAt) {
+			keys = append(keys, key)
+		}
+	}
+	k.mu.RUnlock()
+
+	set := jwtkeys.JWKSet{Keys: make([]jwtkeys.JWK, 0, len(keys))}
+	for i := range keys {
+		jwk, err := jwtkeys.ToJWK(&keys[i])
+		if err != nil {
+			return jwtkeys.JWKSet{}, fmt.Errorf("%s: %w", op, err)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/jwtkeys"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/secretbox"
+)
+
+var signingKeysNow = time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+func setupRotatingKeys(t *testing.T, alg string) (*RotatingSigningKeys, *MockSigningKeyRepository, *MockTxManager) {
+	repo := new(MockSigningKeyRepository)
+	txManager := new(MockTxManager)
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	keys, err := NewRotatingSigningKeys(repo, txManager, newTestSecretBox(), alg, 30*24*time.Hour, time.Hour, log)
+	require.NoError(t, err)
+	keys.now = func() time.Time { return signingKeysNow }
+	return keys, repo, txManager
+}
+
+func newStoredKey(t *testing.T, alg string, activatesAt time.Time, rotation, overlap time.Duration) models.SigningKey {
+	private, err := jwtkeys.GeneratePrivateKey(alg)
+	require.NoError(t, err)
+	encoded, err := jwtkeys.MarshalPrivateKey(private)
+	require.NoError(t, err)
+	kid := uuid.NewString()
+	sealed, err := newTestSecretBox().Seal(encoded, kid)
+	require.NoError(t, err)
+
+	return models.SigningKey{
+		KID:           kid,
+		Algorithm:     alg,
+		PrivateKey:    sealed,
+		ActivatesAt:   activatesAt,
+		DeactivatesAt: activatesAt.Add(rotation),
+		ExpiresAt:     activatesAt.Add(rotation + overlap),
+	}
+}
+
+// openStoredKey расшифровывает закрытый ключ из newStoredKey
+func openStoredKey(t *testing.T, stored models.SigningKey) string {
+	encoded, err := newTestSecretBox().Open(stored.PrivateKey, stored.KID)
+	require.NoError(t, err)
+	return encoded
+}
+
+func TestRotatingSigningKeys_Rotate_CreatesFirstKey(t *testing.T) {
+	keys, repo, txManager := setupRotatingKeys(t, jwtkeys.AlgEdDSA)
+	ctx := context.Background()
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	repo.On("LockRotationTx", ctx, mock.Anything).Return(nil)
+	repo.On("DeleteExpiredTx", ctx, mock.Anything, signingKeysNow).Return(nil)
+	repo.On("ListValidTx", ctx, mock.Anything, signingKeysNow).Return([]models.SigningKey{}, nil)
+	repo.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(k models.SigningKey) bool {
+		return k.Algorithm == jwtkeys.AlgEdDSA &&
+			secretbox.IsSealed(k.PrivateKey) &&
+			k.ActivatesAt.Equal(signingKeysNow) &&
+			k.DeactivatesAt.Equal(signingKeysNow.Add(30*24*time.Hour)) &&
+			k.ExpiresAt.Equal(k.DeactivatesAt.Add(time.Hour))
+	})).Return(nil).Once()
+
+	require.NoError(t, keys.Rotate(ctx))
+
+	key, err := keys.SigningKey(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, jwtkeys.AlgEdDSA, key.Algorithm)
+	assert.NotEmpty(t, key.ID)
+
+	repo.AssertExpectations(t)
+}
+
+func TestRotatingSigningKeys_Rotate_PublishesSuccessorBeforeUse(t *testing.T) {
+	keys, repo, txManager := setupRotatingKeys(t, jwtkeys.AlgEdDSA)
+	ctx := context.Background()
+
+	// Текущий ключ перестает подписывать через 30 минут - меньше окна перекрытия
+	current := newStoredKey(t, jwtkeys.AlgEdDSA, signingKeysNow.Add(-30*24*time.Hour+30*time.Minute), 30*24*time.Hour, time.Hour)
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	repo.On("LockRotationTx", ctx, mock.Anything).Return(nil)
+	repo.On("DeleteExpiredTx", ctx, mock.Anything, signingKeysNow).Return(nil)
+	repo.On("ListValidTx", ctx, mock.Anything, signingKeysNow).Return([]models.SigningKey{current}, nil)
+	repo.On("CreateTx", ctx, mock.Anything, mock.MatchedBy(func(k models.SigningKey) bool {
+		return k.ActivatesAt.Equal(current.DeactivatesAt)
+	})).Return(nil).Once()
+
+	require.NoError(t, keys.Rotate(ctx))
+
+	// Подписывает по-прежнему текущий ключ, но следующий уже опубликован
+	signing, err := keys.SigningKey(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, current.KID, signing.ID)
+
+	set, err := keys.JWKS(ctx)
+	require.NoError(t, err)
+	assert.Len(t, set.Keys, 2)
+
+	repo.AssertExpectations(t)
+}
+
+func TestRotatingSigningKeys_Rotate_NothingToDo(t *testing.T) {
+	keys, repo, txManager := setupRotatingKeys(t, jwtkeys.AlgRS256)
+	ctx := context.Background()
+
+	current := newStoredKey(t, jwtkeys.AlgRS256, signingKeysNow.Add(-time.Hour), 30*24*time.Hour, time.Hour)
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	repo.On("LockRotationTx", ctx, mock.Anything).Return(nil)
+	repo.On("DeleteExpiredTx", ctx, mock.Anything, signingKeysNow).Return(nil)
+	repo.On("ListValidTx", ctx, mock.Anything, signingKeysNow).Return([]models.SigningKey{current}, nil)
+
+	require.NoError(t, keys.Rotate(ctx))
+
+	repo.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "UpdatePrivateKeyTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRotatingSigningKeys_Rotate_SealsPlaintextKey(t *testing.T) {
+	keys, repo, txManager := setupRotatingKeys(t, jwtkeys.AlgEdDSA)
+	ctx := context.Background()
+
+	// Ключ, сохраненный до включения шифрования
+	current := newStoredKey(t, jwtkeys.AlgEdDSA, signingKeysNow.Add(-time.Hour), 30*24*time.Hour, time.Hour)
+	encoded := openStoredKey(t, current)
+	legacy := current
+	legacy.PrivateKey = encoded
+
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	repo.On("LockRotationTx", ctx, mock.Anything).Return(nil)
+	repo.On("DeleteExpiredTx", ctx, mock.Anything, signingKeysNow).Return(nil)
+	repo.On("ListValidTx", ctx, mock.Anything, signingKeysNow).Return([]models.SigningKey{legacy}, nil)
+	repo.On("UpdatePrivateKeyTx", ctx, mock.Anything, current.KID, mock.MatchedBy(func(sealed string) bool {
+		opened, err := newTestSecretBox().Open(sealed, current.KID)
+		return err == nil && opened == encoded
+	})).Return(nil).Once()
+
+	require.NoError(t, keys.Rotate(ctx))
+
+	signing, err := keys.SigningKey(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, current.KID, signing.ID)
+	repo.AssertExpectations(t)
+}
+
+func TestRotatingSigningKeys_SetKeys_WrongKID(t *testing.T) {
+	keys, _, _ := setupRotatingKeys(t, jwtkeys.AlgEdDSA)
+
+	// Зашифрованный ключ, перенесенный в строку с другим kid, не расшифровывается
+	stored := newStoredKey(t, jwtkeys.AlgEdDSA, signingKeysNow.Add(-time.Hour), 30*24*time.Hour, time.Hour)
+	stored.KID = uuid.NewString()
+
+	assert.Error(t, keys.setKeys([]models.SigningKey{stored}))
+}
+
+func TestRotatingSigningKeys_JWKS(t *testing.T) {
+	keys, _, _ := setupRotatingKeys(t, jwtkeys.AlgEdDSA)
+	ctx := context.Background()
+
+	stored := newStoredKey(t, jwtkeys.AlgEdDSA, signingKeysNow.Add(-time.Hour), 30*24*time.Hour, time.Hour)
+	require.NoError(t, keys.setKeys([]models.SigningKey{stored}))
+
+	set, err := keys.JWKS(ctx)
+	require.NoError(t, err)
+	require.Len(t, set.Keys, 1)
+
+	jwk := set.Keys[0]
+	assert.Equal(t, "OKP", jwk.Kty)
+	assert.Equal(t, "Ed25519", jwk.Crv)
+	assert.Equal(t, "sig", jwk.Use)
+	assert.Equal(t, jwtkeys.AlgEdDSA, jwk.Alg)
+	assert.Equal(t, stored.KID, jwk.Kid)
+
+	private, err := jwtkeys.ParsePrivateKey(jwtkeys.AlgEdDSA, openStoredKey(t, stored))
+	require.NoError(t, err)
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	require.NoError(t, err)
+	assert.Equal(t, private.Public(), ed25519.PublicKey(x))
+}
+
+func TestAuthService_AsymmetricTokens(t *testing.T) {
+	for _, alg := range []string{jwtkeys.AlgRS256, jwtkeys.AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			service, _, _, tokenRepo, _ := setupAuthService()
+			ctx := context.Background()
+
+			keys, repo, _ := setupRotatingKeys(t, alg)
+			service.keys = keys
+			repo.On("ListValid", ctx, signingKeysNow).Return([]models.SigningKey{}, nil)
+			tokenRepo.On("IsAccessTokenRevoked", ctx, mock.Anything).Return(false, nil)
+
+			rotation := 30 * 24 * time.Hour
+			previous := newStoredKey(t, alg, signingKeysNow.Add(-rotation-10*time.Minute), rotation, time.Hour)
+			current := newStoredKey(t, alg, previous.DeactivatesAt, rotation, time.Hour)
+			require.NoError(t, keys.setKeys([]models.SigningKey{previous, current}))
+
+			user := &models.User{ID: uuid.New(), Username: "testuser"}
+
+			// Новые токены подписываются текущим ключом
+			token, err := service.generateJWT(ctx, user, time.Now(), []string{models.AMRPassword})
+			require.NoError(t, err)
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &models.JWTClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, current.KID, parsed.Header["kid"])
+			assert.Equal(t, alg, parsed.Header["alg"])
+
+			claims, err := service.ValidateToken(ctx, token)
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, claims.UserID)
+
+			// Токены предыдущего ключа принимаются в окне перекрытия
+			oldToken := signWithStoredKey(t, previous, user)
+			_, err = service.ValidateToken(ctx, oldToken)
+			assert.NoError(t, err)
+
+			// Неизвестный kid отклоняется
+			unknown := newStoredKey(t, alg, signingKeysNow.Add(-time.Hour), rotation, time.Hour)
+			_, err = service.ValidateToken(ctx, signWithStoredKey(t, unknown, user))
+			assert.Equal(t, custom_err.ErrInvalidToken, err)
+
+			// HS256 токен с kid асимметричного ключа отклоняется
+			forged := jwt.NewWithClaims(jwt.SigningMethodHS256, newTestClaims(user))
+			forged.Header["kid"] = current.KID
+			forgedToken, err := forged.SignedString([]byte("test-secret"))
+			require.NoError(t, err)
+			_, err = service.ValidateToken(ctx, forgedToken)
+			assert.Equal(t, custom_err.ErrInvalidToken, err)
+		})
+	}
+}
+
+func TestAuthService_ExpiredSigningKeyRejected(t *testing.T) {
+	service, _, _, _, _ := setupAuthService()
+	ctx := context.Background()
+
+	keys, repo, _ := setupRotatingKeys(t, jwtkeys.AlgEdDSA)
+	service.keys = keys
+
+	rotation := 30 * 24 * time.Hour
+	expired := newStoredKey(t, jwtkeys.AlgEdDSA, signingKeysNow.Add(-rotation-2*time.Hour), rotation, time.Hour)
+	current := newStoredKey(t, jwtkeys.AlgEdDSA, expired.DeactivatesAt, rotation, time.Hour)
+	require.NoError(t, keys.setKeys([]models.SigningKey{expired, current}))
+	keys.loadedAt = signingKeysNow.Add(-time.Minute)
+	repo.On("ListValid", ctx, signingKeysNow).Return([]models.SigningKey{current}, nil).Once()
+
+	token := signWithStoredKey(t, expired, &models.User{ID: uuid.New(), Username: "testuser"})
+	_, err := service.ValidateToken(ctx, token)
+
+	assert.Equal(t, custom_err.ErrInvalidToken, err)
+}
+
+func newTestClaims(user *models.User) models.JWTClaims {
+	return models.JWTClaims{
+		UserID:   user.ID,
+		Username: user.Username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+}
+
+func signWithStoredKey(t *testing.T, stored models.SigningKey, user *models.User) string {
+	private, err := jwtkeys.ParsePrivateKey(stored.Algorithm, openStoredKey(t, stored))
+	require.NoError(t, err)
+	key := &jwtkeys.Key{ID: stored.KID, Algorithm: stored.Algorithm, Private: private}
+	method, err := key.SigningMethod()
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(method, newTestClaims(user))
+	token.Header["kid"] = stored.KID
+	signed, err := token.SignedString(key.SignKey())
+	require.NoError(t, err)
+	return signed
+}
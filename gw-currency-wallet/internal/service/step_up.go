@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// StepUp решает, требует ли операция свежего подтверждения вторым фактором
+type StepUp interface {
+	// Require возвращает custom_err.ErrStepUpRequired, если сумма списания превышает порог для валюты,
+	// а токен не подтвержден вторым фактором в пределах допустимого возраста
+	Require(ctx context.Context, claims *models.JWTClaims, operation models.StepUpOperation) error
+}
+
+type StepUpPolicy struct {
+	quotes     postgres.QuoteRepository
+	holds      postgres.HoldRepository
+	currencies CurrencyRegistry
+	// thresholds суммы в основных единицах валюты; валюты без порога step-up не требуют
+	thresholds map[models.Currency]decimal.Decimal
+	maxAge     time.Duration
+}
+
+func NewStepUpPolicy(
+	quotes postgres.QuoteRepository,
+	holds postgres.HoldRepository,
+	currencies CurrencyRegistry,
+	thresholds map[models.Currency]decimal.Decimal,
+	maxAge time.Duration,
+) StepUp {
+	return &StepUpPolicy{
+		quotes:     quotes,
+		holds:      holds,
+		currencies: currencies,
+		thresholds: thresholds,
+		maxAge:     maxAge,
+	}
+}
+
+func (p *StepUpPolicy) Require(ctx context.Context, claims *models.JWTClaims, operation models.StepUpOperation) error {
+	const op = "service.StepUpPolicy.Require"
+
+	if len(p.thresholds) == 0 || claims.SteppedUp(p.maxAge, time.Now()) {
+		return nil
+	}
+
+	currency, amount := operation.DebitCurrency(), operation.Amount
+	// Обмен по котировке выполняется на условиях котировки, поэтому валюта и сумма
+	// берутся из нее, а не из запроса
+	if operation.QuoteID != nil {
+		quote, err := p.quotes.GetByID(ctx, *operation.QuoteID)
+		if err != nil {
+			// Чужую или несуществующую котировку отклонит сам обмен
+			if errors.Is(err, custom_err.ErrNotFound) {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if quote.UserID != claims.UserID {
+			return nil
+		}
+		info, err := p.currencies.Get(ctx, quote.FromCurrency)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		currency, amount = quote.FromCurrency, models.AmountFromMinorUnits(quote.Amount, info.Exponent)
+	}
+	// Списание по холду в валюте холда; без суммы списывается весь холд
+	if operation.HoldID != nil {
+		hold, err := p.holds.GetByID(ctx, *operation.HoldID)
+		if err != nil {
+			// Чужой или несуществующий холд отклонит сам сервис
+			if errors.Is(err, custom_err.ErrNotFound) {
+				return nil
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if hold.UserID != claims.UserID {
+			return nil
+		}
+		info, err := p.currencies.Get(ctx, models.Currency(hold.Currency))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		currency = models.Currency(hold.Currency)
+		if held := models.AmountFromMinorUnits(hold.Amount, info.Exponent); amount.IsZero() || amount.GreaterThan(held) {
+			amount = held
+		}
+	}
+
+	threshold, ok := p.thresholds[currency]
+	if !ok || amount.LessThanOrEqual(threshold) {
+		return nil
+	}
+	return custom_err.ErrStepUpRequired
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+func TestStepUpPolicy_Require(t *testing.T) {
+	userID := uuid.New()
+	plain := &models.JWTClaims{UserID: userID, AMR: []string{models.AMRPassword}, AuthTime: jwt.NewNumericDate(time.Now())}
+	steppedUp := &models.JWTClaims{UserID: userID, AMR: []string{models.AMRPassword, models.AMROTP}, AuthTime: jwt.NewNumericDate(time.Now())}
+
+	ownQuote := &models.ExchangeQuote{ID: uuid.New(), UserID: userID, FromCurrency: models.CurrencyUSD, ToCurrency: models.CurrencyEUR, Amount: 250000}
+	smallQuote := &models.ExchangeQuote{ID: uuid.New(), UserID: userID, FromCurrency: models.CurrencyUSD, ToCurrency: models.CurrencyEUR, Amount: 50000}
+	foreignQuote := &models.ExchangeQuote{ID: uuid.New(), UserID: uuid.New(), FromCurrency: models.CurrencyUSD, ToCurrency: models.CurrencyEUR, Amount: 250000}
+	missingQuote := uuid.New()
+
+	quotes := new(MockQuoteRepository)
+	quotes.On("GetByID", context.Background(), ownQuote.ID).Return(ownQuote, nil)
+	quotes.On("GetByID", context.Background(), smallQuote.ID).Return(smallQuote, nil)
+	quotes.On("GetByID", context.Background(), foreignQuote.ID).Return(foreignQuote, nil)
+	quotes.On("GetByID", context.Background(), missingQuote).Return(nil, custom_err.ErrNotFound)
+
+	largeHold := &models.Hold{ID: uuid.New(), UserID: userID, Currency: "USD", Amount: 300000}
+	foreignHold := &models.Hold{ID: uuid.New(), UserID: uuid.New(), Currency: "USD", Amount: 300000}
+	missingHold := uuid.New()
+
+	holds := new(MockHoldRepository)
+	holds.On("GetByID", context.Background(), largeHold.ID).Return(largeHold, nil)
+	holds.On("GetByID", context.Background(), foreignHold.ID).Return(foreignHold, nil)
+	holds.On("GetByID", context.Background(), missingHold).Return(nil, custom_err.ErrNotFound)
+
+	thresholds := map[models.Currency]decimal.Decimal{models.CurrencyUSD: decimal.NewFromInt(1000)}
+	policy := NewStepUpPolicy(quotes, holds, newTestCurrencyRegistry(), thresholds, 5*time.Minute)
+
+	tests := []struct {
+		name      string
+		claims    *models.JWTClaims
+		operation models.StepUpOperation
+		wantErr   error
+	}{
+		{name: "withdraw at threshold", claims: plain,
+			operation: models.StepUpOperation{Amount: decimal.NewFromInt(1000), Currency: models.CurrencyUSD}},
+		{name: "withdraw above threshold", claims: plain,
+			operation: models.StepUpOperation{Amount: decimal.RequireFromString("1000.01"), Currency: models.CurrencyUSD},
+			wantErr:   custom_err.ErrStepUpRequired},
+		{name: "stepped up token", claims: steppedUp,
+			operation: models.StepUpOperation{Amount: decimal.NewFromInt(5000), Currency: models.CurrencyUSD}},
+		{name: "currency without threshold", claims: plain,
+			operation: models.StepUpOperation{Amount: decimal.NewFromInt(5000), Currency: models.CurrencyEUR}},
+		{name: "exchange uses debit currency", claims: plain,
+			operation: models.StepUpOperation{Amount: decimal.NewFromInt(5000), Currency: models.CurrencyEUR, FromCurrency: models.CurrencyUSD},
+			wantErr:   custom_err.ErrStepUpRequired},
+		{name: "amount from own quote", claims: plain,
+			operation: models.StepUpOperation{QuoteID: &ownQuote.ID},
+			wantErr:   custom_err.ErrStepUpRequired},
+		{name: "quote amount without currency", claims: plain,
+			operation: models.StepUpOperation{QuoteID: &ownQuote.ID, Amount: decimal.NewFromInt(2500)},
+			wantErr:   custom_err.ErrStepUpRequired},
+		{name: "quote terms override request", claims: plain,
+			operation: models.StepUpOperation{QuoteID: &ownQuote.ID, Amount: decimal.NewFromInt(1), FromCurrency: models.CurrencyEUR},
+			wantErr:   custom_err.ErrStepUpRequired},
+		{name: "small quote", claims: plain,
+			operation: models.StepUpOperation{QuoteID: &smallQuote.ID}},
+		{name: "foreign quote is left to exchange", claims: plain,
+			operation: models.StepUpOperation{QuoteID: &foreignQuote.ID}},
+		{name: "missing quote is left to exchange", claims: plain,
+			operation: models.StepUpOperation{QuoteID: &missingQuote}},
+		{name: "full capture of large hold", claims: plain,
+			operation: models.StepUpOperation{HoldID: &largeHold.ID},
+			wantErr:   custom_err.ErrStepUpRequired},
+		{name: "partial capture below threshold", claims: plain,
+			operation: models.StepUpOperation{HoldID: &largeHold.ID, Amount: decimal.NewFromInt(500)}},
+		{name: "capture currency comes from hold", claims: plain,
+			operation: models.StepUpOperation{HoldID: &largeHold.ID, Amount: decimal.NewFromInt(2000), Currency: models.CurrencyEUR},
+			wantErr:   custom_err.ErrStepUpRequired},
+		{name: "capture after step-up", claims: steppedUp,
+			operation: models.StepUpOperation{HoldID: &largeHold.ID}},
+		{name: "foreign hold is left to service", claims: plain,
+			operation: models.StepUpOperation{HoldID: &foreignHold.ID}},
+		{name: "missing hold is left to service", claims: plain,
+			operation: models.StepUpOperation{HoldID: &missingHold}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Require(context.Background(), tt.claims, tt.operation)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestStepUpPolicy_WithoutThresholds(t *testing.T) {
+	policy := NewStepUpPolicy(nil, nil, nil, nil, 5*time.Minute)
+
+	err := policy.Require(context.Background(), &models.JWTClaims{UserID: uuid.New()},
+		models.StepUpOperation{Amount: decimal.NewFromInt(1_000_000), Currency: models.CurrencyUSD})
+	assert.NoError(t, err)
+}
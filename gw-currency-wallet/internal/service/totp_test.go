@@ -0,0 +1,74 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Векторы RFC 6238 (приложение B) для SHA1, усеченные до 6 цифр
+func TestTOTPCode_RFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, totpCode(key, totpStep(time.Unix(tt.unix, 0)), totpDigits), "time %d", tt.unix)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := newTOTPSecret()
+	require.NoError(t, err)
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	current := totpStep(now)
+
+	step, ok := verifyTOTP(secret, totpCode(key, current, totpDigits), now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, current, step)
+
+	// Соседние интервалы принимаются из-за расхождения часов, более далекие - нет
+	step, ok = verifyTOTP(secret, totpCode(key, current-1, totpDigits), now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, current-1, step)
+	_, ok = verifyTOTP(secret, totpCode(key, current+2, totpDigits), now, 0)
+	assert.False(t, ok)
+
+	// Код уже использованного интервала повторно не принимается
+	_, ok = verifyTOTP(secret, totpCode(key, current, totpDigits), now, current)
+	assert.False(t, ok)
+
+	_, ok = verifyTOTP(secret, "12345", now, 0)
+	assert.False(t, ok)
+	_, ok = verifyTOTP("not base32!", "123456", now, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(totpURI("GW Wallet", "alice", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/GW Wallet:alice", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "GW Wallet", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+	assert.Equal(t, "30", uri.Query().Get("period"))
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage/postgres"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+type Transfer interface {
+	Transfer(ctx context.Context, senderID uuid.UUID, req models.TransferRequest) (*models.TransferResponse, error)
+}
+
+// RateProvider источник курсов для переводов с конвертацией
+type RateProvider interface {
+	GetExchangeRate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+type TransferService struct {
+	userRepo   postgres.UserRepository
+	walletRepo postgres.WalletRepository
+	ledger     postgres.LedgerRepository
+	outbox     postgres.OutboxRepository
+	limits     Limits
+	txManager  TxManager
+	rates      RateProvider
+	currencies CurrencyRegistry
+	rounding   models.RoundingMode
+	log        *slog.Logger
+}
+
+func NewTransferService(
+	userRepo postgres.UserRepository,
+	walletRepo postgres.WalletRepository,
+	ledger postgres.LedgerRepository,
+	outbox postgres.OutboxRepository,
+	limits Limits,
+	txManager TxManager,
+	rates RateProvider,
+	currencies CurrencyRegistry,
+	rounding models.RoundingMode,
+	log *slog.Logger,
+) Transfer {
+	return &TransferService{
+		userRepo:   userRepo,
+		walletRepo: walletRepo,
+		ledger:     ledger,
+		outbox:     outbox,
+		limits:     limits,
+		txManager:  txManager,
+		rates:      rates,
+		currencies: currencies,
+		rounding:   rounding,
+		log:        log,
+	}
+}
+
+func (s *TransferService) Transfer(ctx context.Context, senderID uuid.UUID, req models.TransferRequest) (*models.TransferResponse, error) {
+	const op = "service.Transfer"
+
+	if req.ToCurrency == "" {
+		req.ToCurrency = req.Currency
+	}
+	fromCurrency, err := requireEnabledCurrency(ctx, s.currencies, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	toCurrency := fromCurrency
+	if req.ToCurrency != req.Currency {
+		toCurrency, err = requireEnabledCurrency(ctx, s.currencies, req.ToCurrency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	amountInMinorUnits, err := toMinorUnits(req.Amount, fromCurrency)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Recipient) == "" || req.RequestID == "" {
+		return nil, custom_err.ErrInvalidInput
+	}
+
+	recipient, err := s.resolveRecipient(ctx, strings.TrimSpace(req.Recipient))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if recipient.ID == senderID {
+		return nil, custom_err.ErrSelfTransfer
+	}
+
+	rate := decimal.NewFromInt(1)
+	if req.Currency != req.ToCurrency {
+		rate, err = s.rates.GetExchangeRate(ctx, string(req.Currency), string(req.ToCurrency))
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to get exchange rate: %w", op, err)
+		}
+	}
+
+	receivedInMinorUnits, err := models.RoundToMinorUnits(req.Amount.Mul(rate), toCurrency.Exponent, s.rounding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", custom_err.ErrInvalidAmount, err.Error())
+	}
+	if receivedInMinorUnits <= 0 {
+		return nil, fmt.Errorf("%w: amount is too small to convert", custom_err.ErrInvalidAmount)
+	}
+	receivedAmount := models.AmountFromMinorUnits(receivedInMinorUnits, toCurrency.Exponent)
+	transferID := uuid.New()
+
+	s.log.Info("перевод пользователю",
+		slog.String("sender_id", senderID.String()),
+		slog.String("recipient_id", recipient.ID.String()),
+		slog.String("from", string(req.Currency)),
+		slog.String("to", string(req.ToCurrency)),
+		slog.String("amount", req.Amount.String()),
+		slog.String("rate", rate.String()))
+
+	err = s.txManager.WithTx(ctx, func(tx pgx.Tx) error {
+
+		exists, err := s.walletRepo.TransferExistsTx(ctx, tx, req.RequestID)
+		if err != nil {
+			return fmt.Errorf("failed to check transfer: %w", err)
+		}
+		if exists {
+			return custom_err.ErrDuplicateRequest
+		}
+
+		fromWallet, err := s.walletRepo.GetByUserAndCurrency(ctx, senderID, req.Currency)
+		if err != nil {
+			return fmt.Errorf("failed to get sender wallet: %w", err)
+		}
+
+		toWallet, err := s.walletRepo.GetOrCreateByUserAndCurrencyTx(ctx, tx, recipient.ID, req.ToCurrency)
+		if err != nil {
+			return fmt.Errorf("failed to get recipient wallet: %w", err)
+		}
+
+		// Блокируем кошельки в порядке возрастания ID, чтобы встречные переводы не приводили к deadlock
+		balances := make(map[uuid.UUID]models.WalletBalance, 2)
+		for _, walletID := range lockOrder(fromWallet.ID, toWallet.ID) {
+			balance, err := s.walletRepo.GetWalletBalanceForUpdateTx(ctx, tx, walletID)
+			if err != nil {
+				return fmt.Errorf("failed to lock wallet: %w", err)
+			}
+			balances[walletID] = balance
+		}
+
+		// Сумма, зарезервированная холдами, недоступна для перевода
+		if balances[fromWallet.ID].Available() < amountInMinorUnits {
+			return custom_err.ErrInsufficientFunds
+		}
+		senderBalanceAfter := balances[fromWallet.ID].Balance - amountInMinorUnits
+
+		if err := s.limits.CheckTx(ctx, tx, senderID, models.OperationTransferOut, req.Currency, amountInMinorUnits); err != nil {
+			return err
+		}
+		recipientBalanceAfter := balances[toWallet.ID].Balance + receivedInMinorUnits
+
+		postings := []models.Posting{
+			{AccountID: fromWallet.ID, Currency: string(req.Currency), Amount: -amountInMinorUnits},
+			{AccountID: toWallet.ID, Currency: string(req.ToCurrency), Amount: receivedInMinorUnits},
+		}
+		if req.Currency != req.ToCurrency {
+			// Конвертация идет через обменный пункт, как и при обычном обмене
+			fxFromAccountID, err := s.ledger.GetSystemAccountIDTx(ctx, tx, models.SystemAccountFXHouse, string(req.Currency))
+			if err != nil {
+				return fmt.Errorf("failed to get fx account: %w", err)
+			}
+			fxToAccountID, err := s.ledger.GetSystemAccountIDTx(ctx, tx, models.SystemAccountFXHouse, string(req.ToCurrency))
+			if err != nil {
+				return fmt.Errorf("failed to get fx account: %w", err)
+			}
+			postings = append(postings,
+				models.Posting{AccountID: fxFromAccountID, Currency: string(req.Currency), Amount: amountInMinorUnits},
+				models.Posting{AccountID: fxToAccountID, Currency: string(req.ToCurrency), Amount: -receivedInMinorUnits},
+			)
+		}
+
+		err = s.ledger.PostEntryTx(ctx, tx, models.JournalEntry{
+			EntryType: models.OperationTransfer,
+			RequestID: req.RequestID,
+			Postings:  postings,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to post ledger entry: %w", err)
+		}
+
+		err = s.walletRepo.CreateTransferTx(ctx, tx, models.Transfer{
+			ID:                    transferID,
+			SenderID:              senderID,
+			RecipientID:           recipient.ID,
+			FromCurrency:          string(req.Currency),
+			ToCurrency:            string(req.ToCurrency),
+			Amount:                amountInMinorUnits,
+			ReceivedAmount:        receivedInMinorUnits,
+			Rate:                  rate,
+			SenderBalanceAfter:    senderBalanceAfter,
+			RecipientBalanceAfter: recipientBalanceAfter,
+			RequestID:             req.RequestID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create transfer: %w", err)
+		}
+
+		if req.Amount.GreaterThanOrEqual(largeTransferThreshold) || receivedAmount.GreaterThanOrEqual(largeTransferThreshold) {
+			recipientID := recipient.ID
+			err = enqueueLargeTransferTx(ctx, s.outbox, tx, models.LargeTransferEvent{
+				TransactionID: req.RequestID,
+				Type:          string(models.OperationTransfer),
+				UserID:        senderID,
+				RecipientID:   &recipientID,
+				FromCurrency:  string(req.Currency),
+				ToCurrency:    string(req.ToCurrency),
+				Amount:        req.Amount.InexactFloat64(),
+				ExchangedAmt:  receivedAmount.InexactFloat64(),
+				Rate:          rate.InexactFloat64(),
+				Timestamp:     time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &models.TransferResponse{
+		Message:        "Transfer successful",
+		TransferID:     transferID.String(),
+		Recipient:      recipient.Username,
+		Amount:         req.Amount,
+		Currency:       string(req.Currency),
+		ReceivedAmount: receivedAmount,
+		ToCurrency:     string(req.ToCurrency),
+		Rate:           rate,
+	}, nil
+}
+
+// resolveRecipient ищет получателя по email, если значение содержит "@", иначе по username
+func (s *TransferService) resolveRecipient(ctx context.Context, recipient string) (*models.User, error) {
+	var (
+		user *models.User
+		err  error
+	)
+	if strings.Contains(recipient, "@") {
+		user, err = s.userRepo.GetByEmail(ctx, recipient)
+	} else {
+		user, err = s.userRepo.GetByUsername(ctx, recipient)
+	}
+	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrRecipientNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// lockOrder возвращает ID кошельков в детерминированном порядке блокировки
+func lockOrder(a, b uuid.UUID) []uuid.UUID {
+	if bytes.Compare(a[:], b[:]) <= 0 {
+		return []uuid.UUID{a, b}
+	}
+	return []uuid.UUID{b, a}
+}
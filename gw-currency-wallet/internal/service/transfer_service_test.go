@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+)
+
+type transferMocks struct {
+	userRepo   *MockUserRepository
+	walletRepo *MockWalletRepo
+	ledger     *MockLedgerRepo
+	txManager  *MockTxManager
+	rates      *MockRateProvider
+	outbox     *MockOutboxRepository
+}
+
+func setupTransferService() (*TransferService, transferMocks) {
+	m := transferMocks{
+		userRepo:   new(MockUserRepository),
+		walletRepo: new(MockWalletRepo),
+		ledger:     new(MockLedgerRepo),
+		txManager:  new(MockTxManager),
+		rates:      new(MockRateProvider),
+		outbox:     new(MockOutboxRepository),
+	}
+
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	service := &TransferService{
+		userRepo:   m.userRepo,
+		walletRepo: m.walletRepo,
+		ledger:     m.ledger,
+		outbox:     m.outbox,
+		limits:     stubLimits{},
+		txManager:  m.txManager,
+		rates:      m.rates,
+		currencies: newTestCurrencyRegistry(),
+		log:        log,
+	}
+
+	return service, m
+}
+
+func TestTransferService_Transfer_SameCurrency_Success(t *testing.T) {
+	service, m := setupTransferService()
+	ctx := context.Background()
+
+	senderID := uuid.New()
+	recipient := &models.User{ID: uuid.New(), Username: "bob"}
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: senderID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: recipient.ID, Currency: "USD"}
+
+	req := models.TransferRequest{
+		Recipient: "bob",
+		Amount:    decimal.RequireFromString("25"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "transfer-1",
+	}
+
+	m.userRepo.On("GetByUsername", ctx, "bob").Return(recipient, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("TransferExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	m.walletRepo.On("GetByUserAndCurrency", ctx, senderID, models.CurrencyUSD).Return(fromWallet, nil)
+	m.walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, recipient.ID, models.CurrencyUSD).Return(toWallet, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 10000}, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{Balance: 500}, nil)
+	m.ledger.On("PostEntryTx", ctx, mock.Anything, mock.MatchedBy(func(e models.JournalEntry) bool {
+		return e.EntryType == models.OperationTransfer && len(e.Postings) == 2 && e.Validate() == nil
+	})).Return(nil)
+	m.walletRepo.On("CreateTransferTx", ctx, mock.Anything, mock.MatchedBy(func(tr models.Transfer) bool {
+		return tr.SenderID == senderID &&
+			tr.RecipientID == recipient.ID &&
+			tr.Amount == 2500 &&
+			tr.ReceivedAmount == 2500 &&
+			tr.SenderBalanceAfter == 7500 &&
+			tr.RecipientBalanceAfter == 3000
+	})).Return(nil)
+
+	resp, err := service.Transfer(ctx, senderID, req)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, resp)
+	assert.Equal(t, "bob", resp.Recipient)
+	assert.Equal(t, "25", resp.ReceivedAmount.String())
+	assert.Equal(t, "USD", resp.ToCurrency)
+	assert.Equal(t, "1", resp.Rate.String())
+
+	m.walletRepo.AssertExpectations(t)
+	m.ledger.AssertExpectations(t)
+	m.rates.AssertNotCalled(t, "GetExchangeRate", mock.Anything, mock.Anything, mock.Anything)
+	m.outbox.AssertNotCalled(t, "CreateTx", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransferService_Transfer_CrossCurrency_ByEmail(t *testing.T) {
+	service, m := setupTransferService()
+	ctx := context.Background()
+
+	senderID := uuid.New()
+	recipient := &models.User{ID: uuid.New(), Username: "bob", Email: "bob@example.com"}
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: senderID, Currency: "USD"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: recipient.ID, Currency: "RUB"}
+
+	req := models.TransferRequest{
+		Recipient:  "bob@example.com",
+		Amount:     decimal.RequireFromString("400"),
+		Currency:   models.CurrencyUSD,
+		ToCurrency: models.CurrencyRUB,
+		RequestID:  "transfer-2",
+	}
+
+	m.userRepo.On("GetByEmail", ctx, "bob@example.com").Return(recipient, nil)
+	m.rates.On("GetExchangeRate", ctx, "USD", "RUB").Return(decimal.RequireFromString("90"), nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("TransferExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	m.walletRepo.On("GetByUserAndCurrency", ctx, senderID, models.CurrencyUSD).Return(fromWallet, nil)
+	m.walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, recipient.ID, models.CurrencyRUB).Return(toWallet, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 100000}, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{Balance: 0}, nil)
+	m.ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, "USD").Return(uuid.New(), nil)
+	m.ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountFXHouse, "RUB").Return(uuid.New(), nil)
+	m.ledger.On("PostEntryTx", ctx, mock.Anything, mock.MatchedBy(func(e models.JournalEntry) bool {
+		return len(e.Postings) == 4 && e.Validate() == nil
+	})).Return(nil)
+	m.walletRepo.On("CreateTransferTx", ctx, mock.Anything, mock.MatchedBy(func(tr models.Transfer) bool {
+		return tr.ReceivedAmount == 3600000 && tr.Rate.Equal(decimal.RequireFromString("90"))
+	})).Return(nil)
+	m.outbox.On("CreateTx", ctx, mock.Anything, models.OutboxEventLargeTransfer, req.RequestID, largeTransferPayload(func(e models.LargeTransferEvent) bool {
+		return e.Type == string(models.OperationTransfer) &&
+			e.UserID == senderID &&
+			e.RecipientID != nil && *e.RecipientID == recipient.ID
+	})).Return(nil)
+
+	resp, err := service.Transfer(ctx, senderID, req)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, resp)
+	assert.Equal(t, "36000", resp.ReceivedAmount.String())
+	assert.Equal(t, "RUB", resp.ToCurrency)
+
+	m.userRepo.AssertExpectations(t)
+	m.ledger.AssertExpectations(t)
+	m.walletRepo.AssertExpectations(t)
+	m.outbox.AssertExpectations(t)
+}
+
+func TestTransferService_Transfer_RecipientNotFound(t *testing.T) {
+	service, m := setupTransferService()
+	ctx := context.Background()
+
+	m.userRepo.On("GetByUsername", ctx, "ghost").Return(nil, custom_err.ErrNotFound)
+
+	resp, err := service.Transfer(ctx, uuid.New(), models.TransferRequest{
+		Recipient: "ghost",
+		Amount:    decimal.RequireFromString("10"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "transfer-3",
+	})
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrRecipientNotFound)
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestTransferService_Transfer_SelfTransfer(t *testing.T) {
+	service, m := setupTransferService()
+	ctx := context.Background()
+
+	senderID := uuid.New()
+	m.userRepo.On("GetByUsername", ctx, "alice").Return(&models.User{ID: senderID, Username: "alice"}, nil)
+
+	resp, err := service.Transfer(ctx, senderID, models.TransferRequest{
+		Recipient: "alice",
+		Amount:    decimal.RequireFromString("10"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "transfer-4",
+	})
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrSelfTransfer)
+	m.txManager.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+}
+
+func TestTransferService_Transfer_InsufficientFunds(t *testing.T) {
+	service, m := setupTransferService()
+	ctx := context.Background()
+
+	senderID := uuid.New()
+	recipient := &models.User{ID: uuid.New(), Username: "bob"}
+	fromWallet := &models.Wallet{ID: uuid.New(), UserID: senderID, Currency: "EUR"}
+	toWallet := &models.Wallet{ID: uuid.New(), UserID: recipient.ID, Currency: "EUR"}
+
+	req := models.TransferRequest{
+		Recipient: "bob",
+		Amount:    decimal.RequireFromString("50"),
+		Currency:  models.CurrencyEUR,
+		RequestID: "transfer-5",
+	}
+
+	m.userRepo.On("GetByUsername", ctx, "bob").Return(recipient, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("TransferExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	m.walletRepo.On("GetByUserAndCurrency", ctx, senderID, models.CurrencyEUR).Return(fromWallet, nil)
+	m.walletRepo.On("GetOrCreateByUserAndCurrencyTx", ctx, mock.Anything, recipient.ID, models.CurrencyEUR).Return(toWallet, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, fromWallet.ID).Return(models.WalletBalance{Balance: 1000}, nil)
+	m.walletRepo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, toWallet.ID).Return(models.WalletBalance{Balance: 0}, nil)
+
+	resp, err := service.Transfer(ctx, senderID, req)
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrInsufficientFunds)
+	m.ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+	m.walletRepo.AssertNotCalled(t, "CreateTransferTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransferService_Transfer_Duplicate(t *testing.T) {
+	service, m := setupTransferService()
+	ctx := context.Background()
+
+	recipient := &models.User{ID: uuid.New(), Username: "bob"}
+	req := models.TransferRequest{
+		Recipient: "bob",
+		Amount:    decimal.RequireFromString("10"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "transfer-6",
+	}
+
+	m.userRepo.On("GetByUsername", ctx, "bob").Return(recipient, nil)
+	m.txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	m.walletRepo.On("TransferExistsTx", ctx, mock.Anything, req.RequestID).Return(true, nil)
+
+	resp, err := service.Transfer(ctx, uuid.New(), req)
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrDuplicateRequest)
+}
+
+func TestTransferService_Transfer_InvalidInput(t *testing.T) {
+	service, _ := setupTransferService()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     models.TransferRequest
+		wantErr error
+	}{
+		{
+			name:    "invalid currency",
+			req:     models.TransferRequest{Recipient: "bob", Amount: decimal.RequireFromString("10"), Currency: "GBP", RequestID: "r"},
+			wantErr: custom_err.ErrInvalidCurrency,
+		},
+		{
+			name:    "invalid target currency",
+			req:     models.TransferRequest{Recipient: "bob", Amount: decimal.RequireFromString("10"), Currency: models.CurrencyUSD, ToCurrency: "GBP", RequestID: "r"},
+			wantErr: custom_err.ErrInvalidCurrency,
+		},
+		{
+			name:    "non-positive amount",
+			req:     models.TransferRequest{Recipient: "bob", Amount: decimal.RequireFromString("0"), Currency: models.CurrencyUSD, RequestID: "r"},
+			wantErr: custom_err.ErrInvalidAmount,
+		},
+		{
+			name:    "empty recipient",
+			req:     models.TransferRequest{Recipient: " ", Amount: decimal.RequireFromString("10"), Currency: models.CurrencyUSD, RequestID: "r"},
+			wantErr: custom_err.ErrInvalidInput,
+		},
+		{
+			name:    "empty request id",
+			req:     models.TransferRequest{Recipient: "bob", Amount: decimal.RequireFromString("10"), Currency: models.CurrencyUSD},
+			wantErr: custom_err.ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := service.Transfer(ctx, uuid.New(), tt.req)
+
+			assert.Nil(t, resp)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -10,25 +10,42 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
 )
 
 type Wallet interface {
-	GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
+	GetWalletByID(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.Wallet, error)
 
-	GetUserBalance(ctx context.Context, userID uuid.UUID) (*models.UserBalanceResponse, error)
+	GetUserBalance(ctx context.Context, userID uuid.UUID) (*models.BalanceResponse, error)
 	Deposit(ctx context.Context, userID uuid.UUID, req models.DepositRequest) (*models.BalanceOperationResponse, error)
 	Withdraw(ctx context.Context, userID uuid.UUID, req models.WithdrawRequest) (*models.BalanceOperationResponse, error)
+	GetTransactions(ctx context.Context, userID uuid.UUID, req models.TransactionHistoryRequest) (*models.TransactionHistoryResponse, error)
 }
 
 type WalletService struct {
-	repo      postgres.WalletRepository
-	txManager TxManager
+	repo       postgres.WalletRepository
+	ledger     postgres.LedgerRepository
+	limits     Limits
+	txManager  TxManager
+	currencies CurrencyRegistry
+	policy     Policy
 }
 
-func NewWalletService(repo postgres.WalletRepository, txManager TxManager) Wallet {
+func NewWalletService(
+	repo postgres.WalletRepository,
+	ledger postgres.LedgerRepository,
+	limits Limits,
+	txManager TxManager,
+	currencies CurrencyRegistry,
+	policy Policy,
+) Wallet {
 	return &WalletService{
-		repo:      repo,
-		txManager: txManager,
+		repo:       repo,
+		ledger:     ledger,
+		limits:     limits,
+		txManager:  txManager,
+		currencies: currencies,
+		policy:     policy,
 	}
 }
 
@@ -53,24 +70,51 @@ func (s *WalletService) UpdateBalance(ctx context.Context, req models.WalletOper
 			return fmt.Errorf("%s: failed to get balance: %w", op, err)
 		}
 
-		var newBalance int64
+		var signedAmount int64
 		switch req.OperationType {
 		case models.OperationDeposit:
-			newBalance = currentBalance + req.Amount
+			signedAmount = req.Amount
 		case models.OperationWithdraw:
-			newBalance = currentBalance - req.Amount
-			if newBalance < 0 {
-				return custom_err.ErrInsufficientFunds
-			}
+			signedAmount = -req.Amount
 		default:
 			return fmt.Errorf("%s: invalid operation type", op)
 		}
 
-		if err := s.repo.UpdateBalanceTx(ctx, tx, req.WalletID, newBalance); err != nil {
-			return fmt.Errorf("%s: failed to update balance: %w", op, err)
+		// Выводить можно только доступную сумму: зарезервированное холдами не списывается
+		if currentBalance.Available()+signedAmount < 0 {
+			return custom_err.ErrInsufficientFunds
+		}
+		newBalance := currentBalance.Balance + signedAmount
+
+		if err := s.limits.CheckTx(ctx, tx, req.UserID, req.OperationType, req.Currency, req.Amount); err != nil {
+			return err
+		}
+
+		cashAccountID, err := s.ledger.GetSystemAccountIDTx(ctx, tx, models.SystemAccountExternalCash, string(req.Currency))
+		if err != nil {
+			return fmt.Errorf("%s: failed to get cash account: %w", op, err)
+		}
+
+		err = s.ledger.PostEntryTx(ctx, tx, models.JournalEntry{
+			EntryType: req.OperationType,
+			RequestID: req.RequestID,
+			Postings: []models.Posting{
+				{AccountID: req.WalletID, Currency: string(req.Currency), Amount: signedAmount},
+				{AccountID: cashAccountID, Currency: string(req.Currency), Amount: -signedAmount},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("%s: failed to post ledger entry: %w", op, err)
 		}
 
-		if err := s.repo.CreateOperationTx(ctx, tx, req.WalletID, req.Amount, req.RequestID); err != nil {
+		err = s.repo.CreateOperationTx(ctx, tx, models.Operation{
+			WalletID:      req.WalletID,
+			OperationType: req.OperationType,
+			Amount:        signedAmount,
+			BalanceAfter:  newBalance,
+			RequestID:     req.RequestID,
+		})
+		if err != nil {
 			return fmt.Errorf("%s: failed to create operation: %w", op, err)
 		}
 
@@ -78,41 +122,56 @@ func (s *WalletService) UpdateBalance(ctx context.Context, req models.WalletOper
 	})
 }
 
-func (s *WalletService) GetWalletByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
+// GetWalletByID возвращает кошелек, если субъект токена имеет к нему доступ.
+// Чужой кошелек неотличим от несуществующего.
+func (s *WalletService) GetWalletByID(ctx context.Context, actor *models.JWTClaims, id uuid.UUID) (*models.Wallet, error) {
 	const op = "service.GetWalletByID"
 
 	wallet, err := s.repo.GetByID(ctx, id)
 	if err != nil {
+		if errors.Is(err, custom_err.ErrNotFound) {
+			return nil, custom_err.ErrNotFound
+		}
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := s.policy.AuthorizeWallet(actor, wallet, ActionRead); err != nil {
+		return nil, err
+	}
+
 	return wallet, nil
 }
 
-func (s *WalletService) GetUserBalance(ctx context.Context, userID uuid.UUID) (*models.UserBalanceResponse, error) {
+func (s *WalletService) GetUserBalance(ctx context.Context, userID uuid.UUID) (*models.BalanceResponse, error) {
 	const op = "service.GetUserBalance"
 
+	currencies, err := s.currencies.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	wallets, err := s.repo.GetAllUserWallets(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	response := &models.UserBalanceResponse{
-		USD: 0.0,
-		RUB: 0.0,
-		EUR: 0.0,
+	// Кошелек новой валюты создается при первой операции, до этого баланс нулевой
+	response := &models.BalanceResponse{
+		Balance:   make(models.UserBalanceResponse, len(currencies)),
+		Available: make(models.UserBalanceResponse, len(currencies)),
+	}
+	exps := make(exponents, len(currencies))
+	for _, currency := range currencies {
+		exps[string(currency.Code)] = currency.Exponent
+		if currency.Enabled {
+			response.Balance[string(currency.Code)] = decimal.Zero
+			response.Available[string(currency.Code)] = decimal.Zero
+		}
 	}
 
 	for _, wallet := range wallets {
-		balance := models.AmountFromMinorUnits(wallet.Balance)
-		switch models.Currency(wallet.Currency) {
-		case models.CurrencyUSD:
-			response.USD = balance
-		case models.CurrencyRUB:
-			response.RUB = balance
-		case models.CurrencyEUR:
-			response.EUR = balance
-		}
+		response.Balance[wallet.Currency] = models.AmountFromMinorUnits(wallet.Balance, exps.of(wallet.Currency))
+		response.Available[wallet.Currency] = models.AmountFromMinorUnits(wallet.Available(), exps.of(wallet.Currency))
 	}
 
 	return response, nil
@@ -130,24 +189,30 @@ func (s *WalletService) performOperation(
 	ctx context.Context,
 	userID uuid.UUID,
 	currency models.Currency,
-	amount float64,
+	amount decimal.Decimal,
 	requestID string,
 	opType models.OperationType,
 	successMsg string,
 ) (*models.BalanceOperationResponse, error) {
 	const op = "service.performOperation"
 
-	if !currency.IsValid() {
-		return nil, custom_err.ErrInvalidCurrency
+	currencyInfo, err := requireEnabledCurrency(ctx, s.currencies, currency)
+	if err != nil {
+		return nil, err
 	}
-	if amount <= 0 {
-		return nil, custom_err.ErrInvalidAmount
+	amountInMinorUnits, err := toMinorUnits(amount, currencyInfo)
+	if err != nil {
+		return nil, err
 	}
 	if requestID == "" {
 		return nil, custom_err.ErrInvalidInput
 	}
 
-	wallet, err := s.repo.GetByUserAndCurrency(ctx, userID, currency)
+	getWallet := s.repo.GetByUserAndCurrency
+	if opType == models.OperationDeposit {
+		getWallet = s.repo.GetOrCreateByUserAndCurrency
+	}
+	wallet, err := getWallet(ctx, userID, currency)
 	if err != nil {
 		if errors.Is(err, custom_err.ErrNotFound) {
 			return nil, custom_err.ErrNotFound
@@ -155,10 +220,10 @@ func (s *WalletService) performOperation(
 		return nil, fmt.Errorf("%s: failed to get wallet: %w", op, err)
 	}
 
-	amountInMinorUnits := models.AmountToMinorUnits(amount)
-
 	updateReq := models.WalletOperationRequest{
+		UserID:        userID,
 		WalletID:      wallet.ID,
+		Currency:      currency,
 		OperationType: opType,
 		Amount:        amountInMinorUnits,
 		RequestID:     requestID,
@@ -175,6 +240,106 @@ func (s *WalletService) performOperation(
 
 	return &models.BalanceOperationResponse{
 		Message:    successMsg,
-		NewBalance: *balances,
+		NewBalance: balances.Balance,
+		Available:  balances.Available,
 	}, nil
 }
+
+func (s *WalletService) GetTransactions(ctx context.Context, userID uuid.UUID, req models.TransactionHistoryRequest) (*models.TransactionHistoryResponse, error) {
+	const op = "service.GetTransactions"
+
+	// История доступна и по отключенным валютам
+	if req.Currency != "" {
+		if _, err := s.currencies.Get(ctx, req.Currency); err != nil {
+			return nil, err
+		}
+	}
+	switch req.Type {
+	case "", models.OperationDeposit, models.OperationWithdraw, models.OperationExchange,
+		models.OperationTransferIn, models.OperationTransferOut, models.OperationAdjustment, models.OperationCapture:
+	default:
+		return nil, fmt.Errorf("%w: unknown operation type %q", custom_err.ErrInvalidInput, req.Type)
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, fmt.Errorf("%w: 'from' must be before 'to'", custom_err.ErrInvalidInput)
+	}
+
+	limit := req.Limit
+	if limit == 0 {
+		limit = models.DefaultTransactionsLimit
+	}
+	if limit < 0 || limit > models.MaxTransactionsLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", custom_err.ErrInvalidInput, models.MaxTransactionsLimit)
+	}
+
+	filter := models.TransactionFilter{
+		Currency: req.Currency,
+		Type:     req.Type,
+		From:     req.From,
+		To:       req.To,
+		Limit:    limit + 1,
+	}
+	if req.Cursor != "" {
+		cursor, err := models.DecodeTransactionCursor(req.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", custom_err.ErrInvalidCursor, err.Error())
+		}
+		filter.After = cursor
+	}
+
+	records, err := s.repo.ListTransactions(ctx, userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	exps, err := loadExponents(ctx, s.currencies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := &models.TransactionHistoryResponse{
+		Transactions: make([]models.Transaction, 0, min(len(records), limit)),
+	}
+	for i, rec := range records {
+		if i == limit {
+			last := records[limit-1]
+			resp.NextCursor = models.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+			break
+		}
+		resp.Transactions = append(resp.Transactions, toTransaction(rec, exps))
+	}
+
+	return resp, nil
+}
+
+func toTransaction(rec *models.TransactionRecord, exps exponents) models.Transaction {
+	tr := models.Transaction{
+		ID:           rec.ID,
+		Type:         rec.Type,
+		Currency:     rec.Currency,
+		Amount:       models.AmountFromMinorUnits(rec.Amount, exps.of(rec.Currency)),
+		ToCurrency:   rec.ToCurrency,
+		RequestID:    rec.RequestID,
+		Counterparty: rec.Counterparty,
+		CreatedAt:    rec.CreatedAt,
+	}
+	if rec.ToCurrency != "" {
+		toAmount := models.AmountFromMinorUnits(rec.ToAmount, exps.of(rec.ToCurrency))
+		rate := rec.Rate
+		tr.ToAmount = &toAmount
+		tr.Rate = &rate
+	}
+	if rec.Fee > 0 {
+		fee := models.AmountFromMinorUnits(rec.Fee, exps.of(rec.Currency))
+		tr.Fee = &fee
+	}
+	if rec.BalanceAfter != nil {
+		balance := models.AmountFromMinorUnits(*rec.BalanceAfter, exps.of(rec.Currency))
+		tr.BalanceAfter = &balance
+	}
+	if rec.ToBalanceAfter != nil {
+		balance := models.AmountFromMinorUnits(*rec.ToBalanceAfter, exps.of(rec.ToCurrency))
+		tr.ToBalanceAfter = &balance
+	}
+	return tr
+}
@@ -3,30 +3,38 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/models"
 )
 
-func setupWalletService() (*WalletService, *MockWalletRepo, *MockTxManager) {
+func setupWalletService() (*WalletService, *MockWalletRepo, *MockLedgerRepo, *MockTxManager) {
 	repo := new(MockWalletRepo)
+	ledger := new(MockLedgerRepo)
 	txManager := new(MockTxManager)
 
 	service := &WalletService{
-		repo:      repo,
-		txManager: txManager,
+		repo:       repo,
+		ledger:     ledger,
+		limits:     stubLimits{},
+		txManager:  txManager,
+		currencies: newTestCurrencyRegistry(),
+		policy:     NewPolicy(),
 	}
 
-	return service, repo, txManager
+	return service, repo, ledger, txManager
 }
 
 func TestWalletService_GetUserBalance_Success(t *testing.T) {
-	service, repo, _ := setupWalletService()
+	service, repo, _, _ := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 
@@ -57,15 +65,15 @@ func TestWalletService_GetUserBalance_Success(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
-	assert.Equal(t, 1000.50, resp.USD)
-	assert.Equal(t, 50000.00, resp.RUB)
-	assert.Equal(t, 850.75, resp.EUR)
+	assert.Equal(t, "1000.5", resp.Balance["USD"].String())
+	assert.Equal(t, "50000", resp.Balance["RUB"].String())
+	assert.Equal(t, "850.75", resp.Balance["EUR"].String())
 
 	repo.AssertExpectations(t)
 }
 
 func TestWalletService_GetUserBalance_EmptyWallets(t *testing.T) {
-	service, repo, _ := setupWalletService()
+	service, repo, _, _ := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 
@@ -75,21 +83,90 @@ func TestWalletService_GetUserBalance_EmptyWallets(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
-	assert.Equal(t, 0.0, resp.USD)
-	assert.Equal(t, 0.0, resp.RUB)
-	assert.Equal(t, 0.0, resp.EUR)
+	assert.Len(t, resp.Balance, 3)
+	assert.True(t, resp.Balance["USD"].IsZero())
+	assert.True(t, resp.Balance["RUB"].IsZero())
+	assert.True(t, resp.Balance["EUR"].IsZero())
 
 	repo.AssertExpectations(t)
 }
 
+func TestWalletService_GetUserBalance_FollowsRegistry(t *testing.T) {
+	service, repo, _, _ := setupWalletService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// GBP только что добавлена в реестр, EUR отключена, но у пользователя остался кошелек
+	service.currencies = staticCurrencyRegistry{
+		{Code: "GBP", Name: "Pound Sterling", Exponent: 2, Enabled: true},
+		{Code: models.CurrencyUSD, Name: "US Dollar", Exponent: 2, Enabled: true},
+		{Code: models.CurrencyEUR, Name: "Euro", Exponent: 2, Enabled: false},
+		{Code: models.CurrencyRUB, Name: "Russian Ruble", Exponent: 2, Enabled: false},
+	}
+
+	repo.On("GetAllUserWallets", ctx, userID).Return([]*models.Wallet{
+		{ID: uuid.New(), UserID: userID, Currency: string(models.CurrencyEUR), Balance: 1250},
+	}, nil)
+
+	resp, err := service.GetUserBalance(ctx, userID)
+
+	require.NoError(t, err)
+	assert.Len(t, resp.Balance, 3)
+	assert.True(t, resp.Balance["GBP"].IsZero())
+	assert.True(t, resp.Balance["USD"].IsZero())
+	assert.Equal(t, "12.5", resp.Balance["EUR"].String())
+	assert.NotContains(t, resp.Balance, "RUB")
+
+	repo.AssertExpectations(t)
+}
+
+func TestWalletService_Deposit_CurrencyPrecision(t *testing.T) {
+	service, repo, _, _ := setupWalletService()
+	ctx := context.Background()
+
+	service.currencies = staticCurrencyRegistry{
+		{Code: "JPY", Name: "Yen", Exponent: 0, Enabled: true},
+	}
+
+	resp, err := service.Deposit(ctx, uuid.New(), models.DepositRequest{
+		Amount:    decimal.RequireFromString("100.5"),
+		Currency:  "JPY",
+		RequestID: "deposit-jpy",
+	})
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrAmountPrecision)
+	repo.AssertNotCalled(t, "GetOrCreateByUserAndCurrency", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletService_Deposit_DisabledCurrency(t *testing.T) {
+	service, repo, _, _ := setupWalletService()
+	ctx := context.Background()
+
+	service.currencies = staticCurrencyRegistry{
+		{Code: "GBP", Name: "Pound Sterling", Exponent: 2, Enabled: false},
+	}
+
+	resp, err := service.Deposit(ctx, uuid.New(), models.DepositRequest{
+		Amount:    decimal.RequireFromString("10"),
+		Currency:  "GBP",
+		RequestID: "deposit-gbp",
+	})
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrInvalidCurrency)
+	repo.AssertNotCalled(t, "GetOrCreateByUserAndCurrency", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestWalletService_Deposit_Success(t *testing.T) {
-	service, repo, txManager := setupWalletService()
+	service, repo, ledger, txManager := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 	walletID := uuid.New()
+	cashAccountID := uuid.New()
 
 	req := models.DepositRequest{
-		Amount:    500.00,
+		Amount:    decimal.RequireFromString("500.00"),
 		Currency:  models.CurrencyUSD,
 		RequestID: "deposit-001",
 	}
@@ -101,13 +178,27 @@ func TestWalletService_Deposit_Success(t *testing.T) {
 		Balance:  100000,
 	}
 
-	repo.On("GetByUserAndCurrency", ctx, userID, req.Currency).Return(wallet, nil)
+	repo.On("GetOrCreateByUserAndCurrency", ctx, userID, req.Currency).Return(wallet, nil)
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
 	repo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
-	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(int64(100000), nil)
-	repo.On("UpdateBalanceTx", ctx, mock.Anything, walletID, int64(150000)).Return(nil)
-	repo.On("CreateOperationTx", ctx, mock.Anything, walletID, int64(50000), req.RequestID).Return(nil)
+	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(models.WalletBalance{Balance: 100000}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountExternalCash, "USD").Return(cashAccountID, nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, models.JournalEntry{
+		EntryType: models.OperationDeposit,
+		RequestID: req.RequestID,
+		Postings: []models.Posting{
+			{AccountID: walletID, Currency: "USD", Amount: 50000},
+			{AccountID: cashAccountID, Currency: "USD", Amount: -50000},
+		},
+	}).Return(nil)
+	repo.On("CreateOperationTx", ctx, mock.Anything, models.Operation{
+		WalletID:      walletID,
+		OperationType: models.OperationDeposit,
+		Amount:        50000,
+		BalanceAfter:  150000,
+		RequestID:     req.RequestID,
+	}).Return(nil)
 
 	repo.On("GetAllUserWallets", ctx, userID).Return([]*models.Wallet{
 		{ID: walletID, UserID: userID, Currency: string(models.CurrencyUSD), Balance: 150000},
@@ -118,19 +209,20 @@ func TestWalletService_Deposit_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.Equal(t, "Account topped up successfully", resp.Message)
-	assert.Equal(t, 1500.00, resp.NewBalance.USD)
+	assert.Equal(t, "1500", resp.NewBalance["USD"].String())
 
 	repo.AssertExpectations(t)
+	ledger.AssertExpectations(t)
 	txManager.AssertExpectations(t)
 }
 
 func TestWalletService_Deposit_InvalidCurrency(t *testing.T) {
-	service, _, _ := setupWalletService()
+	service, _, _, _ := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 
 	req := models.DepositRequest{
-		Amount:    500.00,
+		Amount:    decimal.RequireFromString("500.00"),
 		Currency:  "INVALID",
 		RequestID: "deposit-001",
 	}
@@ -143,16 +235,16 @@ func TestWalletService_Deposit_InvalidCurrency(t *testing.T) {
 }
 
 func TestWalletService_Deposit_InvalidAmount(t *testing.T) {
-	service, _, _ := setupWalletService()
+	service, _, _, _ := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 
 	tests := []struct {
 		name   string
-		amount float64
+		amount decimal.Decimal
 	}{
-		{"zero amount", 0.0},
-		{"negative amount", -100.0},
+		{"zero amount", decimal.Zero},
+		{"negative amount", decimal.RequireFromString("-100")},
 	}
 
 	for _, tt := range tests {
@@ -173,12 +265,12 @@ func TestWalletService_Deposit_InvalidAmount(t *testing.T) {
 }
 
 func TestWalletService_Deposit_EmptyRequestID(t *testing.T) {
-	service, _, _ := setupWalletService()
+	service, _, _, _ := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 
 	req := models.DepositRequest{
-		Amount:    500.00,
+		Amount:    decimal.RequireFromString("500.00"),
 		Currency:  models.CurrencyUSD,
 		RequestID: "",
 	}
@@ -191,13 +283,13 @@ func TestWalletService_Deposit_EmptyRequestID(t *testing.T) {
 }
 
 func TestWalletService_Deposit_DuplicateRequest(t *testing.T) {
-	service, repo, txManager := setupWalletService()
+	service, repo, _, txManager := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 	walletID := uuid.New()
 
 	req := models.DepositRequest{
-		Amount:    500.00,
+		Amount:    decimal.RequireFromString("500.00"),
 		Currency:  models.CurrencyUSD,
 		RequestID: "deposit-001",
 	}
@@ -209,7 +301,7 @@ func TestWalletService_Deposit_DuplicateRequest(t *testing.T) {
 		Balance:  100000,
 	}
 
-	repo.On("GetByUserAndCurrency", ctx, userID, req.Currency).Return(wallet, nil)
+	repo.On("GetOrCreateByUserAndCurrency", ctx, userID, req.Currency).Return(wallet, nil)
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).
 		Run(func(args mock.Arguments) {
@@ -231,13 +323,14 @@ func TestWalletService_Deposit_DuplicateRequest(t *testing.T) {
 }
 
 func TestWalletService_Withdraw_Success(t *testing.T) {
-	service, repo, txManager := setupWalletService()
+	service, repo, ledger, txManager := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 	walletID := uuid.New()
+	cashAccountID := uuid.New()
 
 	req := models.WithdrawRequest{
-		Amount:    300.00,
+		Amount:    decimal.RequireFromString("300.00"),
 		Currency:  models.CurrencyUSD,
 		RequestID: "withdraw-001",
 	}
@@ -253,9 +346,23 @@ func TestWalletService_Withdraw_Success(t *testing.T) {
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
 	repo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
-	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(int64(100000), nil)
-	repo.On("UpdateBalanceTx", ctx, mock.Anything, walletID, int64(70000)).Return(nil)
-	repo.On("CreateOperationTx", ctx, mock.Anything, walletID, int64(30000), req.RequestID).Return(nil)
+	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(models.WalletBalance{Balance: 100000}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountExternalCash, "USD").Return(cashAccountID, nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, models.JournalEntry{
+		EntryType: models.OperationWithdraw,
+		RequestID: req.RequestID,
+		Postings: []models.Posting{
+			{AccountID: walletID, Currency: "USD", Amount: -30000},
+			{AccountID: cashAccountID, Currency: "USD", Amount: 30000},
+		},
+	}).Return(nil)
+	repo.On("CreateOperationTx", ctx, mock.Anything, models.Operation{
+		WalletID:      walletID,
+		OperationType: models.OperationWithdraw,
+		Amount:        -30000,
+		BalanceAfter:  70000,
+		RequestID:     req.RequestID,
+	}).Return(nil)
 
 	repo.On("GetAllUserWallets", ctx, userID).Return([]*models.Wallet{
 		{ID: walletID, UserID: userID, Currency: string(models.CurrencyUSD), Balance: 70000},
@@ -266,20 +373,21 @@ func TestWalletService_Withdraw_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 	assert.Equal(t, "Withdrawal successful", resp.Message)
-	assert.Equal(t, 700.00, resp.NewBalance.USD)
+	assert.Equal(t, "700", resp.NewBalance["USD"].String())
 
 	repo.AssertExpectations(t)
+	ledger.AssertExpectations(t)
 	txManager.AssertExpectations(t)
 }
 
 func TestWalletService_Withdraw_InsufficientFunds(t *testing.T) {
-	service, repo, txManager := setupWalletService()
+	service, repo, _, txManager := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 	walletID := uuid.New()
 
 	req := models.WithdrawRequest{
-		Amount:    1500.00,
+		Amount:    decimal.RequireFromString("1500.00"),
 		Currency:  models.CurrencyUSD,
 		RequestID: "withdraw-001",
 	}
@@ -301,7 +409,7 @@ func TestWalletService_Withdraw_InsufficientFunds(t *testing.T) {
 		Return(custom_err.ErrInsufficientFunds)
 
 	repo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
-	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(int64(100000), nil)
+	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(models.WalletBalance{Balance: 100000}, nil)
 
 	resp, err := service.Withdraw(ctx, userID, req)
 
@@ -313,13 +421,94 @@ func TestWalletService_Withdraw_InsufficientFunds(t *testing.T) {
 	txManager.AssertExpectations(t)
 }
 
+func TestWalletService_Withdraw_HeldFundsUnavailable(t *testing.T) {
+	service, repo, ledger, txManager := setupWalletService()
+	ctx := context.Background()
+	userID := uuid.New()
+	walletID := uuid.New()
+
+	req := models.WithdrawRequest{
+		Amount:    decimal.RequireFromString("300.00"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "withdraw-001",
+	}
+
+	wallet := &models.Wallet{ID: walletID, UserID: userID, Currency: string(models.CurrencyUSD), Balance: 100000}
+
+	repo.On("GetByUserAndCurrency", ctx, userID, req.Currency).Return(wallet, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	repo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	// Баланс 1000, но 800 зарезервировано холдами
+	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).
+		Return(models.WalletBalance{Balance: 100000, Held: 80000}, nil)
+
+	resp, err := service.Withdraw(ctx, userID, req)
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrInsufficientFunds)
+	ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWalletService_GetUserBalance_ExcludesHeldFromAvailable(t *testing.T) {
+	service, repo, _, _ := setupWalletService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	repo.On("GetAllUserWallets", ctx, userID).Return([]*models.Wallet{
+		{ID: uuid.New(), UserID: userID, Currency: string(models.CurrencyUSD), Balance: 100000, HeldBalance: 25050},
+	}, nil)
+
+	resp, err := service.GetUserBalance(ctx, userID)
+
+	require.NoError(t, err)
+	assert.Equal(t, "1000", resp.Balance["USD"].String())
+	assert.Equal(t, "749.5", resp.Available["USD"].String())
+	assert.True(t, resp.Available["EUR"].IsZero())
+}
+
+func TestWalletService_Withdraw_LimitExceeded(t *testing.T) {
+	service, repo, ledger, txManager := setupWalletService()
+	limitErr := &models.LimitExceededError{
+		OperationType: models.OperationWithdraw,
+		Currency:      "USD",
+		Period:        models.LimitPeriodDaily,
+		Limit:         decimal.RequireFromString("1000"),
+		Remaining:     decimal.RequireFromString("250"),
+	}
+	service.limits = stubLimits{err: limitErr}
+	ctx := context.Background()
+	userID := uuid.New()
+	walletID := uuid.New()
+
+	req := models.WithdrawRequest{
+		Amount:    decimal.RequireFromString("300.00"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "withdraw-001",
+	}
+
+	wallet := &models.Wallet{ID: walletID, UserID: userID, Currency: string(models.CurrencyUSD), Balance: 100000}
+
+	repo.On("GetByUserAndCurrency", ctx, userID, req.Currency).Return(wallet, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	repo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(models.WalletBalance{Balance: 100000}, nil)
+
+	resp, err := service.Withdraw(ctx, userID, req)
+
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, custom_err.ErrLimitExceeded)
+	assert.ErrorAs(t, err, &limitErr)
+	ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "CreateOperationTx", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestWalletService_Withdraw_WalletNotFound(t *testing.T) {
-	service, repo, _ := setupWalletService()
+	service, repo, _, _ := setupWalletService()
 	ctx := context.Background()
 	userID := uuid.New()
 
 	req := models.WithdrawRequest{
-		Amount:    100.00,
+		Amount:    decimal.RequireFromString("100.00"),
 		Currency:  models.CurrencyUSD,
 		RequestID: "withdraw-001",
 	}
@@ -336,12 +525,14 @@ func TestWalletService_Withdraw_WalletNotFound(t *testing.T) {
 }
 
 func TestWalletService_UpdateBalance_Deposit(t *testing.T) {
-	service, repo, txManager := setupWalletService()
+	service, repo, ledger, txManager := setupWalletService()
 	ctx := context.Background()
 	walletID := uuid.New()
+	cashAccountID := uuid.New()
 
 	req := models.WalletOperationRequest{
 		WalletID:      walletID,
+		Currency:      models.CurrencyUSD,
 		OperationType: models.OperationDeposit,
 		Amount:        50000,
 		RequestID:     "op-001",
@@ -349,25 +540,42 @@ func TestWalletService_UpdateBalance_Deposit(t *testing.T) {
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
 	repo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
-	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(int64(100000), nil)
-	repo.On("UpdateBalanceTx", ctx, mock.Anything, walletID, int64(150000)).Return(nil)
-	repo.On("CreateOperationTx", ctx, mock.Anything, walletID, int64(50000), req.RequestID).Return(nil)
+	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(models.WalletBalance{Balance: 100000}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountExternalCash, "USD").Return(cashAccountID, nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, models.JournalEntry{
+		EntryType: models.OperationDeposit,
+		RequestID: req.RequestID,
+		Postings: []models.Posting{
+			{AccountID: walletID, Currency: "USD", Amount: 50000},
+			{AccountID: cashAccountID, Currency: "USD", Amount: -50000},
+		},
+	}).Return(nil)
+	repo.On("CreateOperationTx", ctx, mock.Anything, models.Operation{
+		WalletID:      walletID,
+		OperationType: models.OperationDeposit,
+		Amount:        50000,
+		BalanceAfter:  150000,
+		RequestID:     req.RequestID,
+	}).Return(nil)
 
 	err := service.UpdateBalance(ctx, req)
 
 	assert.NoError(t, err)
 
 	repo.AssertExpectations(t)
+	ledger.AssertExpectations(t)
 	txManager.AssertExpectations(t)
 }
 
 func TestWalletService_UpdateBalance_Withdraw(t *testing.T) {
-	service, repo, txManager := setupWalletService()
+	service, repo, ledger, txManager := setupWalletService()
 	ctx := context.Background()
 	walletID := uuid.New()
+	cashAccountID := uuid.New()
 
 	req := models.WalletOperationRequest{
 		WalletID:      walletID,
+		Currency:      models.CurrencyUSD,
 		OperationType: models.OperationWithdraw,
 		Amount:        30000,
 		RequestID:     "op-002",
@@ -375,14 +583,144 @@ func TestWalletService_UpdateBalance_Withdraw(t *testing.T) {
 
 	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
 	repo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
-	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(int64(100000), nil)
-	repo.On("UpdateBalanceTx", ctx, mock.Anything, walletID, int64(70000)).Return(nil)
-	repo.On("CreateOperationTx", ctx, mock.Anything, walletID, int64(30000), req.RequestID).Return(nil)
+	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, walletID).Return(models.WalletBalance{Balance: 100000}, nil)
+	ledger.On("GetSystemAccountIDTx", ctx, mock.Anything, models.SystemAccountExternalCash, "USD").Return(cashAccountID, nil)
+	ledger.On("PostEntryTx", ctx, mock.Anything, models.JournalEntry{
+		EntryType: models.OperationWithdraw,
+		RequestID: req.RequestID,
+		Postings: []models.Posting{
+			{AccountID: walletID, Currency: "USD", Amount: -30000},
+			{AccountID: cashAccountID, Currency: "USD", Amount: 30000},
+		},
+	}).Return(nil)
+	repo.On("CreateOperationTx", ctx, mock.Anything, models.Operation{
+		WalletID:      walletID,
+		OperationType: models.OperationWithdraw,
+		Amount:        -30000,
+		BalanceAfter:  70000,
+		RequestID:     req.RequestID,
+	}).Return(nil)
 
 	err := service.UpdateBalance(ctx, req)
 
 	assert.NoError(t, err)
 
 	repo.AssertExpectations(t)
+	ledger.AssertExpectations(t)
 	txManager.AssertExpectations(t)
 }
+
+func TestWalletService_GetTransactions_NextCursor(t *testing.T) {
+	service, repo, _, _ := setupWalletService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	now := time.Now().UTC()
+	balance := int64(150000)
+	records := []*models.TransactionRecord{
+		{ID: uuid.New(), Type: models.OperationDeposit, Currency: "USD", Amount: 50000, BalanceAfter: &balance, CreatedAt: now},
+		{ID: uuid.New(), Type: models.OperationExchange, Currency: "USD", Amount: -10000, ToCurrency: "EUR", ToAmount: 9200, Rate: decimal.RequireFromString("0.92"), CreatedAt: now.Add(-time.Minute)},
+		{ID: uuid.New(), Type: models.OperationWithdraw, Currency: "USD", Amount: -3000, CreatedAt: now.Add(-2 * time.Minute)},
+	}
+
+	repo.On("ListTransactions", ctx, userID, models.TransactionFilter{
+		Currency: models.CurrencyUSD,
+		Limit:    3,
+	}).Return(records, nil)
+
+	resp, err := service.GetTransactions(ctx, userID, models.TransactionHistoryRequest{
+		Currency: models.CurrencyUSD,
+		Limit:    2,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Transactions, 2)
+	assert.Equal(t, "1500", resp.Transactions[0].BalanceAfter.String())
+	assert.Equal(t, "-100", resp.Transactions[1].Amount.String())
+	assert.Equal(t, "92", resp.Transactions[1].ToAmount.String())
+
+	cursor, err := models.DecodeTransactionCursor(resp.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, records[1].ID, cursor.ID)
+	assert.True(t, records[1].CreatedAt.Equal(cursor.CreatedAt))
+
+	repo.AssertExpectations(t)
+}
+
+func TestWalletService_GetTransactions_LastPage(t *testing.T) {
+	service, repo, _, _ := setupWalletService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	after := models.TransactionCursor{CreatedAt: time.Now().UTC(), ID: uuid.New()}
+	repo.On("ListTransactions", ctx, userID, models.TransactionFilter{
+		Type:  models.OperationExchange,
+		After: &after,
+		Limit: models.DefaultTransactionsLimit + 1,
+	}).Return([]*models.TransactionRecord{}, nil)
+
+	resp, err := service.GetTransactions(ctx, userID, models.TransactionHistoryRequest{
+		Type:   models.OperationExchange,
+		Cursor: after.Encode(),
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Transactions)
+	assert.Empty(t, resp.NextCursor)
+
+	repo.AssertExpectations(t)
+}
+
+func TestWalletService_GetTransactions_InvalidInput(t *testing.T) {
+	service, _, _, _ := setupWalletService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	from := time.Now()
+	to := from.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		req     models.TransactionHistoryRequest
+		wantErr error
+	}{
+		{"invalid currency", models.TransactionHistoryRequest{Currency: "GBP"}, custom_err.ErrInvalidCurrency},
+		{"invalid type", models.TransactionHistoryRequest{Type: "REFUND"}, custom_err.ErrInvalidInput},
+		{"limit too large", models.TransactionHistoryRequest{Limit: models.MaxTransactionsLimit + 1}, custom_err.ErrInvalidInput},
+		{"inverted range", models.TransactionHistoryRequest{From: &from, To: &to}, custom_err.ErrInvalidInput},
+		{"malformed cursor", models.TransactionHistoryRequest{Cursor: "not-a-cursor"}, custom_err.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := service.GetTransactions(ctx, userID, tt.req)
+
+			assert.Nil(t, resp)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWalletService_Withdraw_FrozenWallet(t *testing.T) {
+	service, repo, ledger, txManager := setupWalletService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	wallet := &models.Wallet{ID: uuid.New(), UserID: userID, Currency: string(models.CurrencyUSD), Balance: 100000}
+	req := models.WithdrawRequest{
+		Amount:    decimal.RequireFromString("10"),
+		Currency:  models.CurrencyUSD,
+		RequestID: "withdraw-frozen",
+	}
+
+	repo.On("GetByUserAndCurrency", ctx, userID, req.Currency).Return(wallet, nil)
+	txManager.On("WithTx", ctx, mock.AnythingOfType("func(pgx.Tx) error")).Return(nil)
+	repo.On("OperationExistsTx", ctx, mock.Anything, req.RequestID).Return(false, nil)
+	repo.On("GetWalletBalanceForUpdateTx", ctx, mock.Anything, wallet.ID).Return(models.WalletBalance{}, custom_err.ErrWalletFrozen)
+
+	_, err := service.Withdraw(ctx, userID, req)
+
+	assert.ErrorIs(t, err, custom_err.ErrWalletFrozen)
+	ledger.AssertNotCalled(t, "PostEntryTx", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "CreateOperationTx", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AccountTokenRepository одноразовые токены подтверждения email и сброса пароля
+type AccountTokenRepository interface {
+	CreateTx(ctx context.Context, tx pgx.Tx, token *models.AccountToken) error
+	// InvalidateTx гасит неиспользованные токены пользователя с этим назначением
+	InvalidateTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, purpose models.AccountTokenPurpose) error
+	// ConsumeTx отмечает токен использованным; custom_err.ErrNotFound, если токена нет,
+	// он уже использован или истек
+	ConsumeTx(ctx context.Context, tx pgx.Tx, tokenHash string, purpose models.AccountTokenPurpose) (*models.AccountToken, error)
+}
+
+type PgAccountTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAccountTokenRepository(db *pgxpool.Pool) AccountTokenRepository {
+	return &PgAccountTokenRepository{db: db}
+}
+
+func (r *PgAccountTokenRepository) CreateTx(ctx context.Context, tx pgx.Tx, token *models.AccountToken) error {
+	const op = "storage.CreateAccountTokenTx"
+
+	_, err := tx.Exec(ctx, storage.CreateAccountTokenQuery,
+		token.ID, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgAccountTokenRepository) InvalidateTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, purpose models.AccountTokenPurpose) error {
+	const op = "storage.InvalidateAccountTokensTx"
+
+	if _, err := tx.Exec(ctx, storage.InvalidateAccountTokensQuery, userID, purpose); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgAccountTokenRepository) ConsumeTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	tokenHash string,
+	purpose models.AccountTokenPurpose,
+) (*models.AccountToken, error) {
+	const op = "storage.ConsumeAccountTokenTx"
+
+	var token models.AccountToken
+	err := tx.QueryRow(ctx, storage.ConsumeAccountTokenQuery, tokenHash, purpose).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Purpose,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &token, nil
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"net/netip"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// APIKeyRepository персональные API ключи пользователей
+type APIKeyRepository interface {
+	// Create сохраняет ключ; custom_err.ErrAPIKeyLimitReached, если у пользователя уже maxActive действующих ключей
+	Create(ctx context.Context, key models.APIKey, maxActive int) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	// Revoke отзывает ключ пользователя; custom_err.ErrNotFound, если ключа нет или он принадлежит другому
+	Revoke(ctx context.Context, id, userID uuid.UUID) (*models.APIKey, error)
+	// Touch запоминает время и адрес последнего использования
+	Touch(ctx context.Context, id uuid.UUID, ip netip.Addr) error
+}
+
+type PgAPIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) APIKeyRepository {
+	return &PgAPIKeyRepository{db: db}
+}
+
+func (r *PgAPIKeyRepository) Create(ctx context.Context, key models.APIKey, maxActive int) error {
+	const op = "storage.CreateAPIKey"
+
+	res, err := r.db.Exec(ctx, storage.CreateAPIKeyQuery,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.AllowedIPs, key.ExpiresAt, key.CreatedAt,
+		maxActive)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrAPIKeyLimitReached
+	}
+	return nil
+}
+
+func (r *PgAPIKeyRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	const op = "storage.ListAPIKeys"
+
+	rows, err := r.db.Query(ctx, storage.ListAPIKeysQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return keys, nil
+}
+
+func (r *PgAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	const op = "storage.GetAPIKeyByHash"
+
+	var key models.APIKey
+	if err := scanAPIKey(r.db.QueryRow(ctx, storage.GetAPIKeyByHashQuery, keyHash), &key); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &key, nil
+}
+
+func (r *PgAPIKeyRepository) Revoke(ctx context.Context, id, userID uuid.UUID) (*models.APIKey, error) {
+	const op = "storage.RevokeAPIKey"
+
+	var key models.APIKey
+	if err := scanAPIKey(r.db.QueryRow(ctx, storage.RevokeAPIKeyQuery, id, userID), &key); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &key, nil
+}
+
+func (r *PgAPIKeyRepository) Touch(ctx context.Context, id uuid.UUID, ip netip.Addr) error {
+	const op = "storage.TouchAPIKey"
+
+	if _, err := r.db.Exec(ctx, storage.TouchAPIKeyQuery, id, ip); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func scanAPIKey(row pgx.Row, key *models.APIKey) error {
+	return row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.AllowedIPs,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.LastUsedIP,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+}
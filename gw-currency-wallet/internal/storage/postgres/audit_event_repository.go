@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AuditEventRepository журнал событий безопасности. Записи только дописываются:
+// изменение и удаление запрещены триггером в БД.
+type AuditEventRepository interface {
+	// HeadTx блокирует дописывание журнала до конца транзакции и возвращает хэш последней записи;
+	// пустая строка, если журнал пуст
+	HeadTx(ctx context.Context, tx pgx.Tx) (string, error)
+	// CreateTx дописывает событие и заполняет его Seq
+	CreateTx(ctx context.Context, tx pgx.Tx, event *models.AuditEvent) error
+	// List события по фильтру, новые первыми
+	List(ctx context.Context, filter models.AuditEventFilter) ([]*models.AuditEvent, error)
+	// Scan порция журнала после позиции afterSeq в порядке цепочки
+	Scan(ctx context.Context, afterSeq int64, limit int) ([]*models.AuditEvent, error)
+}
+
+type PgAuditEventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditEventRepository(db *pgxpool.Pool) AuditEventRepository {
+	return &PgAuditEventRepository{db: db}
+}
+
+func (r *PgAuditEventRepository) HeadTx(ctx context.Context, tx pgx.Tx) (string, error) {
+	const op = "storage.GetAuditEventChainHeadTx"
+
+	if _, err := tx.Exec(ctx, storage.LockAuditEventChainQuery); err != nil {
+		return "", fmt.Errorf("%s: failed to lock chain: %w", op, err)
+	}
+
+	var hash string
+	if err := tx.QueryRow(ctx, storage.GetAuditEventChainHeadQuery).Scan(&hash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return hash, nil
+}
+
+func (r *PgAuditEventRepository) CreateTx(ctx context.Context, tx pgx.Tx, event *models.AuditEvent) error {
+	const op = "storage.CreateAuditEventTx"
+
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("%s: failed to encode metadata: %w", op, err)
+	}
+
+	if err := tx.QueryRow(ctx, storage.CreateAuditEventQuery,
+		event.ID, event.Type, event.UserID, event.ActorID, event.IP, event.UserAgent, encoded,
+		event.CreatedAt, event.PrevHash, event.Hash,
+	).Scan(&event.Seq); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgAuditEventRepository) List(ctx context.Context, filter models.AuditEventFilter) ([]*models.AuditEvent, error) {
+	const op = "storage.ListAuditEvents"
+
+	var (
+		eventType *string
+		beforeSeq *int64
+	)
+	if filter.Type != "" {
+		t := string(filter.Type)
+		eventType = &t
+	}
+	if filter.BeforeSeq > 0 {
+		beforeSeq = &filter.BeforeSeq
+	}
+
+	rows, err := r.db.Query(ctx, storage.ListAuditEventsQuery,
+		filter.UserID, eventType, filter.IP, filter.From, filter.To, beforeSeq, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return collectAuditEvents(op, rows)
+}
+
+func (r *PgAuditEventRepository) Scan(ctx context.Context, afterSeq int64, limit int) ([]*models.AuditEvent, error) {
+	const op = "storage.ScanAuditEvents"
+
+	rows, err := r.db.Query(ctx, storage.ScanAuditEventsQuery, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return collectAuditEvents(op, rows)
+}
+
+func collectAuditEvents(op string, rows pgx.Rows) ([]*models.AuditEvent, error) {
+	defer rows.Close()
+
+	var events []*models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.Seq,
+			&event.Type,
+			&event.UserID,
+			&event.ActorID,
+			&event.IP,
+			&event.UserAgent,
+			&event.Metadata,
+			&event.CreatedAt,
+			&event.PrevHash,
+			&event.Hash,
+		); err != nil {
+			return nil, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditRepository interface {
+	Create(ctx context.Context, entry models.AuditEntry) error
+	CreateTx(ctx context.Context, tx pgx.Tx, entry models.AuditEntry) error
+}
+
+type pgxExecer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+type PgAuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) AuditRepository {
+	return &PgAuditRepository{db: db}
+}
+
+func (r *PgAuditRepository) Create(ctx context.Context, entry models.AuditEntry) error {
+	const op = "storage.CreateAuditEntry"
+
+	if err := r.exec(ctx, r.db, entry); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgAuditRepository) CreateTx(ctx context.Context, tx pgx.Tx, entry models.AuditEntry) error {
+	const op = "storage.CreateAuditEntryTx"
+
+	if err := r.exec(ctx, tx, entry); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgAuditRepository) exec(ctx context.Context, q pgxExecer, entry models.AuditEntry) error {
+	details := entry.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to encode details: %w", err)
+	}
+
+	var reason *string
+	if entry.Reason != "" {
+		reason = &entry.Reason
+	}
+
+	_, err = q.Exec(ctx, storage.CreateAdminAuditEntryQuery,
+		entry.ActorID, entry.ActorRole, entry.Action,
+		entry.TargetUserID, entry.TargetWalletID, reason, encoded)
+	return err
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type FeeRepository interface {
+	// ListPricing возвращает все правила спреда и комиссий со ступенями по возрастанию min_amount
+	ListPricing(ctx context.Context) ([]models.ExchangePricing, error)
+}
+
+type PgFeeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewFeeRepository(db *pgxpool.Pool) FeeRepository {
+	return &PgFeeRepository{db: db}
+}
+
+func (r *PgFeeRepository) ListPricing(ctx context.Context) ([]models.ExchangePricing, error) {
+	const op = "storage.ListExchangePricing"
+
+	rows, err := r.db.Query(ctx, storage.ListExchangePricingQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var pricing []models.ExchangePricing
+	index := make(map[[2]string]int)
+	for rows.Next() {
+		var p models.ExchangePricing
+		if err := rows.Scan(&p.FromCurrency, &p.ToCurrency, &p.SpreadBps); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		index[[2]string{p.FromCurrency, p.ToCurrency}] = len(pricing)
+		pricing = append(pricing, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tierRows, err := r.db.Query(ctx, storage.ListExchangeFeeTiersQuery)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tierRows.Close()
+
+	for tierRows.Next() {
+		var (
+			from, to string
+			tier     models.FeeTier
+		)
+		if err := tierRows.Scan(&from, &to, &tier.MinAmount, &tier.Percent, &tier.Fixed); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if i, ok := index[[2]string{from, to}]; ok {
+			pricing[i].Tiers = append(pricing[i].Tiers, tier)
+		}
+	}
+	if err := tierRows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return pricing, nil
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// HoldRepository холды кошельков. Методы *Tx вызываются после блокировки кошелька холда
+// и поддерживают wallets.held_balance в той же транзакции.
+type HoldRepository interface {
+	HoldExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error)
+	// CreateTx создает холд и резервирует его сумму на кошельке
+	CreateTx(ctx context.Context, tx pgx.Tx, hold models.Hold) (*models.Hold, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Hold, error)
+	// CloseTx переводит активный холд в статус status и снимает резерв с кошелька.
+	// custom_err.ErrHoldNotActive, если холд уже закрыт или его срок не соответствует статусу к now.
+	CloseTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, status models.HoldStatus, captured int64, now time.Time) (*models.Hold, error)
+	// ListExpired активные холды, срок которых истек к now
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]models.Hold, error)
+}
+
+type PgHoldRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewHoldRepository(db *pgxpool.Pool) HoldRepository {
+	return &PgHoldRepository{db: db}
+}
+
+func (r *PgHoldRepository) HoldExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	const op = "storage.HoldExistsTx"
+
+	var exists bool
+	if err := tx.QueryRow(ctx, storage.HoldExistsQuery, requestID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return exists, nil
+}
+
+func (r *PgHoldRepository) CreateTx(ctx context.Context, tx pgx.Tx, hold models.Hold) (*models.Hold, error) {
+	const op = "storage.CreateHoldTx"
+
+	var created models.Hold
+	err := scanHold(tx.QueryRow(ctx, storage.CreateHoldQuery,
+		hold.ID, hold.WalletID, hold.UserID, hold.Currency, hold.Amount,
+		hold.Description, hold.RequestID, hold.ExpiresAt), &created)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.Exec(ctx, storage.AddWalletHeldBalanceQuery, hold.WalletID, hold.Amount); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &created, nil
+}
+
+func (r *PgHoldRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	const op = "storage.GetHoldByID"
+
+	var hold models.Hold
+	if err := scanHold(r.db.QueryRow(ctx, storage.GetHoldByIDQuery, id), &hold); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &hold, nil
+}
+
+func (r *PgHoldRepository) CloseTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+	status models.HoldStatus,
+	captured int64,
+	now time.Time,
+) (*models.Hold, error) {
+	const op = "storage.CloseHoldTx"
+
+	var hold models.Hold
+	if err := scanHold(tx.QueryRow(ctx, storage.CloseHoldQuery, id, status, captured, now), &hold); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrHoldNotActive
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.Exec(ctx, storage.AddWalletHeldBalanceQuery, hold.WalletID, -hold.Amount); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &hold, nil
+}
+
+func (r *PgHoldRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]models.Hold, error) {
+	const op = "storage.ListExpiredHolds"
+
+	rows, err := r.db.Query(ctx, storage.ListExpiredHoldsQuery, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var holds []models.Hold
+	for rows.Next() {
+		var hold models.Hold
+		if err := scanHold(rows, &hold); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		holds = append(holds, hold)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return holds, nil
+}
+
+func scanHold(row pgx.Row, hold *models.Hold) error {
+	return row.Scan(
+		&hold.ID,
+		&hold.WalletID,
+		&hold.UserID,
+		&hold.Currency,
+		&hold.Amount,
+		&hold.CapturedAmount,
+		&hold.Status,
+		&hold.Description,
+		&hold.RequestID,
+		&hold.ExpiresAt,
+		&hold.CreatedAt,
+		&hold.UpdatedAt,
+	)
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type IdempotencyRepository interface {
+	// Reserve занимает ключ на ttl. false, если ключ занят действующей записью;
+	// запись без ответа старше lockTimeout считается брошенной и перезаписывается.
+	Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, ttl, lockTimeout time.Duration) (bool, error)
+	// Get действующая запись ключа; custom_err.ErrNotFound, если ее нет или она просрочена
+	Get(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error)
+	SaveResponse(ctx context.Context, userID uuid.UUID, key string, status int, contentType string, body []byte) error
+	// Release удаляет незавершенную запись, чтобы запрос можно было повторить
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type PgIdempotencyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(db *pgxpool.Pool) IdempotencyRepository {
+	return &PgIdempotencyRepository{db: db}
+}
+
+func (r *PgIdempotencyRepository) Reserve(ctx context.Context, userID uuid.UUID, key, fingerprint string, ttl, lockTimeout time.Duration) (bool, error) {
+	const op = "storage.ReserveIdempotencyKey"
+
+	res, err := r.db.Exec(ctx, storage.ReserveIdempotencyKeyQuery, userID, key, fingerprint, ttl.Seconds(), lockTimeout.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return res.RowsAffected() == 1, nil
+}
+
+func (r *PgIdempotencyRepository) Get(ctx context.Context, userID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	const op = "storage.GetIdempotencyKey"
+
+	var rec models.IdempotencyRecord
+	err := r.db.QueryRow(ctx, storage.GetIdempotencyKeyQuery, userID, key).Scan(
+		&rec.UserID, &rec.Key, &rec.Fingerprint, &rec.StatusCode,
+		&rec.ContentType, &rec.Body, &rec.CreatedAt, &rec.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &rec, nil
+}
+
+func (r *PgIdempotencyRepository) SaveResponse(ctx context.Context, userID uuid.UUID, key string, status int, contentType string, body []byte) error {
+	const op = "storage.SaveIdempotentResponse"
+
+	if _, err := r.db.Exec(ctx, storage.SaveIdempotentResponseQuery, userID, key, status, contentType, body); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgIdempotencyRepository) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	const op = "storage.ReleaseIdempotencyKey"
+
+	if _, err := r.db.Exec(ctx, storage.ReleaseIdempotencyKeyQuery, userID, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	const op = "storage.DeleteExpiredIdempotencyKeys"
+
+	res, err := r.db.Exec(ctx, storage.DeleteExpiredIdempotencyKeysQuery)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LedgerRepository interface {
+	GetSystemAccountIDTx(ctx context.Context, tx pgx.Tx, code string, currency string) (uuid.UUID, error)
+	PostEntryTx(ctx context.Context, tx pgx.Tx, entry models.JournalEntry) error
+
+	GetAccountBalance(ctx context.Context, accountID uuid.UUID) (int64, error)
+}
+
+type PgLedgerRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLedgerRepository(db *pgxpool.Pool) LedgerRepository {
+	return &PgLedgerRepository{db: db}
+}
+
+func (r *PgLedgerRepository) GetSystemAccountIDTx(ctx context.Context, tx pgx.Tx, code string, currency string) (uuid.UUID, error) {
+	const op = "storage.GetSystemAccountIDTx"
+
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, storage.GetOrCreateSystemAccountQuery, code, currency).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Счет только что создан параллельной транзакцией: ее строку видит только следующий запрос
+		err = tx.QueryRow(ctx, storage.GetSystemAccountQuery, code, currency).Scan(&id)
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// PostEntryTx записывает проводку и обновляет проекцию балансов затронутых кошельков
+func (r *PgLedgerRepository) PostEntryTx(ctx context.Context, tx pgx.Tx, entry models.JournalEntry) error {
+	const op = "storage.PostEntryTx"
+
+	if err := entry.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+
+	_, err := tx.Exec(ctx, storage.CreateJournalEntryQuery, entry.ID, entry.EntryType, entry.RequestID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return custom_err.ErrDuplicateRequest
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, p := range entry.Postings {
+		res, err := tx.Exec(ctx, storage.CreatePostingQuery, entry.ID, p.AccountID, p.Currency, p.Amount)
+		if err != nil {
+			return fmt.Errorf("%s: failed to create posting: %w", op, err)
+		}
+		if res.RowsAffected() == 0 {
+			return fmt.Errorf("%s: account %s not found in %s: %w", op, p.AccountID, p.Currency, custom_err.ErrNotFound)
+		}
+
+		_, err = tx.Exec(ctx, storage.ApplyPostingToWalletQuery, p.Amount, p.AccountID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+				return custom_err.ErrInsufficientFunds
+			}
+			return fmt.Errorf("%s: failed to apply posting: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *PgLedgerRepository) GetAccountBalance(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	const op = "storage.GetAccountBalance"
+
+	var balance int64
+	if err := r.db.QueryRow(ctx, storage.GetAccountLedgerBalanceQuery, accountID).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return balance, nil
+}
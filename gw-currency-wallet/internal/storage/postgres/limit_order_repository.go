@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LimitOrderRepository лимитные ордера. Методы *Tx поддерживают резерв ордера
+// в wallets.held_balance кошелька-источника в той же транзакции.
+type LimitOrderRepository interface {
+	OrderExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error)
+	// CreateTx создает ордер и резервирует его сумму на кошельке
+	CreateTx(ctx context.Context, tx pgx.Tx, order models.LimitOrder) (*models.LimitOrder, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.LimitOrder, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, status models.LimitOrderStatus, limit int) ([]models.LimitOrder, error)
+	// ListOpen открытые ордера, действующие к now, с id больше after
+	ListOpen(ctx context.Context, now time.Time, after uuid.UUID, limit int) ([]models.LimitOrder, error)
+	// ListExpired открытые ордера, срок которых истек к now
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]models.LimitOrder, error)
+	// CloseTx переводит открытый ордер в статус status и снимает резерв с кошелька.
+	// custom_err.ErrOrderNotOpen, если ордер уже закрыт или его срок не соответствует статусу к now.
+	CloseTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, status models.LimitOrderStatus, fill models.LimitOrderFill, now time.Time) (*models.LimitOrder, error)
+}
+
+type PgLimitOrderRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLimitOrderRepository(db *pgxpool.Pool) LimitOrderRepository {
+	return &PgLimitOrderRepository{db: db}
+}
+
+func (r *PgLimitOrderRepository) OrderExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	const op = "storage.LimitOrderExistsTx"
+
+	var exists bool
+	if err := tx.QueryRow(ctx, storage.LimitOrderExistsQuery, requestID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return exists, nil
+}
+
+func (r *PgLimitOrderRepository) CreateTx(ctx context.Context, tx pgx.Tx, order models.LimitOrder) (*models.LimitOrder, error) {
+	const op = "storage.CreateLimitOrderTx"
+
+	var created models.LimitOrder
+	err := scanLimitOrder(tx.QueryRow(ctx, storage.CreateLimitOrderQuery,
+		order.ID, order.UserID, order.WalletID, order.FromCurrency, order.ToCurrency,
+		order.Amount, order.Rate, order.RequestID, order.ExpiresAt), &created)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.Exec(ctx, storage.AddWalletHeldBalanceQuery, order.WalletID, order.Amount); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &created, nil
+}
+
+func (r *PgLimitOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.LimitOrder, error) {
+	const op = "storage.GetLimitOrderByID"
+
+	var order models.LimitOrder
+	if err := scanLimitOrder(r.db.QueryRow(ctx, storage.GetLimitOrderByIDQuery, id), &order); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &order, nil
+}
+
+func (r *PgLimitOrderRepository) ListByUser(ctx context.Context, userID uuid.UUID, status models.LimitOrderStatus, limit int) ([]models.LimitOrder, error) {
+	const op = "storage.ListUserLimitOrders"
+
+	orders, err := r.list(ctx, storage.ListUserLimitOrdersQuery, userID, string(status), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return orders, nil
+}
+
+func (r *PgLimitOrderRepository) ListOpen(ctx context.Context, now time.Time, after uuid.UUID, limit int) ([]models.LimitOrder, error) {
+	const op = "storage.ListOpenLimitOrders"
+
+	orders, err := r.list(ctx, storage.ListOpenLimitOrdersQuery, now, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return orders, nil
+}
+
+func (r *PgLimitOrderRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]models.LimitOrder, error) {
+	const op = "storage.ListExpiredLimitOrders"
+
+	orders, err := r.list(ctx, storage.ListExpiredLimitOrdersQuery, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return orders, nil
+}
+
+func (r *PgLimitOrderRepository) CloseTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+	status models.LimitOrderStatus,
+	fill models.LimitOrderFill,
+	now time.Time,
+) (*models.LimitOrder, error) {
+	const op = "storage.CloseLimitOrderTx"
+
+	var order models.LimitOrder
+	err := scanLimitOrder(tx.QueryRow(ctx, storage.CloseLimitOrderQuery,
+		id, status, fill.ExchangedAmount, fill.Rate, fill.Fee, now), &order)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrOrderNotOpen
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.Exec(ctx, storage.AddWalletHeldBalanceQuery, order.WalletID, -order.Amount); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &order, nil
+}
+
+func (r *PgLimitOrderRepository) list(ctx context.Context, query string, args ...any) ([]models.LimitOrder, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.LimitOrder
+	for rows.Next() {
+		var order models.LimitOrder
+		if err := scanLimitOrder(rows, &order); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+func scanLimitOrder(row pgx.Row, order *models.LimitOrder) error {
+	return row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.WalletID,
+		&order.FromCurrency,
+		&order.ToCurrency,
+		&order.Amount,
+		&order.Rate,
+		&order.Status,
+		&order.Fill.ExchangedAmount,
+		&order.Fill.Rate,
+		&order.Fill.Fee,
+		&order.RequestID,
+		&order.ExpiresAt,
+		&order.FilledAt,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LimitRepository interface {
+	// ListForUser лимиты по умолчанию и переопределения пользователя
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.TransactionLimit, error)
+	ListForOperationTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string) ([]models.TransactionLimit, error)
+	// SumOperationsTx сумма операций пользователя типа opType в валюте списания за последние window
+	SumOperationsTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string, window time.Duration) (int64, error)
+	UpsertOverrideTx(ctx context.Context, tx pgx.Tx, limit models.TransactionLimit) error
+	DeleteOverrideTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string, period models.LimitPeriod) error
+}
+
+type PgLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLimitRepository(db *pgxpool.Pool) LimitRepository {
+	return &PgLimitRepository{db: db}
+}
+
+func (r *PgLimitRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.TransactionLimit, error) {
+	const op = "storage.ListUserLimits"
+
+	rows, err := r.db.Query(ctx, storage.ListUserLimitsQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	limits, err := scanLimits(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return limits, nil
+}
+
+func (r *PgLimitRepository) ListForOperationTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string) ([]models.TransactionLimit, error) {
+	const op = "storage.ListUserOperationLimitsTx"
+
+	rows, err := tx.Query(ctx, storage.ListUserOperationLimitsQuery, userID, opType, currency)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	limits, err := scanLimits(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return limits, nil
+}
+
+func scanLimits(rows pgx.Rows) ([]models.TransactionLimit, error) {
+	defer rows.Close()
+
+	var limits []models.TransactionLimit
+	for rows.Next() {
+		var l models.TransactionLimit
+		if err := rows.Scan(&l.UserID, &l.OperationType, &l.Currency, &l.Period, &l.MaxAmount, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		limits = append(limits, l)
+	}
+	return limits, rows.Err()
+}
+
+func (r *PgLimitRepository) SumOperationsTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string, window time.Duration) (int64, error) {
+	const op = "storage.SumUserOperationsTx"
+
+	var (
+		query string
+		args  []any
+	)
+	switch opType {
+	case models.OperationDeposit:
+		query, args = storage.SumUserBalanceOperationsQuery, []any{userID, currency, []string{string(opType)}, window.Seconds()}
+	case models.OperationWithdraw:
+		// Списание по холду выводит средства так же, как вывод
+		query, args = storage.SumUserBalanceOperationsQuery,
+			[]any{userID, currency, []string{string(models.OperationWithdraw), string(models.OperationCapture)}, window.Seconds()}
+	case models.OperationExchange:
+		query, args = storage.SumUserExchangesQuery, []any{userID, currency, window.Seconds()}
+	case models.OperationTransferOut:
+		query, args = storage.SumUserTransfersOutQuery, []any{userID, currency, window.Seconds()}
+	default:
+		return 0, fmt.Errorf("%s: unsupported operation type %q", op, opType)
+	}
+
+	var sum int64
+	if err := tx.QueryRow(ctx, query, args...).Scan(&sum); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return sum, nil
+}
+
+func (r *PgLimitRepository) UpsertOverrideTx(ctx context.Context, tx pgx.Tx, limit models.TransactionLimit) error {
+	const op = "storage.UpsertUserLimitTx"
+
+	_, err := tx.Exec(ctx, storage.UpsertUserLimitQuery,
+		limit.UserID, limit.OperationType, limit.Currency, limit.Period, limit.MaxAmount)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgLimitRepository) DeleteOverrideTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, opType models.OperationType, currency string, period models.LimitPeriod) error {
+	const op = "storage.DeleteUserLimitTx"
+
+	if _, err := tx.Exec(ctx, storage.DeleteUserLimitQuery, userID, opType, currency, period); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LoginThrottleRepository счетчики неверных попыток входа и журнал неудачных входов.
+// Состояние хранится в базе, поэтому ограничение общее для всех реплик сервиса.
+type LoginThrottleRepository interface {
+	// BlockedUntil самый поздний действующий запрет входа по имени или адресу; nil, если запретов нет
+	BlockedUntil(ctx context.Context, usernameKey, ipKey string) (*time.Time, error)
+	// RecordFailureTx учитывает неверную попытку и возвращает число попыток подряд. Счетчик начинается
+	// заново, если предыдущая неверная попытка была раньше чем window назад.
+	RecordFailureTx(ctx context.Context, tx pgx.Tx, scope models.LoginThrottleScope, key string, window time.Duration) (int, error)
+	// BlockTx запрещает вход по ключу на delay; более длинный действующий запрет сохраняется
+	BlockTx(ctx context.Context, tx pgx.Tx, scope models.LoginThrottleScope, key string, delay time.Duration) error
+	// Reset удаляет счетчик ключа и заодно счетчики, устаревшие дольше window
+	Reset(ctx context.Context, scope models.LoginThrottleScope, key string, window time.Duration) error
+
+	CreateFailureTx(ctx context.Context, tx pgx.Tx, failure models.LoginFailure) error
+}
+
+type PgLoginThrottleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginThrottleRepository(db *pgxpool.Pool) LoginThrottleRepository {
+	return &PgLoginThrottleRepository{db: db}
+}
+
+func (r *PgLoginThrottleRepository) BlockedUntil(ctx context.Context, usernameKey, ipKey string) (*time.Time, error) {
+	const op = "storage.GetLoginBlockedUntil"
+
+	var until *time.Time
+	if err := r.db.QueryRow(ctx, storage.GetLoginBlockedUntilQuery, usernameKey, ipKey).Scan(&until); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return until, nil
+}
+
+func (r *PgLoginThrottleRepository) RecordFailureTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	scope models.LoginThrottleScope,
+	key string,
+	window time.Duration,
+) (int, error) {
+	const op = "storage.RecordLoginThrottleFailureTx"
+
+	var attempts int
+	if err := tx.QueryRow(ctx, storage.RecordLoginThrottleFailureQuery, scope, key, window.Seconds()).Scan(&attempts); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return attempts, nil
+}
+
+func (r *PgLoginThrottleRepository) BlockTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	scope models.LoginThrottleScope,
+	key string,
+	delay time.Duration,
+) error {
+	const op = "storage.BlockLoginThrottleTx"
+
+	if _, err := tx.Exec(ctx, storage.BlockLoginThrottleQuery, scope, key, delay.Seconds()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgLoginThrottleRepository) Reset(ctx context.Context, scope models.LoginThrottleScope, key string, window time.Duration) error {
+	const op = "storage.ResetLoginThrottle"
+
+	if _, err := r.db.Exec(ctx, storage.ResetLoginThrottleQuery, scope, key); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := r.db.Exec(ctx, storage.DeleteStaleLoginThrottlesQuery, window.Seconds()); err != nil {
+		return fmt.Errorf("%s: failed to purge stale throttles: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgLoginThrottleRepository) CreateFailureTx(ctx context.Context, tx pgx.Tx, failure models.LoginFailure) error {
+	const op = "storage.CreateLoginFailureTx"
+
+	if _, err := tx.Exec(ctx, storage.CreateLoginFailureQuery,
+		failure.ID, failure.Username, failure.UserID, failure.IP, failure.Reason, failure.CreatedAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MFARepository настройки TOTP, коды восстановления и токены второго шага входа
+type MFARepository interface {
+	Get(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error)
+	GetForUpdateTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (*models.UserMFA, error)
+	// SavePending сохраняет секрет неподтвержденного подключения; custom_err.ErrMFAAlreadyEnabled,
+	// если TOTP уже включен
+	SavePending(ctx context.Context, userID uuid.UUID, secret string) error
+	// ListUnsealedSecrets настройки, секрет которых не начинается с sealedPrefix
+	ListUnsealedSecrets(ctx context.Context, sealedPrefix string) ([]models.UserMFA, error)
+	// ReplaceSecret заменяет секрет, если он все еще равен old
+	ReplaceSecret(ctx context.Context, userID uuid.UUID, old, secret string) error
+	// EnableTx завершает подключение; custom_err.ErrMFANotEnrolled, если подключение не начато или уже завершено
+	EnableTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, step int64) error
+	RecordSuccessTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, step int64) error
+	// RecordFailureTx учитывает неверный код и возвращает срок блокировки, если попытки исчерпаны
+	RecordFailureTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, maxAttempts int, lockFor time.Duration) (*time.Time, error)
+	DeleteTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error
+
+	// ReplaceRecoveryCodesTx удаляет прежние коды восстановления и сохраняет новые
+	ReplaceRecoveryCodesTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHashes []string) error
+	// ConsumeRecoveryCodeTx гасит код; custom_err.ErrNotFound, если кода нет или он уже использован
+	ConsumeRecoveryCodeTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+
+	CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error
+	GetChallengeForUpdateTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.MFAChallenge, error)
+	MarkChallengeUsedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error
+}
+
+type PgMFARepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMFARepository(db *pgxpool.Pool) MFARepository {
+	return &PgMFARepository{db: db}
+}
+
+func (r *PgMFARepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserMFA, error) {
+	const op = "storage.GetUserMFA"
+
+	mfa, err := scanUserMFA(r.db.QueryRow(ctx, storage.GetUserMFAQuery, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return mfa, nil
+}
+
+func (r *PgMFARepository) GetForUpdateTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (*models.UserMFA, error) {
+	const op = "storage.GetUserMFAForUpdateTx"
+
+	mfa, err := scanUserMFA(tx.QueryRow(ctx, storage.GetUserMFAForUpdateQuery, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return mfa, nil
+}
+
+func scanUserMFA(row pgx.Row) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	err := row.Scan(
+		&mfa.UserID,
+		&mfa.TOTPSecret,
+		&mfa.EnabledAt,
+		&mfa.LastUsedStep,
+		&mfa.FailedAttempts,
+		&mfa.LockedUntil,
+		&mfa.CreatedAt,
+		&mfa.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (r *PgMFARepository) SavePending(ctx context.Context, userID uuid.UUID, secret string) error {
+	const op = "storage.SavePendingUserMFA"
+
+	res, err := r.db.Exec(ctx, storage.SavePendingUserMFAQuery, userID, secret)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+func (r *PgMFARepository) ListUnsealedSecrets(ctx context.Context, sealedPrefix string) ([]models.UserMFA, error) {
+	const op = "storage.ListUnsealedUserMFASecrets"
+
+	rows, err := r.db.Query(ctx, storage.ListUnsealedUserMFASecretsQuery, sealedPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var result []models.UserMFA
+	for rows.Next() {
+		var mfa models.UserMFA
+		if err := rows.Scan(&mfa.UserID, &mfa.TOTPSecret); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		result = append(result, mfa)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return result, nil
+}
+
+func (r *PgMFARepository) ReplaceSecret(ctx context.Context, userID uuid.UUID, old, secret string) error {
+	const op = "storage.ReplaceUserMFASecret"
+
+	if _, err := r.db.Exec(ctx, storage.ReplaceUserMFASecretQuery, userID, old, secret); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgMFARepository) EnableTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, step int64) error {
+	const op = "storage.EnableUserMFATx"
+
+	res, err := tx.Exec(ctx, storage.EnableUserMFAQuery, userID, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrMFANotEnrolled
+	}
+	return nil
+}
+
+func (r *PgMFARepository) RecordSuccessTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, step int64) error {
+	const op = "storage.RecordMFASuccessTx"
+
+	if _, err := tx.Exec(ctx, storage.RecordMFASuccessQuery, userID, step); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgMFARepository) RecordFailureTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	maxAttempts int,
+	lockFor time.Duration,
+) (*time.Time, error) {
+	const op = "storage.RecordMFAFailureTx"
+
+	var lockedUntil *time.Time
+	err := tx.QueryRow(ctx, storage.RecordMFAFailureQuery, userID, maxAttempts, lockFor.Seconds()).Scan(&lockedUntil)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return lockedUntil, nil
+}
+
+func (r *PgMFARepository) DeleteTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	const op = "storage.DeleteUserMFATx"
+
+	if _, err := tx.Exec(ctx, storage.DeleteUserMFAQuery, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.Exec(ctx, storage.DeleteMFARecoveryCodesQuery, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgMFARepository) ReplaceRecoveryCodesTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHashes []string) error {
+	const op = "storage.ReplaceMFARecoveryCodesTx"
+
+	if _, err := tx.Exec(ctx, storage.DeleteMFARecoveryCodesQuery, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(ctx, storage.CreateMFARecoveryCodeQuery, uuid.New(), userID, hash); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	return nil
+}
+
+func (r *PgMFARepository) ConsumeRecoveryCodeTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, codeHash string) error {
+	const op = "storage.ConsumeMFARecoveryCodeTx"
+
+	res, err := tx.Exec(ctx, storage.ConsumeMFARecoveryCodeQuery, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrNotFound
+	}
+	return nil
+}
+
+func (r *PgMFARepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	const op = "storage.CountMFARecoveryCodes"
+
+	var count int
+	if err := r.db.QueryRow(ctx, storage.CountMFARecoveryCodesQuery, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+func (r *PgMFARepository) CreateChallenge(ctx context.Context, challenge *models.MFAChallenge) error {
+	const op = "storage.CreateMFAChallenge"
+
+	_, err := r.db.Exec(ctx, storage.CreateMFAChallengeQuery,
+		challenge.ID, challenge.UserID, challenge.TokenHash, challenge.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgMFARepository) GetChallengeForUpdateTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.MFAChallenge, error) {
+	const op = "storage.GetMFAChallengeForUpdateTx"
+
+	var c models.MFAChallenge
+	err := tx.QueryRow(ctx, storage.GetMFAChallengeForUpdateQuery, tokenHash).Scan(
+		&c.ID,
+		&c.UserID,
+		&c.TokenHash,
+		&c.ExpiresAt,
+		&c.UsedAt,
+		&c.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &c, nil
+}
+
+func (r *PgMFARepository) MarkChallengeUsedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	const op = "storage.MarkMFAChallengeUsedTx"
+
+	res, err := tx.Exec(ctx, storage.MarkMFAChallengeUsedQuery, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrInvalidToken
+	}
+	return nil
+}
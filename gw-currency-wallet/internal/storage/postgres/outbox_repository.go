@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxRepository interface {
+	CreateTx(ctx context.Context, tx pgx.Tx, eventType, key string, payload []byte) error
+	// Claim захватывает до limit готовых к отправке сообщений на время lease
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error)
+	MarkSent(ctx context.Context, id uuid.UUID) error
+	MarkFailed(ctx context.Context, id uuid.UUID, retryIn time.Duration, reason string) error
+	DeleteSentBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type PgOutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) OutboxRepository {
+	return &PgOutboxRepository{db: db}
+}
+
+func (r *PgOutboxRepository) CreateTx(ctx context.Context, tx pgx.Tx, eventType, key string, payload []byte) error {
+	const op = "storage.CreateOutboxMessageTx"
+
+	if _, err := tx.Exec(ctx, storage.CreateOutboxMessageQuery, eventType, key, payload); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgOutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	const op = "storage.ClaimOutboxMessages"
+
+	rows, err := r.db.Query(ctx, storage.ClaimOutboxMessagesQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var m models.OutboxMessage
+		if err := rows.Scan(&m.ID, &m.EventType, &m.Key, &m.Payload, &m.Attempts, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return messages, nil
+}
+
+func (r *PgOutboxRepository) MarkSent(ctx context.Context, id uuid.UUID) error {
+	const op = "storage.MarkOutboxMessageSent"
+
+	if _, err := r.db.Exec(ctx, storage.MarkOutboxMessageSentQuery, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgOutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, retryIn time.Duration, reason string) error {
+	const op = "storage.MarkOutboxMessageFailed"
+
+	if _, err := r.db.Exec(ctx, storage.MarkOutboxMessageFailedQuery, id, retryIn.Seconds(), reason); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgOutboxRepository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.DeleteSentOutboxMessages"
+
+	res, err := r.db.Exec(ctx, storage.DeleteSentOutboxMessagesQuery, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type QuoteRepository interface {
+	Create(ctx context.Context, quote models.ExchangeQuote) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.ExchangeQuote, error)
+	// UseTx помечает котировку использованной; custom_err.ErrQuoteExpired, если она истекла к now или уже использована
+	UseTx(ctx context.Context, tx pgx.Tx, id, userID uuid.UUID, now time.Time) error
+}
+
+type PgQuoteRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewQuoteRepository(db *pgxpool.Pool) QuoteRepository {
+	return &PgQuoteRepository{db: db}
+}
+
+func (r *PgQuoteRepository) Create(ctx context.Context, quote models.ExchangeQuote) error {
+	const op = "storage.CreateExchangeQuote"
+
+	_, err := r.db.Exec(ctx, storage.CreateExchangeQuoteQuery,
+		quote.ID, quote.UserID, quote.FromCurrency, quote.ToCurrency,
+		quote.Amount, quote.ExchangedAmount, quote.Rate, quote.Fee, quote.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgQuoteRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ExchangeQuote, error) {
+	const op = "storage.GetExchangeQuote"
+
+	var q models.ExchangeQuote
+	err := r.db.QueryRow(ctx, storage.GetExchangeQuoteQuery, id).Scan(
+		&q.ID, &q.UserID, &q.FromCurrency, &q.ToCurrency,
+		&q.Amount, &q.ExchangedAmount, &q.Rate, &q.Fee,
+		&q.ExpiresAt, &q.UsedAt, &q.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &q, nil
+}
+
+func (r *PgQuoteRepository) UseTx(ctx context.Context, tx pgx.Tx, id, userID uuid.UUID, now time.Time) error {
+	const op = "storage.UseExchangeQuoteTx"
+
+	res, err := tx.Exec(ctx, storage.UseExchangeQuoteQuery, id, userID, now)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrQuoteExpired
+	}
+	return nil
+}
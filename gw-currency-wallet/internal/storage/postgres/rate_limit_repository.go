@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RateLimitRepository корзины токенов ограничения частоты запросов. Корзина меняется одним
+// запросом, поэтому ограничение общее для всех реплик сервиса и не требует блокировок.
+type RateLimitRepository interface {
+	// Take пополняет корзину key по правилу limit и берет из нее токен, если он есть.
+	// Возвращает остаток токенов и получил ли запрос токен.
+	Take(ctx context.Context, key string, limit models.RateLimit) (float64, bool, error)
+	// DeleteIdle удаляет корзины, не использовавшиеся дольше idle
+	DeleteIdle(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+type PgRateLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitRepository(db *pgxpool.Pool) RateLimitRepository {
+	return &PgRateLimitRepository{db: db}
+}
+
+func (r *PgRateLimitRepository) Take(ctx context.Context, key string, limit models.RateLimit) (float64, bool, error) {
+	const op = "storage.TakeRateLimitToken"
+
+	var (
+		tokens  float64
+		allowed bool
+	)
+	if err := r.db.QueryRow(ctx, storage.TakeRateLimitTokenQuery, key, float64(limit.Burst), limit.Rate()).
+		Scan(&tokens, &allowed); err != nil {
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	return tokens, allowed, nil
+}
+
+func (r *PgRateLimitRepository) DeleteIdle(ctx context.Context, idle time.Duration) (int64, error) {
+	const op = "storage.DeleteIdleRateLimitBuckets"
+
+	tag, err := r.db.Exec(ctx, storage.DeleteIdleRateLimitBucketsQuery, idle.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReconciliationRepository прогоны сверки балансов и найденные расхождения
+type ReconciliationRepository interface {
+	// FailStaleReportsTx помечает неудачными прогоны, которые не обновлялись дольше staleAfter
+	FailStaleReportsTx(ctx context.Context, tx pgx.Tx, staleAfter time.Duration) (int64, error)
+	// CreateReportTx начинает прогон; custom_err.ErrReconciliationInProgress, если другой прогон еще идет
+	CreateReportTx(ctx context.Context, tx pgx.Tx, report models.ReconciliationReport) (*models.ReconciliationReport, error)
+	UpdateProgress(ctx context.Context, id uuid.UUID, walletsChecked, discrepancyCount int64) error
+	FinishReportTx(
+		ctx context.Context,
+		tx pgx.Tx,
+		id uuid.UUID,
+		status models.ReconciliationStatus,
+		walletsChecked, discrepancyCount int64,
+		errMsg string,
+	) (*models.ReconciliationReport, error)
+	GetReport(ctx context.Context, id uuid.UUID) (*models.ReconciliationReport, error)
+	ListReports(ctx context.Context, limit int) ([]models.ReconciliationReport, error)
+
+	// ScanWallets порция кошельков с id больше after и их суммы, пересчитанные из истории
+	ScanWallets(ctx context.Context, after uuid.UUID, limit int) ([]models.WalletReconciliation, error)
+	CreateDiscrepancies(ctx context.Context, reportID uuid.UUID, wallets []models.WalletReconciliation) error
+	// StreamDiscrepancies передает расхождения прогона в fn по одному, не загружая их в память целиком
+	StreamDiscrepancies(ctx context.Context, reportID uuid.UUID, fn func(models.WalletReconciliation) error) error
+}
+
+type PgReconciliationRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReconciliationRepository(db *pgxpool.Pool) ReconciliationRepository {
+	return &PgReconciliationRepository{db: db}
+}
+
+func (r *PgReconciliationRepository) FailStaleReportsTx(ctx context.Context, tx pgx.Tx, staleAfter time.Duration) (int64, error) {
+	const op = "storage.FailStaleReconciliationReportsTx"
+
+	tag, err := tx.Exec(ctx, storage.FailStaleReconciliationReportsQuery, staleAfter.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *PgReconciliationRepository) CreateReportTx(ctx context.Context, tx pgx.Tx, report models.ReconciliationReport) (*models.ReconciliationReport, error) {
+	const op = "storage.CreateReconciliationReportTx"
+
+	var created models.ReconciliationReport
+	err := scanReconciliationReport(tx.QueryRow(ctx, storage.CreateReconciliationReportQuery,
+		report.ID, report.Trigger, report.RequestedBy), &created)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, custom_err.ErrReconciliationInProgress
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &created, nil
+}
+
+func (r *PgReconciliationRepository) UpdateProgress(ctx context.Context, id uuid.UUID, walletsChecked, discrepancyCount int64) error {
+	const op = "storage.UpdateReconciliationProgress"
+
+	if _, err := r.db.Exec(ctx, storage.UpdateReconciliationProgressQuery, id, walletsChecked, discrepancyCount); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgReconciliationRepository) FinishReportTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	id uuid.UUID,
+	status models.ReconciliationStatus,
+	walletsChecked, discrepancyCount int64,
+	errMsg string,
+) (*models.ReconciliationReport, error) {
+	const op = "storage.FinishReconciliationReportTx"
+
+	var report models.ReconciliationReport
+	err := scanReconciliationReport(tx.QueryRow(ctx, storage.FinishReconciliationReportQuery,
+		id, status, walletsChecked, discrepancyCount, errMsg), &report)
+	if err != nil {
+		// Прогон уже помечен брошенным другим экземпляром
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &report, nil
+}
+
+func (r *PgReconciliationRepository) GetReport(ctx context.Context, id uuid.UUID) (*models.ReconciliationReport, error) {
+	const op = "storage.GetReconciliationReport"
+
+	var report models.ReconciliationReport
+	if err := scanReconciliationReport(r.db.QueryRow(ctx, storage.GetReconciliationReportQuery, id), &report); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &report, nil
+}
+
+func (r *PgReconciliationRepository) ListReports(ctx context.Context, limit int) ([]models.ReconciliationReport, error) {
+	const op = "storage.ListReconciliationReports"
+
+	rows, err := r.db.Query(ctx, storage.ListReconciliationReportsQuery, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var reports []models.ReconciliationReport
+	for rows.Next() {
+		var report models.ReconciliationReport
+		if err := scanReconciliationReport(rows, &report); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		reports = append(reports, report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return reports, nil
+}
+
+func (r *PgReconciliationRepository) ScanWallets(ctx context.Context, after uuid.UUID, limit int) ([]models.WalletReconciliation, error) {
+	const op = "storage.ScanWalletReconciliation"
+
+	rows, err := r.db.Query(ctx, storage.ScanWalletReconciliationQuery, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	wallets := make([]models.WalletReconciliation, 0, limit)
+	for rows.Next() {
+		var w models.WalletReconciliation
+		if err := rows.Scan(&w.WalletID, &w.UserID, &w.Currency, &w.Balance, &w.HeldBalance, &w.LedgerBalance, &w.Reserved); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		wallets = append(wallets, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return wallets, nil
+}
+
+func (r *PgReconciliationRepository) CreateDiscrepancies(ctx context.Context, reportID uuid.UUID, wallets []models.WalletReconciliation) error {
+	const op = "storage.CreateReconciliationDiscrepancies"
+
+	_, err := r.db.CopyFrom(ctx,
+		pgx.Identifier{"reconciliation_discrepancies"},
+		[]string{"report_id", "wallet_id", "user_id", "currency", "balance", "ledger_balance", "held_balance", "reserved_balance"},
+		pgx.CopyFromSlice(len(wallets), func(i int) ([]any, error) {
+			w := wallets[i]
+			return []any{reportID, w.WalletID, w.UserID, w.Currency, w.Balance, w.LedgerBalance, w.HeldBalance, w.Reserved}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgReconciliationRepository) StreamDiscrepancies(
+	ctx context.Context,
+	reportID uuid.UUID,
+	fn func(models.WalletReconciliation) error,
+) error {
+	const op = "storage.StreamReconciliationDiscrepancies"
+
+	rows, err := r.db.Query(ctx, storage.ListReconciliationDiscrepanciesQuery, reportID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w models.WalletReconciliation
+		if err := rows.Scan(&w.WalletID, &w.UserID, &w.Currency, &w.Balance, &w.HeldBalance, &w.LedgerBalance, &w.Reserved); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if err := fn(w); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func scanReconciliationReport(row pgx.Row, report *models.ReconciliationReport) error {
+	return row.Scan(
+		&report.ID,
+		&report.Trigger,
+		&report.Status,
+		&report.RequestedBy,
+		&report.WalletsChecked,
+		&report.DiscrepancyCount,
+		&report.Error,
+		&report.StartedAt,
+		&report.FinishedAt,
+	)
+}
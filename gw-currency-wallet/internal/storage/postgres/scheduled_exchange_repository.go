@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ScheduledExchangeRepository запланированные обмены и результаты их запусков
+type ScheduledExchangeRepository interface {
+	ScheduleExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error)
+	CreateTx(ctx context.Context, tx pgx.Tx, schedule models.ScheduledExchange) (*models.ScheduledExchange, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.ScheduledExchange, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, status models.ScheduledExchangeStatus, limit int) ([]models.ScheduledExchange, error)
+	// ListDue активные обмены, запуск которых наступил к now
+	ListDue(ctx context.Context, now time.Time, limit int) ([]models.ScheduledExchange, error)
+	// AdvanceTx переносит запуск с scheduledFor на next; next = nil завершает обмен.
+	// custom_err.ErrScheduleNotActive, если обмен не активен или запуск уже перенесен.
+	AdvanceTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, scheduledFor time.Time, next *time.Time) error
+	// Cancel отменяет активный обмен; custom_err.ErrScheduleNotActive, если он уже завершен или отменен
+	Cancel(ctx context.Context, id uuid.UUID) (*models.ScheduledExchange, error)
+	CreateRunTx(ctx context.Context, tx pgx.Tx, run models.ScheduledExchangeRun) error
+	ListRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]models.ScheduledExchangeRun, error)
+}
+
+type PgScheduledExchangeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewScheduledExchangeRepository(db *pgxpool.Pool) ScheduledExchangeRepository {
+	return &PgScheduledExchangeRepository{db: db}
+}
+
+func (r *PgScheduledExchangeRepository) ScheduleExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	const op = "storage.ScheduledExchangeExistsTx"
+
+	var exists bool
+	if err := tx.QueryRow(ctx, storage.ScheduledExchangeExistsQuery, requestID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return exists, nil
+}
+
+func (r *PgScheduledExchangeRepository) CreateTx(ctx context.Context, tx pgx.Tx, schedule models.ScheduledExchange) (*models.ScheduledExchange, error) {
+	const op = "storage.CreateScheduledExchangeTx"
+
+	var created models.ScheduledExchange
+	err := scanScheduledExchange(tx.QueryRow(ctx, storage.CreateScheduledExchangeQuery,
+		schedule.ID, schedule.UserID, schedule.FromCurrency, schedule.ToCurrency, schedule.Amount,
+		schedule.Cron, schedule.NextRunAt, schedule.RequestID), &created)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &created, nil
+}
+
+func (r *PgScheduledExchangeRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ScheduledExchange, error) {
+	const op = "storage.GetScheduledExchangeByID"
+
+	var schedule models.ScheduledExchange
+	if err := scanScheduledExchange(r.db.QueryRow(ctx, storage.GetScheduledExchangeByIDQuery, id), &schedule); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &schedule, nil
+}
+
+func (r *PgScheduledExchangeRepository) ListByUser(
+	ctx context.Context,
+	userID uuid.UUID,
+	status models.ScheduledExchangeStatus,
+	limit int,
+) ([]models.ScheduledExchange, error) {
+	const op = "storage.ListUserScheduledExchanges"
+
+	schedules, err := r.list(ctx, storage.ListUserScheduledExchangesQuery, userID, string(status), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return schedules, nil
+}
+
+func (r *PgScheduledExchangeRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]models.ScheduledExchange, error) {
+	const op = "storage.ListDueScheduledExchanges"
+
+	schedules, err := r.list(ctx, storage.ListDueScheduledExchangesQuery, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return schedules, nil
+}
+
+func (r *PgScheduledExchangeRepository) AdvanceTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, scheduledFor time.Time, next *time.Time) error {
+	const op = "storage.AdvanceScheduledExchangeTx"
+
+	tag, err := tx.Exec(ctx, storage.AdvanceScheduledExchangeQuery, id, scheduledFor, next)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return custom_err.ErrScheduleNotActive
+	}
+	return nil
+}
+
+func (r *PgScheduledExchangeRepository) Cancel(ctx context.Context, id uuid.UUID) (*models.ScheduledExchange, error) {
+	const op = "storage.CancelScheduledExchange"
+
+	var schedule models.ScheduledExchange
+	if err := scanScheduledExchange(r.db.QueryRow(ctx, storage.CancelScheduledExchangeQuery, id), &schedule); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrScheduleNotActive
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &schedule, nil
+}
+
+func (r *PgScheduledExchangeRepository) CreateRunTx(ctx context.Context, tx pgx.Tx, run models.ScheduledExchangeRun) error {
+	const op = "storage.CreateScheduledExchangeRunTx"
+
+	var rate any
+	if run.Status == models.ScheduledExchangeRunSucceeded {
+		rate = run.Fill.Rate
+	}
+	_, err := tx.Exec(ctx, storage.CreateScheduledExchangeRunQuery,
+		run.ID, run.ScheduleID, run.ScheduledFor, run.RequestID, run.Status,
+		run.Fill.ExchangedAmount, rate, run.Fill.Fee, run.ErrorCode, run.Error)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgScheduledExchangeRepository) ListRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]models.ScheduledExchangeRun, error) {
+	const op = "storage.ListScheduledExchangeRuns"
+
+	rows, err := r.db.Query(ctx, storage.ListScheduledExchangeRunsQuery, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var runs []models.ScheduledExchangeRun
+	for rows.Next() {
+		var run models.ScheduledExchangeRun
+		err := rows.Scan(
+			&run.ID,
+			&run.ScheduleID,
+			&run.ScheduledFor,
+			&run.RequestID,
+			&run.Status,
+			&run.Fill.ExchangedAmount,
+			&run.Fill.Rate,
+			&run.Fill.Fee,
+			&run.ErrorCode,
+			&run.Error,
+			&run.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return runs, nil
+}
+
+func (r *PgScheduledExchangeRepository) list(ctx context.Context, query string, args ...any) ([]models.ScheduledExchange, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.ScheduledExchange
+	for rows.Next() {
+		var schedule models.ScheduledExchange
+		if err := scanScheduledExchange(rows, &schedule); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+func scanScheduledExchange(row pgx.Row, schedule *models.ScheduledExchange) error {
+	return row.Scan(
+		&schedule.ID,
+		&schedule.UserID,
+		&schedule.FromCurrency,
+		&schedule.ToCurrency,
+		&schedule.Amount,
+		&schedule.Cron,
+		&schedule.Status,
+		&schedule.NextRunAt,
+		&schedule.RequestID,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+}
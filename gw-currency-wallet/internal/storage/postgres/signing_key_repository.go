@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SigningKeyRepository interface {
+	ListValid(ctx context.Context, now time.Time) ([]models.SigningKey, error)
+	ListValidTx(ctx context.Context, tx pgx.Tx, now time.Time) ([]models.SigningKey, error)
+	CreateTx(ctx context.Context, tx pgx.Tx, key models.SigningKey) error
+	// UpdatePrivateKeyTx заменяет сохраненный закрытый ключ, например зашифрованным
+	UpdatePrivateKeyTx(ctx context.Context, tx pgx.Tx, kid, privateKey string) error
+	DeleteExpiredTx(ctx context.Context, tx pgx.Tx, now time.Time) error
+	LockRotationTx(ctx context.Context, tx pgx.Tx) error
+}
+
+type PgSigningKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSigningKeyRepository(db *pgxpool.Pool) SigningKeyRepository {
+	return &PgSigningKeyRepository{db: db}
+}
+
+func (r *PgSigningKeyRepository) ListValid(ctx context.Context, now time.Time) ([]models.SigningKey, error) {
+	const op = "storage.ListValidSigningKeys"
+
+	rows, err := r.db.Query(ctx, storage.ListValidSigningKeysQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	keys, err := scanSigningKeys(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return keys, nil
+}
+
+func (r *PgSigningKeyRepository) ListValidTx(ctx context.Context, tx pgx.Tx, now time.Time) ([]models.SigningKey, error) {
+	const op = "storage.ListValidSigningKeysTx"
+
+	rows, err := tx.Query(ctx, storage.ListValidSigningKeysQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	keys, err := scanSigningKeys(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return keys, nil
+}
+
+func (r *PgSigningKeyRepository) CreateTx(ctx context.Context, tx pgx.Tx, key models.SigningKey) error {
+	const op = "storage.CreateSigningKeyTx"
+
+	_, err := tx.Exec(ctx, storage.CreateSigningKeyQuery,
+		key.KID, key.Algorithm, key.PrivateKey, key.ActivatesAt, key.DeactivatesAt, key.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgSigningKeyRepository) UpdatePrivateKeyTx(ctx context.Context, tx pgx.Tx, kid, privateKey string) error {
+	const op = "storage.UpdateSigningKeyPrivateKeyTx"
+
+	if _, err := tx.Exec(ctx, storage.UpdateSigningKeyPrivateKeyQuery, kid, privateKey); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgSigningKeyRepository) DeleteExpiredTx(ctx context.Context, tx pgx.Tx, now time.Time) error {
+	const op = "storage.DeleteExpiredSigningKeysTx"
+
+	if _, err := tx.Exec(ctx, storage.DeleteExpiredSigningKeysQuery, now); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgSigningKeyRepository) LockRotationTx(ctx context.Context, tx pgx.Tx) error {
+	const op = "storage.LockSigningKeyRotationTx"
+
+	if _, err := tx.Exec(ctx, storage.LockSigningKeyRotationQuery); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func scanSigningKeys(rows pgx.Rows) ([]models.SigningKey, error) {
+	defer rows.Close()
+
+	var keys []models.SigningKey
+	for rows.Next() {
+		var key models.SigningKey
+		if err := rows.Scan(
+			&key.KID,
+			&key.Algorithm,
+			&key.PrivateKey,
+			&key.ActivatesAt,
+			&key.DeactivatesAt,
+			&key.ExpiresAt,
+			&key.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gw-currency-wallet/internal/custom_err"
+	"gw-currency-wallet/internal/models"
+	"gw-currency-wallet/internal/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	CreateRefreshTokenTx(ctx context.Context, tx pgx.Tx, token *models.RefreshToken) error
+	GetRefreshTokenForUpdateTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.RefreshToken, error)
+	MarkRefreshTokenUsedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error
+	RevokeRefreshTokenFamilyTx(ctx context.Context, tx pgx.Tx, familyID uuid.UUID) error
+	RevokeRefreshTokenFamilyByToken(ctx context.Context, userID uuid.UUID, tokenHash string) error
+	RevokeUserRefreshTokensTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error
+
+	RevokeAccessToken(ctx context.Context, jti uuid.UUID, userID uuid.UUID, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error)
+}
+
+type PgTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTokenRepository(db *pgxpool.Pool) TokenRepository {
+	return &PgTokenRepository{db: db}
+}
+
+func (r *PgTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	const op = "storage.CreateRefreshToken"
+
+	_, err := r.db.Exec(ctx, storage.CreateRefreshTokenQuery,
+		token.ID, token.FamilyID, token.UserID, token.TokenHash, token.ExpiresAt, token.AuthTime, token.AMR)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgTokenRepository) CreateRefreshTokenTx(ctx context.Context, tx pgx.Tx, token *models.RefreshToken) error {
+	const op = "storage.CreateRefreshTokenTx"
+
+	_, err := tx.Exec(ctx, storage.CreateRefreshTokenQuery,
+		token.ID, token.FamilyID, token.UserID, token.TokenHash, token.ExpiresAt, token.AuthTime, token.AMR)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgTokenRepository) GetRefreshTokenForUpdateTx(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.RefreshToken, error) {
+	const op = "storage.GetRefreshTokenForUpdateTx"
+
+	var token models.RefreshToken
+	err := tx.QueryRow(ctx, storage.GetRefreshTokenForUpdateQuery, tokenHash).Scan(
+		&token.ID,
+		&token.FamilyID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.AuthTime,
+		&token.AMR,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &token, nil
+}
+
+func (r *PgTokenRepository) MarkRefreshTokenUsedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	const op = "storage.MarkRefreshTokenUsedTx"
+
+	res, err := tx.Exec(ctx, storage.MarkRefreshTokenUsedQuery, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func (r *PgTokenRepository) RevokeRefreshTokenFamilyTx(ctx context.Context, tx pgx.Tx, familyID uuid.UUID) error {
+	const op = "storage.RevokeRefreshTokenFamilyTx"
+
+	if _, err := tx.Exec(ctx, storage.RevokeRefreshTokenFamilyQuery, familyID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgTokenRepository) RevokeRefreshTokenFamilyByToken(ctx context.Context, userID uuid.UUID, tokenHash string) error {
+	const op = "storage.RevokeRefreshTokenFamilyByToken"
+
+	if _, err := r.db.Exec(ctx, storage.RevokeRefreshTokenFamilyByTokenQuery, tokenHash, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgTokenRepository) RevokeUserRefreshTokensTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+	const op = "storage.RevokeUserRefreshTokensTx"
+
+	if _, err := tx.Exec(ctx, storage.RevokeUserRefreshTokensQuery, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeAccessToken добавляет jti в denylist и заодно удаляет записи об уже истекших токенах
+func (r *PgTokenRepository) RevokeAccessToken(ctx context.Context, jti uuid.UUID, userID uuid.UUID, expiresAt time.Time) error {
+	const op = "storage.RevokeAccessToken"
+
+	if _, err := r.db.Exec(ctx, storage.RevokeAccessTokenQuery, jti, userID, expiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := r.db.Exec(ctx, storage.DeleteExpiredRevokedAccessTokensQuery); err != nil {
+		return fmt.Errorf("%s: failed to purge denylist: %w", op, err)
+	}
+	return nil
+}
+
+func (r *PgTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	const op = "storage.IsAccessTokenRevoked"
+
+	var revoked bool
+	if err := r.db.QueryRow(ctx, storage.IsAccessTokenRevokedQuery, jti).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return revoked, nil
+}
@@ -7,6 +7,8 @@ import (
 	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/models"
 	"gw-currency-wallet/internal/storage"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -18,6 +20,18 @@ type UserRepository interface {
 
 	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+
+	Search(ctx context.Context, filter models.UserSearchFilter) ([]*models.User, error)
+	SetRoleTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, role models.Role) (*models.User, error)
+
+	MarkEmailVerifiedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error
+	UpdatePasswordTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, passwordHash string) error
+
+	// LockTx блокирует вход до until; более длинная действующая блокировка сохраняется
+	LockTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, until time.Time) error
+	// UnlockTx снимает блокировку входа и сбрасывает счетчик неверных попыток по имени пользователя
+	UnlockTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*models.User, error)
 }
 type PgUserRepository struct {
 	db *pgxpool.Pool
@@ -35,14 +49,7 @@ func (r *PgUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.U
 	const op = "storage.GetByID"
 
 	var user models.User
-	err := r.db.QueryRow(ctx, storage.GetUserByIDQuery, id).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := scanUser(r.db.QueryRow(ctx, storage.GetUserByIDQuery, id), &user)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -58,14 +65,7 @@ func (r *PgUserRepository) GetByUsername(ctx context.Context, username string) (
 	const op = "storage.GetByUsername"
 
 	var user models.User
-	err := r.db.QueryRow(ctx, storage.GetUserByUsernameQuery, username).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := scanUser(r.db.QueryRow(ctx, storage.GetUserByUsernameQuery, username), &user)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -76,3 +76,140 @@ func (r *PgUserRepository) GetByUsername(ctx context.Context, username string) (
 
 	return &user, nil
 }
+
+func (r *PgUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	const op = "storage.GetByEmail"
+
+	var user models.User
+	err := scanUser(r.db.QueryRow(ctx, storage.GetUserByEmailQuery, email), &user)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &user, nil
+}
+
+// Search ищет пользователей по подстроке имени или email, постранично по username
+func (r *PgUserRepository) Search(ctx context.Context, filter models.UserSearchFilter) ([]*models.User, error) {
+	const op = "storage.SearchUsers"
+
+	var query, role, after *string
+	if filter.Query != "" {
+		q := escapeLike(filter.Query)
+		query = &q
+	}
+	if filter.Role != "" {
+		ro := string(filter.Role)
+		role = &ro
+	}
+	if filter.AfterUsername != "" {
+		after = &filter.AfterUsername
+	}
+
+	rows, err := r.db.Query(ctx, storage.SearchUsersQuery, query, role, after, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		users = append(users, &user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
+
+func (r *PgUserRepository) SetRoleTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, role models.Role) (*models.User, error) {
+	const op = "storage.SetRoleTx"
+
+	var user models.User
+	if err := scanUser(tx.QueryRow(ctx, storage.SetUserRoleQuery, id, role), &user); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &user, nil
+}
+
+func (r *PgUserRepository) MarkEmailVerifiedTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	const op = "storage.MarkEmailVerifiedTx"
+
+	res, err := tx.Exec(ctx, storage.MarkUserEmailVerifiedQuery, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrNotFound
+	}
+	return nil
+}
+
+func (r *PgUserRepository) UpdatePasswordTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, passwordHash string) error {
+	const op = "storage.UpdatePasswordTx"
+
+	res, err := tx.Exec(ctx, storage.UpdateUserPasswordQuery, id, passwordHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrNotFound
+	}
+	return nil
+}
+
+func (r *PgUserRepository) LockTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, until time.Time) error {
+	const op = "storage.LockUserTx"
+
+	res, err := tx.Exec(ctx, storage.LockUserQuery, id, until)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.RowsAffected() == 0 {
+		return custom_err.ErrNotFound
+	}
+	return nil
+}
+
+func (r *PgUserRepository) UnlockTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*models.User, error) {
+	const op = "storage.UnlockUserTx"
+
+	var user models.User
+	if err := scanUser(tx.QueryRow(ctx, storage.UnlockUserQuery, id), &user); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &user, nil
+}
+
+func scanUser(row pgx.Row, user *models.User) error {
+	return row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.LockedUntil,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -33,6 +33,8 @@ func (r *PgUserRepository) execCreate(ctx context.Context, q pgxQueryer, user *m
 		&createdUser.ID,
 		&createdUser.Username,
 		&createdUser.Email,
+		&createdUser.Role,
+		&createdUser.EmailVerifiedAt,
 		&createdUser.CreatedAt,
 		&createdUser.UpdatedAt,
 	)
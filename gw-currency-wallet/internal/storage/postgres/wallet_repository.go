@@ -7,6 +7,7 @@ import (
 	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/models"
 	"gw-currency-wallet/internal/storage"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -14,18 +15,26 @@ import (
 )
 
 type WalletRepository interface {
-	GetWalletBalanceForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (int64, error)
-	UpdateBalanceTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, newBalance int64) error
+	GetWalletBalanceForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (models.WalletBalance, error)
+	GetWalletForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (*models.Wallet, error)
+	SetFrozenTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, frozen bool, reason string) (*models.Wallet, error)
 	OperationExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error)
-	CreateOperationTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, amount int64, requestID string) error
+	CreateOperationTx(ctx context.Context, tx pgx.Tx, op models.Operation) error
 	CreateWalletTx(ctx context.Context, tx pgx.Tx, wallet *models.Wallet) error
+	GetOrCreateByUserAndCurrencyTx(ctx context.Context, tx pgx.Tx, userID uuid.UUID, currency models.Currency) (*models.Wallet, error)
 
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error)
 	GetByUserAndCurrency(ctx context.Context, userID uuid.UUID, currency models.Currency) (*models.Wallet, error)
+	GetOrCreateByUserAndCurrency(ctx context.Context, userID uuid.UUID, currency models.Currency) (*models.Wallet, error)
 	GetAllUserWallets(ctx context.Context, userID uuid.UUID) ([]*models.Wallet, error)
 
 	ExchangeOperationExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error)
 	CreateExchangeOperationTx(ctx context.Context, tx pgx.Tx, op models.ExchangeOperation) error
+
+	TransferExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error)
+	CreateTransferTx(ctx context.Context, tx pgx.Tx, transfer models.Transfer) error
+
+	ListTransactions(ctx context.Context, userID uuid.UUID, filter models.TransactionFilter) ([]*models.TransactionRecord, error)
 }
 type PgWalletRepository struct {
 	db *pgxpool.Pool
@@ -38,15 +47,7 @@ func NewWalletRepository(db *pgxpool.Pool) WalletRepository {
 func (r *PgWalletRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Wallet, error) {
 	const op = "storage.GetByID"
 	var wallet models.Wallet
-	err := r.db.QueryRow(ctx, storage.GetWalletByIDQuery, id).Scan(
-		&wallet.ID,
-		&wallet.UserID,
-		&wallet.Currency,
-		&wallet.Balance,
-		&wallet.Version,
-		&wallet.CreatedAt,
-		&wallet.UpdatedAt,
-	)
+	err := scanWallet(r.db.QueryRow(ctx, storage.GetWalletByIDQuery, id), &wallet)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, custom_err.ErrNotFound
@@ -59,15 +60,7 @@ func (r *PgWalletRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 func (r *PgWalletRepository) GetByUserAndCurrency(ctx context.Context, userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
 	const op = "storage.GetByUserAndCurrency"
 	var wallet models.Wallet
-	err := r.db.QueryRow(ctx, storage.GetWalletByUserAndCurrencyQuery, userID, currency).Scan(
-		&wallet.ID,
-		&wallet.UserID,
-		&wallet.Currency,
-		&wallet.Balance,
-		&wallet.Version,
-		&wallet.CreatedAt,
-		&wallet.UpdatedAt,
-	)
+	err := scanWallet(r.db.QueryRow(ctx, storage.GetWalletByUserAndCurrencyQuery, userID, currency), &wallet)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, custom_err.ErrNotFound
@@ -77,6 +70,42 @@ func (r *PgWalletRepository) GetByUserAndCurrency(ctx context.Context, userID uu
 	return &wallet, nil
 }
 
+// GetOrCreateByUserAndCurrency возвращает кошелек пользователя в валюте, создавая его при отсутствии
+func (r *PgWalletRepository) GetOrCreateByUserAndCurrency(ctx context.Context, userID uuid.UUID, currency models.Currency) (*models.Wallet, error) {
+	const op = "storage.GetOrCreateByUserAndCurrency"
+	var wallet models.Wallet
+	err := scanWallet(r.db.QueryRow(ctx, storage.GetOrCreateWalletQuery, uuid.New(), userID, currency), &wallet)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Кошелек только что создан параллельным запросом: его строку видит только следующий запрос
+			return r.GetByUserAndCurrency(ctx, userID, currency)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &wallet, nil
+}
+
+// GetOrCreateByUserAndCurrencyTx как GetOrCreateByUserAndCurrency, но в транзакции tx: созданный кошелек
+// откатывается вместе с операцией, для которой он понадобился
+func (r *PgWalletRepository) GetOrCreateByUserAndCurrencyTx(
+	ctx context.Context,
+	tx pgx.Tx,
+	userID uuid.UUID,
+	currency models.Currency,
+) (*models.Wallet, error) {
+	const op = "storage.GetOrCreateByUserAndCurrencyTx"
+	var wallet models.Wallet
+	err := scanWallet(tx.QueryRow(ctx, storage.GetOrCreateWalletQuery, uuid.New(), userID, currency), &wallet)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Кошелек только что создан параллельной транзакцией: ее строку видит только следующий запрос
+		err = scanWallet(tx.QueryRow(ctx, storage.GetWalletByUserAndCurrencyQuery, userID, currency), &wallet)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &wallet, nil
+}
+
 func (r *PgWalletRepository) GetAllUserWallets(ctx context.Context, userID uuid.UUID) ([]*models.Wallet, error) {
 	const op = "storage.GetAllUserWallets"
 
@@ -89,15 +118,7 @@ func (r *PgWalletRepository) GetAllUserWallets(ctx context.Context, userID uuid.
 	var wallets []*models.Wallet
 	for rows.Next() {
 		var wallet models.Wallet
-		err := rows.Scan(
-			&wallet.ID,
-			&wallet.UserID,
-			&wallet.Currency,
-			&wallet.Balance,
-			&wallet.Version,
-			&wallet.CreatedAt,
-			&wallet.UpdatedAt,
-		)
+		err := scanWallet(rows, &wallet)
 		if err != nil {
 			return nil, fmt.Errorf("%s: scan error: %w", op, err)
 		}
@@ -105,3 +126,101 @@ func (r *PgWalletRepository) GetAllUserWallets(ctx context.Context, userID uuid.
 	}
 	return wallets, nil
 }
+
+func (r *PgWalletRepository) ListTransactions(ctx context.Context, userID uuid.UUID, filter models.TransactionFilter) ([]*models.TransactionRecord, error) {
+	const op = "storage.ListTransactions"
+
+	var currency, opType *string
+	if filter.Currency != "" {
+		c := string(filter.Currency)
+		currency = &c
+	}
+	if filter.Type != "" {
+		t := string(filter.Type)
+		opType = &t
+	}
+
+	var afterCreatedAt *time.Time
+	var afterID *uuid.UUID
+	if filter.After != nil {
+		afterCreatedAt = &filter.After.CreatedAt
+		afterID = &filter.After.ID
+	}
+
+	rows, err := r.db.Query(ctx, storage.ListUserTransactionsQuery,
+		userID, currency, opType, filter.From, filter.To, afterCreatedAt, afterID, filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var records []*models.TransactionRecord
+	for rows.Next() {
+		var (
+			rec          models.TransactionRecord
+			recType      *string
+			toCurrency   *string
+			requestID    *string
+			counterparty *string
+		)
+		err := rows.Scan(
+			&rec.ID,
+			&recType,
+			&rec.Currency,
+			&rec.Amount,
+			&rec.BalanceAfter,
+			&toCurrency,
+			&rec.ToAmount,
+			&rec.ToBalanceAfter,
+			&rec.Rate,
+			&rec.Fee,
+			&requestID,
+			&counterparty,
+			&rec.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		if recType != nil {
+			rec.Type = models.OperationType(*recType)
+		}
+		if toCurrency != nil {
+			rec.ToCurrency = *toCurrency
+		}
+		if requestID != nil {
+			rec.RequestID = *requestID
+		}
+		if counterparty != nil {
+			rec.Counterparty = *counterparty
+		}
+		records = append(records, &rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return records, nil
+}
+
+func scanWallet(row pgx.Row, wallet *models.Wallet) error {
+	var frozenReason *string
+	err := row.Scan(
+		&wallet.ID,
+		&wallet.UserID,
+		&wallet.Currency,
+		&wallet.Balance,
+		&wallet.HeldBalance,
+		&wallet.Version,
+		&wallet.FrozenAt,
+		&frozenReason,
+		&wallet.CreatedAt,
+		&wallet.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if frozenReason != nil {
+		wallet.FrozenReason = *frozenReason
+	}
+	return nil
+}
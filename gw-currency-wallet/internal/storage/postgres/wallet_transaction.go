@@ -3,7 +3,6 @@ package postgres
 import (
 	"context"
 	"errors"
-	"fmt"
 	"gw-currency-wallet/internal/custom_err"
 	"gw-currency-wallet/internal/models"
 	"gw-currency-wallet/internal/storage"
@@ -13,37 +12,48 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
-func (r *PgWalletRepository) GetWalletBalanceForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (int64, error) {
-	var balance int64
-	err := tx.QueryRow(ctx, storage.GetWalletStateQuery, walletID).Scan(&balance)
+// GetWalletBalanceForUpdateTx блокирует кошелек для операции пользователя и возвращает его баланс
+// вместе с суммой холдов. По замороженному кошельку возвращает custom_err.ErrWalletFrozen
+func (r *PgWalletRepository) GetWalletBalanceForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (models.WalletBalance, error) {
+	var (
+		balance models.WalletBalance
+		frozen  bool
+	)
+	err := tx.QueryRow(ctx, storage.GetWalletStateQuery, walletID).Scan(&balance.Balance, &balance.Held, &frozen)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return 0, custom_err.ErrNotFound
+			return models.WalletBalance{}, custom_err.ErrNotFound
 		}
-		return 0, err
+		return models.WalletBalance{}, err
+	}
+	if frozen {
+		return models.WalletBalance{}, custom_err.ErrWalletFrozen
 	}
 	return balance, nil
 }
-func (r *PgWalletRepository) UpdateBalanceTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, newBalance int64) error {
-	res, err := tx.Exec(ctx,
-		storage.UpdateWalletBalanceQuery,
-		newBalance,
-		walletID,
-	)
-	if err != nil {
 
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23514" {
-			return custom_err.ErrInsufficientFunds
+// GetWalletForUpdateTx блокирует кошелек независимо от заморозки
+func (r *PgWalletRepository) GetWalletForUpdateTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID) (*models.Wallet, error) {
+	var wallet models.Wallet
+	if err := scanWallet(tx.QueryRow(ctx, storage.GetWalletForUpdateQuery, walletID), &wallet); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
 		}
-		return err
+		return nil, err
 	}
+	return &wallet, nil
+}
 
-	if res.RowsAffected() == 0 {
-		return custom_err.ErrNotFound
+// SetFrozenTx замораживает кошелек с указанием причины или снимает заморозку
+func (r *PgWalletRepository) SetFrozenTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, frozen bool, reason string) (*models.Wallet, error) {
+	var wallet models.Wallet
+	if err := scanWallet(tx.QueryRow(ctx, storage.SetWalletFrozenQuery, walletID, frozen, reason), &wallet); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, custom_err.ErrNotFound
+		}
+		return nil, err
 	}
-
-	return nil
+	return &wallet, nil
 }
 
 func (r *PgWalletRepository) OperationExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
@@ -61,14 +71,9 @@ func (r *PgWalletRepository) ExchangeOperationExistsTx(ctx context.Context, tx p
 	return exists, err
 }
 
-func (r *PgWalletRepository) RequestIDExists(ctx context.Context, q pgxQueryer, table, requestID string) (bool, error) {
-	query := fmt.Sprintf(storage.CheckOperationExistsQuery, table)
-	var exists bool
-	err := q.QueryRow(ctx, query, requestID).Scan(&exists)
-	return exists, err
-}
-func (r *PgWalletRepository) CreateOperationTx(ctx context.Context, tx pgx.Tx, walletID uuid.UUID, amount int64, requestID string) error {
-	_, err := tx.Exec(ctx, storage.CreateOperationQuery, walletID, amount, requestID)
+func (r *PgWalletRepository) CreateOperationTx(ctx context.Context, tx pgx.Tx, op models.Operation) error {
+	_, err := tx.Exec(ctx, storage.CreateOperationQuery,
+		op.WalletID, op.OperationType, op.Amount, op.BalanceAfter, op.RequestID)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -79,16 +84,46 @@ func (r *PgWalletRepository) CreateOperationTx(ctx context.Context, tx pgx.Tx, w
 	return nil
 }
 
+// CreateWalletTx создает кошелек вместе с его счетом в главной книге
 func (r *PgWalletRepository) CreateWalletTx(ctx context.Context, tx pgx.Tx, wallet *models.Wallet) error {
 	_, err := tx.Exec(ctx, storage.CreateWalletQuery,
 		wallet.ID, wallet.UserID, wallet.Currency, wallet.Balance)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, storage.CreateWalletLedgerAccountQuery, wallet.ID, wallet.Currency)
 	return err
 }
 
 func (r *PgWalletRepository) CreateExchangeOperationTx(ctx context.Context, tx pgx.Tx, op models.ExchangeOperation) error {
 	_, err := tx.Exec(ctx, storage.CreateExchangeOperationQuery,
 		op.UserID, op.FromCurrency, op.ToCurrency,
-		op.Amount, op.ExchangedAmount, op.Rate, op.RequestID)
+		op.Amount, op.ExchangedAmount, op.Rate,
+		op.FromBalanceAfter, op.ToBalanceAfter, op.RequestID, op.QuoteID, op.Fee)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return custom_err.ErrDuplicateRequest
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *PgWalletRepository) TransferExistsTx(ctx context.Context, tx pgx.Tx, requestID string) (bool, error) {
+	var exists bool
+	err := tx.QueryRow(ctx, storage.TransferExistsQuery, requestID).Scan(&exists)
+	return exists, err
+}
+
+func (r *PgWalletRepository) CreateTransferTx(ctx context.Context, tx pgx.Tx, transfer models.Transfer) error {
+	_, err := tx.Exec(ctx, storage.CreateTransferQuery,
+		transfer.ID, transfer.SenderID, transfer.RecipientID,
+		transfer.FromCurrency, transfer.ToCurrency,
+		transfer.Amount, transfer.ReceivedAmount, transfer.Rate,
+		transfer.SenderBalanceAfter, transfer.RecipientBalanceAfter, transfer.RequestID)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
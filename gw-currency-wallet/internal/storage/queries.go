@@ -3,21 +3,21 @@ package storage
 const (
 	// Wallet queries
 	GetWalletByIDQuery = `
-		SELECT id, user_id, currency, balance, version, created_at, updated_at
+		SELECT id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at
 		FROM wallets
 		WHERE id = $1
 	`
 
 	// Получить конкретный кошелек пользователя по валюте
 	GetWalletByUserAndCurrencyQuery = `
-		SELECT id, user_id, currency, balance, version, created_at, updated_at
+		SELECT id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at
 		FROM wallets
 		WHERE user_id = $1 AND currency = $2
 	`
 
 	// Получить все кошельки пользователя
 	GetAllUserWalletsQuery = `
-		SELECT id, user_id, currency, balance, version, created_at, updated_at
+		SELECT id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at
 		FROM wallets
 		WHERE user_id = $1
 		ORDER BY currency
@@ -27,28 +27,57 @@ const (
 	CreateWalletQuery = `
 		INSERT INTO wallets (id, user_id, currency, balance)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, user_id, currency, balance, version, created_at, updated_at
+		RETURNING id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at
+	`
+
+	// Кошелек и его счет в главной книге создаются при первом обращении к валюте.
+	// Если кошелек создан параллельным запросом после начала выполнения, запрос вернет пустой результат
+	GetOrCreateWalletQuery = `
+		WITH ins AS (
+			INSERT INTO wallets (id, user_id, currency, balance)
+			VALUES ($1, $2, $3, 0)
+			ON CONFLICT (user_id, currency) DO NOTHING
+			RETURNING id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at
+		), ins_account AS (
+			INSERT INTO ledger_accounts (id, wallet_id, currency)
+			SELECT id, id, currency FROM ins
+		)
+		SELECT id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at FROM ins
+		UNION ALL
+		SELECT id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at
+		FROM wallets
+		WHERE user_id = $2 AND currency = $3
+		LIMIT 1
+	`
+
+	// Кошелек целиком с блокировкой строки; используется административными операциями
+	GetWalletForUpdateQuery = `
+		SELECT id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at
+		FROM wallets
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	SetWalletFrozenQuery = `
+		UPDATE wallets
+		SET frozen_at = CASE WHEN $2 THEN now() END,
+		    frozen_reason = CASE WHEN $2 THEN $3 END
+		WHERE id = $1
+		RETURNING id, user_id, currency, balance, held_balance, version, frozen_at, frozen_reason, created_at, updated_at
 	`
 
 	// Transaction queries (с FOR UPDATE для блокировки)
 	GetWalletStateQuery = `
-		SELECT balance 
+		SELECT balance, held_balance, frozen_at IS NOT NULL
     FROM wallets
     WHERE id = $1 
     FOR UPDATE NOWAIT
 	`
 
-	// Обновление баланса
-	UpdateWalletBalanceQuery = `
-		UPDATE wallets 
-		SET balance = $1
-		WHERE id = $2
-	`
-
 	// Operation queries
 	CreateOperationQuery = `
-		INSERT INTO operations (wallet_id, amount, request_id) 
-		VALUES ($1, $2, $3)
+		INSERT INTO operations (wallet_id, operation_type, amount, balance_after, request_id)
+		VALUES ($1, $2, $3, $4, $5)
 	`
 
 	CheckOperationExistsQuery = `
@@ -63,25 +92,82 @@ const (
 	CreateUserQuery = `
 		INSERT INTO users (id, username, email, password_hash)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, username, email, created_at, updated_at
+		RETURNING id, username, email, role, email_verified_at, created_at, updated_at
 	`
 
 	GetUserByUsernameQuery = `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, role, email_verified_at, locked_until, created_at, updated_at
 		FROM users
 		WHERE username = $1
 	`
 
 	GetUserByEmailQuery = `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, role, email_verified_at, locked_until, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
 
 	GetUserByIDQuery = `
-		SELECT id, username, email, password_hash, created_at, updated_at
+		SELECT id, username, email, password_hash, role, email_verified_at, locked_until, created_at, updated_at
+		FROM users
+		WHERE id = $1
+	`
+
+	// Поиск по подстроке имени или email ($1 уже экранирован для LIKE), keyset по username
+	SearchUsersQuery = `
+		SELECT id, username, email, password_hash, role, email_verified_at, locked_until, created_at, updated_at
 		FROM users
+		WHERE ($1::text IS NULL OR username ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%')
+		  AND ($2::text IS NULL OR role = $2)
+		  AND ($3::text IS NULL OR username > $3)
+		ORDER BY username
+		LIMIT $4
+	`
+
+	SetUserRoleQuery = `
+		UPDATE users
+		SET role = $2
 		WHERE id = $1
+		RETURNING id, username, email, password_hash, role, email_verified_at, locked_until, created_at, updated_at
+	`
+
+	// Повторное подтверждение не меняет исходное время
+	MarkUserEmailVerifiedQuery = `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, now()),
+		    updated_at = now()
+		WHERE id = $1
+	`
+
+	UpdateUserPasswordQuery = `
+		UPDATE users
+		SET password_hash = $2,
+		    updated_at = now()
+		WHERE id = $1
+	`
+
+	// Блокировка не сокращается, если уже действует более длинная
+	LockUserQuery = `
+		UPDATE users
+		SET locked_until = GREATEST(COALESCE(locked_until, $2), $2),
+		    updated_at = now()
+		WHERE id = $1
+	`
+
+	// Снятие блокировки сбрасывает и счетчик неверных попыток по имени пользователя
+	UnlockUserQuery = `
+		WITH unlocked AS (
+			UPDATE users
+			SET locked_until = NULL,
+			    updated_at = now()
+			WHERE id = $1
+			RETURNING id, username, email, password_hash, role, email_verified_at, locked_until, created_at, updated_at
+		), cleared AS (
+			DELETE FROM login_throttles
+			WHERE scope = 'username' AND key IN (SELECT lower(username) FROM unlocked)
+		)
+		SELECT id, username, email, password_hash, role, email_verified_at, locked_until, created_at, updated_at
+		FROM unlocked
 	`
 
 	CheckUserExistsByUsernameQuery = `
@@ -109,7 +195,973 @@ const (
 
 	CreateExchangeOperationQuery = `
 	INSERT INTO exchange_operations (
-            user_id, from_currency, to_currency, amount, exchanged_amount, rate, request_id
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7)
+            user_id, from_currency, to_currency, amount, exchanged_amount, rate,
+            from_balance_after, to_balance_after, request_id, quote_id, fee
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	// Exchange quote queries
+	CreateExchangeQuoteQuery = `
+		INSERT INTO exchange_quotes (
+			id, user_id, from_currency, to_currency, amount, exchanged_amount, rate, fee, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	GetExchangeQuoteQuery = `
+		SELECT id, user_id, from_currency, to_currency, amount, exchanged_amount, rate, fee,
+		       expires_at, used_at, created_at
+		FROM exchange_quotes
+		WHERE id = $1
+	`
+
+	// Котировка одноразовая: условие used_at IS NULL не дает двум конкурентным обменам использовать ее дважды
+	UseExchangeQuoteQuery = `
+		UPDATE exchange_quotes
+		SET used_at = now()
+		WHERE id = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > $3
+	`
+
+	// Единая лента операций пользователя: пополнения/выводы, обмены и переводы (обе стороны).
+	// Сортировка (created_at DESC, id DESC), курсор — последняя строка предыдущей страницы.
+	ListUserTransactionsQuery = `
+		SELECT t.id, t.type, t.currency, t.amount, t.balance_after,
+		       t.to_currency, t.to_amount, t.to_balance_after, t.rate, t.fee, t.request_id,
+		       u.username AS counterparty, t.created_at
+		FROM (
+			SELECT o.id, o.operation_type AS type, w.currency, o.amount, o.balance_after,
+			       NULL::varchar AS to_currency, 0::bigint AS to_amount, NULL::bigint AS to_balance_after,
+			       0::numeric AS rate, 0::bigint AS fee, o.request_id, NULL::uuid AS counterparty_id, o.created_at
+			FROM operations o
+			JOIN wallets w ON w.id = o.wallet_id
+			WHERE w.user_id = $1
+
+			UNION ALL
+
+			SELECT e.id, 'EXCHANGE', e.from_currency, -e.amount, e.from_balance_after,
+			       e.to_currency, e.exchanged_amount, e.to_balance_after,
+			       e.rate, e.fee, e.request_id, NULL::uuid, e.created_at
+			FROM exchange_operations e
+			WHERE e.user_id = $1
+
+			UNION ALL
+
+			SELECT tr.id, 'TRANSFER_OUT', tr.from_currency, -tr.amount, tr.sender_balance_after,
+			       tr.to_currency, tr.received_amount, NULL::bigint,
+			       tr.rate, 0::bigint, tr.request_id, tr.recipient_id, tr.created_at
+			FROM transfers tr
+			WHERE tr.sender_id = $1
+
+			UNION ALL
+
+			SELECT tr.id, 'TRANSFER_IN', tr.to_currency, tr.received_amount, tr.recipient_balance_after,
+			       NULL::varchar, 0::bigint, NULL::bigint,
+			       tr.rate, 0::bigint, NULL::text, tr.sender_id, tr.created_at
+			FROM transfers tr
+			WHERE tr.recipient_id = $1
+		) t
+		LEFT JOIN users u ON u.id = t.counterparty_id
+		WHERE ($2::text IS NULL OR t.currency = $2 OR t.to_currency = $2)
+		  AND ($3::text IS NULL OR t.type = $3)
+		  AND ($4::timestamptz IS NULL OR t.created_at >= $4)
+		  AND ($5::timestamptz IS NULL OR t.created_at < $5)
+		  AND ($6::timestamptz IS NULL OR (t.created_at, t.id) < ($6, $7::uuid))
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $8
+	`
+
+	// Hold queries
+	HoldExistsQuery = `
+		SELECT EXISTS(
+			SELECT 1
+			FROM wallet_holds
+			WHERE request_id = $1
+		)
+	`
+
+	CreateHoldQuery = `
+		INSERT INTO wallet_holds (
+			id, wallet_id, user_id, currency, amount, description, request_id, expires_at
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id, wallet_id, user_id, currency, amount, captured_amount, status,
+		          COALESCE(description, ''), request_id, expires_at, created_at, updated_at
+	`
+
+	GetHoldByIDQuery = `
+		SELECT id, wallet_id, user_id, currency, amount, captured_amount, status,
+		       COALESCE(description, ''), request_id, expires_at, created_at, updated_at
+		FROM wallet_holds
+		WHERE id = $1
+	`
+
+	// Холд закрывается один раз: списать или освободить можно только действующий холд,
+	// пометить истекшим - только тот, срок которого прошел к $4
+	CloseHoldQuery = `
+		UPDATE wallet_holds
+		SET status = $2, captured_amount = $3, updated_at = now()
+		WHERE id = $1
+		  AND status = 'ACTIVE'
+		  AND (expires_at > $4) = ($2 <> 'EXPIRED')
+		RETURNING id, wallet_id, user_id, currency, amount, captured_amount, status,
+		          COALESCE(description, ''), request_id, expires_at, created_at, updated_at
+	`
+
+	// Изменить сумму, зарезервированную холдами кошелька
+	AddWalletHeldBalanceQuery = `
+		UPDATE wallets
+		SET held_balance = held_balance + $2
+		WHERE id = $1
+	`
+
+	ListExpiredHoldsQuery = `
+		SELECT id, wallet_id, user_id, currency, amount, captured_amount, status,
+		       COALESCE(description, ''), request_id, expires_at, created_at, updated_at
+		FROM wallet_holds
+		WHERE status = 'ACTIVE' AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+	`
+
+	// Limit order queries
+	LimitOrderExistsQuery = `
+		SELECT EXISTS(
+			SELECT 1
+			FROM limit_orders
+			WHERE request_id = $1
+		)
+	`
+
+	CreateLimitOrderQuery = `
+		INSERT INTO limit_orders (
+			id, user_id, wallet_id, from_currency, to_currency, amount, rate, request_id, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, user_id, wallet_id, from_currency, to_currency, amount, rate, status,
+		       exchanged_amount, COALESCE(executed_rate, 0), fee, request_id, expires_at, filled_at,
+		       created_at, updated_at
+	`
+
+	GetLimitOrderByIDQuery = `
+		SELECT id, user_id, wallet_id, from_currency, to_currency, amount, rate, status,
+		       exchanged_amount, COALESCE(executed_rate, 0), fee, request_id, expires_at, filled_at,
+		       created_at, updated_at
+		FROM limit_orders
+		WHERE id = $1
+	`
+
+	// Ордера пользователя, новые первыми; $2 = '' - без фильтра по статусу
+	ListUserLimitOrdersQuery = `
+		SELECT id, user_id, wallet_id, from_currency, to_currency, amount, rate, status,
+		       exchanged_amount, COALESCE(executed_rate, 0), fee, request_id, expires_at, filled_at,
+		       created_at, updated_at
+		FROM limit_orders
+		WHERE user_id = $1
+		  AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`
+
+	// Открытые действующие ордера порциями по id: ордера после $2
+	ListOpenLimitOrdersQuery = `
+		SELECT id, user_id, wallet_id, from_currency, to_currency, amount, rate, status,
+		       exchanged_amount, COALESCE(executed_rate, 0), fee, request_id, expires_at, filled_at,
+		       created_at, updated_at
+		FROM limit_orders
+		WHERE status = 'OPEN'
+		  AND (expires_at IS NULL OR expires_at > $1)
+		  AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+
+	ListExpiredLimitOrdersQuery = `
+		SELECT id, user_id, wallet_id, from_currency, to_currency, amount, rate, status,
+		       exchanged_amount, COALESCE(executed_rate, 0), fee, request_id, expires_at, filled_at,
+		       created_at, updated_at
+		FROM limit_orders
+		WHERE status = 'OPEN' AND expires_at <= $1
+		ORDER BY expires_at
+		LIMIT $2
+	`
+
+	// Ордер закрывается один раз: исполнить или отменить можно только действующий ордер,
+	// пометить истекшим - только тот, срок которого прошел к $6
+	CloseLimitOrderQuery = `
+		UPDATE limit_orders
+		SET status = $2,
+		    exchanged_amount = $3,
+		    executed_rate = CASE WHEN $2 = 'FILLED' THEN $4::numeric END,
+		    fee = $5,
+		    filled_at = CASE WHEN $2 = 'FILLED' THEN $6::timestamptz END,
+		    updated_at = now()
+		WHERE id = $1
+		  AND status = 'OPEN'
+		  AND (expires_at IS NULL OR expires_at > $6) = ($2 <> 'EXPIRED')
+		RETURNING id, user_id, wallet_id, from_currency, to_currency, amount, rate, status,
+		       exchanged_amount, COALESCE(executed_rate, 0), fee, request_id, expires_at, filled_at,
+		       created_at, updated_at
+	`
+
+	// Scheduled exchange queries
+	ScheduledExchangeExistsQuery = `
+		SELECT EXISTS(
+			SELECT 1
+			FROM scheduled_exchanges
+			WHERE request_id = $1
+		)
+	`
+
+	CreateScheduledExchangeQuery = `
+		INSERT INTO scheduled_exchanges (
+			id, user_id, from_currency, to_currency, amount, cron, next_run_at, request_id
+		) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
+		RETURNING id, user_id, from_currency, to_currency, amount, COALESCE(cron, ''), status,
+		       next_run_at, request_id, created_at, updated_at
+	`
+
+	GetScheduledExchangeByIDQuery = `
+		SELECT id, user_id, from_currency, to_currency, amount, COALESCE(cron, ''), status,
+		       next_run_at, request_id, created_at, updated_at
+		FROM scheduled_exchanges
+		WHERE id = $1
+	`
+
+	// Запланированные обмены пользователя, новые первыми; $2 = '' - без фильтра по статусу
+	ListUserScheduledExchangesQuery = `
+		SELECT id, user_id, from_currency, to_currency, amount, COALESCE(cron, ''), status,
+		       next_run_at, request_id, created_at, updated_at
+		FROM scheduled_exchanges
+		WHERE user_id = $1
+		  AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`
+
+	ListDueScheduledExchangesQuery = `
+		SELECT id, user_id, from_currency, to_currency, amount, COALESCE(cron, ''), status,
+		       next_run_at, request_id, created_at, updated_at
+		FROM scheduled_exchanges
+		WHERE status = 'ACTIVE' AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2
+	`
+
+	// Переносит запуск с $2 на $3 только если его еще не перенес другой обработчик;
+	// $3 IS NULL завершает разовый обмен
+	AdvanceScheduledExchangeQuery = `
+		UPDATE scheduled_exchanges
+		SET next_run_at = $3,
+		    status = CASE WHEN $3::timestamptz IS NULL THEN 'COMPLETED' ELSE status END,
+		    updated_at = now()
+		WHERE id = $1 AND status = 'ACTIVE' AND next_run_at = $2
+	`
+
+	CancelScheduledExchangeQuery = `
+		UPDATE scheduled_exchanges
+		SET status = 'CANCELLED',
+		    next_run_at = NULL,
+		    updated_at = now()
+		WHERE id = $1 AND status = 'ACTIVE'
+		RETURNING id, user_id, from_currency, to_currency, amount, COALESCE(cron, ''), status,
+		       next_run_at, request_id, created_at, updated_at
+	`
+
+	CreateScheduledExchangeRunQuery = `
+		INSERT INTO scheduled_exchange_runs (
+			id, schedule_id, scheduled_for, request_id, status, exchanged_amount, rate, fee,
+			error_code, error
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''))
+	`
+
+	ListScheduledExchangeRunsQuery = `
+		SELECT id, schedule_id, scheduled_for, request_id, status, exchanged_amount,
+		       COALESCE(rate, 0), fee, COALESCE(error_code, ''), COALESCE(error, ''), created_at
+		FROM scheduled_exchange_runs
+		WHERE schedule_id = $1
+		ORDER BY scheduled_for DESC
+		LIMIT $2
+	`
+
+	// Exchange pricing queries
+	ListExchangePricingQuery = `
+		SELECT from_currency, to_currency, spread_bps
+		FROM exchange_pricing
+	`
+
+	ListExchangeFeeTiersQuery = `
+		SELECT from_currency, to_currency, min_amount, percent_fee, fixed_fee
+		FROM exchange_fee_tiers
+		ORDER BY from_currency, to_currency, min_amount
+	`
+
+	TransferExistsQuery = `
+		SELECT EXISTS(
+			SELECT 1
+			FROM transfers
+			WHERE request_id = $1
+		)
+	`
+
+	CreateTransferQuery = `
+		INSERT INTO transfers (
+			id, sender_id, recipient_id, from_currency, to_currency, amount, received_amount, rate,
+			sender_balance_after, recipient_balance_after, request_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	// Ledger queries
+	CreateWalletLedgerAccountQuery = `
+		INSERT INTO ledger_accounts (id, wallet_id, currency)
+		VALUES ($1, $1, $2)
+	`
+
+	// Системный счет создается при первом обращении. Как и у GetOrCreateWalletQuery, результат пуст,
+	// если счет создан параллельной транзакцией после начала выполнения
+	GetOrCreateSystemAccountQuery = `
+		WITH ins AS (
+			INSERT INTO ledger_accounts (system_code, currency)
+			VALUES ($1, $2)
+			ON CONFLICT (system_code, currency) DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM ins
+		UNION ALL
+		SELECT id FROM ledger_accounts WHERE system_code = $1 AND currency = $2
+		LIMIT 1
+	`
+
+	GetSystemAccountQuery = `
+		SELECT id FROM ledger_accounts WHERE system_code = $1 AND currency = $2
+	`
+
+	CreateJournalEntryQuery = `
+		INSERT INTO journal_entries (id, entry_type, request_id)
+		VALUES ($1, $2, $3)
+	`
+
+	// Валюта записи должна совпадать с валютой счета, иначе строка не вставится
+	CreatePostingQuery = `
+		INSERT INTO postings (journal_entry_id, account_id, currency, amount)
+		SELECT $1, id, currency, $4
+		FROM ledger_accounts
+		WHERE id = $2 AND currency = $3
+	`
+
+	// Обновление проекции баланса для счетов кошельков (для системных счетов не затрагивает строк)
+	ApplyPostingToWalletQuery = `
+		UPDATE wallets w
+		SET balance = w.balance + $1
+		FROM ledger_accounts a
+		WHERE a.id = $2 AND a.wallet_id = w.id
+	`
+
+	GetAccountLedgerBalanceQuery = `
+		SELECT COALESCE(SUM(amount), 0)::bigint
+		FROM postings
+		WHERE account_id = $1
+	`
+
+	// Token queries
+	CreateRefreshTokenQuery = `
+		INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, expires_at, auth_time, amr)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	GetRefreshTokenForUpdateQuery = `
+		SELECT id, family_id, user_id, token_hash, expires_at, used_at, revoked_at, auth_time, amr, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+
+	MarkRefreshTokenUsedQuery = `
+		UPDATE refresh_tokens
+		SET used_at = now()
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	RevokeRefreshTokenFamilyQuery = `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	// Отзыв семейства по предъявленному токену; чужой токен не затрагивается
+	RevokeRefreshTokenFamilyByTokenQuery = `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE revoked_at IS NULL
+		  AND family_id = (
+			SELECT family_id FROM refresh_tokens WHERE token_hash = $1 AND user_id = $2
+		  )
+	`
+
+	// Отзыв всех refresh токенов пользователя, например после сброса пароля
+	RevokeUserRefreshTokensQuery = `
+		UPDATE refresh_tokens
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	RevokeAccessTokenQuery = `
+		INSERT INTO revoked_access_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	// Записи об истекших токенах больше не нужны: такие токены отклоняются по exp
+	DeleteExpiredRevokedAccessTokensQuery = `
+		DELETE FROM revoked_access_tokens
+		WHERE expires_at < now()
+	`
+
+	IsAccessTokenRevokedQuery = `
+		SELECT EXISTS(
+			SELECT 1
+			FROM revoked_access_tokens
+			WHERE jti = $1
+		)
+	`
+
+	// Account token queries
+	CreateAccountTokenQuery = `
+		INSERT INTO account_tokens (id, user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	// Неиспользованные токены пользователя с тем же назначением перестают действовать
+	InvalidateAccountTokensQuery = `
+		UPDATE account_tokens
+		SET used_at = now()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+
+	// Использование токена: срабатывает один раз и только до истечения срока
+	ConsumeAccountTokenQuery = `
+		UPDATE account_tokens
+		SET used_at = now()
+		WHERE token_hash = $1
+		  AND purpose = $2
+		  AND used_at IS NULL
+		  AND expires_at > now()
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+	`
+
+	// MFA queries
+	GetUserMFAQuery = `
+		SELECT user_id, totp_secret, enabled_at, last_used_step, failed_attempts, locked_until, created_at, updated_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	GetUserMFAForUpdateQuery = `
+		SELECT user_id, totp_secret, enabled_at, last_used_step, failed_attempts, locked_until, created_at, updated_at
+		FROM user_mfa
+		WHERE user_id = $1
+		FOR UPDATE
+	`
+
+	// Начатое, но не подтвержденное подключение заменяется новым секретом; включенное не трогается
+	SavePendingUserMFAQuery = `
+		INSERT INTO user_mfa (user_id, totp_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret = EXCLUDED.totp_secret,
+		    last_used_step = 0,
+		    failed_attempts = 0,
+		    locked_until = NULL,
+		    updated_at = now()
+		WHERE user_mfa.enabled_at IS NULL
+	`
+
+	// Условие по прежнему значению не дает затереть секрет, замененный параллельным подключением
+	ReplaceUserMFASecretQuery = `
+		UPDATE user_mfa
+		SET totp_secret = $3,
+		    updated_at = now()
+		WHERE user_id = $1 AND totp_secret = $2
+	`
+
+	ListUnsealedUserMFASecretsQuery = `
+		SELECT user_id, totp_secret
+		FROM user_mfa
+		WHERE left(totp_secret, length($1)) <> $1
+	`
+
+	EnableUserMFAQuery = `
+		UPDATE user_mfa
+		SET enabled_at = now(), last_used_step = $2, failed_attempts = 0, locked_until = NULL, updated_at = now()
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`
+
+	RecordMFASuccessQuery = `
+		UPDATE user_mfa
+		SET last_used_step = GREATEST(last_used_step, $2), failed_attempts = 0, locked_until = NULL, updated_at = now()
+		WHERE user_id = $1
+	`
+
+	// Неверная попытка; на $2-й подряд проверка блокируется на $3 секунд и счетчик начинается заново
+	RecordMFAFailureQuery = `
+		UPDATE user_mfa
+		SET failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+		    locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN now() + make_interval(secs => $3) ELSE locked_until END,
+		    updated_at = now()
+		WHERE user_id = $1
+		RETURNING locked_until
+	`
+
+	DeleteUserMFAQuery = `
+		DELETE FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	DeleteMFARecoveryCodesQuery = `
+		DELETE FROM mfa_recovery_codes
+		WHERE user_id = $1
+	`
+
+	CreateMFARecoveryCodeQuery = `
+		INSERT INTO mfa_recovery_codes (id, user_id, code_hash)
+		VALUES ($1, $2, $3)
+	`
+
+	ConsumeMFARecoveryCodeQuery = `
+		UPDATE mfa_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	CountMFARecoveryCodesQuery = `
+		SELECT COUNT(*)
+		FROM mfa_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	// Заодно удаляются истекшие токены второго шага
+	CreateMFAChallengeQuery = `
+		WITH purged AS (
+			DELETE FROM mfa_challenges WHERE expires_at < now() - interval '1 day'
+		)
+		INSERT INTO mfa_challenges (id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	GetMFAChallengeForUpdateQuery = `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM mfa_challenges
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+
+	MarkMFAChallengeUsedQuery = `
+		UPDATE mfa_challenges
+		SET used_at = now()
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	// Login throttle queries
+	// Самый поздний действующий запрет входа по имени ($1) или адресу ($2)
+	GetLoginBlockedUntilQuery = `
+		SELECT max(blocked_until)
+		FROM login_throttles
+		WHERE ((scope = 'username' AND key = $1) OR (scope = 'ip' AND key = $2))
+		  AND blocked_until > now()
+	`
+
+	// Неверная попытка; счетчик начинается заново, если предыдущая была раньше чем $3 секунд назад
+	RecordLoginThrottleFailureQuery = `
+		INSERT INTO login_throttles (scope, key, failed_attempts, last_failed_at)
+		VALUES ($1, $2, 1, now())
+		ON CONFLICT (scope, key) DO UPDATE
+		SET failed_attempts = CASE
+		        WHEN login_throttles.last_failed_at < now() - make_interval(secs => $3) THEN 1
+		        ELSE login_throttles.failed_attempts + 1
+		    END,
+		    blocked_until = CASE
+		        WHEN login_throttles.last_failed_at < now() - make_interval(secs => $3) THEN NULL
+		        ELSE login_throttles.blocked_until
+		    END,
+		    last_failed_at = now()
+		RETURNING failed_attempts
+	`
+
+	BlockLoginThrottleQuery = `
+		UPDATE login_throttles
+		SET blocked_until = GREATEST(COALESCE(blocked_until, now()), now() + make_interval(secs => $3))
+		WHERE scope = $1 AND key = $2
+	`
+
+	ResetLoginThrottleQuery = `
+		DELETE FROM login_throttles
+		WHERE scope = $1 AND key = $2
+	`
+
+	// Счетчики без неверных попыток дольше $1 секунд больше ни на что не влияют
+	DeleteStaleLoginThrottlesQuery = `
+		DELETE FROM login_throttles
+		WHERE last_failed_at < now() - make_interval(secs => $1)
+		  AND (blocked_until IS NULL OR blocked_until < now())
+	`
+
+	CreateLoginFailureQuery = `
+		INSERT INTO login_failures (id, username, user_id, ip, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	// API key queries
+	// Ключ создается, только если у пользователя меньше $10 действующих ключей
+	CreateAPIKeyQuery = `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at, created_at)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9
+		WHERE (
+			SELECT COUNT(*) FROM api_keys
+			WHERE user_id = $2 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+		) < $10
+	`
+
+	ListAPIKeysQuery = `
+		SELECT id, user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at,
+		       last_used_at, last_used_ip, revoked_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	GetAPIKeyByHashQuery = `
+		SELECT id, user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at,
+		       last_used_at, last_used_ip, revoked_at, created_at
+		FROM api_keys
+		WHERE key_hash = $1
+	`
+
+	RevokeAPIKeyQuery = `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, now())
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, name, prefix, key_hash, scopes, allowed_ips, expires_at,
+		          last_used_at, last_used_ip, revoked_at, created_at
+	`
+
+	// Время использования обновляется не чаще раза в минуту, чтобы не писать в БД на каждый запрос
+	TouchAPIKeyQuery = `
+		UPDATE api_keys
+		SET last_used_at = now(), last_used_ip = $2
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute' OR last_used_ip IS DISTINCT FROM $2)
+	`
+
+	ListValidSigningKeysQuery = `
+		SELECT kid, algorithm, private_key, activates_at, deactivates_at, expires_at, created_at
+		FROM signing_keys
+		WHERE expires_at > $1
+		ORDER BY activates_at
+	`
+
+	CreateSigningKeyQuery = `
+		INSERT INTO signing_keys (kid, algorithm, private_key, activates_at, deactivates_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	UpdateSigningKeyPrivateKeyQuery = `
+		UPDATE signing_keys
+		SET private_key = $2
+		WHERE kid = $1
+	`
+
+	DeleteExpiredSigningKeysQuery = `
+		DELETE FROM signing_keys
+		WHERE expires_at <= $1
+	`
+
+	// Ротацию выполняет один экземпляр сервиса за раз
+	LockSigningKeyRotationQuery = `
+		SELECT pg_advisory_xact_lock(hashtext('signing_keys_rotation'))
+	`
+
+	// Admin audit queries
+	CreateAdminAuditEntryQuery = `
+		INSERT INTO admin_audit_log (actor_id, actor_role, action, target_user_id, target_wallet_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	// Audit event queries
+	// Записи дописываются по одной, чтобы каждая ссылалась на хэш предыдущей
+	LockAuditEventChainQuery = `
+		SELECT pg_advisory_xact_lock(hashtext('audit_events_chain'))
+	`
+
+	GetAuditEventChainHeadQuery = `
+		SELECT hash
+		FROM audit_events
+		ORDER BY seq DESC
+		LIMIT 1
+	`
+
+	CreateAuditEventQuery = `
+		INSERT INTO audit_events (id, event_type, user_id, actor_id, ip, user_agent, metadata, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING seq
+	`
+
+	ListAuditEventsQuery = `
+		SELECT id, seq, event_type, user_id, actor_id, ip, user_agent, metadata, created_at, prev_hash, hash
+		FROM audit_events
+		WHERE ($1::uuid IS NULL OR user_id = $1)
+		  AND ($2::text IS NULL OR event_type = $2)
+		  AND ($3::inet IS NULL OR ip = $3)
+		  AND ($4::timestamptz IS NULL OR created_at >= $4)
+		  AND ($5::timestamptz IS NULL OR created_at < $5)
+		  AND ($6::bigint IS NULL OR seq < $6)
+		ORDER BY seq DESC
+		LIMIT $7
+	`
+
+	// Порция журнала после позиции $1 в порядке цепочки
+	ScanAuditEventsQuery = `
+		SELECT id, seq, event_type, user_id, actor_id, ip, user_agent, metadata, created_at, prev_hash, hash
+		FROM audit_events
+		WHERE seq > $1
+		ORDER BY seq
+		LIMIT $2
+	`
+
+	// Rate limit queries
+	// Корзина $1 пополняется до емкости $2 со скоростью $3 токенов в секунду и отдает токен, если он есть.
+	// Новая корзина создается полной. allowed хранит итог последнего запроса, чтобы вернуть его из RETURNING.
+	TakeRateLimitTokenQuery = `
+		INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+		VALUES ($1, $2::float8 - 1, TRUE, now())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8)
+				- CASE WHEN LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8) >= 1
+					THEN 1 ELSE 0 END,
+			allowed = LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8) >= 1,
+			updated_at = now()
+		RETURNING tokens, allowed
+	`
+
+	DeleteIdleRateLimitBucketsQuery = `
+		DELETE FROM rate_limit_buckets
+		WHERE updated_at < now() - make_interval(secs => $1)
+	`
+
+	// Outbox queries
+	CreateOutboxMessageQuery = `
+		INSERT INTO outbox (event_type, message_key, payload)
+		VALUES ($1, $2, $3)
+	`
+
+	// Захват пачки готовых к отправке сообщений. SKIP LOCKED не дает двум экземплярам сервиса
+	// захватить одну строку, а сдвиг next_attempt_at на время аренды - повторно выбрать ее,
+	// пока идет отправка. Если экземпляр упал, строка снова станет доступна после аренды.
+	ClaimOutboxMessagesQuery = `
+		UPDATE outbox o
+		SET next_attempt_at = now() + make_interval(secs => $2),
+		    attempts = o.attempts + 1
+		FROM (
+			SELECT id
+			FROM outbox
+			WHERE sent_at IS NULL AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		) claimed
+		WHERE o.id = claimed.id
+		RETURNING o.id, o.event_type, o.message_key, o.payload, o.attempts, o.created_at
+	`
+
+	MarkOutboxMessageSentQuery = `
+		UPDATE outbox
+		SET sent_at = now(), last_error = NULL
+		WHERE id = $1
+	`
+
+	MarkOutboxMessageFailedQuery = `
+		UPDATE outbox
+		SET next_attempt_at = now() + make_interval(secs => $2), last_error = $3
+		WHERE id = $1 AND sent_at IS NULL
+	`
+
+	DeleteSentOutboxMessagesQuery = `
+		DELETE FROM outbox
+		WHERE sent_at < $1
+	`
+
+	// Idempotency key queries
+	// Резервирование ключа. Занятая запись перезаписывается, только если она просрочена
+	// или брошена: ответ не сохранен дольше $5 секунд (экземпляр упал посреди запроса).
+	ReserveIdempotencyKeyQuery = `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, fingerprint, expires_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint,
+		    status_code = NULL,
+		    content_type = NULL,
+		    response_body = NULL,
+		    created_at = now(),
+		    expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= now()
+		   OR (idempotency_keys.status_code IS NULL
+		       AND idempotency_keys.created_at <= now() - make_interval(secs => $5))
+	`
+
+	GetIdempotencyKeyQuery = `
+		SELECT user_id, idempotency_key, fingerprint, COALESCE(status_code, 0),
+		       COALESCE(content_type, ''), response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND expires_at > now()
+	`
+
+	SaveIdempotentResponseQuery = `
+		UPDATE idempotency_keys
+		SET status_code = $3, content_type = $4, response_body = $5
+		WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL
+	`
+
+	// Снять резерв можно только с незавершенного запроса
+	ReleaseIdempotencyKeyQuery = `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL
+	`
+
+	DeleteExpiredIdempotencyKeysQuery = `
+		DELETE FROM idempotency_keys
+		WHERE expires_at <= now()
+	`
+
+	// Transaction limit queries
+	// Лимиты по умолчанию и переопределения пользователя; переопределение идет после лимита по умолчанию
+	ListUserLimitsQuery = `
+		SELECT user_id, operation_type, currency, period, max_amount, updated_at
+		FROM transaction_limits
+		WHERE user_id IS NULL OR user_id = $1
+		ORDER BY operation_type, currency, period, user_id NULLS FIRST
+	`
+
+	ListUserOperationLimitsQuery = `
+		SELECT user_id, operation_type, currency, period, max_amount, updated_at
+		FROM transaction_limits
+		WHERE (user_id IS NULL OR user_id = $1) AND operation_type = $2 AND currency = $3
+		ORDER BY period, user_id NULLS FIRST
+	`
+
+	UpsertUserLimitQuery = `
+		INSERT INTO transaction_limits (user_id, operation_type, currency, period, max_amount)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, operation_type, currency, period) WHERE user_id IS NOT NULL
+		DO UPDATE SET max_amount = EXCLUDED.max_amount, updated_at = now()
+	`
+
+	DeleteUserLimitQuery = `
+		DELETE FROM transaction_limits
+		WHERE user_id = $1 AND operation_type = $2 AND currency = $3 AND period = $4
+	`
+
+	// Суммы операций пользователя за последние $N секунд в валюте списания.
+	// $3 - типы операций кошелька, которые учитываются в лимите
+	SumUserBalanceOperationsQuery = `
+		SELECT COALESCE(SUM(ABS(o.amount)), 0)::bigint
+		FROM operations o
+		JOIN wallets w ON w.id = o.wallet_id
+		WHERE w.user_id = $1 AND w.currency = $2 AND o.operation_type = ANY($3::text[])
+		  AND o.created_at > now() - make_interval(secs => $4)
+	`
+
+	SumUserExchangesQuery = `
+		SELECT COALESCE(SUM(amount), 0)::bigint
+		FROM exchange_operations
+		WHERE user_id = $1 AND from_currency = $2
+		  AND created_at > now() - make_interval(secs => $3)
+	`
+
+	SumUserTransfersOutQuery = `
+		SELECT COALESCE(SUM(amount), 0)::bigint
+		FROM transfers
+		WHERE sender_id = $1 AND from_currency = $2
+		  AND created_at > now() - make_interval(secs => $3)
+	`
+
+	// Reconciliation queries
+	// Прогон, который не обновлялся дольше $1 секунд, брошен упавшим экземпляром
+	FailStaleReconciliationReportsQuery = `
+		UPDATE reconciliation_reports
+		SET status = 'FAILED',
+		    error = 'abandoned',
+		    finished_at = now(),
+		    updated_at = now()
+		WHERE status = 'RUNNING'
+		  AND updated_at < now() - make_interval(secs => $1)
+	`
+
+	CreateReconciliationReportQuery = `
+		INSERT INTO reconciliation_reports (id, trigger, requested_by)
+		VALUES ($1, $2, $3)
+		RETURNING id, trigger, status, requested_by, wallets_checked, discrepancy_count,
+		       COALESCE(error, ''), started_at, finished_at
+	`
+
+	UpdateReconciliationProgressQuery = `
+		UPDATE reconciliation_reports
+		SET wallets_checked = $2,
+		    discrepancy_count = $3,
+		    updated_at = now()
+		WHERE id = $1
+	`
+
+	FinishReconciliationReportQuery = `
+		UPDATE reconciliation_reports
+		SET status = $2,
+		    wallets_checked = $3,
+		    discrepancy_count = $4,
+		    error = NULLIF($5, ''),
+		    finished_at = now(),
+		    updated_at = now()
+		WHERE id = $1 AND status = 'RUNNING'
+		RETURNING id, trigger, status, requested_by, wallets_checked, discrepancy_count,
+		       COALESCE(error, ''), started_at, finished_at
+	`
+
+	GetReconciliationReportQuery = `
+		SELECT id, trigger, status, requested_by, wallets_checked, discrepancy_count,
+		       COALESCE(error, ''), started_at, finished_at
+		FROM reconciliation_reports
+		WHERE id = $1
+	`
+
+	ListReconciliationReportsQuery = `
+		SELECT id, trigger, status, requested_by, wallets_checked, discrepancy_count,
+		       COALESCE(error, ''), started_at, finished_at
+		FROM reconciliation_reports
+		ORDER BY started_at DESC
+		LIMIT $1
+	`
+
+	// Порция кошельков после $1 с суммами, пересчитанными из истории. Одно выражение читает
+	// согласованный снимок, поэтому кошелек и его записи не расходятся из-за параллельных операций.
+	ScanWalletReconciliationQuery = `
+		SELECT w.id, w.user_id, w.currency, w.balance, w.held_balance,
+		       COALESCE(l.total, 0)::bigint,
+		       (COALESCE(h.total, 0) + COALESCE(o.total, 0))::bigint
+		FROM (
+			SELECT id, user_id, currency, balance, held_balance
+			FROM wallets
+			WHERE id > $1
+			ORDER BY id
+			LIMIT $2
+		) w
+		LEFT JOIN LATERAL (
+			SELECT SUM(amount) AS total FROM postings WHERE account_id = w.id
+		) l ON true
+		LEFT JOIN LATERAL (
+			SELECT SUM(amount) AS total FROM wallet_holds WHERE wallet_id = w.id AND status = 'ACTIVE'
+		) h ON true
+		LEFT JOIN LATERAL (
+			SELECT SUM(amount) AS total FROM limit_orders WHERE wallet_id = w.id AND status = 'OPEN'
+		) o ON true
+		ORDER BY w.id
+	`
+
+	ListReconciliationDiscrepanciesQuery = `
+		SELECT wallet_id, user_id, currency, balance, held_balance, ledger_balance, reserved_balance
+		FROM reconciliation_discrepancies
+		WHERE report_id = $1
+		ORDER BY wallet_id
 	`
 )
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_exchange_operations_user_created;
+DROP INDEX IF EXISTS idx_operations_wallet_created;
+
+ALTER TABLE exchange_operations
+    DROP COLUMN IF EXISTS to_balance_after,
+    DROP COLUMN IF EXISTS from_balance_after;
+
+ALTER TABLE operations
+    DROP COLUMN IF EXISTS balance_after,
+    DROP COLUMN IF EXISTS operation_type;
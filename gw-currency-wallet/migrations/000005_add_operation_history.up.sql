@@ -0,0 +1,20 @@
+-- Тип операции, знаковая сумма и баланс после операции для истории
+ALTER TABLE operations
+    ADD COLUMN operation_type VARCHAR(16) NULL
+        CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW')),
+    ADD COLUMN balance_after BIGINT NULL;
+
+-- Балансы обоих кошельков после обмена
+ALTER TABLE exchange_operations
+    ADD COLUMN from_balance_after BIGINT NULL,
+    ADD COLUMN to_balance_after BIGINT NULL;
+
+-- Индексы для постраничной выборки истории (created_at DESC, id DESC)
+CREATE INDEX IF NOT EXISTS idx_operations_wallet_created
+    ON operations(wallet_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_exchange_operations_user_created
+    ON exchange_operations(user_id, created_at DESC, id DESC);
+
+COMMENT ON COLUMN operations.operation_type IS 'DEPOSIT or WITHDRAW, NULL for rows created before history tracking';
+COMMENT ON COLUMN operations.amount IS 'Signed amount in minor units: positive for deposits, negative for withdrawals';
+COMMENT ON COLUMN operations.balance_after IS 'Wallet balance in minor units after the operation';
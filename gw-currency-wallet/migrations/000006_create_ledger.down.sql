@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS trigger_postings_balanced ON postings;
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+
+DROP INDEX IF EXISTS idx_postings_journal_entry_id;
+DROP INDEX IF EXISTS idx_postings_account_id;
+
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS journal_entries;
+DROP TABLE IF EXISTS ledger_accounts;
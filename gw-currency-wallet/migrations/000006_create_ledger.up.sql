@@ -0,0 +1,82 @@
+-- Счета главной книги: кошельки пользователей и системные счета
+-- Счет кошелька имеет тот же id, что и сам кошелек
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    wallet_id UUID NULL UNIQUE REFERENCES wallets(id) ON DELETE CASCADE,
+    system_code VARCHAR(32) NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_ledger_account_owner CHECK ((wallet_id IS NULL) <> (system_code IS NULL)),
+    CONSTRAINT uq_ledger_accounts_system UNIQUE (system_code, currency)
+);
+
+-- Проводки: одна бизнес-операция = одна проводка
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entry_type VARCHAR(32) NOT NULL,
+    request_id TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT uq_journal_entries_request UNIQUE (entry_type, request_id)
+);
+
+-- Записи по счетам: amount > 0 увеличивает остаток счета, amount < 0 уменьшает
+CREATE TABLE IF NOT EXISTS postings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    journal_entry_id UUID NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_postings_account_id ON postings(account_id);
+CREATE INDEX IF NOT EXISTS idx_postings_journal_entry_id ON postings(journal_entry_id);
+
+-- Проводка должна быть сбалансирована в каждой валюте; проверяется при коммите
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM postings
+        WHERE journal_entry_id = NEW.journal_entry_id
+        GROUP BY currency
+        HAVING SUM(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.journal_entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER trigger_postings_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+    EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Счета для существующих кошельков
+INSERT INTO ledger_accounts (id, wallet_id, currency)
+SELECT id, id, currency FROM wallets
+ON CONFLICT DO NOTHING;
+
+-- Входящие остатки: существующие балансы переносятся в книгу против счета OPENING_BALANCE
+INSERT INTO ledger_accounts (system_code, currency)
+SELECT DISTINCT 'OPENING_BALANCE', currency FROM wallets WHERE balance <> 0
+ON CONFLICT DO NOTHING;
+
+INSERT INTO journal_entries (entry_type, request_id)
+SELECT 'OPENING_BALANCE', 'opening:' || id FROM wallets WHERE balance <> 0;
+
+INSERT INTO postings (journal_entry_id, account_id, currency, amount)
+SELECT j.id, w.id, w.currency, w.balance
+FROM wallets w
+JOIN journal_entries j ON j.entry_type = 'OPENING_BALANCE' AND j.request_id = 'opening:' || w.id
+UNION ALL
+SELECT j.id, a.id, w.currency, -w.balance
+FROM wallets w
+JOIN journal_entries j ON j.entry_type = 'OPENING_BALANCE' AND j.request_id = 'opening:' || w.id
+JOIN ledger_accounts a ON a.system_code = 'OPENING_BALANCE' AND a.currency = w.currency;
+
+COMMENT ON TABLE postings IS 'Double-entry postings; wallets.balance is a cached projection of SUM(amount) per wallet account';
+COMMENT ON COLUMN wallets.balance IS 'Cached projection of ledger postings for this wallet account, in minor units';
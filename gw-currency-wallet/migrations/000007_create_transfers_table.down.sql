@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transfers_recipient_created;
+DROP INDEX IF EXISTS idx_transfers_sender_created;
+
+DROP TABLE IF EXISTS transfers;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS transfers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    received_amount BIGINT NOT NULL CHECK (received_amount > 0),
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    sender_balance_after BIGINT NOT NULL,
+    recipient_balance_after BIGINT NOT NULL,
+    request_id TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_transfer_parties CHECK (sender_id <> recipient_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_sender_created
+    ON transfers(sender_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_transfers_recipient_created
+    ON transfers(recipient_id, created_at DESC, id DESC);
+
+COMMENT ON TABLE transfers IS 'Peer-to-peer transfers between users, optionally cross-currency';
+COMMENT ON COLUMN transfers.rate IS 'Exchange rate applied, 1 for same-currency transfers';
@@ -0,0 +1,14 @@
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS check_currency_code;
+
+-- NOT VALID: кошельки в валютах, добавленных через реестр, остаются как есть
+ALTER TABLE wallets
+    ADD CONSTRAINT check_currency
+        CHECK (currency IN ('USD', 'RUB', 'EUR')) NOT VALID;
+ALTER TABLE exchange_operations
+    ADD CONSTRAINT exchange_operations_from_currency_check
+        CHECK (from_currency IN ('USD', 'RUB', 'EUR')) NOT VALID;
+ALTER TABLE exchange_operations
+    ADD CONSTRAINT exchange_operations_to_currency_check
+        CHECK (to_currency IN ('USD', 'RUB', 'EUR')) NOT VALID;
+
+COMMENT ON COLUMN wallets.currency IS 'Currency code: USD, RUB, or EUR';
@@ -0,0 +1,9 @@
+-- Список валют теперь берется из реестра exchanger сервиса, в схеме проверяется только формат кода
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS check_currency;
+ALTER TABLE exchange_operations DROP CONSTRAINT IF EXISTS exchange_operations_from_currency_check;
+ALTER TABLE exchange_operations DROP CONSTRAINT IF EXISTS exchange_operations_to_currency_check;
+
+ALTER TABLE wallets
+    ADD CONSTRAINT check_currency_code CHECK (currency ~ '^[A-Z]{3}$');
+
+COMMENT ON COLUMN wallets.currency IS 'ISO 4217 currency code from the currency registry';
@@ -0,0 +1,16 @@
+-- Ключи подписи JWT. Ключ подписывает новые токены в [activates_at, deactivates_at)
+-- и принимается при проверке до expires_at (deactivates_at + окно перекрытия)
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL CHECK (algorithm IN ('RS256', 'EdDSA')),
+    private_key TEXT NOT NULL,
+    activates_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    deactivates_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_signing_key_period CHECK (activates_at < deactivates_at AND deactivates_at <= expires_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_signing_keys_expires_at ON signing_keys(expires_at);
+
+COMMENT ON COLUMN signing_keys.private_key IS 'PKCS#8 PEM; public part is served at /.well-known/jwks.json';
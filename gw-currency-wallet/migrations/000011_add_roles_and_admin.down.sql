@@ -0,0 +1,19 @@
+-- Ручные корректировки - финансовые записи, поэтому откат не удаляет их, а останавливается
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM operations WHERE operation_type = 'ADJUSTMENT') THEN
+        RAISE EXCEPTION 'operations contain ADJUSTMENT records; rollback would lose financial history';
+    END IF;
+END $$;
+
+DROP TABLE IF EXISTS admin_audit_log;
+
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS check_operation_type;
+ALTER TABLE operations
+    ADD CONSTRAINT operations_operation_type_check CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW'));
+
+ALTER TABLE wallets
+    DROP COLUMN IF EXISTS frozen_at,
+    DROP COLUMN IF EXISTS frozen_reason;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,33 @@
+-- Роли пользователей
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user'
+        CONSTRAINT check_user_role CHECK (role IN ('user', 'support', 'admin'));
+
+-- Заморозка кошелька: операции по замороженному кошельку запрещены, кроме ручных корректировок
+ALTER TABLE wallets
+    ADD COLUMN frozen_at TIMESTAMP WITH TIME ZONE NULL,
+    ADD COLUMN frozen_reason TEXT NULL;
+
+-- Ручные корректировки баланса попадают в историю операций
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_operation_type_check;
+ALTER TABLE operations
+    ADD CONSTRAINT check_operation_type CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'ADJUSTMENT'));
+
+-- Журнал действий сотрудников поддержки и администраторов
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID NOT NULL REFERENCES users(id),
+    actor_role VARCHAR(16) NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target_user_id UUID NULL REFERENCES users(id),
+    target_wallet_id UUID NULL REFERENCES wallets(id),
+    reason TEXT NULL,
+    details JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_actor ON admin_audit_log(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_target_user ON admin_audit_log(target_user_id, created_at DESC);
+
+COMMENT ON COLUMN users.role IS 'user, support (read-only admin API) or admin';
+COMMENT ON COLUMN wallets.frozen_at IS 'Set while the wallet is frozen; user operations on a frozen wallet are rejected';
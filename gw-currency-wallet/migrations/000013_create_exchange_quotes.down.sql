@@ -0,0 +1,4 @@
+ALTER TABLE exchange_operations DROP COLUMN IF EXISTS quote_id;
+
+DROP INDEX IF EXISTS idx_exchange_quotes_expires_at;
+DROP TABLE IF EXISTS exchange_quotes;
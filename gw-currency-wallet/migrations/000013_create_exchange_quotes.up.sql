@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS exchange_quotes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    exchanged_amount BIGINT NOT NULL CHECK (exchanged_amount > 0),
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    CONSTRAINT check_quote_currencies CHECK (from_currency <> to_currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_quotes_expires_at ON exchange_quotes(expires_at);
+
+ALTER TABLE exchange_operations
+    ADD COLUMN IF NOT EXISTS quote_id UUID NULL UNIQUE REFERENCES exchange_quotes(id) ON DELETE SET NULL;
+
+COMMENT ON TABLE exchange_quotes IS 'Single-use exchange quotes: the rate and amounts are locked until expires_at';
+COMMENT ON COLUMN exchange_operations.quote_id IS 'Quote the exchange was executed at, NULL for exchanges at the current rate';
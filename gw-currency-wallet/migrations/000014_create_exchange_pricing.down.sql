@@ -0,0 +1,5 @@
+ALTER TABLE exchange_quotes DROP COLUMN IF EXISTS fee;
+ALTER TABLE exchange_operations DROP COLUMN IF EXISTS fee;
+
+DROP TABLE IF EXISTS exchange_fee_tiers;
+DROP TABLE IF EXISTS exchange_pricing;
@@ -0,0 +1,36 @@
+-- Спред и комиссии обмена по парам валют. '*' вместо кода означает любую валюту;
+-- выбирается наиболее точное совпадение: пара, (from, *), (*, to), (*, *).
+CREATE TABLE IF NOT EXISTS exchange_pricing (
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    spread_bps INTEGER NOT NULL DEFAULT 0 CHECK (spread_bps >= 0 AND spread_bps < 10000),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT now(),
+    PRIMARY KEY (from_currency, to_currency)
+);
+
+-- Ступени комиссии: к сумме применяется ступень с наибольшим min_amount, не превышающим ее.
+-- Суммы в минимальных единицах валюты списания, поэтому для from_currency = '*' допустима
+-- только процентная комиссия без ступеней.
+CREATE TABLE IF NOT EXISTS exchange_fee_tiers (
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    min_amount BIGINT NOT NULL DEFAULT 0 CHECK (min_amount >= 0),
+    percent_fee NUMERIC(7, 6) NOT NULL DEFAULT 0 CHECK (percent_fee >= 0 AND percent_fee < 1),
+    fixed_fee BIGINT NOT NULL DEFAULT 0 CHECK (fixed_fee >= 0),
+    PRIMARY KEY (from_currency, to_currency, min_amount),
+    FOREIGN KEY (from_currency, to_currency)
+        REFERENCES exchange_pricing(from_currency, to_currency) ON DELETE CASCADE,
+    CONSTRAINT check_fee_tier_wildcard CHECK (from_currency <> '*' OR (min_amount = 0 AND fixed_fee = 0))
+);
+
+INSERT INTO exchange_pricing (from_currency, to_currency, spread_bps)
+VALUES ('*', '*', 0)
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE exchange_operations
+    ADD COLUMN IF NOT EXISTS fee BIGINT NOT NULL DEFAULT 0 CHECK (fee >= 0);
+ALTER TABLE exchange_quotes
+    ADD COLUMN IF NOT EXISTS fee BIGINT NOT NULL DEFAULT 0 CHECK (fee >= 0);
+
+COMMENT ON COLUMN exchange_pricing.spread_bps IS 'Client rate is mid * (1 - spread_bps / 10000)';
+COMMENT ON COLUMN exchange_operations.fee IS 'Fee in minor units of from_currency, credited to the FEE_INCOME account';
@@ -0,0 +1,18 @@
+-- Ключи Idempotency-Key: отпечаток запроса и сохраненный ответ для повтора.
+-- Пока запрос выполняется, status_code = NULL; брошенная (упал экземпляр) или просроченная
+-- запись перезаписывается следующим запросом с тем же ключом.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INTEGER NULL,
+    content_type TEXT NULL,
+    response_body BYTEA NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMENT ON COLUMN idempotency_keys.fingerprint IS 'SHA-256 of method, path and body; reusing the key with another fingerprint is rejected';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_exchange_operations_user_from_created;
+DROP INDEX IF EXISTS idx_operations_wallet_type_created;
+DROP INDEX IF EXISTS idx_transaction_limits_user;
+DROP INDEX IF EXISTS idx_transaction_limits_default;
+DROP TABLE IF EXISTS transaction_limits;
@@ -0,0 +1,27 @@
+-- Лимиты суммы операций пользователя за скользящий период (DAILY - 24 часа, MONTHLY - 30 дней).
+-- Строка с user_id = NULL - лимит по умолчанию, с user_id - переопределение для пользователя.
+-- max_amount = NULL в переопределении снимает лимит по умолчанию для пользователя.
+CREATE TABLE IF NOT EXISTS transaction_limits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NULL REFERENCES users(id) ON DELETE CASCADE,
+    operation_type VARCHAR(16) NOT NULL
+        CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'EXCHANGE', 'TRANSFER_OUT')),
+    currency VARCHAR(3) NOT NULL,
+    period VARCHAR(8) NOT NULL CHECK (period IN ('DAILY', 'MONTHLY')),
+    max_amount BIGINT NULL CHECK (max_amount >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT check_default_limit_amount CHECK (user_id IS NOT NULL OR max_amount IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_limits_default
+    ON transaction_limits(operation_type, currency, period) WHERE user_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transaction_limits_user
+    ON transaction_limits(user_id, operation_type, currency, period) WHERE user_id IS NOT NULL;
+
+-- Суммы за период считаются по истории операций пользователя
+CREATE INDEX IF NOT EXISTS idx_operations_wallet_type_created
+    ON operations(wallet_id, operation_type, created_at);
+CREATE INDEX IF NOT EXISTS idx_exchange_operations_user_from_created
+    ON exchange_operations(user_id, from_currency, created_at);
+
+COMMENT ON COLUMN transaction_limits.max_amount IS 'Minor units of currency; NULL only in a per-user override and means unlimited';
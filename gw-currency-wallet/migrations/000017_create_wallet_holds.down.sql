@@ -0,0 +1,19 @@
+-- Списания по холдам - финансовые записи, поэтому откат не удаляет их, а останавливается
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM operations WHERE operation_type = 'CAPTURE') THEN
+        RAISE EXCEPTION 'operations contain CAPTURE records; rollback would lose financial history';
+    END IF;
+END $$;
+
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS check_operation_type;
+ALTER TABLE operations
+    ADD CONSTRAINT check_operation_type CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'ADJUSTMENT'));
+
+DROP INDEX IF EXISTS idx_wallet_holds_wallet;
+DROP INDEX IF EXISTS idx_wallet_holds_active_expires;
+DROP TABLE IF EXISTS wallet_holds;
+
+ALTER TABLE wallets
+    DROP CONSTRAINT IF EXISTS check_wallets_held_balance,
+    DROP COLUMN IF EXISTS held_balance;
@@ -0,0 +1,34 @@
+-- Холды: средства кошелька, зарезервированные до списания (capture) или освобождения (release).
+-- wallets.held_balance - кэш суммы активных холдов кошелька, обновляется в той же транзакции.
+ALTER TABLE wallets
+    ADD COLUMN held_balance BIGINT NOT NULL DEFAULT 0,
+    ADD CONSTRAINT check_wallets_held_balance CHECK (held_balance >= 0 AND held_balance <= balance);
+
+CREATE TABLE IF NOT EXISTS wallet_holds (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    captured_amount BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'ACTIVE'
+        CHECK (status IN ('ACTIVE', 'CAPTURED', 'RELEASED', 'EXPIRED')),
+    description TEXT NULL,
+    request_id TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT check_hold_captured_amount CHECK (captured_amount >= 0 AND captured_amount <= amount)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_holds_active_expires
+    ON wallet_holds(expires_at) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_wallet_holds_wallet ON wallet_holds(wallet_id);
+
+-- Списание по холду попадает в историю операций
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS check_operation_type;
+ALTER TABLE operations
+    ADD CONSTRAINT check_operation_type CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'ADJUSTMENT', 'CAPTURE'));
+
+COMMENT ON COLUMN wallets.held_balance IS 'Sum of active holds in minor units; available balance is balance - held_balance';
+COMMENT ON COLUMN wallet_holds.captured_amount IS 'Amount debited on capture, the rest of the hold is released';
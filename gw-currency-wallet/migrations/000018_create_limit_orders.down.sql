@@ -0,0 +1,16 @@
+-- Резерв открытых ордеров возвращается в доступный баланс
+UPDATE wallets w
+SET held_balance = w.held_balance - o.amount
+FROM (
+    SELECT wallet_id, SUM(amount) AS amount
+    FROM limit_orders
+    WHERE status = 'OPEN'
+    GROUP BY wallet_id
+) o
+WHERE w.id = o.wallet_id;
+
+DROP INDEX IF EXISTS idx_limit_orders_user_created;
+DROP INDEX IF EXISTS idx_limit_orders_open;
+DROP TABLE IF EXISTS limit_orders;
+
+COMMENT ON COLUMN wallets.held_balance IS 'Sum of active holds in minor units; available balance is balance - held_balance';
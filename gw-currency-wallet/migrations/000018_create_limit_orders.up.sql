@@ -0,0 +1,29 @@
+-- Лимитные ордера: обмен выполняется фоновой задачей, когда курс для клиента достигает rate.
+-- Сумма открытого ордера зарезервирована в wallets.held_balance кошелька-источника.
+CREATE TABLE IF NOT EXISTS limit_orders (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    rate NUMERIC(20, 10) NOT NULL CHECK (rate > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'OPEN'
+        CHECK (status IN ('OPEN', 'FILLED', 'CANCELLED', 'EXPIRED')),
+    exchanged_amount BIGINT NOT NULL DEFAULT 0,
+    executed_rate NUMERIC(20, 10) NULL,
+    fee BIGINT NOT NULL DEFAULT 0,
+    request_id TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NULL,
+    filled_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT check_limit_order_currencies CHECK (from_currency <> to_currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_limit_orders_open ON limit_orders(id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_limit_orders_user_created ON limit_orders(user_id, created_at DESC);
+
+COMMENT ON COLUMN limit_orders.rate IS 'Minimum client rate (to_currency per unit of from_currency, after spread)';
+COMMENT ON COLUMN limit_orders.expires_at IS 'NULL means good-till-cancel';
+COMMENT ON COLUMN wallets.held_balance IS 'Sum of active holds and open limit orders in minor units; available balance is balance - held_balance';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS scheduled_exchange_runs;
+DROP INDEX IF EXISTS idx_scheduled_exchanges_user_created;
+DROP INDEX IF EXISTS idx_scheduled_exchanges_due;
+DROP TABLE IF EXISTS scheduled_exchanges;
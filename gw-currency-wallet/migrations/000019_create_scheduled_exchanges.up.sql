@@ -0,0 +1,42 @@
+-- Запланированные обмены: разовые (run_at) и повторяющиеся по cron-выражению.
+-- Планировщик выполняет обмен в next_run_at и записывает результат каждого запуска.
+CREATE TABLE IF NOT EXISTS scheduled_exchanges (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    cron TEXT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'ACTIVE'
+        CHECK (status IN ('ACTIVE', 'COMPLETED', 'CANCELLED')),
+    next_run_at TIMESTAMP WITH TIME ZONE NULL,
+    request_id TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT check_scheduled_exchange_currencies CHECK (from_currency <> to_currency),
+    CONSTRAINT check_scheduled_exchange_next_run CHECK ((status = 'ACTIVE') = (next_run_at IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_exchanges_due
+    ON scheduled_exchanges(next_run_at) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_scheduled_exchanges_user_created
+    ON scheduled_exchanges(user_id, created_at DESC);
+
+-- Один запуск на каждое запланированное время; request_id совпадает с requestID обмена
+CREATE TABLE IF NOT EXISTS scheduled_exchange_runs (
+    id UUID PRIMARY KEY,
+    schedule_id UUID NOT NULL REFERENCES scheduled_exchanges(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMP WITH TIME ZONE NOT NULL,
+    request_id TEXT NOT NULL UNIQUE,
+    status VARCHAR(16) NOT NULL CHECK (status IN ('SUCCEEDED', 'FAILED')),
+    exchanged_amount BIGINT NOT NULL DEFAULT 0,
+    rate NUMERIC(20, 10) NULL,
+    fee BIGINT NOT NULL DEFAULT 0,
+    error_code TEXT NULL,
+    error TEXT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT uq_scheduled_exchange_runs_time UNIQUE (schedule_id, scheduled_for)
+);
+
+COMMENT ON COLUMN scheduled_exchanges.cron IS 'Five-field cron expression in UTC; NULL for a one-off exchange';
+COMMENT ON COLUMN scheduled_exchanges.next_run_at IS 'NULL once the schedule is completed or cancelled';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_limit_orders_wallet_open;
+DROP TABLE IF EXISTS reconciliation_discrepancies;
+DROP INDEX IF EXISTS idx_reconciliation_reports_running;
+DROP INDEX IF EXISTS idx_reconciliation_reports_started;
+DROP TABLE IF EXISTS reconciliation_reports;
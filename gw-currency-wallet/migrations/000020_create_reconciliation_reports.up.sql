@@ -0,0 +1,38 @@
+-- Сверка балансов: wallets.balance сравнивается с суммой записей главной книги,
+-- wallets.held_balance - с суммой активных холдов и открытых лимитных ордеров
+CREATE TABLE IF NOT EXISTS reconciliation_reports (
+    id UUID PRIMARY KEY,
+    trigger VARCHAR(16) NOT NULL CHECK (trigger IN ('SCHEDULED', 'MANUAL')),
+    status VARCHAR(16) NOT NULL DEFAULT 'RUNNING'
+        CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED')),
+    requested_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    wallets_checked BIGINT NOT NULL DEFAULT 0,
+    discrepancy_count BIGINT NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    finished_at TIMESTAMP WITH TIME ZONE NULL,
+    -- updated_at обновляется после каждой порции кошельков; по нему находятся брошенные прогоны
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_reports_started ON reconciliation_reports(started_at DESC);
+-- Одновременно выполняется не больше одного прогона на все экземпляры сервиса
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reconciliation_reports_running
+    ON reconciliation_reports((true)) WHERE status = 'RUNNING';
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    report_id UUID NOT NULL REFERENCES reconciliation_reports(id) ON DELETE CASCADE,
+    wallet_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    balance BIGINT NOT NULL,
+    ledger_balance BIGINT NOT NULL,
+    held_balance BIGINT NOT NULL,
+    reserved_balance BIGINT NOT NULL,
+    PRIMARY KEY (report_id, wallet_id)
+);
+
+-- Резерв открытых ордеров кошелька пересчитывается при сверке
+CREATE INDEX IF NOT EXISTS idx_limit_orders_wallet_open ON limit_orders(wallet_id) WHERE status = 'OPEN';
+
+COMMENT ON COLUMN reconciliation_discrepancies.reserved_balance IS 'Sum of active holds and open limit orders at reconciliation time';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_account_tokens_user_unused;
+DROP TABLE IF EXISTS account_tokens;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
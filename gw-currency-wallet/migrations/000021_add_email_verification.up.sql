@@ -0,0 +1,22 @@
+-- Подтверждение email: до подтверждения выводы средств запрещены
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE NULL;
+
+-- Пользователи, зарегистрированные до появления подтверждения, считаются подтвержденными,
+-- чтобы не заблокировать им выводы
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+-- Одноразовые токены подтверждения email и сброса пароля; хранится только SHA-256 хэш
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL CHECK (purpose IN ('EMAIL_VERIFICATION', 'PASSWORD_RESET')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_unused
+    ON account_tokens(user_id, purpose) WHERE used_at IS NULL;
+
+COMMENT ON COLUMN account_tokens.used_at IS 'Set when the token is redeemed or superseded by a newer token of the same purpose';
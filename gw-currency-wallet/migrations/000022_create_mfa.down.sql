@@ -0,0 +1,7 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS amr;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS auth_time;
+
+DROP INDEX IF EXISTS idx_mfa_challenges_expires_at;
+DROP TABLE IF EXISTS mfa_challenges;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,43 @@
+-- Двухфакторная аутентификация по TOTP (RFC 6238). Строка создается при начале подключения,
+-- enabled_at заполняется после подтверждения первым кодом
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    totp_secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+-- Одноразовые коды восстановления; хранится только SHA-256 хэш
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    CONSTRAINT uq_mfa_recovery_codes_user_hash UNIQUE (user_id, code_hash)
+);
+
+-- Токены второго шага входа: выдаются после проверки пароля, обмениваются на пару токенов вместе с кодом
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires_at ON mfa_challenges(expires_at);
+
+-- Способ и момент входа переносятся из семейства refresh токенов в каждый новый access токен
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP WITH TIME ZONE NULL;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{pwd}';
+UPDATE refresh_tokens SET auth_time = COALESCE(created_at, now()) WHERE auth_time IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN auth_time SET NOT NULL;
+
+COMMENT ON COLUMN user_mfa.last_used_step IS 'Last accepted TOTP time step; codes of this or earlier steps are rejected to prevent replay';
+COMMENT ON COLUMN refresh_tokens.auth_time IS 'When the user authenticated for this token family (OIDC auth_time)';
@@ -0,0 +1,21 @@
+-- Персональные API ключи для скриптов и интеграций; хранится только SHA-256 хэш ключа
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL CHECK (cardinality(scopes) > 0),
+    allowed_ips CIDR[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE NULL,
+    last_used_ip INET NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id, created_at DESC);
+
+COMMENT ON COLUMN api_keys.prefix IS 'First characters of the key, shown in listings to tell keys apart';
+COMMENT ON COLUMN api_keys.allowed_ips IS 'Networks the key may be used from; empty means any address';
+COMMENT ON COLUMN api_keys.last_used_at IS 'Updated at most once a minute';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS login_failures;
+DROP TABLE IF EXISTS login_throttles;
+ALTER TABLE users DROP COLUMN IF EXISTS locked_until;
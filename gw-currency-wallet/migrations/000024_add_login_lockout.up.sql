@@ -0,0 +1,32 @@
+-- Временная блокировка учетной записи после серии неверных паролей; снимается по времени или администратором
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE NULL;
+
+-- Счетчики неверных входов по имени пользователя и по адресу клиента. Хранятся в базе,
+-- чтобы ограничение действовало на всех репликах сервиса
+CREATE TABLE IF NOT EXISTS login_throttles (
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('username', 'ip')),
+    key VARCHAR(64) NOT NULL,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    blocked_until TIMESTAMP WITH TIME ZONE NULL,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_throttles_last_failed_at ON login_throttles(last_failed_at);
+
+-- Журнал неверных попыток входа, в том числе для несуществующих имен
+CREATE TABLE IF NOT EXISTS login_failures (
+    id UUID PRIMARY KEY,
+    username VARCHAR(50) NOT NULL,
+    user_id UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    ip INET NULL,
+    reason VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_user_id ON login_failures(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_failures_ip ON login_failures(ip, created_at DESC);
+
+COMMENT ON COLUMN login_throttles.key IS 'Lower-cased username or client IP address';
+COMMENT ON COLUMN login_throttles.blocked_until IS 'Login attempts for this key are rejected until this moment';
+COMMENT ON COLUMN login_failures.reason IS 'invalid_password, unknown_user, throttled or locked';
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS trigger_audit_events_no_truncate ON audit_events;
+DROP TRIGGER IF EXISTS trigger_audit_events_append_only ON audit_events;
+DROP FUNCTION IF EXISTS reject_audit_events_change();
+
+DROP INDEX IF EXISTS idx_audit_events_created_at;
+DROP INDEX IF EXISTS idx_audit_events_user_id;
+
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,42 @@
+-- Журнал событий безопасности: входы, регистрации, смена пароля, выпуск токенов и ключей.
+-- Записи только дописываются; каждая содержит хэш предыдущей, поэтому изменение или удаление
+-- записи в середине журнала обнаруживается проверкой цепочки. Внешних ключей нет, чтобы
+-- журнал не менялся вместе с учетными записями.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY,
+    seq BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE,
+    event_type VARCHAR(64) NOT NULL,
+    user_id UUID NULL,
+    actor_id UUID NULL,
+    ip INET NULL,
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    hash VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id, seq DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+
+CREATE OR REPLACE FUNCTION reject_audit_events_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_audit_events_change();
+
+CREATE TRIGGER trigger_audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION reject_audit_events_change();
+
+COMMENT ON COLUMN audit_events.user_id IS 'Account the event belongs to; NULL for failed logins with an unknown username';
+COMMENT ON COLUMN audit_events.actor_id IS 'Who performed the action: the user themselves or an administrator';
+COMMENT ON COLUMN audit_events.prev_hash IS 'Hash of the previous event in seq order; empty for the first event';
+COMMENT ON COLUMN audit_events.hash IS 'SHA-256 over prev_hash and the event fields';
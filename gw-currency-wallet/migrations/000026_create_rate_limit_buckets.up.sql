@@ -0,0 +1,14 @@
+-- Корзины токенов ограничения частоты запросов, общие для всех экземпляров сервиса.
+-- Таблица не журналируется: после сбоя БД корзины просто начинаются заново полными.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(128) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets(updated_at);
+
+COMMENT ON COLUMN rate_limit_buckets.key IS 'Policy name and client: <policy>:user:<id> or <policy>:ip:<address>';
+COMMENT ON COLUMN rate_limit_buckets.tokens IS 'Tokens left after the last request, as of updated_at';
+COMMENT ON COLUMN rate_limit_buckets.allowed IS 'Whether the last request got a token';
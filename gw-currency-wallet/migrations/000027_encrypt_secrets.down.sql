@@ -0,0 +1,4 @@
+-- Тип колонки не сужается: зашифрованные секреты не помещаются в VARCHAR(64),
+-- а расшифровать их без ключа миграция не может
+COMMENT ON COLUMN user_mfa.totp_secret IS NULL;
+COMMENT ON COLUMN signing_keys.private_key IS 'PKCS#8 PEM; public part is served at /.well-known/jwks.json';
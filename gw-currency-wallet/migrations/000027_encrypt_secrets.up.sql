@@ -0,0 +1,7 @@
+-- Секреты TOTP и закрытые ключи подписи хранятся зашифрованными ключом SECRETS_ENCRYPTION_KEY.
+-- Зашифрованный секрет длиннее исходного base32, поэтому колонка расширяется.
+-- Уже сохраненные открытые значения шифрует сервис при запуске.
+ALTER TABLE user_mfa ALTER COLUMN totp_secret TYPE TEXT;
+
+COMMENT ON COLUMN user_mfa.totp_secret IS 'Base32 TOTP secret encrypted with SECRETS_ENCRYPTION_KEY (enc:v1:...)';
+COMMENT ON COLUMN signing_keys.private_key IS 'PKCS#8 PEM encrypted with SECRETS_ENCRYPTION_KEY (enc:v1:...); public part is served at /.well-known/jwks.json';
@@ -0,0 +1,4 @@
+-- Удаляются только счета без записей; использованный счет будет снова создан при первой проводке
+DELETE FROM ledger_accounts a
+WHERE a.system_code IN ('EXTERNAL_CASH', 'FX_HOUSE', 'FEE_INCOME', 'MANUAL_ADJUSTMENT', 'HOLD_SETTLEMENT')
+  AND NOT EXISTS (SELECT 1 FROM postings p WHERE p.account_id = a.id);
@@ -0,0 +1,11 @@
+-- Системные счета создаются заранее для базовых валют и валют существующих кошельков, чтобы операции
+-- не создавали их конкурентно. Для новых валют счет по-прежнему создается при первой проводке.
+INSERT INTO ledger_accounts (system_code, currency)
+SELECT code, currency
+FROM unnest(ARRAY['EXTERNAL_CASH', 'FX_HOUSE', 'FEE_INCOME', 'MANUAL_ADJUSTMENT', 'HOLD_SETTLEMENT']) AS code
+CROSS JOIN (
+    SELECT unnest(ARRAY['USD', 'EUR', 'RUB']) AS currency
+    UNION
+    SELECT DISTINCT currency FROM wallets
+) AS currencies
+ON CONFLICT (system_code, currency) DO NOTHING;
@@ -0,0 +1,52 @@
+-- Строки с длинными кодами валют не помещаются в VARCHAR(3). Кошельки, проводки и заявки удалять нельзя,
+-- поэтому откат останавливается, пока такие строки есть
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM wallets WHERE length(currency) > 3)
+        OR EXISTS (SELECT 1 FROM ledger_accounts WHERE length(currency) > 3)
+        OR EXISTS (SELECT 1 FROM exchange_operations WHERE length(from_currency) > 3 OR length(to_currency) > 3)
+        OR EXISTS (SELECT 1 FROM transfers WHERE length(from_currency) > 3 OR length(to_currency) > 3)
+        OR EXISTS (SELECT 1 FROM exchange_quotes WHERE length(from_currency) > 3 OR length(to_currency) > 3)
+        OR EXISTS (SELECT 1 FROM exchange_pricing WHERE length(from_currency) > 3 OR length(to_currency) > 3)
+        OR EXISTS (SELECT 1 FROM transaction_limits WHERE length(currency) > 3)
+        OR EXISTS (SELECT 1 FROM limit_orders WHERE length(from_currency) > 3 OR length(to_currency) > 3)
+        OR EXISTS (SELECT 1 FROM scheduled_exchanges WHERE length(from_currency) > 3 OR length(to_currency) > 3)
+        OR EXISTS (SELECT 1 FROM reconciliation_discrepancies WHERE length(currency) > 3)
+    THEN
+        RAISE EXCEPTION 'currency codes longer than 3 characters are in use; rollback would lose data';
+    END IF;
+END $$;
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS check_currency_code;
+ALTER TABLE wallets ALTER COLUMN currency TYPE VARCHAR(3);
+ALTER TABLE wallets
+    ADD CONSTRAINT check_currency_code CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE exchange_operations
+    ALTER COLUMN from_currency TYPE VARCHAR(3),
+    ALTER COLUMN to_currency TYPE VARCHAR(3);
+ALTER TABLE ledger_accounts ALTER COLUMN currency TYPE VARCHAR(3);
+ALTER TABLE postings ALTER COLUMN currency TYPE VARCHAR(3);
+ALTER TABLE transfers
+    ALTER COLUMN from_currency TYPE VARCHAR(3),
+    ALTER COLUMN to_currency TYPE VARCHAR(3);
+ALTER TABLE exchange_quotes
+    ALTER COLUMN from_currency TYPE VARCHAR(3),
+    ALTER COLUMN to_currency TYPE VARCHAR(3);
+ALTER TABLE exchange_pricing
+    ALTER COLUMN from_currency TYPE VARCHAR(3),
+    ALTER COLUMN to_currency TYPE VARCHAR(3);
+ALTER TABLE exchange_fee_tiers
+    ALTER COLUMN from_currency TYPE VARCHAR(3),
+    ALTER COLUMN to_currency TYPE VARCHAR(3);
+ALTER TABLE transaction_limits ALTER COLUMN currency TYPE VARCHAR(3);
+ALTER TABLE wallet_holds ALTER COLUMN currency TYPE VARCHAR(3);
+ALTER TABLE limit_orders
+    ALTER COLUMN from_currency TYPE VARCHAR(3),
+    ALTER COLUMN to_currency TYPE VARCHAR(3);
+ALTER TABLE scheduled_exchanges
+    ALTER COLUMN from_currency TYPE VARCHAR(3),
+    ALTER COLUMN to_currency TYPE VARCHAR(3);
+ALTER TABLE reconciliation_discrepancies ALTER COLUMN currency TYPE VARCHAR(3);
+
+COMMENT ON COLUMN wallets.currency IS 'ISO 4217 currency code from the currency registry';
@@ -0,0 +1,35 @@
+-- Коды валют длиннее трех символов (токены вроде USDT): формат 3-10 заглавных латинских букв и цифр,
+-- первая - буква. Расширение VARCHAR не перезаписывает таблицы и сохраняет индексы.
+ALTER TABLE wallets ALTER COLUMN currency TYPE VARCHAR(10);
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS check_currency_code;
+ALTER TABLE wallets
+    ADD CONSTRAINT check_currency_code CHECK (currency ~ '^[A-Z][A-Z0-9]{2,9}$');
+
+ALTER TABLE exchange_operations
+    ALTER COLUMN from_currency TYPE VARCHAR(10),
+    ALTER COLUMN to_currency TYPE VARCHAR(10);
+ALTER TABLE ledger_accounts ALTER COLUMN currency TYPE VARCHAR(10);
+ALTER TABLE postings ALTER COLUMN currency TYPE VARCHAR(10);
+ALTER TABLE transfers
+    ALTER COLUMN from_currency TYPE VARCHAR(10),
+    ALTER COLUMN to_currency TYPE VARCHAR(10);
+ALTER TABLE exchange_quotes
+    ALTER COLUMN from_currency TYPE VARCHAR(10),
+    ALTER COLUMN to_currency TYPE VARCHAR(10);
+ALTER TABLE exchange_pricing
+    ALTER COLUMN from_currency TYPE VARCHAR(10),
+    ALTER COLUMN to_currency TYPE VARCHAR(10);
+ALTER TABLE exchange_fee_tiers
+    ALTER COLUMN from_currency TYPE VARCHAR(10),
+    ALTER COLUMN to_currency TYPE VARCHAR(10);
+ALTER TABLE transaction_limits ALTER COLUMN currency TYPE VARCHAR(10);
+ALTER TABLE wallet_holds ALTER COLUMN currency TYPE VARCHAR(10);
+ALTER TABLE limit_orders
+    ALTER COLUMN from_currency TYPE VARCHAR(10),
+    ALTER COLUMN to_currency TYPE VARCHAR(10);
+ALTER TABLE scheduled_exchanges
+    ALTER COLUMN from_currency TYPE VARCHAR(10),
+    ALTER COLUMN to_currency TYPE VARCHAR(10);
+ALTER TABLE reconciliation_discrepancies ALTER COLUMN currency TYPE VARCHAR(10);
+
+COMMENT ON COLUMN wallets.currency IS 'Currency code from the currency registry: ISO 4217 or a token code such as USDT';
@@ -8,6 +8,7 @@ require (
 	github.com/jackc/pgx/v5 v5.7.6
 	github.com/joho/godotenv v1.5.1
 	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/shopspring/decimal v1.4.0
 	google.golang.org/grpc v1.76.0
 	google.golang.org/protobuf v1.36.6
 )
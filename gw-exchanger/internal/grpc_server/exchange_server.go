@@ -2,10 +2,12 @@ package grpc_server
 
 import (
 	"context"
+	"errors"
 	"gw-exchanger/internal/storage"
 	pb "gw-exchanger/proto-exchange"
 	"log/slog"
 
+	"github.com/shopspring/decimal"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -16,11 +18,8 @@ type ExchangeServer struct {
 	log     *slog.Logger
 }
 
-var supportedCurrencies = map[string]bool{
-	"USD": true,
-	"RUB": true,
-	"EUR": true,
-}
+// rateScale количество знаков после запятой в кросс-курсе
+const rateScale = 10
 
 func NewExchangeServer(storage storage.Storage, log *slog.Logger) *ExchangeServer {
 	return &ExchangeServer{
@@ -39,23 +38,26 @@ func (s *ExchangeServer) GetExchangeRates(ctx context.Context, req *pb.Empty) (*
 	}
 
 	ratesMap := make(map[string]float64, len(rates))
+	decimalRates := make(map[string]string, len(rates))
 	for _, rate := range rates {
-		ratesMap[rate.Currency] = rate.Rate
+		ratesMap[rate.Currency] = rate.Rate.InexactFloat64()
+		decimalRates[rate.Currency] = rate.Rate.String()
 	}
 
 	return &pb.ExchangeRatesResponse{
-		Rates: ratesMap,
+		Rates:        ratesMap,
+		RatesDecimal: decimalRates,
 	}, nil
 }
 
 func (s *ExchangeServer) GetExchangeRateForCurrency(ctx context.Context, req *pb.CurrencyRequest) (*pb.ExchangeRateResponse, error) {
 	const op = "grpc_server.GetExchangeRateForCurrency"
 
-	if !supportedCurrencies[req.FromCurrency] {
-		return nil, status.Errorf(codes.InvalidArgument, "unsupported from_currency: %s", req.FromCurrency)
+	if err := s.checkCurrency(ctx, "from_currency", req.FromCurrency); err != nil {
+		return nil, err
 	}
-	if !supportedCurrencies[req.ToCurrency] {
-		return nil, status.Errorf(codes.InvalidArgument, "unsupported to_currency: %s", req.ToCurrency)
+	if err := s.checkCurrency(ctx, "to_currency", req.ToCurrency); err != nil {
+		return nil, err
 	}
 	if req.FromCurrency == req.ToCurrency {
 		return nil, status.Error(codes.InvalidArgument, "from_currency and to_currency must be different")
@@ -68,6 +70,9 @@ func (s *ExchangeServer) GetExchangeRateForCurrency(ctx context.Context, req *pb
 
 	fromRate, err := s.storage.GetRateByCurrency(ctx, req.FromCurrency)
 	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "no exchange rate for %s", req.FromCurrency)
+		}
 		s.log.Error("ошибка получения курса исходной валюты",
 			slog.String("op", op),
 			slog.String("currency", req.FromCurrency),
@@ -77,6 +82,9 @@ func (s *ExchangeServer) GetExchangeRateForCurrency(ctx context.Context, req *pb
 
 	toRate, err := s.storage.GetRateByCurrency(ctx, req.ToCurrency)
 	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "no exchange rate for %s", req.ToCurrency)
+		}
 		s.log.Error("ошибка получения курса целевой валюты",
 			slog.String("op", op),
 			slog.String("currency", req.ToCurrency),
@@ -84,26 +92,77 @@ func (s *ExchangeServer) GetExchangeRateForCurrency(ctx context.Context, req *pb
 		return nil, status.Errorf(codes.Internal, "%s: failed to get to_currency rate", op)
 	}
 
-	if fromRate.Rate == 0 || toRate.Rate == 0 {
+	if fromRate.Rate.Sign() <= 0 || toRate.Rate.Sign() <= 0 {
 		s.log.Error("invalid rate in database",
 			slog.String("from", req.FromCurrency),
-			slog.Float64("from_rate", fromRate.Rate),
+			slog.String("from_rate", fromRate.Rate.String()),
 			slog.String("to", req.ToCurrency),
-			slog.Float64("to_rate", toRate.Rate))
+			slog.String("to_rate", toRate.Rate.String()))
 		return nil, status.Error(codes.Internal, "invalid exchange rate data")
 	}
 
-	rate := toRate.Rate / fromRate.Rate
+	rate := crossRate(fromRate.Rate, toRate.Rate)
 
 	s.log.Info("отправлен курс обмена",
 		slog.String("op", op),
 		slog.String("from", req.FromCurrency),
 		slog.String("to", req.ToCurrency),
-		slog.Float64("rate", rate))
+		slog.String("rate", rate.String()))
 
 	return &pb.ExchangeRateResponse{
 		FromCurrency: req.FromCurrency,
 		ToCurrency:   req.ToCurrency,
-		Rate:         rate,
+		Rate:         rate.InexactFloat64(),
+		RateDecimal:  rate.String(),
 	}, nil
 }
+
+func (s *ExchangeServer) GetCurrencies(ctx context.Context, req *pb.Empty) (*pb.CurrenciesResponse, error) {
+	const op = "grpc_server.GetCurrencies"
+
+	currencies, err := s.storage.GetCurrencies(ctx)
+	if err != nil {
+		s.log.Error("failed to get currencies", slog.String("op", op), slog.String("error", err.Error()))
+		return nil, status.Error(codes.Internal, "failed to get currencies")
+	}
+
+	resp := &pb.CurrenciesResponse{
+		Currencies: make([]*pb.Currency, 0, len(currencies)),
+	}
+	for _, c := range currencies {
+		resp.Currencies = append(resp.Currencies, &pb.Currency{
+			Code:     c.Code,
+			Name:     c.Name,
+			Exponent: c.Exponent,
+			Enabled:  c.Enabled,
+		})
+	}
+
+	return resp, nil
+}
+
+// checkCurrency проверяет, что валюта есть в реестре и включена
+func (s *ExchangeServer) checkCurrency(ctx context.Context, field, code string) error {
+	const op = "grpc_server.checkCurrency"
+
+	currency, err := s.storage.GetCurrency(ctx, code)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return status.Errorf(codes.InvalidArgument, "unsupported %s: %s", field, code)
+		}
+		s.log.Error("ошибка получения валюты из реестра",
+			slog.String("op", op),
+			slog.String("currency", code),
+			slog.String("error", err.Error()))
+		return status.Errorf(codes.Internal, "%s: failed to get currency", op)
+	}
+	if !currency.Enabled {
+		return status.Errorf(codes.InvalidArgument, "disabled %s: %s", field, code)
+	}
+	return nil
+}
+
+// crossRate вычисляет курс from→to через курсы к базовой валюте с округлением до rateScale знаков
+func crossRate(fromRate, toRate decimal.Decimal) decimal.Decimal {
+	return toRate.DivRound(fromRate, rateScale)
+}
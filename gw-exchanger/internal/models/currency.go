@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Currency запись реестра валют
+type Currency struct {
+	Code      string    `db:"code"`
+	Name      string    `db:"name"`
+	Exponent  int32     `db:"exponent"`
+	Enabled   bool      `db:"enabled"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -4,11 +4,12 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 type ExchangeRate struct {
-	ID        uuid.UUID `db:"id"`
-	Currency  string    `db:"currency"`
-	Rate      float64   `db:"rate"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID        uuid.UUID       `db:"id"`
+	Currency  string          `db:"currency"`
+	Rate      decimal.Decimal `db:"rate"`
+	UpdatedAt time.Time       `db:"updated_at"`
 }
@@ -51,7 +51,7 @@ func (s *PostgresStorage) GetRateByCurrency(ctx context.Context, currency string
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("currency %s not found: %w", currency, err)
+			return nil, fmt.Errorf("rate for currency %s: %w", currency, storage.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get rate: %w", err)
 	}
@@ -59,6 +59,50 @@ func (s *PostgresStorage) GetRateByCurrency(ctx context.Context, currency string
 	return &rate, nil
 }
 
+func (s *PostgresStorage) GetCurrencies(ctx context.Context) ([]models.Currency, error) {
+
+	rows, err := s.pool.Query(ctx, storage.GetAllCurrenciesQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query currencies: %w", err)
+	}
+	defer rows.Close()
+
+	var currencies []models.Currency
+	for rows.Next() {
+		var c models.Currency
+		if err := rows.Scan(&c.Code, &c.Name, &c.Exponent, &c.Enabled, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan currency: %w", err)
+		}
+		currencies = append(currencies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read currencies: %w", err)
+	}
+
+	return currencies, nil
+}
+
+func (s *PostgresStorage) GetCurrency(ctx context.Context, code string) (*models.Currency, error) {
+
+	var c models.Currency
+	err := s.pool.QueryRow(ctx, storage.GetCurrencyByCodeQuery, code).Scan(
+		&c.Code,
+		&c.Name,
+		&c.Exponent,
+		&c.Enabled,
+		&c.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("currency %s: %w", code, storage.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get currency: %w", err)
+	}
+
+	return &c, nil
+}
+
 func (s *PostgresStorage) Close() {
 	s.pool.Close()
 }
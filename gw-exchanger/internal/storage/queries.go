@@ -1,10 +1,13 @@
 package storage
 
 const (
+	// Курсы только включенных валют
 	GetAllRatesQuery = `
-		SELECT id, currency, rate, updated_at
-		FROM exchange_rates
-		ORDER BY currency
+		SELECT r.id, r.currency, r.rate, r.updated_at
+		FROM exchange_rates r
+		JOIN currencies c ON c.code = r.currency
+		WHERE c.enabled
+		ORDER BY r.currency
 	`
 
 	GetRateByCurrencyQuery = `
@@ -12,4 +15,16 @@ const (
 		FROM exchange_rates		
 		WHERE currency = $1
 	`
+
+	GetAllCurrenciesQuery = `
+		SELECT code, name, exponent, enabled, created_at
+		FROM currencies
+		ORDER BY code
+	`
+
+	GetCurrencyByCodeQuery = `
+		SELECT code, name, exponent, enabled, created_at
+		FROM currencies
+		WHERE code = $1
+	`
 )
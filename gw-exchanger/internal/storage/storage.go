@@ -2,11 +2,17 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"gw-exchanger/internal/models"
 )
 
+// ErrNotFound запись не найдена
+var ErrNotFound = errors.New("not found")
+
 type Storage interface {
 	GetAllRates(ctx context.Context) ([]models.ExchangeRate, error)
 	GetRateByCurrency(ctx context.Context, currency string) (*models.ExchangeRate, error)
+	GetCurrencies(ctx context.Context) ([]models.Currency, error)
+	GetCurrency(ctx context.Context, code string) (*models.Currency, error)
 	Close()
 }
@@ -0,0 +1,2 @@
+ALTER TABLE exchange_rates
+    ALTER COLUMN rate TYPE DOUBLE PRECISION USING rate::DOUBLE PRECISION;
@@ -0,0 +1,3 @@
+-- Курсы хранятся точно, без погрешности двоичной плавающей точки
+ALTER TABLE exchange_rates
+    ALTER COLUMN rate TYPE NUMERIC(20, 10) USING rate::NUMERIC(20, 10);
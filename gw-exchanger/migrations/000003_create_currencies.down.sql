@@ -0,0 +1,2 @@
+ALTER TABLE exchange_rates DROP CONSTRAINT IF EXISTS fk_exchange_rates_currency;
+DROP TABLE IF EXISTS currencies;
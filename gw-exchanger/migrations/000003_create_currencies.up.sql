@@ -0,0 +1,23 @@
+-- Реестр валют: единственное место, где перечислены поддерживаемые валюты
+CREATE TABLE IF NOT EXISTS currencies (
+    code VARCHAR(3) PRIMARY KEY CHECK (code ~ '^[A-Z]{3}$'),
+    name VARCHAR(64) NOT NULL,
+    exponent SMALLINT NOT NULL DEFAULT 2 CHECK (exponent BETWEEN 0 AND 18),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO currencies (code, name, exponent) VALUES
+    ('USD', 'US Dollar', 2),
+    ('RUB', 'Russian Ruble', 2),
+    ('EUR', 'Euro', 2)
+ON CONFLICT (code) DO NOTHING;
+
+-- Курс можно завести только для валюты из реестра
+INSERT INTO currencies (code, name)
+SELECT currency, currency FROM exchange_rates
+ON CONFLICT (code) DO NOTHING;
+
+ALTER TABLE exchange_rates
+    ADD CONSTRAINT fk_exchange_rates_currency
+        FOREIGN KEY (currency) REFERENCES currencies(code);
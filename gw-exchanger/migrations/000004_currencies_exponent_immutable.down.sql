@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trigger_currencies_exponent_immutable ON currencies;
+DROP FUNCTION IF EXISTS prevent_currency_exponent_change();
@@ -0,0 +1,15 @@
+-- Суммы в wallet сервисе хранятся в минимальных единицах, поэтому смена точности валюты исказила бы балансы
+CREATE OR REPLACE FUNCTION prevent_currency_exponent_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.exponent <> OLD.exponent THEN
+        RAISE EXCEPTION 'exponent of currency % cannot be changed', OLD.code;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_currencies_exponent_immutable
+    BEFORE UPDATE OF exponent ON currencies
+    FOR EACH ROW
+    EXECUTE FUNCTION prevent_currency_exponent_change();
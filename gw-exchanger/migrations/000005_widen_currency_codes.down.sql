@@ -0,0 +1,13 @@
+-- Валюту с длинным кодом могут использовать кошельки, поэтому откат не удаляет ее, а останавливается
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM currencies WHERE length(code) > 3) THEN
+        RAISE EXCEPTION 'currency codes longer than 3 characters are in use';
+    END IF;
+END $$;
+
+ALTER TABLE currencies DROP CONSTRAINT IF EXISTS currencies_code_check;
+ALTER TABLE exchange_rates ALTER COLUMN currency TYPE VARCHAR(3);
+ALTER TABLE currencies ALTER COLUMN code TYPE VARCHAR(3);
+ALTER TABLE currencies
+    ADD CONSTRAINT currencies_code_check CHECK (code ~ '^[A-Z]{3}$');
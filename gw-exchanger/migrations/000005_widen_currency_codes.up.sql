@@ -0,0 +1,6 @@
+-- Коды валют длиннее трех символов (токены вроде USDT): 3-10 заглавных латинских букв и цифр, первая - буква
+ALTER TABLE currencies DROP CONSTRAINT IF EXISTS currencies_code_check;
+ALTER TABLE currencies ALTER COLUMN code TYPE VARCHAR(10);
+ALTER TABLE exchange_rates ALTER COLUMN currency TYPE VARCHAR(10);
+ALTER TABLE currencies
+    ADD CONSTRAINT currencies_code_check CHECK (code ~ '^[A-Z][A-Z0-9]{2,9}$');
@@ -76,10 +76,12 @@ func (x *CurrencyRequest) GetToCurrency() string {
 
 // Ответ с курсом обмена для конкретной валюты
 type ExchangeRateResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	FromCurrency  string                 `protobuf:"bytes,1,opt,name=from_currency,json=fromCurrency,proto3" json:"from_currency,omitempty"`
-	ToCurrency    string                 `protobuf:"bytes,2,opt,name=to_currency,json=toCurrency,proto3" json:"to_currency,omitempty"`
-	Rate          float64                `protobuf:"fixed64,3,opt,name=rate,proto3" json:"rate,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	FromCurrency string                 `protobuf:"bytes,1,opt,name=from_currency,json=fromCurrency,proto3" json:"from_currency,omitempty"`
+	ToCurrency   string                 `protobuf:"bytes,2,opt,name=to_currency,json=toCurrency,proto3" json:"to_currency,omitempty"`
+	// Deprecated: Marked as deprecated in exchange.proto.
+	Rate          float64 `protobuf:"fixed64,3,opt,name=rate,proto3" json:"rate,omitempty"`                                // устарело: приближенное значение, используйте rate_decimal
+	RateDecimal   string  `protobuf:"bytes,4,opt,name=rate_decimal,json=rateDecimal,proto3" json:"rate_decimal,omitempty"` // точный курс в виде десятичной строки
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -128,6 +130,7 @@ func (x *ExchangeRateResponse) GetToCurrency() string {
 	return ""
 }
 
+// Deprecated: Marked as deprecated in exchange.proto.
 func (x *ExchangeRateResponse) GetRate() float64 {
 	if x != nil {
 		return x.Rate
@@ -135,10 +138,19 @@ func (x *ExchangeRateResponse) GetRate() float64 {
 	return 0
 }
 
+func (x *ExchangeRateResponse) GetRateDecimal() string {
+	if x != nil {
+		return x.RateDecimal
+	}
+	return ""
+}
+
 // Ответ с курсами обмена всех валют
 type ExchangeRatesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Rates         map[string]float64     `protobuf:"bytes,1,rep,name=rates,proto3" json:"rates,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"` // ключ: валюта, значение: курс
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Deprecated: Marked as deprecated in exchange.proto.
+	Rates         map[string]float64 `protobuf:"bytes,1,rep,name=rates,proto3" json:"rates,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"`                                 // устарело: используйте rates_decimal
+	RatesDecimal  map[string]string  `protobuf:"bytes,2,rep,name=rates_decimal,json=ratesDecimal,proto3" json:"rates_decimal,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // ключ: валюта, значение: курс в виде десятичной строки
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -173,6 +185,7 @@ func (*ExchangeRatesResponse) Descriptor() ([]byte, []int) {
 	return file_exchange_proto_rawDescGZIP(), []int{2}
 }
 
+// Deprecated: Marked as deprecated in exchange.proto.
 func (x *ExchangeRatesResponse) GetRates() map[string]float64 {
 	if x != nil {
 		return x.Rates
@@ -180,6 +193,127 @@ func (x *ExchangeRatesResponse) GetRates() map[string]float64 {
 	return nil
 }
 
+func (x *ExchangeRatesResponse) GetRatesDecimal() map[string]string {
+	if x != nil {
+		return x.RatesDecimal
+	}
+	return nil
+}
+
+// Валюта из реестра
+type Currency struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"` // код ISO 4217, например USD
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Exponent      int32                  `protobuf:"varint,3,opt,name=exponent,proto3" json:"exponent,omitempty"` // количество знаков после запятой в минимальных единицах
+	Enabled       bool                   `protobuf:"varint,4,opt,name=enabled,proto3" json:"enabled,omitempty"`   // отключенная валюта недоступна для новых операций
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Currency) Reset() {
+	*x = Currency{}
+	mi := &file_exchange_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Currency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Currency) ProtoMessage() {}
+
+func (x *Currency) ProtoReflect() protoreflect.Message {
+	mi := &file_exchange_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Currency.ProtoReflect.Descriptor instead.
+func (*Currency) Descriptor() ([]byte, []int) {
+	return file_exchange_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Currency) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Currency) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Currency) GetExponent() int32 {
+	if x != nil {
+		return x.Exponent
+	}
+	return 0
+}
+
+func (x *Currency) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+// Ответ с реестром валют, включая отключенные
+type CurrenciesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Currencies    []*Currency            `protobuf:"bytes,1,rep,name=currencies,proto3" json:"currencies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CurrenciesResponse) Reset() {
+	*x = CurrenciesResponse{}
+	mi := &file_exchange_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CurrenciesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CurrenciesResponse) ProtoMessage() {}
+
+func (x *CurrenciesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_exchange_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CurrenciesResponse.ProtoReflect.Descriptor instead.
+func (*CurrenciesResponse) Descriptor() ([]byte, []int) {
+	return file_exchange_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CurrenciesResponse) GetCurrencies() []*Currency {
+	if x != nil {
+		return x.Currencies
+	}
+	return nil
+}
+
 // Пустое сообщение
 type Empty struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -189,7 +323,7 @@ type Empty struct {
 
 func (x *Empty) Reset() {
 	*x = Empty{}
-	mi := &file_exchange_proto_msgTypes[3]
+	mi := &file_exchange_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -201,7 +335,7 @@ func (x *Empty) String() string {
 func (*Empty) ProtoMessage() {}
 
 func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_exchange_proto_msgTypes[3]
+	mi := &file_exchange_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -214,7 +348,7 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Empty.ProtoReflect.Descriptor instead.
 func (*Empty) Descriptor() ([]byte, []int) {
-	return file_exchange_proto_rawDescGZIP(), []int{3}
+	return file_exchange_proto_rawDescGZIP(), []int{5}
 }
 
 var File_exchange_proto protoreflect.FileDescriptor
@@ -225,22 +359,37 @@ const file_exchange_proto_rawDesc = "" +
 	"\x0fCurrencyRequest\x12#\n" +
 	"\rfrom_currency\x18\x01 \x01(\tR\ffromCurrency\x12\x1f\n" +
 	"\vto_currency\x18\x02 \x01(\tR\n" +
-	"toCurrency\"p\n" +
+	"toCurrency\"\x97\x01\n" +
 	"\x14ExchangeRateResponse\x12#\n" +
 	"\rfrom_currency\x18\x01 \x01(\tR\ffromCurrency\x12\x1f\n" +
 	"\vto_currency\x18\x02 \x01(\tR\n" +
-	"toCurrency\x12\x12\n" +
-	"\x04rate\x18\x03 \x01(\x01R\x04rate\"\x93\x01\n" +
-	"\x15ExchangeRatesResponse\x12@\n" +
-	"\x05rates\x18\x01 \x03(\v2*.exchange.ExchangeRatesResponse.RatesEntryR\x05rates\x1a8\n" +
+	"toCurrency\x12\x16\n" +
+	"\x04rate\x18\x03 \x01(\x01B\x02\x18\x01R\x04rate\x12!\n" +
+	"\frate_decimal\x18\x04 \x01(\tR\vrateDecimal\"\xb0\x02\n" +
+	"\x15ExchangeRatesResponse\x12D\n" +
+	"\x05rates\x18\x01 \x03(\v2*.exchange.ExchangeRatesResponse.RatesEntryB\x02\x18\x01R\x05rates\x12V\n" +
+	"\rrates_decimal\x18\x02 \x03(\v21.exchange.ExchangeRatesResponse.RatesDecimalEntryR\fratesDecimal\x1a8\n" +
 	"\n" +
 	"RatesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"\a\n" +
-	"\x05Empty2\xb0\x01\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\x1a?\n" +
+	"\x11RatesDecimalEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"h\n" +
+	"\bCurrency\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\bexponent\x18\x03 \x01(\x05R\bexponent\x12\x18\n" +
+	"\aenabled\x18\x04 \x01(\bR\aenabled\"H\n" +
+	"\x12CurrenciesResponse\x122\n" +
+	"\n" +
+	"currencies\x18\x01 \x03(\v2\x12.exchange.CurrencyR\n" +
+	"currencies\"\a\n" +
+	"\x05Empty2\xf0\x01\n" +
 	"\x0fExchangeService\x12D\n" +
 	"\x10GetExchangeRates\x12\x0f.exchange.Empty\x1a\x1f.exchange.ExchangeRatesResponse\x12W\n" +
-	"\x1aGetExchangeRateForCurrency\x12\x19.exchange.CurrencyRequest\x1a\x1e.exchange.ExchangeRateResponseB+Z)gw-exchanger/proto-exchange/exchange_grpcb\x06proto3"
+	"\x1aGetExchangeRateForCurrency\x12\x19.exchange.CurrencyRequest\x1a\x1e.exchange.ExchangeRateResponse\x12>\n" +
+	"\rGetCurrencies\x12\x0f.exchange.Empty\x1a\x1c.exchange.CurrenciesResponseB+Z)gw-exchanger/proto-exchange/exchange_grpcb\x06proto3"
 
 var (
 	file_exchange_proto_rawDescOnce sync.Once
@@ -254,25 +403,32 @@ func file_exchange_proto_rawDescGZIP() []byte {
 	return file_exchange_proto_rawDescData
 }
 
-var file_exchange_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_exchange_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_exchange_proto_goTypes = []any{
 	(*CurrencyRequest)(nil),       // 0: exchange.CurrencyRequest
 	(*ExchangeRateResponse)(nil),  // 1: exchange.ExchangeRateResponse
 	(*ExchangeRatesResponse)(nil), // 2: exchange.ExchangeRatesResponse
-	(*Empty)(nil),                 // 3: exchange.Empty
-	nil,                           // 4: exchange.ExchangeRatesResponse.RatesEntry
+	(*Currency)(nil),              // 3: exchange.Currency
+	(*CurrenciesResponse)(nil),    // 4: exchange.CurrenciesResponse
+	(*Empty)(nil),                 // 5: exchange.Empty
+	nil,                           // 6: exchange.ExchangeRatesResponse.RatesEntry
+	nil,                           // 7: exchange.ExchangeRatesResponse.RatesDecimalEntry
 }
 var file_exchange_proto_depIdxs = []int32{
-	4, // 0: exchange.ExchangeRatesResponse.rates:type_name -> exchange.ExchangeRatesResponse.RatesEntry
-	3, // 1: exchange.ExchangeService.GetExchangeRates:input_type -> exchange.Empty
-	0, // 2: exchange.ExchangeService.GetExchangeRateForCurrency:input_type -> exchange.CurrencyRequest
-	2, // 3: exchange.ExchangeService.GetExchangeRates:output_type -> exchange.ExchangeRatesResponse
-	1, // 4: exchange.ExchangeService.GetExchangeRateForCurrency:output_type -> exchange.ExchangeRateResponse
-	3, // [3:5] is the sub-list for method output_type
-	1, // [1:3] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	6, // 0: exchange.ExchangeRatesResponse.rates:type_name -> exchange.ExchangeRatesResponse.RatesEntry
+	7, // 1: exchange.ExchangeRatesResponse.rates_decimal:type_name -> exchange.ExchangeRatesResponse.RatesDecimalEntry
+	3, // 2: exchange.CurrenciesResponse.currencies:type_name -> exchange.Currency
+	5, // 3: exchange.ExchangeService.GetExchangeRates:input_type -> exchange.Empty
+	0, // 4: exchange.ExchangeService.GetExchangeRateForCurrency:input_type -> exchange.CurrencyRequest
+	5, // 5: exchange.ExchangeService.GetCurrencies:input_type -> exchange.Empty
+	2, // 6: exchange.ExchangeService.GetExchangeRates:output_type -> exchange.ExchangeRatesResponse
+	1, // 7: exchange.ExchangeService.GetExchangeRateForCurrency:output_type -> exchange.ExchangeRateResponse
+	4, // 8: exchange.ExchangeService.GetCurrencies:output_type -> exchange.CurrenciesResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_exchange_proto_init() }
@@ -286,7 +442,7 @@ func file_exchange_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_exchange_proto_rawDesc), len(file_exchange_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -21,6 +21,7 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	ExchangeService_GetExchangeRates_FullMethodName           = "/exchange.ExchangeService/GetExchangeRates"
 	ExchangeService_GetExchangeRateForCurrency_FullMethodName = "/exchange.ExchangeService/GetExchangeRateForCurrency"
+	ExchangeService_GetCurrencies_FullMethodName              = "/exchange.ExchangeService/GetCurrencies"
 )
 
 // ExchangeServiceClient is the client API for ExchangeService service.
@@ -33,6 +34,8 @@ type ExchangeServiceClient interface {
 	GetExchangeRates(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ExchangeRatesResponse, error)
 	// Получение курса обмена для конкретной валюты
 	GetExchangeRateForCurrency(ctx context.Context, in *CurrencyRequest, opts ...grpc.CallOption) (*ExchangeRateResponse, error)
+	// Получение реестра валют
+	GetCurrencies(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CurrenciesResponse, error)
 }
 
 type exchangeServiceClient struct {
@@ -63,6 +66,16 @@ func (c *exchangeServiceClient) GetExchangeRateForCurrency(ctx context.Context,
 	return out, nil
 }
 
+func (c *exchangeServiceClient) GetCurrencies(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CurrenciesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CurrenciesResponse)
+	err := c.cc.Invoke(ctx, ExchangeService_GetCurrencies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ExchangeServiceServer is the server API for ExchangeService service.
 // All implementations must embed UnimplementedExchangeServiceServer
 // for forward compatibility.
@@ -73,6 +86,8 @@ type ExchangeServiceServer interface {
 	GetExchangeRates(context.Context, *Empty) (*ExchangeRatesResponse, error)
 	// Получение курса обмена для конкретной валюты
 	GetExchangeRateForCurrency(context.Context, *CurrencyRequest) (*ExchangeRateResponse, error)
+	// Получение реестра валют
+	GetCurrencies(context.Context, *Empty) (*CurrenciesResponse, error)
 	mustEmbedUnimplementedExchangeServiceServer()
 }
 
@@ -89,6 +104,9 @@ func (UnimplementedExchangeServiceServer) GetExchangeRates(context.Context, *Emp
 func (UnimplementedExchangeServiceServer) GetExchangeRateForCurrency(context.Context, *CurrencyRequest) (*ExchangeRateResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetExchangeRateForCurrency not implemented")
 }
+func (UnimplementedExchangeServiceServer) GetCurrencies(context.Context, *Empty) (*CurrenciesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCurrencies not implemented")
+}
 func (UnimplementedExchangeServiceServer) mustEmbedUnimplementedExchangeServiceServer() {}
 func (UnimplementedExchangeServiceServer) testEmbeddedByValue()                         {}
 
@@ -146,6 +164,24 @@ func _ExchangeService_GetExchangeRateForCurrency_Handler(srv interface{}, ctx co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ExchangeService_GetCurrencies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServiceServer).GetCurrencies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExchangeService_GetCurrencies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServiceServer).GetCurrencies(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ExchangeService_ServiceDesc is the grpc.ServiceDesc for ExchangeService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -161,6 +197,10 @@ var ExchangeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetExchangeRateForCurrency",
 			Handler:    _ExchangeService_GetExchangeRateForCurrency_Handler,
 		},
+		{
+			MethodName: "GetCurrencies",
+			Handler:    _ExchangeService_GetCurrencies_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "exchange.proto",
@@ -154,7 +154,9 @@ func (h *consumerGroupHandler) processMessage(ctx context.Context, message *sara
 
 	notification := &models.LargeTransferNotification{
 		TransactionID: kafkaMsg.TransactionID,
+		Type:          kafkaMsg.Type,
 		UserID:        kafkaMsg.UserID,
+		RecipientID:   kafkaMsg.RecipientID,
 		FromCurrency:  kafkaMsg.FromCurrency,
 		ToCurrency:    kafkaMsg.ToCurrency,
 		Amount:        kafkaMsg.Amount,
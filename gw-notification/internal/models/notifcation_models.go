@@ -5,7 +5,9 @@ import "time"
 type LargeTransferNotification struct {
 	ID            string    `bson:"_id,omitempty" json:"id"`
 	TransactionID string    `bson:"transaction_id" json:"transaction_id"`
+	Type          string    `bson:"type" json:"type"`
 	UserID        string    `bson:"user_id" json:"user_id"`
+	RecipientID   string    `bson:"recipient_id,omitempty" json:"recipient_id,omitempty"`
 	FromCurrency  string    `bson:"from_currency" json:"from_currency"`
 	ToCurrency    string    `bson:"to_currency" json:"to_currency"`
 	Amount        float64   `bson:"amount" json:"amount"`
@@ -17,7 +19,9 @@ type LargeTransferNotification struct {
 
 type KafkaMessage struct {
 	TransactionID string    `json:"transaction_id"`
+	Type          string    `json:"type"`
 	UserID        string    `json:"user_id"`
+	RecipientID   string    `json:"recipient_id,omitempty"`
 	FromCurrency  string    `json:"from_currency"`
 	ToCurrency    string    `json:"to_currency"`
 	Amount        float64   `json:"amount"`